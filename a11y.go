@@ -0,0 +1,142 @@
+// a11y.go
+// Screen reader mode: loop states in words in a plain list, and spoken or
+// audible announcements of state changes.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"jaudio/internal/slstate"
+)
+
+var (
+	// a11yMode (--a11y) replaces the mixer table with a list of loops
+	// described in words.
+	a11yMode bool
+	// a11yAnnounce (--a11y-announce) is a command run with each
+	// announcement as its last argument, e.g. spd-say.
+	a11yAnnounce string
+
+	// lastAnnouncement is shown above the loop list. It is guarded by mu.
+	lastAnnouncement string
+	// announcements feeds the announcer; full means announcements are
+	// dropped rather than queued behind a slow command.
+	announcements = make(chan string, 8)
+)
+
+// stateWords describe loop states, in English.
+var stateWords = map[slstate.State]string{
+	slstate.Unknown:    "unknown",
+	slstate.Off:        "empty",
+	slstate.WaitStart:  "waiting to record",
+	slstate.Record:     "recording",
+	slstate.WaitStop:   "finishing recording",
+	slstate.Play:       "playing",
+	slstate.Overdub:    "overdubbing",
+	slstate.Multiply:   "multiplying",
+	slstate.Insert:     "inserting",
+	slstate.Replace:    "replacing",
+	slstate.Delay:      "delay",
+	slstate.Mute:       "muted",
+	slstate.Scratch:    "scratching",
+	slstate.OneShot:    "playing once",
+	slstate.Substitute: "substituting",
+	slstate.Pause:      "paused",
+	slstate.OffMuted:   "empty, muted",
+}
+
+// stateWord describes a state in the current language.
+func stateWord(s slstate.State) string {
+	if w, ok := stateWords[s]; ok {
+		return tr(w)
+	}
+	return s.String()
+}
+
+// dbWords gives an amplitude in whole dB, or "silent".
+func dbWords(amp float32) string {
+	if amp <= 0 || ampToDB(amp) < meterMinDB {
+		return tr("silent")
+	}
+	return fmt.Sprintf("%.0f dB", ampToDB(amp))
+}
+
+// a11yLoopText describes a loop, e.g. "Loop 2: playing, level -6 dB, in
+// silent, out -12 dB, 1.5 s".
+func a11yLoopText(i int, ls *LoopState) string {
+	if !ls.haveState {
+		return trf("Loop %d: %s", i+1, tr("no state from the engine yet"))
+	}
+	parts := []string{stateWord(ls.State)}
+	if ls.countIn != nil {
+		parts = append(parts, trf("recording in %d beats", ls.countIn.beatsLeft(time.Now())))
+	}
+	if t := (slstate.Transition{From: ls.State, To: ls.NextState}); t.Pending() {
+		parts = append(parts, trf("next %s", stateWord(t.To)))
+	}
+	parts = append(parts,
+		trf("level %s", dbWords(ls.Wet)),
+		trf("in %s", dbWords(ls.InPeakMeter*meterTrim(i))),
+		trf("out %s", dbWords(ls.OutPeakMeter*meterTrim(i))),
+		fmt.Sprintf("%.1f s", ls.LoopPos))
+	if now := time.Now(); ls.stale("loop_pos", now) || ls.stale("in_peak_meter", now) || ls.stale("out_peak_meter", now) {
+		parts = append(parts, tr("meters stale"))
+	}
+	return trf("Loop %d: %s", i+1, strings.Join(parts, ", "))
+}
+
+// a11yText is the Mixer page in screen reader mode: the last announcement,
+// then one line per loop. The caller must hold mu.
+func a11yText(loops []*LoopState) string {
+	var b strings.Builder
+	if lastAnnouncement != "" {
+		b.WriteString(trf("Last change: %s", lastAnnouncement) + "\n\n")
+	}
+	for i, ls := range loops {
+		b.WriteString(a11yLoopText(i, ls) + "\n")
+	}
+	return b.String()
+}
+
+// announceState announces a state change. The caller must hold mu.
+func announceState(e stateEvent) {
+	text := trf("Loop %d: %s", e.Loop+1, stateWord(e.To))
+	lastAnnouncement = text
+	select {
+	case announcements <- text:
+	default:
+		tuiLog.Debug("announcement dropped", "text", text)
+	}
+}
+
+// startAnnouncer rings the terminal bell with beep and runs the
+// --a11y-announce command for each announcement, one at a time.
+func startAnnouncer(beep func()) {
+	args := strings.Fields(a11yAnnounce)
+	goService("announcer", func(ctx context.Context) error {
+		for {
+			var text string
+			select {
+			case <-ctx.Done():
+				return nil
+			case text = <-announcements:
+			}
+			beep()
+			if len(args) == 0 {
+				continue
+			}
+			cmd := exec.CommandContext(ctx, args[0], append(args[1:], text)...)
+			start := time.Now()
+			if out, err := cmd.CombinedOutput(); err != nil {
+				tuiLog.Warn("announce command failed", "cmd", args[0], "err", err, "output", strings.TrimSpace(string(out)))
+			} else {
+				tuiLog.Debug("announced", "text", text, "in", time.Since(start))
+			}
+		}
+	})
+}
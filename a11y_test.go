@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"jaudio/internal/slstate"
+)
+
+// TestA11yLoopText tests loops described in words
+func TestA11yLoopText(t *testing.T) {
+	tests := []struct {
+		ls   LoopState
+		want string
+	}{
+		{LoopState{}, "Loop 1: no state from the engine yet"},
+		{LoopState{haveState: true, State: slstate.Play, NextState: slstate.Overdub, Wet: 0.5, InPeakMeter: 0, OutPeakMeter: 0.25, LoopPos: 1.25},
+			"Loop 1: playing, next overdubbing, level -6 dB, in silent, out -12 dB, 1.2 s"},
+		{LoopState{haveState: true, State: slstate.OffMuted, NextState: slstate.Unknown, Wet: 1},
+			"Loop 1: empty, muted, level 0 dB, in silent, out silent, 0.0 s"},
+	}
+	for _, tt := range tests {
+		if got := a11yLoopText(0, &tt.ls); got != tt.want {
+			t.Errorf("a11yLoopText = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// TestAnnounceState tests that state changes are announced through the
+// bell and the announce command, and shown above the loop list
+func TestAnnounceState(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell")
+	}
+	dir := t.TempDir()
+	script, out := filepath.Join(dir, "say"), filepath.Join(dir, "said")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\" >> "+out+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	defer func(cmd, last string) { a11yAnnounce, lastAnnouncement = cmd, last }(a11yAnnounce, lastAnnouncement)
+	a11yAnnounce = script + " -r 20"
+	// An announcer of its own, stopped after the test, so one left from an
+	// earlier run cannot take the announcement.
+	old := services
+	services = newServiceGroup(context.Background())
+	t.Cleanup(func() {
+		stopServices()
+		services = old
+	})
+	beeps := make(chan bool, 4)
+	startAnnouncer(func() { beeps <- true })
+
+	mu.Lock()
+	announceState(stateEvent{Loop: 1, From: slstate.Play, To: slstate.Record})
+	text := a11yText([]*LoopState{{}})
+	mu.Unlock()
+	if !strings.HasPrefix(text, "Last change: Loop 2: recording\n") {
+		t.Errorf("a11yText = %q", text)
+	}
+	select {
+	case <-beeps:
+	case <-time.After(time.Second):
+		t.Fatal("no bell")
+	}
+	eventually(t, "announce command", func() bool {
+		data, _ := os.ReadFile(out)
+		return string(data) == "-r 20 Loop 2: recording\n"
+	})
+}
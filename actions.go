@@ -0,0 +1,152 @@
+// actions.go
+// GUI actions run from MIDI, OSC and macros: select a loop, faders, scenes,
+// songs and loop commands.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// actionStep is one parsed action. value is from 0 to 1: a fader position,
+// or the velocity or CC value that triggered it.
+type actionStep func(value float32)
+
+// parseActions parses actions separated by ";", which run in order. fader
+// reports whether one of them follows a continuous value.
+func parseActions(s string, macros map[string]string) (steps []actionStep, fader bool, err error) {
+	return parseActionsIn(s, macros, nil)
+}
+
+// parseActionsIn parses actions inside the macros named in outer.
+func parseActionsIn(s string, macros map[string]string, outer []string) (steps []actionStep, fader bool, err error) {
+	for _, a := range strings.Split(s, ";") {
+		a = strings.TrimSpace(a)
+		if name, ok := strings.CutPrefix(a, "macro "); ok {
+			name = strings.TrimSpace(name)
+			body, ok := macros[name]
+			switch {
+			case !ok:
+				return nil, false, fmt.Errorf("unknown macro %q", name)
+			case slices.Contains(outer, name):
+				return nil, false, fmt.Errorf("macro %q runs itself", name)
+			}
+			inner, f, err := parseActionsIn(body, macros, append(slices.Clip(outer), name))
+			if err != nil {
+				return nil, false, fmt.Errorf("macro %s: %w", name, err)
+			}
+			steps, fader = append(steps, inner...), fader || f
+			continue
+		}
+		step, f, err := parseAction(a)
+		if err != nil {
+			return nil, false, err
+		}
+		steps, fader = append(steps, step), fader || f
+	}
+	return steps, fader, nil
+}
+
+// runActions runs parsed actions with value.
+func runActions(steps []actionStep, value float32) {
+	for _, step := range steps {
+		step(value)
+	}
+}
+
+// parseAction parses one action:
+//
+//	select 2 | select next | select prev   the loop on the Loop page
+//	level 2 | level                        a Level fader
+//	scene 3                                recall a scene
+//	song 2 | song next | song prev         switch song
+//	record 1 | mute 2,3 | undo all | ...   a loop command, as for footswitches
+//	cancel_record 1 | cancel_record all    undo records in progress
+//	stop_ramps                             stop fades and scene ramps
+//	macro name                             the actions of a macro
+func parseAction(s string) (step actionStep, fader bool, err error) {
+	verb, arg, _ := strings.Cut(s, " ")
+	arg = strings.TrimSpace(arg)
+	num := func(name string) (int, error) {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("%s needs a number from 1, not %q", name, arg)
+		}
+		return n - 1, nil
+	}
+	switch verb {
+	case "select":
+		move := map[string]int{"next": 1, "prev": -1}[arg]
+		n, err := num("select")
+		if move == 0 && err != nil {
+			return nil, false, err
+		}
+		return func(float32) {
+			mu.Lock()
+			i := n
+			if move != 0 {
+				i = selectedLoop + move
+			}
+			selectLoop(i)
+			mu.Unlock()
+		}, false, nil
+	case "level":
+		loop := -1
+		if arg != "" {
+			if loop, err = num("level"); err != nil {
+				return nil, false, err
+			}
+		}
+		return func(v float32) {
+			i := loop
+			if i < 0 {
+				mu.Lock()
+				i = selectedLoop
+				mu.Unlock()
+			}
+			setLevel(i, lvlLaw.amplitude(v, levelMax, meterMinDB))
+		}, true, nil
+	case "scene":
+		n, err := num("scene")
+		if err != nil {
+			return nil, false, err
+		}
+		return func(float32) { recallScene(n) }, false, nil
+	case "song":
+		move := map[string]int{"next": 1, "prev": -1}[arg]
+		n, err := num("song")
+		if move == 0 && err != nil {
+			return nil, false, err
+		}
+		return func(float32) {
+			i := n
+			if move != 0 {
+				mu.Lock()
+				i = currentSong + move
+				mu.Unlock()
+			}
+			switchSong(i)
+		}, false, nil
+	case "cancel_record":
+		var loops []int
+		if arg != "" {
+			if loops, err = parseLoopList(arg); err != nil {
+				return nil, false, err
+			}
+		}
+		return func(float32) { cancelRecords(loops) }, false, nil
+	case "stop_ramps":
+		if arg != "" {
+			return nil, false, fmt.Errorf("stop_ramps takes no loops, not %q", arg)
+		}
+		return func(float32) { stopRamps() }, false, nil
+	}
+	a, err := parseFootAction(s)
+	if err != nil {
+		return nil, false, fmt.Errorf("unknown action %q", s)
+	}
+	return func(float32) { a.run() }, false, nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+// TestParseActions tests action lists, macro expansion and macros that run themselves
+func TestParseActions(t *testing.T) {
+	macros := map[string]string{
+		"intro": "select 1; scene 2",
+		"verse": "macro intro; level 3",
+		"loopA": "macro loopB",
+		"loopB": "song next; macro loopA",
+	}
+	steps, fader, err := parseActions("record 1; macro verse", macros)
+	if err != nil || len(steps) != 4 || !fader {
+		t.Errorf("got %d steps, fader %v, err %v; want 4, true, nil", len(steps), fader, err)
+	}
+	for _, bad := range []string{"macro loopA", "macro missing", "select", "scene 0", "song last", "level x", "jump 1"} {
+		if _, _, err := parseActions(bad, macros); err == nil {
+			t.Errorf("parseActions(%q) succeeded", bad)
+		}
+	}
+	if _, err := parseConfig([]byte("macros: {a: macro b, b: select 1}")); err != nil {
+		t.Error(err)
+	}
+	if _, err := parseConfig([]byte("macros: {a: macro a}")); err == nil {
+		t.Error("a macro running itself was accepted")
+	}
+}
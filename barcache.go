@@ -0,0 +1,43 @@
+// barcache.go
+// Bar strings: each "█…█" and padding a bar is drawn with is built once for
+// its fill and width and reused by every cell and frame after, rather than
+// repeated character by character each time. A resize changes the bars'
+// width, so it drops them all.
+
+package main
+
+import "strings"
+
+// barKey is a bar's text: full characters of █, then empty ones up to
+// width.
+type barKey struct {
+	full, width int
+	empty       rune
+}
+
+// barTexts are the bar texts built since the last resize. Guarded by mu.
+var barTexts = map[barKey]string{}
+
+// barText is full characters of █ followed by empty up to width characters
+// in all. The caller must hold mu.
+func barText(full, width int, empty rune) string {
+	k := barKey{full, width, empty}
+	s, ok := barTexts[k]
+	if !ok {
+		s = strings.Repeat("█", full) + strings.Repeat(string(empty), width-full)
+		barTexts[k] = s
+	}
+	return s
+}
+
+// blanks is n characters of empty. The caller must hold mu.
+func blanks(n int, empty rune) string {
+	return barText(0, n, empty)
+}
+
+// dropBarCaches forgets the bar texts and cells, whose widths a resize has
+// changed. The caller must hold mu.
+func dropBarCaches() {
+	clear(barTexts)
+	clear(barCells)
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+// TestBarText tests the bar texts and that a resize drops them
+func TestBarText(t *testing.T) {
+	defer dropBarCaches()
+	tests := []struct {
+		full, width int
+		empty       rune
+		want        string
+	}{
+		{3, 5, ' ', "███  "},
+		{0, 4, '░', "░░░░"},
+		{2, 2, ' ', "██"},
+		{0, 0, ' ', ""},
+	}
+	for _, tt := range tests {
+		if got := barText(tt.full, tt.width, tt.empty); got != tt.want {
+			t.Errorf("barText(%d, %d, %q) = %q, want %q", tt.full, tt.width, tt.empty, got, tt.want)
+		}
+	}
+	if allocs := testing.AllocsPerRun(100, func() { barText(3, 5, ' ') }); allocs != 0 {
+		t.Errorf("%v allocations per cached bar text, want 0", allocs)
+	}
+
+	barCell(0.5, 10)
+	dropBarCaches()
+	if len(barTexts) != 0 || len(barCells) != 0 {
+		t.Errorf("after a resize: %d bar texts, %d bar cells", len(barTexts), len(barCells))
+	}
+	if got := selectedBarCell(0.5, 4).text(); got != "██░░" {
+		t.Errorf("selected half bar = %q", got)
+	}
+}
@@ -0,0 +1,149 @@
+// bounce.go
+// Session bounce: B saves every loop's audio and a JSON manifest of names,
+// lengths, tempo and levels into a new dated folder in --audio-dir.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const bounceManifestFile = "manifest.json"
+
+// bounceManifest describes a bounced session folder.
+type bounceManifest struct {
+	Bounced time.Time    `json:"bounced"`
+	Song    string       `json:"song,omitempty"`
+	Tempo   float32      `json:"tempo,omitempty"`
+	Loops   []bounceLoop `json:"loops"`
+}
+
+// bounceLoop is one loop of a bounce. File is relative to the folder, and
+// empty when the loop was empty or its save failed.
+type bounceLoop struct {
+	Loop     int                `json:"loop"`
+	Name     string             `json:"name"`
+	File     string             `json:"file,omitempty"`
+	Length   float32            `json:"length"`
+	State    string             `json:"state,omitempty"`
+	Level    float32            `json:"level"`
+	Controls map[string]float32 `json:"controls,omitempty"`
+}
+
+// captureBounce describes loops for the manifest, named after the current
+// song's loops where it names them, or else the config file's loop names.
+// The caller holds mu.
+func captureBounce(loops []*LoopState, now time.Time) bounceManifest {
+	m := bounceManifest{Bounced: now, Tempo: globals["tempo"]}
+	var names []songLoop
+	if currentSong >= 0 && currentSong < len(songs.Songs) {
+		m.Song = songs.Songs[currentSong].Name
+		names = songs.Songs[currentSong].Loops
+	}
+	for i, ls := range loops {
+		l := bounceLoop{Loop: i + 1, Name: fmt.Sprintf("Loop %d", i+1), Level: ls.Wet}
+		if i < len(names) && names[i].Name != "" {
+			l.Name = names[i].Name
+		} else if name := loopNames[i]; name != "" {
+			l.Name = name
+		}
+		if ls.haveState {
+			l.State = ls.State.String()
+		}
+		l.Length = ls.controls["loop_len"]
+		for _, c := range sceneControls {
+			if v, ok := ls.controls[c]; ok {
+				if l.Controls == nil {
+					l.Controls = make(map[string]float32)
+				}
+				l.Controls[c] = v
+			}
+		}
+		m.Loops = append(m.Loops, l)
+	}
+	return m
+}
+
+// bounceSession asks the engine to save every loop that holds audio into a
+// new session-<date>-<time> folder in --audio-dir, and writes the manifest
+// there once the files are written, then calls done with the folder.
+func bounceSession(done func(dir string)) {
+	if !engineSharesFiles("bouncing loops") {
+		return
+	}
+	mu.Lock()
+	now := time.Now()
+	m := captureBounce(currentLoops(), now)
+	mu.Unlock()
+	if len(m.Loops) == 0 {
+		tuiLog.Warn("nothing to bounce")
+		return
+	}
+
+	root, err := filepath.Abs(audioDir)
+	if err != nil {
+		tuiLog.Warn("session not bounced", "err", err)
+		notify(slog.LevelWarn, trf("Session not bounced: %v", err))
+		return
+	}
+	base := filepath.Join(root, "session-"+now.Format("2006-01-02-150405"))
+	dir := base
+	for n := 2; ; n++ {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			break
+		}
+		dir = fmt.Sprintf("%s-%d", base, n)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		tuiLog.Warn("session not bounced", "err", err)
+		notify(slog.LevelWarn, trf("Session not bounced: %v", err))
+		return
+	}
+
+	tuiLog.Info("bouncing session", "dir", dir)
+	var wg sync.WaitGroup
+	for i := range m.Loops {
+		l := &m.Loops[i]
+		if l.Length <= 0 {
+			continue
+		}
+		l.File = fmt.Sprintf("loop%d.wav", l.Loop)
+		file := filepath.Join(dir, l.File)
+		sl.SaveLoop(i, file)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := waitForFile(file, copyFileTimeout, copyFilePoll); err != nil {
+				tuiLog.Warn("loop not bounced", "loop", l.Loop, "err", err)
+				l.File = ""
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		saved := 0
+		for _, l := range m.Loops {
+			if l.File != "" {
+				saved++
+			}
+		}
+		data, err := json.MarshalIndent(m, "", "  ")
+		if err == nil {
+			err = os.WriteFile(filepath.Join(dir, bounceManifestFile), append(data, '\n'), 0o644)
+		}
+		if err != nil {
+			tuiLog.Warn("bounce manifest not written", "dir", dir, "err", err)
+			notify(slog.LevelWarn, trf("Session not bounced: %v", err))
+			return
+		}
+		tuiLog.Info("session bounced", "dir", dir, "loops", saved)
+		notify(slog.LevelInfo, trf("Session bounced to %s (%d loops)", filepath.Base(dir), saved))
+		done(dir)
+	}()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCaptureBounce tests naming loops after the current song
+func TestCaptureBounce(t *testing.T) {
+	defer func(s setlist, cur int) { songs, currentSong = s, cur }(songs, currentSong)
+	songs = setlist{Songs: []song{{Name: "Intro", Loops: []songLoop{{Name: "Drums"}, {}}}}}
+	currentSong = 0
+	loops := []*LoopState{
+		{Wet: 0.5, controls: map[string]float32{"loop_len": 4, "feedback": 1}},
+		{Wet: 1, controls: map[string]float32{}},
+		{controls: map[string]float32{}},
+	}
+	m := captureBounce(loops, time.Now())
+	if m.Song != "Intro" || len(m.Loops) != 3 {
+		t.Fatalf("manifest %+v, want 3 loops of Intro", m)
+	}
+	for i, want := range []string{"Drums", "Loop 2", "Loop 3"} {
+		if m.Loops[i].Name != want {
+			t.Errorf("loop %d named %q, want %q", i+1, m.Loops[i].Name, want)
+		}
+	}
+	if l := m.Loops[0]; l.Length != 4 || l.Level != 0.5 || l.Controls["feedback"] != 1 || len(l.Controls) != 1 {
+		t.Errorf("loop 1 captured as %+v", l)
+	}
+	if m.Loops[1].Controls != nil {
+		t.Errorf("loop 2 has controls %v, want none", m.Loops[1].Controls)
+	}
+}
+
+// TestBounceSession tests bouncing through the simulator: recorded loops
+// get files, empty ones are only listed
+func TestBounceSession(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 2)
+	sl = startClient(t, sim)
+	defer func(dir string) { sl, audioDir = nil, dir }(audioDir)
+	audioDir = t.TempDir()
+	eventually(t, "engine online", func() bool { return loopCount == 2 })
+	sim.Handle(hitMessage(1, "record"))
+	time.Sleep(50 * time.Millisecond)
+	sim.Handle(hitMessage(1, "record"))
+	eventually(t, "loop recorded", func() bool { return getLoopState(1).controls["loop_len"] > 0 })
+
+	bounced := make(chan string, 2)
+	for range 2 {
+		bounceSession(func(dir string) { bounced <- dir })
+	}
+	var dirs []string
+	for range 2 {
+		select {
+		case dir := <-bounced:
+			dirs = append(dirs, dir)
+		case <-time.After(5 * time.Second):
+			t.Fatal("session not bounced")
+		}
+	}
+	if dirs[0] == dirs[1] {
+		t.Errorf("both bounces went to %s", dirs[0])
+	}
+
+	dir := dirs[0]
+	if filepath.Dir(dir) != audioDir || !strings.HasPrefix(filepath.Base(dir), "session-") {
+		t.Errorf("bounced to %s, want session-… in %s", dir, audioDir)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, bounceManifestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m bounceManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Loops) != 2 {
+		t.Fatalf("manifest lists %d loops, want 2", len(m.Loops))
+	}
+	if l := m.Loops[0]; l.File != "" || l.Length != 0 {
+		t.Errorf("empty loop bounced as %+v", l)
+	}
+	if l := m.Loops[1]; l.File != "loop2.wav" || l.Length <= 0 || l.Name != "Loop 2" {
+		t.Errorf("recorded loop bounced as %+v", l)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "loop2.wav")); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "loop1.wav")); err == nil {
+		t.Error("empty loop saved")
+	}
+}
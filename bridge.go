@@ -0,0 +1,20 @@
+// bridge.go
+// Headless bridge mode for running as a service.
+
+package main
+
+import "context"
+
+// runBridge serves the REST API until the services stop, on SIGINT or
+// SIGTERM or because one failed, and returns the error it failed with.
+// The engine connection must already be started; loop state changes are
+// logged by handleOSC.
+func runBridge(addr string) error {
+	goService("REST API", func(ctx context.Context) error { return serveHTTP(ctx, addr) })
+	logger.Info("bridge running", "engine", engineAddr())
+	<-servicesDone()
+	logger.Info("bridge stopping")
+	err := stopServices()
+	engineProc.stop()
+	return err
+}
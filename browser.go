@@ -0,0 +1,386 @@
+// browser.go
+// Audio file browser: pick a WAV, AIFF or FLAC file, with its format read
+// from the header, and load it into the selected loop with load_loop.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// audioDir is where the file browser opens first (--audio-dir).
+var audioDir = ""
+
+// audioExts are the files the browser lists, all of which SooperLooper
+// loads through libsndfile.
+var audioExts = []string{".wav", ".aif", ".aiff", ".aifc", ".flac"}
+
+// browserEntry is a directory or audio file in the browser.
+type browserEntry struct {
+	Name string
+	Dir  bool
+}
+
+// listAudioDir lists dir's subdirectories, then its audio files, each by
+// name. Hidden entries are left out.
+func listAudioDir(dir string) ([]browserEntry, error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var dirs, files []browserEntry
+	for _, de := range des {
+		name := de.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		isDir := de.IsDir()
+		if de.Type()&os.ModeSymlink != 0 {
+			if fi, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				isDir = fi.IsDir()
+			}
+		}
+		switch {
+		case isDir:
+			dirs = append(dirs, browserEntry{name, true})
+		case slices.Contains(audioExts, strings.ToLower(filepath.Ext(name))):
+			files = append(files, browserEntry{name, false})
+		}
+	}
+	return append(dirs, files...), nil
+}
+
+// audioInfo is what an audio file's header says about its audio.
+type audioInfo struct {
+	Format   string // WAV, AIFF or FLAC
+	Channels int
+	Rate     int
+	Bits     int
+	Float    bool
+	Frames   int64
+}
+
+// Duration is the audio's length.
+func (a audioInfo) Duration() time.Duration {
+	if a.Rate == 0 {
+		return 0
+	}
+	return time.Duration(float64(a.Frames) / float64(a.Rate) * float64(time.Second))
+}
+
+var errNotAudio = errors.New("not a WAV, AIFF or FLAC file")
+
+// readAudioInfo reads the header of the audio file at path.
+func readAudioInfo(path string) (audioInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return audioInfo{}, err
+	}
+	defer f.Close()
+	var magic [12]byte
+	if _, err := io.ReadFull(f, magic[:]); err != nil {
+		return audioInfo{}, errNotAudio
+	}
+	switch {
+	case string(magic[:4]) == "RIFF" && string(magic[8:]) == "WAVE":
+		return wavInfo(f)
+	case string(magic[:4]) == "FORM" && (string(magic[8:]) == "AIFF" || string(magic[8:]) == "AIFC"):
+		return aiffInfo(f, string(magic[8:]) == "AIFC")
+	case string(magic[:4]) == "fLaC":
+		return flacInfo(io.MultiReader(bytes.NewReader(magic[4:]), f))
+	}
+	return audioInfo{}, errNotAudio
+}
+
+// riffChunks calls chunk with the ID and size of each chunk from r's
+// position on, until chunk returns true or the chunks end. The chunk's
+// data is next in r; what chunk leaves unread is skipped.
+func riffChunks(r io.ReadSeeker, order binary.ByteOrder, chunk func(id string, size int64) (done bool)) {
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return
+		}
+		size := int64(order.Uint32(hdr[4:]))
+		start, err := r.Seek(0, io.SeekCurrent)
+		if err != nil || chunk(string(hdr[:4]), size) {
+			return
+		}
+		// Chunks are padded to an even length.
+		if _, err := r.Seek(start+size+size%2, io.SeekStart); err != nil {
+			return
+		}
+	}
+}
+
+// wavInfo reads the fmt and data chunks after the RIFF header.
+func wavInfo(r io.ReadSeeker) (audioInfo, error) {
+	a := audioInfo{Format: "WAV"}
+	var dataSize int64 = -1
+	riffChunks(r, binary.LittleEndian, func(id string, size int64) bool {
+		switch id {
+		case "fmt ":
+			var f struct {
+				Format, Channels uint16
+				Rate             uint32
+				_                uint32
+				_                uint16
+				Bits             uint16
+				_                uint16
+				_                uint16
+				_                uint32
+				SubFormat        uint16
+			}
+			data := make([]byte, binary.Size(f))
+			if _, err := io.ReadFull(r, data[:min(size, int64(len(data)))]); size < 16 || err != nil {
+				return true
+			}
+			binary.Read(bytes.NewReader(data), binary.LittleEndian, &f)
+			format := f.Format
+			if format == 0xfffe { // WAVE_FORMAT_EXTENSIBLE
+				format = f.SubFormat
+			}
+			a.Channels, a.Rate, a.Bits, a.Float = int(f.Channels), int(f.Rate), int(f.Bits), format == 3
+		case "data":
+			dataSize = size
+		}
+		return a.Channels > 0 && dataSize >= 0
+	})
+	if a.Channels == 0 || a.Bits == 0 || dataSize < 0 {
+		return audioInfo{}, errors.New("WAV without fmt and data chunks")
+	}
+	a.Frames = dataSize / int64(a.Channels*((a.Bits+7)/8))
+	return a, nil
+}
+
+// aiffInfo reads the COMM chunk after the FORM header.
+func aiffInfo(r io.ReadSeeker, aifc bool) (audioInfo, error) {
+	a := audioInfo{Format: "AIFF"}
+	riffChunks(r, binary.BigEndian, func(id string, size int64) bool {
+		if id != "COMM" {
+			return false
+		}
+		var c struct {
+			Channels int16
+			Frames   uint32
+			Bits     int16
+			Rate     [10]byte
+			Kind     [4]byte
+		}
+		data := make([]byte, binary.Size(c))
+		if _, err := io.ReadFull(r, data[:min(size, int64(len(data)))]); err != nil {
+			return true
+		}
+		binary.Read(bytes.NewReader(data), binary.BigEndian, &c)
+		a.Channels, a.Frames, a.Bits = int(c.Channels), int64(c.Frames), int(c.Bits)
+		a.Rate = int(math.Round(extendedFloat(c.Rate)))
+		kind := strings.ToLower(string(c.Kind[:]))
+		a.Float = aifc && (kind == "fl32" || kind == "fl64")
+		return true
+	})
+	if a.Channels == 0 {
+		return audioInfo{}, errors.New("AIFF without a COMM chunk")
+	}
+	return a, nil
+}
+
+// extendedFloat decodes an 80-bit IEEE 754 extended float, as AIFF gives
+// its sample rate.
+func extendedFloat(b [10]byte) float64 {
+	exp := int(binary.BigEndian.Uint16(b[:2]) & 0x7fff)
+	mant := binary.BigEndian.Uint64(b[2:])
+	if exp == 0 && mant == 0 {
+		return 0
+	}
+	v := math.Ldexp(float64(mant), exp-16383-63)
+	if b[0]&0x80 != 0 {
+		v = -v
+	}
+	return v
+}
+
+// flacInfo reads the STREAMINFO block after the fLaC marker.
+func flacInfo(r io.Reader) (audioInfo, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil || hdr[0]&0x7f != 0 {
+		return audioInfo{}, errors.New("FLAC without STREAMINFO")
+	}
+	var si [34]byte
+	if _, err := io.ReadFull(r, si[:]); err != nil {
+		return audioInfo{}, err
+	}
+	// From byte 10: 20 bits rate, 3 bits channels-1, 5 bits bits-1 and 36
+	// bits of frames.
+	v := binary.BigEndian.Uint64(si[10:18])
+	return audioInfo{
+		Format:   "FLAC",
+		Rate:     int(v >> 44),
+		Channels: int(v>>41&7) + 1,
+		Bits:     int(v>>36&31) + 1,
+		Frames:   int64(v & (1<<36 - 1)),
+	}, nil
+}
+
+// loadLoopFile asks the engine to load the audio file at path into loop i.
+// The engine reads the file itself, so it must run on this machine.
+func loadLoopFile(i int, path string) {
+	if !engineSharesFiles("loading files") {
+		return
+	}
+	tuiLog.Info("loading loop", "loop", i+1, "file", path)
+	sl.LoadLoop(i, path)
+}
+
+// fileBrowser is the file browser: the current directory's entries and
+// what the selected file's header says.
+type fileBrowser struct {
+	root *tview.Flex
+	list *tview.List
+	info *tview.TextView
+
+	dir     string
+	loop    int
+	entries []browserEntry
+}
+
+// newFileBrowser builds the browser. Enter on a file calls load with it,
+// and Esc calls close.
+func newFileBrowser(load func(loop int, path string), close func()) *fileBrowser {
+	b := &fileBrowser{
+		list: tview.NewList().ShowSecondaryText(false).SetHighlightFullLine(true),
+		info: tview.NewTextView().SetDynamicColors(true),
+	}
+	b.info.SetBorder(true)
+	b.root = tview.NewFlex().
+		AddItem(b.list, 0, 1, true).
+		AddItem(b.info, 0, 1, false)
+	b.root.SetBorder(true)
+
+	b.list.SetChangedFunc(func(i int, _, _ string, _ rune) { b.preview(i) })
+	b.list.SetSelectedFunc(func(i int, _, _ string, _ rune) {
+		if i >= len(b.entries) {
+			return
+		}
+		if e := b.entries[i]; e.Dir {
+			b.show(filepath.Join(b.dir, e.Name), "")
+		} else {
+			load(b.loop, filepath.Join(b.dir, e.Name))
+		}
+	})
+	b.list.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Key() {
+		case tcell.KeyEscape:
+			close()
+		case tcell.KeyBackspace, tcell.KeyBackspace2, tcell.KeyLeft:
+			b.show(filepath.Dir(b.dir), filepath.Base(b.dir))
+		case tcell.KeyRight:
+			return tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)
+		default:
+			return ev
+		}
+		return nil
+	})
+	return b
+}
+
+// open shows the browser for loop i, in the directory last browsed.
+func (b *fileBrowser) open(i int) {
+	dir := b.dir
+	if dir == "" {
+		dir = audioDir
+	}
+	b.openAt(i, dir, "")
+}
+
+// openAt shows the browser for loop i in dir, with the entry named sel
+// selected.
+func (b *fileBrowser) openAt(i int, dir, sel string) {
+	b.loop = i
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	b.root.SetTitle(" " + trf("Load into loop %d (Enter: load, Backspace: up, Esc: close)", i+1) + " ")
+	b.show(dir, sel)
+}
+
+// show lists dir, selecting the entry named sel.
+func (b *fileBrowser) show(dir, sel string) {
+	entries, err := listAudioDir(dir)
+	if err != nil {
+		tuiLog.Warn("directory not listed", "dir", dir, "err", err)
+		if b.dir != "" {
+			return
+		}
+	}
+	b.dir, b.entries = dir, entries
+	b.list.Clear()
+	cur := 0
+	for i, e := range entries {
+		label := tview.Escape(e.Name)
+		if e.Dir {
+			label = "[blue]" + label + "/[-]"
+		}
+		b.list.AddItem(label, "", 0, nil)
+		if e.Name == sel {
+			cur = i
+		}
+	}
+	b.info.SetTitle(" " + tview.Escape(dir) + " ")
+	b.list.SetCurrentItem(cur)
+	b.preview(cur)
+}
+
+// preview shows entry i's header.
+func (b *fileBrowser) preview(i int) {
+	if i >= len(b.entries) {
+		b.info.SetText(tr("No audio files here."))
+		return
+	}
+	e := b.entries[i]
+	if e.Dir {
+		b.info.SetText("")
+		return
+	}
+	path := filepath.Join(b.dir, e.Name)
+	a, err := readAudioInfo(path)
+	if err != nil {
+		b.info.SetText("[red]" + tview.Escape(err.Error()) + "[-]")
+		return
+	}
+	bits := fmt.Sprint(a.Bits)
+	if a.Float {
+		bits += " " + tr("float")
+	}
+	var s strings.Builder
+	fmt.Fprintf(&s, "%s\n\n", tview.Escape(e.Name))
+	for _, row := range [][2]string{
+		{tr("Format"), a.Format},
+		{tr("Channels"), fmt.Sprint(a.Channels)},
+		{tr("Sample rate"), fmt.Sprintf("%d Hz", a.Rate)},
+		{tr("Bits"), bits},
+		{tr("Length"), fmt.Sprintf("%.2f s", a.Duration().Seconds())},
+	} {
+		fmt.Fprintf(&s, "%-12s %s\n", row[0], row[1])
+	}
+	s.WriteString("\n")
+	_, _, w, _ := b.info.GetInnerRect()
+	if peaks, err := wavPeaks(path, max(w, 20)*2); err != nil {
+		s.WriteString("[gray]" + tview.Escape(err.Error()) + "[-]\n")
+	} else {
+		s.WriteString("[green]" + strings.Join(waveformLines(peaks, waveformRows), "\n") + "[-]\n")
+	}
+	b.info.SetText(s.String())
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// chunk returns a RIFF or IFF chunk, padded to an even length.
+func chunk(order binary.AppendByteOrder, id string, data []byte) []byte {
+	b := append([]byte(id), order.AppendUint32(nil, uint32(len(data)))...)
+	b = append(b, data...)
+	if len(data)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// TestReadAudioInfo tests the WAV, AIFF and FLAC headers
+func TestReadAudioInfo(t *testing.T) {
+	le, be := binary.LittleEndian, binary.BigEndian
+	dir := t.TempDir()
+
+	// 24-bit stereo WAV at 48 kHz, a second long, with a chunk before fmt.
+	var fmtChunk []byte
+	fmtChunk = le.AppendUint16(fmtChunk, 1)
+	fmtChunk = le.AppendUint16(fmtChunk, 2)
+	fmtChunk = le.AppendUint32(fmtChunk, 48000)
+	fmtChunk = le.AppendUint32(fmtChunk, 48000*6)
+	fmtChunk = le.AppendUint16(fmtChunk, 6)
+	fmtChunk = le.AppendUint16(fmtChunk, 24)
+	wav := append([]byte("RIFF\x00\x00\x00\x00WAVE"), chunk(le, "LIST", []byte("odd"))...)
+	wav = append(wav, chunk(le, "fmt ", fmtChunk)...)
+	wav = append(wav, chunk(le, "data", make([]byte, 48000*6))...)
+
+	// Mono 16-bit AIFF at 44.1 kHz, 22050 frames. 44100 as an 80-bit
+	// extended float is 0x400e ac44 0000 0000 0000.
+	var comm []byte
+	comm = be.AppendUint16(comm, 1)
+	comm = be.AppendUint32(comm, 22050)
+	comm = be.AppendUint16(comm, 16)
+	comm = append(comm, 0x40, 0x0e, 0xac, 0x44, 0, 0, 0, 0, 0, 0)
+	aiff := append([]byte("FORM\x00\x00\x00\x00AIFF"), chunk(be, "COMM", comm)...)
+
+	// FLAC, 96 kHz, 2 channels, 24 bits, 96000 frames.
+	streamInfo := make([]byte, 34)
+	be.PutUint64(streamInfo[10:], 96000<<44|1<<41|23<<36|96000)
+	flac := append([]byte("fLaC\x00\x00\x00\x22"), streamInfo...)
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want audioInfo
+	}{
+		{"a.wav", wav, audioInfo{Format: "WAV", Channels: 2, Rate: 48000, Bits: 24, Frames: 48000}},
+		{"a.aiff", aiff, audioInfo{Format: "AIFF", Channels: 1, Rate: 44100, Bits: 16, Frames: 22050}},
+		{"a.flac", flac, audioInfo{Format: "FLAC", Channels: 2, Rate: 96000, Bits: 24, Frames: 96000}},
+	} {
+		path := filepath.Join(dir, tc.name)
+		if err := os.WriteFile(path, tc.data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readAudioInfo(path)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: %+v, %v; want %+v", tc.name, got, err, tc.want)
+		}
+	}
+	if d := (audioInfo{Rate: 44100, Frames: 22050}).Duration().Seconds(); d != 0.5 {
+		t.Errorf("duration %g s, want 0.5", d)
+	}
+
+	for name, data := range map[string][]byte{
+		"text.wav":  []byte("not audio at all"),
+		"nofmt.wav": append([]byte("RIFF\x00\x00\x00\x00WAVE"), chunk(le, "data", nil)...),
+		"short.wav": []byte("RIFF"),
+	} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, data, 0o644)
+		if a, err := readAudioInfo(path); err == nil {
+			t.Errorf("%s: %+v, want an error", name, a)
+		}
+	}
+}
+
+// TestListAudioDir tests that directories come first, then audio files,
+// with other and hidden files left out
+func TestListAudioDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{"takes", ".cache"} {
+		os.Mkdir(filepath.Join(dir, d), 0o755)
+	}
+	for _, f := range []string{"b.WAV", "a.flac", "notes.txt", ".hidden.wav", "c.aif"} {
+		os.WriteFile(filepath.Join(dir, f), nil, 0o644)
+	}
+	got, err := listAudioDir(dir)
+	want := []browserEntry{{"takes", true}, {"a.flac", false}, {"b.WAV", false}, {"c.aif", false}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("listAudioDir = %v, %v; want %v", got, err, want)
+	}
+	if _, err := listAudioDir(filepath.Join(dir, "missing")); err == nil {
+		t.Error("listing a missing directory succeeded")
+	}
+}
@@ -0,0 +1,116 @@
+// buttons.go
+// Which loop states light the Rec, Dub and Mute buttons, built in or from
+// the config file.
+
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"jaudio/internal/slstate"
+)
+
+// ButtonState says when a button shows ON in green (OnStates), and when it
+// shows ON or OFF in yellow because the loop is about to turn it on or off.
+type ButtonState struct {
+	OnStates   []slstate.State
+	PendingOn  []stateRule
+	PendingOff []stateRule
+}
+
+// stateRule matches a loop in one of From whose next state is one of To.
+// Unknown in To stands for no next state.
+type stateRule struct {
+	From []slstate.State `yaml:"from"`
+	To   []slstate.State `yaml:"to"`
+}
+
+func matchRules(rules []stateRule, state, next slstate.State) bool {
+	for _, r := range rules {
+		if state.In(r.From...) && next.In(r.To...) {
+			return true
+		}
+	}
+	return false
+}
+
+// buttonDefs are the buttons by table column name, for SooperLooper 1.7.
+var buttonDefs = map[string]ButtonState{
+	"RECORD": {
+		OnStates:   []slstate.State{slstate.Record, slstate.WaitStop},
+		PendingOn:  []stateRule{{From: []slstate.State{slstate.WaitStart}, To: []slstate.State{slstate.Play, slstate.Unknown}}},
+		PendingOff: []stateRule{{From: []slstate.State{slstate.Record, slstate.WaitStop}, To: []slstate.State{slstate.Play}}},
+	},
+	"OVERDUB": {
+		OnStates:   []slstate.State{slstate.Overdub},
+		PendingOn:  []stateRule{{From: []slstate.State{slstate.Play}, To: []slstate.State{slstate.Overdub}}},
+		PendingOff: []stateRule{{From: []slstate.State{slstate.Overdub}, To: []slstate.State{slstate.Play}}},
+	},
+	"MUTE": {
+		OnStates:   []slstate.State{slstate.Mute, slstate.OffMuted},
+		PendingOn:  []stateRule{{From: []slstate.State{slstate.Play}, To: []slstate.State{slstate.Mute}}},
+		PendingOff: []stateRule{{From: []slstate.State{slstate.Mute, slstate.OffMuted}, To: []slstate.State{slstate.Play}}},
+	},
+}
+
+// buttonConfig is a button in the config file's buttons section, for
+// engines whose state codes differ:
+//
+//	buttons:
+//	  record:
+//	    on: [Record, WaitStop]
+//	    pending_on: [{from: [WaitStart], to: [Play, Unknown]}]
+//	    pending_off: [{from: [Record, 3], to: [Play]}]
+//
+// States are names or codes. Lists left out keep the built-in ones.
+type buttonConfig struct {
+	On         []slstate.State `yaml:"on"`
+	PendingOn  []stateRule     `yaml:"pending_on"`
+	PendingOff []stateRule     `yaml:"pending_off"`
+}
+
+func (c buttonConfig) validate() error {
+	check := func(what string, rules []stateRule) error {
+		for i, r := range rules {
+			if len(r.From) == 0 || len(r.To) == 0 {
+				return fmt.Errorf("%s[%d]: from and to are required", what, i)
+			}
+		}
+		return nil
+	}
+	if err := check("pending_on", c.PendingOn); err != nil {
+		return err
+	}
+	return check("pending_off", c.PendingOff)
+}
+
+// buttonKey returns the buttonDefs key for a config file button name.
+func buttonKey(name string) (string, error) {
+	key := strings.ToUpper(name)
+	if _, ok := buttonDefs[key]; !ok {
+		return "", fmt.Errorf("unknown button %q, want record, overdub or mute", name)
+	}
+	return key, nil
+}
+
+// applyButtons replaces the built-in button lists with those in the config
+// file, which has been validated.
+func applyButtons(cfg map[string]buttonConfig) {
+	for _, name := range slices.Sorted(maps.Keys(cfg)) {
+		key, _ := buttonKey(name)
+		def, c := buttonDefs[key], cfg[name]
+		if c.On != nil {
+			def.OnStates = c.On
+		}
+		if c.PendingOn != nil {
+			def.PendingOn = c.PendingOn
+		}
+		if c.PendingOff != nil {
+			def.PendingOff = c.PendingOff
+		}
+		buttonDefs[key] = def
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	"jaudio/internal/slstate"
+)
+
+// TestButtonStateCell tests the built-in button rules
+func TestButtonStateCell(t *testing.T) {
+	tests := []struct {
+		button      string
+		state, next slstate.State
+		want        string
+	}{
+		{"RECORD", slstate.WaitStart, slstate.Unknown, "yellow ON"},
+		{"RECORD", slstate.Record, slstate.Unknown, "green ON"},
+		{"RECORD", slstate.WaitStop, slstate.Play, "yellow OFF"},
+		{"OVERDUB", slstate.Play, slstate.Overdub, "yellow ON"},
+		{"OVERDUB", slstate.Play, slstate.Unknown, "red OFF"},
+		{"MUTE", slstate.OffMuted, slstate.Unknown, "green ON"},
+		{"MUTE", slstate.Mute, slstate.Play, "yellow OFF"},
+	}
+	for _, tt := range tests {
+		c := buttonStateCell(tt.state, tt.next, buttonDefs[tt.button])
+		if got := c.Spans[0].Color.Name() + " " + strings.TrimSpace(c.text()); got != tt.want {
+			t.Errorf("%s in %v→%v = %q, want %q", tt.button, tt.state, tt.next, got, tt.want)
+		}
+	}
+}
+
+// TestButtonConfig tests button overrides from the config file
+func TestButtonConfig(t *testing.T) {
+	defer func(d map[string]ButtonState) { buttonDefs = d }(maps.Clone(buttonDefs))
+
+	cfg, err := parseConfig([]byte(`
+buttons:
+  mute: {on: [Mute, 21]}
+  Record: {pending_off: [{from: [Record, 3], to: [Play, Pause]}]}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyButtons(cfg.Buttons)
+	if got := buttonStateCell(21, slstate.Unknown, buttonDefs["MUTE"]).text(); got != " ON " {
+		t.Errorf("mute in state 21 = %q, want ON", got)
+	}
+	if got := buttonStateCell(slstate.OffMuted, slstate.Unknown, buttonDefs["MUTE"]).text(); got != " OFF " {
+		t.Errorf("mute in OffMuted = %q, want OFF", got)
+	}
+	if r := buttonDefs["MUTE"].PendingOn; len(r) != 1 || r[0].To[0] != slstate.Mute {
+		t.Errorf("mute pending_on = %v, want the built-in rule", r)
+	}
+	if !matchRules(buttonDefs["RECORD"].PendingOff, slstate.WaitStop, slstate.Pause) {
+		t.Errorf("record pending_off did not take the configured rule")
+	}
+
+	for _, bad := range []string{
+		"buttons: {loop: {on: [Play]}}",
+		"buttons: {mute: {on: [Muted]}}",
+		"buttons: {mute: {pending_on: [{from: [Play]}]}}",
+	} {
+		if _, err := parseConfig([]byte(bad)); err == nil {
+			t.Errorf("parseConfig(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// cellcache.go
+// Cutting the render path's allocations: button and bar cells are built
+// once for each look and shared from then on, and the TUI's tview cells are
+// kept from frame to frame and updated in place, so a redraw no longer
+// allocates for every loop and column.
+
+package main
+
+import (
+	"math"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// buttonLook is how a button cell looks: its translated label and color.
+type buttonLook struct {
+	label string
+	color tcell.Color
+}
+
+// barLook is how a bar cell looks: its width, how much of it is full, and
+// its color.
+type barLook struct {
+	width, full int
+	color       tcell.Color
+}
+
+var (
+	// buttonCells and barCells are the cells built so far, by look, bar
+	// cells since the last resize. Guarded by mu, as renderTable is. Their
+	// spans are shared, so they are replaced, never changed in place.
+	buttonCells = map[buttonLook]cell{}
+	barCells    = map[barLook]cell{}
+)
+
+// sharedButtonCell is the button cell showing label in color.
+func sharedButtonCell(label string, color tcell.Color) cell {
+	look := buttonLook{label, color}
+	c, ok := buttonCells[look]
+	if !ok {
+		c = textCell(" "+label+" ", color)
+		buttonCells[look] = c
+	}
+	return c
+}
+
+// sharedBarCell is the bar cell width characters wide, filled to fill.
+func sharedBarCell(fill float32, width int) cell {
+	full := min(max(int(math.Ceil(float64(fill)*float64(width))), 0), width)
+	look := barLook{width, full, meterColor(fill)}
+	c, ok := barCells[look]
+	if !ok {
+		c = cell{Spans: []span{{Text: barText(full, width, ' '), Color: look.color}}, Align: tview.AlignLeft}
+		barCells[look] = c
+	}
+	return c
+}
+
+// setTableCell makes tc show c, as a new cell from c.tableCell would. It
+// sets the fields one by one, as replacing *tc would also zero where tview
+// last drew it, which clicks are matched against until the next draw.
+func (c cell) setTableCell(tc *tview.TableCell) {
+	tc.Reference, tc.Clicked = nil, nil
+	tc.Align, tc.MaxWidth, tc.Expansion = c.Align, c.MaxWidth, c.Expansion
+	tc.Color, tc.BackgroundColor, tc.Attributes = tcell.ColorDefault, tcell.ColorDefault, tcell.AttrNone
+	tc.SelectedStyle = tcell.StyleDefault
+	tc.Transparent, tc.NotSelectable = true, c.Header
+	if len(c.Spans) == 1 {
+		s := c.Spans[0]
+		tc.Text, tc.Style = s.Text, tcell.StyleDefault.Foreground(s.Color).Bold(s.Bold)
+	} else {
+		tc.Text = c.tagged()
+		tc.Style = tcell.StyleDefault.Foreground(tview.Styles.PrimaryTextColor).Background(tview.Styles.PrimitiveBackgroundColor)
+	}
+	if c.Background != tcell.ColorDefault {
+		tc.SetBackgroundColor(c.Background)
+	}
+}
+
+// cellGrid keeps the cells of a tview table from one frame to the next.
+type cellGrid struct {
+	table *tview.Table
+	cells [][]*tview.TableCell
+}
+
+// set shows rows in the table. Rows of the same shape as the last update
+// the cells in place; others replace them.
+func (g *cellGrid) set(rows [][]cell) {
+	if !g.sameShape(rows) {
+		g.table.Clear()
+		g.cells = make([][]*tview.TableCell, len(rows))
+		for r, row := range rows {
+			g.cells[r] = make([]*tview.TableCell, len(row))
+			for c, cl := range row {
+				g.cells[r][c] = cl.tableCell()
+				g.table.SetCell(r, c, g.cells[r][c])
+			}
+		}
+		return
+	}
+	for r, row := range rows {
+		for c, cl := range row {
+			cl.setTableCell(g.cells[r][c])
+		}
+	}
+}
+
+func (g *cellGrid) sameShape(rows [][]cell) bool {
+	if len(rows) != len(g.cells) {
+		return false
+	}
+	for r, row := range rows {
+		if len(row) != len(g.cells[r]) {
+			return false
+		}
+	}
+	return true
+}
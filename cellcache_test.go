@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"jaudio/internal/slstate"
+)
+
+// TestSharedCellsAllocs tests that button and bar cells seen before are
+// built without allocating
+func TestSharedCellsAllocs(t *testing.T) {
+	buttonStateCell(slstate.Record, slstate.Unknown, buttonDefs["RECORD"])
+	meterBarCell(0.3, 20)
+	allocs := testing.AllocsPerRun(100, func() {
+		buttonStateCell(slstate.Record, slstate.Unknown, buttonDefs["RECORD"])
+		meterBarCell(0.3, 20)
+	})
+	if allocs != 0 {
+		t.Errorf("%v allocations per button and bar cell, want 0", allocs)
+	}
+	if got := barCell(0.5, 10).text(); got != "█████     " {
+		t.Errorf("half bar = %q", got)
+	}
+	if got := barCell(0.5, 10).Spans[0].Color; got != meterColor(0.5) {
+		t.Errorf("half bar color = %v", got)
+	}
+}
+
+// TestSetTableCell tests that an updated tview cell is as a new one would be
+func TestSetTableCell(t *testing.T) {
+	c := cell{Spans: []span{{Text: "a["}, {Text: "b", Color: tcell.ColorRed}}, Align: tview.AlignRight, MaxWidth: 4, Expansion: 1, Header: true, Background: tcell.ColorBlue}
+	want := tview.NewTableCell(c.tagged()).SetAlign(tview.AlignRight).SetMaxWidth(4).SetExpansion(1).SetSelectable(false).SetBackgroundColor(tcell.ColorBlue)
+	tc := textCell("old", tcell.ColorGreen).tableCell()
+	c.setTableCell(tc)
+	if !reflect.DeepEqual(tc, want) {
+		t.Errorf("multi-span cell = %+v, want %+v", *tc, *want)
+	}
+
+	c = textCell("x", tcell.ColorYellow)
+	want = tview.NewTableCell("x").SetStyle(tcell.StyleDefault.Foreground(tcell.ColorYellow)).SetAlign(tview.AlignCenter)
+	c.setTableCell(tc)
+	if !reflect.DeepEqual(tc, want) {
+		t.Errorf("single-span cell = %+v, want %+v", *tc, *want)
+	}
+}
+
+// TestCellGrid tests that rows of the same shape update the table's cells
+// in place, keeping where they were drawn, and rows of another shape
+// replace them
+func TestCellGrid(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	table := tview.NewTable()
+	table.SetRect(0, 0, 20, 5)
+	g := &cellGrid{table: table}
+	g.set([][]cell{{textCell("a", 0), textCell("b", 0)}, {textCell("c", 0), textCell("d", 0)}})
+	first := table.GetCell(1, 1)
+	table.Draw(screen)
+	x, y, width := first.GetLastPosition()
+
+	g.set([][]cell{{textCell("a", 0), textCell("b", 0)}, {textCell("c", 0), textCell("e", 0)}})
+	if table.GetCell(1, 1) != first || first.Text != "e" {
+		t.Errorf("same shape: cell replaced or not updated, text %q", table.GetCell(1, 1).Text)
+	}
+	if x2, y2, width2 := first.GetLastPosition(); width == 0 || x2 != x || y2 != y || width2 != width {
+		t.Errorf("updated cell at %d,%d width %d, drawn at %d,%d width %d", x2, y2, width2, x, y, width)
+	}
+
+	g.set([][]cell{{textCell("a", 0), textCell("b", 0)}})
+	if table.GetRowCount() != 1 || table.GetCell(0, 1).Text != "b" {
+		t.Errorf("fewer rows: %d rows, cell %q", table.GetRowCount(), table.GetCell(0, 1).Text)
+	}
+}
@@ -0,0 +1,88 @@
+// chords.go
+// Vim-style key chords: loop numbers typed before a command, e.g. 3r or 2,5m.
+
+package main
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// chordTimeout is how long a chord waits for its next key. A lone page
+// number switches page once it runs out, as vim's timeoutlen does.
+const chordTimeout = 600 * time.Millisecond
+
+// chordVerbs are the engine commands a chord can end with. The loop keys
+// (d, y, v, w, =) end a chord too.
+var chordVerbs = map[rune]string{
+	'r': "record",
+	'o': "overdub",
+	'x': "multiply",
+	'i': "insert",
+	'R': "replace",
+	'S': "substitute",
+	'm': "mute",
+	'p': "pause",
+	't': "trigger",
+	'O': "oneshot",
+	'u': "undo",
+	'U': "redo",
+	'~': "reverse",
+	's': "solo",
+}
+
+type chordStep int
+
+const (
+	chordMore  chordStep = iota // the key continues the chord
+	chordDone                   // the key ended the chord with a verb
+	chordOther                  // the key is not part of a chord
+)
+
+// chordInput collects a chord's loop numbers, separated by commas, until
+// a verb ends it. isVerb reports whether a key is a verb.
+type chordInput struct {
+	isVerb func(rune) bool
+	typed  []rune
+}
+
+func (c *chordInput) pending() bool { return len(c.typed) > 0 }
+
+// text is the chord typed so far, for the status bar.
+func (c *chordInput) text() string { return string(c.typed) }
+
+// add feeds the next key to the chord. When it is a verb ending the
+// chord, add returns the loops (0-based, in the order typed, each once)
+// and the verb. Any other key leaves the chord to the caller, who should
+// flush it.
+func (c *chordInput) add(r rune) (step chordStep, loops []int, verb rune) {
+	last := rune(0)
+	if c.pending() {
+		last = c.typed[len(c.typed)-1]
+	}
+	switch {
+	case r >= '1' && r <= '9', r == '0' && last >= '0' && last <= '9':
+		c.typed = append(c.typed, r)
+		return chordMore, nil, 0
+	case r == ',' && last >= '0' && last <= '9':
+		c.typed = append(c.typed, r)
+		return chordMore, nil, 0
+	case c.pending() && last != ',' && c.isVerb(r):
+		for _, n := range strings.Split(c.flush(), ",") {
+			if i, _ := strconv.Atoi(n); !slices.Contains(loops, i-1) {
+				loops = append(loops, i-1)
+			}
+		}
+		return chordDone, loops, r
+	}
+	return chordOther, nil, 0
+}
+
+// flush ends the chord, returning what was typed.
+func (c *chordInput) flush() string {
+	s := string(c.typed)
+	c.typed = c.typed[:0]
+	return s
+}
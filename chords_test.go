@@ -0,0 +1,52 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func feedChord(c *chordInput, keys string) (step chordStep, loops []int, verb rune) {
+	for _, r := range keys {
+		step, loops, verb = c.add(r)
+	}
+	return step, loops, verb
+}
+
+// TestChordInput tests loop lists, verbs and keys that break a chord
+func TestChordInput(t *testing.T) {
+	isVerb := func(r rune) bool { _, ok := chordVerbs[r]; return ok || r == 'd' }
+	for _, tt := range []struct {
+		keys  string
+		step  chordStep
+		loops []int
+		verb  rune
+	}{
+		{"3r", chordDone, []int{2}, 'r'},
+		{"2,5m", chordDone, []int{1, 4}, 'm'},
+		{"12u", chordDone, []int{11}, 'u'},
+		{"2,2,3d", chordDone, []int{1, 2}, 'd'},
+		{"2,5", chordMore, nil, 0},
+		{"2,m", chordOther, nil, 0},
+		{"0", chordOther, nil, 0},
+		{"r", chordOther, nil, 0},
+		{"3f", chordOther, nil, 0},
+	} {
+		c := chordInput{isVerb: isVerb}
+		step, loops, verb := feedChord(&c, tt.keys)
+		if step != tt.step || !slices.Equal(loops, tt.loops) || verb != tt.verb {
+			t.Errorf("%q: step %d, loops %v, verb %q; want %d, %v, %q", tt.keys, step, loops, verb, tt.step, tt.loops, tt.verb)
+		}
+	}
+}
+
+// TestChordFlush tests that a chord left without a verb hands back what was typed
+func TestChordFlush(t *testing.T) {
+	c := chordInput{isVerb: func(rune) bool { return false }}
+	feedChord(&c, "2,5")
+	if !c.pending() || c.text() != "2,5" {
+		t.Fatalf("pending %v, text %q", c.pending(), c.text())
+	}
+	if got := c.flush(); got != "2,5" || c.pending() {
+		t.Errorf("flush = %q, still pending %v", got, c.pending())
+	}
+}
@@ -0,0 +1,127 @@
+// clock.go
+// Sync indicator: the engine's sync source, incoming MIDI clock and slip.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	midiClockTick     = 0xf8
+	midiClockStart    = 0xfa
+	midiClockContinue = 0xfb
+	midiClockStop     = 0xfc
+
+	clockPPQN = 24
+	// clockTimeout is how long without ticks before the clock counts as
+	// dead. At 30 BPM ticks are 83 ms apart.
+	clockTimeout = 500 * time.Millisecond
+	// clockSlipWarn is the slip shown in red.
+	clockSlipWarn = 20 * time.Millisecond
+)
+
+// midiClock follows MIDI clock received on the MIDI input. It is guarded
+// by mu.
+type midiClock struct {
+	ticks   [clockPPQN + 1]time.Time // the last beat's worth of ticks
+	n       int                      // ticks since the clock appeared or started
+	running bool                     // between Start/Continue and Stop
+	slip    time.Duration            // engine beat offset at the last clock beat
+	slipOK  bool
+}
+
+var clockIn midiClock
+
+// handle takes a real-time message received at now. The caller must hold
+// mu.
+func (c *midiClock) handle(b byte, now time.Time) {
+	switch b {
+	case midiClockStart:
+		c.n, c.running = 0, true
+	case midiClockContinue:
+		c.running = true
+	case midiClockStop:
+		c.running = false
+	case midiClockTick:
+		if !c.alive(now) {
+			c.n = 0
+		}
+		c.ticks[c.n%len(c.ticks)] = now
+		c.n++
+		if c.n%clockPPQN == 1 {
+			c.slip, c.slipOK = engineBeatOffset(now)
+		}
+	}
+}
+
+// alive reports whether ticks are arriving.
+func (c *midiClock) alive(now time.Time) bool {
+	return c.n > 0 && now.Sub(c.ticks[(c.n-1)%len(c.ticks)]) < clockTimeout
+}
+
+// bpm is the tempo of the last beat of ticks, or 0 before there is one.
+func (c *midiClock) bpm() float64 {
+	if c.n < len(c.ticks) {
+		return 0
+	}
+	last := c.ticks[(c.n-1)%len(c.ticks)]
+	first := c.ticks[c.n%len(c.ticks)]
+	return 60 / last.Sub(first).Seconds()
+}
+
+// engineBeatOffset is how far the engine's beat, counted from loop 1's
+// position, is from the nearest beat at now. The caller must hold mu.
+func engineBeatOffset(now time.Time) (time.Duration, bool) {
+	tempo := float64(globals["tempo"])
+	if ls := loopStates[0]; tempo <= 0 || ls == nil || !ls.haveState || ls.State.Stopped() {
+		return 0, false
+	}
+	beats := metronomePos(now) * tempo / 60
+	off := beats - math.Round(beats)
+	return time.Duration(off * 60 / tempo * float64(time.Second)), true
+}
+
+// syncStatus is the status bar sync indicator, shown when the engine
+// follows an external clock or MIDI clock comes in. The caller must hold
+// mu.
+func syncStatus(now time.Time, midiIn bool) string {
+	src, ok := globals["sync_source"]
+	switch {
+	case ok && src == -1:
+		return "[green]" + trf("JACK sync %s", bpmText(float64(globals["tempo"]))) + "[-]"
+	case ok && src == -2 && !midiIn:
+		return trf("MIDI sync %s (clock not monitored)", bpmText(float64(globals["tempo"])))
+	case ok && src == -2 && !clockIn.alive(now):
+		return "[red]" + tr("MIDI sync: no clock") + "[-]"
+	case !clockIn.alive(now):
+		return ""
+	}
+	color := "green"
+	text := trf("MIDI clock %s", bpmText(clockIn.bpm()))
+	if !clockIn.running {
+		color, text = "yellow", text+" "+tr("stopped")
+	}
+	if clockIn.slipOK {
+		text += " " + trf("slip %+d ms", clockIn.slip.Round(time.Millisecond).Milliseconds())
+		if clockIn.slip.Abs() > clockSlipWarn {
+			color = "red"
+			if a11yMode {
+				text += " " + tr("(too far)")
+			}
+		}
+	}
+	if ok && src != -2 {
+		text += " " + tr("(engine not synced to it)")
+	}
+	return fmt.Sprintf("[%s]%s[-]", color, text)
+}
+
+func bpmText(bpm float64) string {
+	if bpm <= 0 {
+		return "– BPM"
+	}
+	return fmt.Sprintf("%.1f BPM", bpm)
+}
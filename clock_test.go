@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMIDIClock tests the incoming tempo and when the clock counts as dead
+func TestMIDIClock(t *testing.T) {
+	var c midiClock
+	mu.Lock()
+	defer mu.Unlock()
+	now := time.Unix(1000, 0)
+	c.handle(midiClockStart, now)
+	tick := time.Minute / 120 / clockPPQN
+	for i := 0; i < 2*clockPPQN; i++ {
+		now = now.Add(tick)
+		c.handle(midiClockTick, now)
+	}
+	if bpm := c.bpm(); bpm < 119.9 || bpm > 120.1 {
+		t.Errorf("bpm = %g, want 120", bpm)
+	}
+	if !c.alive(now) || !c.running {
+		t.Errorf("alive %v, running %v", c.alive(now), c.running)
+	}
+	c.handle(midiClockStop, now)
+	if c.running || c.alive(now.Add(clockTimeout)) {
+		t.Errorf("after stop and timeout: alive %v, running %v", c.alive(now.Add(clockTimeout)), c.running)
+	}
+}
+
+// TestSyncStatus tests the sync indicator for each sync source and the slip
+func TestSyncStatus(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+	defer func(g map[string]float32, l map[int]*LoopState, c midiClock) {
+		globals, loopStates, clockIn = g, l, c
+	}(globals, loopStates, clockIn)
+	now := time.Unix(1000, 0)
+	globals = map[string]float32{"tempo": 120, "sync_source": -1}
+	loopStates = map[int]*LoopState{}
+	clockIn = midiClock{}
+
+	for _, tt := range []struct {
+		src    float32
+		midiIn bool
+		want   string
+	}{
+		{-1, false, "JACK sync 120.0 BPM"},
+		{-2, false, "MIDI sync 120.0 BPM (clock not monitored)"},
+		{-2, true, "MIDI sync: no clock"},
+		{0, true, ""},
+	} {
+		globals["sync_source"] = tt.src
+		if got := syncStatus(now, tt.midiIn); !strings.Contains(got, tt.want) || (tt.want == "") != (got == "") {
+			t.Errorf("sync_source %g: %q, want %q", tt.src, got, tt.want)
+		}
+	}
+
+	// Loop 1 plays 30 ms after a beat when the clock beat comes.
+	globals["sync_source"] = -2
+	loopStates[0] = &LoopState{haveState: true, State: 4, LoopPos: 0.53}
+	clockIn.handle(midiClockStart, now)
+	clockIn.handle(midiClockTick, now)
+	if got := syncStatus(now, true); !strings.Contains(got, "slip +30 ms") || !strings.HasPrefix(got, "[red]") {
+		t.Errorf("status %q, want a red slip of +30 ms", got)
+	}
+}
@@ -0,0 +1,69 @@
+// Command slmock is a SooperLooper OSC simulator for developing and testing
+// sooperGUI without audio hardware. It also mocks the mixer strip gain
+// endpoint that the Level column talks to.
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	"jaudio/internal/slmock"
+)
+
+func main() {
+	host := flag.String("host", "127.0.0.1", "address to listen on")
+	port := flag.Int("port", 9951, "SooperLooper OSC port (0 disables)")
+	mixerPort := flag.Int("mixer-port", 9090, "mixer strip gain OSC port (0 disables)")
+	loops := flag.Int("loops", 3, "number of loops at startup")
+	tick := flag.Duration("tick", 10*time.Millisecond, "simulation step")
+	scenarioFile := flag.String("scenario", "", "YAML scenario of timed events, meter envelopes and faults")
+	verbose := flag.Bool("v", false, "log every message received")
+	flag.Parse()
+
+	var scenario *slmock.Scenario
+	if *scenarioFile != "" {
+		s, err := slmock.LoadScenario(*scenarioFile)
+		if err != nil {
+			log.Fatalf("scenario: %v", err)
+		}
+		if s.Loops > 0 {
+			*loops = s.Loops
+		}
+		scenario = s
+	}
+
+	e := slmock.New(*loops)
+	if *verbose {
+		e.Logf = log.Printf
+	}
+	if scenario != nil {
+		e.Play(scenario, time.Now())
+		log.Printf("playing scenario %s", *scenarioFile)
+	}
+
+	serve := func(p int) {
+		addr := net.JoinHostPort(*host, strconv.Itoa(p))
+		pc, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			log.Fatalf("listen %s: %v", addr, err)
+		}
+		log.Printf("listening on udp://%s", addr)
+		go func() {
+			if err := e.Serve(pc); err != nil {
+				log.Fatalf("serve %s: %v", addr, err)
+			}
+		}()
+	}
+	if *port != 0 {
+		serve(*port)
+	}
+	if *mixerPort != 0 && *mixerPort != *port {
+		serve(*mixerPort)
+	}
+
+	log.Printf("slmock %s with %d loops", slmock.Version, *loops)
+	e.Run(*tick, nil)
+}
@@ -0,0 +1,309 @@
+// config.go
+// The config file, and the engine profile pushed to the engine on connect.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hypebeast/go-osc/osc"
+	"gopkg.in/yaml.v3"
+)
+
+// config is the --config file:
+//
+//	engine: {host: 127.0.0.1, port: 9951}
+//	terminal: tmux-pane
+//	loop_names: {1: Drums, 2: Bass}
+//	profile:
+//	  loop_count: 4
+//	  tempo: 120
+//	  sync_source: internal
+//	  globals: {eighth_per_cycle: 8}
+//	  loops: {sync: true, quantize: cycle, controls: {feedback: 0.9}}
+//	footswitches:
+//	  - device: /dev/input/event5
+//	    keys: {KEY_A: record, KEY_B: overdub}
+//	midi:
+//	  device: /dev/snd/midiC1D0
+//	  bindings: [{note: 36, action: record 1}]
+//	mackie:
+//	  device: /dev/snd/midiC2D0
+//	mqtt:
+//	  broker: 192.168.1.10:1883
+//	mirrors:
+//	  - {host: 192.168.1.30, port: 9000}
+//	macros:
+//	  drop: "mute 1; mute 2; record 3"
+//	buttons:
+//	  mute: {on: [Mute, OffMuted, 21]}
+//	record_lengths: {1: 4 cycles, 2: 8s, 3: off}
+//	groups: {percussion: [1, 2, 3]}
+//	meter_trims: {2: -6, 4: 3.5}
+//	cues: {Record: bell, loop_end: paplay click.wav, clip: bell}
+//	hooks: {loop_record_start: "curl -s http://lights/red"}
+//	display: {refresh_rate: 100, latency_warn: 20}
+//	profiles:
+//	  live: {engine: {host: 192.168.1.20}, panes: [scenes]}
+type config struct {
+	// Engine is where the engine is reached, over --osc-host and
+	// --osc-port unless those are given.
+	Engine *profileEngine `yaml:"engine"`
+	// Terminal is where the TUI opens without --tmux: window, tmux-window,
+	// tmux-pane or current.
+	Terminal string `yaml:"terminal"`
+	// LoopNames label loops, by loop number.
+	LoopNames    map[int]string     `yaml:"loop_names"`
+	Profile      *engineProfile     `yaml:"profile"`
+	Footswitches []footswitchConfig `yaml:"footswitches"`
+	MIDI         *midiConfig        `yaml:"midi"`
+	Mackie       *mackieConfig      `yaml:"mackie"`
+	MQTT         *mqttConfig        `yaml:"mqtt"`
+	Mirrors      []mirrorConfig     `yaml:"mirrors"`
+	// Macros are named actions, run with "macro <name>" from MIDI or OSC.
+	Macros map[string]string `yaml:"macros"`
+	// Buttons override the states that light the Rec, Dub and Mute
+	// buttons, by button name.
+	Buttons map[string]buttonConfig `yaml:"buttons"`
+	// RecordLengths override --record-length, by loop number.
+	RecordLengths map[int]string `yaml:"record_lengths"`
+	// Groups are link groups of loops, by name, whose Levels move together.
+	Groups map[string][]int `yaml:"groups"`
+	// MeterTrims offset the meters of loops, in dB by loop number.
+	MeterTrims map[int]float64 `yaml:"meter_trims"`
+	// Cues bind loop_end, clip and loop states to bell or a command.
+	Cues map[string]string `yaml:"cues"`
+	// Hooks are shell commands run on events, by event name.
+	Hooks map[string]string `yaml:"hooks"`
+	// Display overrides the display flags.
+	Display displayConfig `yaml:"display"`
+	// Profiles are named setups, one of which --profile picks.
+	Profiles map[string]namedProfile `yaml:"profiles"`
+}
+
+// engineProfile is the engine state set when the engine connects, so every
+// session starts from the same place. Settings left out are not sent.
+type engineProfile struct {
+	LoopCount  int                `yaml:"loop_count"`
+	Tempo      float32            `yaml:"tempo"`
+	SyncSource string             `yaml:"sync_source"`
+	Globals    map[string]float32 `yaml:"globals"`
+	Loops      profileLoops       `yaml:"loops"`
+
+	// EveryConnect pushes the profile again whenever the engine comes
+	// back, not only the first time, e.g. after --spawn-engine restarts it.
+	EveryConnect bool `yaml:"every_connect"`
+}
+
+// profileLoops are the settings given to every loop.
+type profileLoops struct {
+	Sync     *bool              `yaml:"sync"`
+	Quantize string             `yaml:"quantize"`
+	Controls map[string]float32 `yaml:"controls"`
+}
+
+// syncSources are SooperLooper's sync_source values by name. Loops are
+// named loop1, loop2 and so on.
+var syncSources = map[string]float32{"internal": -3, "midi": -2, "jack": -1, "none": 0}
+
+// New loops are stereo with room for at least 40 seconds, sooperlooper's
+// defaults.
+const (
+	profileLoopChannels = 2
+	profileLoopSeconds  = 40
+)
+
+var (
+	configFile = ""
+	appConfig  config
+)
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "sooperGUI", "config.yaml")
+}
+
+// loadConfig reads the config file. A missing file is an empty config
+// unless it was named with --config.
+func loadConfig(file string, named bool) (config, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) && !named {
+		return parseConfig(nil)
+	}
+	if err != nil {
+		return config{}, err
+	}
+	cfg, err := parseConfig(data)
+	if err != nil {
+		return config{}, fmt.Errorf("%s: %w", file, err)
+	}
+	return cfg, nil
+}
+
+func parseConfig(data []byte) (config, error) {
+	var cfg config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return config{}, err
+	}
+	cfg.Macros = addPanicMacro(cfg.Macros)
+	if err := cfg.Engine.validate(); err != nil {
+		return config{}, fmt.Errorf("engine: %w", err)
+	}
+	if cfg.Terminal != "" && !slices.Contains(terminalChoices, cfg.Terminal) {
+		return config{}, fmt.Errorf("terminal must be one of %s", strings.Join(terminalChoices, ", "))
+	}
+	if err := validateLoopNames(cfg.LoopNames); err != nil {
+		return config{}, fmt.Errorf("loop_names: %w", err)
+	}
+	if p := cfg.Profile; p != nil {
+		if err := p.validate(); err != nil {
+			return config{}, fmt.Errorf("profile: %w", err)
+		}
+	}
+	for i, f := range cfg.Footswitches {
+		if _, err := f.bindings(); err != nil {
+			return config{}, fmt.Errorf("footswitches[%d]: %w", i, err)
+		}
+	}
+	for i, m := range cfg.Mirrors {
+		if err := m.validate(); err != nil {
+			return config{}, fmt.Errorf("mirrors[%d]: %w", i, err)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Macros)) {
+		if _, _, err := parseActions(cfg.Macros[name], cfg.Macros); err != nil {
+			return config{}, fmt.Errorf("macros: %s: %w", name, err)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Buttons)) {
+		if _, err := buttonKey(name); err != nil {
+			return config{}, fmt.Errorf("buttons: %w", err)
+		}
+		if err := cfg.Buttons[name].validate(); err != nil {
+			return config{}, fmt.Errorf("buttons: %s: %w", name, err)
+		}
+	}
+	for _, n := range slices.Sorted(maps.Keys(cfg.RecordLengths)) {
+		if n < 1 || n > maxLoops {
+			return config{}, fmt.Errorf("record_lengths: loop %d must be 1 to %d", n, maxLoops)
+		}
+		if _, err := parseRecordLength(cfg.RecordLengths[n]); err != nil {
+			return config{}, fmt.Errorf("record_lengths: %d: %w", n, err)
+		}
+	}
+	if err := validateGroups(cfg.Groups); err != nil {
+		return config{}, fmt.Errorf("groups: %w", err)
+	}
+	if err := validateMeterTrims(cfg.MeterTrims); err != nil {
+		return config{}, fmt.Errorf("meter_trims: %w", err)
+	}
+	if err := validateCues(cfg.Cues); err != nil {
+		return config{}, fmt.Errorf("cues: %w", err)
+	}
+	if err := validateHooks(cfg.Hooks); err != nil {
+		return config{}, fmt.Errorf("hooks: %w", err)
+	}
+	if cfg.MIDI != nil {
+		if _, err := cfg.MIDI.routes(cfg.Macros); err != nil {
+			return config{}, fmt.Errorf("midi: %w", err)
+		}
+	}
+	if cfg.Mackie != nil {
+		if err := cfg.Mackie.validate(); err != nil {
+			return config{}, fmt.Errorf("mackie: %w", err)
+		}
+	}
+	if cfg.MQTT != nil {
+		if err := cfg.MQTT.validate(); err != nil {
+			return config{}, fmt.Errorf("mqtt: %w", err)
+		}
+	}
+	if err := cfg.Display.validate(); err != nil {
+		return config{}, fmt.Errorf("display: %w", err)
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Profiles)) {
+		if err := cfg.Profiles[name].validate(cfg.Macros); err != nil {
+			return config{}, fmt.Errorf("profiles: %s: %w", name, err)
+		}
+	}
+	return cfg, nil
+}
+
+func (p *engineProfile) validate() error {
+	if _, err := p.syncSource(); err != nil {
+		return err
+	}
+	switch {
+	case p.LoopCount < 0 || p.LoopCount > maxLoops:
+		return fmt.Errorf("loop_count must be between 0 and %d", maxLoops)
+	case p.Tempo < 0:
+		return fmt.Errorf("tempo must not be negative")
+	}
+	if _, ok := quantizeModes[p.Loops.Quantize]; p.Loops.Quantize != "" && !ok {
+		return fmt.Errorf("quantize must be off, cycle, 8th or loop, not %q", p.Loops.Quantize)
+	}
+	return nil
+}
+
+// syncSource returns the sync_source value, or 0 and nil if unset.
+func (p *engineProfile) syncSource() (float32, error) {
+	if p.SyncSource == "" {
+		return 0, nil
+	}
+	if v, ok := syncSources[p.SyncSource]; ok {
+		return v, nil
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(p.SyncSource, "loop")); err == nil && strings.HasPrefix(p.SyncSource, "loop") && n >= 1 && n <= maxLoops {
+		return float32(n), nil
+	}
+	return 0, fmt.Errorf("sync_source must be internal, midi, jack, none or loop<N>, not %q", p.SyncSource)
+}
+
+// messages returns the messages that set up the profile on an engine with
+// loops loops. Loops are added or removed first; the loop settings then go
+// to all loops at once (/sl/-1).
+func (p *engineProfile) messages(loops int) []*osc.Message {
+	var msgs []*osc.Message
+	for n := loops; n < p.LoopCount; n++ {
+		msgs = append(msgs, osc.NewMessage("/loop_add", int32(profileLoopChannels), float32(profileLoopSeconds)))
+	}
+	for n := loops; p.LoopCount > 0 && n > p.LoopCount; n-- {
+		msgs = append(msgs, osc.NewMessage("/loop_del", int32(-1)))
+	}
+	if p.Tempo > 0 {
+		msgs = append(msgs, osc.NewMessage("/set", "tempo", p.Tempo))
+	}
+	if v, _ := p.syncSource(); p.SyncSource != "" {
+		msgs = append(msgs, osc.NewMessage("/set", "sync_source", v))
+	}
+	for _, name := range slices.Sorted(maps.Keys(p.Globals)) {
+		msgs = append(msgs, osc.NewMessage("/set", name, p.Globals[name]))
+	}
+
+	const all = "/sl/-1/set"
+	if l := p.Loops; l.Sync != nil {
+		v := float32(0)
+		if *l.Sync {
+			v = 1
+		}
+		msgs = append(msgs, osc.NewMessage(all, "sync", v))
+	}
+	if q := p.Loops.Quantize; q != "" {
+		msgs = append(msgs, osc.NewMessage(all, "quantize", float32(quantizeModes[q])))
+	}
+	for _, name := range slices.Sorted(maps.Keys(p.Loops.Controls)) {
+		msgs = append(msgs, osc.NewMessage(all, name, p.Loops.Controls[name]))
+	}
+	return msgs
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testConfig = `
+profile:
+  loop_count: 3
+  tempo: 100
+  sync_source: loop2
+  globals: {eighth_per_cycle: 8}
+  loops: {sync: true, quantize: 8th, controls: {wet: 0.8, feedback: 0.5}}
+`
+
+// TestParseConfig tests config and profile validation
+func TestParseConfig(t *testing.T) {
+	cfg, err := parseConfig([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := cfg.Profile; p == nil || p.LoopCount != 3 || p.Loops.Controls["feedback"] != 0.5 {
+		t.Errorf("parsed %+v", cfg.Profile)
+	}
+	if _, err := loadConfig("contrib/config.example.yaml", true); err != nil {
+		t.Errorf("example config: %v", err)
+	}
+	if cfg, err := parseConfig(nil); err != nil || cfg.Profile != nil {
+		t.Errorf("empty config = %+v, %v", cfg, err)
+	}
+	for _, bad := range []string{
+		"profile: {loop_count: -1}",
+		"profile: {loop_count: 65}",
+		"profile: {tempo: -5}",
+		"profile: {sync_source: loop0}",
+		"profile: {sync_source: clock}",
+		"profile: {loops: {quantize: bar}}",
+		"footswitches: [{device: /dev/input/event5, keys: {KEY_A: jump}}]",
+		"mackie: {device: ''}",
+	} {
+		if _, err := parseConfig([]byte(bad)); err == nil {
+			t.Errorf("parseConfig(%q) succeeded", bad)
+		}
+	}
+}
+
+// TestProfileMessages tests the OSC messages that set up a profile
+func TestProfileMessages(t *testing.T) {
+	cfg, _ := parseConfig([]byte(testConfig))
+	var got []string
+	for _, m := range cfg.Profile.messages(1) {
+		got = append(got, m.String())
+	}
+	want := []string{
+		"/loop_add ,if 2 40",
+		"/loop_add ,if 2 40",
+		"/set ,sf tempo 100",
+		"/set ,sf sync_source 2",
+		"/set ,sf eighth_per_cycle 8",
+		"/sl/-1/set ,sf sync 1",
+		"/sl/-1/set ,sf quantize 2",
+		"/sl/-1/set ,sf feedback 0.5",
+		"/sl/-1/set ,sf wet 0.8",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("messages(1) =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if msgs := cfg.Profile.messages(5); msgs[0].String() != "/loop_del ,i -1" || msgs[1].String() != "/loop_del ,i -1" {
+		t.Errorf("messages(5) starts %v %v, want two loop_del", msgs[0], msgs[1])
+	}
+}
+
+// TestProfileOnConnect tests that the profile reaches the engine when it connects
+func TestProfileOnConnect(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 2)
+	cfg, _ := parseConfig([]byte(testConfig))
+	appConfig = cfg
+	defer func() { appConfig = config{} }()
+	startClient(t, sim)
+	eventually(t, "profile applied", func() bool {
+		return sim.LoopCount() == 3 && sim.Global("tempo") == 100 && sim.Control(2, "feedback") == 0.5
+	})
+	eventually(t, "added loop seen", func() bool { return loopCount == 3 })
+}
@@ -0,0 +1,98 @@
+// copyloop.go
+// Copying a loop's audio to another loop through a temporary file.
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	errorPrefix      = "/error/"
+	copyFileTimeout  = 30 * time.Second
+	copyFilePoll     = 100 * time.Millisecond
+	copyFileLifetime = time.Minute
+)
+
+var (
+	// copySource is the loop marked with y, or -1.
+	copySource = -1
+	copyDir    = os.TempDir()
+)
+
+// markCopySource remembers loop i as the source for the next paste.
+func markCopySource(i int) {
+	mu.Lock()
+	defer mu.Unlock()
+	if i >= loopCount {
+		return
+	}
+	copySource = i
+	tuiLog.Info("loop copied", "loop", i+1)
+}
+
+// pasteLoop copies the marked loop into loop to. SooperLooper has no copy
+// command, so the source is saved with save_loop and the file loaded into
+// the target with load_loop. Both run in the engine, which must therefore
+// share this machine's temp directory.
+func pasteLoop(to int) {
+	mu.Lock()
+	from, n := copySource, loopCount
+	mu.Unlock()
+	switch {
+	case from < 0:
+		tuiLog.Warn("nothing to paste; mark a loop with y first")
+		return
+	case to >= n || to == from || !engineSharesFiles("copying loops"):
+		return
+	}
+
+	file := filepath.Join(copyDir, fmt.Sprintf("sooperGUI-copy-%d-%d.wav", os.Getpid(), time.Now().UnixNano()))
+	tuiLog.Info("pasting loop", "from", from+1, "to", to+1, "file", file)
+	sl.SaveLoop(from, file)
+	go func() {
+		if err := waitForFile(file, copyFileTimeout, copyFilePoll); err != nil {
+			tuiLog.Warn("loop paste failed", "from", from+1, "to", to+1, "err", err)
+			return
+		}
+		sl.LoadLoop(to, file)
+		time.AfterFunc(copyFileLifetime, func() { os.Remove(file) })
+	}()
+}
+
+// engineSharesFiles reports whether there is an engine that reads and
+// writes this machine's files, warning that what needs one if not.
+func engineSharesFiles(what string) bool {
+	switch {
+	case sl == nil:
+		tuiLog.Warn(what + " needs an engine")
+		notify(slog.LevelWarn, tr("This needs an engine"))
+		return false
+	case getLocalIP(oscHost) != "127.0.0.1":
+		tuiLog.Warn(what+" needs the engine on this machine", "host", oscHost)
+		notify(slog.LevelWarn, trf("This needs the engine on this machine, not %s", oscHost))
+		return false
+	}
+	return true
+}
+
+// waitForFile waits until file exists and its size has stopped changing
+// for one poll interval, i.e. the engine has finished writing it.
+func waitForFile(file string, timeout, poll time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	last := int64(-1)
+	for time.Now().Before(deadline) {
+		if fi, err := os.Stat(file); err == nil && fi.Size() > 0 {
+			if fi.Size() == last {
+				return nil
+			}
+			last = fi.Size()
+		}
+		time.Sleep(poll)
+	}
+	return fmt.Errorf("%s not written within %v", file, timeout)
+}
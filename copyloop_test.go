@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"jaudio/internal/slmock"
+)
+
+// TestWaitForFile tests waiting for the engine to finish a file
+func TestWaitForFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "loop.wav")
+	if err := waitForFile(file, 50*time.Millisecond, 10*time.Millisecond); err == nil {
+		t.Error("waitForFile succeeded for a missing file")
+	}
+	if err := os.WriteFile(file, []byte("audio"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitForFile(file, time.Second, 10*time.Millisecond); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestPasteLoop tests copying a loop through the simulator
+func TestPasteLoop(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 3)
+	sl = startClient(t, sim)
+	defer func(dir string) { sl, copySource, copyDir = nil, -1, dir }(copyDir)
+	copyDir = t.TempDir()
+	eventually(t, "engine online", func() bool { return loopCount == 3 })
+
+	sim.Handle(hitMessage(0, "record"))
+	time.Sleep(50 * time.Millisecond)
+	sim.Handle(hitMessage(0, "record"))
+
+	markCopySource(0)
+	pasteLoop(2)
+	eventually(t, "loop 3 playing", func() bool { return sim.State(2) == slmock.StatePlaying })
+	if got, want := sim.Control(2, "loop_len"), sim.Control(0, "loop_len"); got != want {
+		t.Errorf("pasted loop length = %v, want %v", got, want)
+	}
+	if got := sim.State(1); got != slmock.StateOff {
+		t.Errorf("loop 2 state = %d, want untouched", got)
+	}
+}
@@ -0,0 +1,99 @@
+// countin.go
+// Count-in: record waits for the first bar line at least --count-in beats
+// away, counting down in the Rec button.
+
+package main
+
+import (
+	"math"
+	"time"
+
+	"jaudio/internal/slstate"
+)
+
+// countInBeats (--count-in) is the least number of beats a record waits;
+// 0 records straight away.
+var countInBeats = 0
+
+// countIn is a record waiting for its count-in.
+type countIn struct {
+	At   time.Time
+	Beat time.Duration
+	seq  int
+}
+
+var countInSeq int
+
+// countInDelay returns how long from song position pos (seconds) to the
+// first bar line at least beats beats away.
+func countInDelay(pos float64, tempo, eighths float32, beats int) time.Duration {
+	_, _, beatsPerBar := beatAt(0, tempo, eighths)
+	beat := 60 / float64(tempo)
+	bar := beat * float64(beatsPerBar)
+	earliest := pos + float64(beats)*beat
+	// Allow for rounding when earliest falls on a bar line.
+	start := math.Ceil(earliest/bar-1e-9) * bar
+	return time.Duration((start - pos) * float64(time.Second))
+}
+
+// beatsLeft is the countdown shown for c at now, from beats down to 1.
+func (c *countIn) beatsLeft(now time.Time) int {
+	if c.Beat <= 0 {
+		return 0
+	}
+	return max(int(math.Ceil(float64(c.At.Sub(now))/float64(c.Beat))), 1)
+}
+
+// hitLoop sends a loop command from the GUI. With --count-in, record on a
+// loop that is not recording waits for the count-in, and record during a
+// count-in cancels it.
+func hitLoop(i int, cmd string) {
+	if cmd != "record" || countInBeats <= 0 || i < 0 {
+		mu.Lock()
+		awaitCommand(i, cmd, time.Now())
+		mu.Unlock()
+		sl.Hit(i, cmd)
+		return
+	}
+	mu.Lock()
+	ls := getLoopState(i)
+	switch {
+	case ls.countIn != nil:
+		ls.countIn = nil
+		mu.Unlock()
+		tuiLog.Info("count-in cancelled", "loop", i+1)
+		return
+	case ls.State.In(slstate.WaitStart, slstate.Record, slstate.WaitStop):
+		awaitCommand(i, cmd, time.Now())
+		mu.Unlock()
+		sl.Hit(i, cmd)
+		return
+	}
+	now := time.Now()
+	tempo := globals["tempo"]
+	if tempo <= 0 {
+		tempo = fadeFallbackTempo
+		tuiLog.Warn("tempo unknown, counting in at the fallback tempo", "bpm", tempo)
+	}
+	delay := countInDelay(metronomePos(now), tempo, globals["eighth_per_cycle"], countInBeats)
+	countInSeq++
+	c := &countIn{At: now.Add(delay), Beat: time.Duration(60 / float64(tempo) * float64(time.Second)), seq: countInSeq}
+	ls.countIn = c
+	mu.Unlock()
+	tuiLog.Info("count-in", "loop", i+1, "in", delay)
+
+	time.AfterFunc(delay, func() {
+		mu.Lock()
+		ls := getLoopState(i)
+		due := ls.countIn == c
+		if due {
+			ls.countIn = nil
+			awaitCommand(i, "record", time.Now())
+		}
+		client := sl
+		mu.Unlock()
+		if due {
+			client.Hit(i, "record")
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+	"jaudio/internal/slmock"
+)
+
+// TestCountInDelay tests the wait to the first bar line far enough away,
+// at 120 BPM in 4/4
+func TestCountInDelay(t *testing.T) {
+	tests := []struct {
+		pos   float64
+		beats int
+		want  time.Duration
+	}{
+		{0, 4, 2 * time.Second},
+		{0, 1, 2 * time.Second},
+		{0, 5, 4 * time.Second},
+		{0.5, 4, 3500 * time.Millisecond},
+		{1.4, 1, 600 * time.Millisecond},
+		{1.9, 2, 2100 * time.Millisecond},
+		{2, 0, 0},
+	}
+	for _, tt := range tests {
+		got := countInDelay(tt.pos, 120, 8, tt.beats)
+		if d := got - tt.want; d < -time.Millisecond || d > time.Millisecond {
+			t.Errorf("countInDelay(%v, %d beats) = %v, want %v", tt.pos, tt.beats, got, tt.want)
+		}
+	}
+}
+
+// TestCountInBeatsLeft tests the countdown in the Rec button
+func TestCountInBeatsLeft(t *testing.T) {
+	now := time.Now()
+	c := &countIn{At: now.Add(1500 * time.Millisecond), Beat: 500 * time.Millisecond}
+	for _, tt := range []struct {
+		at   time.Duration
+		want int
+	}{{0, 3}, {100 * time.Millisecond, 3}, {600 * time.Millisecond, 2}, {1400 * time.Millisecond, 1}, {2 * time.Second, 1}} {
+		if got := c.beatsLeft(now.Add(tt.at)); got != tt.want {
+			t.Errorf("beatsLeft after %v = %d, want %d", tt.at, got, tt.want)
+		}
+	}
+}
+
+// TestHitLoopCountIn tests that record waits for the count-in, and that a
+// second record cancels it
+func TestHitLoopCountIn(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 2)
+	sim.Handle(osc.NewMessage("/set", "tempo", float32(3000)))
+	sl = startClient(t, sim)
+	defer func(beats int) {
+		mu.Lock()
+		sl, countInBeats = nil, beats
+		mu.Unlock()
+	}(countInBeats)
+	countInBeats = 2
+	eventually(t, "tempo", func() bool { return loopCount == 2 && globals["tempo"] == 3000 })
+
+	hitLoop(1, "record")
+	hitLoop(1, "record")
+	hitLoop(0, "record")
+	mu.Lock()
+	waiting, cancelled := getLoopState(0).countIn != nil, getLoopState(1).countIn == nil
+	mu.Unlock()
+	if !waiting || !cancelled || sim.State(0) != slmock.StateOff {
+		t.Fatalf("count-in: loop 1 waiting %v, loop 2 cancelled %v, loop 1 state %d", waiting, cancelled, sim.State(0))
+	}
+	eventually(t, "loop 1 recording", func() bool { return sim.State(0) == slmock.StateRecording })
+	time.Sleep(300 * time.Millisecond)
+	if sim.State(1) != slmock.StateOff {
+		t.Errorf("cancelled loop 2 state = %d, want off", sim.State(1))
+	}
+}
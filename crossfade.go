@@ -0,0 +1,73 @@
+// crossfade.go
+// A/B crossfader that mixes every loop's settings between two scenes.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+const crossfadeStep = 0.05
+
+// crossfader mixes scene slot A (Pos 0) into scene slot B (Pos 1).
+type crossfader struct {
+	A, B int
+	Pos  float32
+}
+
+var (
+	xfade         = crossfader{A: 0, B: 1}
+	showCrossfade bool
+)
+
+// mix returns the settings at the fader position, and false unless both
+// slots hold a scene.
+func (x crossfader) mix(list []scene) (scene, bool) {
+	if x.A >= len(list) || x.B >= len(list) {
+		return scene{}, false
+	}
+	return lerpScene(list[x.A], list[x.B], x.Pos), true
+}
+
+// nextSlot returns the scene slot after n, wrapping around.
+func nextSlot(n int, list []scene) int {
+	if len(list) == 0 {
+		return n
+	}
+	return (n + 1) % len(list)
+}
+
+// moveCrossfader sets the fader position and sends the mixed settings. It
+// stops any scene recall ramp in progress.
+func moveCrossfader(pos float32) {
+	mu.Lock()
+	xfade.Pos = min(max(pos, 0), 1)
+	s, ok := xfade.mix(scenes)
+	sceneRampSeq++
+	mu.Unlock()
+	if ok {
+		applyScene(s)
+	}
+}
+
+// crossfadeLine lays the crossfader out in width columns. It returns the
+// text and the first column and width of the bar, for mouse hits.
+func crossfadeLine(x crossfader, list []scene, width int) (text string, barX, barW int) {
+	label := func(slot int) string {
+		if slot >= len(list) {
+			return fmt.Sprintf("F%d –", slot+1)
+		}
+		return fmt.Sprintf("F%d %s", slot+1, list[slot].Name)
+	}
+	left := " A " + label(x.A) + " "
+	right := " " + label(x.B) + " B "
+	barX = utf8.RuneCountInString(left)
+	barW = max(width-barX-utf8.RuneCountInString(right), 3)
+
+	knob := int(math.Round(float64(x.Pos) * float64(barW-1)))
+	bar := strings.Repeat("─", knob) + "█" + strings.Repeat("─", barW-knob-1)
+	return left + bar + right, barX, barW
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+// TestCrossfaderMix tests mixing between the A and B scenes
+func TestCrossfaderMix(t *testing.T) {
+	list := []scene{
+		{Name: "Verse", Loops: []sceneLoop{{Level: 0}}},
+		{Name: "Chorus", Loops: []sceneLoop{{Level: 1}}},
+	}
+	x := crossfader{A: 0, B: 1, Pos: 0.25}
+	if s, ok := x.mix(list); !ok || s.Loops[0].Level != 0.25 {
+		t.Errorf("mix at 0.25 = %+v, %v, want level 0.25", s, ok)
+	}
+	x.B = 2
+	if _, ok := x.mix(list); ok {
+		t.Error("mix with an empty B slot succeeded")
+	}
+	if got := nextSlot(1, list); got != 0 {
+		t.Errorf("nextSlot(1) = %d, want 0", got)
+	}
+}
+
+// TestCrossfadeLine tests the crossfader layout and knob position
+func TestCrossfadeLine(t *testing.T) {
+	list := []scene{{Name: "Verse"}}
+	tests := []struct {
+		pos  float32
+		want string
+	}{
+		{0, " A F1 Verse █──────── F2 – B "},
+		{0.5, " A F1 Verse ────█──── F2 – B "},
+		{1, " A F1 Verse ────────█ F2 – B "},
+	}
+	for _, tt := range tests {
+		got, barX, barW := crossfadeLine(crossfader{A: 0, B: 1, Pos: tt.pos}, list, 29)
+		if got != tt.want || barX != 12 || barW != 9 {
+			t.Errorf("pos %v: got %q (bar at %d, width %d), want %q (bar at 12, width 9)", tt.pos, got, barX, barW, tt.want)
+		}
+	}
+}
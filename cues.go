@@ -0,0 +1,156 @@
+// cues.go
+// Audio cues: the config file's cues bind loop events to the terminal bell
+// or a command such as paplay click.wav, so a performer who is not watching
+// the screen hears that a record started, a loop is about to come round, or
+// a meter clipped.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"jaudio/internal/slstate"
+)
+
+const (
+	// cueLoopEnd sounds --cue-lead seconds before a playing loop comes
+	// round.
+	cueLoopEnd = "loop_end"
+	// cueClip sounds when a loop's input or output meter reaches full scale.
+	cueClip = "clip"
+	// cueBell is the command that rings the terminal bell.
+	cueBell = "bell"
+	// cueGap is the shortest time between two sounds of one cue, so loops
+	// in sync sound it once.
+	cueGap = 100 * time.Millisecond
+)
+
+// soundCue is a cue to sound: its event, and its command or nil for the
+// bell.
+type soundCue struct {
+	event string
+	args  []string
+}
+
+var (
+	// cueLead (--cue-lead) is how many seconds before its end a loop sounds
+	// loop_end.
+	cueLead = 0.5
+	// cueBindings are the commands of the config file's cues by event, nil
+	// for the bell. Guarded by mu, as the config file may be reloaded.
+	cueBindings map[string][]string
+	// cues feeds the cue player; full means cues are dropped rather than
+	// sounded late.
+	cues = make(chan soundCue, 8)
+)
+
+// validateCues checks the config file's cues: loop_end, clip or a loop
+// state name, each to bell or a command.
+func validateCues(bindings map[string]string) error {
+	for _, event := range slices.Sorted(maps.Keys(bindings)) {
+		if _, ok := slstate.Parse(event); !ok && event != cueLoopEnd && event != cueClip {
+			return fmt.Errorf("%s: must be %s, %s or a loop state", event, cueLoopEnd, cueClip)
+		}
+		if len(strings.Fields(bindings[event])) == 0 {
+			return fmt.Errorf("%s: needs bell or a command", event)
+		}
+	}
+	return nil
+}
+
+// setCues sets the cues from the config file's cues. They have been
+// validated.
+func setCues(bindings map[string]string) {
+	cueBindings = map[string][]string{}
+	for event, command := range bindings {
+		if command == cueBell {
+			cueBindings[event] = nil
+		} else {
+			cueBindings[event] = strings.Fields(command)
+		}
+	}
+}
+
+// cue sounds the cue bound to event, if there is one, without waiting.
+func cue(event string) {
+	args, ok := cueBindings[event]
+	if !ok {
+		return
+	}
+	select {
+	case cues <- soundCue{event, args}:
+	default:
+		tuiLog.Debug("cue dropped", "cue", event)
+	}
+}
+
+// cueLoopPos sounds loop_end when a playing loop's position moves from
+// from to to past --cue-lead seconds before its end. The caller must hold
+// mu.
+func cueLoopPos(ls *LoopState, from, to float32) {
+	at := ls.controls["loop_len"] - float32(cueLead)
+	if at <= 0 || ls.State.Recording() || ls.State.Stopped() || ls.State == slstate.WaitStart {
+		return
+	}
+	if from < at && to >= at {
+		cue(cueLoopEnd)
+	}
+}
+
+// meterClip sounds clip and runs the clip hook when either of loop i's
+// meters reaches full scale, once until both fall back. The caller must
+// hold mu.
+func meterClip(i int, ls *LoopState) {
+	clipping := ls.InPeakMeter >= 1 || ls.OutPeakMeter >= 1
+	if clipping && !ls.clipping {
+		cue(cueClip)
+		meter := "out"
+		if ls.InPeakMeter >= 1 {
+			meter = "in"
+		}
+		runHook("clip", strconv.Itoa(i+1), meter)
+	}
+	ls.clipping = clipping
+}
+
+// startCues sounds cues: it rings the terminal bell with beep, and starts
+// commands without waiting for them so a slow one delays no other cue.
+func startCues(beep func()) {
+	goService("cues", func(ctx context.Context) error {
+		last := map[string]time.Time{}
+		for {
+			var c soundCue
+			select {
+			case <-ctx.Done():
+				return nil
+			case c = <-cues:
+			}
+			now := time.Now()
+			if now.Sub(last[c.event]) < cueGap {
+				continue
+			}
+			last[c.event] = now
+			if c.args == nil {
+				beep()
+				continue
+			}
+			cmd := exec.Command(c.args[0], c.args[1:]...)
+			if err := cmd.Start(); err != nil {
+				tuiLog.Warn("cue command failed", "cue", c.event, "cmd", c.args[0], "err", err)
+				continue
+			}
+			go func() {
+				if err := cmd.Wait(); err != nil {
+					tuiLog.Warn("cue command failed", "cue", c.event, "cmd", c.args[0], "err", err)
+				}
+			}()
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"jaudio/internal/slstate"
+)
+
+// TestValidateCues tests cue events and commands
+func TestValidateCues(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+	cfg, err := parseConfig([]byte("cues: {Record: bell, loop_end: paplay click.wav, clip: bell}"))
+	if err != nil {
+		t.Fatalf("good cues: %v", err)
+	}
+	setCues(cfg.Cues)
+	defer setCues(nil)
+	if args, ok := cueBindings[cueLoopEnd]; !ok || !slices.Equal(args, []string{"paplay", "click.wav"}) {
+		t.Errorf("loop_end runs %q, want paplay click.wav", args)
+	}
+	if args, ok := cueBindings["Record"]; !ok || args != nil {
+		t.Errorf("Record runs %q, want the bell", args)
+	}
+	for _, bad := range []string{"cues: {Recording: bell}", "cues: {clip: ''}", "cues: {loop_end: ' '}"} {
+		if _, err := parseConfig([]byte(bad)); err == nil {
+			t.Errorf("%s was accepted", bad)
+		}
+	}
+}
+
+// TestCueEvents tests when the loop_end and clip cues sound
+func TestCueEvents(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+	setCues(map[string]string{cueLoopEnd: "bell", cueClip: "click"})
+	defer setCues(nil)
+	sounded := func() []string {
+		var got []string
+		for {
+			select {
+			case c := <-cues:
+				got = append(got, c.event)
+			default:
+				return got
+			}
+		}
+	}
+
+	ls := &LoopState{State: slstate.Play}
+	ls.setControl("loop_len", 4)
+	cueLoopPos(ls, 3.2, 3.4)
+	cueLoopPos(ls, 3.4, 3.6)
+	cueLoopPos(ls, 3.6, 0.1)
+	ls.State = slstate.Record
+	cueLoopPos(ls, 3.4, 3.6)
+	if got := sounded(); !slices.Equal(got, []string{cueLoopEnd}) {
+		t.Errorf("loop positions sounded %q, want loop_end once", got)
+	}
+
+	ls.InPeakMeter = 1.2
+	meterClip(0, ls)
+	ls.OutPeakMeter = 1
+	meterClip(0, ls)
+	ls.InPeakMeter, ls.OutPeakMeter = 0.5, 0.5
+	meterClip(0, ls)
+	ls.OutPeakMeter = 1
+	meterClip(0, ls)
+	if got := sounded(); !slices.Equal(got, []string{cueClip, cueClip}) {
+		t.Errorf("meters sounded %q, want clip twice", got)
+	}
+}
+
+// TestCueEngine tests that a state change from the engine sounds its cue
+func TestCueEngine(t *testing.T) {
+	mu.Lock()
+	setCues(map[string]string{"Record": "bell"})
+	mu.Unlock()
+	sim := startSim(t, "127.0.0.1:0", 1)
+	sl = startClient(t, sim)
+	defer func() {
+		mu.Lock()
+		sl = nil
+		setCues(nil)
+		mu.Unlock()
+	}()
+	eventually(t, "a loop", func() bool { return loopCount == 1 && getLoopState(0).haveState })
+
+	sim.Handle(hitMessage(0, "record"))
+	select {
+	case c := <-cues:
+		if c.event != "Record" || c.args != nil {
+			t.Errorf("cue %q %q, want Record on the bell", c.event, c.args)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("no cue for the record")
+	}
+}
@@ -0,0 +1,55 @@
+// cycles.go
+// Cycle bars: --pos-style cycles shows how many cycles each loop holds and
+// a bar with one segment per cycle, lit up to the one playing, so loops of
+// different multiples can be followed side by side.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	// cycleBarMax is the most segments a cycle bar has. Longer loops share
+	// each segment between several cycles.
+	cycleBarMax = 12
+	// cyclePosWidth is the width of the Pos column showing cycle bars: the
+	// count, e.g. " 16× ", and the bar.
+	cyclePosWidth = 5 + cycleBarMax + 1
+)
+
+// cycleCount is how many cycles of cycleLen a loop of loopLen holds, at
+// least one, or 0 for an empty loop. Without a cycle length from the
+// engine, the loop is one cycle.
+func cycleCount(loopLen, cycleLen float32) int {
+	switch {
+	case loopLen <= 0:
+		return 0
+	case cycleLen <= 0:
+		return 1
+	}
+	return max(int(math.Round(float64(loopLen/cycleLen))), 1)
+}
+
+// cyclesCell shows the cycle count of a loop and its cycle bar at pos:
+// cycles played in green, the one playing in yellow, the rest dim.
+func cyclesCell(pos, loopLen, cycleLen float32) cell {
+	n := cycleCount(loopLen, cycleLen)
+	if n == 0 {
+		return textCell(" – ", tcell.ColorDefault)
+	}
+	per := (n + cycleBarMax - 1) / cycleBarMax // cycles per segment
+	segments := (n + per - 1) / per
+	current := min(max(int(float64(pos)/float64(loopLen)*float64(n)), 0), n-1) / per
+	return cell{Spans: []span{
+		{Text: fmt.Sprintf(" %d× ", n)},
+		{Text: strings.Repeat("▰", current), Color: tcell.ColorGreen},
+		{Text: "▰", Color: tcell.ColorYellow, Bold: true},
+		{Text: strings.Repeat("▱", segments-current-1), Color: tcell.ColorGray},
+	}, Align: tview.AlignLeft}
+}
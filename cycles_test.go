@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// TestCycleCount tests counting cycles in a loop
+func TestCycleCount(t *testing.T) {
+	for _, tc := range []struct {
+		loopLen, cycleLen float32
+		want              int
+	}{
+		{0, 2, 0},
+		{4, 0, 1},
+		{2, 2, 1},
+		{6, 2, 3},
+		{5.99, 2, 3},
+		{1, 2, 1},
+	} {
+		if got := cycleCount(tc.loopLen, tc.cycleLen); got != tc.want {
+			t.Errorf("cycleCount(%g, %g) = %d, want %d", tc.loopLen, tc.cycleLen, got, tc.want)
+		}
+	}
+}
+
+// TestCyclesCell tests the cycle bar as the playhead moves
+func TestCyclesCell(t *testing.T) {
+	for _, tc := range []struct {
+		pos, loopLen, cycleLen float32
+		want                   string
+	}{
+		{0, 0, 0, " – "},
+		{0.5, 6, 2, " 3× ▰▱▱"},
+		{2.5, 6, 2, " 3× ▰▰▱"},
+		{5.9, 6, 2, " 3× ▰▰▰"},
+		{7, 6, 2, " 3× ▰▰▰"},
+		// 24 cycles, two per segment: the 11th cycle is in the 6th.
+		{10.5, 24, 1, " 24× ▰▰▰▰▰▰▱▱▱▱▱▱"},
+	} {
+		c := cyclesCell(tc.pos, tc.loopLen, tc.cycleLen)
+		if got := c.text(); got != tc.want {
+			t.Errorf("pos %g of %g in cycles of %g: %q, want %q", tc.pos, tc.loopLen, tc.cycleLen, got, tc.want)
+		}
+	}
+	c := cyclesCell(2.5, 6, 2)
+	if c.Spans[1].Color != tcell.ColorGreen || c.Spans[2].Color != tcell.ColorYellow {
+		t.Errorf("spans %+v, want played cycles green and the playing one yellow", c.Spans)
+	}
+}
@@ -0,0 +1,141 @@
+// demo.go
+// Built-in fake engine for --demo, driving the UI without SooperLooper.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+const (
+	demoLoops = 4
+	demoTick  = 50 * time.Millisecond
+)
+
+// demoStep is one stage of the scripted loop life cycle.
+type demoStep struct {
+	state, next int
+	dur         time.Duration
+}
+
+var demoScript = []demoStep{
+	{0, -1, 2 * time.Second},
+	{1, 2, 500 * time.Millisecond},
+	{2, 4, 4 * time.Second},
+	{4, -1, 6 * time.Second},
+	{4, 5, 500 * time.Millisecond},
+	{5, 4, 3 * time.Second},
+	{4, -1, 4 * time.Second},
+	{4, 10, 500 * time.Millisecond},
+	{10, 4, 2 * time.Second},
+	{4, -1, 3 * time.Second},
+}
+
+// demoEngine produces the OSC messages SooperLooper would send, so the demo
+// exercises the same handleOSC path as a real engine.
+type demoEngine struct {
+	start time.Time
+	rng   *rand.Rand
+}
+
+func newDemoEngine(start time.Time) *demoEngine {
+	return &demoEngine{start: start, rng: rand.New(rand.NewSource(1))}
+}
+
+func (d *demoEngine) hello() *osc.Message {
+	m := osc.NewMessage("/pong")
+	m.Append("osc.udp://demo:0", "demo", int32(demoLoops))
+	return m
+}
+
+func (d *demoEngine) tick(now time.Time) []*osc.Message {
+	var out []*osc.Message
+	cycle := demoScriptLength()
+	for i := 0; i < demoLoops; i++ {
+		// Stagger the loops so they are not all in the same state.
+		t := now.Sub(d.start) + time.Duration(i)*cycle/demoLoops
+		step := demoStepAt(t % cycle)
+		secs := t.Seconds()
+
+		var in, outLevel float32
+		switch step.state {
+		case 2, 5:
+			in = d.envelope(secs, 3.1+float64(i))
+		}
+		switch step.state {
+		case 4, 5:
+			outLevel = d.envelope(secs, 2.3+float64(i)*0.7)
+		}
+		loopLen := 4 + float64(i)
+		pos := float32(math.Mod(secs, loopLen))
+
+		out = append(out,
+			demoUpdate(i, "state", float32(step.state)),
+			demoUpdate(i, "next_state", float32(step.next)),
+			demoUpdate(i, "loop_pos", pos),
+			demoUpdate(i, "loop_len", float32(loopLen)),
+			demoUpdate(i, "in_peak_meter", in),
+			demoUpdate(i, "out_peak_meter", outLevel),
+		)
+	}
+	return out
+}
+
+// envelope is a pulsing level with some jitter, occasionally peaking near
+// full scale so the meter colours and clip tick get exercised.
+func (d *demoEngine) envelope(secs, rate float64) float32 {
+	beat := math.Pow(math.Abs(math.Sin(secs*rate)), 3)
+	return float32(0.05 + 0.85*beat + 0.1*d.rng.Float64())
+}
+
+func demoUpdate(loop int, control string, v float32) *osc.Message {
+	m := osc.NewMessage(fmt.Sprintf("/sl/%d/update_%s", loop, control))
+	m.Append(int32(loop), control, v)
+	return m
+}
+
+func demoStepAt(t time.Duration) demoStep {
+	for _, s := range demoScript {
+		if t < s.dur {
+			return s
+		}
+		t -= s.dur
+	}
+	return demoScript[len(demoScript)-1]
+}
+
+func demoScriptLength() time.Duration {
+	var total time.Duration
+	for _, s := range demoScript {
+		total += s.dur
+	}
+	return total
+}
+
+// runDemo plays the demo engine into handleOSC until ctx is done.
+func runDemo(ctx context.Context) error {
+	d := newDemoEngine(time.Now())
+	handleOSC(d.hello())
+	t := time.NewTicker(demoTick)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-t.C:
+			for _, m := range d.tick(now) {
+				trace.add(false, m)
+				handleOSC(m)
+			}
+			mu.Lock()
+			foldLoops()
+			mu.Unlock()
+		}
+	}
+}
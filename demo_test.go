@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"jaudio/internal/slstate"
+)
+
+// TestDemoEngine tests that the fake engine drives every loop through record and play via handleOSC
+func TestDemoEngine(t *testing.T) {
+	mu.Lock()
+	loopStates = make(map[int]*LoopState)
+	mu.Unlock()
+
+	start := time.Now()
+	d := newDemoEngine(start)
+	handleOSC(d.hello())
+	if loopCount != demoLoops {
+		t.Fatalf("loopCount = %d after hello, want %d", loopCount, demoLoops)
+	}
+
+	seen := make(map[int]map[slstate.State]bool)
+	for now := start; now.Sub(start) < demoScriptLength(); now = now.Add(demoTick) {
+		for _, m := range d.tick(now) {
+			handleOSC(m)
+		}
+		mu.Lock()
+		foldLoops()
+		mu.Unlock()
+		for i := 0; i < demoLoops; i++ {
+			ls := loopStates[i]
+			if seen[i] == nil {
+				seen[i] = make(map[slstate.State]bool)
+			}
+			seen[i][ls.State] = true
+			if ls.State == slstate.Record && ls.InPeakMeter <= 0 {
+				t.Errorf("loop %d recording with silent input meter", i)
+			}
+		}
+	}
+	for i := 0; i < demoLoops; i++ {
+		for _, state := range []slstate.State{slstate.Record, slstate.Play, slstate.Overdub, slstate.Mute} {
+			if !seen[i][state] {
+				t.Errorf("loop %d never reached state %s", i, state)
+			}
+		}
+	}
+	if len(history.events) == 0 {
+		t.Errorf("no state transitions recorded")
+	}
+}
@@ -0,0 +1,210 @@
+// detail.go
+// The Loop page: every control of the selected loop, editable.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+type detailKind int
+
+const (
+	detailNumber detailKind = iota
+	detailToggle
+	detailChoice
+	detailReadOnly // lengths and times, in seconds
+)
+
+// detailControl is one row of the Loop page.
+type detailControl struct {
+	Name           string
+	Kind           detailKind
+	Min, Max, Step float32
+	Choices        []string // detailChoice values 0, 1, ...
+}
+
+// detailControls are the loop controls on the Loop page, in order.
+var detailControls = []detailControl{
+	{Name: "wet", Max: 1, Step: 0.05},
+	{Name: "dry", Max: 1, Step: 0.05},
+	{Name: "feedback", Max: 1, Step: 0.05},
+	{Name: "input_gain", Max: 1, Step: 0.05},
+	{Name: "rate", Min: 0.25, Max: 4, Step: 0.05},
+	{Name: "stretch_ratio", Min: 0.5, Max: 4, Step: 0.05},
+	{Name: "pitch_shift", Min: -12, Max: 12, Step: 1},
+	{Name: "pan_1", Max: 1, Step: 0.05},
+	{Name: "pan_2", Max: 1, Step: 0.05},
+	{Name: "quantize", Kind: detailChoice, Choices: []string{"off", "cycle", "8th", "loop"}},
+	{Name: "sync", Kind: detailToggle},
+	{Name: "playback_sync", Kind: detailToggle},
+	{Name: "relative_sync", Kind: detailToggle},
+	{Name: "round", Kind: detailToggle},
+	{Name: "mute_quantized", Kind: detailToggle},
+	{Name: "overdub_quantized", Kind: detailToggle},
+	{Name: "use_feedback_play", Kind: detailToggle},
+	{Name: "loop_len", Kind: detailReadOnly},
+	{Name: "cycle_len", Kind: detailReadOnly},
+	{Name: "free_time", Kind: detailReadOnly},
+	{Name: "total_time", Kind: detailReadOnly},
+}
+
+const detailHistoryLines = 8
+
+// isLoopControl reports whether updates of ctrl are kept in the loop's
+// controls: the scene controls and those on the Loop page.
+func isLoopControl(ctrl string) bool {
+	return slices.Contains(sceneControls, ctrl) || slices.Contains(previewControls, ctrl) ||
+		slices.ContainsFunc(detailControls, func(d detailControl) bool { return d.Name == ctrl })
+}
+
+// format shows a value of the control.
+func (d detailControl) format(v float32, ok bool) string {
+	if !ok {
+		return "–"
+	}
+	switch d.Kind {
+	case detailToggle:
+		if v != 0 {
+			return "on"
+		}
+		return "off"
+	case detailChoice:
+		if i := int(v); i >= 0 && i < len(d.Choices) {
+			return d.Choices[i]
+		}
+	case detailReadOnly:
+		return fmt.Sprintf("%.2f s", v)
+	}
+	return strconv.FormatFloat(float64(v), 'g', 4, 32)
+}
+
+// step returns the value dir (+1 or -1) steps from v: a nudge for
+// numbers, the next choice, or the other toggle state.
+func (d detailControl) step(v float32, dir int) float32 {
+	switch d.Kind {
+	case detailToggle:
+		if v != 0 {
+			return 0
+		}
+		return 1
+	case detailChoice:
+		n := len(d.Choices)
+		return float32(((int(v)+dir)%n + n) % n)
+	case detailNumber:
+		return min(max(v+float32(dir)*d.Step, d.Min), d.Max)
+	}
+	return v
+}
+
+// parse reads a typed value: a number, or a choice or on/off by name.
+func (d detailControl) parse(s string) (float32, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch d.Kind {
+	case detailToggle:
+		switch s {
+		case "on", "1", "true":
+			return 1, nil
+		case "off", "0", "false":
+			return 0, nil
+		}
+		return 0, fmt.Errorf("%s is on or off", d.Name)
+	case detailChoice:
+		for i, c := range d.Choices {
+			if s == c {
+				return float32(i), nil
+			}
+		}
+		return 0, fmt.Errorf("%s is one of %s", d.Name, strings.Join(d.Choices, ", "))
+	case detailReadOnly:
+		return 0, fmt.Errorf("%s cannot be set", d.Name)
+	}
+	f, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%s needs a number", d.Name)
+	}
+	if v := float32(f); v < d.Min || v > d.Max {
+		return 0, fmt.Errorf("%s must be between %g and %g", d.Name, d.Min, d.Max)
+	}
+	return float32(f), nil
+}
+
+// detailHeaderText is the top of the Loop page. The caller must hold mu.
+func detailHeaderText(i int, ls *LoopState) string {
+	if !ls.haveState {
+		return " [::b]" + loopLabel(i) + "[::-]  " + tr("no state from the engine yet")
+	}
+	text := " [::b]" + loopLabel(i) + "[::-]  " + fmt.Sprintf("%s → %s  %.2f s  ", ls.State, ls.NextState, ls.LoopPos) + trf("Level %.3f", ls.Wet)
+	if name, ok := linkGroups[i]; ok {
+		text += "  " + trf("Group %s", tview.Escape(name))
+	}
+	if trim := meterTrimText(i); trim != "" {
+		text += "  " + trim
+	}
+	return text
+}
+
+// loopHistoryText lists the loop's recent state transitions. The caller
+// must hold mu.
+func loopHistoryText(h *stateHistory, loop, n int) string {
+	var b strings.Builder
+	for _, e := range h.recentFor(loop, n) {
+		b.WriteString(" " + e.String() + "\n")
+	}
+	if b.Len() == 0 {
+		return " " + tr("No state changes yet.") + "\n"
+	}
+	return b.String()
+}
+
+// detailValue returns loop i's value of the control, if known. The caller
+// must hold mu.
+func detailValue(i int, d detailControl) (float32, bool) {
+	v, ok := getLoopState(i).controls[d.Name]
+	return v, ok
+}
+
+// setDetailControl sets a control of loop i from the Loop page, as a change
+// that can be undone once the engine has reported the value before.
+func setDetailControl(i int, d detailControl, v float32) {
+	if d.Kind == detailReadOnly {
+		return
+	}
+	mu.Lock()
+	if from, ok := detailValue(i, d); ok {
+		guiUndo.record(guiChange{At: time.Now(), Loop: i, Control: d.Name, From: from, To: v})
+	}
+	mu.Unlock()
+	sendGUIValue(i, d.Name, v)
+}
+
+// detailRow is the Loop page row for a control: name, value and, for
+// numbers, a bar showing where the value sits in its range.
+func detailRow(d detailControl, v float32, ok bool, barWidth int) []cell {
+	valueColor := tcell.ColorYellow
+	if d.Kind == detailReadOnly {
+		valueColor = tcell.ColorGray
+	}
+	row := []cell{
+		textCell(" "+d.Name, tcell.ColorDefault),
+		textCell(d.format(v, ok), valueColor),
+		textCell("", tcell.ColorDefault),
+	}
+	if d.Kind == detailNumber && ok {
+		n := int(math.Round(float64((v - d.Min) / (d.Max - d.Min) * float32(barWidth))))
+		n = min(max(n, 0), barWidth)
+		row[2] = textCell(barText(n, barWidth, '░'), tcell.ColorGreen)
+	}
+	for k := range row {
+		row[k].Align = tview.AlignLeft
+	}
+	return row
+}
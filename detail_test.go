@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+)
+
+func detailControlNamed(name string) detailControl {
+	for _, d := range detailControls {
+		if d.Name == name {
+			return d
+		}
+	}
+	panic(name)
+}
+
+// TestDetailControlStep tests nudging, cycling and toggling Loop page controls
+func TestDetailControlStep(t *testing.T) {
+	tests := []struct {
+		name string
+		v    float32
+		dir  int
+		want float32
+	}{
+		{"rate", 1, 1, 1.05},
+		{"rate", 0.25, -1, 0.25},
+		{"pitch_shift", 12, 1, 12},
+		{"quantize", 3, 1, 0},
+		{"quantize", 0, -1, 3},
+		{"sync", 0, -1, 1},
+		{"sync", 1, 1, 0},
+		{"loop_len", 4, 1, 4},
+	}
+	for _, tt := range tests {
+		if got := detailControlNamed(tt.name).step(tt.v, tt.dir); got != tt.want {
+			t.Errorf("%s.step(%v, %d) = %v, want %v", tt.name, tt.v, tt.dir, got, tt.want)
+		}
+	}
+}
+
+// TestDetailControlParse tests typed values on the Loop page
+func TestDetailControlParse(t *testing.T) {
+	good := []struct {
+		name, text string
+		want       float32
+	}{
+		{"feedback", " 0.5 ", 0.5},
+		{"quantize", "8th", 2},
+		{"sync", "ON", 1},
+		{"round", "0", 0},
+	}
+	for _, tt := range good {
+		if got, err := detailControlNamed(tt.name).parse(tt.text); err != nil || got != tt.want {
+			t.Errorf("%s.parse(%q) = %v, %v, want %v", tt.name, tt.text, got, err, tt.want)
+		}
+	}
+	for _, bad := range []struct{ name, text string }{
+		{"feedback", "1.5"},
+		{"rate", "fast"},
+		{"quantize", "bar"},
+		{"sync", "maybe"},
+		{"loop_len", "4"},
+	} {
+		if _, err := detailControlNamed(bad.name).parse(bad.text); err == nil {
+			t.Errorf("%s.parse(%q) succeeded", bad.name, bad.text)
+		}
+	}
+}
+
+// TestDetailRow tests the Loop page rows
+func TestDetailRow(t *testing.T) {
+	tests := []struct {
+		name       string
+		v          float32
+		ok         bool
+		value, bar string
+	}{
+		{"pan_1", 0.5, true, "0.5", "█████░░░░░"},
+		{"pan_1", 0, false, "–", ""},
+		{"quantize", 1, true, "cycle", ""},
+		{"sync", 1, true, "on", ""},
+		{"cycle_len", 2.5, true, "2.50 s", ""},
+	}
+	for _, tt := range tests {
+		row := detailRow(detailControlNamed(tt.name), tt.v, tt.ok, 10)
+		if row[0].text() != " "+tt.name || row[1].text() != tt.value || row[2].text() != tt.bar {
+			t.Errorf("detailRow(%s, %v) = %q %q %q, want value %q bar %q", tt.name, tt.v, row[0].text(), row[1].text(), row[2].text(), tt.value, tt.bar)
+		}
+	}
+}
+
+// TestDetailControlsPolled tests that the Loop page's controls are polled for the selected loop
+func TestDetailControlsPolled(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 2)
+	mu.Lock()
+	currentPage, selectedLoop = pageLoop, 1
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		currentPage, selectedLoop = pageMixer, 0
+		mu.Unlock()
+	}()
+	startClient(t, sim)
+	eventually(t, "loop 2 rate", func() bool {
+		if ls := loopStates[1]; ls != nil {
+			_, ok := ls.controls["rate"]
+			return ok
+		}
+		return false
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := getLoopState(0).controls["rate"]; ok {
+		t.Error("polled rate of a loop not on the Loop page")
+	}
+}
@@ -0,0 +1,209 @@
+// diag.go
+// Runtime diagnostics: --pprof serves net/http/pprof, and at
+// /debug/sooperGUI a page of sooperGUI's own figures (goroutines, the
+// depths of the OSC queues, and how long redraws take), for profiling
+// stutter during long sessions. With --debug the status bar shows the 95th
+// percentiles of the table updates and OSC handling, and the log warns when
+// a redraw takes longer than the refresh interval.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"slices"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// pprofAddr (--pprof) is where the diagnostics are served, if anywhere.
+var pprofAddr = ""
+
+// timingSamples is how many of the latest durations timingStats keeps.
+const timingSamples = 256
+
+// timingStats keeps the latest durations of something done over and over,
+// such as a redraw.
+type timingStats struct {
+	mu      sync.Mutex
+	samples [timingSamples]time.Duration
+	n       int // ever added
+	max     time.Duration
+}
+
+// timingSummary sums up the durations kept.
+type timingSummary struct {
+	Count          int
+	Last           time.Duration
+	Mean, P95, Max time.Duration
+}
+
+var (
+	// tableTimes are how long updateTable takes.
+	tableTimes timingStats
+	// drawTimes are how long drawing the screen takes.
+	drawTimes timingStats
+	// oscTimes are how long handleOSC takes, waiting for mu included.
+	oscTimes timingStats
+)
+
+// frameWarnEvery is how often at most the log warns of redraws over the
+// refresh interval.
+const frameWarnEvery = 10 * time.Second
+
+var (
+	// frameOverruns counts redraws over the refresh interval since the last
+	// warning. Owned by the TUI goroutine, as is frameWarned.
+	frameOverruns int
+	frameWarned   time.Time
+)
+
+func (s *timingStats) add(d time.Duration) {
+	s.mu.Lock()
+	s.samples[s.n%timingSamples] = d
+	s.n++
+	s.max = max(s.max, d)
+	s.mu.Unlock()
+}
+
+// since adds the time since start, for deferring.
+func (s *timingStats) since(start time.Time) {
+	s.add(time.Since(start))
+}
+
+// summary is the last duration, the mean and 95th percentile of those kept,
+// and the longest ever.
+func (s *timingStats) summary() timingSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.n == 0 {
+		return timingSummary{}
+	}
+	kept := slices.Clone(s.samples[:min(s.n, timingSamples)])
+	var total time.Duration
+	for _, d := range kept {
+		total += d
+	}
+	slices.Sort(kept)
+	return timingSummary{
+		Count: s.n,
+		Last:  s.samples[(s.n-1)%timingSamples],
+		Mean:  total / time.Duration(len(kept)),
+		P95:   kept[(len(kept)*95+99)/100-1],
+		Max:   s.max,
+	}
+}
+
+// ms is d in milliseconds.
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func (t timingSummary) String() string {
+	if t.Count == 0 {
+		return "none yet"
+	}
+	return fmt.Sprintf("%d, last %.2f ms, mean %.2f ms, p95 %.2f ms, max %.2f ms", t.Count, ms(t.Last), ms(t.Mean), ms(t.P95), ms(t.Max))
+}
+
+// frameStatus is the status bar's 95th percentiles of table updates and
+// OSC handling, shown with --debug.
+func frameStatus() string {
+	return trf("table p95 %.2f ms  osc p95 %.2f ms", ms(tableTimes.summary().P95), ms(oscTimes.summary().P95))
+}
+
+// checkFrameBudget warns, at most every frameWarnEvery, of redraws that
+// took longer than the refresh interval. Called on the TUI goroutine.
+func checkFrameBudget(took, budget time.Duration, now time.Time) {
+	if took <= budget {
+		return
+	}
+	frameOverruns++
+	if now.Sub(frameWarned) < frameWarnEvery {
+		return
+	}
+	tuiLog.Warn("redraw over the refresh interval", "took", took.Round(10*time.Microsecond), "refresh", budget, "overruns", frameOverruns)
+	frameOverruns = 0
+	frameWarned = now
+}
+
+// depths are how many packets wait in each worker's queue.
+func (q *sendQueue) depths() []int {
+	out := make([]int, len(q.queues))
+	for i, ch := range q.queues {
+		out[i] = len(ch)
+	}
+	return out
+}
+
+// backlog is how many critical commands await confirmation and sets await
+// their echoes.
+func (c *SLClient) backlog() (commands, sets int) {
+	c.confirms.mu.Lock()
+	commands = len(c.confirms.pending)
+	c.confirms.mu.Unlock()
+	c.sets.mu.Lock()
+	sets = len(c.sets.pending)
+	c.sets.mu.Unlock()
+	return commands, sets
+}
+
+// newDiagHandler serves net/http/pprof under /debug/pprof/ and the
+// figures page at /debug/sooperGUI.
+func newDiagHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("GET /debug/sooperGUI", handleDiag)
+	mux.Handle("GET /{$}", http.RedirectHandler("/debug/sooperGUI", http.StatusFound))
+	return mux
+}
+
+func handleDiag(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "goroutines\t%d\n", runtime.NumGoroutine())
+	fmt.Fprintf(tw, "heap\t%.1f MB in use, %d GCs\n", float64(mem.HeapInuse)/(1<<20), mem.NumGC)
+	if c := sl; c != nil {
+		commands, sets := c.backlog()
+		fmt.Fprintf(tw, "send queues\t%v of %d each\n", c.out.depths(), sendQueueLen)
+		fmt.Fprintf(tw, "unconfirmed\t%d commands, %d sets\n", commands, sets)
+	} else {
+		fmt.Fprintln(tw, "send queues\tnot connected")
+	}
+	mu.Lock()
+	rate, rejected, n := refreshRate, rejectedLoopUpdates, len(loopStates)
+	mu.Unlock()
+	fmt.Fprintf(tw, "refresh\tevery %d ms\n", rate)
+	fmt.Fprintf(tw, "loop states\t%d kept, %d updates for unknown loops dropped\n", n, rejected)
+	fmt.Fprintf(tw, "table updates\t%v\n", tableTimes.summary())
+	fmt.Fprintf(tw, "screen draws\t%v\n", drawTimes.summary())
+	fmt.Fprintf(tw, "OSC handling\t%v\n", oscTimes.summary())
+	fmt.Fprintln(tw, "profiles\t/debug/pprof/")
+	tw.Flush()
+}
+
+// startDiag serves the diagnostics on --pprof, if given.
+func startDiag() {
+	if pprofAddr == "" {
+		return
+	}
+	if host, _, err := net.SplitHostPort(pprofAddr); err == nil {
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			httpLog.Warn("diagnostics reachable from other machines", "addr", pprofAddr)
+		}
+	}
+	goService("diagnostics", func(ctx context.Context) error {
+		return serveHandler(ctx, pprofAddr, newDiagHandler(), "diagnostics listening")
+	})
+}
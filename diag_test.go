@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTimingStats tests the summary of kept durations
+func TestTimingStats(t *testing.T) {
+	var s timingStats
+	if got := s.summary().String(); got != "none yet" {
+		t.Errorf("empty summary = %q", got)
+	}
+	s.add(40 * time.Millisecond)
+	for range timingSamples {
+		s.add(2 * time.Millisecond)
+	}
+	s.add(4 * time.Millisecond)
+	got := s.summary()
+	// The 40 ms sample has been overwritten, but stays the longest.
+	want := timingSummary{Count: timingSamples + 2, Last: 4 * time.Millisecond, Mean: 2*time.Millisecond + 2*time.Millisecond/timingSamples, P95: 2 * time.Millisecond, Max: 40 * time.Millisecond}
+	if got != want {
+		t.Errorf("summary = %+v, want %+v", got, want)
+	}
+	if str := got.String(); !strings.Contains(str, "last 4.00 ms") || !strings.Contains(str, "max 40.00 ms") {
+		t.Errorf("String() = %q", str)
+	}
+
+	var p timingStats
+	for i := 100; i >= 1; i-- {
+		p.add(time.Duration(i) * time.Millisecond)
+	}
+	if got := p.summary().P95; got != 95*time.Millisecond {
+		t.Errorf("p95 of 1 to 100 ms = %v", got)
+	}
+}
+
+// TestCheckFrameBudget tests that redraws over the refresh interval are
+// counted and warned of at most every frameWarnEvery
+func TestCheckFrameBudget(t *testing.T) {
+	defer func() { frameOverruns, frameWarned = 0, time.Time{} }()
+	start := time.Now()
+	checkFrameBudget(100*time.Millisecond, 200*time.Millisecond, start)
+	if frameOverruns != 0 || !frameWarned.IsZero() {
+		t.Fatalf("redraw within budget counted: %d, %v", frameOverruns, frameWarned)
+	}
+	checkFrameBudget(300*time.Millisecond, 200*time.Millisecond, start)
+	if frameOverruns != 0 || !frameWarned.Equal(start) {
+		t.Fatalf("first overrun not warned of: %d, %v", frameOverruns, frameWarned)
+	}
+	checkFrameBudget(300*time.Millisecond, 200*time.Millisecond, start.Add(time.Second))
+	checkFrameBudget(300*time.Millisecond, 200*time.Millisecond, start.Add(2*time.Second))
+	if frameOverruns != 2 || !frameWarned.Equal(start) {
+		t.Errorf("overruns within %v = %d, warned %v", frameWarnEvery, frameOverruns, frameWarned)
+	}
+	checkFrameBudget(300*time.Millisecond, 200*time.Millisecond, start.Add(frameWarnEvery))
+	if frameOverruns != 0 || !frameWarned.Equal(start.Add(frameWarnEvery)) {
+		t.Errorf("overruns after %v = %d, warned %v", frameWarnEvery, frameOverruns, frameWarned)
+	}
+}
+
+// TestDiagHandler tests the diagnostics page and the pprof index
+func TestDiagHandler(t *testing.T) {
+	sl = startClient(t, startSim(t, "127.0.0.1:0", 1))
+	defer func() { sl = nil }()
+	tableTimes.add(3 * time.Millisecond)
+
+	h := newDiagHandler()
+	rec := restRequest(t, h, "GET", "/debug/sooperGUI", "")
+	for _, want := range []string{"goroutines", "send queues", "of 256 each", "0 commands, 0 sets", "last 3.00 ms", "screen draws"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("GET /debug/sooperGUI lacks %q:\n%s", want, rec.Body)
+		}
+	}
+	if rec := restRequest(t, h, "GET", "/debug/pprof/", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine") {
+		t.Errorf("GET /debug/pprof/ = %d", rec.Code)
+	}
+	if rec := restRequest(t, h, "GET", "/", ""); rec.Code != http.StatusFound {
+		t.Errorf("GET / = %d", rec.Code)
+	}
+}
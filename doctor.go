@@ -0,0 +1,307 @@
+// doctor.go
+// sooperGUI doctor: checks step by step that the engine can be reached and
+// that its replies come back, then prints a diagnosis, to triage "nothing
+// updates" reports. It resolves the engine's host, finds the interface the
+// route to it leaves from, pings the engine, checks its version, and asks
+// it to echo a global and a loop control to the reply port.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// doctorWait is how long each check waits for the engine by default.
+const doctorWait = 2 * time.Second
+
+// doctorReport prints the checks' results and collects hints for the
+// diagnosis.
+type doctorReport struct {
+	w      io.Writer
+	failed bool
+	hints  []string
+}
+
+func (r *doctorReport) ok(format string, args ...any) {
+	fmt.Fprintf(r.w, "  ok    "+format+"\n", args...)
+}
+
+func (r *doctorReport) warn(format string, args ...any) {
+	fmt.Fprintf(r.w, "  warn  "+format+"\n", args...)
+}
+
+func (r *doctorReport) fail(format string, args ...any) {
+	r.failed = true
+	fmt.Fprintf(r.w, "  FAIL  "+format+"\n", args...)
+}
+
+func (r *doctorReport) hint(format string, args ...any) {
+	r.hints = append(r.hints, fmt.Sprintf(format, args...))
+}
+
+// diagnose prints the hints, or that all is well.
+func (r *doctorReport) diagnose(addr string) {
+	fmt.Fprintln(r.w, "\nDiagnosis:")
+	if len(r.hints) == 0 {
+		r.hints = []string{fmt.Sprintf("The engine at %s is reachable and its replies arrive. If sooperGUI still shows nothing, run it with --debug and look for \"in\" lines in the log.", addr)}
+	}
+	for _, h := range r.hints {
+		fmt.Fprint(r.w, wrapText(h, "  - ", "    ", 78))
+	}
+}
+
+// wrapText wraps s at width, starting with first and going on with indent.
+func wrapText(s, first, indent string, width int) string {
+	var b strings.Builder
+	line := first
+	for i, word := range strings.Fields(s) {
+		if i > 0 && len(line)+1+len(word) > width {
+			b.WriteString(line + "\n")
+			line = indent + word
+			continue
+		}
+		if i > 0 {
+			line += " "
+		}
+		line += word
+	}
+	b.WriteString(line + "\n")
+	return b.String()
+}
+
+// doctor runs sooperGUI doctor with args, returning the exit code.
+func doctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.StringVar(&oscHost, "osc-host", oscHost, "OSC host of the engine")
+	fs.IntVar(&oscPort, "osc-port", oscPort, "OSC UDP port of the engine")
+	fs.IntVar(&listenPort, "listen-port", listenPort, "UDP port for the engine's replies (0 picks a free port)")
+	fs.StringVar(&returnHost, "return-host", returnHost, "Host the engine sends replies to")
+	fs.IntVar(&returnPort, "return-port", returnPort, "Port the engine sends replies to")
+	fs.StringVar(&configFile, "config", configFile, "Config file with the engine's address")
+	fs.StringVar(&profileName, "profile", profileName, "Profile of the config file to use")
+	wait := fs.Duration("wait", doctorWait, "How long to wait for each reply")
+	if err := fs.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return 0
+	} else if err != nil {
+		return 2
+	}
+
+	console.Set(nil)
+	named := configFile != ""
+	if !named {
+		configFile = defaultConfigPath()
+	}
+	cfg, err := loadConfig(configFile, named)
+	if err == nil {
+		var p namedProfile
+		p, err = cfg.useProfile(profileName)
+		useEngine(cfg.Engine, fs)
+		useEngine(p.Engine, fs)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sooperGUI doctor:", err)
+		return 1
+	}
+
+	r := &doctorReport{w: os.Stdout}
+	addr := net.JoinHostPort(oscHost, strconv.Itoa(oscPort))
+	fmt.Fprintf(r.w, "sooperGUI doctor: engine at %s\n", addr)
+	r.run(oscHost, oscPort, *wait)
+	r.diagnose(addr)
+	if r.failed {
+		return 1
+	}
+	return 0
+}
+
+// run checks the engine at host and port, waiting up to wait for each
+// reply.
+func (r *doctorReport) run(host string, port int, wait time.Duration) {
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		r.fail("cannot resolve %s: %v", host, err)
+		r.hint("Check --osc-host, or the engine section of the config file.")
+		return
+	}
+	r.ok("%s resolves to %s", host, addr.IP)
+
+	// The probe socket is connected, so an ICMP port unreachable for what
+	// it sends comes back as an error on it.
+	probe, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		r.fail("no route to %s: %v", addr.IP, err)
+		r.hint("This machine has no route to the engine's host: check the network and --osc-host.")
+		return
+	}
+	defer probe.Close()
+	routeIP := probe.LocalAddr().(*net.UDPAddr).IP
+	r.ok("the route to the engine leaves from %s", routeIP)
+
+	conn, err := net.ListenPacket("udp", ":"+strconv.Itoa(listenPort))
+	if err != nil {
+		r.fail("cannot listen for replies on UDP port %d: %v", listenPort, err)
+		r.hint("Another program, maybe another sooperGUI, has UDP port %d: stop it, or use another --listen-port.", listenPort)
+		return
+	}
+	defer conn.Close()
+	localPort := conn.LocalAddr().(*net.UDPAddr).Port
+	returnURL := replyURL(host, localPort)
+	r.ok("replies are asked for at %s", returnURL)
+	if u, err := net.ResolveUDPAddr("udp", strings.TrimPrefix(returnURL, "osc.udp://")); err == nil && returnHost == "" && !u.IP.Equal(routeIP) {
+		r.warn("replies go to %s, but the route to the engine leaves from %s", u.IP, routeIP)
+		r.hint("If the engine cannot reach %s, run sooperGUI with --return-host %s.", u.IP, routeIP)
+	}
+
+	refused := make(chan bool, 1)
+	go func() {
+		probe.SetReadDeadline(time.Now().Add(wait))
+		_, err := probe.Read(make([]byte, 1))
+		refused <- errors.Is(err, syscall.ECONNREFUSED)
+	}()
+	sent := time.Now()
+	if err := doctorSend(probe, osc.NewMessage("/ping", returnURL, "/doctor/pong")); err != nil {
+		r.fail("cannot send to %s: %v", addr, err)
+		r.hint("Sending to the engine failed here: check the network and --osc-host.")
+		return
+	}
+	pong, err := doctorAwait(conn, "/doctor/pong", wait)
+	if err != nil {
+		if <-refused {
+			r.fail("nothing listens on UDP %s (port unreachable)", addr)
+			r.hint("Start the engine with sooperlooper -p %d, or let sooperGUI start it with --spawn-engine, or point --osc-port at the port it uses.", port)
+			return
+		}
+		r.fail("no reply to /ping within %s", wait)
+		r.noReplyHints(addr, localPort)
+		return
+	}
+	r.ok("the engine answered /ping in %s", time.Since(sent).Round(100*time.Microsecond))
+	r.checkVersion(pong)
+
+	loops := 0
+	if len(pong.Arguments) > 2 {
+		if n, ok := pong.Arguments[2].(int32); ok {
+			loops = int(n)
+		}
+	}
+	doctorSend(probe, osc.NewMessage("/get", "tempo", returnURL, "/doctor/echo"))
+	if _, err := doctorAwait(conn, "/doctor/echo", wait); err != nil {
+		r.fail("the engine answers /ping but did not echo the tempo")
+		r.hint("The engine does not answer gets: it may not be SooperLooper, or too old for sooperGUI.")
+		return
+	}
+	r.ok("the engine echoed the tempo")
+	if loops == 0 {
+		r.warn("the engine has no loops, so no loop could echo")
+		r.hint("The engine has no loops: add some in SooperLooper, with --engine-loops, or with the config file's profile loop_count.")
+		return
+	}
+	doctorSend(probe, osc.NewMessage("/sl/0/get", "loop_pos", returnURL, "/doctor/loop"))
+	if _, err := doctorAwait(conn, "/doctor/loop", wait); err != nil {
+		r.fail("the engine did not echo loop 1's position")
+		r.hint("The engine answers global gets but not loop ones: check that it is SooperLooper.")
+		return
+	}
+	r.ok("loop 1 echoed its position")
+}
+
+// checkVersion reports the engine's version, from its pong, and warns of
+// versions whose state codes may differ from those sooperGUI knows.
+func (r *doctorReport) checkVersion(pong *osc.Message) {
+	var version string
+	if len(pong.Arguments) > 1 {
+		version, _ = pong.Arguments[1].(string)
+	}
+	major, minor, ok := parseEngineVersion(version)
+	switch {
+	case !ok:
+		r.warn("the engine's version %q is not SooperLooper's", version)
+		r.hint("The engine's version is unknown: if states show wrongly, set them in the buttons section of the config file.")
+	case major < 1 || major == 1 && minor < 7:
+		r.warn("SooperLooper %s is older than 1.7, whose state codes sooperGUI uses", version)
+		r.hint("Update SooperLooper to 1.7 or later, or set its states in the buttons section of the config file.")
+	default:
+		r.ok("SooperLooper %s", version)
+	}
+}
+
+// parseEngineVersion reads the major and minor numbers of a version such
+// as "1.7.9" or "1.6rc1".
+func parseEngineVersion(v string) (major, minor int, ok bool) {
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	digits := parts[1]
+	if i := strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		digits = digits[:i]
+	}
+	minor, err = strconv.Atoi(digits)
+	return major, minor, err == nil
+}
+
+// noReplyHints explains a ping that went unanswered without the port being
+// unreachable: the ping was lost on the way, or the reply on the way back.
+func (r *doctorReport) noReplyHints(addr *net.UDPAddr, localPort int) {
+	if addr.IP.IsLoopback() {
+		r.hint("Nothing answered on %s: is the engine running, and listening on port %d?", addr, addr.Port)
+		return
+	}
+	r.hint("Either the engine did not get the ping, or its reply did not get back. Check that it runs on %s and listens on UDP port %d, and that no firewall there blocks that port.", addr.IP, addr.Port)
+	port := strconv.Itoa(localPort)
+	if listenPort == 0 {
+		r.hint("Replies come back to a random port here, which a firewall may block: run with a fixed --listen-port, e.g. --listen-port 9952, and allow it.")
+		port = "9952"
+	}
+	if _, err := exec.LookPath("ufw"); err == nil {
+		r.hint("With ufw: sudo ufw allow from %s proto udp to any port %s", addr.IP, port)
+	} else if _, err := exec.LookPath("firewall-cmd"); err == nil {
+		r.hint("With firewalld: sudo firewall-cmd --add-port=%s/udp", port)
+	}
+	r.hint("Behind NAT, a VPN or in a container, the engine may not reach this machine's address: set --return-host and --return-port to an address it can reach, or use --tunnel.")
+}
+
+// doctorSend sends m on conn.
+func doctorSend(conn net.Conn, m *osc.Message) error {
+	data, err := m.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(data)
+	return err
+}
+
+// doctorAwait reads conn until a message for path arrives, for up to wait.
+func doctorAwait(conn net.PacketConn, path string, wait time.Duration) (*osc.Message, error) {
+	conn.SetReadDeadline(time.Now().Add(wait))
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return nil, err
+		}
+		p, err := osc.ParsePacket(string(buf[:n]))
+		if err != nil {
+			continue
+		}
+		if m, ok := p.(*osc.Message); ok && m.Address == path {
+			return m, nil
+		}
+	}
+}
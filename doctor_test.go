@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDoctor tests the checks against a running engine and a port nothing
+// listens on
+func TestDoctor(t *testing.T) {
+	s := startSim(t, "127.0.0.1:0", 2)
+	var out strings.Builder
+	r := &doctorReport{w: &out}
+	r.run("127.0.0.1", s.port(), time.Second)
+	if r.failed || len(r.hints) > 0 || !strings.Contains(out.String(), "loop 1 echoed") {
+		t.Errorf("against the engine:\n%s%v", out.String(), r.hints)
+	}
+
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := c.LocalAddr().(*net.UDPAddr).Port
+	c.Close()
+	out.Reset()
+	r = &doctorReport{w: &out}
+	r.run("127.0.0.1", closed, 500*time.Millisecond)
+	if !r.failed || len(r.hints) != 1 || !strings.Contains(r.hints[0], "sooperlooper -p") {
+		t.Errorf("against a closed port:\n%s%v", out.String(), r.hints)
+	}
+}
+
+// TestParseEngineVersion tests reading SooperLooper versions
+func TestParseEngineVersion(t *testing.T) {
+	for _, tc := range []struct {
+		v            string
+		major, minor int
+		ok           bool
+	}{
+		{"1.7.9", 1, 7, true},
+		{"1.7.9-slmock", 1, 7, true},
+		{"2.0", 2, 0, true},
+		{"1.6rc1", 1, 6, true},
+		{"", 0, 0, false},
+		{"x.y", 0, 0, false},
+	} {
+		major, minor, ok := parseEngineVersion(tc.v)
+		if major != tc.major || minor != tc.minor || ok != tc.ok {
+			t.Errorf("parseEngineVersion(%q) = %d, %d, %v", tc.v, major, minor, ok)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// engineerr.go
+// Engine errors: the replies SooperLooper sends on the error paths of
+// commands that take one, and the commands it would ignore without a word
+// (a loop it does not have, a control it does not know), shown as toasts.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+var (
+	// engineControls are the loop controls SooperLooper knows, read-only
+	// ones included. It sets and gets nothing else.
+	engineControls = []string{
+		"rec_thresh", "feedback", "dry", "wet", "input_gain", "rate",
+		"scratch_pos", "delay_trigger", "quantize", "round", "redo_is_tap",
+		"sync", "playback_sync", "use_rate", "fade_samples",
+		"use_feedback_play", "use_common_ins", "use_common_outs",
+		"relative_sync", "use_safety_feedback", "pan_1", "pan_2", "pan_3",
+		"pan_4", "input_latency", "output_latency", "trigger_latency",
+		"autoset_latency", "mute_quantized", "overdub_quantized",
+		"replace_quantized", "discrete_prefader", "round_integer_tempo",
+		"stretch_ratio", "tempo_stretch", "pitch_shift",
+		"state", "next_state", "loop_len", "loop_pos", "cycle_len",
+		"free_time", "total_time", "rate_output", "in_peak_meter",
+		"out_peak_meter", "is_soloed", "waiting", "channel_count",
+	}
+	// engineGlobals are SooperLooper's global controls.
+	engineGlobals = []string{
+		"tempo", "eighth_per_cycle", "dry", "wet", "input_gain",
+		"sync_source", "tap_tempo", "save_loop", "auto_disable_latency",
+		"select_next_loop", "select_prev_loop", "select_all_loops",
+		"selected_loop_num", "output_midi_clock", "use_midi_start",
+		"use_midi_stop", "send_midi_start_on_trigger", "smart_eighths",
+		"jack_timebase_master", "output_clock",
+	}
+)
+
+// checkLoopCommand returns why the engine would ignore a command to loop
+// when it has loops loops, or nil. Loops -1 (all) and -3 (selected) are
+// wildcards. control, if set, is a loop control, or with loop -2 a global
+// one.
+func checkLoopCommand(loop, loops int, control string) error {
+	switch {
+	case loop == -2:
+		if control != "" && !slices.Contains(engineGlobals, control) {
+			return errors.New(trf("unknown global control %q", control))
+		}
+		return nil
+	case loop != -1 && loop != -3 && (loop < 0 || loop >= loops):
+		return errors.New(trf("no loop %d (the engine has %d)", loop+1, loops))
+	case control != "" && !slices.Contains(engineControls, control):
+		return errors.New(trf("unknown control %q", control))
+	}
+	return nil
+}
+
+// engineError logs an engine error for op and shows it as a toast.
+func engineError(op, text string) {
+	oscLog.Warn("engine error", "op", op, "err", text)
+	notify(slog.LevelError, op+": "+text)
+}
+
+// engineErrorReply reports a reply on an /error/<op> path, whose arguments
+// are the engine's message.
+func engineErrorReply(addr string, args []any) {
+	parts := make([]string, len(args))
+	for i, a := range args {
+		parts[i] = fmt.Sprint(a)
+	}
+	text := strings.Join(parts, " ")
+	if text == "" {
+		text = tr("failed")
+	}
+	engineError(strings.TrimPrefix(addr, errorPrefix), text)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCheckLoopCommand tests which commands the engine would ignore
+func TestCheckLoopCommand(t *testing.T) {
+	for _, tc := range []struct {
+		loop    int
+		control string
+		ok      bool
+	}{
+		{0, "", true},
+		{1, "wet", true},
+		{-1, "feedback", true},
+		{-3, "", true},
+		{2, "", false},
+		{-4, "", false},
+		{0, "wetness", false},
+		{-2, "tempo", true},
+		{-2, "wet", true},
+		{-2, "loop_len", false},
+	} {
+		err := checkLoopCommand(tc.loop, 2, tc.control)
+		if (err == nil) != tc.ok {
+			t.Errorf("loop %d control %q: %v, want ok %v", tc.loop, tc.control, err, tc.ok)
+		}
+	}
+}
+
+// TestEngineErrorReply tests showing an error reply as a toast
+func TestEngineErrorReply(t *testing.T) {
+	engineErrorReply(errorPrefix+"save_loop", []any{"loop is empty"})
+	if ts := activeToasts(time.Now()); len(ts) == 0 || ts[0].Text != "save_loop: loop is empty" || ts[0].Level != slog.LevelError {
+		t.Errorf("toasts %+v, want the reply as an error", ts)
+	}
+}
+
+// TestEngineErrors tests error replies and ignored commands against the
+// simulator
+func TestEngineErrors(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 2)
+	sl = startClient(t, sim)
+	defer func() { sl = nil }()
+	eventually(t, "engine online", func() bool { return loopCount == 2 })
+	eventually(t, "client online", func() bool { return sl.Online() })
+	toast := func() string {
+		if ts := activeToasts(time.Now()); len(ts) > 0 {
+			return ts[0].Text
+		}
+		return ""
+	}
+
+	sl.SaveLoop(0, t.TempDir()+"/empty.wav")
+	eventually(t, "error reply", func() bool { return strings.Contains(toast(), "save_loop: loop is empty") })
+
+	sl.Hit(5, "record")
+	if text := toast(); !strings.Contains(text, "record: no loop 6") {
+		t.Errorf("toast %q, want a missing loop", text)
+	}
+	sl.Set(1, "wetness", 0.5)
+	if text := toast(); !strings.Contains(text, `set: unknown control "wetness"`) {
+		t.Errorf("toast %q, want an unknown control", text)
+	}
+}
@@ -0,0 +1,236 @@
+// export.go
+// Loop export: w saves a loop to a WAV file, optionally peak normalized,
+// trimmed of silence and faded at the edges on the way, as set in a form.
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+const (
+	// exportPeakDB is where peak normalizing puts the loudest sample.
+	exportPeakDB = -1.0
+	// exportSilenceDB is the level below which trimming counts audio as
+	// silence.
+	exportSilenceDB = -60.0
+	exportMaxFadeMs = 10000
+)
+
+// exportSettings is how loops are processed on the way to the file.
+type exportSettings struct {
+	Normalize bool
+	Trim      bool
+	FadeMs    int
+}
+
+// exportOptions are the settings last saved with. The TUI goroutine owns
+// them.
+var exportOptions exportSettings
+
+// processing reports whether e changes the audio at all.
+func (e exportSettings) processing() bool {
+	return e.Normalize || e.Trim || e.FadeMs > 0
+}
+
+// exportResult is what processing did, for the log.
+type exportResult struct {
+	Trimmed time.Duration
+	GainDB  float64
+}
+
+// processExport applies e to interleaved samples: trims, then normalizes,
+// then fades.
+func processExport(e exportSettings, samples []float32, channels, rate int) ([]float32, exportResult) {
+	var res exportResult
+	if e.Trim {
+		before := len(samples)
+		samples = trimSilence(samples, channels, float32(math.Pow(10, exportSilenceDB/20)))
+		res.Trimmed = time.Duration(float64((before-len(samples))/channels) / float64(rate) * float64(time.Second))
+	}
+	if e.Normalize {
+		res.GainDB = normalizePeak(samples, exportPeakDB)
+	}
+	if e.FadeMs > 0 {
+		fadeEdges(samples, channels, e.FadeMs*rate/1000)
+	}
+	return samples, res
+}
+
+// trimSilence cuts the frames before the first and after the last with a
+// sample at threshold or above. Audio that is silent throughout is left
+// alone.
+func trimSilence(samples []float32, channels int, threshold float32) []float32 {
+	frames := len(samples) / channels
+	loud := func(f int) bool {
+		for _, v := range samples[f*channels : (f+1)*channels] {
+			if v >= threshold || v <= -threshold {
+				return true
+			}
+		}
+		return false
+	}
+	first, last := 0, frames-1
+	for first < frames && !loud(first) {
+		first++
+	}
+	if first == frames {
+		return samples
+	}
+	for !loud(last) {
+		last--
+	}
+	return samples[first*channels : (last+1)*channels]
+}
+
+// normalizePeak scales samples so the loudest is at peakDB, and returns
+// the gain in dB. Silence is left alone.
+func normalizePeak(samples []float32, peakDB float64) float64 {
+	var peak float32
+	for _, v := range samples {
+		peak = max(peak, v, -v)
+	}
+	if peak == 0 {
+		return 0
+	}
+	gain := float32(math.Pow(10, peakDB/20)) / peak
+	for i := range samples {
+		samples[i] *= gain
+	}
+	return 20 * math.Log10(float64(gain))
+}
+
+// fadeEdges fades the first and last n frames in and out linearly, over at
+// most half the audio each.
+func fadeEdges(samples []float32, channels, n int) {
+	frames := len(samples) / channels
+	n = min(n, frames/2)
+	for f := range n {
+		g := float32(f) / float32(n)
+		for c := range channels {
+			samples[f*channels+c] *= g
+			samples[(frames-1-f)*channels+c] *= g
+		}
+	}
+}
+
+// writeWAV writes interleaved samples to path as a 32-bit float WAV file,
+// through a temporary file so a partial file never has the name.
+func writeWAV(path string, rate, channels int, samples []float32) error {
+	le := binary.LittleEndian
+	size := len(samples) * 4
+	var b []byte
+	b = append(b, "RIFF"...)
+	b = le.AppendUint32(b, uint32(4+(8+16)+(8+4)+(8+size)))
+	b = append(b, "WAVEfmt "...)
+	b = le.AppendUint32(b, 16)
+	b = le.AppendUint16(b, 3) // WAVE_FORMAT_IEEE_FLOAT
+	b = le.AppendUint16(b, uint16(channels))
+	b = le.AppendUint32(b, uint32(rate))
+	b = le.AppendUint32(b, uint32(rate*channels*4))
+	b = le.AppendUint16(b, uint16(channels*4))
+	b = le.AppendUint16(b, 32)
+	b = append(b, "fact"...)
+	b = le.AppendUint32(b, 4)
+	b = le.AppendUint32(b, uint32(len(samples)/channels))
+	b = append(b, "data"...)
+	b = le.AppendUint32(b, uint32(size))
+	for _, v := range samples {
+		b = le.AppendUint32(b, math.Float32bits(v))
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// saveLoopFile asks the engine to save loop i's audio to a new WAV file in
+// --audio-dir, processed as e says, and calls saved with its path once it
+// is written. The engine writes the file, or with processing a temporary
+// one that is read back, processed and written out here.
+func saveLoopFile(i int, e exportSettings, saved func(path string)) {
+	mu.Lock()
+	n := loopCount
+	mu.Unlock()
+	if i >= n || !engineSharesFiles("saving loops") {
+		return
+	}
+	dir, err := filepath.Abs(audioDir)
+	if err != nil {
+		tuiLog.Warn("loop not saved", "loop", i+1, "err", err)
+		notify(slog.LevelWarn, trf("Loop %d not saved: %v", i+1, err))
+		return
+	}
+	base := filepath.Join(dir, fmt.Sprintf("loop%d-%s", i+1, time.Now().Format("20060102-150405")))
+	file := base + ".wav"
+	for n := 2; ; n++ {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			break
+		}
+		file = fmt.Sprintf("%s-%d.wav", base, n)
+	}
+	engineFile := file
+	if e.processing() {
+		engineFile = filepath.Join(copyDir, fmt.Sprintf("sooperGUI-export-%d-%d.wav", os.Getpid(), time.Now().UnixNano()))
+	}
+	tuiLog.Info("saving loop", "loop", i+1, "file", file)
+	sl.SaveLoop(i, engineFile)
+	go func() {
+		if err := waitForFile(engineFile, copyFileTimeout, copyFilePoll); err != nil {
+			tuiLog.Warn("loop save failed", "loop", i+1, "err", err)
+			notify(slog.LevelWarn, trf("Loop %d not saved: %v", i+1, err))
+			return
+		}
+		if e.processing() {
+			defer os.Remove(engineFile)
+			a, samples, err := readWAV(engineFile)
+			if err == nil {
+				var res exportResult
+				samples, res = processExport(e, samples, a.Channels, a.Rate)
+				tuiLog.Info("loop processed", "loop", i+1, "trimmed", res.Trimmed.Round(time.Millisecond), "gain_db", math.Round(res.GainDB*10)/10)
+				err = writeWAV(file, a.Rate, a.Channels, samples)
+			}
+			if err != nil {
+				tuiLog.Warn("loop save failed", "loop", i+1, "err", err)
+				notify(slog.LevelWarn, trf("Loop %d not saved: %v", i+1, err))
+				return
+			}
+		}
+		tuiLog.Info("loop saved", "loop", i+1, "file", file)
+		notify(slog.LevelInfo, trf("Loop %d saved to %s", i+1, filepath.Base(file)))
+		saved(file)
+	}()
+}
+
+// newExportForm builds the form that saves loop i with settings starting
+// at e. Save calls save with the settings, and Cancel or Esc calls cancel.
+func newExportForm(i int, e exportSettings, save func(exportSettings), cancel func()) *tview.Form {
+	form := tview.NewForm()
+	normalize := tview.NewCheckbox().SetLabel(trf("Peak normalize to %g dBFS", exportPeakDB)).SetChecked(e.Normalize)
+	trim := tview.NewCheckbox().SetLabel(tr("Trim silence")).SetChecked(e.Trim)
+	fade := tview.NewInputField().SetLabel(tr("Fade edges (ms)")).SetText(strconv.Itoa(e.FadeMs)).
+		SetFieldWidth(6).SetAcceptanceFunc(tview.InputFieldInteger)
+	form.AddFormItem(normalize).AddFormItem(trim).AddFormItem(fade).
+		AddButton(tr("Save"), func() {
+			ms, _ := strconv.Atoi(fade.GetText())
+			save(exportSettings{
+				Normalize: normalize.IsChecked(),
+				Trim:      trim.IsChecked(),
+				FadeMs:    min(max(ms, 0), exportMaxFadeMs),
+			})
+		}).
+		AddButton(tr("Cancel"), cancel).
+		SetCancelFunc(cancel)
+	form.SetBorder(true).SetTitle(" " + trf("Save loop %d", i+1) + " ")
+	return form
+}
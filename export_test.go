@@ -0,0 +1,123 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestProcessExport tests trimming, normalizing and fading
+func TestProcessExport(t *testing.T) {
+	// Stereo at 10 Hz: silence, then 8 frames of audio peaking at 0.5 on
+	// the right, then silence.
+	samples := []float32{0, 0, 0.0001, 0}
+	for range 8 {
+		samples = append(samples, 0.25, -0.5)
+	}
+	samples = append(samples, 0, 0.00005, 0, 0)
+
+	out, res := processExport(exportSettings{Trim: true}, append([]float32(nil), samples...), 2, 10)
+	if len(out) != 16 || res.Trimmed != 400*time.Millisecond {
+		t.Errorf("trimmed to %d samples, %v cut; want 16 and 400ms", len(out), res.Trimmed)
+	}
+
+	out, res = processExport(exportSettings{Trim: true, Normalize: true}, append([]float32(nil), samples...), 2, 10)
+	if math.Abs(res.GainDB-(exportPeakDB+20*math.Log10(2))) > 1e-3 {
+		t.Errorf("gain %g dB, want a 0.5 peak raised to %g dBFS", res.GainDB, exportPeakDB)
+	}
+	if peak := math.Pow(10, exportPeakDB/20); math.Abs(float64(-out[1])-peak) > 1e-6 {
+		t.Errorf("normalized peak %g, want %g", -out[1], peak)
+	}
+
+	// 200 ms at 10 Hz is 2 frames each end.
+	out, _ = processExport(exportSettings{Trim: true, FadeMs: 200}, append([]float32(nil), samples...), 2, 10)
+	for f, want := range []float32{0, 0.125, 0.25, 0.25, 0.25, 0.25, 0.125, 0} {
+		if out[f*2] != want {
+			t.Errorf("faded frame %d = %g, want %g", f, out[f*2], want)
+		}
+	}
+
+	silent := make([]float32, 8)
+	if out := trimSilence(silent, 2, 0.001); len(out) != 8 {
+		t.Errorf("silence trimmed to %d samples, want it left", len(out))
+	}
+	if g := normalizePeak(silent, exportPeakDB); g != 0 {
+		t.Errorf("silence normalized by %g dB", g)
+	}
+}
+
+// TestWriteWAV tests that written files read back
+func TestWriteWAV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+	samples := []float32{0, 0.5, -0.25, 1, -1, 0.125}
+	if err := writeWAV(path, 44100, 2, samples); err != nil {
+		t.Fatal(err)
+	}
+	a, got, err := readWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Channels != 2 || a.Rate != 44100 || !a.Float || a.Frames != 3 {
+		t.Errorf("header %+v, want 3 frames of stereo float at 44100 Hz", a)
+	}
+	for i := range samples {
+		if got[i] != samples[i] {
+			t.Fatalf("samples %v, want %v", got, samples)
+		}
+	}
+	if _, err := os.Stat(path + ".tmp"); err == nil {
+		t.Error("temporary file left behind")
+	}
+}
+
+// TestSaveLoopFile tests saving a loop through the simulator, whose files
+// are not audio, so processing them fails
+func TestSaveLoopFile(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 2)
+	sl = startClient(t, sim)
+	defer func(dir, copied string) { sl, audioDir, copyDir = nil, dir, copied }(audioDir, copyDir)
+	audioDir, copyDir = t.TempDir(), t.TempDir()
+	eventually(t, "engine online", func() bool { return loopCount == 2 })
+	sim.Handle(hitMessage(1, "record"))
+	time.Sleep(50 * time.Millisecond)
+	sim.Handle(hitMessage(1, "record"))
+
+	saved := make(chan string, 1)
+	var paths []string
+	for range 2 {
+		saveLoopFile(1, exportSettings{}, func(path string) { saved <- path })
+		select {
+		case path := <-saved:
+			paths = append(paths, path)
+		case <-time.After(5 * time.Second):
+			t.Fatal("loop not saved")
+		}
+	}
+	for _, path := range paths {
+		if filepath.Dir(path) != audioDir || !strings.HasPrefix(filepath.Base(path), "loop2-") {
+			t.Errorf("saved to %s, want loop2-… in %s", path, audioDir)
+		}
+	}
+	if paths[0] == paths[1] {
+		t.Errorf("both saves went to %s", paths[0])
+	}
+
+	saveLoopFile(1, exportSettings{Normalize: true}, func(path string) { saved <- path })
+	eventually(t, "engine file removed", func() bool {
+		files, _ := os.ReadDir(copyDir)
+		failed := false
+		for _, l := range logLines.tail(logRingSize, slog.LevelWarn) {
+			failed = failed || strings.Contains(l.Text, "loop save failed")
+		}
+		return len(files) == 0 && failed
+	})
+	select {
+	case path := <-saved:
+		t.Errorf("unprocessable loop saved to %s", path)
+	default:
+	}
+}
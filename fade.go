@@ -0,0 +1,110 @@
+// fade.go
+// Timed fade-out of a loop's feedback or wet control over a number of bars.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const fadeFallbackTempo = 120
+
+var (
+	fadeBars    = 4
+	fadeControl = "feedback"
+
+	// fades maps each loop being faded to the sequence number of its fade.
+	fades   = make(map[int]int)
+	fadeSeq int
+)
+
+// fadeDuration returns how long bars bars last at tempo, with a cycle of
+// eighths eighth notes per bar.
+func fadeDuration(bars int, tempo, eighths float32) time.Duration {
+	if tempo <= 0 {
+		tempo = fadeFallbackTempo
+	}
+	_, _, beatsPerBar := beatAt(0, tempo, eighths)
+	return time.Duration(float64(bars*beatsPerBar) * 60 / float64(tempo) * float64(time.Second))
+}
+
+// fadeValue returns the control value elapsed into a linear fade from from
+// to zero.
+func fadeValue(from float32, elapsed, dur time.Duration) float32 {
+	if elapsed >= dur || dur <= 0 {
+		return 0
+	}
+	return from * (1 - float32(elapsed)/float32(dur))
+}
+
+// toggleFade starts fading loop i out over --fade-bars, or stops a fade
+// already running on it, leaving the control where it is.
+func toggleFade(i int) {
+	mu.Lock()
+	defer mu.Unlock()
+	if i >= loopCount {
+		return
+	}
+	if _, ok := fades[i]; ok {
+		delete(fades, i)
+		tuiLog.Info("fade stopped", "loop", i+1)
+		return
+	}
+	from, ok := getLoopState(i).controls[fadeControl]
+	if !ok {
+		from = 1
+	}
+	tempo := globals["tempo"]
+	if tempo <= 0 {
+		tuiLog.Warn("tempo unknown, fading at the fallback tempo", "bpm", fadeFallbackTempo)
+	}
+	dur := fadeDuration(fadeBars, tempo, globals["eighth_per_cycle"])
+	fadeSeq++
+	seq := fadeSeq
+	fades[i] = seq
+	tuiLog.Info("fade", "loop", i+1, "control", fadeControl, "bars", fadeBars, "dur", dur)
+
+	goService("fade", func(ctx context.Context) error {
+		start := time.Now()
+		for {
+			elapsed := time.Since(start)
+			v := fadeValue(from, elapsed, dur)
+			mu.Lock()
+			if fades[i] != seq {
+				mu.Unlock()
+				return nil
+			}
+			getLoopState(i).setControl(fadeControl, v)
+			if v == 0 {
+				delete(fades, i)
+			}
+			mu.Unlock()
+			controlThrottle(fadeControl).Set(i+1, v)
+			if v == 0 || !sleepCtx(ctx, sceneRampFrame) {
+				return nil
+			}
+		}
+	})
+}
+
+// fadeStatus lists the loops being faded for the status bar. The caller
+// must hold mu.
+func fadeStatus() string {
+	if len(fades) == 0 {
+		return ""
+	}
+	loops := make([]int, 0, len(fades))
+	for i := range fades {
+		loops = append(loops, i)
+	}
+	sort.Ints(loops)
+	ids := make([]string, len(loops))
+	for k, i := range loops {
+		ids[k] = fmt.Sprintf("L%d", i+1)
+	}
+	return "[yellow]" + tr("fade") + " " + strings.Join(ids, " ") + "[-]"
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// TestFadeDuration tests the length of a fade in bars
+func TestFadeDuration(t *testing.T) {
+	tests := []struct {
+		bars           int
+		tempo, eighths float32
+		want           time.Duration
+	}{
+		{4, 120, 8, 8 * time.Second},
+		{2, 90, 6, 4 * time.Second},
+		{1, 0, 8, 2 * time.Second}, // fallback tempo
+	}
+	for _, tt := range tests {
+		if got := fadeDuration(tt.bars, tt.tempo, tt.eighths); got != tt.want {
+			t.Errorf("fadeDuration(%d, %v, %v) = %v, want %v", tt.bars, tt.tempo, tt.eighths, got, tt.want)
+		}
+	}
+	if got := fadeValue(0.8, time.Second, 4*time.Second); got != 0.6 {
+		t.Errorf("fadeValue a quarter in = %v, want 0.6", got)
+	}
+	if got := fadeValue(0.8, 5*time.Second, 4*time.Second); got != 0 {
+		t.Errorf("fadeValue after the end = %v, want 0", got)
+	}
+}
+
+// TestFadeOut tests a fade against the simulator
+func TestFadeOut(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 2)
+	sl = startClient(t, sim)
+	defer func(bars int) { sl, fadeBars = nil, bars }(fadeBars)
+	eventually(t, "feedback of loop 2", func() bool { return loopStates[1] != nil && loopStates[1].controls["feedback"] == 1 })
+
+	fadeBars = 1
+	sim.Handle(osc.NewMessage("/set", "tempo", float32(1200))) // 200 ms per bar
+	sim.Handle(osc.NewMessage("/set", "eighth_per_cycle", float32(8)))
+	eventually(t, "tempo polled", func() bool { return globals["tempo"] == 1200 && globals["eighth_per_cycle"] == 8 })
+	toggleFade(1)
+	mu.Lock()
+	status := fadeStatus()
+	mu.Unlock()
+	if status != "[yellow]fade L2[-]" {
+		t.Errorf("fadeStatus() = %q", status)
+	}
+	eventually(t, "fade finished", func() bool { return len(fades) == 0 })
+	eventually(t, "engine feedback at 0", func() bool { return sim.Control(1, "feedback") == 0 })
+	if got := sim.Control(0, "feedback"); got != 1 {
+		t.Errorf("loop 1 feedback = %v, want untouched", got)
+	}
+}
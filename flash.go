@@ -0,0 +1,43 @@
+// flash.go
+// Row flashes: a loop's row lights up briefly in the color of its new state
+// when the engine reports a transition, such as a record starting at the
+// quantize point, to be caught out of the corner of the eye.
+
+package main
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+
+	"jaudio/internal/slstate"
+)
+
+// flashTime is how long a row stays lit after a transition.
+const flashTime = 400 * time.Millisecond
+
+// flashRows (--flash) turns the row flashes on.
+var flashRows = true
+
+// flashColor is the background a row flashes in on going to state s.
+func flashColor(s slstate.State) tcell.Color {
+	switch s {
+	case slstate.Record, slstate.WaitStop:
+		return tcell.ColorMaroon
+	case slstate.Overdub, slstate.Multiply, slstate.Insert, slstate.Replace, slstate.Substitute:
+		return tcell.ColorOlive
+	case slstate.Play, slstate.OneShot:
+		return tcell.ColorDarkGreen
+	case slstate.Mute, slstate.OffMuted, slstate.Pause, slstate.Off:
+		return tcell.ColorDimGray
+	}
+	return tcell.ColorNavy
+}
+
+// flashRow lights the cells of a row in the color of state s.
+func flashRow(row []cell, s slstate.State) {
+	bg := flashColor(s)
+	for i := range row {
+		row[i].Background = bg
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+
+	"jaudio/internal/slstate"
+)
+
+// TestFlashRow tests that a loop's row is lit only while its flash lasts
+func TestFlashRow(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+	now := time.Now()
+	loops := []*LoopState{
+		{State: slstate.Record, flashUntil: now.Add(flashTime)},
+		{State: slstate.Play, flashUntil: now.Add(-time.Millisecond)},
+	}
+	rows := renderTable(tableOptions{Width: 100, Flash: true}, loops, now)
+	for c := range rows[1] {
+		if got := rows[1][c].Background; got != tcell.ColorMaroon {
+			t.Errorf("recording row column %d background %v, want maroon", c, got)
+		}
+	}
+	if got := rows[2][colID].Background; got != tcell.ColorDefault {
+		t.Errorf("row lit %v after its flash ended", got)
+	}
+	rows = renderTable(tableOptions{Width: 100}, loops, now)
+	if got := rows[1][colID].Background; got != tcell.ColorDefault {
+		t.Errorf("row lit %v with flashes off", got)
+	}
+}
+
+// TestFlashEngine tests that a state change from the engine starts a flash
+func TestFlashEngine(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 1)
+	sl = startClient(t, sim)
+	defer func() {
+		mu.Lock()
+		sl = nil
+		mu.Unlock()
+	}()
+	eventually(t, "a loop", func() bool { return loopCount == 1 && getLoopState(0).haveState })
+
+	sim.Handle(hitMessage(0, "record"))
+	eventually(t, "a flash", func() bool {
+		ls := getLoopState(0)
+		return ls.State == slstate.Record && time.Until(ls.flashUntil) > 0
+	})
+}
@@ -0,0 +1,41 @@
+// follow.go
+// Follow mode: with --follow, or after F, the selected loop jumps to the
+// loop whose state changed last, or stays with a loop that is recording,
+// so in a fast performance the loop being played is the one highlighted.
+
+package main
+
+import "jaudio/internal/slstate"
+
+// followAudio turns follow mode on. It is guarded by mu.
+var followAudio bool
+
+// followLoop selects, in follow mode, the loop to show after loop i went
+// to state to: i, unless another loop is recording and i is not, when that
+// one is. The caller must hold mu.
+func followLoop(i int, to slstate.State) {
+	if !followAudio {
+		return
+	}
+	if !to.Recording() {
+		if getLoopState(selectedLoop).State.Recording() && selectedLoop != i {
+			return
+		}
+		for j := range loopCount {
+			if j != i && getLoopState(j).State.Recording() {
+				selectLoop(j)
+				return
+			}
+		}
+	}
+	selectLoop(i)
+}
+
+// followStatus is the status bar note while follow mode is on. The caller
+// must hold mu.
+func followStatus() string {
+	if !followAudio {
+		return ""
+	}
+	return trf("follow L%d", selectedLoop+1)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	"jaudio/internal/slstate"
+)
+
+// TestFollowLoop tests which loop follow mode selects
+func TestFollowLoop(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+	defer func() {
+		followAudio, selectedLoop = false, 0
+		loopStates = make(map[int]*LoopState)
+	}()
+	loopStates = make(map[int]*LoopState)
+	loopCount, selectedLoop = 3, 0
+
+	followLoop(2, slstate.Play)
+	if selectedLoop != 0 {
+		t.Errorf("selected loop %d with follow mode off", selectedLoop+1)
+	}
+	followAudio = true
+	followLoop(2, slstate.Play)
+	if selectedLoop != 2 {
+		t.Errorf("selected loop %d, want 3 which changed", selectedLoop+1)
+	}
+	getLoopState(1).State = slstate.Record
+	followLoop(1, slstate.Record)
+	followLoop(0, slstate.Mute)
+	if selectedLoop != 1 {
+		t.Errorf("selected loop %d, want 2 which records", selectedLoop+1)
+	}
+	selectedLoop = 0
+	followLoop(2, slstate.Overdub)
+	if selectedLoop != 1 {
+		t.Errorf("selected loop %d, want the recording loop 2 found", selectedLoop+1)
+	}
+	getLoopState(1).State = slstate.Play
+	followLoop(1, slstate.Play)
+	followLoop(0, slstate.Play)
+	if selectedLoop != 0 {
+		t.Errorf("selected loop %d, want 1 once nothing records", selectedLoop+1)
+	}
+}
+
+// TestFollowEngine tests that state changes from the engine move the
+// selection in follow mode
+func TestFollowEngine(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 2)
+	sl = startClient(t, sim)
+	defer func() {
+		mu.Lock()
+		sl, followAudio, selectedLoop = nil, false, 0
+		mu.Unlock()
+	}()
+	mu.Lock()
+	followAudio = true
+	mu.Unlock()
+	eventually(t, "two loops", func() bool { return loopCount == 2 && getLoopState(1).haveState })
+
+	sim.Handle(hitMessage(1, "record"))
+	eventually(t, "loop 2 selected", func() bool { return selectedLoop == 1 })
+	mu.Lock()
+	defer mu.Unlock()
+	if got := followStatus(); got != "follow L2" {
+		t.Errorf("status %q, want follow L2", got)
+	}
+}
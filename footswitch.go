@@ -0,0 +1,241 @@
+// footswitch.go
+// Footswitches: USB HID pedals and GPIO buttons read from /dev/input.
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// footswitchConfig is one input device in the config file:
+//
+//	footswitches:
+//	  - device: /dev/input/by-id/usb-PCsensor_FootSwitch-event-kbd
+//	    keys: {KEY_A: record 1, KEY_B: overdub 1, KEY_C: mute 2,3}
+type footswitchConfig struct {
+	Device string            `yaml:"device"`
+	Keys   map[string]string `yaml:"keys"`
+}
+
+// footAction is a loop command bound to a footswitch key. Loops nil means
+// the selected loop, and -1 all loops.
+type footAction struct {
+	Cmd   string
+	Loops []int
+}
+
+// An input_event is a struct timeval (two C longs), then type, code and
+// value.
+const (
+	inputEventSize = 2*strconv.IntSize/8 + 8
+	evKey          = 1
+	keyReleased    = 0
+	keyPressed     = 1 // 2 is autorepeat
+
+	footswitchRetry = 2 * time.Second
+)
+
+// inputKeyCodes are the evdev key names accepted in key mappings. Other
+// keys can be given by number.
+var inputKeyCodes = func() map[string]uint16 {
+	codes := map[string]uint16{
+		"KEY_0": 11, "KEY_ENTER": 28, "KEY_SPACE": 57,
+		"KEY_F11": 87, "KEY_F12": 88,
+		"KEY_UP": 103, "KEY_PAGEUP": 104, "KEY_LEFT": 105,
+		"KEY_RIGHT": 106, "KEY_DOWN": 108, "KEY_PAGEDOWN": 109,
+	}
+	for i := range 9 {
+		codes[fmt.Sprintf("KEY_%d", i+1)] = uint16(2 + i)
+	}
+	for row, start := range map[string]uint16{"QWERTYUIOP": 16, "ASDFGHJKL": 30, "ZXCVBNM": 44} {
+		for i, r := range row {
+			codes["KEY_"+string(r)] = start + uint16(i)
+		}
+	}
+	for i := range 10 {
+		codes[fmt.Sprintf("KEY_F%d", i+1)] = uint16(59 + i)
+		codes[fmt.Sprintf("BTN_%d", i)] = uint16(0x100 + i)
+	}
+	return codes
+}()
+
+// bindings parses the key mapping. Actions are a loop command, then the
+// loops it acts on: "record 1", "mute 2,3" or "undo all". Without loops
+// the command goes to the loop on the Loop page.
+func (f footswitchConfig) bindings() (map[uint16]footAction, error) {
+	if f.Device == "" {
+		return nil, errors.New("device is required")
+	}
+	out := make(map[uint16]footAction, len(f.Keys))
+	for key, action := range f.Keys {
+		code, ok := inputKeyCodes[strings.ToUpper(key)]
+		if !ok {
+			n, err := strconv.ParseUint(key, 0, 16)
+			if err != nil {
+				return nil, fmt.Errorf("unknown key %q: use a name like KEY_A or BTN_0, or a code", key)
+			}
+			code = uint16(n)
+		}
+		a, err := parseFootAction(action)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		out[code] = a
+	}
+	return out, nil
+}
+
+func parseFootAction(s string) (footAction, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 || !slices.Contains(hitCommands, fields[0]) {
+		return footAction{}, fmt.Errorf("%q is not a loop command and loops, e.g. \"record 1\"", s)
+	}
+	a := footAction{Cmd: fields[0]}
+	if len(fields) == 1 {
+		return a, nil
+	}
+	loops, err := parseLoopList(fields[1])
+	if err != nil {
+		return footAction{}, err
+	}
+	a.Loops = loops
+	return a, nil
+}
+
+// parseLoopList parses the loops an action acts on, "2,3" or "all", as
+// indexes. All loops is -1, the engine's index for every loop.
+func parseLoopList(s string) ([]int, error) {
+	if s == "all" {
+		return []int{-1}, nil
+	}
+	var loops []int
+	for _, n := range strings.Split(s, ",") {
+		i, err := strconv.Atoi(n)
+		if err != nil || i < 1 || i > maxLoops {
+			return nil, fmt.Errorf("loop %q must be 1 to %d or all", n, maxLoops)
+		}
+		loops = append(loops, i-1)
+	}
+	return loops, nil
+}
+
+// targets returns the loops a acts on now.
+func (a footAction) targets() []int {
+	if a.Loops != nil {
+		return a.Loops
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return []int{selectedLoop}
+}
+
+func (a footAction) run() {
+	for _, i := range a.targets() {
+		hitLoop(i, a.Cmd)
+	}
+}
+
+// hold starts a's momentary command, returning the loops to release.
+func (a footAction) hold() []int {
+	loops := a.targets()
+	holdLoops(loops, a.Cmd)
+	return loops
+}
+
+// startFootswitches reads every configured footswitch until the services
+// stop. The config file has been validated.
+func startFootswitches(cfgs []footswitchConfig) {
+	for _, f := range cfgs {
+		b, _ := f.bindings()
+		goService("footswitch "+f.Device, func(ctx context.Context) error {
+			watchFootswitch(ctx, f.Device, b)
+			return nil
+		})
+	}
+}
+
+// watchFootswitch reads the device until ctx is done, reopening it whenever
+// it goes away so a pedal can be unplugged and plugged back in. Momentary
+// commands last while their key is down.
+func watchFootswitch(ctx context.Context, device string, b map[uint16]footAction) {
+	var lastErr string
+	held := map[uint16][]int{}
+	for {
+		err := readFootswitch(ctx, device, func(code uint16, pressed bool) {
+			a, ok := b[code]
+			switch {
+			case !ok:
+			case momentary(a.Cmd) && pressed:
+				footLog.Debug("held", "device", device, "code", code, "cmd", a.Cmd)
+				held[code] = a.hold()
+			case momentary(a.Cmd):
+				releaseLoops(held[code], a.Cmd)
+				delete(held, code)
+			case pressed:
+				footLog.Debug("pressed", "device", device, "code", code, "cmd", a.Cmd)
+				a.run()
+			}
+		})
+		for code, loops := range held {
+			releaseLoops(loops, b[code].Cmd)
+			delete(held, code)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err.Error() != lastErr {
+			footLog.Warn("footswitch unavailable", "device", device, "err", err)
+			lastErr = err.Error()
+		}
+		if !sleepCtx(ctx, footswitchRetry) {
+			return
+		}
+	}
+}
+
+// readFootswitch opens the device and calls key for each key press and
+// release until reading fails or ctx is done.
+func readFootswitch(ctx context.Context, device string, key func(code uint16, pressed bool)) error {
+	f, err := os.Open(device)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stop := context.AfterFunc(ctx, func() { f.Close() })
+	defer stop()
+	if err := grabInput(f); err != nil {
+		footLog.Warn("footswitch not grabbed; its keys also reach the terminal", "device", device, "err", err)
+	}
+	footLog.Info("footswitch open", "device", device)
+	return readKeys(f, key)
+}
+
+// readKeys decodes input events from r, calling key for each key press
+// and release, until r fails. Repeats are left out.
+func readKeys(r io.Reader, key func(code uint16, pressed bool)) error {
+	buf := make([]byte, inputEventSize)
+	for {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		ev := buf[inputEventSize-8:]
+		typ := binary.NativeEndian.Uint16(ev[0:])
+		code := binary.NativeEndian.Uint16(ev[2:])
+		value := int32(binary.NativeEndian.Uint32(ev[4:]))
+		if typ == evKey && (value == keyPressed || value == keyReleased) {
+			key(code, value == keyPressed)
+		}
+	}
+}
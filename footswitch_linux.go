@@ -0,0 +1,19 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+const evIOCGRAB = 0x40044590
+
+// grabInput takes the input device for sooperGUI alone, so a pedal that
+// acts as a keyboard does not also type into the console.
+func grabInput(f *os.File) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), evIOCGRAB, 1); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+)
+
+// TestFootswitchBindings tests key names, codes and action parsing
+func TestFootswitchBindings(t *testing.T) {
+	f := footswitchConfig{Device: "/dev/input/event5", Keys: map[string]string{
+		"KEY_A": "record 1",
+		"key_b": "mute 2,3",
+		"BTN_0": "undo all",
+		"0x2c0": "overdub",
+	}}
+	b, err := f.bindings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for code, want := range map[uint16]footAction{
+		30:    {Cmd: "record", Loops: []int{0}},
+		48:    {Cmd: "mute", Loops: []int{1, 2}},
+		0x100: {Cmd: "undo", Loops: []int{-1}},
+		0x2c0: {Cmd: "overdub"},
+	} {
+		if got := b[code]; got.Cmd != want.Cmd || !slices.Equal(got.Loops, want.Loops) {
+			t.Errorf("code %#x: %+v, want %+v", code, got, want)
+		}
+	}
+
+	for _, bad := range []footswitchConfig{
+		{Keys: map[string]string{"KEY_A": "record"}},
+		{Device: "x", Keys: map[string]string{"KEY_HOME": "record"}},
+		{Device: "x", Keys: map[string]string{"KEY_A": "explode 1"}},
+		{Device: "x", Keys: map[string]string{"KEY_A": "record 0"}},
+		{Device: "x", Keys: map[string]string{"KEY_A": "record 1 2"}},
+	} {
+		if _, err := bad.bindings(); err == nil {
+			t.Errorf("%+v: no error", bad)
+		}
+	}
+}
+
+func inputEvent(typ, code uint16, value int32) []byte {
+	b := make([]byte, inputEventSize)
+	ev := b[inputEventSize-8:]
+	binary.NativeEndian.PutUint16(ev[0:], typ)
+	binary.NativeEndian.PutUint16(ev[2:], code)
+	binary.NativeEndian.PutUint32(ev[4:], uint32(value))
+	return b
+}
+
+// TestReadKeys tests that key presses and releases are reported, not repeats or other events
+func TestReadKeys(t *testing.T) {
+	var in bytes.Buffer
+	in.Write(inputEvent(4, 4, 0x70004)) // EV_MSC scan code
+	in.Write(inputEvent(evKey, 30, 1))
+	in.Write(inputEvent(0, 0, 0)) // EV_SYN
+	in.Write(inputEvent(evKey, 30, 2))
+	in.Write(inputEvent(evKey, 30, 0))
+	in.Write(inputEvent(evKey, 48, 1))
+	var got []string
+	err := readKeys(&in, func(code uint16, pressed bool) { got = append(got, fmt.Sprint(code, pressed)) })
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("err = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if want := []string{"30 true", "30 false", "48 true"}; !slices.Equal(got, want) {
+		t.Errorf("keys %v, want %v", got, want)
+	}
+}
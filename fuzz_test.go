@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// FuzzHandleOSC feeds handleOSC messages with random addresses and argument
+// type combinations. Each byte of types picks the type of one argument.
+func FuzzHandleOSC(f *testing.F) {
+	cfg, _ := mixerPreset("slmock")
+	extMixer = newMixer(cfg)
+	f.Add("/sl/0/update_state", "\x00\x03\x01", int32(0), float32(4), "state")
+	f.Add("/sl/1/update_in_peak_meter", "\x00\x03\x02", int32(1), float32(0.5), "in_peak_meter")
+	f.Add("/pong/1", "\x03\x03\x00", int32(3), float32(0), "1.7.0")
+	f.Add("/pong", "\x03\x03\x00", int32(math.MaxInt32), float32(0), "")
+	f.Add("/strip/Sooper1/Gain/Gain%20(dB)", "\x01", int32(0), float32(0.7), "")
+	f.Add("/strip/Sooper0/Gain/Gain%20(dB)", "\x04", int32(0), float32(0), "")
+	f.Add("/sl/-3/update_loop_pos", "\x00\x03\x01", int32(-3), float32(1), "loop_pos")
+	f.Add("/sl//update_wet", "", int32(0), float32(0), "")
+	f.Add("/sl/99999999999/update_state", "\x00\x03\x01", int32(0), float32(math.NaN()), "state")
+
+	f.Fuzz(func(t *testing.T, addr, types string, i int32, x float32, s string) {
+		m := osc.NewMessage(addr)
+		for _, c := range []byte(types) {
+			switch c % 8 {
+			case 0:
+				m.Append(i)
+			case 1:
+				m.Append(x)
+			case 2:
+				m.Append(float64(x))
+			case 3:
+				m.Append(s)
+			case 4:
+				m.Append(int64(i))
+			case 5:
+				m.Append(true)
+			case 6:
+				m.Append([]byte(s))
+			case 7:
+				m.Append(nil)
+			}
+		}
+		handleOSC(m)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if loopCount < 0 || loopCount > maxLoops {
+			t.Fatalf("loopCount = %d after %s %v", loopCount, addr, m.Arguments)
+		}
+		for idx := range loopStates {
+			if !validLoopIndex(idx) {
+				t.Fatalf("loop state created for index %d by %s %v", idx, addr, m.Arguments)
+			}
+		}
+	})
+}
+
+// FuzzParseLoopUpdate checks parseLoopUpdate against the addresses it is
+// meant to accept.
+func FuzzParseLoopUpdate(f *testing.F) {
+	f.Add("0/update_state", 0)
+	f.Add("12/update_loop_pos", 12)
+	f.Add("3", 0)
+	f.Add("", 0)
+
+	f.Fuzz(func(t *testing.T, rest string, n int) {
+		parseLoopUpdate(rest)
+		built := fmt.Sprintf("%d/update_state", n)
+		if got, ctrl, ok := parseLoopUpdate(built); !ok || got != n || ctrl != "state" {
+			t.Errorf("parseLoopUpdate(%q) = %d, %q, %v, want %d, state", built, got, ctrl, ok, n)
+		}
+	})
+}
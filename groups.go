@@ -0,0 +1,90 @@
+// groups.go
+// Link groups: the config file's groups tie loops together, such as all the
+// percussion stems, so moving one member's Level bar moves the others by the
+// same dB, keeping their offsets.
+
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// linkGroups are the group names of the loops in a link group, by loop
+// index. Guarded by mu, as the config file may be reloaded.
+var linkGroups map[int]string
+
+// validateGroups checks the config file's groups: loop numbers in range,
+// and no loop in two groups.
+func validateGroups(groups map[string][]int) error {
+	in := map[int]string{}
+	for _, name := range slices.Sorted(maps.Keys(groups)) {
+		for _, n := range groups[name] {
+			if n < 1 || n > maxLoops {
+				return fmt.Errorf("%s: loop %d must be 1 to %d", name, n, maxLoops)
+			}
+			if other, ok := in[n]; ok && other != name {
+				return fmt.Errorf("%s: loop %d is already in %s", name, n, other)
+			}
+			in[n] = name
+		}
+	}
+	return nil
+}
+
+// setLinkGroups sets the link groups from the config file's groups, by
+// loop number. They have been validated.
+func setLinkGroups(groups map[string][]int) {
+	linkGroups = map[int]string{}
+	for name, loops := range groups {
+		for _, n := range loops {
+			linkGroups[n-1] = name
+		}
+	}
+}
+
+// linkedLoops returns the other loops in loop i's link group, in order.
+// The caller must hold mu.
+func linkedLoops(i int) []int {
+	name, ok := linkGroups[i]
+	if !ok {
+		return nil
+	}
+	var out []int
+	for j := range loopCount {
+		if j != i && linkGroups[j] == name {
+			out = append(out, j)
+		}
+	}
+	return out
+}
+
+// linkedLevels moves the Levels in from, of a loop and its group, by the dB
+// that takes loop idx to to. The move stops where the loudest loop reaches
+// levelMax, so the offsets between them hold. Silent loops have no offset:
+// they stay silent while idx is heard, and come up with idx when it starts
+// from silence, which leaves the others. Taking idx to silence silences
+// them all.
+func linkedLevels(from map[int]float32, idx int, to float32) map[int]float32 {
+	out := maps.Clone(from)
+	if to <= 0 {
+		for i := range out {
+			out[i] = 0
+		}
+		return out
+	}
+	silent := from[idx] <= 0
+	db := ampToDB(to) - ampToDB(max(from[idx], float32(dbToAmp(meterMinDB))))
+	for _, v := range from {
+		if !silent && v > 0 {
+			db = min(db, ampToDB(levelMax)-ampToDB(v))
+		}
+	}
+	for i, v := range from {
+		if silent == (v <= 0) {
+			out[i] = nudgeLevel(v, db)
+		}
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestValidateGroups tests loop numbers and loops in two groups
+func TestValidateGroups(t *testing.T) {
+	if _, err := parseConfig([]byte("groups: {percussion: [1, 2, 3], keys: [4]}")); err != nil {
+		t.Errorf("good groups: %v", err)
+	}
+	for _, bad := range []string{
+		"groups: {percussion: [0, 1]}",
+		"groups: {percussion: [1, 99]}",
+		"groups: {percussion: [1, 2], keys: [2, 3]}",
+	} {
+		if _, err := parseConfig([]byte(bad)); err == nil {
+			t.Errorf("%s was accepted", bad)
+		}
+	}
+}
+
+// TestLinkedLevels tests that a group moves by the same dB, stops at
+// levelMax and treats silence apart
+func TestLinkedLevels(t *testing.T) {
+	near := func(a, b float32) bool { return math.Abs(ampToDB(a)-ampToDB(b)) < 0.01 }
+	half := float32(dbToAmp(-6))
+
+	got := linkedLevels(map[int]float32{0: 0.5, 1: 0.25, 2: 0}, 0, half*0.5)
+	if !near(got[0], half*0.5) || !near(got[1], half*0.25) || got[2] != 0 {
+		t.Errorf("6 dB down = %v, want both heard loops 6 dB down and loop 3 silent", got)
+	}
+	got = linkedLevels(map[int]float32{0: 0.25, 1: levelMax / 2}, 0, 1)
+	if !near(got[1], levelMax) || !near(got[0], 0.5) {
+		t.Errorf("up = %v, want loop 2 at levelMax and loop 1 6 dB up", got)
+	}
+	got = linkedLevels(map[int]float32{0: 0, 1: 0, 2: 0.5}, 0, 0.1)
+	if !near(got[0], 0.1) || !near(got[1], 0.1) || got[2] != 0.5 {
+		t.Errorf("from silence = %v, want the silent loops at 0.1 and loop 3 left", got)
+	}
+	got = linkedLevels(map[int]float32{0: 0.5, 1: 0.25}, 1, 0)
+	if got[0] != 0 || got[1] != 0 {
+		t.Errorf("to silence = %v, want both silent", got)
+	}
+}
+
+// TestEditLinkedLevel tests that a drag moves the group from where it
+// started, and undoes as one
+func TestEditLinkedLevel(t *testing.T) {
+	levelThrottle = newSendThrottle(1000, 0, func(int, float32) {})
+	reset := func(groups map[string][]int) {
+		mu.Lock()
+		guiUndo = undoStack{}
+		loopStates = make(map[int]*LoopState)
+		loopCount = 3
+		setLinkGroups(groups)
+		mu.Unlock()
+	}
+	t.Cleanup(func() { reset(nil) })
+	reset(map[string][]int{"drums": {1, 2}})
+	mu.Lock()
+	getLoopState(0).Wet, getLoopState(1).Wet, getLoopState(2).Wet = 0.5, 0.25, 0.7
+	mu.Unlock()
+	wets := func() (a, b, c float32) {
+		mu.Lock()
+		defer mu.Unlock()
+		return getLoopState(0).Wet, getLoopState(1).Wet, getLoopState(2).Wet
+	}
+
+	// Down to where loop 2 is silent, then back up.
+	editLevel(0, float32(dbToAmp(meterMinDB+3)))
+	if _, b, _ := wets(); b != 0 {
+		t.Errorf("loop 2 at %v, want silent below the floor", b)
+	}
+	editLevel(0, 0.25)
+	if a, b, c := wets(); a != 0.25 || math.Abs(ampToDB(b)-ampToDB(0.125)) > 0.01 || c != 0.7 {
+		t.Errorf("levels %v %v %v, want 0.25, 0.125 and loop 3 left at 0.7", a, b, c)
+	}
+
+	undoGUIChange(false)
+	if a, b, _ := wets(); a != 0.5 || b != 0.25 {
+		t.Errorf("after undo %v %v, want 0.5 and 0.25", a, b)
+	}
+	undoGUIChange(true)
+	if a, b, _ := wets(); a != 0.25 || math.Abs(ampToDB(b)-ampToDB(0.125)) > 0.01 {
+		t.Errorf("after redo %v %v, want 0.25 and 0.125", a, b)
+	}
+}
@@ -0,0 +1,93 @@
+// headless.go
+// One-shot rendering of the table to stdout, without the TUI.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// renderOnce waits until every loop has reported its state, lets one more
+// refresh interval pass so meters fill in, then writes the table to w.
+func renderOnce(w io.Writer, format string, width int, timeout time.Duration) error {
+	style := plainSpan
+	switch format {
+	case "plain":
+	case "ansi":
+		style = ansiSpan
+	default:
+		return fmt.Errorf("unknown render format %q (want plain or ansi)", format)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for !engineReady() {
+		if time.Now().After(deadline) {
+			return errors.New("no reply from the engine")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	time.Sleep(time.Duration(refreshRate) * time.Millisecond)
+
+	mu.Lock()
+	loops := make([]*LoopState, loopCount)
+	for i := range loops {
+		loops[i] = getLoopState(i)
+	}
+	rows := renderTable(tableOptions{Width: width, Braille: meterStyle == "braille", Gradient: meterColors == "gradient", PosClock: posClock(posStyle, width), PosCycles: posStyle == "cycles"}, loops, time.Now())
+	mu.Unlock()
+
+	_, err := io.WriteString(w, formatRows(rows, style))
+	return err
+}
+
+// engineReady reports whether the engine has answered a ping and every loop
+// it reported has a known state.
+func engineReady() bool {
+	if sl != nil && !sl.Online() {
+		return false
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 0; i < loopCount; i++ {
+		if ls := loopStates[i]; ls == nil || !ls.haveState {
+			return false
+		}
+	}
+	return true
+}
+
+var ansiColors = map[tcell.Color]string{
+	tcell.ColorRed:    "31",
+	tcell.ColorGreen:  "32",
+	tcell.ColorYellow: "33",
+}
+
+// ansiSpan renders a span with SGR escape codes, using the basic palette
+// where possible so the output respects the terminal's color scheme.
+func ansiSpan(s span) string {
+	var codes string
+	if s.Bold {
+		codes = "1"
+	}
+	if s.Color != tcell.ColorDefault {
+		c, ok := ansiColors[s.Color]
+		if !ok {
+			r, g, b := s.Color.RGB()
+			c = "38;2;" + strconv.Itoa(int(r)) + ";" + strconv.Itoa(int(g)) + ";" + strconv.Itoa(int(b))
+		}
+		if codes != "" {
+			codes += ";"
+		}
+		codes += c
+	}
+	if codes == "" || s.Text == "" {
+		return s.Text
+	}
+	return "\x1b[" + codes + "m" + s.Text + "\x1b[0m"
+}
@@ -0,0 +1,56 @@
+// history.go
+// Timestamped record of loop state transitions.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"jaudio/internal/slstate"
+)
+
+const historyLimit = 1000
+
+type stateEvent struct {
+	At       time.Time
+	Loop     int
+	From, To slstate.State
+}
+
+func (e stateEvent) String() string {
+	return fmt.Sprintf("%s L%d %s→%s", e.At.Format("15:04:05"), e.Loop+1, e.From, e.To)
+}
+
+// stateHistory keeps the most recent transitions, oldest first. It is
+// guarded by mu along with loopStates.
+type stateHistory struct {
+	events []stateEvent
+}
+
+func (h *stateHistory) record(e stateEvent) {
+	h.events = append(h.events, e)
+	if len(h.events) > historyLimit {
+		h.events = append(h.events[:0], h.events[len(h.events)-historyLimit:]...)
+	}
+}
+
+// recent returns up to n events, newest first.
+func (h *stateHistory) recent(n int) []stateEvent {
+	out := make([]stateEvent, 0, min(n, len(h.events)))
+	for i := len(h.events) - 1; i >= 0 && len(out) < n; i-- {
+		out = append(out, h.events[i])
+	}
+	return out
+}
+
+// recentFor returns up to n events of one loop, newest first.
+func (h *stateHistory) recentFor(loop, n int) []stateEvent {
+	var out []stateEvent
+	for i := len(h.events) - 1; i >= 0 && len(out) < n; i-- {
+		if h.events[i].Loop == loop {
+			out = append(out, h.events[i])
+		}
+	}
+	return out
+}
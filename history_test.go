@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"jaudio/internal/slstate"
+)
+
+// TestStateEventString tests the formatting of a state transition
+func TestStateEventString(t *testing.T) {
+	at := time.Date(2025, 5, 10, 12, 3, 5, 0, time.Local)
+	e := stateEvent{At: at, Loop: 1, From: 4, To: 5}
+	if got, want := e.String(), "12:03:05 L2 Play→Overdub"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	e = stateEvent{At: at, Loop: 0, From: 99, To: 0}
+	if got, want := e.String(), "12:03:05 L1 State99→Off"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+// TestStateHistory tests ordering and the size limit of the history
+func TestStateHistory(t *testing.T) {
+	var h stateHistory
+	for i := 0; i < historyLimit+10; i++ {
+		h.record(stateEvent{Loop: i})
+	}
+	if len(h.events) != historyLimit {
+		t.Fatalf("kept %d events, want %d", len(h.events), historyLimit)
+	}
+	got := h.recent(3)
+	if len(got) != 3 || got[0].Loop != historyLimit+9 || got[2].Loop != historyLimit+7 {
+		t.Errorf("recent(3) = %v, want the newest three, newest first", got)
+	}
+	if got := (&stateHistory{}).recent(5); len(got) != 0 {
+		t.Errorf("recent on empty history = %v", got)
+	}
+}
+
+// TestHistoryRecentFor tests filtering transitions by loop
+func TestHistoryRecentFor(t *testing.T) {
+	var h stateHistory
+	for i, loop := range []int{0, 1, 0, 1, 1} {
+		h.record(stateEvent{Loop: loop, From: slstate.State(i), To: slstate.State(i + 1)})
+	}
+	got := h.recentFor(1, 2)
+	if len(got) != 2 || got[0].From != 4 || got[1].From != 3 {
+		t.Errorf("recentFor(1, 2) = %v, want the last two loop 2 events, newest first", got)
+	}
+}
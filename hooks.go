@@ -0,0 +1,133 @@
+// hooks.go
+// Hooks: the config file's hooks run a shell command on events such as a
+// loop starting to record or the engine going away, with the event's data
+// in SOOPERGUI_ environment variables, for lighting cues, logging or
+// notifications.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"jaudio/internal/slstate"
+)
+
+// hookEvents are the events hooks can run on, and the variables each sets
+// besides SOOPERGUI_EVENT and SOOPERGUI_TIME.
+var hookEvents = map[string][]string{
+	"loop_state":        {"SOOPERGUI_LOOP", "SOOPERGUI_FROM", "SOOPERGUI_TO"},
+	"loop_record_start": {"SOOPERGUI_LOOP", "SOOPERGUI_FROM", "SOOPERGUI_TO"},
+	"loop_record_stop":  {"SOOPERGUI_LOOP", "SOOPERGUI_FROM", "SOOPERGUI_TO"},
+	"clip":              {"SOOPERGUI_LOOP", "SOOPERGUI_METER"},
+	"engine_connect":    {"SOOPERGUI_ENGINE", "SOOPERGUI_LOOPS"},
+	"engine_disconnect": {"SOOPERGUI_ENGINE"},
+}
+
+// hookTimeout is how long a hook may run before it is killed, so a stuck
+// one does not hold up the hooks after it.
+const hookTimeout = 10 * time.Second
+
+// hookRun is a hook to run: its event, command and environment.
+type hookRun struct {
+	event   string
+	command string
+	env     []string
+}
+
+var (
+	// hookCommands are the config file's hooks by event. They are set at
+	// startup and not changed after.
+	hookCommands map[string]string
+	// hookRuns feeds the hook runner; full means hooks are dropped rather
+	// than queued behind slow ones.
+	hookRuns = make(chan hookRun, 32)
+)
+
+// validateHooks checks the config file's hooks: known events, each with a
+// command.
+func validateHooks(hooks map[string]string) error {
+	for _, event := range slices.Sorted(maps.Keys(hooks)) {
+		if _, ok := hookEvents[event]; !ok {
+			return fmt.Errorf("%s: must be one of %s", event, strings.Join(slices.Sorted(maps.Keys(hookEvents)), ", "))
+		}
+		if strings.TrimSpace(hooks[event]) == "" {
+			return fmt.Errorf("%s: needs a command", event)
+		}
+	}
+	return nil
+}
+
+// setHooks sets the hooks from the config file's hooks. They have been
+// validated.
+func setHooks(hooks map[string]string) {
+	hookCommands = maps.Clone(hooks)
+}
+
+// runHook runs the hook for event, if there is one, without waiting. vars
+// are its variables' values, in hookEvents order.
+func runHook(event string, vars ...string) {
+	command, ok := hookCommands[event]
+	if !ok {
+		return
+	}
+	env := []string{"SOOPERGUI_EVENT=" + event, "SOOPERGUI_TIME=" + time.Now().Format(time.RFC3339Nano)}
+	for i, name := range hookEvents[event] {
+		env = append(env, name+"="+vars[i])
+	}
+	select {
+	case hookRuns <- hookRun{event, command, env}:
+	default:
+		tuiLog.Warn("hook dropped", "hook", event)
+	}
+}
+
+// stateHooks runs the hooks for a loop state change.
+func stateHooks(e stateEvent) {
+	vars := []string{strconv.Itoa(e.Loop + 1), e.From.String(), e.To.String()}
+	runHook("loop_state", vars...)
+	switch {
+	case e.To == slstate.Record:
+		runHook("loop_record_start", vars...)
+	case e.From.Recording() && !e.To.Recording():
+		runHook("loop_record_stop", vars...)
+	}
+}
+
+// startHooks runs hooks one at a time, in order. A hook still running on
+// quit is killed.
+func startHooks() {
+	goService("hooks", func(ctx context.Context) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case h := <-hookRuns:
+				h.run(ctx)
+			}
+		}
+	})
+}
+
+// run runs the hook's command for at most hookTimeout, or until ctx is
+// done.
+func (h hookRun) run(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+	cmd := host.shellCommand(ctx, h.command)
+	cmd.Env = append(os.Environ(), h.env...)
+	// Children left behind by a killed shell may hold its output open.
+	cmd.WaitDelay = time.Second
+	start := time.Now()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		tuiLog.Warn("hook failed", "hook", h.event, "err", err, "output", strings.TrimSpace(string(out)))
+	} else {
+		tuiLog.Debug("hook ran", "hook", h.event, "in", time.Since(start))
+	}
+}
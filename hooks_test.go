@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"jaudio/internal/slstate"
+)
+
+// TestValidateHooks tests hook events and commands
+func TestValidateHooks(t *testing.T) {
+	if _, err := parseConfig([]byte(`hooks: {loop_record_start: "curl -s http://lights/red", engine_disconnect: notify-send gone}`)); err != nil {
+		t.Errorf("good hooks: %v", err)
+	}
+	for _, bad := range []string{"hooks: {record: echo}", "hooks: {clip: ' '}"} {
+		if _, err := parseConfig([]byte(bad)); err == nil {
+			t.Errorf("%s was accepted", bad)
+		}
+	}
+}
+
+// TestStateHooks tests which hooks a state change runs, and their
+// variables
+func TestStateHooks(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+	setHooks(map[string]string{"loop_state": "a", "loop_record_start": "b", "loop_record_stop": "c"})
+	defer setHooks(nil)
+	ran := func() (events []string, last []string) {
+		for {
+			select {
+			case h := <-hookRuns:
+				events, last = append(events, h.event), h.env
+			default:
+				return events, last
+			}
+		}
+	}
+
+	stateHooks(stateEvent{Loop: 1, From: slstate.WaitStart, To: slstate.Record})
+	events, env := ran()
+	if !slices.Equal(events, []string{"loop_state", "loop_record_start"}) {
+		t.Errorf("record start ran %q", events)
+	}
+	for _, v := range []string{"SOOPERGUI_EVENT=loop_record_start", "SOOPERGUI_LOOP=2", "SOOPERGUI_FROM=WaitStart", "SOOPERGUI_TO=Record"} {
+		if !slices.Contains(env, v) {
+			t.Errorf("environment %q lacks %s", env, v)
+		}
+	}
+	stateHooks(stateEvent{Loop: 1, From: slstate.WaitStop, To: slstate.Play})
+	stateHooks(stateEvent{Loop: 1, From: slstate.Play, To: slstate.Overdub})
+	if events, _ := ran(); !slices.Equal(events, []string{"loop_state", "loop_record_stop", "loop_state"}) {
+		t.Errorf("record stop and overdub ran %q", events)
+	}
+}
+
+// TestRunHooks tests that a hook's command runs with its variables
+func TestRunHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell")
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	mu.Lock()
+	setHooks(map[string]string{"clip": `echo "$SOOPERGUI_EVENT $SOOPERGUI_LOOP $SOOPERGUI_METER" > ` + out})
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		setHooks(nil)
+		mu.Unlock()
+	}()
+
+	mu.Lock()
+	meterClip(2, &LoopState{InPeakMeter: 1})
+	mu.Unlock()
+	select {
+	case h := <-hookRuns:
+		h.run(context.Background())
+	default:
+		t.Fatal("clip ran no hook")
+	}
+	data, _ := os.ReadFile(out)
+	if got := strings.TrimSpace(string(data)); got != "clip 3 in" {
+		t.Errorf("hook wrote %q, want clip 3 in", got)
+	}
+}
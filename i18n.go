@@ -0,0 +1,76 @@
+// i18n.go
+// Translations of the TUI's text, and the choice of language.
+
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// The TUI's text is written in English in the code. Each other language
+// has a catalog, in locale_<lang>.go, mapping the English text or format
+// string to its translation. Text missing from a catalog shows in English.
+var (
+	// catalogs are the translations by language, English excluded.
+	catalogs = map[string]map[string]string{
+		"de": catalogDE,
+	}
+	// catalog is the current language's, nil for English.
+	catalog map[string]string
+)
+
+// languages are the languages there is text for.
+func languages() []string {
+	return append([]string{"en"}, slices.Sorted(maps.Keys(catalogs))...)
+}
+
+// setLanguage picks the language: lang, from --lang, if set, otherwise
+// $SOOPERGUI_LANG or the locale ($LC_ALL, $LC_MESSAGES, $LANG). Locales
+// such as de_DE.UTF-8 give their language; unknown ones give English.
+func setLanguage(lang string) error {
+	if lang == "" {
+		lang = envLanguage()
+		if _, ok := catalogs[lang]; !ok {
+			lang = "en"
+		}
+	}
+	if lang == "en" {
+		catalog = nil
+		return nil
+	}
+	cat, ok := catalogs[lang]
+	if !ok {
+		return fmt.Errorf("unknown language %q, want one of %s", lang, strings.Join(languages(), ", "))
+	}
+	catalog = cat
+	return nil
+}
+
+// envLanguage is the language named by the environment, or "".
+func envLanguage() string {
+	for _, name := range []string{"SOOPERGUI_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			lang, _, _ := strings.Cut(v, "_")
+			lang, _, _ = strings.Cut(lang, ".")
+			return strings.ToLower(lang)
+		}
+	}
+	return ""
+}
+
+// tr returns English text in the current language.
+func tr(s string) string {
+	if t, ok := catalog[s]; ok {
+		return t
+	}
+	return s
+}
+
+// trf formats with an English format string in the current language.
+func trf(format string, args ...any) string {
+	return fmt.Sprintf(tr(format), args...)
+}
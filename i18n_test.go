@@ -0,0 +1,111 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// translatable returns the English text passed as a literal to tr and trf
+// in the package's source.
+func translatable(t *testing.T) []string {
+	t.Helper()
+	files, _ := filepath.Glob("*.go")
+	fset := token.NewFileSet()
+	seen := map[string]bool{}
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			if fn, ok := call.Fun.(*ast.Ident); !ok || fn.Name != "tr" && fn.Name != "trf" {
+				return true
+			}
+			if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				s, _ := strconv.Unquote(lit.Value)
+				seen[s] = true
+			}
+			return true
+		})
+	}
+	return slices.Sorted(maps.Keys(seen))
+}
+
+// verbs are the formatting verbs of a format string, in order.
+var verbs = regexp.MustCompile(`%[-+# 0]*[0-9.]*[a-zA-Z%]`)
+
+// TestCatalogs tests that every catalog translates all of the TUI's text,
+// including the help, page names and loop commands passed to tr
+// indirectly, with the same formatting verbs
+func TestCatalogs(t *testing.T) {
+	want := translatable(t)
+	want = append(want, usage)
+	want = append(want, pageNames...)
+	for _, cmd := range hitCommands {
+		want = append(want, strings.ReplaceAll(cmd, "_", " "))
+	}
+	want = append(want, "ON", "OFF")
+	want = append(want, slices.Collect(maps.Values(stateWords))...)
+	for lang, cat := range catalogs {
+		for _, s := range want {
+			tr, ok := cat[s]
+			switch {
+			case !ok:
+				t.Errorf("%s: no translation of %q", lang, s)
+			case !slices.Equal(verbs.FindAllString(tr, -1), verbs.FindAllString(s, -1)):
+				t.Errorf("%s: %q has other verbs than %q", lang, tr, s)
+			}
+		}
+		for s := range cat {
+			if !slices.Contains(want, s) {
+				t.Errorf("%s: %q is not used", lang, s)
+			}
+		}
+	}
+}
+
+// TestSetLanguage tests picking the language from the flag and the
+// environment
+func TestSetLanguage(t *testing.T) {
+	defer setLanguage("en")
+	for _, name := range []string{"SOOPERGUI_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		t.Setenv(name, "")
+	}
+
+	t.Setenv("LANG", "de_DE.UTF-8")
+	if err := setLanguage(""); err != nil || tr("Mute") != catalogDE["Mute"] {
+		t.Errorf("LANG=de_DE.UTF-8 gave %q, %v", tr("Mute"), err)
+	}
+	t.Setenv("LC_ALL", "C")
+	if err := setLanguage(""); err != nil || tr("Mute") != "Mute" {
+		t.Errorf("LC_ALL=C gave %q, %v", tr("Mute"), err)
+	}
+	t.Setenv("SOOPERGUI_LANG", "de")
+	if err := setLanguage("en"); err != nil || tr("Mute") != "Mute" {
+		t.Errorf("--lang en gave %q, %v", tr("Mute"), err)
+	}
+	if err := setLanguage("fr"); err == nil {
+		t.Errorf("setLanguage(fr) succeeded")
+	}
+	if err := setLanguage("de"); err != nil || trf("Loop %d", 3) != "Loop 3" {
+		t.Errorf("trf(Loop %%d, 3) in German = %q, %v", trf("Loop %d", 3), err)
+	}
+	if got := tr("not in any catalog"); got != "not in any catalog" {
+		t.Errorf("untranslated text = %q", got)
+	}
+}
@@ -0,0 +1,214 @@
+// inspector.go
+// Developer screen listing live OSC traffic.
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/hypebeast/go-osc/osc"
+	"github.com/rivo/tview"
+)
+
+const traceSize = 1000
+
+var trace = &oscTrace{entries: make([]traceEntry, traceSize)}
+
+type traceEntry struct {
+	At  time.Time
+	Out bool
+	Msg *osc.Message
+}
+
+// oscTrace is a ring of the most recent OSC messages in both directions.
+type oscTrace struct {
+	mu      sync.Mutex
+	entries []traceEntry
+	next    int
+	full    bool
+}
+
+func (t *oscTrace) add(out bool, m *osc.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries[t.next] = traceEntry{time.Now(), out, m}
+	t.next = (t.next + 1) % len(t.entries)
+	if t.next == 0 {
+		t.full = true
+	}
+}
+
+// matching returns entries whose address matches filter, oldest first. A
+// filter containing glob characters is matched with path.Match, otherwise
+// as a substring.
+func (t *oscTrace) matching(filter string) []traceEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []traceEntry
+	start, count := 0, t.next
+	if t.full {
+		start, count = t.next, len(t.entries)
+	}
+	for i := 0; i < count; i++ {
+		e := t.entries[(start+i)%len(t.entries)]
+		if addressMatches(e.Msg.Address, filter) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func addressMatches(addr, filter string) bool {
+	if filter == "" {
+		return true
+	}
+	if strings.ContainsAny(filter, "*?[") {
+		ok, _ := path.Match(filter, addr)
+		return ok
+	}
+	return strings.Contains(addr, filter)
+}
+
+func oscSend(c oscSender, m *osc.Message) {
+	if c == nil {
+		return
+	}
+	trace.add(true, m)
+	oscLog.Debug("out", "addr", m.Address, "args", m.Arguments)
+	_ = c.Send(m)
+	if isControlChange(m.Address) {
+		mirrorOut(m)
+	}
+}
+
+// oscSendBundle sends messages as one bundle, so the engine applies them
+// together. The bundle is timetagged immediate: liblo holds back bundles
+// stamped in its future, which a clock behind ours would make every one.
+func oscSendBundle(c oscSender, msgs []*osc.Message) {
+	if c == nil || len(msgs) == 0 {
+		return
+	}
+	b := osc.NewBundle(time.Time{})
+	for _, m := range msgs {
+		trace.add(true, m)
+		oscLog.Debug("out", "addr", m.Address, "args", m.Arguments)
+		b.Append(m)
+	}
+	_ = c.Send(b)
+	for _, m := range msgs {
+		if isControlChange(m.Address) {
+			mirrorOut(m)
+		}
+	}
+}
+
+// inspector is the OSC traffic screen: a filter field, the message list and
+// a detail view of the selected message.
+type inspector struct {
+	root   *tview.Flex
+	filter *tview.InputField
+	list   *tview.Table
+	detail *tview.TextView
+
+	paused bool
+	hex    bool
+	shown  []traceEntry
+}
+
+func newInspector(app *tview.Application) *inspector {
+	in := &inspector{
+		filter: tview.NewInputField().SetLabel(tr("Filter:") + " "),
+		list:   tview.NewTable().SetSelectable(true, false).SetFixed(1, 0),
+		detail: tview.NewTextView(),
+	}
+	in.list.SetBorder(true)
+	in.detail.SetBorder(true).SetTitle(" " + tr("Message") + " ")
+	in.root = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(in.filter, 1, 0, false).
+		AddItem(in.list, 0, 2, true).
+		AddItem(in.detail, 0, 1, false)
+
+	in.filter.SetDoneFunc(func(tcell.Key) { app.SetFocus(in.list) })
+	in.list.SetSelectionChangedFunc(func(row, _ int) { in.showDetail(row) })
+	in.list.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() != tcell.KeyRune {
+			return ev
+		}
+		switch ev.Rune() {
+		case '/':
+			app.SetFocus(in.filter)
+		case ' ', 'p':
+			in.paused = !in.paused
+		case 'x':
+			in.hex = !in.hex
+			row, _ := in.list.GetSelection()
+			in.showDetail(row)
+		default:
+			return ev
+		}
+		return nil
+	})
+	return in
+}
+
+func (in *inspector) refresh() {
+	state := tr("live")
+	if in.paused {
+		state = tr("paused")
+	}
+	in.list.SetTitle(" " + trf("OSC traffic (%s) – /: filter, space: pause, x: hex, F10: close", state) + " ")
+	if in.paused {
+		return
+	}
+
+	follow := false
+	if row, _ := in.list.GetSelection(); row >= len(in.shown) {
+		follow = true
+	}
+	in.shown = trace.matching(in.filter.GetText())
+	in.list.Clear()
+	for i, h := range []string{tr("Time"), tr("Dir"), tr("Address"), tr("Arguments")} {
+		in.list.SetCell(0, i, tview.NewTableCell(h).SetSelectable(false).SetAttributes(tcell.AttrBold))
+	}
+	for i, e := range in.shown {
+		dir, color := "IN", tcell.ColorGreen
+		if e.Out {
+			dir, color = "OUT", tcell.ColorYellow
+		}
+		in.list.SetCell(i+1, 0, tview.NewTableCell(e.At.Format("15:04:05.000")))
+		in.list.SetCell(i+1, 1, tview.NewTableCell(dir).SetTextColor(color))
+		in.list.SetCell(i+1, 2, tview.NewTableCell(tview.Escape(e.Msg.Address)))
+		in.list.SetCell(i+1, 3, tview.NewTableCell(tview.Escape(fmt.Sprint(e.Msg.Arguments))).SetExpansion(1))
+	}
+	if follow && len(in.shown) > 0 {
+		in.list.Select(len(in.shown), 0)
+	}
+}
+
+func (in *inspector) showDetail(row int) {
+	if row < 1 || row > len(in.shown) {
+		in.detail.SetText("")
+		return
+	}
+	m := in.shown[row-1].Msg
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", m.Address)
+	if tags, err := m.TypeTags(); err == nil {
+		fmt.Fprintf(&b, "type tags: %s\n", tags)
+	}
+	for i, a := range m.Arguments {
+		fmt.Fprintf(&b, "  [%d] %T %v\n", i, a, a)
+	}
+	if in.hex {
+		if data, err := m.MarshalBinary(); err == nil {
+			b.WriteString("\n" + hex.Dump(data))
+		}
+	}
+	in.detail.SetText(tview.Escape(b.String()))
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// TestAddressMatches tests substring and glob address filters
+func TestAddressMatches(t *testing.T) {
+	tests := []struct {
+		addr, filter string
+		want         bool
+	}{
+		{"/sl/0/update_state", "", true},
+		{"/sl/0/update_state", "update_state", true},
+		{"/sl/0/update_state", "meter", false},
+		{"/sl/0/update_state", "/sl/*/update_state", true},
+		{"/sl/0/update_state", "/sl/1/*", false},
+		{"/strip/Sooper1/Gain/Gain%20(dB)", "/strip/*/Gain/*", true},
+	}
+	for _, tt := range tests {
+		if got := addressMatches(tt.addr, tt.filter); got != tt.want {
+			t.Errorf("addressMatches(%q, %q) = %v, want %v", tt.addr, tt.filter, got, tt.want)
+		}
+	}
+}
+
+// TestOSCTraceRing tests that the trace keeps the newest messages in order
+func TestOSCTraceRing(t *testing.T) {
+	tr := &oscTrace{entries: make([]traceEntry, 3)}
+	for _, addr := range []string{"/a", "/b", "/c", "/d"} {
+		tr.add(addr == "/b", osc.NewMessage(addr))
+	}
+	got := tr.matching("")
+	if len(got) != 3 || got[0].Msg.Address != "/b" || got[2].Msg.Address != "/d" {
+		t.Fatalf("matching(\"\") = %v, want /b /c /d", got)
+	}
+	if !got[0].Out || got[1].Out {
+		t.Errorf("direction not kept: %v", got)
+	}
+	if got := tr.matching("/c"); len(got) != 1 {
+		t.Errorf("matching(\"/c\") returned %d entries, want 1", len(got))
+	}
+}
+
+// bundleSender keeps the last packet sent on it.
+type bundleSender struct{ last osc.Packet }
+
+func (s *bundleSender) Send(p osc.Packet) error {
+	s.last = p
+	return nil
+}
+
+// TestOSCSendBundle tests that bundles hold the messages in order and are
+// timetagged immediate, whatever the engine's clock
+func TestOSCSendBundle(t *testing.T) {
+	s := &bundleSender{}
+	oscSendBundle(s, []*osc.Message{osc.NewMessage("/a"), osc.NewMessage("/b")})
+	b, ok := s.last.(*osc.Bundle)
+	if !ok || len(b.Messages) != 2 || b.Messages[0].Address != "/a" || b.Messages[1].Address != "/b" {
+		t.Fatalf("sent %#v", s.last)
+	}
+	if tag := b.Timetag.TimeTag(); tag != 1 {
+		t.Errorf("timetag %#x, want 1 (immediate)", tag)
+	}
+}
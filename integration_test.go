@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+	"jaudio/internal/slmock"
+)
+
+// simEngine runs an slmock engine on a loopback UDP address.
+type simEngine struct {
+	*slmock.Engine
+	conn net.PacketConn
+	stop chan struct{}
+}
+
+func startSim(t *testing.T, addr string, loops int) *simEngine {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &simEngine{Engine: slmock.New(loops), conn: conn, stop: make(chan struct{})}
+	go s.Serve(conn)
+	go s.Run(10*time.Millisecond, s.stop)
+	t.Cleanup(s.close)
+	return s
+}
+
+func (s *simEngine) close() {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+		s.conn.Close()
+	}
+}
+
+func (s *simEngine) port() int { return s.conn.LocalAddr().(*net.UDPAddr).Port }
+
+// startClient resets the state store and connects an SLClient to the
+// simulator, which serves as both engine and mixer.
+func startClient(t *testing.T, sim *simEngine) *SLClient {
+	t.Helper()
+	mu.Lock()
+	loopStates = make(map[int]*LoopState)
+	loopCount = 1
+	history = stateHistory{}
+	mu.Unlock()
+	latency = newLatencyTracker()
+
+	cfg, _ := mixerPreset("slmock")
+	cfg.Port = sim.port()
+	mix := newMixer(cfg)
+	mu.Lock()
+	extMixer = mix
+	mu.Unlock()
+	c, err := newSLClient("127.0.0.1:0", "127.0.0.1", sim.port(), mix, handleOSC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.pingEvery, c.pollEvery = 50*time.Millisecond, 20*time.Millisecond
+	c.profile = appConfig.Profile
+	c.Start()
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func hitMessage(loop int, cmd string) *osc.Message {
+	m := osc.NewMessage(fmt.Sprintf("/sl/%d/hit", loop))
+	m.Append(cmd)
+	return m
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		ok := cond()
+		mu.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+// TestIntegrationCommands tests commands and auto updates end to end
+func TestIntegrationCommands(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 3)
+	c := startClient(t, sim)
+
+	eventually(t, "loop count from /pong", func() bool { return loopCount == 3 })
+	eventually(t, "state of every loop", func() bool {
+		for i := 0; i < 3; i++ {
+			if ls := loopStates[i]; ls == nil || !ls.haveState {
+				return false
+			}
+		}
+		return true
+	})
+
+	c.Hit(0, "record")
+	eventually(t, "loop 0 recording", func() bool { return loopStates[0].State == slmock.StateRecording })
+	time.Sleep(100 * time.Millisecond)
+	c.Hit(0, "record")
+	eventually(t, "loop 0 playing", func() bool { return loopStates[0].State == slmock.StatePlaying })
+	eventually(t, "loop 0 position update", func() bool { return loopStates[0].LoopPos > 0 })
+	eventually(t, "loop 2 input meter update", func() bool { return loopStates[2].InPeakMeter > 0 })
+	eventually(t, "loop 0 output meter update", func() bool { return loopStates[0].OutPeakMeter > 0 })
+
+	mu.Lock()
+	events := history.recent(10)
+	mu.Unlock()
+	if len(events) < 2 || events[0].To != slmock.StatePlaying || events[1].To != slmock.StateRecording {
+		t.Errorf("history = %v, want Rec then Play for loop 0", events)
+	}
+
+	// The slmock mixer takes gain in dB.
+	c.SetStripGain(2, 0.1)
+	eventually(t, "strip gain stored by the mixer", func() bool {
+		v, ok := sim.StripGain(2)
+		return ok && math.Abs(float64(v)+20) < 1e-4
+	})
+	eventually(t, "strip gain polled back", func() bool { return math.Abs(float64(loopStates[1].Wet)-0.1) < 1e-6 })
+
+	eventually(t, "initial feedback value", func() bool { return loopStates[2].controls["feedback"] == 1 })
+	c.Set(2, "feedback", 0.3)
+	eventually(t, "feedback change update", func() bool { return loopStates[2].controls["feedback"] == 0.3 })
+}
+
+// TestIntegrationReconnect tests that auto updates are registered again
+// after the engine restarts
+func TestIntegrationReconnect(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 2)
+	c := startClient(t, sim)
+	addr := sim.conn.LocalAddr().String()
+
+	eventually(t, "engine online", func() bool { return c.Online() && loopCount == 2 })
+
+	sim.close()
+	eventually(t, "engine offline", func() bool { return !c.Online() })
+
+	sim = startSim(t, addr, 4)
+	sim.Handle(hitMessage(0, "record"))
+	sim.Handle(hitMessage(0, "record"))
+	mu.Lock()
+	getLoopState(0).LoopPos = -1
+	mu.Unlock()
+
+	eventually(t, "engine back online", func() bool { return c.Online() && loopCount == 4 })
+	eventually(t, "loop 0 position from the new engine", func() bool { return loopStates[0].LoopPos >= 0 })
+	eventually(t, "input meter for a new loop", func() bool {
+		ls := loopStates[3]
+		return ls != nil && ls.InPeakMeter > 0
+	})
+}
+
+// TestIntegrationAutoUpdates tests that loop states, globals and the Loop
+// page controls are registered for rather than polled, and that a lost
+// registration is made again
+func TestIntegrationAutoUpdates(t *testing.T) {
+	t.Cleanup(func() {
+		mu.Lock()
+		currentPage, selectedLoop = pageMixer, 0
+		delete(globals, "tempo")
+		mu.Unlock()
+	})
+	mu.Lock()
+	delete(globals, "tempo")
+	mu.Unlock()
+	sim := startSim(t, "127.0.0.1:0", 2)
+	c := startClient(t, sim)
+	eventually(t, "engine online", func() bool { return c.Online() && loopCount == 2 })
+	for _, ctrl := range polledControls {
+		eventually(t, ctrl+" registered", func() bool { return sim.Subscribed(1, ctrl) })
+	}
+
+	// The registration's get is answered with the old tempo, and replies
+	// are handled concurrently, so wait for it before changing the tempo.
+	eventually(t, "tempo registered", func() bool {
+		_, ok := globals["tempo"]
+		return sim.Subscribed(-2, "tempo") && ok
+	})
+	sim.Handle(osc.NewMessage("/set", "tempo", float32(90)))
+	eventually(t, "tempo update", func() bool { return globals["tempo"] == 90 })
+
+	mu.Lock()
+	currentPage, selectedLoop = pageLoop, 1
+	mu.Unlock()
+	eventually(t, "Loop page registered", func() bool { return sim.Subscribed(1, "rate") })
+	mu.Lock()
+	selectedLoop = 0
+	mu.Unlock()
+	eventually(t, "Loop page moved", func() bool { return sim.Subscribed(0, "rate") && !sim.Subscribed(1, "rate") })
+
+	c.mu.Lock()
+	c.reregisterEvery = 100 * time.Millisecond
+	c.mu.Unlock()
+	lost := osc.NewMessage("/sl/0/unregister_auto_update")
+	lost.Append("state", c.returnURL, "/sl/0/update_state")
+	sim.Handle(lost)
+	eventually(t, "state registered again", func() bool { return sim.Subscribed(0, "state") })
+}
+
+// TestReplyURL tests the reply address and its --return-host and
+// --return-port overrides
+func TestReplyURL(t *testing.T) {
+	defer func() { returnHost, returnPort = "", 0 }()
+	if got, want := replyURL("localhost", 5000), "osc.udp://127.0.0.1:5000"; got != want {
+		t.Errorf("replyURL = %q, want %q", got, want)
+	}
+	returnHost = "10.0.0.5"
+	if got, want := replyURL("127.0.0.1", 5000), "osc.udp://10.0.0.5:5000"; got != want {
+		t.Errorf("with --return-host, replyURL = %q, want %q", got, want)
+	}
+	returnHost, returnPort = "fd00::5", 9000
+	if got, want := replyURL("127.0.0.1", 5000), "osc.udp://[fd00::5]:9000"; got != want {
+		t.Errorf("with --return-host and --return-port, replyURL = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,747 @@
+// Package slmock simulates the SooperLooper OSC interface closely enough to
+// drive sooperGUI end to end: /ping, per-loop get/set, register_auto_update
+// with periodic updates, register_update for loop and global controls, and
+// the /sl/<n>/hit state machine. It also mocks the strip gain endpoint of an
+// external mixer.
+package slmock
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+const Version = "1.7.9-slmock"
+
+// SooperLooper loop states.
+const (
+	StateUnknown    = -1
+	StateOff        = 0
+	StateWaitStart  = 1
+	StateRecording  = 2
+	StateWaitStop   = 3
+	StatePlaying    = 4
+	StateOverdub    = 5
+	StateMultiply   = 6
+	StateInsert     = 7
+	StateReplace    = 8
+	StateMuted      = 10
+	StateScratch    = 11
+	StateOneShot    = 12
+	StateSubstitute = 13
+	StatePaused     = 14
+	StateOffMuted   = 20
+)
+
+// Loop index wildcards accepted in /sl/<n>/ addresses, and the index
+// global controls are sent with.
+const (
+	allLoops     = -1
+	globalLoop   = -2
+	selectedLoop = -3
+)
+
+var stripGainPath = regexp.MustCompile(`^/strip/Sooper(\d+)/Gain/Gain%20\(dB\)$`)
+
+var defaultControls = map[string]float32{
+	"wet": 1, "dry": 1, "feedback": 1, "input_gain": 1, "rate": 1,
+	"pan_1": 0.5, "pan_2": 0.5, "stretch_ratio": 1,
+	"quantize": 0, "sync": 0, "playback_sync": 0, "round": 0,
+	"relative_sync": 0, "mute_quantized": 0, "overdub_quantized": 0,
+	"replace_quantized": 0,
+	"use_feedback_play": 0, "rec_thresh": 0, "scratch_pos": 0,
+	"fade_samples": 0, "redo_is_tap": 0, "pitch_shift": 0, "tempo_stretch": 0,
+}
+
+var defaultGlobals = map[string]float32{
+	"tempo": 120, "eighth_per_cycle": 16, "sync_source": 0,
+	"dry": 1, "wet": 1, "input_gain": 1, "selected_loop_num": 0,
+	"smart_eighths": 1, "output_midi_clock": 0, "use_midi_start": 1,
+	"use_midi_stop": 1, "jack_timebase_master": 0,
+}
+
+// Engine is a simulated SooperLooper instance. Create it with New, feed it
+// messages with Serve or Handle, and advance time with Run or Tick.
+type Engine struct {
+	// Logf, if set, receives a line for every message handled.
+	Logf func(format string, args ...any)
+
+	mu      sync.Mutex
+	loops   []*loop
+	globals map[string]float32
+	subs    []*subscription
+	strips  map[int]float32
+	// stripSubs are the return URLs of /register_strip_gain requests.
+	stripSubs []string
+	clients   map[string]*osc.Client
+	rng       *rand.Rand
+	last      time.Time
+	now       time.Time
+
+	scenario  *Scenario
+	start     time.Time
+	nextEvent int
+	pending   []pendingReply
+}
+
+type loop struct {
+	state, next int
+	controls    map[string]float32
+	pos         float64
+	length      float64
+	cycle       float64
+	recStart    time.Time
+	inPeak      float32
+	outPeak     float32
+}
+
+// subscription is a register_auto_update (periodic) or, with a zero
+// interval, a register_update (on change) request.
+type subscription struct {
+	loop     int
+	control  string
+	interval time.Duration
+	url      string
+	path     string
+	last     time.Time
+}
+
+func New(loops int) *Engine {
+	e := &Engine{
+		globals: make(map[string]float32),
+		strips:  make(map[int]float32),
+		clients: make(map[string]*osc.Client),
+		rng:     rand.New(rand.NewSource(1)),
+	}
+	for k, v := range defaultGlobals {
+		e.globals[k] = v
+	}
+	for i := 0; i < loops; i++ {
+		e.loops = append(e.loops, newLoop())
+	}
+	return e
+}
+
+func newLoop() *loop {
+	l := &loop{state: StateOff, next: StateUnknown, controls: make(map[string]float32)}
+	for k, v := range defaultControls {
+		l.controls[k] = v
+	}
+	return l
+}
+
+// Serve handles OSC packets from pc until it is closed.
+func (e *Engine) Serve(pc net.PacketConn) error {
+	d := osc.NewStandardDispatcher()
+	d.AddMsgHandler("*", e.Handle)
+	return (&osc.Server{Dispatcher: d}).Serve(pc)
+}
+
+// Run advances the simulation every interval until stop is closed.
+func (e *Engine) Run(interval time.Duration, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-t.C:
+			e.Tick(now)
+		}
+	}
+}
+
+// LoopCount returns the current number of loops.
+func (e *Engine) LoopCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.loops)
+}
+
+// State returns the state of loop i, or StateUnknown if it does not exist.
+func (e *Engine) State(i int) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if i < 0 || i >= len(e.loops) {
+		return StateUnknown
+	}
+	return e.loops[i].state
+}
+
+// Control returns the value of a per-loop control.
+func (e *Engine) Control(i int, name string) float32 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if i < 0 || i >= len(e.loops) {
+		return 0
+	}
+	return e.value(i, name)
+}
+
+// Subscribed reports whether updates of a control of loop i, or of a
+// global control with i -2, are registered.
+func (e *Engine) Subscribed(i int, name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.ContainsFunc(e.subs, func(s *subscription) bool { return s.loop == i && s.control == name })
+}
+
+// Global returns a global control such as "tempo".
+func (e *Engine) Global(name string) float32 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.globals[name]
+}
+
+// StripGain returns the last gain set on mixer strip Sooper<id>, in dB.
+func (e *Engine) StripGain(id int) (float32, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	v, ok := e.strips[id]
+	return v, ok
+}
+
+// SetStripGain moves mixer strip Sooper<id> as if by hand, reporting the
+// change to /register_strip_gain subscribers.
+func (e *Engine) SetStripGain(id int, v float32) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.now = time.Now()
+	e.setStrip(id, v)
+}
+
+func (e *Engine) setStrip(id int, v float32) {
+	e.strips[id] = v
+	for _, url := range e.stripSubs {
+		e.send(url, fmt.Sprintf("/strip/Sooper%d/Gain/Gain%%20(dB)", id), v)
+	}
+}
+
+func (e *Engine) logf(format string, args ...any) {
+	if e.Logf != nil {
+		e.Logf(format, args...)
+	}
+}
+
+// Handle processes one incoming message.
+func (e *Engine) Handle(m *osc.Message) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.now = time.Now()
+	e.logf("in %s %v", m.Address, m.Arguments)
+
+	switch addr := m.Address; {
+	case addr == "/ping":
+		url, path, ok := stringArgs2(m, 0)
+		if ok {
+			e.send(url, path, "osc.udp://slmock/", Version, int32(len(e.loops)))
+		}
+	case addr == "/get":
+		if name, ok := stringArg(m, 0); ok {
+			if url, path, ok := stringArgs2(m, 1); ok {
+				e.send(url, path, int32(globalLoop), name, e.globals[name])
+			}
+		}
+	case addr == "/set":
+		name, ok1 := stringArg(m, 0)
+		v, ok2 := floatArg(m, 1)
+		if ok1 && ok2 {
+			e.globals[name] = v
+			e.notify(globalLoop, name)
+		}
+	case addr == "/register_update":
+		name, ok1 := stringArg(m, 0)
+		url, path, ok2 := stringArgs2(m, 1)
+		if ok1 && ok2 {
+			e.unsubscribe(globalLoop, name, url, path)
+			e.subs = append(e.subs, &subscription{loop: globalLoop, control: name, url: url, path: path})
+		}
+	case addr == "/unregister_update":
+		name, ok1 := stringArg(m, 0)
+		url, path, ok2 := stringArgs2(m, 1)
+		if ok1 && ok2 {
+			e.unsubscribe(globalLoop, name, url, path)
+		}
+	case addr == "/loop_add":
+		e.loops = append(e.loops, newLoop())
+	case addr == "/loop_del":
+		if i, ok := intArg(m, 0); ok {
+			e.deleteLoop(i)
+		}
+	case addr == "/get_strip_gain":
+		id, ok1 := intArg(m, 0)
+		url, path, ok2 := stringArgs2(m, 1)
+		if ok1 && ok2 {
+			v, ok := e.strips[id]
+			if !ok {
+				v = 0.75
+			}
+			e.send(url, path, v)
+		}
+	case addr == "/register_strip_gain":
+		// Like a mixer control surface: report every strip gain change,
+		// including the ones this client makes.
+		if url, ok := stringArg(m, 0); ok && !slices.Contains(e.stripSubs, url) {
+			e.stripSubs = append(e.stripSubs, url)
+		}
+	case stripGainPath.MatchString(addr):
+		id, _ := strconv.Atoi(stripGainPath.FindStringSubmatch(addr)[1])
+		if v, ok := floatArg(m, 0); ok {
+			e.setStrip(id, v)
+		}
+	case strings.HasPrefix(addr, "/sl/"):
+		parts := strings.SplitN(strings.TrimPrefix(addr, "/sl/"), "/", 2)
+		idx, err := strconv.Atoi(parts[0])
+		if err != nil || len(parts) != 2 {
+			e.logf("bad loop address %s", addr)
+			return
+		}
+		for _, i := range e.targets(idx) {
+			e.loopCommand(i, parts[1], m)
+		}
+	default:
+		e.logf("unhandled %s", addr)
+	}
+}
+
+func (e *Engine) targets(idx int) []int {
+	switch idx {
+	case allLoops:
+		out := make([]int, len(e.loops))
+		for i := range out {
+			out[i] = i
+		}
+		return out
+	case selectedLoop:
+		idx = int(e.globals["selected_loop_num"])
+	}
+	if idx < 0 || idx >= len(e.loops) {
+		return nil
+	}
+	return []int{idx}
+}
+
+func (e *Engine) loopCommand(i int, cmd string, m *osc.Message) {
+	l := e.loops[i]
+	switch cmd {
+	case "hit", "down":
+		if name, ok := stringArg(m, 0); ok {
+			e.hit(l, name, e.now)
+		}
+	case "up":
+		// Momentary release ends the operation that the matching down started.
+		if name, ok := stringArg(m, 0); ok && isMomentary(l.state, name) {
+			e.hit(l, name, e.now)
+		}
+	case "get":
+		name, ok1 := stringArg(m, 0)
+		url, path, ok2 := stringArgs2(m, 1)
+		if ok1 && ok2 {
+			e.send(url, path, int32(i), name, e.value(i, name))
+		}
+	case "set":
+		name, ok1 := stringArg(m, 0)
+		v, ok2 := floatArg(m, 1)
+		if ok1 && ok2 {
+			l.controls[name] = v
+			// While scratching, the position follows scratch_pos.
+			if name == "scratch_pos" && l.state == StateScratch {
+				l.pos = float64(min(max(v, 0), 1)) * l.length
+			}
+			e.notify(i, name)
+		}
+	case "register_update":
+		// Updates on change only, unlike register_auto_update.
+		name, ok1 := stringArg(m, 0)
+		url, path, ok2 := stringArgs2(m, 1)
+		if ok1 && ok2 {
+			e.unsubscribe(i, name, url, path)
+			e.subs = append(e.subs, &subscription{loop: i, control: name, url: url, path: path})
+		}
+	case "unregister_update":
+		name, ok1 := stringArg(m, 0)
+		url, path, ok2 := stringArgs2(m, 1)
+		if ok1 && ok2 {
+			e.unsubscribe(i, name, url, path)
+		}
+	case "save_loop":
+		// Instead of audio, the file records the loop length and cycle.
+		file, ok1 := stringArg(m, 0)
+		url, errPath, ok2 := stringArgs2(m, 3)
+		if !ok1 || !ok2 {
+			return
+		}
+		if l.length == 0 {
+			e.send(url, errPath, "loop is empty")
+			return
+		}
+		data := fmt.Sprintf("slmock-loop %g %g\n", l.length, l.cycle)
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			e.send(url, errPath, err.Error())
+		}
+	case "load_loop":
+		file, ok1 := stringArg(m, 0)
+		url, errPath, ok2 := stringArgs2(m, 1)
+		if !ok1 || !ok2 {
+			return
+		}
+		data, err := os.ReadFile(file)
+		var length, cycle float64
+		if err == nil {
+			_, err = fmt.Sscanf(string(data), "slmock-loop %g %g", &length, &cycle)
+		}
+		if err != nil || length <= 0 {
+			e.send(url, errPath, fmt.Sprintf("cannot load %s", file))
+			return
+		}
+		l.length, l.cycle, l.pos, l.state = length, cycle, 0, StatePlaying
+	case "register_auto_update":
+		name, ok1 := stringArg(m, 0)
+		ms, ok2 := intArg(m, 1)
+		url, path, ok3 := stringArgs2(m, 2)
+		if ok1 && ok2 && ok3 {
+			e.unsubscribe(i, name, url, path)
+			e.subs = append(e.subs, &subscription{
+				loop: i, control: name, interval: time.Duration(max(ms, 10)) * time.Millisecond,
+				url: url, path: path,
+			})
+		}
+	case "unregister_auto_update":
+		name, ok1 := stringArg(m, 0)
+		url, path, ok2 := stringArgs2(m, 1)
+		if ok1 && ok2 {
+			e.unsubscribe(i, name, url, path)
+		}
+	default:
+		e.logf("unhandled loop command %s", cmd)
+	}
+}
+
+// notify sends a changed control, of loop i or a global one, to its
+// register_update subscribers.
+func (e *Engine) notify(i int, name string) {
+	for _, s := range e.subs {
+		if s.interval != 0 || s.loop != i || s.control != name {
+			continue
+		}
+		if i == globalLoop {
+			e.send(s.url, s.path, int32(i), name, e.globals[name])
+		} else {
+			e.send(s.url, s.path, int32(i), name, e.value(i, name))
+		}
+	}
+}
+
+func (e *Engine) unsubscribe(i int, name, url, path string) {
+	kept := e.subs[:0]
+	for _, s := range e.subs {
+		if s.loop != i || s.control != name || s.url != url || s.path != path {
+			kept = append(kept, s)
+		}
+	}
+	e.subs = kept
+}
+
+func (e *Engine) deleteLoop(i int) {
+	if i == allLoops {
+		i = len(e.loops) - 1
+	}
+	if i < 0 || i >= len(e.loops) {
+		return
+	}
+	e.loops = append(e.loops[:i], e.loops[i+1:]...)
+	kept := e.subs[:0]
+	for _, s := range e.subs {
+		switch {
+		case s.loop == i:
+			continue
+		case s.loop > i:
+			s.loop--
+		}
+		kept = append(kept, s)
+	}
+	e.subs = kept
+}
+
+func isMomentary(state int, cmd string) bool {
+	switch cmd {
+	case "overdub":
+		return state == StateOverdub
+	case "multiply":
+		return state == StateMultiply
+	case "insert":
+		return state == StateInsert
+	case "replace":
+		return state == StateReplace
+	case "substitute":
+		return state == StateSubstitute
+	}
+	return false
+}
+
+// hit applies a SooperLooper command to the loop's state machine.
+func (e *Engine) hit(l *loop, cmd string, now time.Time) {
+	hasAudio := l.length > 0
+	toggle := func(active int) {
+		switch {
+		case l.state == active:
+			l.state = StatePlaying
+		case hasAudio && (l.state == StatePlaying || l.state == StateMuted):
+			l.state = active
+		}
+	}
+
+	switch cmd {
+	case "record":
+		if l.state == StateRecording {
+			l.length = max(now.Sub(l.recStart).Seconds(), 0.1)
+			l.cycle, l.pos, l.state = l.length, 0, StatePlaying
+		} else {
+			l.state, l.recStart, l.length, l.pos = StateRecording, now, 0, 0
+		}
+	case "record_or_overdub":
+		if hasAudio && l.state != StateRecording {
+			e.hit(l, "overdub", now)
+		} else {
+			e.hit(l, "record", now)
+		}
+	case "overdub":
+		toggle(StateOverdub)
+	case "multiply":
+		if l.state == StateMultiply {
+			l.length += l.cycle
+		}
+		toggle(StateMultiply)
+	case "insert":
+		if l.state == StateInsert {
+			l.length += l.cycle
+		}
+		toggle(StateInsert)
+	case "replace":
+		toggle(StateReplace)
+	case "substitute":
+		toggle(StateSubstitute)
+	case "mute", "mute_on", "mute_off":
+		switch {
+		case cmd != "mute_on" && l.state == StateMuted:
+			l.state = StatePlaying
+		case cmd != "mute_on" && l.state == StateOffMuted:
+			l.state = StateOff
+		case cmd != "mute_off" && l.state == StateOff:
+			l.state = StateOffMuted
+		case cmd != "mute_off" && hasAudio && l.state != StateRecording:
+			l.state = StateMuted
+		}
+	case "pause":
+		switch {
+		case l.state == StatePaused:
+			l.state = StatePlaying
+		case hasAudio:
+			l.state = StatePaused
+		}
+	case "scratch":
+		toggle(StateScratch)
+	case "trigger":
+		if hasAudio {
+			l.state, l.pos = StatePlaying, 0
+		}
+	case "oneshot":
+		if hasAudio {
+			l.state, l.pos = StateOneShot, 0
+		}
+	case "undo":
+		// Undo during a record throws the take away.
+		if l.state == StateRecording {
+			l.state, l.length, l.cycle, l.pos = StateOff, 0, 0, 0
+		}
+	case "undo_all":
+		l.state, l.length, l.cycle, l.pos = StateOff, 0, 0, 0
+	default:
+		// redo, reverse, solo and friends change audio, not state.
+	}
+	l.next = StateUnknown
+}
+
+// Tick advances loop positions and meters to now and sends any auto updates
+// that are due.
+func (e *Engine) Tick(now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	dt := 0.0
+	if !e.last.IsZero() {
+		dt = now.Sub(e.last).Seconds()
+	}
+	e.last, e.now = now, now
+
+	e.playEvents()
+	for _, l := range e.loops {
+		e.advance(l, now, dt)
+	}
+	e.applyEnvelopes()
+	e.flushPending()
+	for _, s := range e.subs {
+		if s.interval == 0 || now.Sub(s.last) < s.interval || s.loop >= len(e.loops) {
+			continue
+		}
+		s.last = now
+		e.send(s.url, s.path, int32(s.loop), s.control, e.value(s.loop, s.control))
+	}
+}
+
+func (e *Engine) advance(l *loop, now time.Time, dt float64) {
+	input := float32(0.02 + 0.05*e.rng.Float64())
+	switch l.state {
+	case StateRecording:
+		l.length = now.Sub(l.recStart).Seconds()
+		l.pos = l.length
+		input = float32(0.3 + 0.6*e.rng.Float64())
+	case StateOverdub, StateMultiply, StateInsert, StateReplace, StateSubstitute:
+		input = float32(0.3 + 0.6*e.rng.Float64())
+	}
+	l.inPeak = input * l.controls["input_gain"]
+
+	l.outPeak = 0
+	switch l.state {
+	case StatePlaying, StateOverdub, StateMultiply, StateInsert, StateReplace, StateSubstitute, StateOneShot:
+		l.pos += dt * float64(l.controls["rate"])
+		if l.length > 0 && l.pos >= l.length {
+			l.pos = math.Mod(l.pos, l.length)
+			if l.state == StateOneShot {
+				l.state = StateMuted
+			}
+		}
+		l.outPeak = float32(0.2+0.5*e.rng.Float64()) * l.controls["wet"]
+	}
+}
+
+func (e *Engine) value(i int, name string) float32 {
+	l := e.loops[i]
+	switch name {
+	case "state":
+		return float32(l.state)
+	case "next_state":
+		return float32(l.next)
+	case "loop_pos":
+		return float32(l.pos)
+	case "loop_len":
+		return float32(l.length)
+	case "cycle_len":
+		return float32(l.cycle)
+	case "free_time":
+		return float32(max(40-l.length, 0))
+	case "total_time":
+		return 40
+	case "waiting":
+		if l.state == StateWaitStart || l.state == StateWaitStop {
+			return 1
+		}
+		return 0
+	case "in_peak_meter":
+		return l.inPeak
+	case "out_peak_meter":
+		return l.outPeak
+	case "rate_output":
+		return l.controls["rate"]
+	}
+	return l.controls[name]
+}
+
+func (e *Engine) send(url, path string, args ...any) {
+	m := osc.NewMessage(path)
+	m.Append(args...)
+	drop, delay := e.fault(path)
+	switch {
+	case drop:
+		e.logf("fault: dropped %s", path)
+	case delay > 0:
+		e.logf("fault: delaying %s by %s", path, delay)
+		e.pending = append(e.pending, pendingReply{due: e.now.Add(delay), url: url, msg: m})
+	default:
+		e.sendNow(url, m)
+	}
+}
+
+func (e *Engine) sendNow(url string, m *osc.Message) {
+	c, err := e.client(url)
+	if err != nil {
+		e.logf("bad return url %q: %v", url, err)
+		return
+	}
+	path := m.Address
+	if err := c.Send(m); err != nil {
+		e.logf("send %s to %s: %v", path, url, err)
+	}
+}
+
+func (e *Engine) client(url string) (*osc.Client, error) {
+	if c, ok := e.clients[url]; ok {
+		return c, nil
+	}
+	hostPort := strings.TrimSuffix(strings.TrimPrefix(url, "osc.udp://"), "/")
+	host, portStr, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("port %q: %w", portStr, err)
+	}
+	c := osc.NewClient(host, port)
+	e.clients[url] = c
+	return c, nil
+}
+
+func stringArg(m *osc.Message, i int) (string, bool) {
+	if i >= len(m.Arguments) {
+		return "", false
+	}
+	s, ok := m.Arguments[i].(string)
+	return s, ok
+}
+
+func stringArgs2(m *osc.Message, i int) (string, string, bool) {
+	a, ok1 := stringArg(m, i)
+	b, ok2 := stringArg(m, i+1)
+	return a, b, ok1 && ok2
+}
+
+func intArg(m *osc.Message, i int) (int, bool) {
+	if i >= len(m.Arguments) {
+		return 0, false
+	}
+	switch v := m.Arguments[i].(type) {
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	case float32:
+		return int(v), true
+	}
+	return 0, false
+}
+
+func floatArg(m *osc.Message, i int) (float32, bool) {
+	if i >= len(m.Arguments) {
+		return 0, false
+	}
+	switch v := m.Arguments[i].(type) {
+	case float32:
+		return v, true
+	case float64:
+		return float32(v), true
+	case int32:
+		return float32(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,227 @@
+package slmock
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// listen returns the return URL of a loopback OSC receiver and the channel
+// its messages arrive on.
+func listen(t *testing.T) (string, <-chan *osc.Message) {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	got := make(chan *osc.Message, 16)
+	d := osc.NewStandardDispatcher()
+	d.AddMsgHandler("*", func(m *osc.Message) { got <- m })
+	go (&osc.Server{Dispatcher: d}).Serve(pc)
+	return "osc.udp://" + pc.LocalAddr().String() + "/", got
+}
+
+func expectMsg(t *testing.T, got <-chan *osc.Message, addr string) *osc.Message {
+	t.Helper()
+	select {
+	case m := <-got:
+		if m.Address != addr {
+			t.Fatalf("got %s, want %s", m.Address, addr)
+		}
+		return m
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %s", addr)
+	}
+	return nil
+}
+
+func expectNone(t *testing.T, got <-chan *osc.Message) {
+	t.Helper()
+	select {
+	case m := <-got:
+		t.Errorf("got unexpected %s", m.Address)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func sendHit(e *Engine, loop int, cmd string) {
+	m := osc.NewMessage("/sl/" + strconv.Itoa(loop) + "/hit")
+	m.Append(cmd)
+	e.Handle(m)
+}
+
+// TestHitStateMachine tests the transitions driven by /sl/<n>/hit
+func TestHitStateMachine(t *testing.T) {
+	e := New(2)
+	steps := []struct {
+		cmd  string
+		want int
+	}{
+		{"overdub", StateOff}, // nothing recorded yet
+		{"mute", StateOffMuted},
+		{"mute", StateOff},
+		{"record", StateRecording},
+		{"undo", StateOff},
+		{"record", StateRecording},
+		{"record", StatePlaying},
+		{"undo", StatePlaying},
+		{"overdub", StateOverdub},
+		{"overdub", StatePlaying},
+		{"mute", StateMuted},
+		{"overdub", StateOverdub},
+		{"mute_on", StateMuted},
+		{"mute_off", StatePlaying},
+		{"pause", StatePaused},
+		{"trigger", StatePlaying},
+		{"undo_all", StateOff},
+	}
+	for _, s := range steps {
+		sendHit(e, 0, s.cmd)
+		if got := e.State(0); got != s.want {
+			t.Fatalf("after %s state = %d, want %d", s.cmd, got, s.want)
+		}
+	}
+	if got := e.State(1); got != StateOff {
+		t.Errorf("loop 1 state = %d, want untouched", got)
+	}
+}
+
+// TestScratch tests that scratch_pos moves a scratching loop's position
+func TestScratch(t *testing.T) {
+	e := New(1)
+	now := time.Now()
+	e.Tick(now)
+	sendHit(e, 0, "record")
+	e.Tick(now.Add(4 * time.Second))
+	sendHit(e, 0, "record")
+	sendHit(e, 0, "scratch")
+	if got := e.State(0); got != StateScratch {
+		t.Fatalf("state = %d after scratch, want %d", got, StateScratch)
+	}
+	m := osc.NewMessage("/sl/0/set")
+	m.Append("scratch_pos", float32(0.25))
+	e.Handle(m)
+	sendHit(e, 0, "scratch")
+	length := e.Control(0, "loop_len")
+	if got := e.Control(0, "loop_pos"); length <= 0 || got != length/4 || e.State(0) != StatePlaying {
+		t.Errorf("loop_pos = %v of %v in state %d, want a quarter, playing", got, length, e.State(0))
+	}
+}
+
+// TestAllLoopsAndSet tests /sl/-1/ addressing and per-loop set
+func TestAllLoopsAndSet(t *testing.T) {
+	e := New(3)
+	m := osc.NewMessage("/sl/-1/set")
+	m.Append("wet", float32(0.5))
+	e.Handle(m)
+	for i := 0; i < 3; i++ {
+		if got := e.Control(i, "wet"); got != 0.5 {
+			t.Errorf("loop %d wet = %v, want 0.5", i, got)
+		}
+	}
+
+	e.Handle(osc.NewMessage("/loop_add"))
+	del := osc.NewMessage("/loop_del")
+	del.Append(int32(0))
+	e.Handle(del)
+	if got := e.LoopCount(); got != 3 {
+		t.Errorf("LoopCount() = %d after add and delete, want 3", got)
+	}
+	if got := e.Control(2, "wet"); got != 1 {
+		t.Errorf("added loop wet = %v, want default 1", got)
+	}
+}
+
+// TestOSCRoundTrip tests ping, get, auto and change updates over loopback
+// UDP
+func TestOSCRoundTrip(t *testing.T) {
+	e := New(2)
+	sendHit(e, 1, "record")
+
+	url, got := listen(t)
+	expect := func(addr string) *osc.Message { return expectMsg(t, got, addr) }
+
+	ping := osc.NewMessage("/ping")
+	ping.Append(url, "/pong")
+	e.Handle(ping)
+	if m := expect("/pong"); len(m.Arguments) != 3 || m.Arguments[2] != int32(2) {
+		t.Errorf("/pong args = %v, want loop count 2", m.Arguments)
+	}
+
+	get := osc.NewMessage("/sl/1/get")
+	get.Append("state", url, "/sl/1/update_state")
+	e.Handle(get)
+	if m := expect("/sl/1/update_state"); m.Arguments[2] != float32(StateRecording) {
+		t.Errorf("get state args = %v, want recording", m.Arguments)
+	}
+
+	reg := osc.NewMessage("/sl/0/register_auto_update")
+	reg.Append("loop_pos", int32(100), url, "/sl/0/update_loop_pos")
+	e.Handle(reg)
+	now := time.Now()
+	e.Tick(now)
+	expect("/sl/0/update_loop_pos")
+	e.Tick(now.Add(50 * time.Millisecond))
+	e.Tick(now.Add(150 * time.Millisecond))
+	expect("/sl/0/update_loop_pos")
+
+	unreg := osc.NewMessage("/sl/0/unregister_auto_update")
+	unreg.Append("loop_pos", url, "/sl/0/update_loop_pos")
+	e.Handle(unreg)
+	e.Tick(now.Add(time.Second))
+	expectNone(t, got)
+
+	reg = osc.NewMessage("/sl/0/register_update")
+	reg.Append("wet", url, "/sl/0/update_wet")
+	e.Handle(reg)
+	e.Tick(now.Add(2 * time.Second))
+	expectNone(t, got)
+	set := osc.NewMessage("/sl/0/set")
+	set.Append("wet", float32(0.25))
+	e.Handle(set)
+	if m := expect("/sl/0/update_wet"); m.Arguments[2] != float32(0.25) {
+		t.Errorf("update_wet args = %v, want 0.25", m.Arguments)
+	}
+}
+
+// TestStripGain tests the mocked mixer strip gain endpoint
+func TestStripGain(t *testing.T) {
+	e := New(1)
+	if _, ok := e.StripGain(1); ok {
+		t.Fatal("strip gain set before any message")
+	}
+	m := osc.NewMessage("/strip/Sooper1/Gain/Gain%20(dB)")
+	m.Append(float32(0.4))
+	e.Handle(m)
+	if v, ok := e.StripGain(1); !ok || v != 0.4 {
+		t.Errorf("StripGain(1) = %v, %v, want 0.4", v, ok)
+	}
+}
+
+// TestSaveLoadLoop tests copying a loop through save_loop and load_loop
+func TestSaveLoadLoop(t *testing.T) {
+	e := New(2)
+	url, got := listen(t)
+	file := t.TempDir() + "/loop.wav"
+
+	save := osc.NewMessage("/sl/0/save_loop")
+	save.Append(file, "float", "little", url, "/error")
+	e.Handle(save)
+	expectMsg(t, got, "/error") // nothing recorded yet
+
+	sendHit(e, 0, "record")
+	time.Sleep(20 * time.Millisecond)
+	sendHit(e, 0, "record")
+	e.Handle(save)
+	load := osc.NewMessage("/sl/1/load_loop")
+	load.Append(file, url, "/error")
+	e.Handle(load)
+	expectNone(t, got)
+	if e.State(1) != StatePlaying || e.Control(1, "loop_len") != e.Control(0, "loop_len") {
+		t.Errorf("loaded loop state %d, length %v, want playing with length %v", e.State(1), e.Control(1, "loop_len"), e.Control(0, "loop_len"))
+	}
+}
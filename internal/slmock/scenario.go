@@ -0,0 +1,228 @@
+package slmock
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is a scripted run of the simulator, loaded from YAML:
+//
+//	loops: 2
+//	seed: 7
+//	events:
+//	  - {at: 0s, loop: 0, hit: record}
+//	  - {at: 2s, loop: 0, hit: record}
+//	  - {at: 3s, loop: -1, set: {wet: 0.5}}
+//	  - {at: 4s, loop: 1, state: 10}
+//	meters:
+//	  - {loop: 0, meter: in, from: 0s, to: 2s, points: [0, 0.9, 0.2]}
+//	faults:
+//	  - {from: 5s, to: 8s, match: "/pong/*", drop: 1}
+//	  - {match: "/sl/*/update_state", delay: 300ms}
+//
+// Times are offsets from when the scenario starts playing.
+type Scenario struct {
+	Loops  int        `yaml:"loops"`
+	Seed   int64      `yaml:"seed"`
+	Events []Event    `yaml:"events"`
+	Meters []Envelope `yaml:"meters"`
+	Faults []Fault    `yaml:"faults"`
+}
+
+// Event changes a loop (-1 for all loops) at a point in time: a hit command,
+// a forced state, or control values.
+type Event struct {
+	At    time.Duration      `yaml:"at"`
+	Loop  int                `yaml:"loop"`
+	Hit   string             `yaml:"hit"`
+	State *int               `yaml:"state"`
+	Set   map[string]float32 `yaml:"set"`
+}
+
+// Envelope overrides a loop's in or out peak meter between From and To with
+// points spread evenly over that span and interpolated linearly.
+type Envelope struct {
+	Loop   int           `yaml:"loop"`
+	Meter  string        `yaml:"meter"`
+	From   time.Duration `yaml:"from"`
+	To     time.Duration `yaml:"to"`
+	Points []float32     `yaml:"points"`
+}
+
+// Fault drops or delays replies whose address matches the Match glob (all
+// replies if empty) between From and To (no end if To is zero). Drop is a
+// probability from 0 to 1.
+type Fault struct {
+	From  time.Duration `yaml:"from"`
+	To    time.Duration `yaml:"to"`
+	Match string        `yaml:"match"`
+	Drop  float64       `yaml:"drop"`
+	Delay time.Duration `yaml:"delay"`
+}
+
+type pendingReply struct {
+	due time.Time
+	url string
+	msg *osc.Message
+}
+
+// LoadScenario reads and validates a scenario file.
+func LoadScenario(file string) (*Scenario, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ParseScenario(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return s, nil
+}
+
+// ParseScenario decodes and validates a YAML scenario.
+func ParseScenario(data []byte) (*Scenario, error) {
+	var s Scenario
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	for i, ev := range s.Events {
+		if ev.Hit == "" && ev.State == nil && len(ev.Set) == 0 {
+			return nil, fmt.Errorf("event %d: needs hit, state or set", i)
+		}
+	}
+	for i, env := range s.Meters {
+		if env.Meter != "in" && env.Meter != "out" {
+			return nil, fmt.Errorf("meter %d: meter must be in or out, not %q", i, env.Meter)
+		}
+		if len(env.Points) == 0 || env.To <= env.From {
+			return nil, fmt.Errorf("meter %d: needs points and to after from", i)
+		}
+	}
+	for i, f := range s.Faults {
+		if f.Drop < 0 || f.Drop > 1 {
+			return nil, fmt.Errorf("fault %d: drop must be between 0 and 1", i)
+		}
+		if _, err := path.Match(f.Match, ""); err != nil {
+			return nil, fmt.Errorf("fault %d: match: %w", i, err)
+		}
+	}
+	sort.SliceStable(s.Events, func(i, j int) bool { return s.Events[i].At < s.Events[j].At })
+	return &s, nil
+}
+
+// Play starts (or restarts) the scenario at start. Scenario time advances
+// with Tick, so a test driving Tick with synthetic times gets the same run
+// every time.
+func (e *Engine) Play(s *Scenario, start time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scenario, e.start, e.nextEvent = s, start, 0
+	e.rng = rand.New(rand.NewSource(s.Seed))
+}
+
+func (e *Engine) elapsed() time.Duration {
+	return e.now.Sub(e.start)
+}
+
+func (e *Engine) playEvents() {
+	if e.scenario == nil {
+		return
+	}
+	for ; e.nextEvent < len(e.scenario.Events); e.nextEvent++ {
+		ev := e.scenario.Events[e.nextEvent]
+		if ev.At > e.elapsed() {
+			return
+		}
+		for _, i := range e.targets(ev.Loop) {
+			l := e.loops[i]
+			if ev.Hit != "" {
+				e.hit(l, ev.Hit, e.start.Add(ev.At))
+			}
+			if ev.State != nil {
+				l.state = *ev.State
+			}
+			for k, v := range ev.Set {
+				l.controls[k] = v
+				e.notify(i, k)
+			}
+		}
+	}
+}
+
+func (e *Engine) applyEnvelopes() {
+	if e.scenario == nil {
+		return
+	}
+	t := e.elapsed()
+	for _, env := range e.scenario.Meters {
+		v, ok := env.at(t)
+		if !ok {
+			continue
+		}
+		for _, i := range e.targets(env.Loop) {
+			if env.Meter == "in" {
+				e.loops[i].inPeak = v
+			} else {
+				e.loops[i].outPeak = v
+			}
+		}
+	}
+}
+
+func (env Envelope) at(t time.Duration) (float32, bool) {
+	if t < env.From || t > env.To {
+		return 0, false
+	}
+	if len(env.Points) == 1 {
+		return env.Points[0], true
+	}
+	x := float64(t-env.From) / float64(env.To-env.From) * float64(len(env.Points)-1)
+	i := int(x)
+	if i >= len(env.Points)-1 {
+		return env.Points[len(env.Points)-1], true
+	}
+	frac := float32(x - float64(i))
+	return env.Points[i] + (env.Points[i+1]-env.Points[i])*frac, true
+}
+
+// fault reports whether a reply to addr should be dropped or delayed now.
+func (e *Engine) fault(addr string) (drop bool, delay time.Duration) {
+	if e.scenario == nil {
+		return false, 0
+	}
+	t := e.elapsed()
+	for _, f := range e.scenario.Faults {
+		if t < f.From || (f.To > 0 && t >= f.To) {
+			continue
+		}
+		if f.Match != "" {
+			if ok, _ := path.Match(f.Match, addr); !ok {
+				continue
+			}
+		}
+		if f.Drop > 0 && e.rng.Float64() < f.Drop {
+			return true, 0
+		}
+		delay = max(delay, f.Delay)
+	}
+	return false, delay
+}
+
+func (e *Engine) flushPending() {
+	kept := e.pending[:0]
+	for _, p := range e.pending {
+		if e.now.Before(p.due) {
+			kept = append(kept, p)
+			continue
+		}
+		e.sendNow(p.url, p.msg)
+	}
+	e.pending = kept
+}
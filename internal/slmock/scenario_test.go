@@ -0,0 +1,105 @@
+package slmock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// TestParseScenarioErrors tests scenario validation
+func TestParseScenarioErrors(t *testing.T) {
+	for _, src := range []string{
+		"events: [{at: 1s, loop: 0}]",
+		"meters: [{loop: 0, meter: side, from: 0s, to: 1s, points: [1]}]",
+		"meters: [{loop: 0, meter: in, from: 2s, to: 1s, points: [1]}]",
+		"faults: [{drop: 2}]",
+		"faults: [{match: '[', delay: 1s}]",
+		"events: [{at: soon, loop: 0, hit: record}]",
+	} {
+		if _, err := ParseScenario([]byte(src)); err == nil {
+			t.Errorf("ParseScenario(%q) succeeded, want error", src)
+		}
+	}
+}
+
+// TestEnvelopeAt tests linear interpolation of meter envelopes
+func TestEnvelopeAt(t *testing.T) {
+	env := Envelope{From: time.Second, To: 3 * time.Second, Points: []float32{0, 1, 0}}
+	tests := []struct {
+		at   time.Duration
+		want float32
+		ok   bool
+	}{
+		{0, 0, false},
+		{time.Second, 0, true},
+		{1500 * time.Millisecond, 0.5, true},
+		{2 * time.Second, 1, true},
+		{3 * time.Second, 0, true},
+		{4 * time.Second, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := env.at(tt.at)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("at(%s) = %v, %v, want %v, %v", tt.at, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// TestScenarioPlayback tests events, envelopes and faults from a scenario file
+func TestScenarioPlayback(t *testing.T) {
+	s, err := LoadScenario("testdata/pong-dropout.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := New(s.Loops)
+	start := time.Now()
+	e.Play(s, start)
+	at := func(d time.Duration) { e.Tick(start.Add(d)) }
+
+	at(0)
+	if got := e.State(0); got != StateRecording {
+		t.Fatalf("state at 0s = %d, want recording", got)
+	}
+	at(time.Second)
+	if got := e.Control(0, "in_peak_meter"); got != 1 {
+		t.Errorf("in peak at 1s = %v, want envelope peak 1", got)
+	}
+	at(3 * time.Second)
+	if e.State(0) != StatePlaying || e.State(1) != StateMuted {
+		t.Errorf("states at 3s = %d, %d, want playing, muted", e.State(0), e.State(1))
+	}
+	if got := e.Control(1, "loop_len"); got != 0 {
+		t.Errorf("loop 1 length = %v, want 0", got)
+	}
+	if got := e.Control(0, "loop_len"); got != 2 {
+		t.Errorf("loop 0 length = %v, want 2", got)
+	}
+	if got := e.Control(1, "wet"); got != 0.5 {
+		t.Errorf("loop 1 wet = %v, want 0.5", got)
+	}
+
+	url, got := listen(t)
+	ping := func() {
+		m := osc.NewMessage("/ping")
+		m.Append(url, "/pong/1")
+		e.Handle(m)
+	}
+
+	// Handle stamps messages with the wall clock, so move scenario time by
+	// shifting the start rather than by ticking.
+	e.Play(s, time.Now().Add(-5*time.Second))
+	ping()
+	expectNone(t, got)
+
+	e.Play(s, time.Now().Add(-7*time.Second))
+	ping()
+	expectMsg(t, got, "/pong/1")
+
+	get := osc.NewMessage("/sl/0/get")
+	get.Append("state", url, "/sl/0/update_state")
+	e.Handle(get)
+	expectNone(t, got)
+	e.Tick(time.Now().Add(time.Second))
+	expectMsg(t, got, "/sl/0/update_state")
+}
@@ -0,0 +1,125 @@
+// Package slstate names SooperLooper's loop state codes, as reported by
+// the state and next_state controls, and answers the questions sooperGUI
+// asks about them.
+package slstate
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// State is a loop state code.
+type State int
+
+// SooperLooper loop states. Codes 15 to 19 are not used.
+const (
+	Unknown    State = -1
+	Off        State = 0
+	WaitStart  State = 1
+	Record     State = 2
+	WaitStop   State = 3
+	Play       State = 4
+	Overdub    State = 5
+	Multiply   State = 6
+	Insert     State = 7
+	Replace    State = 8
+	Delay      State = 9
+	Mute       State = 10
+	Scratch    State = 11
+	OneShot    State = 12
+	Substitute State = 13
+	Pause      State = 14
+	OffMuted   State = 20
+)
+
+var names = map[State]string{
+	Unknown:    "Unknown",
+	Off:        "Off",
+	WaitStart:  "WaitStart",
+	Record:     "Record",
+	WaitStop:   "WaitStop",
+	Play:       "Play",
+	Overdub:    "Overdub",
+	Multiply:   "Multiply",
+	Insert:     "Insert",
+	Replace:    "Replace",
+	Delay:      "Delay",
+	Mute:       "Mute",
+	Scratch:    "Scratch",
+	OneShot:    "OneShot",
+	Substitute: "Substitute",
+	Pause:      "Pause",
+	OffMuted:   "OffMuted",
+}
+
+// String returns the state's name, or "State" and the code for codes
+// SooperLooper does not define.
+func (s State) String() string {
+	if name, ok := names[s]; ok {
+		return name
+	}
+	return "State" + strconv.Itoa(int(s))
+}
+
+// Parse returns the state with the given name, as returned by String.
+func Parse(name string) (State, bool) {
+	for s, n := range names {
+		if n == name {
+			return s, true
+		}
+	}
+	return Unknown, false
+}
+
+// UnmarshalText reads a state name, or a code for states without one, so
+// that states can be given in config files.
+func (s *State) UnmarshalText(text []byte) error {
+	if st, ok := Parse(string(text)); ok {
+		*s = st
+		return nil
+	}
+	n, err := strconv.Atoi(string(text))
+	if err != nil {
+		return fmt.Errorf("unknown loop state %q", text)
+	}
+	*s = State(n)
+	return nil
+}
+
+// In reports whether s is one of states.
+func (s State) In(states ...State) bool {
+	for _, t := range states {
+		if s == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Recording reports whether the loop is recording, including waiting for
+// the sync point to stop.
+func (s State) Recording() bool { return s == Record || s == WaitStop }
+
+// Muted reports whether the loop is muted, whether or not it has content.
+func (s State) Muted() bool { return s == Mute || s == OffMuted }
+
+// Stopped reports whether the loop's position stands still: empty, paused
+// or muted while empty.
+func (s State) Stopped() bool { return s == Off || s == OffMuted || s == Pause }
+
+// Transition is a loop's current state and the state it goes to next.
+// To is Unknown when nothing is pending.
+type Transition struct {
+	From, To State
+}
+
+// Pending reports whether the loop is waiting to change state.
+func (t Transition) Pending() bool { return t.To != Unknown && t.To != t.From }
+
+// String returns "From→To", or just From when nothing is pending.
+func (t Transition) String() string {
+	if !t.Pending() {
+		return t.From.String()
+	}
+	return t.From.String() + "→" + t.To.String()
+}
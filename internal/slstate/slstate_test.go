@@ -0,0 +1,89 @@
+package slstate
+
+import "testing"
+
+// TestString tests state names, including codes SooperLooper does not define
+func TestString(t *testing.T) {
+	tests := []struct {
+		s    State
+		want string
+	}{
+		{Unknown, "Unknown"},
+		{Off, "Off"},
+		{Record, "Record"},
+		{Play, "Play"},
+		{OffMuted, "OffMuted"},
+		{State(17), "State17"},
+	}
+	for _, tt := range tests {
+		if got := tt.s.String(); got != tt.want {
+			t.Errorf("State(%d).String() = %q, want %q", int(tt.s), got, tt.want)
+		}
+	}
+}
+
+// TestParse tests that every name parses back to its state
+func TestParse(t *testing.T) {
+	for s, name := range names {
+		if got, ok := Parse(name); !ok || got != s {
+			t.Errorf("Parse(%q) = %v, %v, want %v", name, got, ok, s)
+		}
+	}
+	if _, ok := Parse("State17"); ok {
+		t.Errorf("Parse(State17) succeeded")
+	}
+}
+
+// TestHelpers tests the state groups
+func TestHelpers(t *testing.T) {
+	for s := Unknown; s <= OffMuted; s++ {
+		if got, want := s.Recording(), s == Record || s == WaitStop; got != want {
+			t.Errorf("%v.Recording() = %v", s, got)
+		}
+		if got, want := s.Muted(), s.In(Mute, OffMuted); got != want {
+			t.Errorf("%v.Muted() = %v", s, got)
+		}
+		if got, want := s.Stopped(), s.In(Off, OffMuted, Pause); got != want {
+			t.Errorf("%v.Stopped() = %v", s, got)
+		}
+	}
+	if Play.In() {
+		t.Errorf("Play.In() with no states = true")
+	}
+}
+
+// TestTransition tests pending transitions and their names
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		t       Transition
+		pending bool
+		want    string
+	}{
+		{Transition{Play, Overdub}, true, "Play→Overdub"},
+		{Transition{Play, Unknown}, false, "Play"},
+		{Transition{Mute, Mute}, false, "Mute"},
+		{Transition{WaitStart, Record}, true, "WaitStart→Record"},
+	}
+	for _, tt := range tests {
+		if got := tt.t.Pending(); got != tt.pending {
+			t.Errorf("%v.Pending() = %v, want %v", tt.t, got, tt.pending)
+		}
+		if got := tt.t.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// TestUnmarshalText tests reading states by name or code
+func TestUnmarshalText(t *testing.T) {
+	for text, want := range map[string]State{"Play": Play, "4": Play, "21": State(21), "-1": Unknown} {
+		var s State
+		if err := s.UnmarshalText([]byte(text)); err != nil || s != want {
+			t.Errorf("UnmarshalText(%q) = %v, %v, want %v", text, s, err, want)
+		}
+	}
+	var s State
+	if err := s.UnmarshalText([]byte("Playing")); err == nil {
+		t.Errorf("UnmarshalText(Playing) succeeded")
+	}
+}
@@ -0,0 +1,92 @@
+// latency.go
+// OSC round-trip time measurement using sequenced pings.
+
+package main
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	pingInterval = 2 * time.Second
+	pongPrefix   = "/pong/"
+)
+
+// latencyTracker matches pong replies to the pings that caused them. Each
+// ping asks for its reply on /pong/<seq>, so a late reply to an old ping
+// cannot be mistaken for the newest one.
+type latencyTracker struct {
+	mu        sync.Mutex
+	seq       int
+	sent      map[int]time.Time
+	rtt       time.Duration
+	lastReply time.Time
+	lost      int // pings given up on since takeLost
+}
+
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{sent: make(map[int]time.Time)}
+}
+
+// next records a ping sent at now and returns the reply path to use.
+func (l *latencyTracker) next(now time.Time) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	l.sent[l.seq] = now
+	for seq, at := range l.sent {
+		if now.Sub(at) > 10*pingInterval {
+			delete(l.sent, seq)
+			l.lost++
+		}
+	}
+	return pongPrefix + strconv.Itoa(l.seq)
+}
+
+// reply handles a pong received on addr at now.
+func (l *latencyTracker) reply(addr string, now time.Time) bool {
+	seq, err := strconv.Atoi(strings.TrimPrefix(addr, pongPrefix))
+	if err != nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	at, ok := l.sent[seq]
+	if !ok {
+		return false
+	}
+	delete(l.sent, seq)
+	l.rtt, l.lastReply = now.Sub(at), now
+	return true
+}
+
+// takeLost returns the number of pings given up on without a reply since
+// the last call.
+func (l *latencyTracker) takeLost() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := l.lost
+	l.lost = 0
+	return n
+}
+
+// current returns the last measured RTT, and false if no reply has arrived
+// for a few ping intervals.
+func (l *latencyTracker) current(now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lastReply.IsZero() || now.Sub(l.lastReply) > 3*pingInterval {
+		return 0, false
+	}
+	return l.rtt, true
+}
+
+// repliedSince reports whether a pong has arrived at or after t.
+func (l *latencyTracker) repliedSince(t time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.lastReply.IsZero() && !l.lastReply.Before(t)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestLatencyTracker tests RTT measurement from sequenced ping replies
+func TestLatencyTracker(t *testing.T) {
+	l := newLatencyTracker()
+	t0 := time.Now()
+
+	if _, ok := l.current(t0); ok {
+		t.Errorf("current() ok before any reply")
+	}
+	first := l.next(t0)
+	second := l.next(t0.Add(pingInterval))
+	if first == second {
+		t.Fatalf("pings share reply path %q", first)
+	}
+
+	if !l.reply(second, t0.Add(pingInterval+5*time.Millisecond)) {
+		t.Fatalf("reply(%q) not matched", second)
+	}
+	if rtt, ok := l.current(t0.Add(pingInterval + 6*time.Millisecond)); !ok || rtt != 5*time.Millisecond {
+		t.Errorf("current() = %v, %v; want 5ms, true", rtt, ok)
+	}
+	if l.reply(second, t0.Add(3*pingInterval)) {
+		t.Errorf("duplicate reply matched")
+	}
+	if l.reply("/pong", t0) || l.reply("/pong/x", t0) {
+		t.Errorf("unsequenced reply matched")
+	}
+	if _, ok := l.current(t0.Add(10 * pingInterval)); ok {
+		t.Errorf("current() ok after replies stopped")
+	}
+}
@@ -0,0 +1,82 @@
+// launcher.go
+// Relaunching the TUI in its own terminal window or tmux pane.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// relaunchInTerminal runs sooperGUI again in a new terminal window (st on
+// Linux and the BSDs, Terminal.app on macOS, a console window on Windows)
+// and exits. It returns if no terminal is available.
+func relaunchInTerminal() {
+	self, err := os.Executable()
+	if err != nil {
+		fatal(launcherLog, "cannot find executable", "err", err)
+	}
+	cmd, wait := host.terminalCommand(self, os.Args[1:])
+	if cmd == nil {
+		return
+	}
+	cmd.Env = append(os.Environ(), "SOOPERGUI_XTERM=1")
+	cmd.Stdout, cmd.Stderr, cmd.Stdin = os.Stdout, os.Stderr, os.Stdin
+	launcherLog.Info("launching new terminal window", "cmd", cmd.Args[0])
+	if err := cmd.Start(); err != nil {
+		fatal(launcherLog, "failed to launch terminal", "cmd", cmd.Args[0], "err", err)
+	}
+	if wait {
+		go func() {
+			time.Sleep(time.Second)
+			host.nudgeResize(cmd.Process)
+		}()
+	}
+	cmd.Wait()
+	os.Exit(0)
+}
+
+// launchInTmux opens sooperGUI in a new tmux window or pane of the current
+// session. tmux sizes the new pane itself, so no resize nudge is needed.
+func launchInTmux(mode, size string) error {
+	if os.Getenv("TMUX") == "" {
+		return errors.New("--tmux needs to be run inside a tmux session")
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args, err := tmuxArgs(mode, size, self, os.Args[1:])
+	if err != nil {
+		return err
+	}
+	launcherLog.Info("opening tmux "+mode, "size", size)
+	out, err := exec.Command("tmux", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("tmux: %v: %s", err, out)
+	}
+	return nil
+}
+
+// tmuxArgs builds the tmux command line. mode is "window" or "pane"; a
+// pane is split below the current one, size lines tall (or a percentage
+// such as "50%").
+func tmuxArgs(mode, size, self string, args []string) ([]string, error) {
+	var out []string
+	switch mode {
+	case "window":
+		out = []string{"new-window", "-n", "sooperGUI"}
+	case "pane":
+		out = []string{"split-window", "-v"}
+		if size != "" {
+			out = append(out, "-l", size)
+		}
+	default:
+		return nil, fmt.Errorf("unknown --tmux mode %q (want window or pane)", mode)
+	}
+	out = append(out, "-e", "SOOPERGUI_XTERM=1", "--", self)
+	return append(out, args...), nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTmuxArgs tests the tmux command line for windows and panes
+func TestTmuxArgs(t *testing.T) {
+	tests := []struct {
+		mode, size string
+		want       string
+	}{
+		{"window", "50%", "new-window -n sooperGUI -e SOOPERGUI_XTERM=1 -- /bin/sg --demo"},
+		{"pane", "12", "split-window -v -l 12 -e SOOPERGUI_XTERM=1 -- /bin/sg --demo"},
+		{"pane", "", "split-window -v -e SOOPERGUI_XTERM=1 -- /bin/sg --demo"},
+	}
+	for _, tt := range tests {
+		got, err := tmuxArgs(tt.mode, tt.size, "/bin/sg", []string{"--demo"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("tmuxArgs(%q, %q) = %q, want %q", tt.mode, tt.size, got, tt.want)
+		}
+	}
+	if _, err := tmuxArgs("tab", "", "/bin/sg", nil); err == nil {
+		t.Error("tmuxArgs accepted an unknown mode")
+	}
+}
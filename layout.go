@@ -0,0 +1,309 @@
+// layout.go
+// export-layout: controller layouts for TouchOSC and Open Stage Control,
+// with a strip per loop and buttons for the scenes, macros and songs,
+// sending /gui/action to sooperGUI.
+
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// layoutWidth and layoutHeight are the layout's size, a landscape
+	// tablet's.
+	layoutWidth  = 1024
+	layoutHeight = 768
+	// layoutStripsPerRow loops get a strip in each row of strips.
+	layoutStripsPerRow = 8
+	// layoutStripsHeight is the part of the height for the loop strips,
+	// the rest being the rows of buttons below.
+	layoutStripsHeight = 560
+	layoutButtonRows   = 4
+	layoutGap          = 4
+	// layoutEngineWait is how long export-layout waits for the engine to
+	// tell its loop count.
+	layoutEngineWait = 2 * time.Second
+)
+
+// layoutLoopCommands get a button on each loop's strip.
+var layoutLoopCommands = []struct{ label, cmd string }{
+	{"Rec", "record"}, {"Dub", "overdub"}, {"Mute", "mute"}, {"Undo", "undo"},
+}
+
+// layoutControl is a control in a layout: a fader sends its action with
+// its position, a button its action when pressed, and a label nothing.
+type layoutControl struct {
+	Kind       string // "fader", "button" or "label"
+	Label      string
+	Action     string
+	X, Y, W, H int
+}
+
+// layoutSpec is what a layout is made for.
+type layoutSpec struct {
+	Loops  int
+	Scenes []string
+	Macros []string
+	Songs  bool
+}
+
+// layoutControls lays out a strip for each loop, in rows of up to
+// layoutStripsPerRow, then rows of buttons. Buttons that do not fit are
+// left out and reported.
+func layoutControls(spec layoutSpec) (controls []layoutControl, left []string) {
+	perRow := min(max(spec.Loops, 1), layoutStripsPerRow)
+	rows := (spec.Loops + perRow - 1) / perRow
+	stripW := layoutWidth / perRow
+	stripH := layoutStripsHeight / max(rows, 1)
+	for i := range spec.Loops {
+		x, y := i%perRow*stripW, i/perRow*stripH
+		w := stripW - layoutGap
+		labelH := stripH / 10
+		buttonH := stripH / 10
+		faderH := stripH - labelH - len(layoutLoopCommands)*buttonH - layoutGap
+		n := i + 1
+		controls = append(controls,
+			layoutControl{Kind: "label", Label: fmt.Sprintf("Loop %d", n), X: x, Y: y, W: w, H: labelH},
+			layoutControl{Kind: "fader", Label: fmt.Sprintf("Level %d", n), Action: fmt.Sprintf("level %d", n), X: x, Y: y + labelH, W: w, H: faderH})
+		for j, c := range layoutLoopCommands {
+			controls = append(controls, layoutControl{Kind: "button", Label: c.label, Action: fmt.Sprintf("%s %d", c.cmd, n),
+				X: x, Y: y + labelH + faderH + j*buttonH, W: w, H: buttonH - layoutGap})
+		}
+	}
+
+	type button struct{ label, action string }
+	var buttons []button
+	for i, name := range spec.Scenes {
+		buttons = append(buttons, button{name, fmt.Sprintf("scene %d", i+1)})
+	}
+	for _, name := range spec.Macros {
+		buttons = append(buttons, button{name, "macro " + name})
+	}
+	if spec.Songs {
+		buttons = append(buttons, button{"Song ◀", "song prev"}, button{"Song ▶", "song next"})
+	}
+	buttonW := layoutWidth / layoutStripsPerRow
+	buttonH := (layoutHeight - layoutStripsHeight) / layoutButtonRows
+	for i, b := range buttons {
+		if i >= layoutButtonRows*layoutStripsPerRow {
+			left = append(left, b.label)
+			continue
+		}
+		controls = append(controls, layoutControl{Kind: "button", Label: b.label, Action: b.action,
+			X: i % layoutStripsPerRow * buttonW, Y: layoutStripsHeight + i/layoutStripsPerRow*buttonH,
+			W: buttonW - layoutGap, H: buttonH - layoutGap})
+	}
+	return controls, left
+}
+
+// writeOpenStageControl writes controls as an Open Stage Control session.
+func writeOpenStageControl(w io.Writer, controls []layoutControl) error {
+	widgets := make([]map[string]any, 0, len(controls))
+	for i, c := range controls {
+		wd := map[string]any{
+			"type": c.Kind, "id": fmt.Sprintf("%s_%d", c.Kind, i+1), "label": c.Label,
+			"left": c.X, "top": c.Y, "width": c.W, "height": c.H,
+		}
+		switch c.Kind {
+		case "label":
+			wd["type"] = "text"
+			wd["value"] = c.Label
+		case "fader":
+			wd["address"], wd["preArgs"] = guiActionAddress, []string{c.Action}
+			wd["range"] = map[string]float32{"min": 0, "max": 1}
+		case "button":
+			wd["address"], wd["preArgs"] = guiActionAddress, []string{c.Action}
+			wd["mode"], wd["on"], wd["off"] = "tap", 1, 0
+		}
+		widgets = append(widgets, wd)
+	}
+	session := map[string]any{
+		"createdWith": "Open Stage Control",
+		"version":     "1.26.2",
+		"type":        "session",
+		"content": map[string]any{
+			"type": "root", "id": "root", "widgets": widgets, "tabs": []any{},
+		},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(session)
+}
+
+// writeTouchOSC writes controls as a TouchOSC document: zlib compressed
+// XML, sending on the first connection.
+func writeTouchOSC(w io.Writer, controls []layoutControl) error {
+	var b bytes.Buffer
+	b.WriteString("<?xml version='1.0' encoding='UTF-8'?>\n<lexml version='3'>\n")
+	toscNodeStart(&b, 0, "GROUP", "sooperGUI", 0, 0, layoutWidth, layoutHeight)
+	b.WriteString("<children>\n")
+	for i, c := range controls {
+		switch c.Kind {
+		case "label":
+			toscNodeStart(&b, i+1, "LABEL", c.Label, c.X, c.Y, c.W, c.H)
+			fmt.Fprintf(&b, "<values><value><key>%s</key><locked>0</locked><lockedDefaultCurrent>0</lockedDefaultCurrent><default>%s</default><defaultPull>0</defaultPull></value></values>\n",
+				cdata("text"), cdata(c.Label))
+		case "fader":
+			toscNodeStart(&b, i+1, "FADER", c.Label, c.X, c.Y, c.W, c.H)
+			toscMessage(&b, c.Action, "ANY", true)
+		case "button":
+			toscNodeStart(&b, i+1, "BUTTON", c.Label, c.X, c.Y, c.W, c.H)
+			toscMessage(&b, c.Action, "RISE", false)
+		}
+		b.WriteString("</node>\n")
+	}
+	b.WriteString("</children>\n</node>\n</lexml>\n")
+
+	z := zlib.NewWriter(w)
+	if _, err := z.Write(b.Bytes()); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+func cdata(s string) string {
+	return "<![CDATA[" + strings.ReplaceAll(s, "]]>", "]]]]><![CDATA[>") + "]]>"
+}
+
+// toscNodeStart opens node n, leaving its values, messages and children
+// to the caller. Nodes get IDs from their number, so output is repeatable.
+func toscNodeStart(b *bytes.Buffer, n int, kind, name string, x, y, w, h int) {
+	fmt.Fprintf(b, "<node ID='%08x-0000-4000-8000-000000000000' type='%s'>\n<properties>\n", n, kind)
+	fmt.Fprintf(b, "<property type='s'><key>%s</key><value>%s</value></property>\n", cdata("name"), cdata(name))
+	fmt.Fprintf(b, "<property type='r'><key>%s</key><value><x>%d</x><y>%d</y><w>%d</w><h>%d</h></value></property>\n", cdata("frame"), x, y, w, h)
+	b.WriteString("</properties>\n")
+}
+
+// toscMessage sends /gui/action with action, and the control's value for
+// faders, when the value changes as condition says.
+func toscMessage(b *bytes.Buffer, action, condition string, withValue bool) {
+	partial := func(kind, conversion, value string) string {
+		return fmt.Sprintf("<partial><type>%s</type><conversion>%s</conversion><value>%s</value><scaleMin>0</scaleMin><scaleMax>1</scaleMax></partial>",
+			kind, conversion, cdata(value))
+	}
+	args := partial("CONSTANT", "STRING", action)
+	if withValue {
+		args += partial("VALUE", "FLOAT", "x")
+	}
+	fmt.Fprintf(b, "<messages><osc><enabled>1</enabled><send>1</send><receive>0</receive><feedback>0</feedback><noDuplicates>0</noDuplicates><connections>00001</connections>"+
+		"<triggers><trigger><var>%s</var><condition>%s</condition></trigger></triggers><path>%s</path><arguments>%s</arguments></osc></messages>\n",
+		cdata("x"), condition, partial("CONSTANT", "STRING", guiActionAddress), args)
+}
+
+// guiActionAddress is where layouts send their actions.
+const guiActionAddress = "/gui/action"
+
+// exportLayout is the export-layout command. It returns the exit code.
+func exportLayout(args []string) int {
+	fs := flag.NewFlagSet("export-layout", flag.ContinueOnError)
+	format := fs.String("format", "", "Layout format: touchosc or opensc")
+	output := fs.String("output", "-", "File to write, - for stdout")
+	loops := fs.Int("loops", 0, "Loop strips (default the engine's loop count, or the config profile's)")
+	fs.StringVar(&oscHost, "osc-host", oscHost, "OSC host of the engine asked for its loop count")
+	fs.IntVar(&oscPort, "osc-port", oscPort, "OSC UDP port of the engine")
+	fs.StringVar(&configFile, "config", configFile, "Config file with the macros")
+	fs.StringVar(&scenesFile, "scenes-file", scenesFile, "Scene file")
+	fs.StringVar(&setlistFile, "setlist", setlistFile, "Setlist file; adds song buttons")
+	if err := fs.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return 0
+	} else if err != nil {
+		return 2
+	}
+	fail := func(err error) int {
+		fmt.Fprintln(os.Stderr, "sooperGUI export-layout:", err)
+		return 1
+	}
+	write := map[string]func(io.Writer, []layoutControl) error{"touchosc": writeTouchOSC, "opensc": writeOpenStageControl}[*format]
+	if write == nil {
+		return fail(fmt.Errorf("--format must be touchosc or opensc, not %q", *format))
+	}
+
+	console.Set(nil)
+	named := configFile != ""
+	if !named {
+		configFile = defaultConfigPath()
+	}
+	cfg, err := loadConfig(configFile, named)
+	if err != nil {
+		return fail(err)
+	}
+	if scenesFile == "" {
+		scenesFile = defaultScenesPath()
+	}
+	saved, err := loadScenes(scenesFile)
+	if err != nil {
+		return fail(err)
+	}
+	spec := layoutSpec{Loops: *loops, Songs: setlistFile != ""}
+	for _, s := range saved {
+		spec.Scenes = append(spec.Scenes, s.Name)
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Macros)) {
+		if name != panicMacro {
+			spec.Macros = append(spec.Macros, name)
+		}
+	}
+	if spec.Loops <= 0 {
+		// The profile's loops are added when sooperGUI connects, so the
+		// rig has the more of the two.
+		var profile int
+		if cfg.Profile != nil {
+			profile = cfg.Profile.LoopCount
+		}
+		n, err := engineLoopCount(layoutEngineWait)
+		if err != nil && profile == 0 {
+			return fail(fmt.Errorf("%w; give the loop count with --loops", err))
+		}
+		spec.Loops = max(n, profile)
+	}
+
+	controls, left := layoutControls(spec)
+	if len(left) > 0 {
+		fmt.Fprintln(os.Stderr, "sooperGUI export-layout: no room for", strings.Join(left, ", "))
+	}
+	w := io.Writer(os.Stdout)
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fail(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := write(w, controls); err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+// engineLoopCount asks the engine how many loops it has.
+func engineLoopCount(timeout time.Duration) (int, error) {
+	c, err := newSLClient(":0", oscHost, oscPort, nil, handleOSC)
+	if err != nil {
+		return 0, err
+	}
+	c.Start()
+	defer c.Close()
+	deadline := time.Now().Add(timeout)
+	for !c.Online() {
+		if time.Now().After(deadline) {
+			return 0, errors.New("no reply from the engine")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return loopCount, nil
+}
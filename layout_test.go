@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestLayoutControls tests that layouts fit the page and send actions
+// sooperGUI runs
+func TestLayoutControls(t *testing.T) {
+	macros := map[string]string{"intro": "scene 1; record 1"}
+	controls, left := layoutControls(layoutSpec{Loops: 10, Scenes: []string{"Verse", "Chorus"}, Macros: []string{"intro"}, Songs: true})
+	if len(left) != 0 {
+		t.Errorf("left out %v", left)
+	}
+	faders := 0
+	for _, c := range controls {
+		if c.X < 0 || c.Y < 0 || c.W <= 0 || c.H <= 0 || c.X+c.W > layoutWidth || c.Y+c.H > layoutHeight {
+			t.Errorf("%s %q at %d,%d %dx%d is off the page", c.Kind, c.Label, c.X, c.Y, c.W, c.H)
+		}
+		if c.Kind == "fader" {
+			faders++
+		}
+		if c.Kind == "label" {
+			continue
+		}
+		if _, fader, err := parseActions(c.Action, macros); err != nil || fader != (c.Kind == "fader") {
+			t.Errorf("%s %q sends %q: fader %v, %v", c.Kind, c.Label, c.Action, fader, err)
+		}
+	}
+	if faders != 10 {
+		t.Errorf("%d faders, want one a loop", faders)
+	}
+
+	many := make([]string, layoutButtonRows*layoutStripsPerRow+2)
+	for i := range many {
+		many[i] = "m"
+	}
+	if _, left := layoutControls(layoutSpec{Loops: 1, Macros: many}); len(left) != 2 {
+		t.Errorf("left out %d buttons, want 2", len(left))
+	}
+}
+
+// TestLayoutFormats tests the Open Stage Control and TouchOSC files
+func TestLayoutFormats(t *testing.T) {
+	controls, _ := layoutControls(layoutSpec{Loops: 2, Scenes: []string{"A ]]> B"}})
+
+	var b bytes.Buffer
+	if err := writeOpenStageControl(&b, controls); err != nil {
+		t.Fatal(err)
+	}
+	var session struct {
+		Type    string
+		Content struct {
+			Widgets []struct {
+				Type, Address string
+				PreArgs       []string
+			}
+		}
+	}
+	if err := json.Unmarshal(b.Bytes(), &session); err != nil {
+		t.Fatal(err)
+	}
+	w := session.Content.Widgets
+	if session.Type != "session" || len(w) != len(controls) {
+		t.Fatalf("session %q with %d widgets, want %d", session.Type, len(w), len(controls))
+	}
+	if w[1].Type != "fader" || w[1].Address != guiActionAddress || w[1].PreArgs[0] != "level 1" {
+		t.Errorf("second widget = %+v, want loop 1's Level", w[1])
+	}
+
+	b.Reset()
+	if err := writeTouchOSC(&b, controls); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zlib.NewReader(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := io.ReadAll(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lexml struct {
+		Nodes []struct {
+			Type string `xml:"type,attr"`
+		} `xml:"node>children>node"`
+	}
+	if err := xml.Unmarshal(doc, &lexml); err != nil {
+		t.Fatal(err)
+	}
+	if len(lexml.Nodes) != len(controls) || lexml.Nodes[1].Type != "FADER" {
+		t.Errorf("%d nodes, second %+v; want %d with a fader second", len(lexml.Nodes), lexml.Nodes[1], len(controls))
+	}
+	if !strings.Contains(string(doc), "A ]]]]><![CDATA[> B") {
+		t.Error("scene name not escaped")
+	}
+}
@@ -0,0 +1,41 @@
+// levelentry.go
+// Typed levels: = then a loop number opens a field taking the loop's Level
+// in dB, either absolute (-6) or, after Tab, relative to the current Level
+// (+3, -6).
+
+package main
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// levelDBText shows a Level in dB for the entry field.
+func levelDBText(wet float32) string {
+	if wet <= 0 {
+		return "-inf"
+	}
+	return strconv.FormatFloat(math.Round(ampToDB(wet)*10)/10, 'f', -1, 64)
+}
+
+// parseLevelEntry reads a Level typed in dB, with or without a "dB"
+// suffix. Absolute entries are the new Level, "-inf" for silence, and
+// relative ones are added to current. The result is not yet limited to
+// the Level range.
+func parseLevelEntry(s string, relative bool, current float32) (float32, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimSpace(strings.TrimSuffix(s, "db"))
+	if !relative && (s == "-inf" || s == "-∞") {
+		return 0, nil
+	}
+	db, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(db) || math.IsInf(db, 0) {
+		return 0, errors.New(trf("%q is not a level in dB", s))
+	}
+	if relative {
+		return nudgeLevel(current, db), nil
+	}
+	return float32(dbToAmp(db)), nil
+}
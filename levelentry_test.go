@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestParseLevelEntry tests absolute and relative dB entries
+func TestParseLevelEntry(t *testing.T) {
+	half := float32(dbToAmp(-6))
+	for _, tc := range []struct {
+		text     string
+		relative bool
+		current  float32
+		wantDB   float64 // math.Inf(-1) for silence
+		err      bool
+	}{
+		{"-6", false, 0.9, -6, false},
+		{" -12.5 dB ", false, 0.9, -12.5, false},
+		{"0", false, 0.1, 0, false},
+		{"-inf", false, 0.5, math.Inf(-1), false},
+		{"+3", true, half, -3, false},
+		{"-6", true, half, -12, false},
+		{"5dB", true, half, -1, false},
+		{"-200", true, half, math.Inf(-1), false},
+		{"-inf", true, half, 0, true},
+		{"loud", false, 0.5, 0, true},
+		{"", true, 0.5, 0, true},
+	} {
+		v, err := parseLevelEntry(tc.text, tc.relative, tc.current)
+		switch {
+		case tc.err:
+			if err == nil {
+				t.Errorf("%q (relative %v) gave %g, want an error", tc.text, tc.relative, v)
+			}
+		case err != nil:
+			t.Errorf("%q (relative %v): %v", tc.text, tc.relative, err)
+		case math.IsInf(tc.wantDB, -1):
+			if v != 0 {
+				t.Errorf("%q (relative %v) gave %g, want silence", tc.text, tc.relative, v)
+			}
+		case math.Abs(ampToDB(v)-tc.wantDB) > 0.01:
+			t.Errorf("%q (relative %v) gave %.2f dB, want %g", tc.text, tc.relative, ampToDB(v), tc.wantDB)
+		}
+	}
+}
+
+// TestLevelDBText tests showing a Level in the entry field
+func TestLevelDBText(t *testing.T) {
+	for wet, want := range map[float32]string{0: "-inf", 1: "0", 0.5: "-6", float32(dbToAmp(-12.34)): "-12.3"} {
+		if got := levelDBText(wet); got != want {
+			t.Errorf("levelDBText(%g) = %q, want %q", wet, got, want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// levelkeys.go
+// Level keys: on the Mixer page, Up and Down select a loop's Level cell,
+// and + and - or Left and Right nudge it by --level-step dB, PgUp and PgDn
+// by --level-coarse-step, so Levels can be set without a mouse.
+
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var (
+	// levelStepDB (--level-step) is how far + and - move a Level.
+	levelStepDB = 1.0
+	// levelCoarseStepDB (--level-coarse-step) is how far PgUp and PgDn do.
+	levelCoarseStepDB = 6.0
+	// levelSelected is set while the selected loop's Level cell takes the
+	// level keys. It belongs to the TUI goroutine.
+	levelSelected bool
+)
+
+// levelKeyStep is the dB a key moves the selected Level by, and false for
+// keys that do not move it.
+func levelKeyStep(ev *tcell.EventKey) (float64, bool) {
+	switch {
+	case ev.Key() == tcell.KeyRight, ev.Key() == tcell.KeyRune && ev.Rune() == '+':
+		return levelStepDB, true
+	case ev.Key() == tcell.KeyLeft, ev.Key() == tcell.KeyRune && ev.Rune() == '-':
+		return -levelStepDB, true
+	case ev.Key() == tcell.KeyPgUp:
+		return levelCoarseStepDB, true
+	case ev.Key() == tcell.KeyPgDn:
+		return -levelCoarseStepDB, true
+	}
+	return 0, false
+}
+
+// handleLevelKey takes a Mixer page key for the Level cells: Up and Down
+// select the cell, then the loop above or below, Esc lets it go, and the
+// step keys nudge it. It reports whether it took the key.
+func handleLevelKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyUp, tcell.KeyDown:
+		mu.Lock()
+		if levelSelected {
+			if ev.Key() == tcell.KeyUp {
+				selectLoop(selectedLoop - 1)
+			} else {
+				selectLoop(selectedLoop + 1)
+			}
+		}
+		mu.Unlock()
+		levelSelected = true
+		return true
+	case tcell.KeyEscape:
+		if !levelSelected {
+			return false
+		}
+		levelSelected = false
+		return true
+	}
+	step, ok := levelKeyStep(ev)
+	if !ok || !levelSelected {
+		return false
+	}
+	mu.Lock()
+	i := selectedLoop
+	wet := getLoopState(i).Wet
+	mu.Unlock()
+	editLevel(i, nudgeLevel(wet, step))
+	return true
+}
+
+// selectedIDCell marks the ID of the selected loop, while its Level cell is
+// selected or follow mode is on.
+func selectedIDCell(i int) cell {
+	return cell{Spans: []span{{Text: " ▸" + strconv.Itoa(i+1) + " ", Color: tcell.ColorYellow, Bold: true}}, Align: tview.AlignCenter}
+}
+
+// selectedBarCell is barCell with the empty part shaded, for the selected
+// Level cell.
+func selectedBarCell(fill float32, width int) cell {
+	c := barCell(fill, width)
+	full := strings.Count(c.Spans[0].Text, "█")
+	c.Spans = []span{
+		{Text: barText(full, full, ' '), Color: c.Spans[0].Color},
+		{Text: blanks(width-full, '░'), Color: tcell.ColorGray},
+	}
+	return c
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// TestHandleLevelKey tests selecting a Level cell and nudging it with the
+// level keys
+func TestHandleLevelKey(t *testing.T) {
+	levelThrottle = newSendThrottle(1000, 0, func(int, float32) {})
+	reset := func() {
+		mu.Lock()
+		guiUndo = undoStack{}
+		loopStates = make(map[int]*LoopState)
+		loopCount, selectedLoop = 2, 0
+		mu.Unlock()
+		levelSelected = false
+	}
+	t.Cleanup(reset)
+	reset()
+	mu.Lock()
+	getLoopState(0).Wet, getLoopState(1).Wet = 0.5, 0.5
+	mu.Unlock()
+	key := func(k tcell.Key, r rune) bool { return handleLevelKey(tcell.NewEventKey(k, r, tcell.ModNone)) }
+	db := func(i int) float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return ampToDB(getLoopState(i).Wet)
+	}
+	start := ampToDB(0.5)
+
+	if key(tcell.KeyRune, '+') {
+		t.Error("+ taken with no Level cell selected")
+	}
+	if !key(tcell.KeyDown, 0) || !levelSelected || selectedLoop != 0 {
+		t.Fatalf("Down: selected %v loop %d, want loop 1's cell", levelSelected, selectedLoop+1)
+	}
+	key(tcell.KeyRune, '+')
+	key(tcell.KeyRight, 0)
+	if got := db(0) - start; math.Abs(got-2*levelStepDB) > 0.01 {
+		t.Errorf("+ and Right moved %.2f dB, want %.2f", got, 2*levelStepDB)
+	}
+	key(tcell.KeyDown, 0)
+	key(tcell.KeyPgDn, 0)
+	if got := db(1) - start; math.Abs(got+levelCoarseStepDB) > 0.01 {
+		t.Errorf("PgDn moved loop 2 %.2f dB, want -%.2f", got, levelCoarseStepDB)
+	}
+	if !key(tcell.KeyEscape, 0) || levelSelected {
+		t.Error("Esc did not let the cell go")
+	}
+	if key(tcell.KeyPgUp, 0) || key(tcell.KeyEscape, 0) {
+		t.Error("keys taken after Esc")
+	}
+}
+
+// TestSelectedLevelCell tests the marks on the selected loop's row
+func TestSelectedLevelCell(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+	loops := []*LoopState{{Wet: 0.5}, {Wet: 0.5}}
+	rows := renderTable(tableOptions{Width: 100, Selected: 2, LevelSelected: true}, loops, time.Now())
+	if got := rows[2][colID].text(); !strings.Contains(got, "▸2") {
+		t.Errorf("ID %q, want the selection mark", got)
+	}
+	if got := rows[2][colLevel].text(); !strings.Contains(got, "░") || strings.Contains(rows[1][colLevel].text(), "░") {
+		t.Errorf("Level cells %q and %q, want only loop 2 shaded", rows[1][colLevel].text(), got)
+	}
+}
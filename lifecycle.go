@@ -0,0 +1,104 @@
+// lifecycle.go
+// Background services, such as the engine connection, the screen refresh,
+// the REST API and the MIDI and MQTT links, run in one errgroup under a
+// shared context. Quitting cancels the context and waits for every service
+// to return, and a service that fails stops the others and the program with
+// its error, instead of exiting from the goroutine that hit it.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rivo/tview"
+	"golang.org/x/sync/errgroup"
+)
+
+// serviceGroup is the errgroup the services run in, and its context.
+type serviceGroup struct {
+	g      *errgroup.Group
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// services is the group goService starts services in. main makes its own
+// with newServiceGroup before starting any; until then, as in tests, they
+// run until the program exits.
+var services = newServiceGroup(context.Background())
+
+// newServiceGroup is a group whose services stop when parent is done.
+func newServiceGroup(parent context.Context) *serviceGroup {
+	ctx, cancel := context.WithCancel(parent)
+	g, ctx := errgroup.WithContext(ctx)
+	return &serviceGroup{g: g, ctx: ctx, cancel: cancel}
+}
+
+// goService runs service in the group. It must return once its context is
+// done, with nil; an error it returns before then stops every service, and
+// is returned by stopServices, prefixed with name.
+func goService(name string, service func(ctx context.Context) error) {
+	s := services
+	s.g.Go(func() error {
+		err := service(s.ctx)
+		if err != nil && s.ctx.Err() == nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// servicesDone is closed when the services are stopping, whether on quit
+// or because one failed.
+func servicesDone() <-chan struct{} {
+	return services.ctx.Done()
+}
+
+// stopServices stops the services and waits for them, returning the error
+// the first one that failed returned.
+func stopServices() error {
+	services.cancel()
+	return services.g.Wait()
+}
+
+// queueUpdate runs f on app's event goroutine and waits for it, unless the
+// services stop first: once the TUI has stopped, queued updates never run.
+func queueUpdate(app *tview.Application, f func()) {
+	done := make(chan struct{})
+	go app.QueueUpdate(func() {
+		f()
+		close(done)
+	})
+	select {
+	case <-done:
+	case <-servicesDone():
+	}
+}
+
+// sleepCtx waits for d, or until ctx is done, reporting whether it waited
+// the whole time.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// tickCtx calls f every d, first straight away, until ctx is done.
+func tickCtx(ctx context.Context, d time.Duration, f func()) {
+	t := time.NewTicker(d)
+	defer t.Stop()
+	for {
+		f()
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
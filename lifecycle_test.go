@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestServicesStop tests that stopping the services waits for every one to
+// return, and that a failing one stops the others with its error
+func TestServicesStop(t *testing.T) {
+	defer func(s *serviceGroup) { services = s }(services)
+
+	services = newServiceGroup(context.Background())
+	returned := make(chan string, 2)
+	for _, name := range []string{"a", "b"} {
+		goService(name, func(ctx context.Context) error {
+			<-ctx.Done()
+			returned <- name
+			return errors.New("ignored once stopping")
+		})
+	}
+	if err := stopServices(); err != nil {
+		t.Errorf("stopServices = %v, want nil", err)
+	}
+	if len(returned) != 2 {
+		t.Errorf("%d services returned before stopServices did, want 2", len(returned))
+	}
+
+	services = newServiceGroup(context.Background())
+	goService("waiting", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	goService("failing", func(ctx context.Context) error {
+		return errors.New("broken")
+	})
+	select {
+	case <-servicesDone():
+	case <-time.After(2 * time.Second):
+		t.Fatal("services not stopped by the failing one")
+	}
+	if err := stopServices(); err == nil || err.Error() != "failing: broken" {
+		t.Errorf("stopServices = %v, want failing: broken", err)
+	}
+}
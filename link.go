@@ -0,0 +1,247 @@
+// link.go
+// Ableton Link: with --link, the Link session on the local network is
+// followed from its peers' discovery messages, and its tempo and peer count
+// are shown in the status bar. sooperGUI only listens and is not a peer
+// itself, so it cannot change the session's tempo. With --link-tempo the
+// session's tempo is pushed to the engine.
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net"
+	"time"
+)
+
+const (
+	// linkGroup is where Link peers multicast their discovery messages.
+	linkGroup = "224.76.78.75:20808"
+	// linkProtocol starts every discovery message, with version 1.
+	linkProtocol = "_asdp_v\x01"
+
+	linkAlive    = 1
+	linkResponse = 2
+	linkByeBye   = 3
+
+	// Payload entry keys.
+	linkTimelineKey = 0x746d6c6e // tmln
+	linkSessionKey  = 0x73657373 // sess
+
+	// linkTempoStep is the smallest tempo difference pushed to the engine.
+	linkTempoStep = 0.01
+	// linkPushAgain is how long a pushed tempo has to show up in the
+	// engine's before it is pushed again.
+	linkPushAgain = time.Second
+)
+
+// linkMessage is a peer's discovery message.
+type linkMessage struct {
+	kind    byte
+	ttl     time.Duration
+	peer    [8]byte
+	session [8]byte
+	// tempo is the session tempo in BPM, 0 without a timeline.
+	tempo float64
+}
+
+// linkPeer is a peer heard from, until its message's time to live runs out.
+type linkPeer struct {
+	session [8]byte
+	tempo   float64
+	heard   time.Time
+	until   time.Time
+}
+
+// linkSession is the Link session as seen from the peers' messages. It is
+// guarded by mu.
+type linkSession struct {
+	peers map[[8]byte]linkPeer
+	// pushed is the tempo last pushed to the engine, at pushedAt.
+	pushed   float64
+	pushedAt time.Time
+}
+
+var (
+	// linkListen (--link) follows the Link session.
+	linkListen bool
+	// linkTempo (--link-tempo) pushes its tempo to the engine.
+	linkTempo bool
+
+	link linkSession
+)
+
+// parseLinkMessage reads a discovery message: the protocol header, the
+// message header (type, time to live in seconds, group and peer id), then
+// payload entries of a 4 byte key, a 4 byte size and the value.
+func parseLinkMessage(b []byte) (linkMessage, error) {
+	const header = len(linkProtocol) + 12
+	if len(b) < header || string(b[:len(linkProtocol)]) != linkProtocol {
+		return linkMessage{}, errors.New("link: not a discovery message")
+	}
+	b = b[len(linkProtocol):]
+	m := linkMessage{kind: b[0], ttl: time.Duration(b[1]) * time.Second}
+	copy(m.peer[:], b[4:12])
+	for b = b[12:]; len(b) > 0; {
+		if len(b) < 8 {
+			return linkMessage{}, errors.New("link: short payload entry")
+		}
+		key, size := binary.BigEndian.Uint32(b), binary.BigEndian.Uint32(b[4:])
+		if uint32(len(b)-8) < size {
+			return linkMessage{}, errors.New("link: short payload entry")
+		}
+		value := b[8 : 8+size]
+		switch {
+		case key == linkTimelineKey && size >= 8:
+			// Microseconds per beat, then the beat and time origins.
+			if us := int64(binary.BigEndian.Uint64(value)); us > 0 {
+				m.tempo = 60e6 / float64(us)
+			}
+		case key == linkSessionKey && size >= 8:
+			copy(m.session[:], value)
+		}
+		b = b[8+size:]
+	}
+	return m, nil
+}
+
+// handle takes a message received at now. The caller must hold mu.
+func (l *linkSession) handle(m linkMessage, now time.Time) {
+	if l.peers == nil {
+		l.peers = map[[8]byte]linkPeer{}
+	}
+	switch m.kind {
+	case linkByeBye:
+		delete(l.peers, m.peer)
+	case linkAlive, linkResponse:
+		p := linkPeer{session: m.session, tempo: m.tempo, heard: now, until: now.Add(m.ttl)}
+		if p.tempo == 0 {
+			p.tempo = l.peers[m.peer].tempo
+		}
+		l.peers[m.peer] = p
+	}
+}
+
+// session is the tempo and peer count at now of the session with the most
+// peers, or of those the one heard from last. The tempo is from its peer
+// with one heard from last. The caller must hold mu.
+func (l *linkSession) session(now time.Time) (tempo float64, peers int) {
+	type tally struct {
+		peers int
+		heard time.Time
+	}
+	sessions := map[[8]byte]tally{}
+	for id, p := range l.peers {
+		if now.After(p.until) {
+			delete(l.peers, id)
+			continue
+		}
+		t := sessions[p.session]
+		t.peers++
+		if p.heard.After(t.heard) {
+			t.heard = p.heard
+		}
+		sessions[p.session] = t
+	}
+	var best [8]byte
+	var most tally
+	for s, t := range sessions {
+		if t.peers > most.peers || t.peers == most.peers && t.heard.After(most.heard) {
+			best, most = s, t
+		}
+	}
+	var heard time.Time
+	for _, p := range l.peers {
+		if p.session == best && p.tempo > 0 && p.heard.After(heard) {
+			tempo, heard = p.tempo, p.heard
+		}
+	}
+	return tempo, most.peers
+}
+
+// tempoToPush is the session's tempo if --link-tempo should push it to the
+// engine at now: it differs from the engine's, and was not just pushed.
+// The caller must hold mu.
+func (l *linkSession) tempoToPush(now time.Time) (float64, bool) {
+	tempo, peers := l.session(now)
+	if !linkTempo || peers == 0 || tempo <= 0 || math.Abs(tempo-float64(globals["tempo"])) < linkTempoStep {
+		return 0, false
+	}
+	if math.Abs(tempo-l.pushed) < linkTempoStep && now.Sub(l.pushedAt) < linkPushAgain {
+		return 0, false
+	}
+	l.pushed, l.pushedAt = tempo, now
+	return tempo, true
+}
+
+// linkStatus is the status bar's Link indicator, shown with --link. The
+// caller must hold mu.
+func linkStatus(now time.Time) string {
+	if !linkListen {
+		return ""
+	}
+	tempo, peers := link.session(now)
+	switch peers {
+	case 0:
+		return "[yellow]" + tr("Link: no peers") + "[-]"
+	case 1:
+		return "[green]" + trf("Link %s, 1 peer", bpmText(tempo)) + "[-]"
+	}
+	return "[green]" + trf("Link %s, %d peers", bpmText(tempo), peers) + "[-]"
+}
+
+// serveLink follows the session from the messages arriving on conn, until
+// it fails.
+func serveLink(conn net.PacketConn) error {
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		m, err := parseLinkMessage(buf[:n])
+		if err != nil {
+			oscLog.Debug("link message ignored", "err", err)
+			continue
+		}
+		now := time.Now()
+		mu.Lock()
+		link.handle(m, now)
+		tempo, push := link.tempoToPush(now)
+		client := sl
+		mu.Unlock()
+		if push {
+			oscLog.Info("link tempo pushed", "bpm", tempo)
+			client.SetGlobal("tempo", float32(tempo))
+		}
+	}
+}
+
+// startLink joins the Link discovery group with --link.
+func startLink() {
+	if !linkListen {
+		return
+	}
+	addr, err := net.ResolveUDPAddr("udp4", linkGroup)
+	if err != nil {
+		oscLog.Warn("link not followed", "err", err)
+		return
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, addr)
+	if err != nil {
+		oscLog.Warn("link not followed", "err", err)
+		return
+	}
+	oscLog.Info("following link", "group", linkGroup)
+	goService("link", func(ctx context.Context) error {
+		stop := context.AfterFunc(ctx, func() { conn.Close() })
+		defer stop()
+		// The tempo shown is a convenience, so losing it stops nothing else.
+		if err := serveLink(conn); err != nil && ctx.Err() == nil {
+			oscLog.Warn("link stopped", "err", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+// linkPacket builds a discovery message from peer in session at bpm, with
+// no timeline for bpm 0.
+func linkPacket(kind byte, ttl byte, peer, session byte, bpm float64) []byte {
+	b := append([]byte(linkProtocol), kind, ttl, 0, 0)
+	b = append(b, peer, 0, 0, 0, 0, 0, 0, 0)
+	if bpm > 0 {
+		b = binary.BigEndian.AppendUint32(b, linkTimelineKey)
+		b = binary.BigEndian.AppendUint32(b, 24)
+		b = binary.BigEndian.AppendUint64(b, uint64(math.Round(60e6/bpm)))
+		b = append(b, make([]byte, 16)...)
+	}
+	b = binary.BigEndian.AppendUint32(b, linkSessionKey)
+	b = binary.BigEndian.AppendUint32(b, 8)
+	return append(b, session, 0, 0, 0, 0, 0, 0, 0)
+}
+
+// TestParseLinkMessage tests reading peers' discovery messages
+func TestParseLinkMessage(t *testing.T) {
+	m, err := parseLinkMessage(linkPacket(linkAlive, 5, 7, 3, 120))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.kind != linkAlive || m.ttl != 5*time.Second || m.peer[0] != 7 || m.session[0] != 3 || math.Abs(m.tempo-120) > 0.001 {
+		t.Errorf("parsed %+v", m)
+	}
+	good := linkPacket(linkAlive, 5, 7, 3, 120)
+	for _, bad := range [][]byte{good[:10], append([]byte("_asdp_v\x02"), good[8:]...), good[:len(good)-3]} {
+		if _, err := parseLinkMessage(bad); err == nil {
+			t.Errorf("% x was accepted", bad)
+		}
+	}
+}
+
+// TestLinkSession tests the peer count and tempo of the largest session
+func TestLinkSession(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+	defer func() { link, linkListen = linkSession{}, false }()
+	now := time.Now()
+	add := func(b []byte, at time.Time) {
+		m, err := parseLinkMessage(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		link.handle(m, at)
+	}
+	linkListen = true
+	if got := linkStatus(now); got != "[yellow]Link: no peers[-]" {
+		t.Errorf("status %q with no peers", got)
+	}
+	add(linkPacket(linkAlive, 5, 1, 1, 100), now.Add(time.Millisecond))
+	add(linkPacket(linkAlive, 5, 2, 2, 140), now)
+	add(linkPacket(linkAlive, 2, 3, 2, 0), now.Add(time.Second))
+	if tempo, peers := link.session(now.Add(time.Second)); peers != 2 || math.Abs(tempo-140) > 0.001 {
+		t.Errorf("session %.1f BPM with %d peers, want 140 with 2", tempo, peers)
+	}
+	if got := linkStatus(now.Add(4 * time.Second)); got != "[green]Link 100.0 BPM, 1 peer[-]" {
+		t.Errorf("status %q once peer 3 timed out", got)
+	}
+	add(linkPacket(linkByeBye, 5, 2, 2, 0), now)
+	add(linkPacket(linkByeBye, 5, 1, 1, 0), now)
+	if _, peers := link.session(now); peers != 0 {
+		t.Errorf("%d peers left after they said bye", peers)
+	}
+}
+
+// TestLinkTempo tests that --link-tempo sets the engine's tempo to the
+// session's
+func TestLinkTempo(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 1)
+	sl = startClient(t, sim)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- serveLink(conn) }()
+	mu.Lock()
+	linkTempo = true
+	mu.Unlock()
+	defer func() {
+		conn.Close()
+		<-done
+		mu.Lock()
+		sl, link, linkTempo = nil, linkSession{}, false
+		delete(globals, "tempo")
+		mu.Unlock()
+	}()
+	eventually(t, "tempo", func() bool { return globals["tempo"] > 0 })
+
+	peer, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	if _, err := peer.Write(linkPacket(linkAlive, 5, 1, 1, 98.5)); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "the Link tempo", func() bool { return math.Abs(float64(globals["tempo"])-98.5) < 0.01 })
+}
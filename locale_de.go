@@ -0,0 +1,408 @@
+// locale_de.go
+// German text for the TUI.
+
+package main
+
+var catalogDE = map[string]string{
+	// Table
+	"ID":           "Nr",
+	"Rec":          "Aufn",
+	"Dub":          "Dub",
+	"Mute":         "Stumm",
+	"Pos":          "Pos",
+	"Meter In":     "Eingang",
+	"Meter Out":    "Ausgang",
+	"Level":        "Pegel",
+	"Level (fine)": "Pegel (fein)",
+	"In (%ds)":     "Ein (%ds)",
+	"Out (%ds)":    "Aus (%ds)",
+	"State Debug":  "Zustand",
+	"ON":           "AN",
+	"OFF":          "AUS",
+
+	// Pages
+	"Mixer":                           "Mixer",
+	"Loop":                            "Loop",
+	"Globals":                         "Global",
+	"MIDI":                            "MIDI",
+	"Log":                             "Log",
+	"Loop %d":                         "Loop %d",
+	"History":                         "Verlauf",
+	"Scenes (F1–F9: recall, c: save)": "Szenen (F1–F9: abrufen, c: speichern)",
+	"Save scene as:":                  "Szene speichern als:",
+	"Songs (PgUp/PgDn: switch)":       "Songs (Bild↑/Bild↓: wechseln)",
+	"Crossfade (a/b: pick scenes, [ ]: move)": "Überblendung (a/b: Szenen wählen, [ ]: bewegen)",
+	"Master (M: mute, { }: move)":             "Master (M: stumm, { }: bewegen)",
+	"Master":                                  "Master",
+	"MUTED":                                   "STUMM",
+	"State History":                           "Zustandsverlauf",
+	"Loop (<, >: other loop; ←, →: change; Enter: type a value)": "Loop (<, >: anderer Loop; ←, →: ändern; Enter: Wert eingeben)",
+	"MIDI Bindings":                     "MIDI-Zuordnungen",
+	"Commands (Enter: run, Esc: close)": "Befehle (Enter: ausführen, Esc: schließen)",
+	"%s (%g to %g):":                    "%s (%g bis %g):",
+
+	// Loop page
+	"no state from the engine yet": "noch kein Zustand von der Engine",
+	"Level %.3f":                   "Pegel %.3f",
+	"Group %s":                     "Gruppe %s",
+	"Meters %+.1f dB":              "Pegelanzeigen %+.1f dB",
+	"No state changes yet.":        "Noch keine Zustandswechsel.",
+
+	// Globals page
+	"Engine":        "Engine",
+	"Loops":         "Loops",
+	"none":          "keiner",
+	"Max send rate": "Max. Senderate",
+	"Level law":     "Pegelkurve",
+	"%s, max %g":    "%s, max. %g",
+	"Meter range":   "Anzeigebereich",
+	"%g to %g dB":   "%g bis %g dB",
+	"Refresh":       "Aktualisierung",
+	"Config":        "Konfiguration",
+	"Scenes":        "Szenen",
+	"Setlist":       "Setlist",
+
+	// MIDI page
+	"No MIDI input. Add a midi section to the config file.": "Kein MIDI-Eingang. Ergänze einen Abschnitt midi in der Konfigurationsdatei.",
+	"Device":        "Gerät",
+	"Last received": "Zuletzt empfangen",
+	"No bindings.":  "Keine Zuordnungen.",
+
+	// Scenes and songs
+	"No scenes. Press c to save the current levels as one.": "Keine Szenen. Mit c werden die aktuellen Pegel als Szene gespeichert.",
+	"Scene %d": "Szene %d",
+	"No setlist. Start with --setlist <file>.": "Keine Setlist. Mit --setlist <Datei> starten.",
+
+	// Status bar
+	"demo engine":                            "Demo-Engine",
+	"copy L%d":                               "Kopie L%d",
+	"table p95 %.2f ms  osc p95 %.2f ms":     "Tabelle p95 %.2f ms  OSC p95 %.2f ms",
+	"loop 1–9?":                              "Loop 1–9?",
+	"command?":                               "Befehl?",
+	"Panic: mute all loops? y/n":             "Panik: alle Loops stumm? y/n",
+	"Seek L%d to %.1f s? y/n or click again": "L%d auf %.1f s setzen? y/n oder erneut klicken",
+	"L%d %s will start now":                  "L%d %s beginnt sofort",
+	"L%d %s will stop now":                   "L%d %s endet sofort",
+	"L%d %s will start %s in %.1f s":         "L%d %s beginnt %s in %.1f s",
+	"L%d %s will stop %s in %.1f s":          "L%d %s endet %s in %.1f s",
+	"at the next cycle":                      "beim nächsten Zyklus",
+	"at the next 8th":                        "bei der nächsten Achtel",
+	"at the loop end":                        "am Loop-Ende",
+	"Restore the session from %s? y/n":       "Sitzung von %s wiederherstellen? y/n",
+	"sooperGUI did not exit cleanly. Restore the session from %s? y/n": "sooperGUI wurde nicht sauber beendet. Sitzung von %s wiederherstellen? y/n",
+	"bar %d":                             "Takt %d",
+	"fade":                               "Ausblenden",
+	"JACK sync %s":                       "JACK-Sync %s",
+	"MIDI sync %s (clock not monitored)": "MIDI-Sync %s (Clock nicht überwacht)",
+	"MIDI sync: no clock":                "MIDI-Sync: keine Clock",
+	"MIDI clock %s":                      "MIDI-Clock %s",
+	"Link: no peers":                     "Link: keine Peers",
+	"Link %s, 1 peer":                    "Link %s, 1 Peer",
+	"Link %s, %d peers":                  "Link %s, %d Peers",
+	"stopped":                            "gestoppt",
+	"slip %+d ms":                        "Versatz %+d ms",
+	"(engine not synced to it)":          "(Engine nicht darauf synchronisiert)",
+
+	// Engine errors
+	"failed":                          "fehlgeschlagen",
+	"%d settings unconfirmed":         "%d Einstellungen unbestätigt",
+	"Config reloaded":                 "Konfiguration neu geladen",
+	"Config reloaded; restart for %s": "Konfiguration neu geladen; Neustart nötig für %s",
+	"Config not reloaded: %v":         "Konfiguration nicht neu geladen: %v",
+	"no reply after %d tries":         "nach %d Versuchen keine Antwort",
+	"unknown control %q":              "unbekannter Regler %q",
+	"unknown global control %q":       "unbekannter globaler Regler %q",
+	"no loop %d (the engine has %d)":  "kein Loop %d (die Engine hat %d)",
+
+	// Notifications
+	"Log ≥%s (l: level, N: notifications)":             "Log ≥%s (l: Stufe, N: Meldungen)",
+	"Log ≥%s (l: level, N: notifications, F12: close)": "Log ≥%s (l: Stufe, N: Meldungen, F12: schließen)",
+	"Notifications ≥%s (l: level, N: log)":             "Meldungen ≥%s (l: Stufe, N: Log)",
+	"Notifications ≥%s (l: level, N: log, F12: close)": "Meldungen ≥%s (l: Stufe, N: Log, F12: schließen)",
+	"Engine connected":                              "Engine verbunden",
+	"Engine not responding":                         "Engine antwortet nicht",
+	"Scene saved: %s":                               "Szene gespeichert: %s",
+	"Scene not saved: %v":                           "Szene nicht gespeichert: %v",
+	"Scene recalled: %s":                            "Szene abgerufen: %s",
+	"Session restored from %s":                      "Sitzung von %s wiederhergestellt",
+	"Loop %d saved to %s":                           "Loop %d gespeichert in %s",
+	"Loop %d not saved: %v":                         "Loop %d nicht gespeichert: %v",
+	"Session bounced to %s (%d loops)":              "Session gebounct in %s (%d Loops)",
+	"Session not bounced: %v":                       "Session nicht gebounct: %v",
+	"This needs an engine":                          "Dafür wird eine Engine gebraucht",
+	"This needs the engine on this machine, not %s": "Dafür wird die Engine auf diesem Rechner gebraucht, nicht %s",
+
+	// Level entry
+	"Loop %d Level in dB (Tab: relative):":         "Pegel von Loop %d in dB (Tab: relativ):",
+	"Loop %d Level change in ±dB (Tab: absolute):": "Pegeländerung von Loop %d in ±dB (Tab: absolut):",
+	"Level not set: %v":                            "Pegel nicht gesetzt: %v",
+	"%q is not a level in dB":                      "%q ist kein Pegel in dB",
+
+	// Terminal too small
+	"Terminal too small: %d×%d": "Terminal zu klein: %d×%d",
+	"needs at least %d×%d":      "mindestens %d×%d nötig",
+
+	// OSC inspector
+	"Filter:":   "Filter:",
+	"Message":   "Nachricht",
+	"live":      "live",
+	"paused":    "angehalten",
+	"Time":      "Zeit",
+	"Dir":       "Richtung",
+	"Address":   "Adresse",
+	"Arguments": "Argumente",
+	"OSC traffic (%s) – /: filter, space: pause, x: hex, F10: close": "OSC-Verkehr (%s) – /: filtern, Leertaste: anhalten, x: hex, F10: schließen",
+
+	// File browser
+	"Load into loop %d (Enter: load, Backspace: up, Esc: close)": "In Loop %d laden (Enter: laden, Rücktaste: nach oben, Esc: schließen)",
+	"No audio files here.": "Hier sind keine Audiodateien.",
+	"Format":               "Format",
+	"Channels":             "Kanäle",
+	"Sample rate":          "Abtastrate",
+	"Bits":                 "Bits",
+	"float":                "Gleitkomma",
+	"Length":               "Länge",
+
+	// Loop export
+	"Save loop %d":              "Loop %d speichern",
+	"Peak normalize to %g dBFS": "Spitze auf %g dBFS normalisieren",
+	"Trim silence":              "Stille abschneiden",
+	"Fade edges (ms)":           "Ränder blenden (ms)",
+	"Save":                      "Speichern",
+	"Cancel":                    "Abbrechen",
+
+	// Command palette
+	"Go to the %s page":                       "Zur Seite %s",
+	"Toggle fine Level drags":                 "Feines Pegelziehen ein/aus",
+	"Toggle sparkline meters":                 "Verlaufsanzeige ein/aus",
+	"Toggle the history pane":                 "Verlauf ein/aus",
+	"Toggle the scene pane":                   "Szenen ein/aus",
+	"Toggle the song navigator":               "Songnavigator ein/aus",
+	"Toggle the crossfader":                   "Überblendung ein/aus",
+	"Toggle follow mode":                      "Folgemodus ein/aus",
+	"follow L%d":                              "folgt L%d",
+	"Toggle the master fader":                 "Master-Fader ein/aus",
+	"Mute or unmute the master fader":         "Master-Fader stumm schalten oder wieder einschalten",
+	"Toggle the beat indicator":               "Taktanzeige ein/aus",
+	"Toggle the log pane":                     "Log ein/aus",
+	"Save a scene":                            "Szene speichern",
+	"Load a file into the selected loop":      "Datei in den gewählten Loop laden",
+	"Bounce every loop to a folder":           "Alle Loops in einen Ordner bouncen",
+	"Undo the last Level or control change":   "Letzte Level- oder Regleränderung rückgängig machen",
+	"Redo the Level or control change undone": "Rückgängig gemachte Level- oder Regleränderung wiederholen",
+	"Panic: mute all loops":                   "Panik: alle Loops stumm",
+	"Toggle the click":                        "Klick ein/aus",
+	"Open the OSC inspector":                  "OSC-Inspektor öffnen",
+	"Macro: %s":                               "Makro: %s",
+	"A new terminal window":                   "Einem neuen Terminalfenster",
+	"A new tmux pane":                         "Einem neuen tmux-Bereich",
+	"A new tmux window":                       "Einem neuen tmux-Fenster",
+	"Engine host":                             "Engine-Host",
+	"Engine port":                             "Engine-Port",
+	"Loop names":                              "Loop-Namen",
+	"No config file yet. Your answers are saved to %s, where you can change them later. Flags given when starting still win.": "Noch keine Konfigurationsdatei. Die Antworten werden in %s gespeichert und lassen sich dort später ändern. Beim Start angegebene Flags haben Vorrang.",
+	"Not saved: %v":            "Nicht gespeichert: %v",
+	"Open the TUI in":          "TUI öffnen in",
+	"Skip":                     "Überspringen",
+	"This terminal":            "Diesem Terminal",
+	"e.g. Drums, Bass, Keys":   "z. B. Drums, Bass, Keys",
+	"sooperGUI setup":          "sooperGUI einrichten",
+	"Switch to the %s profile": "Zum Profil %s wechseln",
+	"Recall scene %d: %s":      "Szene %d abrufen: %s",
+	"Next song":                "Nächster Song",
+	"Previous song":            "Vorheriger Song",
+	"Song %d: %s":              "Song %d: %s",
+	"fade out":                 "ausblenden",
+	"mark as the copy source":  "als Kopierquelle markieren",
+	"paste the copied loop":    "kopierten Loop einfügen",
+	"save to a file":           "in eine Datei speichern",
+	"type the Level in dB":     "Pegel in dB eingeben",
+	"show on the Loop page":    "auf der Loop-Seite zeigen",
+	"record":                   "aufnehmen",
+	"overdub":                  "overdub",
+	"multiply":                 "multiplizieren",
+	"insert":                   "einfügen",
+	"replace":                  "ersetzen",
+	"substitute":               "austauschen",
+	"mute":                     "stumm",
+	"mute on":                  "stumm an",
+	"mute off":                 "stumm aus",
+	"pause":                    "Pause",
+	"trigger":                  "auslösen",
+	"oneshot":                  "einmal abspielen",
+	"undo":                     "rückgängig",
+	"redo":                     "wiederherstellen",
+	"undo all":                 "alles rückgängig",
+	"reverse":                  "rückwärts",
+	"solo":                     "solo",
+
+	// Screen reader mode
+	"(current)":             "(aktuell)",
+	"(no reply)":            "(keine Antwort)",
+	"(slow)":                "(langsam)",
+	"(too far)":             "(zu groß)",
+	"Last change: %s":       "Letzte Änderung: %s",
+	"Loop %d: %s":           "Loop %d: %s",
+	"next %s":               "danach %s",
+	"recording in %d beats": "nimmt in %d Schlägen auf",
+	"meters stale":          "Anzeigen veraltet",
+	"loss %.1f%%":           "Verlust %.1f%%",
+	"%d out of order":       "%d in falscher Reihenfolge",
+	"level %s":              "Pegel %s",
+	"in %s":                 "Eingang %s",
+	"out %s":                "Ausgang %s",
+	"silent":                "still",
+	"unknown":               "unbekannt",
+	"empty":                 "leer",
+	"waiting to record":     "wartet auf Aufnahme",
+	"recording":             "nimmt auf",
+	"finishing recording":   "beendet Aufnahme",
+	"playing":               "spielt",
+	"overdubbing":           "overdubbt",
+	"multiplying":           "multipliziert",
+	"inserting":             "fügt ein",
+	"replacing":             "ersetzt",
+	"delay":                 "Delay",
+	"muted":                 "stumm",
+	"scratching":            "scratcht",
+	"playing once":          "spielt einmal",
+	"substituting":          "tauscht aus",
+	"empty, muted":          "leer, stumm",
+
+	usage: `Aufruf: sooperGUI [OPTIONEN]
+       sooperGUI export-layout --format touchosc|opensc [OPTIONEN]
+  --osc-host         OSC-Host (Standard 127.0.0.1)
+  --osc-port         OSC-UDP-Port (Standard 9951)
+  --refresh-rate     Aktualisierungsrate der TUI in ms (Standard 200)
+  --poll             Loop-Zustände und angezeigte Regler bei jeder
+                     Aktualisierung abfragen, für Engines ohne Auto-Updates
+  --stale-after      Positionen und Pegel nach so vielen Auto-Update-
+                     Intervallen ohne Update grau zeigen (Standard 10,
+                     1 s; 0 nie)
+  --latency-warn     OSC-Laufzeiten über ms hervorheben (Standard 50)
+  --max-send-rate    Max. Pegelnachrichten/s je Loop (Standard 30)
+  --level-ramp       Große Pegelsprünge über ms verteilen, z. B. 100
+                     (Standard 0, aus)
+  --meter-min-db     Unteres Ende der Pegelanzeige in dB (Standard -70)
+  --meter-max-db     Oberes Ende der Pegelanzeige in dB (Standard 0)
+  --meter-release    Rücklauf der Anzeige in dB/s, 0 für roh (Standard 11.8)
+  --rms-window       RMS-Fenster in ms, 0 nur für Spitzen (Standard 300)
+  --sparkline-seconds  Verlauf der Anzeige in der Verlaufsansicht, s
+                     (Standard 10)
+  --meter-style      Pegelbalken: braille, block, oder auto für Braille,
+                     wo das Terminal es darstellen kann (Standard auto)
+  --meter-colors     Farben der Pegelbalken: gradient, zones, oder auto für
+                     einen Verlauf, wo das Terminal True Color hat
+                     (Standard auto)
+  --pos-style        Loop-Position: number (Sekunden), clock (ein Zeichen,
+                     das sich über den Loop füllt), cycles (Zahl der Zyklen
+                     und ein Balken mit einem Segment je Zyklus), oder auto
+                     für clock unter 80 Spalten (Standard auto)
+  --level-max        Gesendeter Pegel bei vollem Pegelbalken
+                     (Standard 0.921)
+  --level-law        Pegelkurve: linear, log oder iec (Standard linear)
+  --level-step       dB, um die + und - eine gewählte Pegelzelle bewegen
+                     (Standard 1)
+  --level-coarse-step
+                     dB, um die Bild auf/ab eine gewählte Pegelzelle
+                     bewegen (Standard 6)
+  --cue-lead         Sekunden vor dem Ende eines Loops, zu denen sein
+                     loop_end-Signal erklingt (Standard 0.5)
+  --scene-ramp       Szenenabruf über ms verteilen (Standard 0, Sprung)
+  --autosave         Die Sitzung alle so viele Sekunden sichern und beim
+                     nächsten Start anbieten (Standard 10, 0 aus)
+  --session-file     Sitzungsdatei
+                     (Standard $XDG_STATE_HOME/sooperGUI/session.json)
+  --scenes-file      Szenendatei
+                     (Standard $XDG_STATE_HOME/sooperGUI/scenes.json)
+  --audio-dir        Verzeichnis, in dem der Dateibrowser (L) öffnet, in das
+                     w Loops speichert und B Sessions bounct (Standard das
+                     aktuelle Verzeichnis)
+  --fade-bars        Länge des Ausblendens mit d<Loop> in Takten (Standard 4)
+  --record-length    Aufnahmen nach dieser Länge beenden, z. B. "4 cycles"
+                     oder 8s (Standard off)
+  --count-in         Aufnahmen am ersten Taktanfang beginnen, der mindestens
+                     so viele Schläge entfernt ist (Standard 0, sofort)
+  --overdub-mode     Overdub-Tasten, -Pedale und -Pads: latch (jeder Druck
+                     schaltet um) oder momentary (nur solange gehalten)
+                     (Standard latch)
+  --fade-control     Regler, den das Ausblenden senkt: feedback oder wet
+                     (Standard feedback)
+  --seek             Was ein Klick in die Pos-Zelle eines spielenden Loops
+                     tut: off, confirm (schlägt einen Sprung vor, y oder
+                     ein zweiter Klick führt ihn aus) oder direct
+                     (Standard confirm)
+  --preview          In der Statusleiste sagen, wann ein Loop-Befehl aus der
+                     Palette unter Quantisierung und Sync wirkt, und nach dem
+                     Senden bis dahin herunterzählen (Standard true)
+  --verify-resend    Einen Regler noch einmal setzen, wenn die Engine den
+                     gesetzten Wert nicht zurückmeldet, bevor er als
+                     unbestätigt markiert wird
+  --mixer            Mixer für die Loop-Pegel: ardour, non-mixer,
+                     slmock oder none (Standard slmock)
+  --mixer-config     Mixer-Konfigurationsdatei (YAML), ersetzt --mixer
+  --setlist          Setlist-Datei (YAML) für den Songnavigator
+  --click-control    Globaler Engine-Regler, den k für einen Klick schaltet
+  --flash            Die Zeile eines Loops kurz in der Farbe seines neuen
+                     Zustands aufleuchten lassen (Standard true;
+                     --flash=false schaltet es ab)
+  --follow           Den Loop wählen, dessen Zustand sich zuletzt geändert
+                     hat, oder den aufnehmenden (mit F umschalten)
+  --link             Tempo und Peer-Zahl der Ableton-Link-Session im Netz
+                     anzeigen
+  --link-tempo       Das Tempo der Engine auf das der Link-Session setzen
+                     (schließt --link ein)
+  --spawn-engine     sooperlooper -p <osc-port> -l <engine-loops> als
+                     Kindprozess starten, mit seiner Ausgabe im Log
+  --engine-cmd       Programm der Engine (Standard sooperlooper)
+  --engine-loops     Loops, mit denen die Engine startet (Standard 1)
+  --engine-restart   Die gestartete Engine neu starten, wenn sie endet
+                     (Standard true; --engine-restart=false schaltet ab)
+  --config           Konfigurationsdatei mit dem Engine-Profil
+                     (Standard $XDG_CONFIG_HOME/sooperGUI/config.yaml)
+  --profile          Dieses Profil der Konfigurationsdatei nutzen: seine
+                     Engine, Bereiche und Makros (wechseln in der Palette,
+                     Strg+P)
+  --debug            Ausführliches Log, und Zeiten für Neuzeichnen und OSC
+                     in der Statusleiste
+  --log-file         Logdatei, rotiert bei 5 MB
+                     (Standard $XDG_STATE_HOME/sooperGUI/sooperGUI.log)
+  --state-debug      Spalte mit dem Loop-Zustand zeigen
+  --dev              Entwickleransichten (F10: OSC-Inspektor)
+  --demo             Mit einer eingebauten Schein-Engine (ohne SooperLooper)
+  --a11y             Modus für Screenreader: Loops in Worten, und ein
+                     Signalton bei Zustandswechseln
+  --a11y-announce    Befehl, der jede Ansage erhält, z. B. spd-say
+  --render-once      Die Tabelle einmal ausgeben und beenden
+  --render-format    Ausgabe von --render-once: plain oder ansi
+                     (Standard plain)
+  --render-width     Breite der Ausgabe von --render-once (Standard 80)
+  --tmux             In einem neuen tmux-Fenster oder -Bereich statt st
+                     öffnen: window oder pane
+  --tmux-size        Höhe des --tmux-Bereichs, Zeilen oder % (Standard 50%)
+  --bridge           Ohne TUI laufen und die REST-API anbieten
+  --http             Die REST-API unter dieser Adresse anbieten
+                     (Standard aus, 127.0.0.1:8080 mit --bridge)
+  --pprof            pprof und eine Seite mit Laufzeitdiagnosen unter dieser
+                     Adresse anbieten, z. B. localhost:6060 (Standard aus)
+  --listen-port      UDP-Port für Antworten der Engine und /gui-Nachrichten
+                     (Standard 0, ein freier Port)
+  --return-host      Host, an den die Engine antwortet (Standard die Adresse
+                     der Schnittstelle in Richtung --osc-host)
+  --return-port      Port, an den die Engine antwortet, wenn NAT oder ein
+                     Container ihn weiterleitet (Standard --listen-port)
+  --tunnel           Die Engine per ssh über diesen user@host erreichen,
+                     auf dem Engine und sooperGUI laufen (Standard aus)
+  --tunnel-cmd       sooperGUI auf dem --tunnel-Host (Standard sooperGUI)
+  --lang             Sprache der TUI: en oder de
+                     (Standard aus $SOOPERGUI_LANG oder dem Locale)
+  -h, --help         Diese Hilfe zeigen
+
+Befehle:
+  export-layout      Ein TouchOSC- oder Open-Stage-Control-Layout mit den
+                     Loops, Szenen und Makros schreiben (export-layout -h
+                     für seine Optionen)
+  doctor             Prüfen, ob die Engine erreichbar ist und ihre Antworten
+                     ankommen, und eine Diagnose ausgeben (doctor -h für
+                     seine Optionen)`,
+}
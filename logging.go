@@ -0,0 +1,303 @@
+// logging.go
+// Structured logging to a size-rotated file, with an optional console copy.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	logMaxSize    = 5 << 20
+	logMaxBackups = 3
+	logRingSize   = 500
+)
+
+var (
+	logLevel = new(slog.LevelVar)
+	console  = &switchWriter{w: os.Stderr}
+	logLines = newLogRing(logRingSize)
+	logger   = newLogger(console)
+
+	oscLog      = logger.With("component", "osc")
+	tuiLog      = logger.With("component", "tui")
+	launcherLog = logger.With("component", "launcher")
+	httpLog     = logger.With("component", "http")
+	engineLog   = logger.With("component", "engine")
+	footLog     = logger.With("component", "footswitch")
+	midiLog     = logger.With("component", "midi")
+	mqttLog     = logger.With("component", "mqtt")
+)
+
+// setupLogging sends logs to path (rotated) as well as the console writer
+// and the log pane. The component loggers are rebuilt so they pick up the
+// new handler.
+func setupLogging(path string) error {
+	if path == "" {
+		path = defaultLogPath()
+	}
+	f, err := openRotatingFile(path, logMaxSize, logMaxBackups)
+	if err != nil {
+		return err
+	}
+	logger = newLogger(io.MultiWriter(f, console))
+	oscLog = logger.With("component", "osc")
+	tuiLog = logger.With("component", "tui")
+	launcherLog = logger.With("component", "launcher")
+	httpLog = logger.With("component", "http")
+	engineLog = logger.With("component", "engine")
+	footLog = logger.With("component", "footswitch")
+	midiLog = logger.With("component", "midi")
+	mqttLog = logger.With("component", "mqtt")
+	return nil
+}
+
+func newLogger(w io.Writer) *slog.Logger {
+	return slog.New(teeHandler{
+		main: slog.NewTextHandler(w, &slog.HandlerOptions{Level: logLevel}),
+		ring: &ringHandler{ring: logLines},
+	})
+}
+
+func defaultLogPath() string {
+	return filepath.Join(stateDir(), "sooperGUI.log")
+}
+
+// stateDir returns $XDG_STATE_HOME/sooperGUI, falling back to
+// ~/.local/state/sooperGUI and then the temp directory.
+func stateDir() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return os.TempDir()
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "sooperGUI")
+}
+
+// fatal logs at error level and exits. Use it only before the TUI is up or
+// for unrecoverable errors.
+func fatal(l *slog.Logger, msg string, args ...any) {
+	l.Error(msg, args...)
+	os.Exit(1)
+}
+
+// switchWriter is an io.Writer whose destination can be replaced at runtime,
+// e.g. to silence console output once the TUI owns the terminal.
+type switchWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *switchWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return len(p), nil
+	}
+	return s.w.Write(p)
+}
+
+func (s *switchWriter) Set(w io.Writer) {
+	s.mu.Lock()
+	s.w = w
+	s.mu.Unlock()
+}
+
+// rotatingFile is a log file that is renamed to path.1 (shifting older
+// backups up to path.<backups>) once it would grow beyond maxSize.
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+	f       *os.File
+	size    int64
+}
+
+func openRotatingFile(path string, maxSize int64, backups int) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("log dir: %w", err)
+	}
+	r := &rotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("log file: %w", err)
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	r.f.Close()
+	for i := r.backups; i > 1; i-- {
+		os.Rename(r.path+"."+strconv.Itoa(i-1), r.path+"."+strconv.Itoa(i))
+	}
+	if r.backups > 0 {
+		os.Rename(r.path, r.path+".1")
+	} else {
+		os.Remove(r.path)
+	}
+	return r.open()
+}
+
+// teeHandler passes records to the file/console handler at the configured
+// level and to the in-memory ring at every level, so the log pane can show
+// debug traffic without it reaching the log file.
+type teeHandler struct {
+	main slog.Handler
+	ring *ringHandler
+}
+
+func (t teeHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return t.main.Enabled(ctx, l) || t.ring.Enabled(ctx, l)
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	if t.main.Enabled(ctx, r.Level) {
+		if err := t.main.Handle(ctx, r.Clone()); err != nil {
+			return err
+		}
+	}
+	return t.ring.Handle(ctx, r)
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return teeHandler{t.main.WithAttrs(attrs), t.ring.WithAttrs(attrs).(*ringHandler)}
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	return teeHandler{t.main.WithGroup(name), t.ring.WithGroup(name).(*ringHandler)}
+}
+
+// nextLogLevel cycles through the levels offered by the log pane filter.
+func nextLogLevel(l slog.Level) slog.Level {
+	switch {
+	case l < slog.LevelInfo:
+		return slog.LevelInfo
+	case l < slog.LevelWarn:
+		return slog.LevelWarn
+	case l < slog.LevelError:
+		return slog.LevelError
+	}
+	return slog.LevelDebug
+}
+
+type logLine struct {
+	Level slog.Level
+	Text  string
+}
+
+// logRing holds the most recent formatted log lines for the log pane.
+type logRing struct {
+	mu    sync.Mutex
+	lines []logLine
+	next  int
+	full  bool
+}
+
+func newLogRing(size int) *logRing {
+	return &logRing{lines: make([]logLine, size)}
+}
+
+func (r *logRing) add(l logLine) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines[r.next] = l
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// tail returns up to n of the newest lines at or above level, oldest first.
+func (r *logRing) tail(n int, level slog.Level) []logLine {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := r.next
+	if r.full {
+		count = len(r.lines)
+	}
+	var out []logLine
+	for i := 1; i <= count && len(out) < n; i++ {
+		l := r.lines[(r.next-i+len(r.lines))%len(r.lines)]
+		if l.Level >= level {
+			out = append(out, l)
+		}
+	}
+	slices.Reverse(out)
+	return out
+}
+
+type ringHandler struct {
+	ring   *logRing
+	prefix string
+	attrs  []slog.Attr
+}
+
+func (h *ringHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *ringHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %-5s %s", r.Time.Format("15:04:05"), r.Level, r.Message)
+	for _, a := range h.attrs {
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		fmt.Fprintf(&b, " %s%s=%v", h.prefix, a.Key, a.Value)
+		return true
+	})
+	h.ring.add(logLine{Level: r.Level, Text: b.String()})
+	return nil
+}
+
+func (h *ringHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		c.attrs = append(slices.Clip(c.attrs), a)
+	}
+	return &c
+}
+
+func (h *ringHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.prefix += name + "."
+	return &c
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRotatingFile tests that the log file rotates and keeps a bounded number of backups
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "test.log")
+	r, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{
+		path:        "dddddddd\n",
+		path + ".1": "cccccccc\n",
+		path + ".2": "bbbbbbbb\n",
+	}
+	for p, content := range want {
+		got, err := os.ReadFile(p)
+		if err != nil {
+			t.Errorf("read %s: %v", p, err)
+			continue
+		}
+		if string(got) != content {
+			t.Errorf("%s = %q, want %q", filepath.Base(p), got, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("%s.3 exists, want at most 2 backups", filepath.Base(path))
+	}
+}
+
+// TestDefaultLogPath tests that the default log path honours XDG_STATE_HOME
+func TestDefaultLogPath(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/tmp/state")
+	if got, want := defaultLogPath(), filepath.Join("/tmp/state", "sooperGUI", "sooperGUI.log"); got != want {
+		t.Errorf("defaultLogPath() = %q, want %q", got, want)
+	}
+	t.Setenv("XDG_STATE_HOME", "")
+	if got := defaultLogPath(); !strings.HasSuffix(got, filepath.Join(".local", "state", "sooperGUI", "sooperGUI.log")) {
+		t.Errorf("defaultLogPath() = %q, want it under ~/.local/state", got)
+	}
+}
+
+// TestLogRing tests wrap-around and level filtering of the log pane buffer
+func TestLogRing(t *testing.T) {
+	r := newLogRing(3)
+	if got := r.tail(10, slog.LevelDebug); len(got) != 0 {
+		t.Errorf("empty ring tail = %v", got)
+	}
+	r.add(logLine{slog.LevelInfo, "one"})
+	r.add(logLine{slog.LevelDebug, "two"})
+	r.add(logLine{slog.LevelError, "three"})
+	r.add(logLine{slog.LevelInfo, "four"})
+
+	texts := func(lines []logLine) []string {
+		var out []string
+		for _, l := range lines {
+			out = append(out, l.Text)
+		}
+		return out
+	}
+	if got, want := strings.Join(texts(r.tail(10, slog.LevelDebug)), ","), "two,three,four"; got != want {
+		t.Errorf("tail(debug) = %s, want %s", got, want)
+	}
+	if got, want := strings.Join(texts(r.tail(10, slog.LevelInfo)), ","), "three,four"; got != want {
+		t.Errorf("tail(info) = %s, want %s", got, want)
+	}
+	if got, want := strings.Join(texts(r.tail(1, slog.LevelDebug)), ","), "four"; got != want {
+		t.Errorf("tail(1) = %s, want %s", got, want)
+	}
+}
+
+// TestTeeHandler tests that debug records reach the ring but not the main handler at info level
+func TestTeeHandler(t *testing.T) {
+	var buf bytes.Buffer
+	ring := newLogRing(10)
+	l := slog.New(teeHandler{
+		main: slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		ring: &ringHandler{ring: ring},
+	}).With("component", "osc")
+
+	l.Debug("in", "addr", "/pong")
+	l.Info("server listening")
+
+	if strings.Contains(buf.String(), "/pong") {
+		t.Errorf("debug record reached the main handler: %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "component=osc") {
+		t.Errorf("main handler output %q lacks the component attribute", buf.String())
+	}
+	lines := ring.tail(10, slog.LevelDebug)
+	if len(lines) != 2 || !strings.Contains(lines[0].Text, "in component=osc addr=/pong") {
+		t.Errorf("ring lines = %v", lines)
+	}
+}
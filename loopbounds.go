@@ -0,0 +1,45 @@
+// loopbounds.go
+// Keeping loopStates to the loops the engine has. Engine updates naming a
+// loop past its loop count, from a stray or malformed message, are counted
+// and dropped rather than growing the map, and the states of loops the
+// engine deletes are dropped when its count shrinks. sooperGUI's own
+// settings for loops the engine has yet to report are kept.
+
+package main
+
+// rejectedLoopUpdates counts the loop updates dropped for naming a loop
+// the engine does not have. Guarded by mu.
+var rejectedLoopUpdates uint64
+
+// knownLoop reports whether the engine has the loop at index i, counting
+// n rejected updates if not. The caller must hold mu.
+func knownLoop(i, n int) bool {
+	if i >= 0 && i < loopCount {
+		return true
+	}
+	if rejectedLoopUpdates == 0 {
+		oscLog.Warn("update for a loop the engine does not have", "loop", i+1, "loops", loopCount)
+	}
+	rejectedLoopUpdates += uint64(n)
+	return false
+}
+
+// dropLoops forgets the loops from index n on, after the engine deleted
+// them. The caller must hold mu.
+func dropLoops(n int) {
+	for i, ls := range loopStates {
+		if i < n {
+			continue
+		}
+		if ls.recordStop != nil {
+			ls.recordStop.Stop()
+		}
+		delete(loopStates, i)
+	}
+	for i := max(n, 0); i < maxLoops; i++ {
+		s := &loopShards[i]
+		s.mu.Lock()
+		s.pending = s.pending[:0]
+		s.mu.Unlock()
+	}
+}
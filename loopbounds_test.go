@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// TestLoopBounds tests that updates for loops the engine does not have are
+// counted and dropped, and that a shrinking loop count drops loop states
+func TestLoopBounds(t *testing.T) {
+	defer func(s map[int]*LoopState, n int, r uint64) {
+		loopStates, loopCount, rejectedLoopUpdates = s, n, r
+	}(loopStates, loopCount, rejectedLoopUpdates)
+	loopStates, loopCount, rejectedLoopUpdates = map[int]*LoopState{}, 2, 0
+
+	handleOSC(osc.NewMessage("/sl/1/update_rate", int32(1), "rate", float32(0.5)))
+	handleOSC(osc.NewMessage("/sl/5/update_rate", int32(5), "rate", float32(0.5)))
+	handleOSC(osc.NewMessage("/sl/63/update_state", int32(63), "state", float32(4)))
+	handleOSC(osc.NewMessage("/sl/1/update_in_peak_meter", int32(1), "in_peak_meter", float32(0.5)))
+	handleOSC(osc.NewMessage("/sl/5/update_in_peak_meter", int32(5), "in_peak_meter", float32(0.5)))
+	handleOSC(osc.NewMessage("/sl/5/update_out_peak_meter", int32(5), "out_peak_meter", float32(0.5)))
+	mu.Lock()
+	defer mu.Unlock()
+	foldLoops()
+	if ls := loopStates[1]; ls == nil || ls.InPeakMeter != 0.5 || ls.controls["rate"] != 0.5 {
+		t.Errorf("loop 2 = %+v", ls)
+	}
+	if loopStates[5] != nil || loopStates[63] != nil {
+		t.Errorf("states kept for loops the engine does not have")
+	}
+	if rejectedLoopUpdates < 4 {
+		t.Errorf("%d rejected updates, want 4", rejectedLoopUpdates)
+	}
+	if getLoopState(maxLoops).Wet = 1; loopStates[maxLoops] != nil {
+		t.Errorf("state kept for a loop there cannot be")
+	}
+
+	queueLoopUpdate(osc.NewMessage("/sl/1/update_in_peak_meter", int32(1), "in_peak_meter", float32(1)))
+	handlePong(osc.NewMessage("/pong", "osc.udp://localhost:9951", "1.7.9", int32(1)))
+	if loopCount != 1 || loopStates[1] != nil {
+		t.Errorf("after deleting a loop: %d loops, states %v", loopCount, loopStates)
+	}
+	if n := len(loopShards[1].pending); n != 0 {
+		t.Errorf("%d meter updates kept for the deleted loop", n)
+	}
+
+	// A level restored before the engine reports the loop is kept.
+	getLoopState(3).Wet = 0.25
+	handlePong(osc.NewMessage("/pong", "osc.udp://localhost:9951", "1.7.9", int32(4)))
+	if ls := loopStates[3]; ls == nil || ls.Wet != 0.25 {
+		t.Errorf("level set before the loop was reported lost: %+v", ls)
+	}
+}
@@ -0,0 +1,52 @@
+// loopnames.go
+// Loop names: the config file's loop_names label loops after their numbers
+// on the Loop page and its tab and in the command palette, and name them in
+// bounce manifests where the current song does not.
+
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// loopNames are the loops' names, by loop index. Guarded by mu, as the
+// config file may be reloaded.
+var loopNames map[int]string
+
+// validateLoopNames checks the config file's loop_names: loop numbers in
+// range, and no blank names.
+func validateLoopNames(names map[int]string) error {
+	for _, n := range slices.Sorted(maps.Keys(names)) {
+		if n < 1 || n > maxLoops {
+			return fmt.Errorf("loop %d must be 1 to %d", n, maxLoops)
+		}
+		if strings.TrimSpace(names[n]) == "" {
+			return fmt.Errorf("loop %d has a blank name", n)
+		}
+	}
+	return nil
+}
+
+// setLoopNames sets the loop names from the config file's, by loop number.
+// They have been validated.
+func setLoopNames(names map[int]string) {
+	loopNames = map[int]string{}
+	for n, name := range names {
+		loopNames[n-1] = strings.TrimSpace(name)
+	}
+}
+
+// loopLabel is "Loop 3", followed by the loop's name if it has one,
+// escaped for tview's color tags. The caller must hold mu.
+func loopLabel(i int) string {
+	label := trf("Loop %d", i+1)
+	if name := loopNames[i]; name != "" {
+		label += " " + tview.Escape(name)
+	}
+	return label
+}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+// TestLoopNames tests loop name validation and the labels they make
+func TestLoopNames(t *testing.T) {
+	for _, bad := range []string{"loop_names: {0: Drums}", "loop_names: {1: ' '}", "terminal: xterm", "engine: {port: -1}"} {
+		if _, err := parseConfig([]byte(bad)); err == nil {
+			t.Errorf("%s: no error", bad)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	defer setLoopNames(nil)
+	setLoopNames(map[int]string{2: " Bass [1] "})
+	if got := loopLabel(0); got != "Loop 1" {
+		t.Errorf("unnamed loop = %q", got)
+	}
+	if got, want := loopLabel(1), "Loop 2 Bass [1[]"; got != want {
+		t.Errorf("named loop = %q, want %q", got, want)
+	}
+}
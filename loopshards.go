@@ -0,0 +1,132 @@
+// loopshards.go
+// Per-loop shards for the engine's loop updates: states, positions,
+// controls and the meters, which come ten times a second for every loop.
+// An update goes to its loop's shard under the shard's own lock, so it
+// does not wait for mu while the renderer or anything else holds it. When
+// mu is free, the update is folded into the loop's state straight away;
+// otherwise holders of mu fold the shards: the TUI before each frame, and
+// the engine client and the demo as often as the engine sends updates.
+
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// loopShardCap is how many updates a shard keeps between folds, the
+// newest, should folding stall.
+const loopShardCap = 256
+
+// loopSample is a loop update waiting to be folded.
+type loopSample struct {
+	ctrl string
+	v    float32
+	at   time.Time
+}
+
+// loopShard is one loop's updates since the last fold.
+type loopShard struct {
+	mu      sync.Mutex
+	pending []loopSample
+	// spare is the slice folded last time, reused for the next updates.
+	// Only folds use it, under mu.
+	spare []loopSample
+}
+
+// loopShards are the loops' shards, by loop index.
+var loopShards [maxLoops]loopShard
+
+// queueLoopUpdate puts msg in its loop's shard if it is a loop update,
+// /sl/<loop>/update_<control>, reporting whether it was one and the index
+// of the shard it went to, or -1 if it was dropped: malformed, or of a
+// control sooperGUI does not show. It does not take mu.
+func queueLoopUpdate(msg *osc.Message) (int, bool) {
+	rest, ok := strings.CutPrefix(msg.Address, "/sl/")
+	if !ok {
+		return -1, false
+	}
+	i, ctrl, ok := parseLoopUpdate(rest)
+	if !ok {
+		return -1, false
+	}
+	if _, ok := loopUpdates[ctrl]; !ok && !isMeter(ctrl) && !isLoopControl(ctrl) {
+		return -1, true
+	}
+	v, ok := loopUpdateValue(msg, i, ctrl)
+	if !ok {
+		return -1, true
+	}
+	loopShards[i].add(loopSample{ctrl, v, time.Now()})
+	return i, true
+}
+
+func isMeter(ctrl string) bool {
+	return ctrl == "in_peak_meter" || ctrl == "out_peak_meter"
+}
+
+func (s *loopShard) add(m loopSample) {
+	s.mu.Lock()
+	if len(s.pending) == loopShardCap {
+		s.pending = append(s.pending[:0], s.pending[1:]...)
+	}
+	s.pending = append(s.pending, m)
+	s.mu.Unlock()
+}
+
+// foldLoops applies the updates waiting in the shards to the loop states.
+// The caller must hold mu.
+func foldLoops() {
+	for i := range loopShards {
+		foldLoop(i)
+	}
+}
+
+// foldLoop applies the updates waiting in the shard of the loop at index
+// i, in the order they came. The caller must hold mu.
+func foldLoop(i int) {
+	s := &loopShards[i]
+	s.mu.Lock()
+	batch := s.pending
+	if len(batch) > 0 {
+		s.pending = s.spare[:0]
+	}
+	s.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+	if knownLoop(i, len(batch)) {
+		ls := getLoopState(i)
+		for _, m := range batch {
+			ls.applyUpdate(i, m)
+		}
+	}
+	s.spare = batch
+}
+
+// applyUpdate applies an update to the loop at index i.
+func (ls *LoopState) applyUpdate(i int, m loopSample) {
+	ls.noteUpdate(m.ctrl, m.at)
+	rms, hist := time.Duration(rmsWindowMs)*time.Millisecond, time.Duration(sparkSeconds)*time.Second
+	switch m.ctrl {
+	case "in_peak_meter":
+		ls.InPeakMeter = m.v
+		ls.inRMS.add(m.v, m.at, rms)
+		ls.inHist.add(m.v, m.at, hist)
+		meterClip(i, ls)
+	case "out_peak_meter":
+		ls.OutPeakMeter = m.v
+		ls.outRMS.add(m.v, m.at, rms)
+		ls.outHist.add(m.v, m.at, hist)
+		meterClip(i, ls)
+	default:
+		if apply, ok := loopUpdates[m.ctrl]; ok {
+			apply(ls, i, m.v)
+		} else {
+			ls.setControl(m.ctrl, m.v)
+		}
+	}
+}
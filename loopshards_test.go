@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// TestLoopShards tests that loop updates wait in their loop's shard while
+// mu is held, and are folded into the loop states in order
+func TestLoopShards(t *testing.T) {
+	defer func(s map[int]*LoopState, n int) { loopStates, loopCount = s, n }(loopStates, loopCount)
+	loopStates, loopCount = map[int]*LoopState{}, 7
+	mu.Lock()
+	foldLoops()
+
+	// Held mu would block an update that took it.
+	done := make(chan struct{})
+	go func() {
+		handleOSC(osc.NewMessage("/sl/6/update_in_peak_meter", int32(6), "in_peak_meter", float32(0.25)))
+		handleOSC(osc.NewMessage("/sl/6/update_in_peak_meter", int32(6), "in_peak_meter", float32(0.5)))
+		handleOSC(osc.NewMessage("/sl/6/update_out_peak_meter", int32(6), "out_peak_meter", float32(0.75)))
+		handleOSC(osc.NewMessage("/sl/6/update_rate", int32(6), "rate", float32(1.5)))
+		handleOSC(osc.NewMessage("/sl/6/update_loop_pos", int32(6), "loop_pos", float32(1)))
+		handleOSC(osc.NewMessage("/sl/6/update_loop_pos", int32(6), "loop_pos", float32(2)))
+		handleOSC(osc.NewMessage("/sl/6/update_bogus", int32(6), "bogus", float32(1)))
+		handleOSC(osc.NewMessage("/sl/5/update_rate", int32(6), "rate", float32(1)))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("loop update waited for mu")
+	}
+	if loopStates[6] != nil {
+		t.Errorf("updates applied before folding")
+	}
+	foldLoops()
+	ls := loopStates[6]
+	if ls == nil || ls.InPeakMeter != 0.5 || ls.OutPeakMeter != 0.75 || ls.controls["rate"] != 1.5 || ls.LoopPos != 2 {
+		t.Fatalf("after folding: %+v", ls)
+	}
+	if _, ok := ls.updated["in_peak_meter"]; !ok {
+		t.Errorf("meter update not noted for staleness")
+	}
+	if _, ok := ls.controls["bogus"]; ok {
+		t.Errorf("unknown control stored")
+	}
+	if loopStates[5] != nil {
+		t.Errorf("update naming another loop applied")
+	}
+	mu.Unlock()
+
+	// With mu free, an update is applied straight away.
+	handleOSC(osc.NewMessage("/sl/6/update_rate", int32(6), "rate", float32(0.5)))
+	mu.Lock()
+	if ls.controls["rate"] != 0.5 {
+		t.Errorf("rate %v after an update with mu free, want 0.5", ls.controls["rate"])
+	}
+	mu.Unlock()
+
+	if _, ok := queueLoopUpdate(osc.NewMessage("/pong", "osc.udp://localhost:9951", "1.7.9", int32(1))); ok {
+		t.Errorf("pong taken as a loop update")
+	}
+	for i := range loopShardCap + 10 {
+		loopShards[7].add(loopSample{v: float32(i)})
+	}
+	if n, first := len(loopShards[7].pending), loopShards[7].pending[0].v; n != loopShardCap || first != 10 {
+		t.Errorf("full shard keeps %d updates from %v, want %d from 10", n, first, loopShardCap)
+	}
+	mu.Lock()
+	foldLoops()
+	mu.Unlock()
+}
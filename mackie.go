@@ -0,0 +1,335 @@
+// mackie.go
+// Mackie Control surfaces: a fader controller such as an X-Touch or a
+// FaderPort in Mackie Control mode moves the loop Levels, and its
+// motorized faders, LEDs, display and meters follow the engine.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// mackieConfig is the config file's mackie section:
+//
+//	mackie:
+//	  device: /dev/snd/midiC2D0
+type mackieConfig struct {
+	Device string `yaml:"device"`
+}
+
+func (c *mackieConfig) validate() error {
+	if c.Device == "" {
+		return fmt.Errorf("device missing")
+	}
+	return nil
+}
+
+const (
+	mackieStrips = 8
+	// mackieFeedback is how often faders, LEDs, the display and meters
+	// are brought up to date.
+	mackieFeedback = 50 * time.Millisecond
+
+	midiChannelPressure = 0xd0
+	midiPitchBend       = 0xe0
+
+	// Mackie Control note numbers, each the first of eight strips.
+	mackieRec    = 0x00
+	mackieSolo   = 0x08
+	mackieMute   = 0x10
+	mackieSelect = 0x18
+	mackieTouch  = 0x68
+	// Bank buttons move the strips by eight loops, channel buttons by one.
+	mackieBankLeft     = 0x2e
+	mackieBankRight    = 0x2f
+	mackieChannelLeft  = 0x30
+	mackieChannelRight = 0x31
+
+	mackieLEDOn    = 0x7f
+	mackieMeterTop = 0x0c
+	// mackieCellWidth is the display's characters per strip and line.
+	mackieCellWidth = 7
+)
+
+// mackieLCD starts a Mackie Control main unit display write.
+var mackieLCD = []byte{0xf0, 0x00, 0x00, 0x66, 0x14, 0x12}
+
+// mackieStrip is what a strip shows of its loop.
+type mackieStrip struct {
+	Fader             uint16 // 0–16383
+	Rec, Mute, Select bool
+	Meter             byte // 0–mackieMeterTop
+	Name, State       string
+}
+
+// mackieSurface maps the strips to the loops from bank on and keeps the
+// surface in step with them.
+type mackieSurface struct {
+	mu      sync.Mutex
+	bank    int
+	touched [mackieStrips]bool
+	shown   [mackieStrips]mackieStrip
+	synced  bool // shown is what the surface shows
+}
+
+// mackieStripsAt is what each strip should show with the loops from bank
+// on. The caller must hold mu.
+func mackieStripsAt(bank int) [mackieStrips]mackieStrip {
+	var out [mackieStrips]mackieStrip
+	for n := range out {
+		i := bank + n
+		if i >= loopCount {
+			continue
+		}
+		ls := getLoopState(i)
+		out[n] = mackieStrip{
+			Fader:  uint16(math.Round(float64(lvlLaw.fill(ls.Wet, levelMax, meterMinDB)) * 16383)),
+			Rec:    ls.State.Recording(),
+			Mute:   ls.State.Muted(),
+			Select: i == selectedLoop,
+			Meter:  mackieMeter(ls.OutPeakMeter * meterTrim(i)),
+			Name:   fmt.Sprintf("Loop %d", i+1),
+			State:  ls.State.String(),
+		}
+		if !ls.haveState {
+			out[n].State = ""
+		}
+	}
+	return out
+}
+
+// mackieMeter scales a peak amplitude to a meter level over the meter
+// scale's dB range.
+func mackieMeter(peak float32) byte {
+	if peak <= 0 {
+		return 0
+	}
+	db := 20 * math.Log10(float64(peak))
+	f := min(max((db-meterMinDB)/(meterMaxDB-meterMinDB), 0), 1)
+	return byte(math.Round(f * mackieMeterTop))
+}
+
+// update returns the MIDI bringing the surface from what it shows to want.
+// Touched faders are left where the hand holds them. Meters are sent on
+// every update while they show a level, as the surface lets them fall.
+func (s *mackieSurface) update(want [mackieStrips]mackieStrip) []byte {
+	var out []byte
+	for n, w := range want {
+		old := s.shown[n]
+		if !s.synced || w.Fader != old.Fader {
+			if s.touched[n] {
+				w.Fader = old.Fader
+			} else {
+				out = append(out, midiPitchBend|byte(n), byte(w.Fader&0x7f), byte(w.Fader>>7))
+			}
+		}
+		for _, led := range []struct {
+			note    byte
+			on, was bool
+		}{{mackieRec, w.Rec, old.Rec}, {mackieMute, w.Mute, old.Mute}, {mackieSelect, w.Select, old.Select}} {
+			if !s.synced || led.on != led.was {
+				v := byte(0)
+				if led.on {
+					v = mackieLEDOn
+				}
+				out = append(out, midiNoteOn, led.note+byte(n), v)
+			}
+		}
+		if !s.synced || w.Name != old.Name {
+			out = append(out, mackieText(n*mackieCellWidth, w.Name)...)
+		}
+		if !s.synced || w.State != old.State {
+			out = append(out, mackieText(mackieStrips*mackieCellWidth+n*mackieCellWidth, w.State)...)
+		}
+		if w.Meter > 0 || old.Meter > 0 {
+			out = append(out, midiChannelPressure, byte(n)<<4|w.Meter)
+		}
+		s.shown[n] = w
+	}
+	s.synced = true
+	return out
+}
+
+// mackieText writes text into the display at offset, padded or cut to a
+// strip's width. Characters the display lacks show as spaces.
+func mackieText(offset int, text string) []byte {
+	out := append([]byte(nil), mackieLCD...)
+	out = append(out, byte(offset))
+	for i := range mackieCellWidth {
+		c := byte(' ')
+		if i < len(text) && text[i] >= 0x20 && text[i] < 0x7f {
+			c = text[i]
+		}
+		out = append(out, c)
+	}
+	return append(out, 0xf7)
+}
+
+// handle acts on a message from the surface.
+func (s *mackieSurface) handle(m midiMessage) {
+	switch m.Kind {
+	case midiPitchBend:
+		n := m.Channel - 1
+		v := uint16(m.Data1) | uint16(m.Data2)<<7
+		s.mu.Lock()
+		i := s.bank + n
+		if n < mackieStrips {
+			// The fader is there already; do not send it back.
+			s.shown[n].Fader = v
+		}
+		s.mu.Unlock()
+		if n >= mackieStrips || !validLoopIndex(i) {
+			return
+		}
+		setLevel(i, lvlLaw.amplitude(float32(v)/16383, levelMax, meterMinDB))
+	case midiNoteOn, midiNoteOff:
+		s.button(m.Data1, m.Kind == midiNoteOn)
+	}
+}
+
+// button acts on a button press, or a fader touch or release.
+func (s *mackieSurface) button(note byte, pressed bool) {
+	s.mu.Lock()
+	if note >= mackieTouch && note < mackieTouch+mackieStrips {
+		n := note - mackieTouch
+		s.touched[n] = pressed
+		if !pressed {
+			// Put the fader back where the loop is, should they differ.
+			s.shown[n].Fader = math.MaxUint16
+		}
+		s.mu.Unlock()
+		return
+	}
+	bank := s.bank
+	s.mu.Unlock()
+	if !pressed {
+		return
+	}
+	strip := int(note % mackieStrips)
+	switch {
+	case note >= mackieRec && note < mackieRec+mackieStrips:
+		if validLoopIndex(bank + strip) {
+			hitLoop(bank+strip, "record")
+		}
+	case note >= mackieSolo && note < mackieSolo+mackieStrips:
+		if validLoopIndex(bank + strip) {
+			sl.Hit(bank+strip, "solo")
+		}
+	case note >= mackieMute && note < mackieMute+mackieStrips:
+		if validLoopIndex(bank + strip) {
+			sl.Hit(bank+strip, "mute")
+		}
+	case note >= mackieSelect && note < mackieSelect+mackieStrips:
+		mu.Lock()
+		if bank+strip < loopCount {
+			selectLoop(bank + strip)
+		}
+		mu.Unlock()
+	case note == mackieBankLeft:
+		s.moveBank(-mackieStrips)
+	case note == mackieBankRight:
+		s.moveBank(mackieStrips)
+	case note == mackieChannelLeft:
+		s.moveBank(-1)
+	case note == mackieChannelRight:
+		s.moveBank(1)
+	}
+}
+
+// moveBank moves the strips by d loops, keeping a loop on strip 1.
+func (s *mackieSurface) moveBank(d int) {
+	mu.Lock()
+	n := loopCount
+	mu.Unlock()
+	s.mu.Lock()
+	s.bank = min(max(s.bank+d, 0), max(n-1, 0))
+	midiLog.Info("mackie bank", "loop", s.bank+1)
+	s.mu.Unlock()
+}
+
+// run keeps the surface on rw in step with the loops, and acts on its
+// messages, until rw fails.
+func (s *mackieSurface) run(rw io.ReadWriter) error {
+	s.mu.Lock()
+	s.synced = false
+	s.mu.Unlock()
+	stop := make(chan struct{})
+	defer close(stop)
+	failed := make(chan error, 1)
+	go func() {
+		t := time.NewTicker(mackieFeedback)
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+			}
+			s.mu.Lock()
+			bank := s.bank
+			s.mu.Unlock()
+			mu.Lock()
+			want := mackieStripsAt(bank)
+			mu.Unlock()
+			s.mu.Lock()
+			out := s.update(want)
+			s.mu.Unlock()
+			if len(out) == 0 {
+				continue
+			}
+			if _, err := rw.Write(out); err != nil {
+				failed <- err
+				return
+			}
+		}
+	}()
+	read := make(chan error, 1)
+	go func() { read <- readMIDI(rw, s.handle) }()
+	select {
+	case err := <-read:
+		return err
+	case err := <-failed:
+		return err
+	}
+}
+
+// startMackie runs the configured surface until the services stop,
+// reopening its device whenever it goes away.
+func startMackie(c *mackieConfig) {
+	if c == nil {
+		return
+	}
+	s := &mackieSurface{}
+	goService("mackie", func(ctx context.Context) error {
+		var lastErr string
+		for {
+			err := func() error {
+				f, err := os.OpenFile(c.Device, os.O_RDWR, 0)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				stop := context.AfterFunc(ctx, func() { f.Close() })
+				defer stop()
+				midiLog.Info("mackie surface open", "device", c.Device)
+				return s.run(f)
+			}()
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err.Error() != lastErr {
+				midiLog.Warn("mackie surface unavailable", "device", c.Device, "err", err)
+				lastErr = err.Error()
+			}
+			if !sleepCtx(ctx, midiRetry) {
+				return nil
+			}
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+
+	"jaudio/internal/slstate"
+)
+
+// setMackieLoops resets the loops to n, restoring them when the test ends.
+func setMackieLoops(t *testing.T, n int) {
+	t.Helper()
+	reset := func() {
+		mu.Lock()
+		loopStates = make(map[int]*LoopState)
+		loopCount, selectedLoop = 1, 0
+		mu.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+	mu.Lock()
+	loopCount = n
+	mu.Unlock()
+}
+
+// TestMackieFeedback tests that the surface gets everything once, then
+// only what changes, with touched faders left alone
+func TestMackieFeedback(t *testing.T) {
+	setMackieLoops(t, 2)
+	mu.Lock()
+	getLoopState(0).Wet = levelMax
+	getLoopState(1).State, getLoopState(1).haveState = slstate.Mute, true
+	selectedLoop = 1
+	want := mackieStripsAt(0)
+	mu.Unlock()
+
+	if s := want[0]; s.Fader != 16383 || s.Name != "Loop 1" || s.Mute {
+		t.Errorf("strip 1 = %+v, want a full fader", s)
+	}
+	if s := want[1]; !s.Mute || !s.Select || s.State != "Mute" {
+		t.Errorf("strip 2 = %+v, want muted and selected", s)
+	}
+
+	var s mackieSurface
+	out := s.update(want)
+	for _, msg := range [][]byte{
+		{midiPitchBend, 0x7f, 0x7f},        // strip 1 fader at the top
+		{midiNoteOn, mackieMute + 1, 0x7f}, // strip 2 Mute lit
+		{midiNoteOn, mackieSelect + 1, 0x7f},
+		mackieText(0, "Loop 1"),
+		mackieText(mackieStrips*mackieCellWidth+7, "Mute"),
+	} {
+		if !bytes.Contains(out, msg) {
+			t.Errorf("first update lacks % x", msg)
+		}
+	}
+	if out := s.update(want); len(out) != 0 {
+		t.Errorf("update with nothing changed = % x", out)
+	}
+
+	want[1].Mute = false
+	if out, msg := s.update(want), []byte{midiNoteOn, mackieMute + 1, 0}; !bytes.Equal(out, msg) {
+		t.Errorf("update after an unmute = % x, want % x", out, msg)
+	}
+
+	s.button(mackieTouch, true)
+	want[0].Fader = 100
+	if out := s.update(want); len(out) != 0 {
+		t.Errorf("update of a touched fader = % x", out)
+	}
+	s.button(mackieTouch, false)
+	if out, msg := s.update(want), []byte{midiPitchBend, 100, 0}; !bytes.Equal(out, msg) {
+		t.Errorf("update after the fader is let go = % x, want % x", out, msg)
+	}
+
+	want[0].Meter = 12
+	if out, msg := s.update(want), []byte{midiChannelPressure, 12}; !bytes.Equal(out, msg) {
+		t.Errorf("meter update = % x, want % x", out, msg)
+	}
+	if got := mackieMeter(1); got != mackieMeterTop {
+		t.Errorf("mackieMeter(0 dB) = %d, want %d", got, mackieMeterTop)
+	}
+}
+
+// TestMackieInput tests faders, banks and select buttons
+func TestMackieInput(t *testing.T) {
+	setMackieLoops(t, 12)
+	var sentMu sync.Mutex
+	sent := map[int]float32{}
+	levelThrottle = newSendThrottle(1000, 0, func(loopID int, v float32) {
+		sentMu.Lock()
+		sent[loopID] = v
+		sentMu.Unlock()
+	})
+
+	var s mackieSurface
+	s.handle(midiMessage{Kind: midiNoteOn, Data1: mackieBankRight, Data2: 0x7f})
+	s.handle(midiMessage{Kind: midiNoteOn, Data1: mackieChannelLeft, Data2: 0x7f})
+	if s.bank != 7 {
+		t.Fatalf("bank = %d after bank right and channel left, want 7", s.bank)
+	}
+	s.handle(midiMessage{Kind: midiNoteOn, Data1: mackieBankRight, Data2: 0x7f})
+	if s.bank != 11 {
+		t.Errorf("bank = %d past the last loop, want 11", s.bank)
+	}
+	s.handle(midiMessage{Kind: midiNoteOn, Data1: mackieBankLeft, Data2: 0x7f})
+
+	// Strip 2's fader to the top is loop 5.
+	s.handle(midiMessage{Kind: midiPitchBend, Channel: 2, Data1: 0x7f, Data2: 0x7f})
+	eventually(t, "level sent", func() bool {
+		sentMu.Lock()
+		defer sentMu.Unlock()
+		return sent[5] == levelMax
+	})
+	if s.shown[1].Fader != 16383 {
+		t.Errorf("moved fader shown at %d, want it not sent back", s.shown[1].Fader)
+	}
+
+	s.handle(midiMessage{Kind: midiNoteOn, Data1: mackieSelect + 2, Data2: 0x7f})
+	if selectedLoop != 5 {
+		t.Errorf("selected loop %d, want 6", selectedLoop+1)
+	}
+}
+
+// TestMackieRun tests a surface session over a byte stream
+func TestMackieRun(t *testing.T) {
+	setMackieLoops(t, 1)
+	levelThrottle = newSendThrottle(1000, 0, func(int, float32) {})
+	fromSurface, toGUI := io.Pipe()
+	toSurface, fromGUI := io.Pipe()
+	var s mackieSurface
+	done := make(chan error, 1)
+	go func() {
+		done <- s.run(struct {
+			io.Reader
+			io.Writer
+		}{fromSurface, fromGUI})
+	}()
+
+	buf := make([]byte, 512)
+	n, err := toSurface.Read(buf)
+	if err != nil || !bytes.Contains(buf[:n], mackieText(0, "Loop 1")) {
+		t.Fatalf("first write = % x, %v, want the display", buf[:n], err)
+	}
+	go io.Copy(io.Discard, toSurface)
+	toGUI.Write([]byte{midiPitchBend, 0, 0x40})
+	eventually(t, "level from the fader", func() bool { return loopStates[0].Wet > 0 })
+	toGUI.Close()
+	if err := <-done; err != io.EOF {
+		t.Errorf("run = %v after the device closed, want EOF", err)
+	}
+}
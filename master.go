@@ -0,0 +1,138 @@
+// master.go
+// Master fader: a VCA over every loop's Level. The loops keep their own
+// Levels, and each mixer strip gets its Level times the master, sent to all
+// strips in one bundle when the master moves. M mutes the master and
+// unmuting brings back where it was.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rivo/tview"
+)
+
+// masterStepDB is how far { and } move the master fader.
+const masterStepDB = 1.0
+
+// masterFader is the gain the master puts on every loop's Level, from 0 to
+// 1 for unity. Muting keeps Level, to come back to.
+type masterFader struct {
+	Level float32 `json:"level"`
+	Muted bool    `json:"muted,omitempty"`
+}
+
+var (
+	// master is guarded by mu.
+	master     = masterFader{Level: 1}
+	showMaster bool
+	// masterThrottle holds master moves to --max-send-rate. Its one value,
+	// for loop ID 0, is the master Level; the send is of every strip.
+	masterThrottle *sendThrottle
+)
+
+// gain is the factor on every loop's Level.
+func (m masterFader) gain() float32 {
+	if m.Muted {
+		return 0
+	}
+	return m.Level
+}
+
+// stripGain is the gain sent to the mixer strip of a loop at Level wet.
+// The caller must hold mu.
+func stripGain(wet float32) float32 {
+	return wet * master.gain()
+}
+
+// reportedLevel is the loop Level behind a strip gain the mixer reports,
+// and false while the master is silent, when the gain tells nothing of it.
+// The caller must hold mu.
+func reportedLevel(gain float32) (float32, bool) {
+	g := master.gain()
+	if g <= 0 {
+		return 0, false
+	}
+	return gain / g, true
+}
+
+// newMasterThrottle returns the throttle for master moves.
+func newMasterThrottle() *sendThrottle {
+	return newSendThrottle(maxSendRate, 0, func(int, float32) { sendStripGains() })
+}
+
+// setMaster moves the master fader to level, keeping it muted if it was.
+func setMaster(level float32) {
+	mu.Lock()
+	master.Level = min(max(level, 0), 1)
+	level = master.Level
+	mu.Unlock()
+	masterThrottle.Set(0, level)
+}
+
+// nudgeMaster moves a master Level by db, to silence below the meter
+// floor and up from it.
+func nudgeMaster(level float32, db float64) float32 {
+	floor := float32(dbToAmp(meterMinDB))
+	if level < floor {
+		if db < 0 {
+			return 0
+		}
+		level = floor
+	}
+	v := level * float32(dbToAmp(db))
+	if v < floor {
+		return 0
+	}
+	return min(v, 1)
+}
+
+// toggleMasterMute mutes every loop through the master, or brings the
+// master back to its Level.
+func toggleMasterMute() {
+	mu.Lock()
+	master.Muted = !master.Muted
+	muted := master.Muted
+	mu.Unlock()
+	tuiLog.Info("master", "muted", muted)
+	sendStripGains()
+}
+
+// sendStripGains sends every loop's Level through the master to the mixer
+// in one bundle.
+func sendStripGains() {
+	mu.Lock()
+	gains := make(map[int]float32, loopCount)
+	for i := range loopCount {
+		gains[i+1] = stripGain(getLoopState(i).Wet)
+	}
+	mu.Unlock()
+	for id, g := range gains {
+		levelThrottle.sentAs(id, g)
+	}
+	sl.SetStripGains(gains)
+}
+
+// masterLine lays the master fader out in width columns. It returns the
+// text, with color tags, and the first column and width of the bar, for
+// mouse hits.
+func masterLine(m masterFader, width int) (text string, barX, barW int) {
+	left := " " + tr("Master") + " "
+	right := fmt.Sprintf(" %5s dB ", levelDBText(m.Level))
+	if m.Muted {
+		right += "[red::b]" + tr("MUTED") + "[-::-] "
+	}
+	barX = utf8.RuneCountInString(left)
+	barW = max(width-barX-tview.TaggedStringWidth(right), 3)
+
+	fill := int(math.Round(float64(lvlLaw.fill(m.Level, 1, meterMinDB)) * float64(barW)))
+	color := "green"
+	if m.Muted {
+		color = "gray"
+	}
+	bar := "[" + color + "]" + strings.Repeat("█", fill) + "[-]" + strings.Repeat("░", barW-fill)
+	return left + bar + right, barX, barW
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestMasterLine tests the master fader's layout, and its mute
+func TestMasterLine(t *testing.T) {
+	text, barX, barW := masterLine(masterFader{Level: 1}, 40)
+	if !strings.HasPrefix(text, " Master ") || barX != 8 || !strings.Contains(text, "0 dB") {
+		t.Errorf("line %q, bar at %d, want the label then the bar at unity", text, barX)
+	}
+	if n := strings.Count(text, "█"); n != barW {
+		t.Errorf("%d of %d filled at unity", n, barW)
+	}
+	text, _, _ = masterLine(masterFader{Level: 0.5, Muted: true}, 40)
+	if !strings.Contains(text, "MUTED") || !strings.Contains(text, "-6 dB") {
+		t.Errorf("muted line %q, want MUTED and the Level kept", text)
+	}
+}
+
+// TestNudgeMaster tests the steps of the master fader at its ends
+func TestNudgeMaster(t *testing.T) {
+	if got := nudgeMaster(1, 3); got != 1 {
+		t.Errorf("above unity = %v, want 1", got)
+	}
+	if got := nudgeMaster(0, -1); got != 0 {
+		t.Errorf("below silence = %v, want 0", got)
+	}
+	if got := nudgeMaster(0, 1); got <= 0 {
+		t.Errorf("up from silence = %v, want the floor and a step", got)
+	}
+}
+
+// TestMasterFader tests that the master scales every strip's gain and its
+// mute brings them back
+func TestMasterFader(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 2)
+	c := startClient(t, sim)
+	sl = c
+	// Sends hold sendMu, so the end of the test follows them.
+	var sendMu sync.Mutex
+	levelThrottle = newSendThrottle(1000, 0, func(id int, v float32) {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		c.SetStripGain(id, v)
+	})
+	masterThrottle = newSendThrottle(1000, 0, func(int, float32) {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		sendStripGains()
+	})
+	t.Cleanup(func() {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		mu.Lock()
+		sl, master = nil, masterFader{Level: 1}
+		levelThrottle = newSendThrottle(1000, 0, func(int, float32) {})
+		mu.Unlock()
+	})
+	eventually(t, "two loops", func() bool { return loopCount == 2 })
+	mu.Lock()
+	getLoopState(0).Wet, getLoopState(1).Wet = 0.5, 0.25
+	mu.Unlock()
+	gains := func(want0, want1 float64) func() bool {
+		return func() bool {
+			g1, _ := sim.StripGain(1)
+			g2, _ := sim.StripGain(2)
+			return math.Abs(float64(g1)-want0) < 0.01 && math.Abs(float64(g2)-want1) < 0.01
+		}
+	}
+
+	setMaster(0.5)
+	eventually(t, "strips at half", gains(ampToDB(0.25), ampToDB(0.125)))
+	toggleMasterMute()
+	eventually(t, "strips silent", func() bool {
+		g1, _ := sim.StripGain(1)
+		g2, _ := sim.StripGain(2)
+		return g1 <= -70 && g2 <= -70
+	})
+	toggleMasterMute()
+	eventually(t, "strips back", gains(ampToDB(0.25), ampToDB(0.125)))
+
+	setLevel(0, 0.25)
+	eventually(t, "Level through the master", gains(ampToDB(0.125), ampToDB(0.125)))
+	mu.Lock()
+	defer mu.Unlock()
+	if wet, ok := reportedLevel(0.125); !ok || wet != 0.25 {
+		t.Errorf("reported 0.125 = %v, %v; want the Level 0.25", wet, ok)
+	}
+}
@@ -0,0 +1,185 @@
+// meters.go
+// Client-side meter ballistics.
+
+package main
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// ballistics applies peak programme meter behaviour to a stream of levels:
+// rises are shown immediately and falls are limited to a release rate in
+// dB per second, so bars decay smoothly between engine updates.
+type ballistics struct {
+	db   float64
+	last time.Time
+}
+
+func (b *ballistics) step(amp float32, now time.Time, releaseDBps, floorDB float64) float32 {
+	target := floorDB
+	if amp > 0 {
+		target = max(ampToDB(amp), floorDB)
+	}
+	if b.last.IsZero() || releaseDBps <= 0 || target >= b.db {
+		b.db = target
+	} else {
+		b.db = max(b.db-releaseDBps*now.Sub(b.last).Seconds(), target)
+	}
+	b.last = now
+	if b.db <= floorDB {
+		return 0
+	}
+	return float32(dbToAmp(b.db))
+}
+
+// rmsWindow averages the engine's peak readings over a sliding time window,
+// giving a loudness-style level to show alongside the instantaneous peak.
+type rmsWindow struct {
+	samples []rmsSample
+	sum     float64
+}
+
+type rmsSample struct {
+	t  time.Time
+	sq float64
+}
+
+func (w *rmsWindow) add(amp float32, now time.Time, window time.Duration) {
+	sq := float64(amp) * float64(amp)
+	w.samples = append(w.samples, rmsSample{now, sq})
+	w.sum += sq
+	w.expire(now, window)
+}
+
+func (w *rmsWindow) value(now time.Time, window time.Duration) float32 {
+	w.expire(now, window)
+	if len(w.samples) == 0 || w.sum <= 0 {
+		return 0
+	}
+	return float32(math.Sqrt(w.sum / float64(len(w.samples))))
+}
+
+func (w *rmsWindow) expire(now time.Time, window time.Duration) {
+	n := 0
+	for n < len(w.samples) && now.Sub(w.samples[n].t) > window {
+		w.sum -= w.samples[n].sq
+		n++
+	}
+	if n > 0 {
+		w.samples = append(w.samples[:0], w.samples[n:]...)
+	}
+	if len(w.samples) == 0 {
+		w.sum = 0
+	}
+}
+
+// levelHistory keeps recent engine readings for the sparkline view.
+type levelHistory struct {
+	samples []historySample
+}
+
+type historySample struct {
+	t   time.Time
+	amp float32
+}
+
+func (h *levelHistory) add(amp float32, now time.Time, span time.Duration) {
+	h.samples = append(h.samples, historySample{now, amp})
+	n := 0
+	for n < len(h.samples) && now.Sub(h.samples[n].t) > span {
+		n++
+	}
+	if n > 0 {
+		h.samples = append(h.samples[:0], h.samples[n:]...)
+	}
+}
+
+// buckets splits the span ending at now into n equal slots, oldest first,
+// each holding the loudest reading that arrived in it.
+func (h *levelHistory) buckets(n int, now time.Time, span time.Duration) []float32 {
+	out := make([]float32, n)
+	if n == 0 {
+		return out
+	}
+	start := now.Add(-span)
+	for _, s := range h.samples {
+		if s.t.Before(start) {
+			continue
+		}
+		i := int(int64(s.t.Sub(start)) * int64(n) / int64(span))
+		if i >= n {
+			i = n - 1
+		}
+		out[i] = max(out[i], s.amp)
+	}
+	return out
+}
+
+// Braille dots bottom to top, for the left and right column of a cell.
+var (
+	brailleLeft  = [4]rune{0x40, 0x04, 0x02, 0x01}
+	brailleRight = [4]rune{0x80, 0x20, 0x10, 0x08}
+)
+
+// brailleSparkline renders fills (0..1) two per character, four dots high.
+func brailleSparkline(fills []float32) string {
+	var b strings.Builder
+	for i := 0; i < len(fills); i += 2 {
+		right := 0
+		if i+1 < len(fills) {
+			right = brailleHeight(fills[i+1])
+		}
+		b.WriteRune(brailleRune(brailleHeight(fills[i]), right))
+	}
+	return b.String()
+}
+
+// brailleHeight is the number of dots, 0 to 4, that show fill.
+func brailleHeight(fill float32) int {
+	return min(max(int(math.Ceil(float64(fill)*4)), 0), 4)
+}
+
+// brailleRune is the braille character with its left and right dot
+// columns lit to the given heights from the bottom.
+func brailleRune(left, right int) rune {
+	r := rune(0x2800)
+	for i := 0; i < left; i++ {
+		r |= brailleLeft[i]
+	}
+	for i := 0; i < right; i++ {
+		r |= brailleRight[i]
+	}
+	return r
+}
+
+// brailleBarHeights lays out a horizontal bar showing fill (0..1) over
+// columns dot columns: full columns, then one filled partway up, so each
+// character has eight steps.
+func brailleBarHeights(fill float32, columns int) []int {
+	steps := min(max(int(math.Ceil(float64(fill)*float64(columns*4))), 0), columns*4)
+	heights := make([]int, columns)
+	for c := range heights {
+		heights[c] = min(max(steps-c*4, 0), 4)
+	}
+	return heights
+}
+
+// brailleBar renders dot column heights two per character, with spaces
+// for characters without dots.
+func brailleBar(heights []int) string {
+	var b strings.Builder
+	for i := 0; i < len(heights); i += 2 {
+		left, right := heights[i], 0
+		if i+1 < len(heights) {
+			right = heights[i+1]
+		}
+		if left == 0 && right == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteRune(brailleRune(left, right))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestBallistics tests instant attack and rate-limited release
+func TestBallistics(t *testing.T) {
+	var b ballistics
+	t0 := time.Now()
+	at := func(ms int) time.Time { return t0.Add(time.Duration(ms) * time.Millisecond) }
+	db := func(v float32) float64 { return ampToDB(v) }
+
+	if got := b.step(1, at(0), 20, -70); got != 1 {
+		t.Fatalf("attack: got %v, want 1", got)
+	}
+	// Signal drops to silence: after 500 ms at 20 dB/s the meter reads -10 dB.
+	if got := db(b.step(0, at(500), 20, -70)); math.Abs(got+10) > 1e-6 {
+		t.Errorf("release after 500ms: got %v dB, want -10", got)
+	}
+	// Release never falls below the incoming level.
+	if got := db(b.step(float32(dbToAmp(-12)), at(1000), 20, -70)); math.Abs(got+12) > 1e-6 {
+		t.Errorf("release floor: got %v dB, want -12", got)
+	}
+	// A new peak is shown immediately.
+	if got := db(b.step(float32(dbToAmp(-3)), at(1010), 20, -70)); math.Abs(got+3) > 1e-6 {
+		t.Errorf("attack: got %v dB, want -3", got)
+	}
+	// Decays all the way to silence.
+	if got := b.step(0, at(10000), 20, -70); got != 0 {
+		t.Errorf("silence: got %v, want 0", got)
+	}
+}
+
+// TestBallisticsDisabled tests that a zero release rate passes levels through
+func TestBallisticsDisabled(t *testing.T) {
+	var b ballistics
+	now := time.Now()
+	b.step(1, now, 0, -70)
+	if got := b.step(0.5, now.Add(time.Millisecond), 0, -70); math.Abs(float64(got-0.5)) > 1e-6 {
+		t.Errorf("got %v, want 0.5", got)
+	}
+}
+
+// TestRMSWindow tests the sliding RMS average
+func TestRMSWindow(t *testing.T) {
+	var w rmsWindow
+	t0 := time.Now()
+	window := 300 * time.Millisecond
+
+	if got := w.value(t0, window); got != 0 {
+		t.Errorf("empty window: got %v, want 0", got)
+	}
+	w.add(1, t0, window)
+	w.add(0, t0.Add(100*time.Millisecond), window)
+	if got := w.value(t0.Add(100*time.Millisecond), window); math.Abs(float64(got)-math.Sqrt(0.5)) > 1e-6 {
+		t.Errorf("two samples: got %v, want %v", got, math.Sqrt(0.5))
+	}
+	// The first sample leaves the window.
+	if got := w.value(t0.Add(350*time.Millisecond), window); got != 0 {
+		t.Errorf("after expiry: got %v, want 0", got)
+	}
+	if got := w.value(t0.Add(time.Second), window); got != 0 || len(w.samples) != 0 {
+		t.Errorf("all expired: got %v with %d samples", got, len(w.samples))
+	}
+}
+
+// TestBrailleSparkline tests the brailleSparkline function
+func TestBrailleSparkline(t *testing.T) {
+	tests := []struct {
+		name  string
+		fills []float32
+		want  string
+	}{
+		{"empty", nil, ""},
+		{"silence", []float32{0, 0}, "\u2800"},
+		{"full", []float32{1, 1}, "\u28ff"},
+		{"left quarter, right half", []float32{0.25, 0.5}, string(rune(0x2800 | 0x40 | 0x80 | 0x20))},
+		{"any level shows a dot", []float32{0.01, 0}, string(rune(0x2800 | 0x40))},
+		{"odd length", []float32{1, 1, 1}, "\u28ff\u2847"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := brailleSparkline(tt.fills); got != tt.want {
+				t.Errorf("brailleSparkline(%v) = %q, want %q", tt.fills, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBrailleBar tests bars of braille dots at eight steps per character
+func TestBrailleBar(t *testing.T) {
+	tests := []struct {
+		name    string
+		fill    float32
+		columns int
+		want    string
+	}{
+		{"silence", 0, 4, "  "},
+		{"full", 1, 4, "\u28ff\u28ff"},
+		{"one dot", 0.01, 4, string(rune(0x2800|0x40)) + " "},
+		{"half a character", 0.25, 4, "\u2847 "},
+		{"left full, right partway", 0.375, 4, string(rune(0x2800|0x47|0x80|0x20)) + " "},
+		{"odd columns", 1, 3, "\u28ff\u2847"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := brailleBar(brailleBarHeights(tt.fill, tt.columns)); got != tt.want {
+				t.Errorf("brailleBar(%v over %d) = %q, want %q", tt.fill, tt.columns, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLevelHistoryBuckets tests that readings land in time slots keeping the maximum
+func TestLevelHistoryBuckets(t *testing.T) {
+	var h levelHistory
+	t0 := time.Now()
+	span := 4 * time.Second
+	h.add(0.9, t0, span)                            // expires
+	h.add(0.2, t0.Add(5*time.Second), span)         // slot 1
+	h.add(0.5, t0.Add(5500*time.Millisecond), span) // slot 1, louder
+	h.add(0.3, t0.Add(7500*time.Millisecond), span) // slot 3
+
+	got := h.buckets(4, t0.Add(8*time.Second), span)
+	want := []float32{0, 0.5, 0, 0.3}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("buckets = %v, want %v", got, want)
+		}
+	}
+	if len(h.samples) != 3 {
+		t.Errorf("kept %d samples, want 3", len(h.samples))
+	}
+}
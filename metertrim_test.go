@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestValidateMeterTrims tests loop numbers and the trim range
+func TestValidateMeterTrims(t *testing.T) {
+	if _, err := parseConfig([]byte("meter_trims: {1: -6, 2: 3.5}")); err != nil {
+		t.Errorf("good trims: %v", err)
+	}
+	for _, bad := range []string{"meter_trims: {0: -6}", "meter_trims: {1: 60}", "meter_trims: {1: .nan}"} {
+		if _, err := parseConfig([]byte(bad)); err == nil {
+			t.Errorf("%s was accepted", bad)
+		}
+	}
+}
+
+// TestMeterTrim tests that a trimmed loop's meters read like an untrimmed
+// loop at the trimmed level, and that its Level is left alone
+func TestMeterTrim(t *testing.T) {
+	defer setMeterTrims(nil)
+	setMeterTrims(map[int]float64{2: -6})
+	now := time.Now()
+	trimmed := &LoopState{InPeakMeter: 0.5, OutPeakMeter: 0.5, Wet: 0.5}
+	plain := &LoopState{InPeakMeter: 0.5, OutPeakMeter: 0.5, Wet: 0.5}
+	quieter := &LoopState{InPeakMeter: float32(0.5 * dbToAmp(-6)), OutPeakMeter: float32(0.5 * dbToAmp(-6)), Wet: 0.5}
+
+	mu.Lock()
+	defer mu.Unlock()
+	rows := renderTable(tableOptions{Width: 100}, []*LoopState{plain, trimmed}, now)
+	want := renderTable(tableOptions{Width: 100}, []*LoopState{quieter}, now)
+	for _, col := range []int{colMeterIn, colMeterOut, colLevel} {
+		if !reflect.DeepEqual(rows[2][col], want[1][col]) {
+			t.Errorf("column %d of the trimmed loop = %+v, want %+v", col, rows[2][col], want[1][col])
+		}
+	}
+	if reflect.DeepEqual(rows[1][colMeterIn], rows[2][colMeterIn]) {
+		t.Error("loop 1 is trimmed too")
+	}
+	if got := meterTrimText(1); got != "Meters -6.0 dB" {
+		t.Errorf("trim text %q", got)
+	}
+}
@@ -0,0 +1,118 @@
+// metronome.go
+// Beat and bar indicator derived from the engine tempo.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+const globalUpdatePrefix = "/global/update_"
+
+var (
+	// globals holds engine-wide controls such as tempo, by name.
+	globals = make(map[string]float32)
+
+	showMetronome bool
+	clickControl  = ""
+	clickOn       bool
+	metroStart    = time.Now()
+)
+
+// beatAt returns the bar and beat (both from 0) pos seconds into the
+// song. A cycle of eighths eighth notes is one bar.
+func beatAt(pos float64, tempo, eighths float32) (bar, beat, beatsPerBar int) {
+	beatsPerBar = max(int(eighths/2), 1)
+	if tempo <= 0 || pos < 0 {
+		return 0, 0, beatsPerBar
+	}
+	n := int(math.Floor(pos * float64(tempo) / 60))
+	return n / beatsPerBar, n % beatsPerBar, beatsPerBar
+}
+
+// metronomeText draws the beat indicator for the status bar: one dot per
+// beat with the current beat lit, the downbeat in red.
+func metronomeText(bar, beat, beatsPerBar int, tempo float32) string {
+	if tempo <= 0 {
+		return "[gray]♩ –[-]"
+	}
+	var b strings.Builder
+	if beatsPerBar <= 16 {
+		for i := 0; i < beatsPerBar; i++ {
+			switch {
+			case i != beat:
+				b.WriteString("○")
+			case i == 0:
+				b.WriteString("[red]●[-]")
+			default:
+				b.WriteString("[yellow]●[-]")
+			}
+		}
+	} else {
+		fmt.Fprintf(&b, "%d/%d", beat+1, beatsPerBar)
+	}
+	fmt.Fprintf(&b, " %s  %g BPM", trf("bar %d", bar+1), math.Round(float64(tempo)*10)/10)
+	return b.String()
+}
+
+// metronomePos returns the song position the beat is counted from: loop
+// 1's position while it runs (not Off, Pause or OffMuted), so the
+// indicator follows the loop, and the time since startup otherwise. The
+// caller must hold mu.
+func metronomePos(now time.Time) float64 {
+	if ls := loopStates[0]; ls != nil && ls.haveState && !ls.State.Stopped() {
+		return float64(ls.LoopPos)
+	}
+	return now.Sub(metroStart).Seconds()
+}
+
+// metronomeStatus is the status bar part of the metronome. The caller
+// must hold mu.
+func metronomeStatus(now time.Time) string {
+	tempo := globals["tempo"]
+	bar, beat, n := beatAt(metronomePos(now), tempo, globals["eighth_per_cycle"])
+	text := metronomeText(bar, beat, n, tempo)
+	if clickControl != "" && clickOn {
+		text += "  [green]click[-]"
+	}
+	return text
+}
+
+// pollGlobal asks for a global control, answered on /global/update_<name>.
+func pollGlobal(c oscSender, control, returnURL string) {
+	m := osc.NewMessage("/get")
+	m.Append(control)
+	m.Append(returnURL)
+	m.Append(globalUpdatePrefix + control)
+	oscSend(c, m)
+}
+
+// registerGlobalUpdate asks for a global control whenever it changes,
+// sent as pollGlobal's answers are.
+func registerGlobalUpdate(c oscSender, control, returnURL string) {
+	m := osc.NewMessage("/register_update")
+	m.Append(control)
+	m.Append(returnURL)
+	m.Append(globalUpdatePrefix + control)
+	oscSend(c, m)
+}
+
+// toggleClick switches the --click-control engine control on or off.
+func toggleClick() {
+	if clickControl == "" {
+		return
+	}
+	mu.Lock()
+	clickOn = !clickOn
+	v := float32(0)
+	if clickOn {
+		v = 1
+	}
+	mu.Unlock()
+	sl.SetGlobal(clickControl, v)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// TestBeatAt tests the bar and beat at a song position
+func TestBeatAt(t *testing.T) {
+	tests := []struct {
+		pos            float64
+		tempo, eighths float32
+		bar, beat, n   int
+	}{
+		{0, 120, 8, 0, 0, 4},
+		{0.49, 120, 8, 0, 0, 4},
+		{0.5, 120, 8, 0, 1, 4},
+		{2.1, 120, 8, 1, 0, 4},
+		{7, 90, 6, 3, 1, 3},
+		{5, 0, 8, 0, 0, 4},
+		{5, 120, 1, 10, 0, 1},
+	}
+	for _, tt := range tests {
+		bar, beat, n := beatAt(tt.pos, tt.tempo, tt.eighths)
+		if bar != tt.bar || beat != tt.beat || n != tt.n {
+			t.Errorf("beatAt(%v, %v, %v) = %d, %d, %d, want %d, %d, %d", tt.pos, tt.tempo, tt.eighths, bar, beat, n, tt.bar, tt.beat, tt.n)
+		}
+	}
+}
+
+// TestMetronomeText tests the status bar beat indicator
+func TestMetronomeText(t *testing.T) {
+	tests := []struct {
+		bar, beat, n int
+		tempo        float32
+		want         string
+	}{
+		{0, 0, 4, 120, "[red]●[-]○○○ bar 1  120 BPM"},
+		{4, 2, 4, 96.25, "○○[yellow]●[-]○ bar 5  96.3 BPM"},
+		{1, 19, 32, 140, "20/32 bar 2  140 BPM"},
+		{0, 0, 4, 0, "[gray]♩ –[-]"},
+	}
+	for _, tt := range tests {
+		if got := metronomeText(tt.bar, tt.beat, tt.n, tt.tempo); got != tt.want {
+			t.Errorf("metronomeText(%d, %d, %d, %v) = %q, want %q", tt.bar, tt.beat, tt.n, tt.tempo, got, tt.want)
+		}
+	}
+}
+
+// TestGlobalUpdate tests that only polled global controls are stored
+func TestGlobalUpdate(t *testing.T) {
+	mu.Lock()
+	globals = make(map[string]float32)
+	mu.Unlock()
+	handleOSC(osc.NewMessage("/global/update_tempo", int32(-2), "tempo", float32(100)))
+	handleOSC(osc.NewMessage("/global/update_bogus", int32(-2), "bogus", float32(1)))
+	handleOSC(osc.NewMessage("/global/update_eighth_per_cycle", int32(-2), "tempo", float32(7)))
+	mu.Lock()
+	defer mu.Unlock()
+	if globals["tempo"] != 100 {
+		t.Errorf("tempo = %v, want 100", globals["tempo"])
+	}
+	if _, ok := globals["bogus"]; ok {
+		t.Error("stored an unpolled global")
+	}
+	if _, ok := globals["eighth_per_cycle"]; ok {
+		t.Error("stored a global from a reply for another control")
+	}
+}
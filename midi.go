@@ -0,0 +1,287 @@
+// midi.go
+// MIDI input to sooperGUI itself: notes and CCs mapped to GUI actions.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// midiConfig is the config file's midi section:
+//
+//	midi:
+//	  device: /dev/snd/midiC1D0
+//	  bindings:
+//	    - {note: 36, action: record 1}
+//	    - {cc: 7, channel: 1, action: level 1}
+//	    - {note: 38, action: "mute 2; mute 3"}
+type midiConfig struct {
+	Device   string        `yaml:"device"`
+	Bindings []midiBinding `yaml:"bindings"`
+}
+
+// midiBinding maps a note or a CC to an action. Channel is 1–16, or 0 for
+// any channel.
+type midiBinding struct {
+	Note    *int   `yaml:"note"`
+	CC      *int   `yaml:"cc"`
+	Channel int    `yaml:"channel"`
+	Action  string `yaml:"action"`
+}
+
+const (
+	midiNoteOff       = 0x80
+	midiNoteOn        = 0x90
+	midiControlChange = 0xb0
+
+	// midiPressed is where a CC counts as a button press: going from below
+	// to at or above it.
+	midiPressed = 64
+	midiRetry   = 2 * time.Second
+)
+
+// lastMIDI describes the last MIDI message received, for the MIDI page.
+// It is guarded by mu.
+var lastMIDI string
+
+// midiMessage is a channel voice message, Channel 1–16, or a real-time
+// message such as a clock tick, Channel 0.
+type midiMessage struct {
+	Kind    byte
+	Channel int
+	Data1   byte
+	Data2   byte
+}
+
+func (m midiMessage) String() string {
+	switch m.Kind {
+	case midiNoteOn:
+		return fmt.Sprintf("note %d velocity %d, channel %d", m.Data1, m.Data2, m.Channel)
+	case midiControlChange:
+		return fmt.Sprintf("cc %d value %d, channel %d", m.Data1, m.Data2, m.Channel)
+	}
+	return fmt.Sprintf("status %#x %d %d, channel %d", m.Kind, m.Data1, m.Data2, m.Channel)
+}
+
+// midiParser turns a raw MIDI byte stream into channel and real-time
+// messages, with running status. Real-time bytes may come in the middle of
+// another message. Other system messages are skipped.
+type midiParser struct {
+	status byte
+	data   []byte
+}
+
+// feed takes the next byte, returning a message when one is complete.
+func (p *midiParser) feed(b byte) (midiMessage, bool) {
+	switch {
+	case b >= 0xf8:
+		return midiMessage{Kind: b}, true
+	case b >= 0xf0: // system common and sysex end running status
+		p.status, p.data = 0, p.data[:0]
+		return midiMessage{}, false
+	case b >= 0x80:
+		p.status, p.data = b, p.data[:0]
+		return midiMessage{}, false
+	case p.status == 0:
+		return midiMessage{}, false
+	}
+	p.data = append(p.data, b)
+	n := 2
+	if kind := p.status & 0xf0; kind == 0xc0 || kind == 0xd0 {
+		n = 1
+	}
+	if len(p.data) < n {
+		return midiMessage{}, false
+	}
+	m := midiMessage{Kind: p.status & 0xf0, Channel: int(p.status&0x0f) + 1, Data1: p.data[0]}
+	if n == 2 {
+		m.Data2 = p.data[1]
+	}
+	p.data = p.data[:0]
+	if m.Kind == midiNoteOn && m.Data2 == 0 {
+		m.Kind = midiNoteOff // note on with velocity 0 is a note off
+	}
+	return m, true
+}
+
+// midiRoute is a parsed binding.
+type midiRoute struct {
+	midiBinding
+	steps []actionStep
+	fader bool // runs on every CC value, not only on presses
+	// hold is the binding's loop command when it is one alone, which
+	// lasts while the note or CC is held if it is momentary.
+	hold *footAction
+}
+
+func (b midiBinding) String() string {
+	src := "any channel"
+	if b.Channel > 0 {
+		src = fmt.Sprintf("channel %d", b.Channel)
+	}
+	if b.Note != nil {
+		return fmt.Sprintf("note %d, %s", *b.Note, src)
+	}
+	return fmt.Sprintf("cc %d, %s", *b.CC, src)
+}
+
+// routes parses the bindings, which may run the macros.
+func (c *midiConfig) routes(macros map[string]string) ([]midiRoute, error) {
+	if c.Device == "" {
+		return nil, errors.New("device is required")
+	}
+	var out []midiRoute
+	for i, b := range c.Bindings {
+		r, err := b.route(macros)
+		if err != nil {
+			return nil, fmt.Errorf("bindings[%d]: %w", i, err)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func (b midiBinding) route(macros map[string]string) (midiRoute, error) {
+	switch {
+	case (b.Note == nil) == (b.CC == nil):
+		return midiRoute{}, errors.New("give one of note or cc")
+	case b.Note != nil && (*b.Note < 0 || *b.Note > 127), b.CC != nil && (*b.CC < 0 || *b.CC > 127):
+		return midiRoute{}, errors.New("note and cc are 0 to 127")
+	case b.Channel < 0 || b.Channel > 16:
+		return midiRoute{}, errors.New("channel is 1 to 16, or 0 for any")
+	}
+	steps, fader, err := parseActions(b.Action, macros)
+	if err != nil {
+		return midiRoute{}, err
+	}
+	if fader && b.CC == nil {
+		return midiRoute{}, fmt.Errorf("%q needs a cc", b.Action)
+	}
+	rt := midiRoute{midiBinding: b, steps: steps, fader: fader}
+	if a, err := parseFootAction(b.Action); err == nil {
+		rt.hold = &a
+	}
+	return rt, nil
+}
+
+// midiRouter runs the routes matching each message.
+type midiRouter struct {
+	routes []midiRoute
+	cc     map[[2]int]byte // last value of each (channel, cc)
+	held   map[int][]int   // loops held by each momentary route
+}
+
+func newMIDIRouter(routes []midiRoute) *midiRouter {
+	return &midiRouter{routes: routes, cc: map[[2]int]byte{}, held: map[int][]int{}}
+}
+
+func (r *midiRouter) handle(m midiMessage) {
+	mu.Lock()
+	if m.Kind >= 0xf8 {
+		clockIn.handle(m.Kind, time.Now())
+		mu.Unlock()
+		return
+	}
+	lastMIDI = m.String()
+	mu.Unlock()
+	var pressed, released bool
+	switch m.Kind {
+	case midiNoteOn:
+		pressed = true
+	case midiNoteOff:
+		released = true
+	case midiControlChange:
+		key := [2]int{m.Channel, int(m.Data1)}
+		pressed = m.Data2 >= midiPressed && r.cc[key] < midiPressed
+		released = m.Data2 < midiPressed && r.cc[key] >= midiPressed
+		r.cc[key] = m.Data2
+	default:
+		return
+	}
+	for i, rt := range r.routes {
+		if rt.Channel != 0 && rt.Channel != m.Channel {
+			continue
+		}
+		switch {
+		case m.Kind != midiControlChange && rt.Note != nil && *rt.Note == int(m.Data1):
+		case m.Kind == midiControlChange && rt.CC != nil && *rt.CC == int(m.Data1):
+		default:
+			continue
+		}
+		if rt.hold != nil && momentary(rt.hold.Cmd) {
+			switch {
+			case pressed:
+				midiLog.Debug("midi", "in", m.String(), "hold", rt.Action)
+				r.held[i] = rt.hold.hold()
+			case released:
+				releaseLoops(r.held[i], rt.hold.Cmd)
+				delete(r.held, i)
+			}
+			continue
+		}
+		if !pressed && !rt.fader {
+			continue
+		}
+		midiLog.Debug("midi", "in", m.String(), "action", rt.Action)
+		runActions(rt.steps, float32(m.Data2)/127)
+	}
+}
+
+// readMIDI parses MIDI from r, passing each message to handle, until r
+// fails.
+func readMIDI(r io.Reader, handle func(midiMessage)) error {
+	var p midiParser
+	buf := make([]byte, 256)
+	for {
+		n, err := r.Read(buf)
+		for _, b := range buf[:n] {
+			if m, ok := p.feed(b); ok {
+				handle(m)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// startMIDI reads the configured MIDI device until the services stop,
+// reopening it whenever it goes away. The config file has been validated.
+func startMIDI(c *midiConfig, macros map[string]string) {
+	if c == nil {
+		return
+	}
+	routes, _ := c.routes(macros)
+	router := newMIDIRouter(routes)
+	goService("midi", func(ctx context.Context) error {
+		var lastErr string
+		for {
+			err := func() error {
+				f, err := os.Open(c.Device)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				stop := context.AfterFunc(ctx, func() { f.Close() })
+				defer stop()
+				midiLog.Info("midi input open", "device", c.Device)
+				return readMIDI(f, router.handle)
+			}()
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err.Error() != lastErr {
+				midiLog.Warn("midi input unavailable", "device", c.Device, "err", err)
+				lastErr = err.Error()
+			}
+			if !sleepCtx(ctx, midiRetry) {
+				return nil
+			}
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestMIDIParser tests running status, interleaved real-time bytes, sysex and note off by velocity 0
+func TestMIDIParser(t *testing.T) {
+	in := []byte{
+		0x90, 36, 100, // note on, channel 1
+		37, 0xf8, 90, // running status, with a clock byte inside
+		0xf0, 0x7e, 0x01, 0xf7, // sysex
+		50,           // data without status: dropped
+		0xb3, 7, 127, // cc 7, channel 4
+		0x90, 36, 0, // note off
+		0xc0, 5, // program change
+	}
+	var got []string
+	if err := readMIDI(bytes.NewReader(in), func(m midiMessage) { got = append(got, m.String()) }); err != io.EOF {
+		t.Errorf("err = %v", err)
+	}
+	want := []string{
+		"note 36 velocity 100, channel 1",
+		"status 0xf8 0 0, channel 0",
+		"note 37 velocity 90, channel 1",
+		"cc 7 value 127, channel 4",
+		"status 0x80 36 0, channel 1",
+		"status 0xc0 5 0, channel 1",
+	}
+	if strings.Join(got, "; ") != strings.Join(want, "; ") {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
+
+// TestMIDIRoutes tests binding validation
+func TestMIDIRoutes(t *testing.T) {
+	cfg, err := parseConfig([]byte(`
+midi:
+  device: /dev/snd/midiC1D0
+  bindings:
+    - {note: 36, action: record 1}
+    - {cc: 7, channel: 2, action: level 1}
+    - {note: 38, action: "select next; scene 2"}
+    - {cc: 20, action: song prev}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes, _ := cfg.MIDI.routes(nil)
+	if len(routes) != 4 || !routes[1].fader || routes[0].fader || len(routes[2].steps) != 2 {
+		t.Errorf("routes %+v", routes)
+	}
+	for _, bad := range []string{
+		"midi: {bindings: []}",
+		"midi: {device: x, bindings: [{action: record 1}]}",
+		"midi: {device: x, bindings: [{note: 1, cc: 2, action: record 1}]}",
+		"midi: {device: x, bindings: [{note: 128, action: record 1}]}",
+		"midi: {device: x, bindings: [{note: 1, channel: 17, action: record 1}]}",
+		"midi: {device: x, bindings: [{note: 1, action: level 1}]}",
+		"midi: {device: x, bindings: [{note: 1, action: select}]}",
+		"midi: {device: x, bindings: [{note: 1, action: dance}]}",
+	} {
+		if _, err := parseConfig([]byte(bad)); err == nil {
+			t.Errorf("parseConfig(%q) succeeded", bad)
+		}
+	}
+}
+
+// TestMIDIRouter tests channel matching and that CC buttons act once per press
+func TestMIDIRouter(t *testing.T) {
+	mu.Lock()
+	defer func(n int) { loopCount, selectedLoop, lastMIDI = n, 0, "" }(loopCount)
+	loopCount, selectedLoop = 8, 0
+	mu.Unlock()
+
+	c := midiConfig{Device: "x", Bindings: []midiBinding{
+		{CC: new(int), Channel: 1, Action: "select next"},
+	}}
+	routes, err := c.routes(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newMIDIRouter(routes)
+	for _, m := range []midiMessage{
+		{Kind: midiControlChange, Channel: 1, Data1: 0, Data2: 127}, // press
+		{Kind: midiControlChange, Channel: 1, Data1: 0, Data2: 100}, // held
+		{Kind: midiControlChange, Channel: 1, Data1: 0, Data2: 0},   // released
+		{Kind: midiControlChange, Channel: 2, Data1: 0, Data2: 127}, // other channel
+		{Kind: midiControlChange, Channel: 1, Data1: 0, Data2: 64},  // press
+	} {
+		r.handle(m)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if selectedLoop != 2 {
+		t.Errorf("selected loop %d, want 2", selectedLoop)
+	}
+	if want := "cc 0 value 64, channel 1"; lastMIDI != want {
+		t.Errorf("lastMIDI = %q, want %q", lastMIDI, want)
+	}
+}
@@ -0,0 +1,208 @@
+// mirror.go
+// Mirroring of outgoing control changes to other OSC targets.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// mirrorConfig is one target in the config file's mirrors list:
+//
+//	mirrors:
+//	  - host: 192.168.1.30
+//	    port: 9000
+//	    rules:
+//	      - {match: /sl/*/set, control: wet, to: "/loop/{loop}/{control}"}
+//	      - {match: /sl/*/hit, drop: true}
+//
+// Without rules every control change is mirrored unchanged. With rules,
+// only messages matching one are, translated by the first that matches.
+type mirrorConfig struct {
+	Host  string       `yaml:"host"`
+	Port  int          `yaml:"port"`
+	Rules []mirrorRule `yaml:"rules"`
+}
+
+// mirrorRule translates a message. Match is an address pattern where each
+// path segment is a glob; Control, if set, is a glob the first string
+// argument (the control or command name) must match. To is the new
+// address, with {1}, {2}, ... for the segments matched by wildcards,
+// {loop} for the 1-based loop of a /sl/<n>/ address ("all" for -1), and
+// {control} for the first string argument, which then leaves the
+// arguments. An empty To keeps the address; Drop sends nothing.
+type mirrorRule struct {
+	Match   string `yaml:"match"`
+	Control string `yaml:"control"`
+	To      string `yaml:"to"`
+	Drop    bool   `yaml:"drop"`
+}
+
+// mirrorTarget is a running mirror.
+type mirrorTarget struct {
+	name   string
+	client *osc.Client
+	rules  []mirrorRule
+}
+
+var (
+	// mirrors are set up from the config file before the engine starts.
+	mirrors []*mirrorTarget
+
+	slAddress   = regexp.MustCompile(`^/sl/(-?\d+)/`)
+	placeholder = regexp.MustCompile(`\{[^}]*\}`)
+)
+
+func (c mirrorConfig) validate() error {
+	switch {
+	case c.Host == "":
+		return errors.New("host is required")
+	case c.Port <= 0 || c.Port > 65535:
+		return fmt.Errorf("port %d out of range", c.Port)
+	}
+	for i, r := range c.Rules {
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("rules[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (r mirrorRule) validate() error {
+	if !strings.HasPrefix(r.Match, "/") {
+		return fmt.Errorf("match %q must be an address starting with /", r.Match)
+	}
+	for _, pat := range append(strings.Split(r.Match, "/"), r.Control) {
+		if _, err := path.Match(pat, ""); err != nil {
+			return fmt.Errorf("bad pattern %q", pat)
+		}
+	}
+	wild := 0
+	for _, seg := range strings.Split(r.Match, "/") {
+		if strings.ContainsAny(seg, "*?[") {
+			wild++
+		}
+	}
+	for _, p := range placeholder.FindAllString(r.To, -1) {
+		switch name := p[1 : len(p)-1]; name {
+		case "loop", "control":
+		default:
+			if n, err := strconv.Atoi(name); err != nil || n < 1 || n > wild {
+				return fmt.Errorf("unknown placeholder %s in %q", p, r.To)
+			}
+		}
+	}
+	return nil
+}
+
+// startMirrors sets up the mirror targets. The config has been validated.
+func startMirrors(cfgs []mirrorConfig) {
+	for _, c := range cfgs {
+		mirrors = append(mirrors, &mirrorTarget{
+			name:   fmt.Sprintf("%s:%d", c.Host, c.Port),
+			client: osc.NewClient(c.Host, c.Port),
+			rules:  c.Rules,
+		})
+	}
+}
+
+// isControlChange reports whether a message sent to the engine changes
+// something, as opposed to a query or registration.
+func isControlChange(addr string) bool {
+	return addr == "/set" || addr == "/loop_add" || addr == "/loop_del" ||
+		slAddress.MatchString(addr) && (strings.HasSuffix(addr, "/set") || strings.HasSuffix(addr, "/hit"))
+}
+
+// mirrorOut sends a control change to every mirror target.
+func mirrorOut(m *osc.Message) {
+	for _, t := range mirrors {
+		if out := t.translate(m); out != nil {
+			oscLog.Debug("mirror", "to", t.name, "addr", out.Address, "args", out.Arguments)
+			_ = t.client.Send(out)
+		}
+	}
+}
+
+// translate applies the first matching rule, returning nil if the message
+// is not mirrored.
+func (t *mirrorTarget) translate(m *osc.Message) *osc.Message {
+	if len(t.rules) == 0 {
+		return m
+	}
+	for _, r := range t.rules {
+		if out, ok := r.apply(m); ok {
+			return out
+		}
+	}
+	return nil
+}
+
+// apply translates m if the rule matches it. out is nil for Drop.
+func (r mirrorRule) apply(m *osc.Message) (out *osc.Message, ok bool) {
+	pat, addr := strings.Split(r.Match, "/"), strings.Split(m.Address, "/")
+	if len(pat) != len(addr) {
+		return nil, false
+	}
+	var captures []string
+	for i := range pat {
+		if ok, _ := path.Match(pat[i], addr[i]); !ok {
+			return nil, false
+		}
+		if strings.ContainsAny(pat[i], "*?[") {
+			captures = append(captures, addr[i])
+		}
+	}
+	control := firstString(m)
+	if r.Control != "" {
+		if ok, _ := path.Match(r.Control, control); !ok {
+			return nil, false
+		}
+	}
+	if r.Drop {
+		return nil, true
+	}
+	if r.To == "" {
+		return m, true
+	}
+
+	loop := ""
+	if sub := slAddress.FindStringSubmatch(m.Address); sub != nil {
+		loop = "all"
+		if n, _ := strconv.Atoi(sub[1]); n >= 0 {
+			loop = strconv.Itoa(n + 1)
+		}
+	}
+	out = osc.NewMessage(placeholder.ReplaceAllStringFunc(r.To, func(p string) string {
+		switch name := p[1 : len(p)-1]; name {
+		case "loop":
+			return loop
+		case "control":
+			return control
+		default:
+			n, _ := strconv.Atoi(name)
+			return captures[n-1]
+		}
+	}))
+	args := m.Arguments
+	if strings.Contains(r.To, "{control}") && control != "" {
+		args = args[1:]
+	}
+	out.Append(args...)
+	return out, true
+}
+
+// firstString returns the message's first argument if it is a string.
+func firstString(m *osc.Message) string {
+	if len(m.Arguments) == 0 {
+		return ""
+	}
+	s, _ := m.Arguments[0].(string)
+	return s
+}
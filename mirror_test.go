@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// TestMirrorTranslate tests rule matching, placeholders and dropping
+func TestMirrorTranslate(t *testing.T) {
+	target := &mirrorTarget{rules: []mirrorRule{
+		{Match: "/sl/*/hit", Control: "undo*", Drop: true},
+		{Match: "/sl/*/set", Control: "wet", To: "/loop/{loop}/{control}"},
+		{Match: "/sl/*/hit", To: "/btn/{1}/{control}"},
+		{Match: "/set"},
+	}}
+	for _, tt := range []struct {
+		in   *osc.Message
+		want string // "" if not mirrored
+	}{
+		{osc.NewMessage("/sl/1/set", "wet", float32(0.5)), "/loop/2/wet ,f 0.5"},
+		{osc.NewMessage("/sl/-1/set", "wet", float32(1)), "/loop/all/wet ,f 1"},
+		{osc.NewMessage("/sl/0/set", "dry", float32(1)), ""},
+		{osc.NewMessage("/sl/0/hit", "record"), "/btn/0/record ,"},
+		{osc.NewMessage("/sl/0/hit", "undo_all"), ""},
+		{osc.NewMessage("/set", "tempo", float32(90)), "/set ,sf tempo 90"},
+	} {
+		got := ""
+		if out := target.translate(tt.in); out != nil {
+			got = out.String()
+		}
+		if got != tt.want {
+			t.Errorf("%s: mirrored %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if out := (&mirrorTarget{}).translate(osc.NewMessage("/sl/0/set", "wet", float32(1))); out == nil || out.Address != "/sl/0/set" {
+		t.Errorf("without rules: %v", out)
+	}
+}
+
+// TestMirrorValidate tests config checks of targets and rules
+func TestMirrorValidate(t *testing.T) {
+	for _, bad := range []string{
+		"mirrors: [{port: 9000}]",
+		"mirrors: [{host: h, port: 70000}]",
+		"mirrors: [{host: h, port: 9000, rules: [{match: sl/*/set}]}]",
+		"mirrors: [{host: h, port: 9000, rules: [{match: '/sl/[/set'}]}]",
+		"mirrors: [{host: h, port: 9000, rules: [{match: /sl/*/set, to: '/x/{2}'}]}]",
+		"mirrors: [{host: h, port: 9000, rules: [{match: /sl/*/set, to: '/x/{strip}'}]}]",
+	} {
+		if _, err := parseConfig([]byte(bad)); err == nil {
+			t.Errorf("parseConfig(%q) succeeded", bad)
+		}
+	}
+	if _, err := parseConfig([]byte("mirrors: [{host: h, port: 9000, rules: [{match: /sl/*/set, to: '/x/{1}/{loop}'}]}]")); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestMirrorOut tests that control changes sent to the engine reach a mirror target, and queries do not
+func TestMirrorOut(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	defer func(m []*mirrorTarget) { mirrors = m }(mirrors)
+	mirrors = nil
+	startMirrors([]mirrorConfig{{Host: "127.0.0.1", Port: conn.LocalAddr().(*net.UDPAddr).Port}})
+
+	engine := osc.NewClient("127.0.0.1", 9)
+	pollControl(engine, 0, "wet", "osc.udp://127.0.0.1:1")
+	setControl(engine, 0, "wet", 0.25)
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := osc.ParsePacket(string(buf[:n]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(p.(*osc.Message)); got != "/sl/0/set ,sf wet 0.25" {
+		t.Errorf("mirrored %q", got)
+	}
+}
@@ -0,0 +1,436 @@
+// mixer.go
+// External mixer strips that carry each loop's Level, defined by config.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+	"gopkg.in/yaml.v3"
+)
+
+// mixerConfig describes how to reach a loop's mixer strip. Message paths
+// and arguments are templates:
+//
+//	{id}        1-based loop ID (an int argument)
+//	{strip}     strip name or number from Strip (an int argument if numeric)
+//	{value}     gain in Unit (a float argument): linear amplitude, dB,
+//	            or fader position
+//	{url}       our OSC return URL
+//	{feedback}  the feedback path for this strip
+//
+// Other arguments are sent as ints or floats if they are numbers, and as
+// strings otherwise.
+type mixerConfig struct {
+	Preset   string         `yaml:"preset"`
+	Host     string         `yaml:"host"`
+	Port     int            `yaml:"port"`
+	Strip    string         `yaml:"strip"`
+	Unit     string         `yaml:"unit"`
+	Min      float32        `yaml:"min"`
+	Max      float32        `yaml:"max"`
+	Set      mixerMessage   `yaml:"set"`
+	Poll     *mixerMessage  `yaml:"poll"`
+	Feedback *mixerFeedback `yaml:"feedback"`
+
+	// Subscribe is sent at startup and when the engine reconnects, to ask
+	// the mixer for gain reports.
+	Subscribe []mixerMessage `yaml:"subscribe"`
+}
+
+type mixerMessage struct {
+	Path string   `yaml:"path"`
+	Args []string `yaml:"args"`
+}
+
+// mixerFeedback is how the mixer reports a strip's gain. The strip is
+// either in the path ({strip}) or the argument at index StripArg.
+type mixerFeedback struct {
+	Path     string `yaml:"path"`
+	StripArg *int   `yaml:"strip_arg"`
+	ValueArg int    `yaml:"value_arg"`
+}
+
+func intPtr(i int) *int { return &i }
+
+// mixerPresets are the built-in mixer configurations.
+var mixerPresets = map[string]mixerConfig{
+	// slmock's mixer mock, and the default.
+	"slmock": {
+		Host: "127.0.0.1", Port: 9090, Strip: "Sooper{id}", Unit: "db", Min: -70, Max: 6,
+		Set:       mixerMessage{Path: "/strip/{strip}/Gain/Gain%20(dB)", Args: []string{"{value}"}},
+		Poll:      &mixerMessage{Path: "/get_strip_gain", Args: []string{"{id}", "{url}", "{feedback}"}},
+		Feedback:  &mixerFeedback{Path: "/strip/{strip}/Gain/Gain%20(dB)"},
+		Subscribe: []mixerMessage{{Path: "/register_strip_gain", Args: []string{"{url}"}}},
+	},
+	// Non-Mixer addresses module parameters by strip name.
+	"non-mixer": {
+		Host: "127.0.0.1", Port: 6000, Strip: "Sooper{id}", Unit: "db", Min: -70, Max: 6,
+		Set:      mixerMessage{Path: "/strip/{strip}/Gain/Gain%20(dB)", Args: []string{"{value}"}},
+		Feedback: &mixerFeedback{Path: "/strip/{strip}/Gain/Gain%20(dB)"},
+	},
+	// Ardour and Mixbus address strips by surface strip ID (ssid).
+	"ardour": {
+		Host: "127.0.0.1", Port: 3819, Strip: "{id}", Unit: "db", Min: -193, Max: 6,
+		Set:      mixerMessage{Path: "/strip/gain", Args: []string{"{strip}", "{value}"}},
+		Feedback: &mixerFeedback{Path: "/strip/gain", StripArg: intPtr(0), ValueArg: 1},
+		// All strips, audio and MIDI tracks and busses, feedback of
+		// variable controls, gain in dB.
+		Subscribe: []mixerMessage{{Path: "/set_surface", Args: []string{"0", "7", "2", "0"}}},
+	},
+}
+
+// mixerPreset returns a copy of a preset that is safe to modify.
+func mixerPreset(name string) (mixerConfig, error) {
+	cfg, ok := mixerPresets[name]
+	if !ok {
+		return mixerConfig{}, fmt.Errorf("unknown mixer preset %q (have %s)", name, mixerPresetNames())
+	}
+	cfg.Preset = name
+	if cfg.Poll != nil {
+		p := *cfg.Poll
+		cfg.Poll = &p
+	}
+	if cfg.Feedback != nil {
+		f := *cfg.Feedback
+		if f.StripArg != nil {
+			f.StripArg = intPtr(*f.StripArg)
+		}
+		cfg.Feedback = &f
+	}
+	return cfg, nil
+}
+
+func mixerPresetNames() string {
+	names := make([]string, 0, len(mixerPresets))
+	for n := range mixerPresets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// loadMixerConfig reads a mixer config file. Fields it leaves out come
+// from its preset, or from slmock if it names none.
+func loadMixerConfig(file string) (mixerConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return mixerConfig{}, err
+	}
+	cfg, err := parseMixerConfig(data)
+	if err != nil {
+		return mixerConfig{}, fmt.Errorf("%s: %w", file, err)
+	}
+	return cfg, nil
+}
+
+func parseMixerConfig(data []byte) (mixerConfig, error) {
+	var head struct {
+		Preset string `yaml:"preset"`
+	}
+	if err := yaml.Unmarshal(data, &head); err != nil {
+		return mixerConfig{}, err
+	}
+	if head.Preset == "" {
+		head.Preset = "slmock"
+	}
+	cfg, err := mixerPreset(head.Preset)
+	if err != nil {
+		return mixerConfig{}, err
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return mixerConfig{}, err
+	}
+	return cfg, cfg.validate()
+}
+
+func (cfg mixerConfig) validate() error {
+	unit, err := parseGainUnit(cfg.Unit)
+	switch {
+	case cfg.Port <= 0 || cfg.Port > 65535:
+		return fmt.Errorf("port %d out of range", cfg.Port)
+	case err != nil:
+		return err
+	case unit == unitFader && (cfg.Min < 0 || cfg.Max > 1):
+		return fmt.Errorf("min and max of a fader must be within 0 and 1")
+	case cfg.Min >= cfg.Max:
+		return fmt.Errorf("min must be below max")
+	case !strings.Contains(cfg.Strip, "{id}"):
+		return fmt.Errorf("strip %q must contain {id}", cfg.Strip)
+	case cfg.Set.Path == "":
+		return fmt.Errorf("set needs a path")
+	}
+	if f := cfg.Feedback; f != nil {
+		if f.StripArg == nil && !strings.Contains(f.Path, "{strip}") {
+			return fmt.Errorf("feedback needs {strip} in its path or a strip_arg")
+		}
+		if f.ValueArg < 0 || (f.StripArg != nil && (*f.StripArg < 0 || *f.StripArg == f.ValueArg)) {
+			return fmt.Errorf("feedback strip_arg and value_arg must be distinct indexes")
+		}
+	}
+	return nil
+}
+
+// mixer sends loop Levels to external mixer strips and reads them back.
+type mixer struct {
+	cfg     mixerConfig
+	unit    gainUnit
+	client  *osc.Client
+	stripRe *regexp.Regexp // matches a strip name, capturing the loop ID
+	feedRe  *regexp.Regexp // matches a feedback path, capturing the strip
+	feedPre string         // starts every feedback path, so others skip feedRe
+	echoes  echoGuard
+
+	// conn, once attached, sends from the reply port, since mixers such
+	// as Ardour send feedback to where requests came from.
+	conn net.PacketConn
+	addr net.Addr
+}
+
+func newMixer(cfg mixerConfig) *mixer {
+	m := &mixer{cfg: cfg, client: osc.NewClient(cfg.Host, cfg.Port), echoes: echoGuard{window: echoWindow}}
+	m.unit, _ = parseGainUnit(cfg.Unit)
+	m.stripRe = regexp.MustCompile("^" + strings.ReplaceAll(regexp.QuoteMeta(cfg.Strip), regexp.QuoteMeta("{id}"), `(\d+)`) + "$")
+	if f := cfg.Feedback; f != nil {
+		m.feedRe = regexp.MustCompile("^" + strings.ReplaceAll(regexp.QuoteMeta(f.Path), regexp.QuoteMeta("{strip}"), `([^/]+)`) + "$")
+		m.feedPre, _ = m.feedRe.LiteralPrefix()
+	}
+	return m
+}
+
+func (m *mixer) strip(loopID int) string {
+	return strings.ReplaceAll(m.cfg.Strip, "{id}", strconv.Itoa(loopID))
+}
+
+// message fills in a message template for a strip.
+func (m *mixer) message(tmpl mixerMessage, loopID int, value float32, returnURL string) *osc.Message {
+	strip := m.strip(loopID)
+	msg := osc.NewMessage(strings.ReplaceAll(tmpl.Path, "{strip}", strip))
+	for _, a := range tmpl.Args {
+		switch a {
+		case "{id}":
+			msg.Append(int32(loopID))
+		case "{strip}":
+			if n, err := strconv.Atoi(strip); err == nil {
+				msg.Append(int32(n))
+			} else {
+				msg.Append(strip)
+			}
+		case "{value}":
+			msg.Append(value)
+		case "{url}":
+			msg.Append(returnURL)
+		case "{feedback}":
+			if m.cfg.Feedback != nil {
+				msg.Append(strings.ReplaceAll(m.cfg.Feedback.Path, "{strip}", strip))
+			}
+		default:
+			if n, err := strconv.ParseInt(a, 10, 32); err == nil {
+				msg.Append(int32(n))
+			} else if f, err := strconv.ParseFloat(a, 32); err == nil {
+				msg.Append(float32(f))
+			} else {
+				msg.Append(a)
+			}
+		}
+	}
+	return msg
+}
+
+// attach makes the mixer send from conn.
+func (m *mixer) attach(conn net.PacketConn) error {
+	if m == nil {
+		return nil
+	}
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port)))
+	if err != nil {
+		return err
+	}
+	m.conn, m.addr = conn, addr
+	return nil
+}
+
+func (m *mixer) send(msg *osc.Message) {
+	if m.conn == nil {
+		oscSend(m.client, msg)
+		return
+	}
+	trace.add(true, msg)
+	oscLog.Debug("out", "addr", msg.Address, "args", msg.Arguments)
+	if data, err := msg.MarshalBinary(); err == nil {
+		_, _ = m.conn.WriteTo(data, m.addr)
+	}
+}
+
+// setGain sends a Level amplitude to the strip of 1-based loopID.
+func (m *mixer) setGain(loopID int, amp float32) {
+	if m == nil {
+		return
+	}
+	m.echoes.sent(loopID, time.Now())
+	msg := m.message(m.cfg.Set, loopID, m.toUnit(amp), "")
+	m.send(msg)
+	mirrorOut(msg)
+}
+
+// setGains sends Level amplitudes to the strips of 1-based loop IDs in one
+// bundle.
+func (m *mixer) setGains(gains map[int]float32) {
+	if m == nil || len(gains) == 0 {
+		return
+	}
+	ids := make([]int, 0, len(gains))
+	for id := range gains {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	now := time.Now()
+	var msgs []*osc.Message
+	for _, id := range ids {
+		m.echoes.sent(id, now)
+		msgs = append(msgs, m.message(m.cfg.Set, id, m.toUnit(gains[id]), ""))
+	}
+	if m.conn == nil {
+		oscSendBundle(m.client, msgs)
+	} else {
+		// Immediate, like oscSendBundle's, so the mixer's clock cannot
+		// hold the ramp back.
+		b := osc.NewBundle(time.Time{})
+		for _, msg := range msgs {
+			trace.add(true, msg)
+			oscLog.Debug("out", "addr", msg.Address, "args", msg.Arguments)
+			b.Append(msg)
+		}
+		if data, err := b.MarshalBinary(); err == nil {
+			_, _ = m.conn.WriteTo(data, m.addr)
+		}
+	}
+	for _, msg := range msgs {
+		mirrorOut(msg)
+	}
+}
+
+// poll asks the mixer for a strip's gain, if the config says how.
+func (m *mixer) poll(loopID int, returnURL string) {
+	if m == nil || m.cfg.Poll == nil {
+		return
+	}
+	m.send(m.message(*m.cfg.Poll, loopID, 0, returnURL))
+}
+
+// subscribe asks the mixer to report gain changes.
+func (m *mixer) subscribe(returnURL string) {
+	if m == nil {
+		return
+	}
+	for _, tmpl := range m.cfg.Subscribe {
+		m.send(m.message(tmpl, 0, 0, returnURL))
+	}
+}
+
+// isFeedback reports whether addr is the mixer's feedback path.
+func (m *mixer) isFeedback(addr string) bool {
+	return m != nil && m.feedRe != nil && strings.HasPrefix(addr, m.feedPre) && m.feedRe.MatchString(addr)
+}
+
+// feedback returns the loop ID and Level amplitude of a gain report, and
+// false if msg is not one or is an echo of a Level just sent.
+func (m *mixer) feedback(msg *osc.Message) (loopID int, amp float32, ok bool) {
+	if m == nil || m.feedRe == nil {
+		return 0, 0, false
+	}
+	match := m.feedRe.FindStringSubmatch(msg.Address)
+	if match == nil {
+		return 0, 0, false
+	}
+	f := m.cfg.Feedback
+	var strip string
+	if f.StripArg != nil {
+		if *f.StripArg >= len(msg.Arguments) {
+			return 0, 0, false
+		}
+		switch a := msg.Arguments[*f.StripArg].(type) {
+		case string:
+			strip = a
+		default:
+			n, ok := argInt(a)
+			if !ok {
+				return 0, 0, false
+			}
+			strip = strconv.Itoa(n)
+		}
+	} else {
+		strip = match[1]
+	}
+	id := m.stripRe.FindStringSubmatch(strip)
+	if id == nil || f.ValueArg >= len(msg.Arguments) {
+		return 0, 0, false
+	}
+	loopID, err := strconv.Atoi(id[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	v, ok := argFloat(msg.Arguments[f.ValueArg])
+	if !ok || m.echoes.recent(loopID, time.Now()) {
+		return 0, 0, false
+	}
+	return loopID, m.fromUnit(v), true
+}
+
+// toUnit converts a Level amplitude to the mixer's unit and range.
+func (m *mixer) toUnit(amp float32) float32 {
+	v := m.cfg.Min
+	if amp > 0 {
+		v = float32(m.unit.fromAmp(amp))
+	}
+	return min(max(v, m.cfg.Min), m.cfg.Max)
+}
+
+// fromUnit converts a gain in the mixer's unit to a Level amplitude. The
+// bottom of a dB range is silence.
+func (m *mixer) fromUnit(v float32) float32 {
+	v = min(max(v, m.cfg.Min), m.cfg.Max)
+	if m.unit == unitDB && v <= m.cfg.Min {
+		return 0
+	}
+	return m.unit.toAmp(float64(v))
+}
+
+// echoWindow is how long after sending a Level its strip's gain reports
+// are ignored. Mixers echo what they receive, and during a drag the echoes
+// of earlier positions would otherwise pull the bar back.
+const echoWindow = 300 * time.Millisecond
+
+// echoGuard remembers when each strip's gain was last sent.
+type echoGuard struct {
+	window time.Duration
+
+	mu   sync.Mutex
+	last map[int]time.Time
+}
+
+func (g *echoGuard) sent(loopID int, now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.last == nil {
+		g.last = make(map[int]time.Time)
+	}
+	g.last[loopID] = now
+}
+
+// recent reports whether loopID's gain was sent within the window.
+func (g *echoGuard) recent(loopID int, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	at, ok := g.last[loopID]
+	return ok && now.Sub(at) < g.window
+}
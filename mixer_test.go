@@ -0,0 +1,199 @@
+package main
+
+import (
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// TestParseMixerConfig tests presets, overrides and validation
+func TestParseMixerConfig(t *testing.T) {
+	cfg, err := parseMixerConfig([]byte("preset: ardour\nport: 3820\nfeedback: {value_arg: 2}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 3820 || cfg.Unit != "db" || cfg.Set.Path != "/strip/gain" || cfg.Feedback.ValueArg != 2 {
+		t.Errorf("parsed %+v", cfg)
+	}
+	if mixerPresets["ardour"].Feedback.ValueArg != 1 {
+		t.Error("parsing a config changed the ardour preset")
+	}
+	if cfg, err := parseMixerConfig([]byte("host: mixer.local")); err != nil || cfg.Preset != "slmock" || cfg.Host != "mixer.local" {
+		t.Errorf("config without a preset = %+v, %v, want slmock with the host", cfg, err)
+	}
+	for _, bad := range []string{
+		"preset: protools",
+		"unit: volts",
+		"strip: Sooper",
+		"min: 2\nmax: 1",
+		"unit: fader",
+		"port: 0",
+		"feedback: {path: /gain}",
+		"preset: ardour\nfeedback: {strip_arg: 1, value_arg: 1}",
+	} {
+		if _, err := parseMixerConfig([]byte(bad)); err == nil {
+			t.Errorf("parseMixerConfig(%q) succeeded", bad)
+		}
+	}
+}
+
+// TestMixerMessages tests the messages sent for each preset
+func TestMixerMessages(t *testing.T) {
+	tests := []struct {
+		preset string
+		amp    float32
+		set    string
+		poll   string
+	}{
+		{"slmock", 0.1, "/strip/Sooper3/Gain/Gain%20(dB) ,f -20", "/get_strip_gain ,iss 3 osc.udp://me:1 /strip/Sooper3/Gain/Gain%20(dB)"},
+		{"non-mixer", 1, "/strip/Sooper3/Gain/Gain%20(dB) ,f 0", ""},
+		{"ardour", 0, "/strip/gain ,if 3 -193", ""},
+		{"ardour-fader", 2, "/strip/fader ,if 3 1", ""},
+	}
+	for _, tt := range tests {
+		cfg, err := mixerPreset(tt.preset)
+		if tt.preset == "ardour-fader" {
+			cfg, err = parseMixerConfig([]byte("preset: ardour\nunit: fader\nmin: 0\nmax: 1\nset: {path: /strip/fader}"))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := newMixer(cfg)
+		if got := m.message(cfg.Set, 3, m.toUnit(tt.amp), "").String(); got != tt.set {
+			t.Errorf("%s set = %q, want %q", tt.preset, got, tt.set)
+		}
+		if cfg.Poll == nil {
+			continue
+		}
+		if got := m.message(*cfg.Poll, 3, 0, "osc.udp://me:1").String(); got != tt.poll {
+			t.Errorf("%s poll = %q, want %q", tt.preset, got, tt.poll)
+		}
+	}
+}
+
+// TestMixerFeedback tests reading gain reports back into Levels
+func TestMixerFeedback(t *testing.T) {
+	slmockCfg, _ := mixerPreset("slmock")
+	ardourCfg, _ := mixerPreset("ardour")
+	tests := []struct {
+		cfg    mixerConfig
+		msg    *osc.Message
+		loopID int
+		amp    float32
+		ok     bool
+	}{
+		{slmockCfg, osc.NewMessage("/strip/Sooper2/Gain/Gain%20(dB)", float32(0)), 2, 1, true},
+		{slmockCfg, osc.NewMessage("/strip/Sooper2/Gain/Gain%20(dB)", float32(-70)), 2, 0, true},
+		{slmockCfg, osc.NewMessage("/strip/Other2/Gain/Gain%20(dB)", float32(0)), 0, 0, false},
+		{slmockCfg, osc.NewMessage("/strip/Sooper2/Gain/Gain%20(dB)", "x"), 0, 0, false},
+		{ardourCfg, osc.NewMessage("/strip/gain", int32(4), float32(-6)), 4, float32(math.Pow(10, -6.0/20)), true},
+		{ardourCfg, osc.NewMessage("/strip/gain", int32(4), float32(-200)), 4, 0, true},
+		{ardourCfg, osc.NewMessage("/strip/gain", int32(4)), 0, 0, false},
+	}
+	for _, tt := range tests {
+		id, amp, ok := newMixer(tt.cfg).feedback(tt.msg)
+		if id != tt.loopID || amp != tt.amp || ok != tt.ok {
+			t.Errorf("%s %v: feedback = %d, %v, %v, want %d, %v, %v", tt.msg.Address, tt.msg.Arguments, id, amp, ok, tt.loopID, tt.amp, tt.ok)
+		}
+	}
+}
+
+// TestEchoGuard tests that gain reports right after a send are ignored
+func TestEchoGuard(t *testing.T) {
+	cfg, _ := mixerPreset("slmock")
+	m := newMixer(cfg)
+	report := osc.NewMessage("/strip/Sooper1/Gain/Gain%20(dB)", float32(0.2))
+	m.echoes.sent(1, time.Now())
+	if _, _, ok := m.feedback(report); ok {
+		t.Error("accepted a report just after sending")
+	}
+	m.echoes.sent(1, time.Now().Add(-echoWindow))
+	if _, _, ok := m.feedback(report); !ok {
+		t.Error("ignored a report after the echo window")
+	}
+	if _, _, ok := m.feedback(osc.NewMessage("/strip/Sooper2/Gain/Gain%20(dB)", float32(0.2))); !ok {
+		t.Error("ignored a report for another strip")
+	}
+}
+
+// TestMixerSync tests that fader moves on the mixer reach the Level
+func TestMixerSync(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 2)
+	cfg, _ := mixerPreset("slmock")
+	cfg.Port, cfg.Poll = sim.port(), nil
+	mix := newMixer(cfg)
+	mu.Lock()
+	extMixer = mix
+	mu.Unlock()
+	c, err := newSLClient("127.0.0.1:0", "127.0.0.1", sim.port(), mix, handleOSC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Start()
+	defer c.Close()
+
+	// The subscription is sent at startup; retry until it has arrived.
+	eventually(t, "gain report", func() bool {
+		sim.SetStripGain(2, 0)
+		ls := loopStates[1]
+		return ls != nil && ls.Wet == 1
+	})
+	c.SetStripGain(2, 0.5)
+	eventually(t, "gain sent", func() bool {
+		v, _ := sim.StripGain(2)
+		return v < 0
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	if got := loopStates[1].Wet; got != 1 {
+		t.Errorf("Level = %v, want the echo of the sent 0.5 ignored", got)
+	}
+}
+
+// TestSetGainsBundle tests that gains attached to a connection go out as
+// one bundle, timetagged immediate
+func TestSetGainsBundle(t *testing.T) {
+	peer, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	cfg, _ := mixerPreset("slmock")
+	cfg.Host, cfg.Port = "127.0.0.1", peer.LocalAddr().(*net.UDPAddr).Port
+	m := newMixer(cfg)
+	if err := m.attach(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	m.setGains(map[int]float32{2: 1, 1: 0.5})
+	buf := make([]byte, 1024)
+	peer.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := peer.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := osc.ParsePacket(string(buf[:n]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, ok := p.(*osc.Bundle)
+	if !ok || len(b.Messages) != 2 {
+		t.Fatalf("sent %#v", p)
+	}
+	for i, msg := range b.Messages {
+		if want := m.message(cfg.Set, i+1, 0, "").Address; msg.Address != want {
+			t.Errorf("message %d to %q, want %q", i, msg.Address, want)
+		}
+	}
+	if tag := b.Timetag.TimeTag(); tag != 1 {
+		t.Errorf("timetag %#x, want 1 (immediate)", tag)
+	}
+}
@@ -0,0 +1,295 @@
+// mqtt.go
+// MQTT publishing: loop states and Levels are published to a broker under
+// the config file's topic, retained, so home and stage automation such as
+// Node-RED or QLC+ can react to the looper. It speaks just enough MQTT
+// 3.1.1 to publish at QoS 0.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mqttConfig is the config file's mqtt section:
+//
+//	mqtt:
+//	  broker: 192.168.1.10:1883
+//	  topic: stage/looper
+//	  username: looper
+//	  password: secret
+//
+// Under the topic, status is online or offline, loops the loop count, and
+// loop/<n>/state and loop/<n>/level each loop's state name and Level.
+type mqttConfig struct {
+	Broker   string `yaml:"broker"`
+	Topic    string `yaml:"topic"`
+	ClientID string `yaml:"client_id"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+const (
+	mqttDefaultPort  = "1883"
+	mqttDefaultTopic = "sooperGUI"
+	// mqttPublishEvery is how often changes are looked for.
+	mqttPublishEvery = 100 * time.Millisecond
+	// mqttKeepAlive is the keep alive sent in CONNECT. A ping goes out
+	// every half of it, and a broker that answers nothing for all of it is
+	// taken to be gone.
+	mqttKeepAlive = 30 * time.Second
+	mqttRetry     = 5 * time.Second
+	mqttTimeout   = 5 * time.Second
+
+	mqttConnect = 0x10
+	mqttConnAck = 0x20
+	mqttPublish = 0x30
+	mqttPingReq = 0xc0
+)
+
+func (c *mqttConfig) validate() error {
+	switch {
+	case c.Broker == "":
+		return errors.New("broker missing")
+	case strings.ContainsAny(c.Topic, "+#"):
+		return fmt.Errorf("topic %q must not have wildcards", c.Topic)
+	case strings.HasPrefix(c.Topic, "/") || strings.HasSuffix(c.Topic, "/"):
+		return fmt.Errorf("topic %q must not start or end with /", c.Topic)
+	case c.Password != "" && c.Username == "":
+		return errors.New("password needs a username")
+	}
+	return nil
+}
+
+// address is the broker's host and port, 1883 if none is given.
+func (c *mqttConfig) address() string {
+	if _, _, err := net.SplitHostPort(c.Broker); err != nil {
+		return net.JoinHostPort(c.Broker, mqttDefaultPort)
+	}
+	return c.Broker
+}
+
+// topic is the root of the published topics.
+func (c *mqttConfig) topic() string {
+	if c.Topic == "" {
+		return mqttDefaultTopic
+	}
+	return c.Topic
+}
+
+// clientID is the client id, sooperGUI-<host> if none is given.
+func (c *mqttConfig) clientID() string {
+	if c.ClientID != "" {
+		return c.ClientID
+	}
+	host, _ := os.Hostname()
+	return "sooperGUI-" + host
+}
+
+// mqttString appends s with its 2 byte length.
+func mqttString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// mqttPacket is a packet of type kind (with its flags) and body.
+func mqttPacket(kind byte, body []byte) []byte {
+	b := []byte{kind}
+	n := len(body)
+	for {
+		d := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			d |= 0x80
+		}
+		b = append(b, d)
+		if n == 0 {
+			break
+		}
+	}
+	return append(b, body...)
+}
+
+// connectPacket is c's CONNECT, with a retained offline status as its will.
+func (c *mqttConfig) connectPacket() []byte {
+	flags := byte(0x02 | 0x04 | 0x20) // clean session, will, will retain
+	if c.Username != "" {
+		flags |= 0x80
+	}
+	if c.Password != "" {
+		flags |= 0x40
+	}
+	b := mqttString(nil, "MQTT")
+	b = append(b, 4, flags)
+	b = binary.BigEndian.AppendUint16(b, uint16(mqttKeepAlive/time.Second))
+	b = mqttString(b, c.clientID())
+	b = mqttString(b, c.topic()+"/status")
+	b = mqttString(b, "offline")
+	if c.Username != "" {
+		b = mqttString(b, c.Username)
+	}
+	if c.Password != "" {
+		b = mqttString(b, c.Password)
+	}
+	return mqttPacket(mqttConnect, b)
+}
+
+// publishPacket is a QoS 0 PUBLISH of payload to topic.
+func publishPacket(topic, payload string, retain bool) []byte {
+	kind := byte(mqttPublish)
+	if retain {
+		kind |= 0x01
+	}
+	return mqttPacket(kind, append(mqttString(nil, topic), payload...))
+}
+
+// readMQTTPacket reads a packet, returning its first byte and body.
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	kind, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, shift := 0, 0
+	for {
+		d, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n |= int(d&0x7f) << shift
+		if d&0x80 == 0 {
+			break
+		}
+		if shift += 7; shift > 21 {
+			return 0, nil, errors.New("mqtt: bad packet length")
+		}
+	}
+	body := make([]byte, n)
+	_, err = io.ReadFull(r, body)
+	return kind, body, err
+}
+
+// mqttState is what is published for the loops: payloads by topic under
+// the root. The caller must hold mu.
+func mqttState() map[string]string {
+	out := map[string]string{"loops": strconv.Itoa(loopCount)}
+	for i := range loopCount {
+		ls := getLoopState(i)
+		if !ls.haveState {
+			continue
+		}
+		n := strconv.Itoa(i + 1)
+		out["loop/"+n+"/state"] = ls.State.String()
+		out["loop/"+n+"/level"] = strconv.FormatFloat(float64(ls.Wet), 'f', 3, 32)
+	}
+	return out
+}
+
+// runMQTT connects to the broker and publishes, until the connection
+// fails or ctx is done. The broker then publishes the offline will.
+func runMQTT(ctx context.Context, c *mqttConfig) error {
+	conn, err := (&net.Dialer{Timeout: mqttTimeout}).DialContext(ctx, "tcp", c.address())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	r := bufio.NewReader(conn)
+	_ = conn.SetDeadline(time.Now().Add(mqttTimeout))
+	if _, err := conn.Write(c.connectPacket()); err != nil {
+		return err
+	}
+	kind, body, err := readMQTTPacket(r)
+	switch {
+	case err != nil:
+		return err
+	case kind != mqttConnAck || len(body) != 2:
+		return fmt.Errorf("mqtt: got packet %#x, want CONNACK", kind)
+	case body[1] != 0:
+		return fmt.Errorf("mqtt: broker refused the connection, code %d", body[1])
+	}
+	_ = conn.SetDeadline(time.Time{})
+	mqttLog.Info("mqtt connected", "broker", c.address(), "topic", c.topic())
+
+	read := make(chan error, 1)
+	go func() {
+		for {
+			// Only PINGRESP is expected; its absence shows in the
+			// deadline.
+			_ = conn.SetReadDeadline(time.Now().Add(mqttKeepAlive))
+			if _, _, err := readMQTTPacket(r); err != nil {
+				read <- err
+				return
+			}
+		}
+	}()
+	root := c.topic() + "/"
+	if _, err := conn.Write(publishPacket(root+"status", "online", true)); err != nil {
+		return err
+	}
+	sent := map[string]string{}
+	lastPing := time.Now()
+	t := time.NewTicker(mqttPublishEvery)
+	defer t.Stop()
+	for {
+		select {
+		case err := <-read:
+			return err
+		case <-t.C:
+		}
+		mu.Lock()
+		state := mqttState()
+		mu.Unlock()
+		var out []byte
+		for topic, payload := range state {
+			if sent[topic] != payload {
+				out = append(out, publishPacket(root+topic, payload, true)...)
+				sent[topic] = payload
+			}
+		}
+		if time.Since(lastPing) >= mqttKeepAlive/2 {
+			out = append(out, mqttPingReq, 0)
+			lastPing = time.Now()
+		}
+		if len(out) == 0 {
+			continue
+		}
+		_ = conn.SetWriteDeadline(time.Now().Add(mqttTimeout))
+		if _, err := conn.Write(out); err != nil {
+			return err
+		}
+	}
+}
+
+// startMQTT publishes to the broker of the config file's mqtt section, if
+// there is one, connecting again when the connection fails.
+func startMQTT(c *mqttConfig) {
+	if c == nil {
+		return
+	}
+	goService("mqtt", func(ctx context.Context) error {
+		var lastErr string
+		for {
+			err := runMQTT(ctx, c)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err.Error() != lastErr {
+				mqttLog.Warn("mqtt broker unavailable", "broker", c.address(), "err", err)
+				lastErr = err.Error()
+			}
+			if !sleepCtx(ctx, mqttRetry) {
+				return nil
+			}
+		}
+	})
+}
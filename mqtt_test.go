@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"jaudio/internal/slstate"
+)
+
+// TestMQTTConfig tests the mqtt section's checks and defaults
+func TestMQTTConfig(t *testing.T) {
+	cfg, err := parseConfig([]byte("mqtt: {broker: lights.local}"))
+	if err != nil {
+		t.Fatalf("good mqtt section: %v", err)
+	}
+	if got := cfg.MQTT.address(); got != "lights.local:1883" {
+		t.Errorf("address %q, want port 1883", got)
+	}
+	if got := cfg.MQTT.topic(); got != "sooperGUI" {
+		t.Errorf("topic %q, want sooperGUI", got)
+	}
+	for _, bad := range []string{"mqtt: {topic: a}", "mqtt: {broker: b, topic: 'a/#'}", "mqtt: {broker: b, topic: a/}", "mqtt: {broker: b, password: p}"} {
+		if _, err := parseConfig([]byte(bad)); err == nil {
+			t.Errorf("%s was accepted", bad)
+		}
+	}
+}
+
+// TestMQTTPacket tests packet lengths over one byte
+func TestMQTTPacket(t *testing.T) {
+	payload := strings.Repeat("x", 300)
+	p := publishPacket("a/b", payload, true)
+	if p[0] != mqttPublish|1 || p[1] != 0x80|(305%128) || p[2] != 305/128 {
+		t.Errorf("header % x, want a retained PUBLISH of 305 bytes", p[:3])
+	}
+	kind, body, err := readMQTTPacket(bufio.NewReader(bytes.NewReader(p)))
+	if err != nil || kind != p[0] || string(body[5:]) != payload {
+		t.Errorf("read back %#x, %d bytes, %v", kind, len(body), err)
+	}
+}
+
+// mqttTopic splits a PUBLISH body into its topic and payload.
+func mqttTopic(body []byte) (string, string) {
+	n := int(binary.BigEndian.Uint16(body))
+	return string(body[2 : 2+n]), string(body[2+n:])
+}
+
+// TestMQTTPublish tests that loop states and Levels reach a broker, and
+// then only their changes
+func TestMQTTPublish(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	mu.Lock()
+	loopStates = make(map[int]*LoopState)
+	loopCount = 1
+	ls := getLoopState(0)
+	ls.State, ls.Wet, ls.haveState = slstate.Play, 0.5, true
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		loopStates = make(map[int]*LoopState)
+		loopCount = 0
+		mu.Unlock()
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- runMQTT(context.Background(), &mqttConfig{Broker: ln.Addr().String(), Topic: "stage/looper", ClientID: "test"})
+	}()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	kind, body, err := readMQTTPacket(r)
+	if err != nil || kind != mqttConnect || !bytes.Contains(body, []byte("stage/looper/status")) {
+		t.Fatalf("CONNECT %#x % x, %v", kind, body, err)
+	}
+	_, _ = conn.Write([]byte{mqttConnAck, 2, 0, 0})
+
+	got := map[string]string{}
+	want := map[string]string{"stage/looper/status": "online", "stage/looper/loops": "1", "stage/looper/loop/1/state": "Play", "stage/looper/loop/1/level": "0.500"}
+	for len(got) < len(want) {
+		kind, body, err := readMQTTPacket(r)
+		if err != nil {
+			t.Fatalf("after %v: %v", got, err)
+		}
+		if kind != mqttPublish|1 {
+			continue
+		}
+		topic, payload := mqttTopic(body)
+		got[topic] = payload
+	}
+	for topic, payload := range want {
+		if got[topic] != payload {
+			t.Errorf("%s = %q, want %q", topic, got[topic], payload)
+		}
+	}
+
+	mu.Lock()
+	ls.State = slstate.Mute
+	mu.Unlock()
+	for {
+		kind, body, err := readMQTTPacket(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kind != mqttPublish|1 {
+			continue
+		}
+		if topic, payload := mqttTopic(body); topic != "stage/looper/loop/1/state" || payload != "Mute" {
+			t.Errorf("published %s = %q, want only the state change", topic, payload)
+		}
+		break
+	}
+	conn.Close()
+	if err := <-done; err == nil {
+		t.Error("no error once the broker went")
+	}
+}
@@ -0,0 +1,67 @@
+// oscrouter.go
+// Dispatching the engine's messages by address. Exact addresses are looked
+// up in a map and prefixes found by binary search in a sorted table, so a
+// message costs the same however many controls are registered for, rather
+// than a string search per kind of message.
+
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// oscPrefixRoute handles the messages whose address starts with prefix,
+// given the rest of the address.
+type oscPrefixRoute struct {
+	prefix string
+	handle func(msg *osc.Message, rest string)
+}
+
+// oscRouter dispatches messages to the handler of their address, or of the
+// longest prefix of it.
+type oscRouter struct {
+	exact    map[string]func(*osc.Message)
+	prefixes []oscPrefixRoute // sorted by prefix
+}
+
+func newOSCRouter() *oscRouter {
+	return &oscRouter{exact: map[string]func(*osc.Message){}}
+}
+
+// handle routes messages to addr to h.
+func (r *oscRouter) handle(addr string, h func(*osc.Message)) {
+	r.exact[addr] = h
+}
+
+// handlePrefix routes messages to addresses starting with prefix to h,
+// unless a longer prefix or the exact address is routed too.
+func (r *oscRouter) handlePrefix(prefix string, h func(msg *osc.Message, rest string)) {
+	i, found := slices.BinarySearchFunc(r.prefixes, prefix, func(p oscPrefixRoute, s string) int { return strings.Compare(p.prefix, s) })
+	if found {
+		r.prefixes[i].handle = h
+		return
+	}
+	r.prefixes = slices.Insert(r.prefixes, i, oscPrefixRoute{prefix, h})
+}
+
+// dispatch runs the handler for msg, reporting whether there was one.
+func (r *oscRouter) dispatch(msg *osc.Message) bool {
+	if h, ok := r.exact[msg.Address]; ok {
+		h(msg)
+		return true
+	}
+	// The prefixes of the address sort before it, shorter ones first, so
+	// the first found going back from where it would sort is the longest.
+	i := sort.Search(len(r.prefixes), func(i int) bool { return r.prefixes[i].prefix > msg.Address })
+	for i--; i >= 0; i-- {
+		if rest, ok := strings.CutPrefix(msg.Address, r.prefixes[i].prefix); ok {
+			r.prefixes[i].handle(msg, rest)
+			return true
+		}
+	}
+	return false
+}
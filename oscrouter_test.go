@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// TestOSCRouter tests that exact addresses win over prefixes and longer
+// prefixes over shorter ones
+func TestOSCRouter(t *testing.T) {
+	var got string
+	r := newOSCRouter()
+	r.handle("/pong", func(*osc.Message) { got = "exact" })
+	for _, p := range []string{"/sl/", "/pong/", "/sl/0/", "/a"} {
+		r.handlePrefix(p, func(_ *osc.Message, rest string) { got = p + " " + rest })
+	}
+	tests := []struct {
+		addr, want string
+		ok         bool
+	}{
+		{"/pong", "exact", true},
+		{"/pong/7", "/pong/ 7", true},
+		{"/sl/0/update_state", "/sl/0/ update_state", true},
+		{"/sl/1/update_state", "/sl/ 1/update_state", true},
+		{"/sl/", "/sl/ ", true},
+		{"/b", "", false},
+		{"/pon", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got = ""
+		if ok := r.dispatch(osc.NewMessage(tt.addr)); ok != tt.ok || got != tt.want {
+			t.Errorf("dispatch(%q) = %v, %q, want %v, %q", tt.addr, ok, got, tt.ok, tt.want)
+		}
+	}
+
+	r.handlePrefix("/sl/", func(*osc.Message, string) { got = "replaced" })
+	if r.dispatch(osc.NewMessage("/sl/1/x")); got != "replaced" || len(r.prefixes) != 4 {
+		t.Errorf("replaced prefix: %q, %d prefixes", got, len(r.prefixes))
+	}
+}
+
+// TestHandleLoopUpdate tests loop updates routed by control
+func TestHandleLoopUpdate(t *testing.T) {
+	defer func(s map[int]*LoopState, n int) { loopStates, loopCount = s, n }(loopStates, loopCount)
+	loopStates, loopCount = map[int]*LoopState{}, 2
+
+	handleOSC(osc.NewMessage("/sl/1/update_loop_pos", int32(1), "loop_pos", float32(2.5)))
+	handleOSC(osc.NewMessage("/sl/1/update_rate", int32(1), "rate", float32(0.5)))
+	handleOSC(osc.NewMessage("/sl/1/update_bogus", int32(1), "bogus", float32(1)))
+	handleOSC(osc.NewMessage("/sl/1/update_rate", int32(0), "rate", float32(2)))
+	handleOSC(osc.NewMessage("/sl/x/update_rate", int32(1), "rate", float32(2)))
+
+	ls := loopStates[1]
+	if ls == nil || ls.LoopPos != 2.5 || ls.controls["rate"] != 0.5 {
+		t.Fatalf("loop 2 = %+v", ls)
+	}
+	if _, ok := ls.controls["bogus"]; ok {
+		t.Errorf("unknown control stored")
+	}
+	if len(loopStates) != 1 {
+		t.Errorf("%d loop states, want 1", len(loopStates))
+	}
+}
@@ -0,0 +1,97 @@
+// overdub.go
+// Overdub modes: latch toggles overdub with each press, momentary
+// overdubs only while the key, pedal or pad is held.
+
+package main
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// overdubMode (--overdub-mode) is latch or momentary.
+var overdubMode = "latch"
+
+// A terminal sends no key releases, only the key again while it repeats.
+// keyHoldDelay outlasts the usual delay before the first repeat, and
+// keyHoldRepeat the gap between repeats.
+const (
+	keyHoldDelay  = 700 * time.Millisecond
+	keyHoldRepeat = 150 * time.Millisecond
+)
+
+// momentary reports whether cmd is held rather than toggled.
+func momentary(cmd string) bool {
+	return cmd == "overdub" && overdubMode == "momentary"
+}
+
+// holdLoops starts cmd on loops until releaseLoops.
+func holdLoops(loops []int, cmd string) {
+	for _, i := range loops {
+		sl.Down(i, cmd)
+	}
+}
+
+// releaseLoops ends cmd on loops, as the key holding it is let go.
+func releaseLoops(loops []int, cmd string) {
+	for _, i := range loops {
+		sl.Up(i, cmd)
+	}
+}
+
+// keyHold is a momentary command held on the terminal keyboard. The key
+// counts as released when it stops repeating or another key comes. Its
+// methods run on the TUI's event goroutine, and queue runs a function
+// there.
+type keyHold struct {
+	queue func(func())
+	key   rune
+	cmd   string
+	loops []int
+	gen   int
+}
+
+// start holds cmd on loops with key, releasing any key held before.
+func (h *keyHold) start(key rune, cmd string, loops []int) {
+	h.release()
+	holdLoops(loops, cmd)
+	h.key, h.cmd, h.loops = key, cmd, loops
+	h.wait(keyHoldDelay)
+}
+
+// repeat takes the next key while one is held, reporting whether it is
+// the held key repeating. Any other key releases the held one.
+func (h *keyHold) repeat(ev *tcell.EventKey) bool {
+	if h.loops == nil {
+		return false
+	}
+	if ev.Key() == tcell.KeyRune && ev.Rune() == h.key {
+		h.wait(keyHoldRepeat)
+		return true
+	}
+	h.release()
+	return false
+}
+
+// wait releases the key unless it repeats within d.
+func (h *keyHold) wait(d time.Duration) {
+	h.gen++
+	gen := h.gen
+	time.AfterFunc(d, func() {
+		h.queue(func() {
+			if gen == h.gen {
+				h.release()
+			}
+		})
+	})
+}
+
+func (h *keyHold) release() {
+	if h.loops == nil {
+		return
+	}
+	releaseLoops(h.loops, h.cmd)
+	h.loops = nil
+	h.gen++
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+
+	"jaudio/internal/slmock"
+)
+
+// startOverdubSim starts an engine with loop 1 playing, and momentary
+// overdubs until the test ends.
+func startOverdubSim(t *testing.T) *simEngine {
+	t.Helper()
+	sim := startSim(t, "127.0.0.1:0", 1)
+	sl = startClient(t, sim)
+	overdubMode = "momentary"
+	t.Cleanup(func() {
+		mu.Lock()
+		sl, overdubMode = nil, "latch"
+		mu.Unlock()
+	})
+	sim.Handle(hitMessage(0, "record"))
+	sim.Handle(hitMessage(0, "record"))
+	eventually(t, "loop 1 playing", func() bool { return sim.State(0) == slmock.StatePlaying })
+	return sim
+}
+
+// TestKeyHold tests that a held key overdubs while it repeats, and stops
+// once it is let go
+func TestKeyHold(t *testing.T) {
+	sim := startOverdubSim(t)
+	var queueMu sync.Mutex
+	h := keyHold{queue: func(f func()) {
+		queueMu.Lock()
+		defer queueMu.Unlock()
+		f()
+	}}
+	o := tcell.NewEventKey(tcell.KeyRune, 'o', tcell.ModNone)
+
+	queueMu.Lock()
+	h.start('o', "overdub", []int{0})
+	queueMu.Unlock()
+	eventually(t, "overdub", func() bool { return sim.State(0) == slmock.StateOverdub })
+	for range 10 {
+		time.Sleep(keyHoldRepeat / 2)
+		queueMu.Lock()
+		if !h.repeat(o) {
+			t.Error("repeat of the held key was not taken")
+		}
+		queueMu.Unlock()
+	}
+	if got := sim.State(0); got != slmock.StateOverdub {
+		t.Fatalf("state = %d while the key repeats, want overdub", got)
+	}
+	eventually(t, "overdub released", func() bool { return sim.State(0) == slmock.StatePlaying })
+
+	queueMu.Lock()
+	h.start('o', "overdub", []int{0})
+	queueMu.Unlock()
+	eventually(t, "overdub again", func() bool { return sim.State(0) == slmock.StateOverdub })
+	queueMu.Lock()
+	if h.repeat(tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone)) {
+		t.Error("another key was taken as a repeat")
+	}
+	queueMu.Unlock()
+	eventually(t, "released by another key", func() bool { return sim.State(0) == slmock.StatePlaying })
+}
+
+// TestMIDIHold tests that a momentary overdub pad lasts from note on to
+// note off
+func TestMIDIHold(t *testing.T) {
+	sim := startOverdubSim(t)
+	note := 36
+	routes, err := (&midiConfig{Device: "x", Bindings: []midiBinding{{Note: &note, Action: "overdub 1"}}}).routes(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newMIDIRouter(routes)
+	r.handle(midiMessage{Kind: midiNoteOn, Channel: 1, Data1: 36, Data2: 100})
+	eventually(t, "overdub", func() bool { return sim.State(0) == slmock.StateOverdub })
+	time.Sleep(50 * time.Millisecond)
+	if got := sim.State(0); got != slmock.StateOverdub {
+		t.Fatalf("state = %d while the pad is held, want overdub", got)
+	}
+	r.handle(midiMessage{Kind: midiNoteOff, Channel: 1, Data1: 36})
+	eventually(t, "overdub released", func() bool { return sim.State(0) == slmock.StatePlaying })
+}
@@ -0,0 +1,117 @@
+// pages.go
+// Tabbed pages: the loop mixer, the selected loop, globals, MIDI and the log.
+
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+const (
+	pageMixer = iota
+	pageLoop
+	pageGlobals
+	pageMIDI
+	pageLog
+)
+
+// pageNames are the tab labels, in English, switched with the number keys
+// 1–5.
+var pageNames = []string{"Mixer", "Loop", "Globals", "MIDI", "Log"}
+
+var (
+	currentPage int
+	// selectedLoop is the loop shown on the Loop page.
+	selectedLoop int
+)
+
+// pageTabsText draws the tab bar with the current page highlighted.
+func pageTabsText(current, loop int) string {
+	var b strings.Builder
+	for i, name := range pageNames {
+		name = tr(name)
+		if i == pageLoop {
+			name = loopLabel(loop)
+		}
+		if i == current && a11yMode {
+			name += " " + tr("(current)")
+		}
+		if i == current {
+			fmt.Fprintf(&b, "[black:green] %d %s [-:-]", i+1, name)
+		} else {
+			fmt.Fprintf(&b, " %d %s ", i+1, name)
+		}
+	}
+	return b.String()
+}
+
+// selectLoop moves the Loop page to loop i, within the loops the engine
+// has. The caller must hold mu.
+func selectLoop(i int) {
+	selectedLoop = min(max(i, 0), loopCount-1)
+}
+
+// globalsPageText lists the engine-wide controls, with ? after those the
+// engine has not confirmed, and connection settings. The caller must hold
+// mu.
+func globalsPageText() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, " %-18s %s:%d\n", tr("Engine"), oscHost, oscPort)
+	fmt.Fprintf(&b, " %-18s %d\n", tr("Loops"), loopCount)
+	for _, name := range slices.Sorted(maps.Keys(globals)) {
+		mark := ""
+		if unconfirmedSets[setKey{-2, name}] {
+			mark = " ?"
+		}
+		fmt.Fprintf(&b, " %-18s %g%s\n", name, globals[name], mark)
+	}
+	b.WriteString("\n")
+	if extMixer != nil {
+		c := extMixer.cfg
+		fmt.Fprintf(&b, " %-18s %s, %s:%d, %s\n", tr("Mixer"), c.Preset, c.Host, c.Port, c.Unit)
+	} else {
+		fmt.Fprintf(&b, " %-18s %s\n", tr("Mixer"), tr("none"))
+	}
+	fmt.Fprintf(&b, " %-18s %d/s\n", tr("Max send rate"), maxSendRate)
+	fmt.Fprintf(&b, " %-18s %s\n", tr("Level law"), trf("%s, max %g", lvlLaw, levelMax))
+	fmt.Fprintf(&b, " %-18s %s\n", tr("Meter range"), trf("%g to %g dB", meterMinDB, meterMaxDB))
+	fmt.Fprintf(&b, " %-18s %d ms\n", tr("Refresh"), refreshRate)
+	b.WriteString("\n")
+	for _, f := range []struct{ name, file string }{
+		{tr("Config"), configFile},
+		{tr("Scenes"), scenesFile},
+		{tr("Setlist"), setlistFile},
+	} {
+		if f.file == "" {
+			f.file = "–"
+		}
+		fmt.Fprintf(&b, " %-18s %s\n", f.name, f.file)
+	}
+	return b.String()
+}
+
+// midiPageText lists the MIDI input bindings and the last message
+// received, to help find a controller's note and CC numbers. The caller
+// must hold mu.
+func midiPageText(c *midiConfig) string {
+	if c == nil {
+		return " " + tr("No MIDI input. Add a midi section to the config file.") + "\n"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, " %-24s %s\n", tr("Device"), c.Device)
+	last := lastMIDI
+	if last == "" {
+		last = "–"
+	}
+	fmt.Fprintf(&b, " %-24s %s\n\n", tr("Last received"), last)
+	for _, bd := range c.Bindings {
+		fmt.Fprintf(&b, " %-24s %s\n", bd, bd.Action)
+	}
+	if len(c.Bindings) == 0 {
+		b.WriteString(" " + tr("No bindings.") + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+// TestPageTabsText tests the tab bar labels and highlight
+func TestPageTabsText(t *testing.T) {
+	want := " 1 Mixer [black:green] 2 Loop 3 [-:-] 3 Globals  4 MIDI  5 Log "
+	if got := pageTabsText(pageLoop, 2); got != want {
+		t.Errorf("pageTabsText = %q, want %q", got, want)
+	}
+}
+
+// TestSelectLoop tests that the selected loop stays within the engine's loops
+func TestSelectLoop(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+	defer func(n int) { loopCount, selectedLoop = n, 0 }(loopCount)
+	loopCount = 3
+	for _, tt := range []struct{ to, want int }{{1, 1}, {-1, 0}, {5, 2}} {
+		selectLoop(tt.to)
+		if selectedLoop != tt.want {
+			t.Errorf("selectLoop(%d) selected %d, want %d", tt.to, selectedLoop, tt.want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// palette.go
+// Ctrl+P command palette: every action, found by fuzzy search.
+
+package main
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const paletteMaxShown = 200
+
+// paletteAction is one entry of the command palette. Running it calls Run,
+// if set, then presses Keys, so actions with a key binding behave exactly
+// like the binding. Loop commands name theirs in Hit, for the preview.
+type paletteAction struct {
+	Name string
+	Run  func()
+	Keys []*tcell.EventKey
+	Hit  string
+	Loop int
+}
+
+// keysLabel shows an action's key binding, e.g. "d 2".
+func (a paletteAction) keysLabel() string {
+	labels := make([]string, len(a.Keys))
+	for i, k := range a.Keys {
+		if k.Key() == tcell.KeyRune {
+			labels[i] = string(k.Rune())
+		} else {
+			labels[i] = tcell.KeyNames[k.Key()]
+		}
+	}
+	return strings.Join(labels, " ")
+}
+
+func runeKey(r rune) *tcell.EventKey { return tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone) }
+
+func specialKey(k tcell.Key) *tcell.EventKey { return tcell.NewEventKey(k, 0, tcell.ModNone) }
+
+// paletteActions lists every action for the current loops, scenes, songs,
+// macros and profiles. The caller must hold mu.
+func paletteActions() []paletteAction {
+	bound := func(name string, keys ...*tcell.EventKey) paletteAction {
+		return paletteAction{Name: name, Keys: keys}
+	}
+	var out []paletteAction
+	for i, name := range pageNames {
+		out = append(out, bound(trf("Go to the %s page", tr(name)), runeKey(rune('1'+i))))
+	}
+	out = append(out,
+		bound(tr("Toggle fine Level drags"), runeKey('f')),
+		bound(tr("Toggle sparkline meters"), runeKey('s')),
+		bound(tr("Toggle the history pane"), runeKey('h')),
+		bound(tr("Toggle the scene pane"), runeKey('p')),
+		bound(tr("Toggle the song navigator"), runeKey('n')),
+		bound(tr("Toggle the crossfader"), runeKey('x')),
+		bound(tr("Toggle the master fader"), runeKey('V')),
+		bound(tr("Toggle follow mode"), runeKey('F')),
+		bound(tr("Mute or unmute the master fader"), runeKey('M')),
+		bound(tr("Toggle the beat indicator"), runeKey('m')),
+		bound(tr("Toggle the log pane"), specialKey(tcell.KeyF12)),
+		bound(tr("Save a scene"), runeKey('c')),
+		bound(tr("Load a file into the selected loop"), runeKey('L')),
+		bound(tr("Bounce every loop to a folder"), runeKey('B')),
+		bound(tr("Undo the last Level or control change"), specialKey(tcell.KeyCtrlZ)),
+		bound(tr("Redo the Level or control change undone"), specialKey(tcell.KeyCtrlY)),
+		bound(tr("Panic: mute all loops"), runeKey('!'), runeKey('y')),
+	)
+	if clickControl != "" {
+		out = append(out, bound(tr("Toggle the click"), runeKey('k')))
+	}
+	if devFlag != nil && *devFlag {
+		out = append(out, bound(tr("Open the OSC inspector"), specialKey(tcell.KeyF10)))
+	}
+	for _, name := range slices.Sorted(maps.Keys(appConfig.Macros)) {
+		if name == panicMacro {
+			continue
+		}
+		steps, _, _ := parseActions(appConfig.Macros[name], appConfig.Macros)
+		out = append(out, paletteAction{Name: trf("Macro: %s", name), Run: func() { runActions(steps, 1) }})
+	}
+	out = append(out, profileActions()...)
+	for i, s := range scenes {
+		out = append(out, bound(trf("Recall scene %d: %s", i+1, s.Name), specialKey(tcell.KeyF1+tcell.Key(i))))
+	}
+	if len(songs.Songs) > 0 {
+		out = append(out,
+			bound(tr("Next song"), specialKey(tcell.KeyPgDn)),
+			bound(tr("Previous song"), specialKey(tcell.KeyPgUp)))
+	}
+	for i, sg := range songs.Songs {
+		out = append(out, paletteAction{Name: trf("Song %d: %s", i+1, sg.Name), Run: func() { switchSong(i) }})
+	}
+	for i := 0; i < loopCount; i++ {
+		loop := loopLabel(i) + ": "
+		for _, cmd := range hitCommands {
+			out = append(out, paletteAction{Name: loop + tr(strings.ReplaceAll(cmd, "_", " ")), Run: func() { hitLoop(i, cmd) }, Hit: cmd, Loop: i})
+		}
+		if i < 9 {
+			n := runeKey(rune('1' + i))
+			out = append(out,
+				bound(loop+tr("fade out"), runeKey('d'), n),
+				bound(loop+tr("mark as the copy source"), runeKey('y'), n),
+				bound(loop+tr("paste the copied loop"), runeKey('v'), n),
+				bound(loop+tr("save to a file"), runeKey('w'), n),
+				bound(loop+tr("type the Level in dB"), runeKey('='), n))
+		}
+		selectThis := func() {
+			mu.Lock()
+			selectLoop(i)
+			mu.Unlock()
+		}
+		out = append(out, paletteAction{Name: loop + tr("show on the Loop page"), Run: selectThis, Keys: []*tcell.EventKey{runeKey('2')}})
+	}
+	return out
+}
+
+// fuzzyScore matches query against name: every query rune must appear in
+// name in order, ignoring case and spaces. Runs of consecutive matches and
+// matches at the start of a word score higher; the best-scoring place to
+// start matching wins. ok is false if there is no match.
+func fuzzyScore(query, name string) (score int, ok bool) {
+	q := []rune(strings.ToLower(strings.ReplaceAll(query, " ", "")))
+	n := []rune(strings.ToLower(name))
+	if len(q) == 0 {
+		return 0, true
+	}
+	for start := range n {
+		if n[start] != q[0] {
+			continue
+		}
+		if s, ok := fuzzyScoreFrom(q, n, start); ok {
+			score = max(score, s)
+		}
+	}
+	return score, score > 0
+}
+
+// fuzzyScoreFrom matches q greedily against n from n[start].
+func fuzzyScoreFrom(q, n []rune, start int) (score int, ok bool) {
+	qi, run := 0, 0
+	for ni := start; ni < len(n) && qi < len(q); ni++ {
+		if n[ni] != q[qi] {
+			run = 0
+			continue
+		}
+		score += 1 + 2*run
+		run++
+		if ni == 0 || !unicode.IsLetter(n[ni-1]) && !unicode.IsDigit(n[ni-1]) {
+			score += 3
+		}
+		qi++
+	}
+	return score, qi == len(q)
+}
+
+// filterPalette returns the actions matching query, best first, keeping
+// the list order among equal scores.
+func filterPalette(actions []paletteAction, query string) []paletteAction {
+	type scored struct {
+		a     paletteAction
+		score int
+	}
+	var matches []scored
+	for _, a := range actions {
+		if s, ok := fuzzyScore(query, a.Name); ok {
+			matches = append(matches, scored{a, s})
+		}
+	}
+	slices.SortStableFunc(matches, func(x, y scored) int { return y.score - x.score })
+	out := make([]paletteAction, 0, min(len(matches), paletteMaxShown))
+	for _, m := range matches[:min(len(matches), paletteMaxShown)] {
+		out = append(out, m.a)
+	}
+	return out
+}
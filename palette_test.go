@@ -0,0 +1,80 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestFuzzyScore tests subsequence matching and that word starts score higher
+func TestFuzzyScore(t *testing.T) {
+	for _, tt := range []struct {
+		query, name string
+		ok          bool
+	}{
+		{"", "Next song", true},
+		{"nxs", "Next song", true},
+		{"NEXT SONG", "Next song", true},
+		{"snx", "Next song", false},
+		{"l2 rec", "Loop 2: record", true},
+	} {
+		if _, ok := fuzzyScore(tt.query, tt.name); ok != tt.ok {
+			t.Errorf("fuzzyScore(%q, %q) ok = %v, want %v", tt.query, tt.name, ok, tt.ok)
+		}
+	}
+	start, _ := fuzzyScore("rec", "Loop 1: record")
+	inside, _ := fuzzyScore("rec", "Loop 1: trigger record")
+	mid, _ := fuzzyScore("rec", "Loop 1: undo redo check")
+	if start < inside || inside <= mid {
+		t.Errorf("scores: word start %d, later word %d, scattered %d", start, inside, mid)
+	}
+}
+
+// TestFilterPalette tests ordering by score, with list order kept on ties
+func TestFilterPalette(t *testing.T) {
+	actions := []paletteAction{
+		{Name: "Toggle the scene pane"},
+		{Name: "Save a scene"},
+		{Name: "Recall scene 1: intro"},
+		{Name: "Next song"},
+	}
+	var got []string
+	for _, a := range filterPalette(actions, "scene") {
+		got = append(got, a.Name)
+	}
+	want := []string{"Toggle the scene pane", "Save a scene", "Recall scene 1: intro"}
+	if !slices.Equal(got, want) {
+		t.Errorf("filterPalette = %q, want %q", got, want)
+	}
+	if n := len(filterPalette(actions, "")); n != len(actions) {
+		t.Errorf("empty query matched %d actions, want %d", n, len(actions))
+	}
+}
+
+// TestPaletteActions tests that per-loop commands and key bindings are listed
+func TestPaletteActions(t *testing.T) {
+	mu.Lock()
+	defer mu.Unlock()
+	defer func(n int, sc []scene, c config) { loopCount, scenes, appConfig = n, sc, c }(loopCount, scenes, appConfig)
+	loopCount, scenes = 2, []scene{{Name: "intro"}}
+	appConfig.Macros = map[string]string{"drop": "mute 1"}
+
+	keys := map[string]string{}
+	for _, a := range paletteActions() {
+		keys[a.Name] = a.keysLabel()
+	}
+	for name, want := range map[string]string{
+		"Go to the Log page":            "5",
+		"Loop 2: fade out":              "d 2",
+		"Recall scene 1: intro":         "F1",
+		"Loop 2: record":                "",
+		"Loop 1: show on the Loop page": "2",
+		"Macro: drop":                   "",
+	} {
+		if got, ok := keys[name]; !ok || got != want {
+			t.Errorf("%q: keys %q (listed %v), want %q", name, got, ok, want)
+		}
+	}
+	if _, ok := keys["Loop 3: record"]; ok {
+		t.Error("listed a command for a loop the engine does not have")
+	}
+}
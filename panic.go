@@ -0,0 +1,81 @@
+// panic.go
+// The panic action: stop the records in progress and mute every loop, run
+// as the panic macro.
+
+package main
+
+import "jaudio/internal/slstate"
+
+// panicMacro is run by the panic key. The config file may define its own,
+// e.g. adding stop_ramps.
+const (
+	panicMacro        = "panic"
+	defaultPanicMacro = "cancel_record all; mute_on all"
+)
+
+// addPanicMacro adds the default panic macro unless macros has one.
+func addPanicMacro(macros map[string]string) map[string]string {
+	if _, ok := macros[panicMacro]; ok {
+		return macros
+	}
+	if macros == nil {
+		macros = make(map[string]string)
+	}
+	macros[panicMacro] = defaultPanicMacro
+	return macros
+}
+
+// cancelRecords stops count-ins and undoes the record on each of loops
+// that is recording or waiting to start or stop. Without loops it acts on the loop on the Loop
+// page; -1 is every loop.
+func cancelRecords(loops []int) {
+	mu.Lock()
+	if loops == nil {
+		loops = []int{selectedLoop}
+	}
+	if len(loops) == 1 && loops[0] == -1 {
+		loops = make([]int, loopCount)
+		for i := range loops {
+			loops[i] = i
+		}
+	}
+	var recording []int
+	for _, i := range loops {
+		if i >= loopCount {
+			continue
+		}
+		ls := getLoopState(i)
+		if ls.countIn != nil {
+			ls.countIn = nil
+			tuiLog.Info("count-in cancelled", "loop", i+1)
+		}
+		if ls.State.In(slstate.WaitStart, slstate.Record, slstate.WaitStop) {
+			recording = append(recording, i)
+		}
+	}
+	mu.Unlock()
+	for _, i := range recording {
+		tuiLog.Info("record cancelled", "loop", i+1)
+		sl.Hit(i, "undo")
+	}
+}
+
+// stopRamps stops the running fades and scene ramps where they are.
+func stopRamps() {
+	mu.Lock()
+	defer mu.Unlock()
+	clear(fades)
+	sceneRampSeq++
+}
+
+// runPanic runs the panic macro.
+func runPanic() {
+	body := appConfig.Macros[panicMacro]
+	tuiLog.Warn("panic", "actions", body)
+	steps, _, err := parseActions(body, appConfig.Macros)
+	if err != nil {
+		tuiLog.Error("panic macro", "err", err)
+		return
+	}
+	runActions(steps, 1)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"jaudio/internal/slmock"
+)
+
+// TestAddPanicMacro tests the default panic macro and one from the config
+// file
+func TestAddPanicMacro(t *testing.T) {
+	cfg, err := parseConfig(nil)
+	if err != nil || cfg.Macros[panicMacro] != defaultPanicMacro {
+		t.Errorf("empty config: panic = %q, %v; want %q", cfg.Macros[panicMacro], err, defaultPanicMacro)
+	}
+	cfg, err = parseConfig([]byte("macros: {panic: mute_on all; stop_ramps, drop: macro panic}"))
+	if err != nil || cfg.Macros[panicMacro] != "mute_on all; stop_ramps" {
+		t.Errorf("own panic = %q, %v", cfg.Macros[panicMacro], err)
+	}
+	for _, bad := range []string{"cancel_record 0", "cancel_record x", "stop_ramps 1"} {
+		if _, _, err := parseActions(bad, nil); err == nil {
+			t.Errorf("parseActions(%q) succeeded", bad)
+		}
+	}
+}
+
+// TestRunPanic tests that the panic mutes playing loops and throws away
+// records in progress
+func TestRunPanic(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 3)
+	sl = startClient(t, sim)
+	defer func(macros map[string]string) { sl, appConfig.Macros = nil, macros }(appConfig.Macros)
+	appConfig.Macros = addPanicMacro(nil)
+	eventually(t, "three loops", func() bool { return loopCount == 3 })
+
+	sim.Handle(hitMessage(2, "record"))
+	time.Sleep(50 * time.Millisecond)
+	sim.Handle(hitMessage(2, "record"))
+	sim.Handle(hitMessage(0, "record"))
+	sim.Handle(hitMessage(1, "record"))
+	eventually(t, "loops 1 and 2 recording", func() bool {
+		return getLoopState(0).State == slmock.StateRecording && getLoopState(1).State == slmock.StateRecording
+	})
+
+	runPanic()
+	empty := func(i int) bool { return sim.State(i) == slmock.StateOff || sim.State(i) == slmock.StateOffMuted }
+	eventually(t, "records thrown away", func() bool { return empty(0) && empty(1) })
+	eventually(t, "loop 3 muted", func() bool { return sim.State(2) == slmock.StateMuted })
+}
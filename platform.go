@@ -0,0 +1,33 @@
+// platform.go
+// Operating system specific parts of the relaunch and log plumbing.
+
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// platform is what differs between operating systems when relaunching the
+// TUI in its own terminal window, and when running the config file's hooks.
+// Implementations live in platform_*.go.
+type platform interface {
+	// terminalCommand returns a command that runs self with args in a new
+	// terminal window, or nil if no supported terminal is available. wait
+	// reports whether the command lasts as long as the TUI does.
+	terminalCommand(self string, args []string) (cmd *exec.Cmd, wait bool)
+	// nudgeResize makes a relaunched TUI re-read its window size.
+	nudgeResize(p *os.Process)
+	// parentConsole returns the launching terminal's stderr, or nil.
+	parentConsole() io.Writer
+	// shellCommand returns a command that runs line with the system shell,
+	// killed when ctx is done.
+	shellCommand(ctx context.Context, line string) *exec.Cmd
+	// restart replaces the running program with self run with args, in
+	// the same terminal. It returns only if that fails.
+	restart(self string, args []string) error
+}
+
+var host platform = hostPlatform{}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// hostPlatform relaunches in a new Terminal.app window on macOS.
+type hostPlatform struct{}
+
+// terminalCommand asks Terminal.app to run the command. osascript returns
+// as soon as the window is open, so the launcher cannot wait for the TUI.
+func (hostPlatform) terminalCommand(self string, args []string) (*exec.Cmd, bool) {
+	if _, err := exec.LookPath("osascript"); err != nil {
+		return nil, false
+	}
+	line := "SOOPERGUI_XTERM=1 exec " + shellQuote(self)
+	for _, a := range args {
+		line += " " + shellQuote(a)
+	}
+	script := `tell application "Terminal" to do script "` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(line) + `"`
+	return exec.Command("osascript", "-e", script), false
+}
+
+// Terminal.app delivers the final size before the program starts.
+func (hostPlatform) nudgeResize(*os.Process) {}
+
+// The new window belongs to a different terminal, so there is nothing to
+// mirror logs to.
+func (hostPlatform) parentConsole() io.Writer { return nil }
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func (hostPlatform) shellCommand(ctx context.Context, line string) *exec.Cmd {
+	return exec.CommandContext(ctx, "sh", "-c", line)
+}
+
+func (hostPlatform) restart(self string, args []string) error {
+	return syscall.Exec(self, append([]string{self}, args...), os.Environ())
+}
@@ -0,0 +1,48 @@
+//go:build unix && !darwin
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// hostPlatform relaunches in st on Linux and the BSDs.
+type hostPlatform struct{}
+
+func (hostPlatform) terminalCommand(self string, args []string) (*exec.Cmd, bool) {
+	if _, err := exec.LookPath("st"); err != nil {
+		return nil, false
+	}
+	cmd := exec.Command("st", "-f", "monospace:size=10", "-c", "sooperGUI", "-e", self)
+	cmd.Args = append(cmd.Args, args...)
+	return cmd, true
+}
+
+// nudgeResize works around st sometimes starting the child before its
+// window has its final size.
+func (hostPlatform) nudgeResize(p *os.Process) {
+	_ = p.Signal(syscall.SIGWINCH)
+}
+
+// parentConsole opens the parent's stderr through /proc, which only exists
+// on Linux.
+func (hostPlatform) parentConsole() io.Writer {
+	f, err := os.OpenFile(fmt.Sprintf("/proc/%d/fd/2", os.Getppid()), os.O_WRONLY, 0)
+	if err != nil {
+		return nil
+	}
+	return f
+}
+
+func (hostPlatform) shellCommand(ctx context.Context, line string) *exec.Cmd {
+	return exec.CommandContext(ctx, "sh", "-c", line)
+}
+
+func (hostPlatform) restart(self string, args []string) error {
+	return syscall.Exec(self, append([]string{self}, args...), os.Environ())
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// hostPlatform relaunches in a new console window on Windows.
+type hostPlatform struct{}
+
+// terminalCommand uses start, which opens a new console window for a
+// console program and returns immediately. The window title comes first.
+func (hostPlatform) terminalCommand(self string, args []string) (*exec.Cmd, bool) {
+	cmd := exec.Command("cmd", "/c", "start", "sooperGUI", self)
+	cmd.Args = append(cmd.Args, args...)
+	return cmd, false
+}
+
+// Console windows report resizes through the console API, no signal needed.
+func (hostPlatform) nudgeResize(*os.Process) {}
+
+// A new console cannot write to the parent's; logs stay in the log file.
+func (hostPlatform) parentConsole() io.Writer { return nil }
+
+func (hostPlatform) shellCommand(ctx context.Context, line string) *exec.Cmd {
+	return exec.CommandContext(ctx, "cmd", "/C", line)
+}
+
+// restart runs self in the same console and exits with its status, as
+// Windows cannot replace a running program.
+func (hostPlatform) restart(self string, args []string) error {
+	cmd := exec.Command(self, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.ExitCode())
+		}
+		return err
+	}
+	os.Exit(0)
+	return nil
+}
@@ -0,0 +1,215 @@
+// preview.go
+// Command preview: while a loop command is highlighted in the command
+// palette, the status bar says what the engine will do with it given the
+// loop's quantize and sync settings, such as "L1 overdub will start at the
+// next cycle in 2.3 s", and once a quantized command is sent it counts down
+// to when it takes effect. Both are worked out from the cached controls and
+// tempo, so they cost no round trip to the engine.
+
+package main
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"jaudio/internal/slstate"
+)
+
+// SooperLooper's quantize values.
+const (
+	quantizeOff = iota
+	quantizeCycle
+	quantize8th
+	quantizeLoop
+)
+
+// previewControls are the controls a preview needs besides the auto update
+// ones, kept up to date for every loop with --preview.
+var previewControls = []string{"quantize", "sync", "mute_quantized", "overdub_quantized", "replace_quantized"}
+
+// quantizedBy is the control that makes each command wait for a quantize
+// boundary when set. Commands not listed take effect at once.
+var quantizedBy = map[string]string{
+	"record":     "sync",
+	"overdub":    "overdub_quantized",
+	"multiply":   "quantize",
+	"insert":     "quantize",
+	"trigger":    "quantize",
+	"oneshot":    "quantize",
+	"reverse":    "quantize",
+	"replace":    "replace_quantized",
+	"substitute": "replace_quantized",
+	"mute":       "mute_quantized",
+	"mute_on":    "mute_quantized",
+	"mute_off":   "mute_quantized",
+}
+
+// endsState are the states commands end when hit in them.
+var endsState = map[string]slstate.State{
+	"record":     slstate.Record,
+	"overdub":    slstate.Overdub,
+	"multiply":   slstate.Multiply,
+	"insert":     slstate.Insert,
+	"replace":    slstate.Replace,
+	"substitute": slstate.Substitute,
+	"mute":       slstate.Mute,
+}
+
+// commandWait is when a loop command takes effect: at due, on the next
+// boundary (quantizeCycle, quantize8th or quantizeLoop), or at once with
+// quantizeOff.
+type commandWait struct {
+	cmd      string
+	stop     bool
+	boundary int
+	due      time.Time
+}
+
+var (
+	// previewCommands (--preview) shows command previews and countdowns.
+	previewCommands = true
+	// commandWaits are the quantized commands sent and not yet due, by
+	// loop. They are guarded by mu.
+	commandWaits = map[int]commandWait{}
+)
+
+// untilBoundary is the time from pos to the next multiple of unit, all in
+// seconds.
+func untilBoundary(pos, unit float64) time.Duration {
+	return time.Duration((unit - math.Mod(max(pos, 0), unit)) * float64(time.Second))
+}
+
+// commandTiming is when loop i will carry out cmd sent at now. ok is false
+// when that cannot be told: the loop is empty, or a control or the tempo it
+// needs is not known yet. The caller must hold mu.
+func commandTiming(i int, cmd string, now time.Time) (w commandWait, ok bool) {
+	ls := getLoopState(i)
+	if !ls.haveState {
+		return w, false
+	}
+	w = commandWait{cmd: cmd, due: now}
+	if s, ok := endsState[cmd]; ok && ls.State == s {
+		w.stop = true
+	}
+	gate, quantizable := quantizedBy[cmd]
+	if !quantizable {
+		return w, true
+	}
+	on, ok := ls.controls[gate]
+	if !ok {
+		return w, false
+	}
+	q, ok := ls.controls["quantize"]
+	if !ok {
+		return w, false
+	}
+	w.boundary = int(q)
+	if cmd == "record" {
+		// Record waits for the sync source, whose cycle the metronome
+		// counts, and for its eighth with quantize 8th.
+		if on == 0 {
+			w.boundary = quantizeOff
+			return w, true
+		}
+		tempo, eighths := float64(globals["tempo"]), float64(globals["eighth_per_cycle"])
+		if tempo <= 0 || eighths <= 0 {
+			return w, false
+		}
+		unit := 30 / tempo
+		if w.boundary != quantize8th {
+			w.boundary, unit = quantizeCycle, unit*eighths
+		}
+		w.due = now.Add(untilBoundary(metronomePos(now), unit))
+		return w, true
+	}
+	length := float64(ls.controls["loop_len"])
+	if length <= 0 {
+		return w, false
+	}
+	if on == 0 || w.boundary < quantizeCycle || w.boundary > quantizeLoop {
+		w.boundary = quantizeOff
+		return w, true
+	}
+	unit := float64(ls.controls["cycle_len"])
+	if unit <= 0 {
+		unit = length
+	}
+	switch w.boundary {
+	case quantize8th:
+		if eighths := float64(globals["eighth_per_cycle"]); eighths > 0 {
+			unit /= eighths
+		}
+	case quantizeLoop:
+		unit = length
+	}
+	w.due = now.Add(untilBoundary(float64(ls.LoopPos), unit))
+	return w, true
+}
+
+// text says what loop i does with the command, counting down from now.
+func (w commandWait) text(i int, now time.Time) string {
+	name := tr(strings.ReplaceAll(w.cmd, "_", " "))
+	var at string
+	switch w.boundary {
+	case quantizeOff:
+		if w.stop {
+			return trf("L%d %s will stop now", i+1, name)
+		}
+		return trf("L%d %s will start now", i+1, name)
+	case quantizeCycle:
+		at = tr("at the next cycle")
+	case quantize8th:
+		at = tr("at the next 8th")
+	case quantizeLoop:
+		at = tr("at the loop end")
+	}
+	in := max(w.due.Sub(now).Seconds(), 0)
+	if w.stop {
+		return trf("L%d %s will stop %s in %.1f s", i+1, name, at, in)
+	}
+	return trf("L%d %s will start %s in %.1f s", i+1, name, at, in)
+}
+
+// preview is the status bar preview of a palette action at now, for loop
+// commands with --preview. The caller must hold mu.
+func (a paletteAction) preview(now time.Time) string {
+	if !previewCommands || a.Hit == "" {
+		return ""
+	}
+	w, ok := commandTiming(a.Loop, a.Hit, now)
+	if !ok {
+		return ""
+	}
+	return w.text(a.Loop, now)
+}
+
+// awaitCommand notes cmd sent to loop i at now, so the status bar counts
+// down to its quantize boundary. The caller must hold mu.
+func awaitCommand(i int, cmd string, now time.Time) {
+	if !previewCommands || i < 0 {
+		return
+	}
+	if w, ok := commandTiming(i, cmd, now); ok && w.boundary != quantizeOff {
+		commandWaits[i] = w
+	} else {
+		delete(commandWaits, i)
+	}
+}
+
+// waitStatus is the status bar countdown of the commands waiting at now.
+// The caller must hold mu.
+func waitStatus(now time.Time) string {
+	var out []string
+	for _, i := range slices.Sorted(maps.Keys(commandWaits)) {
+		w := commandWaits[i]
+		if !now.Before(w.due) {
+			delete(commandWaits, i)
+			continue
+		}
+		out = append(out, w.text(i, now))
+	}
+	return strings.Join(out, "  ")
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"jaudio/internal/slstate"
+)
+
+// TestCommandTiming tests when commands take effect under the loop's
+// quantize and sync settings, at 120 BPM with 8 eighths a cycle
+func TestCommandTiming(t *testing.T) {
+	defer func(g map[string]float32, l map[int]*LoopState) {
+		globals, loopStates = g, l
+	}(globals, loopStates)
+	globals = map[string]float32{"tempo": 120, "eighth_per_cycle": 8}
+
+	tests := []struct {
+		name     string
+		state    slstate.State
+		controls map[string]float32
+		cmd      string
+		ok       bool
+		stop     bool
+		boundary int
+		wait     time.Duration
+	}{
+		{"overdub at the cycle", slstate.Play, map[string]float32{"overdub_quantized": 1, "quantize": 1}, "overdub", true, false, quantizeCycle, 500 * time.Millisecond},
+		{"overdub at the 8th", slstate.Play, map[string]float32{"overdub_quantized": 1, "quantize": 2}, "overdub", true, false, quantize8th, 250 * time.Millisecond},
+		{"overdub at the loop end", slstate.Play, map[string]float32{"overdub_quantized": 1, "quantize": 3}, "overdub", true, false, quantizeLoop, 6500 * time.Millisecond},
+		{"overdub not quantized", slstate.Play, map[string]float32{"overdub_quantized": 0, "quantize": 1}, "overdub", true, false, quantizeOff, 0},
+		{"overdub ended", slstate.Overdub, map[string]float32{"overdub_quantized": 1, "quantize": 1}, "overdub", true, true, quantizeCycle, 500 * time.Millisecond},
+		{"multiply with quantize off", slstate.Play, map[string]float32{"quantize": 0}, "multiply", true, false, quantizeOff, 0},
+		{"pause", slstate.Play, nil, "pause", true, false, quantizeOff, 0},
+		{"record synced", slstate.Play, map[string]float32{"sync": 1, "quantize": 3}, "record", true, false, quantizeCycle, 500 * time.Millisecond},
+		{"record synced to the 8th", slstate.Play, map[string]float32{"sync": 1, "quantize": 2}, "record", true, false, quantize8th, 250 * time.Millisecond},
+		{"quantize unknown", slstate.Play, map[string]float32{"overdub_quantized": 1}, "overdub", false, false, 0, 0},
+	}
+	now := time.Now()
+	for _, tt := range tests {
+		ls := &LoopState{haveState: true, State: tt.state, LoopPos: 1.5, controls: map[string]float32{"loop_len": 8, "cycle_len": 2}}
+		for k, v := range tt.controls {
+			ls.controls[k] = v
+		}
+		loopStates = map[int]*LoopState{0: ls}
+		w, ok := commandTiming(0, tt.cmd, now)
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+		}
+		if !ok {
+			continue
+		}
+		if d := w.due.Sub(now) - tt.wait; w.stop != tt.stop || w.boundary != tt.boundary || d < -time.Millisecond || d > time.Millisecond {
+			t.Errorf("%s: stop %v at %d in %v, want stop %v at %d in %v", tt.name, w.stop, w.boundary, w.due.Sub(now), tt.stop, tt.boundary, tt.wait)
+		}
+	}
+}
+
+// TestWaitStatus tests the countdown of a quantized command once sent, and
+// that it goes when due
+func TestWaitStatus(t *testing.T) {
+	defer func(g map[string]float32, l map[int]*LoopState) {
+		globals, loopStates, commandWaits = g, l, map[int]commandWait{}
+	}(globals, loopStates)
+	globals = map[string]float32{"tempo": 120, "eighth_per_cycle": 8}
+	loopStates = map[int]*LoopState{1: {haveState: true, State: slstate.Play, LoopPos: 1.5,
+		controls: map[string]float32{"loop_len": 8, "cycle_len": 2, "quantize": 1, "overdub_quantized": 1}}}
+
+	now := time.Now()
+	awaitCommand(1, "overdub", now)
+	if got, want := waitStatus(now.Add(200*time.Millisecond)), "L2 overdub will start at the next cycle in 0.3 s"; got != want {
+		t.Errorf("waitStatus = %q, want %q", got, want)
+	}
+	a := paletteAction{Hit: "pause", Loop: 1}
+	if got := a.preview(now); !strings.HasSuffix(got, "pause will start now") {
+		t.Errorf("pause preview = %q", got)
+	}
+	if got := waitStatus(now.Add(time.Second)); got != "" || len(commandWaits) != 0 {
+		t.Errorf("waitStatus after the cycle = %q", got)
+	}
+}
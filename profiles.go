@@ -0,0 +1,171 @@
+// profiles.go
+// Named profiles: the config file's profiles section names setups, such as
+// studio, live and rehearsal, each with its own engine, panes shown at
+// start and macros. --profile picks one at startup, and the command palette
+// switches to another by restarting sooperGUI with it.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// namedProfile is one of the config file's profiles:
+//
+//	profiles:
+//	  live:
+//	    engine: {host: 192.168.1.20, port: 9951}
+//	    panes: [scenes, master]
+//	    macros: {drop: "mute 1; mute 2"}
+type namedProfile struct {
+	// Engine is where the engine is reached, over --osc-host and
+	// --osc-port unless those are given.
+	Engine *profileEngine `yaml:"engine"`
+	// Panes are shown at start, by the names sessions use.
+	Panes []string `yaml:"panes"`
+	// Macros add to the config file's, replacing those of the same name.
+	Macros map[string]string `yaml:"macros"`
+}
+
+// profileEngine is the engine address of a profile or of the config file.
+// Left out, the host or port is that of the flags.
+type profileEngine struct {
+	Host string `yaml:"host,omitempty"`
+	Port int    `yaml:"port,omitempty"`
+}
+
+func (e *profileEngine) validate() error {
+	if e != nil && (e.Port < 0 || e.Port > 65535) {
+		return fmt.Errorf("port %d out of range", e.Port)
+	}
+	return nil
+}
+
+var (
+	// profileName (--profile) is the profile in use, if any.
+	profileName = ""
+	// nextProfile is the profile to restart with once the TUI has quit.
+	// Owned by the TUI goroutine until then.
+	nextProfile = ""
+)
+
+func (p namedProfile) validate(macros map[string]string) error {
+	if err := p.Engine.validate(); err != nil {
+		return fmt.Errorf("engine: %w", err)
+	}
+	for _, name := range p.Panes {
+		if !isSessionPane(name) {
+			return fmt.Errorf("panes: unknown pane %q", name)
+		}
+	}
+	macros = mergeMacros(macros, p.Macros)
+	for _, name := range slices.Sorted(maps.Keys(p.Macros)) {
+		if _, _, err := parseActions(p.Macros[name], macros); err != nil {
+			return fmt.Errorf("macros: %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func isSessionPane(name string) bool {
+	for _, p := range sessionPanes {
+		if p.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeMacros returns base with over added, without changing either.
+func mergeMacros(base, over map[string]string) map[string]string {
+	out := maps.Clone(base)
+	if out == nil {
+		out = make(map[string]string)
+	}
+	maps.Copy(out, over)
+	return out
+}
+
+// useProfile adds the macros of the profile called name to cfg's and
+// returns it. No name is no profile.
+func (cfg *config) useProfile(name string) (namedProfile, error) {
+	if name == "" {
+		return namedProfile{}, nil
+	}
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		if len(cfg.Profiles) == 0 {
+			return namedProfile{}, fmt.Errorf("no profile %q: the config file has no profiles", name)
+		}
+		return namedProfile{}, fmt.Errorf("no profile %q; the config file has %s", name, strings.Join(slices.Sorted(maps.Keys(cfg.Profiles)), ", "))
+	}
+	cfg.Macros = mergeMacros(cfg.Macros, p.Macros)
+	return p, nil
+}
+
+// useEngine points the engine connection at e, except where --osc-host or
+// --osc-port was given to fs.
+func useEngine(e *profileEngine, fs *flag.FlagSet) {
+	if e == nil {
+		return
+	}
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	if e.Host != "" && !given["osc-host"] {
+		oscHost = e.Host
+	}
+	if e.Port != 0 && !given["osc-port"] {
+		oscPort = e.Port
+	}
+}
+
+// switchProfile quits the TUI, to be started again with the profile called
+// name. Called on the TUI goroutine.
+func switchProfile(name string) {
+	tuiLog.Info("switching profile", "from", profileName, "to", name)
+	nextProfile = name
+	services.cancel()
+}
+
+// profileActions are the palette's actions for switching to the profiles
+// not in use. The caller must hold mu.
+func profileActions() []paletteAction {
+	var out []paletteAction
+	for _, name := range slices.Sorted(maps.Keys(appConfig.Profiles)) {
+		if name != profileName {
+			out = append(out, paletteAction{Name: trf("Switch to the %s profile", name), Run: func() { switchProfile(name) }})
+		}
+	}
+	return out
+}
+
+// restartWithProfile runs sooperGUI again with the same flags, but the
+// profile called name. It returns only if that fails.
+func restartWithProfile(name string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	return host.restart(self, profileArgs(os.Args[1:], name))
+}
+
+// profileArgs is args with --profile set to name.
+func profileArgs(args []string, name string) []string {
+	out := []string{"--profile", name}
+	for i := 0; i < len(args); i++ {
+		switch a := strings.TrimLeft(args[i], "-"); {
+		case args[i] == "--":
+			return append(out, args[i:]...)
+		case a == "profile" && args[i] != a:
+			i++
+		case !strings.HasPrefix(a, "profile=") || args[i] == a:
+			out = append(out, args[i])
+		}
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestUseProfile tests profile validation, and that a profile's macros add
+// to the config file's
+func TestUseProfile(t *testing.T) {
+	cfg, err := parseConfig([]byte(`
+macros: {drop: "mute 1", fill: "mute 2"}
+profiles:
+  live: {engine: {port: 9000}, panes: [scenes], macros: {fill: "mute 3", outro: "macro drop"}}
+  studio: {}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := cfg.useProfile("live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Engine.Port != 9000 || !slices.Equal(p.Panes, []string{"scenes"}) {
+		t.Errorf("profile = %+v", p)
+	}
+	if cfg.Macros["drop"] != "mute 1" || cfg.Macros["fill"] != "mute 3" || cfg.Macros["outro"] != "macro drop" {
+		t.Errorf("macros = %v", cfg.Macros)
+	}
+	if _, err := cfg.useProfile("rehearsal"); err == nil || !strings.Contains(err.Error(), "live, studio") {
+		t.Errorf("unknown profile: %v, want the profiles listed", err)
+	}
+	if p, err := cfg.useProfile(""); err != nil || p.Engine != nil {
+		t.Errorf("no profile = %+v, %v", p, err)
+	}
+
+	for _, bad := range []string{
+		"profiles: {live: {engine: {port: 70000}}}",
+		"profiles: {live: {panes: [mixer]}}",
+		"profiles: {live: {macros: {a: jump}}}",
+		"profiles: {live: {macros: {a: macro b}}, studio: {macros: {b: mute 1}}}",
+	} {
+		if _, err := parseConfig([]byte(bad)); err == nil {
+			t.Errorf("%s: no error", bad)
+		}
+	}
+}
+
+// TestProfileArgs tests that a restart keeps the flags but the profile
+func TestProfileArgs(t *testing.T) {
+	for _, tc := range []struct {
+		args, want []string
+	}{
+		{nil, []string{"--profile", "live"}},
+		{[]string{"--debug", "--profile", "studio", "--osc-port", "9000"}, []string{"--profile", "live", "--debug", "--osc-port", "9000"}},
+		{[]string{"-profile=studio", "--config=c.yaml"}, []string{"--profile", "live", "--config=c.yaml"}},
+		{[]string{"--osc-host", "profile", "--", "--profile"}, []string{"--profile", "live", "--osc-host", "profile", "--", "--profile"}},
+	} {
+		if got := profileArgs(tc.args, "live"); !slices.Equal(got, tc.want) {
+			t.Errorf("profileArgs(%q) = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
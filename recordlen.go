@@ -0,0 +1,117 @@
+// recordlen.go
+// Fixed length records: the GUI ends a record once it is a set number of
+// cycles or seconds long.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"jaudio/internal/slstate"
+)
+
+// recordLength is how long records last: Cycles cycles at the engine tempo,
+// or Seconds. The zero value leaves records running.
+type recordLength struct {
+	Cycles  int
+	Seconds float64
+}
+
+var (
+	// recordLengthFlag (--record-length) is the length for every loop.
+	recordLengthFlag = "off"
+	// recordLengths are the lengths by loop index: the config file's,
+	// with --record-length for the other loops at index -1.
+	recordLengths = map[int]recordLength{}
+)
+
+// parseRecordLength parses "4 cycles", "1 cycle", "8s", "8.5 s" or "off".
+func parseRecordLength(s string) (recordLength, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" || s == "" {
+		return recordLength{}, nil
+	}
+	if n, ok := strings.CutSuffix(s, "s"); ok && !strings.HasSuffix(n, "cycle") {
+		secs, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil || secs <= 0 {
+			return recordLength{}, fmt.Errorf("record length %q: seconds must be a positive number", s)
+		}
+		return recordLength{Seconds: secs}, nil
+	}
+	n, unit, _ := strings.Cut(s, " ")
+	cycles, err := strconv.Atoi(n)
+	if err != nil || cycles < 1 || (unit != "cycle" && unit != "cycles") {
+		return recordLength{}, fmt.Errorf("record length %q must be like \"4 cycles\", \"8s\" or off", s)
+	}
+	return recordLength{Cycles: cycles}, nil
+}
+
+// String formats l as parseRecordLength reads it.
+func (l recordLength) String() string {
+	switch {
+	case l.Cycles == 1:
+		return "1 cycle"
+	case l.Cycles > 0:
+		return fmt.Sprintf("%d cycles", l.Cycles)
+	case l.Seconds > 0:
+		return strconv.FormatFloat(l.Seconds, 'g', -1, 64) + "s"
+	}
+	return "off"
+}
+
+// duration is the length at tempo, with a cycle of eighths eighth notes.
+func (l recordLength) duration(tempo, eighths float32) time.Duration {
+	if l.Cycles > 0 {
+		return fadeDuration(l.Cycles, tempo, eighths)
+	}
+	return time.Duration(l.Seconds * float64(time.Second))
+}
+
+// setRecordLengths sets the lengths from --record-length and the config
+// file's record_lengths, by loop number. Both have been validated.
+func setRecordLengths(perLoop map[int]string) {
+	recordLengths = map[int]recordLength{}
+	recordLengths[-1], _ = parseRecordLength(recordLengthFlag)
+	for n, s := range perLoop {
+		recordLengths[n-1], _ = parseRecordLength(s)
+	}
+}
+
+// recordLengthOf returns the record length for loop i.
+func recordLengthOf(i int) recordLength {
+	if l, ok := recordLengths[i]; ok {
+		return l
+	}
+	return recordLengths[-1]
+}
+
+// scheduleRecordStop times the end of loop i's record, now pos seconds
+// long, on each position update while it records. The caller must hold mu.
+func scheduleRecordStop(i int, ls *LoopState) {
+	length := recordLengthOf(i)
+	if length == (recordLength{}) || ls.State != slstate.Record {
+		return
+	}
+	if ls.recordStop != nil {
+		ls.recordStop.Stop()
+	}
+	left := length.duration(globals["tempo"], globals["eighth_per_cycle"]) - time.Duration(float64(ls.LoopPos)*float64(time.Second))
+	var t *time.Timer
+	t = time.AfterFunc(max(left, 0), func() {
+		mu.Lock()
+		due := ls.recordStop == t && ls.State == slstate.Record
+		if ls.recordStop == t {
+			ls.recordStop = nil
+		}
+		client := sl
+		mu.Unlock()
+		if due {
+			tuiLog.Info("record length reached", "loop", i+1, "length", length)
+			client.Hit(i, "record")
+		}
+	})
+	ls.recordStop = t
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"jaudio/internal/slmock"
+)
+
+// TestParseRecordLength tests lengths in cycles and seconds, and bad ones
+func TestParseRecordLength(t *testing.T) {
+	for s, want := range map[string]recordLength{
+		"off":      {},
+		"1 cycle":  {Cycles: 1},
+		"4 cycles": {Cycles: 4},
+		"8s":       {Seconds: 8},
+		"2.5 s":    {Seconds: 2.5},
+	} {
+		got, err := parseRecordLength(s)
+		if err != nil || got != want {
+			t.Errorf("parseRecordLength(%q) = %+v, %v; want %+v", s, got, err, want)
+		}
+		if back, _ := parseRecordLength(got.String()); back != got {
+			t.Errorf("%+v does not read back from %q", got, got.String())
+		}
+	}
+	for _, bad := range []string{"4", "0 cycles", "-2s", "4 bars", "cycles", "xs"} {
+		if _, err := parseRecordLength(bad); err == nil {
+			t.Errorf("parseRecordLength(%q) succeeded", bad)
+		}
+	}
+	if _, err := parseConfig([]byte("record_lengths: {0: 4 cycles}")); err == nil {
+		t.Error("loop 0 was accepted")
+	}
+	if got := (recordLength{Cycles: 2}).duration(120, 8); got != 4*time.Second {
+		t.Errorf("2 cycles of 4/4 at 120 BPM = %v, want 4s", got)
+	}
+}
+
+// TestRecordStop tests that a record ends at its fixed length, on the loop
+// it is set for
+func TestRecordStop(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 2)
+	sl = startClient(t, sim)
+	defer func(flag string) {
+		mu.Lock()
+		sl, recordLengthFlag = nil, flag
+		setRecordLengths(nil)
+		mu.Unlock()
+	}(recordLengthFlag)
+	recordLengthFlag = "off"
+	setRecordLengths(map[int]string{1: "0.3s"})
+	eventually(t, "two loops", func() bool { return loopCount == 2 })
+
+	sim.Handle(hitMessage(0, "record"))
+	sim.Handle(hitMessage(1, "record"))
+	eventually(t, "loop 1 playing", func() bool { return sim.State(0) == slmock.StatePlaying })
+	if got := sim.Control(0, "loop_len"); got < 0.25 || got > 0.45 {
+		t.Errorf("loop 1 is %.2f s long, want 0.3", got)
+	}
+	if sim.State(1) != slmock.StateRecording {
+		t.Errorf("loop 2 state = %d, want still recording", sim.State(1))
+	}
+}
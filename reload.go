@@ -0,0 +1,175 @@
+// reload.go
+// Config hot-reload: the TUI watches the config file and, when it is saved,
+// applies the settings that start nothing (display, buttons, record
+// lengths, groups, meter trims, cues and loop names) without a restart. A
+// file that does not parse or validate is shown as a notification and the
+// running settings are kept. Changes to the sections that start devices and
+// connections, and to macros, hooks and profiles, which those have taken
+// in, are noted as needing a restart.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// configReloadDelay is how long the config file must be left alone before
+// it is read, as editors save in several steps.
+const configReloadDelay = 200 * time.Millisecond
+
+// displayConfig is the config file's display section, which overrides the
+// flags of the same names:
+//
+//	display: {refresh_rate: 100, latency_warn: 20, stale_after: 5}
+type displayConfig struct {
+	RefreshRate int  `yaml:"refresh_rate"`
+	LatencyWarn int  `yaml:"latency_warn"`
+	StaleAfter  *int `yaml:"stale_after"`
+}
+
+var (
+	// builtinButtonDefs are the button lists before the config file's.
+	builtinButtonDefs = maps.Clone(buttonDefs)
+	// displayFlags are the display settings as the flags set them, for
+	// those taken out of the config file. main sets them once the flags
+	// are parsed.
+	displayFlags = struct{ refreshRate, latencyWarnMs, staleAfter int }{refreshRate, latencyWarnMs, staleAfter}
+)
+
+func (d displayConfig) validate() error {
+	switch {
+	case d.RefreshRate != 0 && d.RefreshRate < 10:
+		return errors.New("refresh_rate must be at least 10 ms")
+	case d.LatencyWarn < 0:
+		return errors.New("latency_warn must not be negative")
+	case d.StaleAfter != nil && *d.StaleAfter < 0:
+		return errors.New("stale_after must not be negative")
+	}
+	return nil
+}
+
+// applyConfig applies the settings of cfg that can change while running,
+// over the flags. Once the TUI runs, the caller must hold mu.
+func applyConfig(cfg config) {
+	d := cfg.Display
+	refreshRate, latencyWarnMs, staleAfter = displayFlags.refreshRate, displayFlags.latencyWarnMs, displayFlags.staleAfter
+	if d.RefreshRate > 0 {
+		refreshRate = d.RefreshRate
+	}
+	if d.LatencyWarn > 0 {
+		latencyWarnMs = d.LatencyWarn
+	}
+	if d.StaleAfter != nil {
+		staleAfter = *d.StaleAfter
+	}
+	buttonDefs = maps.Clone(builtinButtonDefs)
+	applyButtons(cfg.Buttons)
+	setRecordLengths(cfg.RecordLengths)
+	setLinkGroups(cfg.Groups)
+	setMeterTrims(cfg.MeterTrims)
+	setCues(cfg.Cues)
+	setLoopNames(cfg.LoopNames)
+}
+
+// keepRestartSections sets the sections of cfg that need a restart to take
+// effect back to those of running, returning the names of those that
+// differed.
+func keepRestartSections(cfg *config, running config) []string {
+	var changed []string
+	for _, s := range []struct {
+		name string
+		same bool
+	}{
+		{"engine", reflect.DeepEqual(cfg.Engine, running.Engine)},
+		{"terminal", cfg.Terminal == running.Terminal},
+		{"profile", reflect.DeepEqual(cfg.Profile, running.Profile)},
+		{"footswitches", reflect.DeepEqual(cfg.Footswitches, running.Footswitches)},
+		{"midi", reflect.DeepEqual(cfg.MIDI, running.MIDI)},
+		{"mackie", reflect.DeepEqual(cfg.Mackie, running.Mackie)},
+		{"mqtt", reflect.DeepEqual(cfg.MQTT, running.MQTT)},
+		{"mirrors", reflect.DeepEqual(cfg.Mirrors, running.Mirrors)},
+		{"macros", reflect.DeepEqual(cfg.Macros, running.Macros)},
+		{"hooks", reflect.DeepEqual(cfg.Hooks, running.Hooks)},
+		{"profiles", reflect.DeepEqual(cfg.Profiles, running.Profiles)},
+	} {
+		if !s.same {
+			changed = append(changed, s.name)
+		}
+	}
+	cfg.Engine, cfg.Terminal = running.Engine, running.Terminal
+	cfg.Profile, cfg.Footswitches, cfg.MIDI, cfg.Mackie = running.Profile, running.Footswitches, running.MIDI, running.Mackie
+	cfg.MQTT, cfg.Mirrors, cfg.Macros, cfg.Hooks = running.MQTT, running.Mirrors, running.Macros, running.Hooks
+	cfg.Profiles = running.Profiles
+	return changed
+}
+
+// reloadConfig reads the config file again and applies what it can.
+func reloadConfig(file string, named bool) {
+	cfg, err := loadConfig(file, named)
+	if err == nil {
+		_, err = cfg.useProfile(profileName)
+	}
+	if err != nil {
+		tuiLog.Warn("config not reloaded", "err", err)
+		notify(slog.LevelError, trf("Config not reloaded: %v", err))
+		return
+	}
+	mu.Lock()
+	restart := keepRestartSections(&cfg, appConfig)
+	appConfig = cfg
+	applyConfig(cfg)
+	mu.Unlock()
+	tuiLog.Info("config reloaded", "file", file, "restart", restart)
+	if len(restart) > 0 {
+		notify(slog.LevelWarn, trf("Config reloaded; restart for %s", strings.Join(restart, ", ")))
+		return
+	}
+	notify(slog.LevelInfo, tr("Config reloaded"))
+}
+
+// watchConfig calls reload once file has changed and been left alone for
+// configReloadDelay, until ctx is done. It watches the file's directory,
+// as editors often save by replacing the file.
+func watchConfig(ctx context.Context, file string, reload func()) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	file = filepath.Clean(file)
+	if err := w.Add(filepath.Dir(file)); err != nil {
+		return fmt.Errorf("watching %s: %w", filepath.Dir(file), err)
+	}
+	settle := time.NewTimer(configReloadDelay)
+	settle.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(e.Name) == file && !e.Has(fsnotify.Chmod) {
+				settle.Reset(configReloadDelay)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case <-settle.C:
+			reload()
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestReloadConfig tests that a reload applies the display settings and
+// groups, keeps the running MIDI section, and keeps everything when the
+// file does not validate
+func TestReloadConfig(t *testing.T) {
+	defer func(c config, r int, g map[int]string) {
+		appConfig, refreshRate, linkGroups = c, r, g
+		applyConfig(appConfig)
+	}(appConfig, refreshRate, linkGroups)
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(data string) {
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("midi: {device: /dev/a}\n")
+	var err error
+	if appConfig, err = loadConfig(file, true); err != nil {
+		t.Fatal(err)
+	}
+	write("midi: {device: /dev/b}\ngroups: {drums: [1, 2]}\ndisplay: {refresh_rate: 50}\n")
+	reloadConfig(file, true)
+	if refreshRate != 50 || linkGroups[1] != "drums" {
+		t.Errorf("refresh rate %d, groups %v: not applied", refreshRate, linkGroups)
+	}
+	if appConfig.MIDI == nil || appConfig.MIDI.Device != "/dev/a" {
+		t.Errorf("midi = %+v, want the running /dev/a", appConfig.MIDI)
+	}
+
+	write("display: {refresh_rate: 1}\n")
+	reloadConfig(file, true)
+	if refreshRate != 50 {
+		t.Errorf("refresh rate %d after an invalid file, want 50", refreshRate)
+	}
+	write("{}\n")
+	reloadConfig(file, true)
+	if refreshRate != displayFlags.refreshRate || len(linkGroups) != 0 {
+		t.Errorf("refresh rate %d, groups %v: not back to the flags", refreshRate, linkGroups)
+	}
+}
+
+// TestKeepRestartSections tests which changed sections need a restart
+func TestKeepRestartSections(t *testing.T) {
+	running := config{Hooks: map[string]string{"clip": "a"}, Macros: map[string]string{"m": "mute 1"}}
+	cfg := config{Hooks: map[string]string{"clip": "b"}, Macros: map[string]string{"m": "mute 1"}, Groups: map[string][]int{"g": {1}}}
+	if got := keepRestartSections(&cfg, running); !slices.Equal(got, []string{"hooks"}) {
+		t.Errorf("restart for %v, want hooks", got)
+	}
+	if cfg.Hooks["clip"] != "a" || cfg.Groups == nil {
+		t.Errorf("config after = %+v, want the running hooks and the new groups", cfg)
+	}
+}
+
+// TestWatchConfig tests that saving the config file, even by replacing it,
+// reloads it once it settles
+func TestWatchConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	reloads := make(chan struct{}, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- watchConfig(ctx, file, func() { reloads <- struct{}{} }) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	awaitReload := func(what string) {
+		t.Helper()
+		select {
+		case <-reloads:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no reload after %s", what)
+		}
+	}
+	// The watch may start after the first write; write until it is seen.
+	deadline := time.Now().Add(2 * time.Second)
+	for len(reloads) == 0 && time.Now().Before(deadline) {
+		os.WriteFile(file, []byte("{}\n"), 0o644)
+		time.Sleep(configReloadDelay + 50*time.Millisecond)
+	}
+	awaitReload("a write")
+	for len(reloads) > 0 {
+		<-reloads
+	}
+
+	tmp := filepath.Join(dir, "config.yaml.tmp")
+	os.WriteFile(tmp, []byte("groups: {g: [1]}\n"), 0o644)
+	if err := os.Rename(tmp, file); err != nil {
+		t.Fatal(err)
+	}
+	awaitReload("a rename")
+	os.WriteFile(filepath.Join(dir, "other.yaml"), nil, 0o644)
+	select {
+	case <-reloads:
+		t.Error("reloaded after another file changed")
+	case <-time.After(configReloadDelay * 2):
+	}
+}
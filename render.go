@@ -0,0 +1,472 @@
+// render.go
+// Table layout as plain cells, independent of the terminal.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"jaudio/internal/slstate"
+)
+
+// Table columns.
+const (
+	colID = iota
+	colRec
+	colDub
+	colMute
+	colPos
+	colMeterIn
+	colMeterOut
+	colLevel
+	colStateDebug
+)
+
+var fixedColWidths = []int{5, 8, 8, 8, 9, 0, 0, 0, 18}
+
+// clockPosWidth is the width of the Pos column showing posGlyph.
+const clockPosWidth = 5
+
+// clockPosBelow is the screen width below which --pos-style auto shows the
+// position as a glyph.
+const clockPosBelow = 80
+
+// posGlyphs fill up as a loop plays from its start to its end.
+var posGlyphs = []rune("○◔◑◕●")
+
+// span is a run of text in one color.
+type span struct {
+	Text  string
+	Color tcell.Color
+	Bold  bool
+}
+
+// cell is what one table cell shows. It converts to a tview cell for the
+// TUI and to text for snapshots and headless output. Cells may share their
+// spans, so a cell's spans are replaced rather than changed in place.
+type cell struct {
+	Spans     []span
+	Align     int
+	MaxWidth  int
+	Expansion int
+	Header    bool
+	// Background fills the cell in the TUI, unless it is the default.
+	Background tcell.Color
+}
+
+func textCell(text string, color tcell.Color) cell {
+	return cell{Spans: []span{{Text: text, Color: color}}, Align: tview.AlignCenter}
+}
+
+func (c cell) text() string {
+	var b strings.Builder
+	for _, s := range c.Spans {
+		b.WriteString(s.Text)
+	}
+	return b.String()
+}
+
+// tagged returns the text with tview color tags.
+func (c cell) tagged() string {
+	var b strings.Builder
+	for _, s := range c.Spans {
+		b.WriteString(tagSpan(s))
+	}
+	return b.String()
+}
+
+func tagSpan(s span) string {
+	text := tview.Escape(s.Text)
+	switch {
+	case text == "":
+		return ""
+	case s.Color != tcell.ColorDefault && s.Bold:
+		return "[" + s.Color.Name(true) + "::b]" + text + "[-::-]"
+	case s.Color != tcell.ColorDefault:
+		return "[" + s.Color.Name(true) + "]" + text + "[-]"
+	case s.Bold:
+		return "[::b]" + text + "[::-]"
+	}
+	return text
+}
+
+func (c cell) tableCell() *tview.TableCell {
+	tc := new(tview.TableCell)
+	c.setTableCell(tc)
+	return tc
+}
+
+// tableOptions are the display settings the table layout depends on.
+type tableOptions struct {
+	Width      int
+	Fine       bool
+	Sparkline  bool
+	StateDebug bool
+	// Braille draws the meters in braille dots instead of blocks.
+	Braille bool
+	// PosClock shows the loop position as a glyph that fills up over the
+	// loop instead of in seconds.
+	PosClock bool
+	// PosCycles shows the loop position as a cycle bar instead, with the
+	// loop's cycle count.
+	PosCycles bool
+	// Gradient colors each character of the meters by its place on the
+	// scale instead of the whole bar by its level.
+	Gradient bool
+	// Selected is the number of the loop marked as selected, 0 for none.
+	Selected int
+	// LevelSelected shades the selected loop's Level cell too, while it
+	// takes the level keys.
+	LevelSelected bool
+	// Flash lights up the rows of loops that changed state in the last
+	// flashTime.
+	Flash bool
+}
+
+// posClock reports whether the Pos column shows a glyph rather than
+// seconds on a screen of the given width, for --pos-style.
+func posClock(style string, width int) bool {
+	return style == "clock" || style == "auto" && width < clockPosBelow
+}
+
+// colWidth is the width of a column other than the three bar columns.
+func (o tableOptions) colWidth(col int) int {
+	switch {
+	case col == colPos && o.PosCycles:
+		return cyclePosWidth
+	case col == colPos && o.PosClock:
+		return clockPosWidth
+	}
+	return fixedColWidths[col]
+}
+
+// minMeterWidth is the narrowest the bar columns get.
+const minMeterWidth = len("Meter In")
+
+// meterWidth returns the width of each of the three bar columns for a
+// screen of the given width.
+func (o tableOptions) meterWidth(numCols int) int {
+	fixedTotal := 0
+	for i := 0; i < numCols; i++ {
+		if i != colMeterIn && i != colMeterOut && i != colLevel {
+			fixedTotal += o.colWidth(i)
+		}
+	}
+	w := max(o.Width-fixedTotal-(numCols-1), 3*minMeterWidth)
+	return max(w/3, 1)
+}
+
+// renderTable lays out the header and one row per loop. It advances each
+// loop's meter ballistics to now, so the caller must hold mu.
+func renderTable(opt tableOptions, loops []*LoopState, now time.Time) [][]cell {
+	headers := []string{tr("ID"), tr("Rec"), tr("Dub"), tr("Mute"), tr("Pos"), tr("Meter In"), tr("Meter Out"), tr("Level")}
+	if opt.Fine {
+		headers[colLevel] = tr("Level (fine)")
+	}
+	if opt.Sparkline {
+		headers[colMeterIn] = trf("In (%ds)", sparkSeconds)
+		headers[colMeterOut] = trf("Out (%ds)", sparkSeconds)
+	}
+	if opt.StateDebug {
+		headers = append(headers, tr("State Debug"))
+	}
+	w := opt.meterWidth(len(headers))
+
+	rows := make([][]cell, 0, len(loops)+1)
+	header := make([]cell, len(headers))
+	for i, h := range headers {
+		c := cell{Spans: []span{{Text: " " + h + " ", Bold: true}}, Align: tview.AlignCenter, MaxWidth: opt.colWidth(i), Header: true}
+		if i == colMeterIn || i == colMeterOut || i == colLevel {
+			c.MaxWidth, c.Expansion = w, 1
+		}
+		header[i] = c
+	}
+	rows = append(rows, header)
+
+	for i, ls := range loops {
+		row := make([]cell, len(headers))
+		row[colID] = textCell(" "+strconv.Itoa(i+1)+" ", tcell.ColorDefault)
+		row[colRec] = buttonStateCell(ls.State, ls.NextState, buttonDefs["RECORD"])
+		if ls.countIn != nil {
+			row[colRec] = cell{Spans: []span{{Text: fmt.Sprintf(" %d ", ls.countIn.beatsLeft(now)), Color: tcell.ColorYellow, Bold: true}}, Align: tview.AlignCenter}
+		}
+		row[colDub] = buttonStateCell(ls.State, ls.NextState, buttonDefs["OVERDUB"])
+		row[colMute] = buttonStateCell(ls.State, ls.NextState, buttonDefs["MUTE"])
+		switch {
+		case opt.PosCycles:
+			row[colPos] = cyclesCell(ls.LoopPos, ls.controls["loop_len"], ls.controls["cycle_len"])
+		case opt.PosClock:
+			row[colPos] = textCell(posGlyph(ls.LoopPos, ls.controls["loop_len"]), tcell.ColorDefault)
+		default:
+			row[colPos] = textCell(fmt.Sprintf(" %.2f ", ls.LoopPos), tcell.ColorDefault)
+		}
+		trim := meterTrim(i)
+		inPeak := ls.inMeter.step(ls.InPeakMeter*trim, now, meterRelease, meterMinDB)
+		outPeak := ls.outMeter.step(ls.OutPeakMeter*trim, now, meterRelease, meterMinDB)
+		switch {
+		case opt.Sparkline:
+			period := time.Duration(sparkSeconds) * time.Second
+			row[colMeterIn] = sparklineCell(&ls.inHist, now, period, w, trim)
+			row[colMeterOut] = sparklineCell(&ls.outHist, now, period, w, trim)
+		case rmsWindowMs > 0 && opt.Braille:
+			window := time.Duration(rmsWindowMs) * time.Millisecond
+			row[colMeterIn] = brailleDualMeterCell(ls.inRMS.value(now, window)*trim, inPeak, w)
+			row[colMeterOut] = brailleDualMeterCell(ls.outRMS.value(now, window)*trim, outPeak, w)
+		case rmsWindowMs > 0:
+			window := time.Duration(rmsWindowMs) * time.Millisecond
+			row[colMeterIn] = dualMeterCell(ls.inRMS.value(now, window)*trim, inPeak, w)
+			row[colMeterOut] = dualMeterCell(ls.outRMS.value(now, window)*trim, outPeak, w)
+		case opt.Braille:
+			row[colMeterIn] = brailleMeterCell(inPeak, w)
+			row[colMeterOut] = brailleMeterCell(outPeak, w)
+		default:
+			row[colMeterIn] = meterBarCell(inPeak, w)
+			row[colMeterOut] = meterBarCell(outPeak, w)
+		}
+		if opt.Gradient && !opt.Sparkline {
+			row[colMeterIn] = gradientCell(row[colMeterIn], w)
+			row[colMeterOut] = gradientCell(row[colMeterOut], w)
+		}
+		for col, ctrl := range map[int]string{colPos: "loop_pos", colMeterIn: "in_peak_meter", colMeterOut: "out_peak_meter"} {
+			if ls.stale(ctrl, now) {
+				row[col] = staleCell(row[col])
+			}
+		}
+		row[colLevel] = barCell(lvlLaw.fill(ls.Wet, levelMax, meterMinDB), w)
+		if i+1 == opt.Selected {
+			row[colID] = selectedIDCell(i)
+			if opt.LevelSelected {
+				row[colLevel] = selectedBarCell(lvlLaw.fill(ls.Wet, levelMax, meterMinDB), w)
+			}
+		}
+		if opt.StateDebug {
+			row[colStateDebug] = textCell(slstate.Transition{From: ls.State, To: ls.NextState}.String(), tcell.ColorDefault)
+		}
+		if opt.Flash && now.Before(ls.flashUntil) {
+			flashRow(row, ls.State)
+		}
+		for c := range row {
+			if c != colMeterIn && c != colMeterOut && c != colLevel && c != colStateDebug {
+				row[c].MaxWidth = opt.colWidth(c)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// posGlyph shows how far through a loop of length loopLen pos is.
+func posGlyph(pos, loopLen float32) string {
+	if loopLen <= 0 {
+		return string(posGlyphs[0])
+	}
+	frac := min(max(pos/loopLen, 0), 1)
+	return string(posGlyphs[int(frac*float32(len(posGlyphs)-1)+0.5)])
+}
+
+// formatRows renders cells as text columns separated by │. style renders
+// one span; the padding is computed from the plain text.
+func formatRows(rows [][]cell, style func(span) string) string {
+	var widths []int
+	for _, row := range rows {
+		for i, c := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], tview.TaggedStringWidth(tview.Escape(c.text())))
+		}
+	}
+	var b strings.Builder
+	for _, row := range rows {
+		for i, c := range row {
+			if i > 0 {
+				b.WriteString("│")
+			}
+			pad := widths[i] - tview.TaggedStringWidth(tview.Escape(c.text()))
+			left := 0
+			switch c.Align {
+			case tview.AlignCenter:
+				left = pad / 2
+			case tview.AlignRight:
+				left = pad
+			}
+			b.WriteString(strings.Repeat(" ", left))
+			for _, s := range c.Spans {
+				b.WriteString(style(s))
+			}
+			b.WriteString(strings.Repeat(" ", pad-left))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// tableWidth is the width tview draws rows in, with borders: each column
+// as wide as its widest cell, up to the cell's MaxWidth.
+func tableWidth(rows [][]cell) int {
+	var widths []int
+	for _, row := range rows {
+		for i, c := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			w := tview.TaggedStringWidth(tview.Escape(c.text()))
+			if c.MaxWidth > 0 {
+				w = min(w, c.MaxWidth)
+			}
+			widths[i] = max(widths[i], w)
+		}
+	}
+	total := len(widths) + 1
+	for _, w := range widths {
+		total += w
+	}
+	return total
+}
+
+// minTableWidth is the width rows laid out by renderTable with opt would
+// take with the bar columns at their narrowest.
+func minTableWidth(opt tableOptions, rows [][]cell) int {
+	if len(rows) == 0 {
+		return 0
+	}
+	return tableWidth(rows) - 3*(opt.meterWidth(len(rows[0]))-minMeterWidth)
+}
+
+func plainSpan(s span) string { return s.Text }
+
+func meterBarCell(val float32, width int) cell {
+	return barCell(amplitudeToMeterFill(val, meterMinDB, meterMaxDB), width)
+}
+
+func barCell(fill float32, width int) cell {
+	return sharedBarCell(fill, width)
+}
+
+// brailleMeterCell is meterBarCell drawn in braille, at eight steps per
+// character.
+func brailleMeterCell(val float32, width int) cell {
+	fill := amplitudeToMeterFill(val, meterMinDB, meterMaxDB)
+	return cell{Spans: []span{{Text: brailleBar(brailleBarHeights(fill, width*2)), Color: meterColor(fill)}}, Align: tview.AlignLeft}
+}
+
+// brailleDualMeterCell is dualMeterCell drawn in braille: the peak tick is
+// one dot column wide, so it sits at half-character precision.
+func brailleDualMeterCell(rms, peak float32, width int) cell {
+	rmsFill := amplitudeToMeterFill(rms, meterMinDB, meterMaxDB)
+	peakFill := amplitudeToMeterFill(peak, meterMinDB, meterMaxDB)
+	heights := brailleBarHeights(rmsFill, width*2)
+	rmsCols := 0
+	for rmsCols < len(heights) && heights[rmsCols] > 0 {
+		rmsCols++
+	}
+	rmsChars := (rmsCols + 1) / 2
+	peakCol := min(int(math.Ceil(float64(peakFill)*float64(width*2)))-1, width*2-1)
+
+	if peakCol >= 0 {
+		heights[peakCol] = 4
+	}
+	text := []rune(brailleBar(heights))
+	c := cell{Align: tview.AlignLeft}
+	c.Spans = append(c.Spans, span{Text: string(text[:rmsChars]), Color: meterColor(rmsFill)})
+	if peakChar := peakCol / 2; peakCol >= 0 && peakChar >= rmsChars {
+		c.Spans = append(c.Spans,
+			span{Text: string(text[rmsChars:peakChar])},
+			span{Text: string(text[peakChar]), Color: meterColor(peakFill)})
+		rmsChars = peakChar + 1
+	}
+	c.Spans = append(c.Spans, span{Text: string(text[rmsChars:])})
+	return c
+}
+
+// dualMeterCell draws the RMS level as a bar with the peak level as a tick.
+func dualMeterCell(rms, peak float32, width int) cell {
+	rmsFill := amplitudeToMeterFill(rms, meterMinDB, meterMaxDB)
+	peakFill := amplitudeToMeterFill(peak, meterMinDB, meterMaxDB)
+	rmsChars := min(max(int(math.Ceil(float64(rmsFill)*float64(width))), 0), width)
+	peakPos := min(int(math.Ceil(float64(peakFill)*float64(width)))-1, width-1)
+
+	c := cell{Align: tview.AlignLeft}
+	c.Spans = append(c.Spans, span{Text: barText(rmsChars, rmsChars, ' '), Color: meterColor(rmsFill)})
+	if peakPos >= rmsChars {
+		c.Spans = append(c.Spans,
+			span{Text: blanks(peakPos-rmsChars, ' ')},
+			span{Text: "│", Color: meterColor(peakFill)})
+		rmsChars = peakPos + 1
+	}
+	c.Spans = append(c.Spans, span{Text: blanks(width-rmsChars, ' ')})
+	return c
+}
+
+// gradientCell recolors the colored characters of a bar cell width
+// characters wide, each by its place on the meter scale.
+func gradientCell(c cell, width int) cell {
+	spans := c.Spans
+	c.Spans = nil
+	i := 0
+	for _, s := range spans {
+		if s.Color == tcell.ColorDefault {
+			c.Spans = append(c.Spans, s)
+			i += utf8.RuneCountInString(s.Text)
+			continue
+		}
+		for _, r := range s.Text {
+			c.Spans = append(c.Spans, span{Text: string(r), Color: gradientColor((float32(i) + 0.5) / float32(width)), Bold: s.Bold})
+			i++
+		}
+	}
+	return c
+}
+
+// gradientColor is the meter color at fill: green, turning yellow towards
+// greenThreshold, then red by yellowThreshold.
+func gradientColor(fill float32) tcell.Color {
+	green, yellow, red := [3]float32{0, 205, 0}, [3]float32{205, 205, 0}, [3]float32{205, 0, 0}
+	switch {
+	case fill < greenThreshold:
+		t := fill / greenThreshold
+		return mixRGB(green, yellow, t*t)
+	case fill < yellowThreshold:
+		return mixRGB(yellow, red, (fill-greenThreshold)/(yellowThreshold-greenThreshold))
+	}
+	return mixRGB(yellow, red, 1)
+}
+
+// mixRGB is the color t of the way from a to b.
+func mixRGB(a, b [3]float32, t float32) tcell.Color {
+	c := func(i int) int32 { return int32(a[i] + (b[i]-a[i])*t + 0.5) }
+	return tcell.NewRGBColor(c(0), c(1), c(2))
+}
+
+func sparklineCell(h *levelHistory, now time.Time, period time.Duration, width int, trim float32) cell {
+	fills := h.buckets(width*2, now, period)
+	var loudest float32
+	for i, amp := range fills {
+		fills[i] = amplitudeToMeterFill(amp*trim, meterMinDB, meterMaxDB)
+		loudest = max(loudest, fills[i])
+	}
+	return cell{Spans: []span{{Text: brailleSparkline(fills), Color: meterColor(loudest)}}, Align: tview.AlignLeft}
+}
+
+func buttonStateCell(state, next slstate.State, def ButtonState) cell {
+	label := "OFF"
+	color := tcell.ColorRed
+
+	switch {
+	case matchRules(def.PendingOn, state, next):
+		label, color = "ON", tcell.ColorYellow
+	case matchRules(def.PendingOff, state, next):
+		label, color = "OFF", tcell.ColorYellow
+	case state.In(def.OnStates...):
+		label, color = "ON", tcell.ColorGreen
+	}
+	return sharedButtonCell(tr(label), color)
+}
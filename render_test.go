@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// goldenLoops returns loop states covering the button, meter and level
+// renderings, with meter history ending at now.
+func goldenLoops(now time.Time) []*LoopState {
+	loops := []*LoopState{
+		{State: 0, NextState: -1},
+		{State: 2, NextState: -1, LoopPos: 3.5, InPeakMeter: 0.5},
+		{State: 4, NextState: 5, LoopPos: 1.25, InPeakMeter: 0.1, OutPeakMeter: 0.9, Wet: 0.5, controls: map[string]float32{"loop_len": 4}},
+		{State: 5, NextState: -1, LoopPos: 7, InPeakMeter: 1, OutPeakMeter: 1, Wet: 0.921, controls: map[string]float32{"loop_len": 8}},
+		{State: 10, NextState: 4, LoopPos: 0.5, OutPeakMeter: 0.03, Wet: 0.2, controls: map[string]float32{"loop_len": 2}},
+	}
+	for i, ls := range loops {
+		for j := 0; j < 20; j++ {
+			at := now.Add(-time.Duration(20-j) * 250 * time.Millisecond)
+			amp := float32(j%(i+2)) / float32(i+2) * ls.OutPeakMeter
+			ls.inRMS.add(ls.InPeakMeter*0.5, at, time.Second)
+			ls.outRMS.add(amp, at, time.Second)
+			ls.inHist.add(amp, at, 10*time.Second)
+			ls.outHist.add(amp, at, 10*time.Second)
+		}
+	}
+	return loops
+}
+
+// TestRenderTableGolden compares rendered tables with files in
+// testdata/render. Run with -update to rewrite them.
+func TestRenderTableGolden(t *testing.T) {
+	defer func(rms, spark int, release float64, law levelLaw) {
+		rmsWindowMs, sparkSeconds, meterRelease, lvlLaw = rms, spark, release, law
+	}(rmsWindowMs, sparkSeconds, meterRelease, lvlLaw)
+	sparkSeconds, meterRelease, lvlLaw = 10, 11.8, lawLinear
+
+	tests := []struct {
+		name string
+		rms  int
+		opt  tableOptions
+	}{
+		{"peak-80", 0, tableOptions{Width: 80}},
+		{"rms-100", 1000, tableOptions{Width: 100}},
+		{"fine-debug-120", 0, tableOptions{Width: 120, Fine: true, StateDebug: true}},
+		{"sparkline-100", 0, tableOptions{Width: 100, Sparkline: true}},
+		{"narrow-40", 0, tableOptions{Width: 40}},
+		{"braille-peak-80", 0, tableOptions{Width: 80, Braille: true}},
+		{"braille-rms-100", 1000, tableOptions{Width: 100, Braille: true}},
+		{"gradient-rms-100", 1000, tableOptions{Width: 100, Gradient: true}},
+		{"clock-60", 0, tableOptions{Width: 60, PosClock: true}},
+		{"braille-gradient-80", 0, tableOptions{Width: 80, Braille: true, Gradient: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rmsWindowMs = tt.rms
+			now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+			got := formatRows(renderTable(tt.opt, goldenLoops(now), now), tagSpan)
+
+			path := filepath.Join("testdata", "render", tt.name+".golden")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run go test -run TestRenderTableGolden -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("rendered table differs from %s\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+// TestFormatRowsPlain tests column padding and alignment of plain output
+func TestFormatRowsPlain(t *testing.T) {
+	rows := [][]cell{
+		{textCell("ID", 0), {Spans: []span{{Text: "Level"}}}},
+		{textCell("1", 0), {Spans: []span{{Text: "█"}, {Text: "│"}}}},
+	}
+	want := "ID│Level\n1 │█│   \n"
+	if got := formatRows(rows, plainSpan); got != want {
+		t.Errorf("formatRows = %q, want %q", got, want)
+	}
+}
+
+// TestANSISpan tests SGR codes for basic, bold and truecolor spans
+func TestANSISpan(t *testing.T) {
+	tests := []struct {
+		s    span
+		want string
+	}{
+		{span{Text: "x"}, "x"},
+		{span{Text: "ON", Color: tcell.ColorGreen}, "\x1b[32mON\x1b[0m"},
+		{span{Text: "ID", Bold: true}, "\x1b[1mID\x1b[0m"},
+		{span{Text: "█", Color: tcell.NewRGBColor(1, 2, 3), Bold: true}, "\x1b[1;38;2;1;2;3m█\x1b[0m"},
+		{span{Color: tcell.ColorRed}, ""},
+	}
+	for _, tt := range tests {
+		if got := ansiSpan(tt.s); got != tt.want {
+			t.Errorf("ansiSpan(%+v) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+// TestGradientColor tests the meter gradient at the ends and the zone
+// thresholds
+func TestGradientColor(t *testing.T) {
+	tests := []struct {
+		fill float32
+		want tcell.Color
+	}{
+		{0, tcell.NewRGBColor(0, 205, 0)},
+		{greenThreshold, tcell.NewRGBColor(205, 205, 0)},
+		{(greenThreshold + yellowThreshold) / 2, tcell.NewRGBColor(205, 103, 0)},
+		{yellowThreshold, tcell.NewRGBColor(205, 0, 0)},
+		{1, tcell.NewRGBColor(205, 0, 0)},
+	}
+	for _, tt := range tests {
+		if got := gradientColor(tt.fill); got != tt.want {
+			t.Errorf("gradientColor(%v) = %s, want %s", tt.fill, got, tt.want)
+		}
+	}
+}
+
+// TestPosGlyph tests the glyph at points through a loop, and before the
+// loop's length is known
+func TestPosGlyph(t *testing.T) {
+	tests := []struct {
+		pos, loopLen float32
+		want         string
+	}{
+		{0, 4, "○"},
+		{0.4, 4, "○"},
+		{1, 4, "◔"},
+		{2, 4, "◑"},
+		{3, 4, "◕"},
+		{3.9, 4, "●"},
+		{5, 4, "●"},
+		{2, 0, "○"},
+	}
+	for _, tt := range tests {
+		if got := posGlyph(tt.pos, tt.loopLen); got != tt.want {
+			t.Errorf("posGlyph(%v, %v) = %q, want %q", tt.pos, tt.loopLen, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// resize.go
+// Terminal size: laying the table out again as soon as the terminal is
+// resized, and a notice in place of the TUI when it is too small.
+
+package main
+
+import (
+	"sync/atomic"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// minScreenHeight fits the tab bar, the bordered table with its header
+// and one loop, and the status bar.
+const minScreenHeight = 7
+
+// resizeScreen passes on a screen's events, noting resizes so the next
+// draw lays the TUI out for the new size before drawing it.
+type resizeScreen struct {
+	tcell.Screen
+	resized atomic.Bool
+}
+
+func newResizeScreen() (*resizeScreen, error) {
+	s, err := tcell.NewScreen()
+	if err != nil {
+		return nil, err
+	}
+	return &resizeScreen{Screen: s}, nil
+}
+
+// PollEvent is called by tview's event loop, which redraws right after a
+// resize event.
+func (s *resizeScreen) PollEvent() tcell.Event {
+	ev := s.Screen.PollEvent()
+	if _, ok := ev.(*tcell.EventResize); ok {
+		s.resized.Store(true)
+	}
+	return ev
+}
+
+// takeResize reports whether the screen was resized since the last call.
+func (s *resizeScreen) takeResize() bool {
+	return s.resized.Swap(false)
+}
+
+// tooSmallLines explain that a w×h terminal is smaller than minW×minH.
+func tooSmallLines(w, h, minW, minH int) []string {
+	return []string{
+		trf("Terminal too small: %d×%d", w, h),
+		trf("needs at least %d×%d", minW, minH),
+	}
+}
+
+// drawTooSmall draws the notice shown instead of the TUI, centred on the
+// screen.
+func drawTooSmall(s tcell.Screen, minW, minH int) {
+	w, h := s.Size()
+	lines := tooSmallLines(w, h, minW, minH)
+	top := max((h-len(lines))/2, 0)
+	for i, l := range lines {
+		tview.Print(s, l, 0, top+i, w, tview.AlignCenter, tcell.ColorYellow)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// TestTableWidth tests the drawn table width, with borders and cells cut
+// at their MaxWidth
+func TestTableWidth(t *testing.T) {
+	rows := [][]cell{
+		{textCell("ID", 0), {Spans: []span{{Text: "Level"}}, MaxWidth: 3}},
+		{textCell("12", 0), {Spans: []span{{Text: "██"}}}},
+	}
+	if got, want := tableWidth(rows), 1+2+1+3+1; got != want {
+		t.Errorf("tableWidth = %d, want %d", got, want)
+	}
+
+	// minTableWidth is the width at the narrowest meters, whatever the
+	// screen width the rows were laid out for.
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, opt := range []tableOptions{{}, {StateDebug: true}, {Braille: true, Fine: true}} {
+		opt.Width = 1
+		want := tableWidth(renderTable(opt, goldenLoops(now), now))
+		for _, w := range []int{1, want, 100, 200} {
+			opt.Width = w
+			if got := minTableWidth(opt, renderTable(opt, goldenLoops(now), now)); got != want {
+				t.Errorf("%+v: minTableWidth = %d, want %d", opt, got, want)
+			}
+		}
+	}
+}
+
+// TestResizeScreen tests that resize events are noted once and passed on
+func TestResizeScreen(t *testing.T) {
+	s := &resizeScreen{Screen: tcell.NewSimulationScreen("")}
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Fini()
+	if s.takeResize() {
+		t.Error("resize noted before any event")
+	}
+
+	s.SetSize(50, 10)
+	if err := s.PostEvent(tcell.NewEventResize(50, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if ev, ok := s.PollEvent().(*tcell.EventResize); !ok {
+		t.Fatalf("PollEvent = %T, want *tcell.EventResize", ev)
+	} else if w, h := ev.Size(); w != 50 || h != 10 {
+		t.Errorf("resized to %d×%d, want 50×10", w, h)
+	}
+	if !s.takeResize() {
+		t.Error("resize not noted")
+	}
+	if s.takeResize() {
+		t.Error("resize noted twice")
+	}
+}
+
+// TestDrawTooSmall tests the notice drawn on a small screen
+func TestDrawTooSmall(t *testing.T) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Fini()
+	s.SetSize(40, 6)
+	drawTooSmall(s, 69, 7)
+	s.Show()
+
+	cells, w, _ := s.GetContents()
+	var rows []string
+	for y := 0; y*w < len(cells); y++ {
+		var b strings.Builder
+		for _, c := range cells[y*w : (y+1)*w] {
+			b.WriteString(string(c.Runes))
+		}
+		rows = append(rows, strings.TrimSpace(b.String()))
+	}
+	if rows[2] != "Terminal too small: 40×6" || rows[3] != "needs at least 69×7" {
+		t.Errorf("screen rows = %q", rows)
+	}
+}
@@ -0,0 +1,194 @@
+// rest.go
+// JSON REST API for loop state and commands.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const defaultHTTPAddr = "127.0.0.1:8080"
+
+// hitCommands are the SooperLooper commands the API accepts.
+var hitCommands = []string{
+	"record", "overdub", "multiply", "insert", "replace", "substitute",
+	"mute", "mute_on", "mute_off", "pause", "trigger", "oneshot",
+	"undo", "redo", "undo_all", "reverse", "solo",
+}
+
+type loopJSON struct {
+	ID        int     `json:"id"`
+	State     string  `json:"state"`
+	StateCode int     `json:"state_code"`
+	NextState int     `json:"next_state"`
+	Position  float32 `json:"position"`
+	InPeak    float32 `json:"in_peak"`
+	OutPeak   float32 `json:"out_peak"`
+	Level     float32 `json:"level"`
+}
+
+type statusJSON struct {
+	Engine string   `json:"engine"`
+	Online bool     `json:"online"`
+	RTTms  *float64 `json:"rtt_ms"`
+	Loops  int      `json:"loops"`
+}
+
+// newRESTHandler serves the API. Loops are addressed by their 1-based ID,
+// as shown in the table.
+func newRESTHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/status", handleStatus)
+	mux.HandleFunc("GET /api/loops", handleLoops)
+	mux.HandleFunc("GET /api/loops/{id}", handleLoop)
+	mux.HandleFunc("POST /api/loops/{id}/hit/{cmd}", handleHit)
+	mux.HandleFunc("PUT /api/loops/{id}/level", handleLevel)
+	return mux
+}
+
+func serveHTTP(ctx context.Context, addr string) error {
+	return serveHandler(ctx, addr, newRESTHandler(), "REST API listening")
+}
+
+// serveHandler serves h on addr until ctx is done, logging msg once it
+// starts.
+func serveHandler(ctx context.Context, addr string, h http.Handler, msg string) error {
+	srv := &http.Server{Addr: addr, Handler: h}
+	stop := context.AfterFunc(ctx, func() {
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	})
+	defer stop()
+	httpLog.Info(msg, "addr", addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	st := statusJSON{Engine: engineAddr(), Online: *demoFlag}
+	if sl != nil {
+		st.Online = sl.Online()
+	}
+	if rtt, ok := latency.current(time.Now()); ok {
+		ms := float64(rtt) / float64(time.Millisecond)
+		st.RTTms = &ms
+	}
+	mu.Lock()
+	st.Loops = loopCount
+	mu.Unlock()
+	writeJSON(w, http.StatusOK, st)
+}
+
+func handleLoops(w http.ResponseWriter, r *http.Request) {
+	mu.Lock()
+	out := make([]loopJSON, loopCount)
+	for i := range out {
+		out[i] = loopToJSON(i, getLoopState(i))
+	}
+	mu.Unlock()
+	writeJSON(w, http.StatusOK, out)
+}
+
+func handleLoop(w http.ResponseWriter, r *http.Request) {
+	idx, err := loopParam(r)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	mu.Lock()
+	out := loopToJSON(idx, getLoopState(idx))
+	mu.Unlock()
+	writeJSON(w, http.StatusOK, out)
+}
+
+func handleHit(w http.ResponseWriter, r *http.Request) {
+	idx, err := loopParam(r)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	cmd := r.PathValue("cmd")
+	if !slices.Contains(hitCommands, cmd) {
+		writeError(w, http.StatusBadRequest, errors.New("unknown command "+strconv.Quote(cmd)))
+		return
+	}
+	if sl == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("no engine connected"))
+		return
+	}
+	httpLog.Info("hit", "loop", idx+1, "cmd", cmd)
+	hitLoop(idx, cmd)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func handleLevel(w http.ResponseWriter, r *http.Request) {
+	idx, err := loopParam(r)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	var body struct {
+		Level *float32 `json:"level"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Level == nil {
+		writeError(w, http.StatusBadRequest, errors.New(`body must be {"level": <amplitude>}`))
+		return
+	}
+	setLevel(idx, *body.Level)
+	mu.Lock()
+	out := loopToJSON(idx, getLoopState(idx))
+	mu.Unlock()
+	writeJSON(w, http.StatusOK, out)
+}
+
+// loopParam returns the 0-based index of the {id} path value.
+func loopParam(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	mu.Lock()
+	n := loopCount
+	mu.Unlock()
+	if err != nil || id < 1 || id > n {
+		return 0, errors.New("no loop " + strconv.Quote(r.PathValue("id")))
+	}
+	return id - 1, nil
+}
+
+func loopToJSON(idx int, ls *LoopState) loopJSON {
+	return loopJSON{
+		ID:        idx + 1,
+		State:     ls.State.String(),
+		StateCode: int(ls.State),
+		NextState: int(ls.NextState),
+		Position:  ls.LoopPos,
+		InPeak:    ls.InPeakMeter,
+		OutPeak:   ls.OutPeakMeter,
+		Level:     ls.Wet,
+	}
+}
+
+func engineAddr() string {
+	if *demoFlag {
+		return "demo"
+	}
+	return net.JoinHostPort(oscHost, strconv.Itoa(oscPort))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
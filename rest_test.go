@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"jaudio/internal/slmock"
+	"jaudio/internal/slstate"
+)
+
+func restRequest(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+// TestRESTAPI tests the REST API against the simulator
+func TestRESTAPI(t *testing.T) {
+	demoFlag = new(bool)
+	levelThrottle = newSendThrottle(0, 0, func(int, float32) {})
+	sim := startSim(t, "127.0.0.1:0", 2)
+	sl = startClient(t, sim)
+	defer func() { sl = nil }()
+	eventually(t, "engine online", func() bool { return loopCount == 2 && loopStates[1] != nil && loopStates[1].haveState })
+
+	h := newRESTHandler()
+	rec := restRequest(t, h, "GET", "/api/status", "")
+	var st statusJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &st); err != nil || !st.Online || st.Loops != 2 {
+		t.Errorf("GET /api/status = %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := restRequest(t, h, "POST", "/api/loops/2/hit/record", ""); rec.Code != http.StatusAccepted {
+		t.Errorf("POST hit = %d %s", rec.Code, rec.Body)
+	}
+	eventually(t, "loop 2 recording", func() bool { return loopStates[1].State == slmock.StateRecording })
+
+	rec = restRequest(t, h, "GET", "/api/loops", "")
+	var loops []loopJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &loops); err != nil || len(loops) != 2 {
+		t.Fatalf("GET /api/loops = %d %s", rec.Code, rec.Body)
+	}
+	if loops[1].ID != 2 || loops[1].State != slstate.Record.String() {
+		t.Errorf("loop 2 = %+v, want recording", loops[1])
+	}
+
+	rec = restRequest(t, h, "PUT", "/api/loops/1/level", `{"level": 0.5}`)
+	var l loopJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &l); err != nil || l.Level != 0.5 {
+		t.Errorf("PUT level = %d %s", rec.Code, rec.Body)
+	}
+
+	for _, tt := range []struct {
+		method, path, body string
+		code               int
+	}{
+		{"GET", "/api/loops/3", "", http.StatusNotFound},
+		{"GET", "/api/loops/x", "", http.StatusNotFound},
+		{"POST", "/api/loops/1/hit/explode", "", http.StatusBadRequest},
+		{"PUT", "/api/loops/1/level", `{"gain": 1}`, http.StatusBadRequest},
+		{"DELETE", "/api/loops/1", "", http.StatusMethodNotAllowed},
+	} {
+		if rec := restRequest(t, h, tt.method, tt.path, tt.body); rec.Code != tt.code {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rec.Code, tt.code)
+		}
+	}
+}
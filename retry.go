@@ -0,0 +1,150 @@
+// retry.go
+// Critical commands, such as update registrations and loop loads, are sent
+// with a get whose reply confirms that they arrived, since UDP may lose
+// them without a word. Those not confirmed in time are sent again, waiting
+// twice as long each time, and reported as engine errors once out of
+// tries.
+
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+const (
+	// confirmTimeout is how long the first send of a critical command
+	// waits for its confirmation; each retry waits twice as long as the
+	// one before.
+	confirmTimeout = 500 * time.Millisecond
+	// confirmAttempts is how many times a critical command is sent.
+	confirmAttempts = 4
+)
+
+// pendingCommand is a critical command awaiting confirmation.
+type pendingCommand struct {
+	op      string
+	send    func()
+	attempt int
+	due     time.Time
+}
+
+// confirmations are the critical commands awaiting confirmation, by the
+// address their reply comes on.
+type confirmations struct {
+	mu      sync.Mutex
+	pending map[string]*pendingCommand
+}
+
+// add records a command sent at now, which reply confirms.
+func (p *confirmations) add(op, reply string, send func(), now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pending == nil {
+		p.pending = make(map[string]*pendingCommand)
+	}
+	p.pending[reply] = &pendingCommand{op: op, send: send, attempt: 1, due: now.Add(confirmTimeout)}
+}
+
+// confirm marks the command confirmed by a reply on addr as arrived.
+func (p *confirmations) confirm(addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pending, addr)
+}
+
+// forget drops every pending command, as when the engine goes offline: it
+// is registered with afresh when it comes back.
+func (p *confirmations) forget() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	clear(p.pending)
+}
+
+// retry sends again the commands due by now, and drops those out of tries,
+// returning their replies by op.
+func (p *confirmations) retry(now time.Time) map[string][]string {
+	var failed map[string][]string
+	var resend []func()
+	p.mu.Lock()
+	for reply, c := range p.pending {
+		switch {
+		case now.Before(c.due):
+		case c.attempt >= confirmAttempts:
+			if failed == nil {
+				failed = make(map[string][]string)
+			}
+			failed[c.op] = append(failed[c.op], reply)
+			delete(p.pending, reply)
+		default:
+			c.due = now.Add(confirmTimeout << c.attempt)
+			c.attempt++
+			resend = append(resend, c.send)
+		}
+	}
+	p.mu.Unlock()
+	for _, send := range resend {
+		send()
+	}
+	return failed
+}
+
+// sendConfirmed calls send, which sends the command op and a get answered
+// on reply, and again until a reply arrives there or it has been sent
+// confirmAttempts times.
+func (c *SLClient) sendConfirmed(op, reply string, send func()) {
+	c.confirms.add(op, reply, send, time.Now())
+	send()
+}
+
+// retryUnconfirmed sends again the critical commands not confirmed by now,
+// and reports those out of tries, once for each command.
+func (c *SLClient) retryUnconfirmed(now time.Time) {
+	failed := c.confirms.retry(now)
+	for _, op := range slices.Sorted(maps.Keys(failed)) {
+		oscLog.Warn("command not confirmed", "op", op, "replies", failed[op], "tries", confirmAttempts)
+		engineError(op, trf("no reply after %d tries", confirmAttempts))
+	}
+}
+
+// registerConfirmed registers auto updates of control on loop, or updates
+// on change with onChange, and gets its value. Any reply on the update
+// path confirms the registration: either it arrived, or an earlier one is
+// still in place.
+func (c *SLClient) registerConfirmed(loop int, control string, onChange bool) {
+	op, register := "register_auto_update", registerAutoUpdate
+	if onChange {
+		op, register = "register_update", registerUpdate
+	}
+	c.sendConfirmed(op, fmt.Sprintf("/sl/%d/update_%s", loop, control), func() {
+		register(c.out, loop, control, c.returnURL)
+		pollControl(c.out, loop, control, c.returnURL)
+	})
+}
+
+// registerGlobalConfirmed registers updates of a global control and gets
+// its value.
+func (c *SLClient) registerGlobalConfirmed(control string) {
+	c.sendConfirmed("register_update", globalUpdatePrefix+control, func() {
+		registerGlobalUpdate(c.out, control, c.returnURL)
+		pollGlobal(c.out, control, c.returnURL)
+	})
+}
+
+// loadConfirmed sends m, a load_loop for loop, followed by a get of the
+// loop's length answered on a path of its own: the engine does not reply
+// to a load that works.
+func (c *SLClient) loadConfirmed(loop int, m *osc.Message) {
+	reply := fmt.Sprintf("/sl/%d/loaded", loop)
+	c.sendConfirmed("load_loop", reply, func() {
+		oscSend(c.out, m)
+		m := osc.NewMessage(fmt.Sprintf("/sl/%d/get", loop))
+		m.Append("loop_len", c.returnURL, reply)
+		oscSend(c.out, m)
+	})
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestConfirmRetry tests that an unconfirmed command is sent again with
+// backoff until out of tries, and that a confirmed one is not
+func TestConfirmRetry(t *testing.T) {
+	var p confirmations
+	now := time.Now()
+	sends := map[string]int{}
+	p.add("register_auto_update", "/sl/0/update_state", func() { sends["state"]++ }, now)
+	p.add("load_loop", "/sl/1/loaded", func() { sends["load"]++ }, now)
+
+	// Retries are due 0.5, 1.5 and 3.5 s in, and the command fails at 7.5.
+	for _, ms := range []int{400, 500, 1400, 1500, 3500} {
+		if failed := p.retry(now.Add(time.Duration(ms) * time.Millisecond)); failed != nil {
+			t.Fatalf("failed at %d ms: %v", ms, failed)
+		}
+		if ms == 500 {
+			p.confirm("/sl/1/loaded")
+		}
+	}
+	if sends["state"] != 3 || sends["load"] != 1 {
+		t.Errorf("sent again %v, want state 3 times and load once", sends)
+	}
+	if failed := p.retry(now.Add(7 * time.Second)); failed != nil {
+		t.Errorf("failed before the last wait: %v", failed)
+	}
+	failed := p.retry(now.Add(7500 * time.Millisecond))
+	if got := failed["register_auto_update"]; len(failed) != 1 || len(got) != 1 || got[0] != "/sl/0/update_state" {
+		t.Errorf("failed = %v, want the state registration", failed)
+	}
+	if len(p.pending) != 0 {
+		t.Errorf("%d commands still pending", len(p.pending))
+	}
+}
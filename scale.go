@@ -0,0 +1,206 @@
+// scale.go
+// Mapping between Level bar fill (0..1) and the amplitude sent to the mixer.
+
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+type levelLaw int
+
+const (
+	lawLinear levelLaw = iota
+	lawLog
+	lawIEC
+)
+
+func parseLevelLaw(s string) (levelLaw, error) {
+	switch s {
+	case "linear":
+		return lawLinear, nil
+	case "log", "db":
+		return lawLog, nil
+	case "iec", "iec60268-18":
+		return lawIEC, nil
+	}
+	return lawLinear, fmt.Errorf("unknown level law %q (want linear, log or iec)", s)
+}
+
+func (l levelLaw) String() string {
+	switch l {
+	case lawLog:
+		return "log"
+	case lawIEC:
+		return "iec"
+	}
+	return "linear"
+}
+
+// fill returns the bar fill for amplitude amp, where maxAmp fills the bar
+// and minDB is the bottom of the log scale.
+func (l levelLaw) fill(amp, maxAmp float32, minDB float64) float32 {
+	if amp <= 0 || maxAmp <= 0 {
+		return 0
+	}
+	var f float64
+	switch l {
+	case lawLinear:
+		f = float64(amp / maxAmp)
+	case lawLog:
+		maxDB := ampToDB(maxAmp)
+		f = (ampToDB(amp) - minDB) / (maxDB - minDB)
+	case lawIEC:
+		f = iecDeflection(ampToDB(amp)) / iecDeflection(ampToDB(maxAmp))
+	}
+	return float32(clampUnit(f))
+}
+
+// amplitude is the inverse of fill.
+func (l levelLaw) amplitude(fill, maxAmp float32, minDB float64) float32 {
+	f := clampUnit(float64(fill))
+	if f == 0 || maxAmp <= 0 {
+		return 0
+	}
+	var amp float64
+	switch l {
+	case lawLinear:
+		amp = f * float64(maxAmp)
+	case lawLog:
+		maxDB := ampToDB(maxAmp)
+		amp = dbToAmp(minDB + f*(maxDB-minDB))
+	case lawIEC:
+		amp = dbToAmp(iecDB(f * iecDeflection(ampToDB(maxAmp))))
+	}
+	if amp > float64(maxAmp) {
+		amp = float64(maxAmp)
+	}
+	return float32(amp)
+}
+
+// iecDeflection is the IEC 60268-18 meter deflection in percent (0..115) for
+// a level in dB, as used by Ardour's meters.
+func iecDeflection(db float64) float64 {
+	switch {
+	case db < -70:
+		return 0
+	case db < -60:
+		return (db + 70) * 0.25
+	case db < -50:
+		return (db+60)*0.5 + 2.5
+	case db < -40:
+		return (db+50)*0.75 + 7.5
+	case db < -30:
+		return (db+40)*1.5 + 15
+	case db < -20:
+		return (db+30)*2 + 30
+	case db < 6:
+		return (db+20)*2.5 + 50
+	}
+	return 115
+}
+
+func iecDB(def float64) float64 {
+	switch {
+	case def < 2.5:
+		return def/0.25 - 70
+	case def < 7.5:
+		return (def-2.5)/0.5 - 60
+	case def < 15:
+		return (def-7.5)/0.75 - 50
+	case def < 30:
+		return (def-15)/1.5 - 40
+	case def < 50:
+		return (def-30)/2 - 30
+	}
+	return (def-50)/2.5 - 20
+}
+
+func ampToDB(amp float32) float64 {
+	return 20 * math.Log10(float64(amp))
+}
+
+func dbToAmp(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+func clampUnit(f float64) float64 {
+	if f < 0 {
+		return 0
+	}
+	if f > 1 {
+		return 1
+	}
+	return f
+}
+
+// gainUnit is the unit a mixer takes strip gain in.
+type gainUnit int
+
+const (
+	unitLinear gainUnit = iota // amplitude, 1 is unity
+	unitDB                     // decibels, 0 is unity
+	unitFader                  // fader position 0..1, as Ardour's /strip/fader
+)
+
+func parseGainUnit(s string) (gainUnit, error) {
+	switch s {
+	case "linear":
+		return unitLinear, nil
+	case "db":
+		return unitDB, nil
+	case "fader":
+		return unitFader, nil
+	}
+	return unitLinear, fmt.Errorf("unit must be linear, db or fader, not %q", s)
+}
+
+func (u gainUnit) String() string {
+	switch u {
+	case unitDB:
+		return "db"
+	case unitFader:
+		return "fader"
+	}
+	return "linear"
+}
+
+// fromAmp converts amplitude amp to the unit. Silence is -Inf in dB.
+func (u gainUnit) fromAmp(amp float32) float64 {
+	switch u {
+	case unitDB:
+		return ampToDB(amp)
+	case unitFader:
+		return faderPosition(float64(amp))
+	}
+	return float64(amp)
+}
+
+// toAmp is the inverse of fromAmp.
+func (u gainUnit) toAmp(v float64) float32 {
+	switch u {
+	case unitDB:
+		return float32(dbToAmp(v))
+	case unitFader:
+		return float32(faderGain(v))
+	}
+	return float32(max(v, 0))
+}
+
+// faderPosition is Ardour's gain_to_slider_position: the fader travel
+// (0..1) for amplitude amp, with +6 dB at the top.
+func faderPosition(amp float64) float64 {
+	if amp <= 0 {
+		return 0
+	}
+	return math.Pow(max((6*math.Log2(amp)+192)/198, 0), 8)
+}
+
+// faderGain is the inverse of faderPosition.
+func faderGain(pos float64) float64 {
+	if pos <= 0 {
+		return 0
+	}
+	return math.Pow(2, (math.Pow(pos, 1.0/8)*198-192)/6)
+}
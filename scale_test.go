@@ -0,0 +1,101 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestLevelLawFill tests the levelLaw fill mapping at known points
+func TestLevelLawFill(t *testing.T) {
+	tests := []struct {
+		name   string
+		law    levelLaw
+		amp    float32
+		maxAmp float32
+		want   float32
+	}{
+		{"linear zero", lawLinear, 0, 1, 0},
+		{"linear half", lawLinear, 0.5, 1, 0.5},
+		{"linear capped", lawLinear, 2, 1, 1},
+		{"log -35dB", lawLog, float32(dbToAmp(-35)), 1, 0.5},
+		{"log at max", lawLog, 0.921, 0.921, 1},
+		{"log below range", lawLog, float32(dbToAmp(-80)), 1, 0},
+		{"iec -20dB", lawIEC, float32(dbToAmp(-20)), 1, 50.0 / 100.0},
+		{"iec -40dB", lawIEC, float32(dbToAmp(-40)), 1, 15.0 / 100.0},
+		{"iec at max", lawIEC, 1, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.law.fill(tt.amp, tt.maxAmp, -70)
+			if math.Abs(float64(got-tt.want)) > 1e-5 {
+				t.Errorf("%v.fill(%v, %v) = %v, want %v", tt.law, tt.amp, tt.maxAmp, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLevelLawRoundTrip tests that amplitude is the inverse of fill for every law
+func TestLevelLawRoundTrip(t *testing.T) {
+	for _, law := range []levelLaw{lawLinear, lawLog, lawIEC} {
+		for _, fill := range []float32{0, 0.01, 0.1, 0.25, 0.5, 0.75, 0.99, 1} {
+			amp := law.amplitude(fill, 0.921, -70)
+			if amp > 0.921 {
+				t.Errorf("%v.amplitude(%v) = %v, exceeds max", law, fill, amp)
+			}
+			if got := law.fill(amp, 0.921, -70); math.Abs(float64(got-fill)) > 1e-4 {
+				t.Errorf("%v: fill(amplitude(%v)) = %v", law, fill, got)
+			}
+		}
+	}
+}
+
+// TestParseLevelLaw tests the parseLevelLaw function
+func TestParseLevelLaw(t *testing.T) {
+	for in, want := range map[string]levelLaw{"linear": lawLinear, "log": lawLog, "db": lawLog, "iec": lawIEC} {
+		if got, err := parseLevelLaw(in); err != nil || got != want {
+			t.Errorf("parseLevelLaw(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := parseLevelLaw("cubic"); err == nil {
+		t.Errorf("parseLevelLaw(%q) succeeded, want error", "cubic")
+	}
+}
+
+// TestGainUnit tests the mixer gain units at known points
+func TestGainUnit(t *testing.T) {
+	tests := []struct {
+		unit gainUnit
+		amp  float32
+		want float64
+	}{
+		{unitLinear, 0.5, 0.5},
+		{unitDB, 1, 0},
+		{unitDB, 0.1, -20},
+		{unitFader, 0, 0},
+		{unitFader, 1, math.Pow(192.0/198, 8)},
+		{unitFader, 2, 1},
+	}
+	for _, tt := range tests {
+		if got := tt.unit.fromAmp(tt.amp); math.Abs(got-tt.want) > 1e-5 {
+			t.Errorf("%v.fromAmp(%v) = %v, want %v", tt.unit, tt.amp, got, tt.want)
+		}
+	}
+	if !math.IsInf(unitDB.fromAmp(0), -1) {
+		t.Error("silence is not -Inf dB")
+	}
+	if _, err := parseGainUnit("volts"); err == nil {
+		t.Error("parseGainUnit accepted volts")
+	}
+}
+
+// TestGainUnitRoundTrip tests that toAmp is the inverse of fromAmp for every unit
+func TestGainUnitRoundTrip(t *testing.T) {
+	for _, unit := range []gainUnit{unitLinear, unitDB, unitFader} {
+		for _, amp := range []float32{0.01, 0.25, 0.5, 0.921, 1, 1.5} {
+			if got := unit.toAmp(unit.fromAmp(amp)); math.Abs(float64(got-amp)) > 1e-5 {
+				t.Errorf("%v: toAmp(fromAmp(%v)) = %v", unit, amp, got)
+			}
+		}
+	}
+}
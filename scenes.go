@@ -0,0 +1,261 @@
+// scenes.go
+// Named snapshots of every loop's mixer settings, saved across sessions.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sceneControls are the engine controls a scene captures, besides the Level.
+var sceneControls = []string{"wet", "dry", "feedback", "pan_1"}
+
+const (
+	maxScenes      = 9 // recalled with F1–F9
+	sceneRampFrame = 33 * time.Millisecond
+)
+
+var (
+	scenes       []scene
+	scenesFile   = ""
+	sceneRampMs  = 0
+	sceneRampSeq int
+
+	controlThrottles   = make(map[string]*sendThrottle)
+	controlThrottlesMu sync.Mutex
+)
+
+// scene is a snapshot of the Level and scene controls of each loop, in
+// loop order.
+type scene struct {
+	Name  string      `json:"name"`
+	Saved time.Time   `json:"saved"`
+	Loops []sceneLoop `json:"loops"`
+}
+
+type sceneLoop struct {
+	Level    float32            `json:"level"`
+	Controls map[string]float32 `json:"controls,omitempty"`
+}
+
+func defaultScenesPath() string {
+	return filepath.Join(stateDir(), "scenes.json")
+}
+
+// captureScene snapshots loops. Controls the engine has not reported are
+// left out, so recalling the scene does not change them.
+func captureScene(name string, loops []*LoopState, now time.Time) scene {
+	s := scene{Name: name, Saved: now, Loops: make([]sceneLoop, len(loops))}
+	for i, ls := range loops {
+		s.Loops[i].Level = ls.Wet
+		for _, ctrl := range sceneControls {
+			if v, ok := ls.controls[ctrl]; ok {
+				if s.Loops[i].Controls == nil {
+					s.Loops[i].Controls = make(map[string]float32)
+				}
+				s.Loops[i].Controls[ctrl] = v
+			}
+		}
+	}
+	return s
+}
+
+// lerpScene returns the settings a fraction t of the way from from to to.
+// Controls missing from from jump straight to their target.
+func lerpScene(from, to scene, t float32) scene {
+	t = min(max(t, 0), 1)
+	out := scene{Name: to.Name, Saved: to.Saved, Loops: make([]sceneLoop, len(to.Loops))}
+	for i, target := range to.Loops {
+		var start sceneLoop
+		if i < len(from.Loops) {
+			start = from.Loops[i]
+		}
+		out.Loops[i].Level = start.Level + (target.Level-start.Level)*t
+		if len(target.Controls) > 0 {
+			out.Loops[i].Controls = make(map[string]float32, len(target.Controls))
+		}
+		for ctrl, v := range target.Controls {
+			if v0, ok := start.Controls[ctrl]; ok && t < 1 {
+				v = v0 + (v-v0)*t
+			}
+			out.Loops[i].Controls[ctrl] = v
+		}
+	}
+	return out
+}
+
+// putScene adds s, replacing a scene with the same name. It fails when all
+// slots are taken.
+func putScene(list []scene, s scene) ([]scene, error) {
+	for i := range list {
+		if strings.EqualFold(list[i].Name, s.Name) {
+			list[i] = s
+			return list, nil
+		}
+	}
+	if len(list) >= maxScenes {
+		return list, fmt.Errorf("all %d scene slots are used; reuse a name to replace one", maxScenes)
+	}
+	return append(list, s), nil
+}
+
+func loadScenes(file string) ([]scene, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []scene
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if len(list) > maxScenes {
+		list = list[:maxScenes]
+	}
+	return list, nil
+}
+
+// saveScenes writes the list through a temporary file, so a crash cannot
+// leave a truncated file behind.
+func saveScenes(file string, list []scene) error {
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// storeScene captures the current loops under name and saves the list.
+func storeScene(name string) error {
+	mu.Lock()
+	if name == "" {
+		name = trf("Scene %d", len(scenes)+1)
+	}
+	list, err := putScene(scenes, captureScene(name, currentLoops(), time.Now()))
+	if err == nil {
+		scenes = list
+		list = append([]scene(nil), scenes...)
+	}
+	mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tuiLog.Info("scene saved", "name", name)
+	if err := saveScenes(scenesFile, list); err != nil {
+		return err
+	}
+	notify(slog.LevelInfo, trf("Scene saved: %s", name))
+	return nil
+}
+
+// recallScene sends scene slot n, ramped over --scene-ramp. A later recall
+// stops a ramp still in progress.
+func recallScene(n int) {
+	mu.Lock()
+	if n >= len(scenes) {
+		mu.Unlock()
+		return
+	}
+	to := scenes[n]
+	from := captureScene("", currentLoops(), time.Now())
+	sceneRampSeq++
+	seq := sceneRampSeq
+	mu.Unlock()
+
+	tuiLog.Info("scene recalled", "name", to.Name)
+	notify(slog.LevelInfo, trf("Scene recalled: %s", to.Name))
+	ramp := time.Duration(sceneRampMs) * time.Millisecond
+	if ramp <= 0 {
+		applyScene(to)
+		return
+	}
+	goService("scene ramp", func(ctx context.Context) error {
+		start := time.Now()
+		for {
+			t := float32(time.Since(start)) / float32(ramp)
+			mu.Lock()
+			stale := seq != sceneRampSeq
+			mu.Unlock()
+			if stale {
+				return nil
+			}
+			applyScene(lerpScene(from, to, t))
+			if t >= 1 || !sleepCtx(ctx, sceneRampFrame) {
+				return nil
+			}
+		}
+	})
+}
+
+// applyScene sends a scene's settings for the loops that exist now.
+func applyScene(s scene) {
+	mu.Lock()
+	n := min(len(s.Loops), loopCount)
+	for i := 0; i < n; i++ {
+		for ctrl, v := range s.Loops[i].Controls {
+			getLoopState(i).setControl(ctrl, v)
+		}
+	}
+	mu.Unlock()
+	for i := 0; i < n; i++ {
+		for ctrl, v := range s.Loops[i].Controls {
+			controlThrottle(ctrl).Set(i+1, v)
+		}
+		setLevel(i, s.Loops[i].Level)
+	}
+}
+
+// controlThrottle returns the send throttle for an engine control, so
+// ramps and crossfades are held to --max-send-rate like Level drags.
+func controlThrottle(ctrl string) *sendThrottle {
+	controlThrottlesMu.Lock()
+	defer controlThrottlesMu.Unlock()
+	t := controlThrottles[ctrl]
+	if t == nil {
+		t = newSendThrottle(maxSendRate, 0, func(loopID int, value float32) {
+			sl.Set(loopID-1, ctrl, value)
+		})
+		controlThrottles[ctrl] = t
+	}
+	return t
+}
+
+// currentLoops returns the state of every loop. The caller must hold mu.
+func currentLoops() []*LoopState {
+	loops := make([]*LoopState, loopCount)
+	for i := range loops {
+		loops[i] = getLoopState(i)
+	}
+	return loops
+}
+
+// sceneListText lists the scenes for the scene pane.
+func sceneListText(list []scene) string {
+	if len(list) == 0 {
+		return " " + tr("No scenes. Press c to save the current levels as one.") + "\n"
+	}
+	var b strings.Builder
+	for i, s := range list {
+		fmt.Fprintf(&b, " F%d  %-20s %s\n", i+1, s.Name, s.Saved.Format("Jan 2 15:04"))
+	}
+	return b.String()
+}
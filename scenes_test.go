@@ -0,0 +1,83 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCaptureAndLerpScene tests snapshots and the ramp between two scenes
+func TestCaptureAndLerpScene(t *testing.T) {
+	now := time.Date(2025, 6, 1, 20, 0, 0, 0, time.UTC)
+	a := &LoopState{Wet: 0.25}
+	a.setControl("wet", 1)
+	a.setControl("feedback", 0)
+	a.setControl("rate", 2) // not a scene control
+	b := &LoopState{Wet: 0.8}
+
+	s := captureScene("Verse", []*LoopState{a, b}, now)
+	want := scene{Name: "Verse", Saved: now, Loops: []sceneLoop{
+		{Level: 0.25, Controls: map[string]float32{"wet": 1, "feedback": 0}},
+		{Level: 0.8},
+	}}
+	if !reflect.DeepEqual(s, want) {
+		t.Fatalf("captureScene = %+v, want %+v", s, want)
+	}
+
+	to := scene{Name: "Chorus", Loops: []sceneLoop{
+		{Level: 0.75, Controls: map[string]float32{"wet": 0, "dry": 0.5}},
+		{Level: 0.4},
+		{Level: 1},
+	}}
+	mid := lerpScene(s, to, 0.5)
+	if got := mid.Loops[0]; got.Level != 0.5 || got.Controls["wet"] != 0.5 || got.Controls["dry"] != 0.5 {
+		t.Errorf("loop 0 halfway = %+v, want level 0.5, wet 0.5 and dry straight to 0.5", got)
+	}
+	if got := mid.Loops[2].Level; got != 0.5 {
+		t.Errorf("loop 2 halfway level = %v, want 0.5 from 0", got)
+	}
+	if end := lerpScene(s, to, 1.5); !reflect.DeepEqual(end.Loops, to.Loops) {
+		t.Errorf("lerpScene past the end = %+v, want the target", end.Loops)
+	}
+}
+
+// TestPutScene tests replacing scenes by name and the slot limit
+func TestPutScene(t *testing.T) {
+	var list []scene
+	var err error
+	for i := 0; i < maxScenes; i++ {
+		if list, err = putScene(list, scene{Name: strings.Repeat("x", i+1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := putScene(list, scene{Name: "new"}); err == nil {
+		t.Error("putScene with all slots used succeeded")
+	}
+	list, err = putScene(list, scene{Name: "XX", Loops: []sceneLoop{{Level: 1}}})
+	if err != nil || len(list) != maxScenes || list[1].Name != "XX" || len(list[1].Loops) != 1 {
+		t.Errorf("replacing xx: err %v, slot 2 = %+v", err, list[1])
+	}
+}
+
+// TestSceneFile tests saving and loading scenes
+func TestSceneFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sub", "scenes.json")
+	if list, err := loadScenes(file); err != nil || list != nil {
+		t.Fatalf("loading a missing file = %v, %v, want no scenes", list, err)
+	}
+	list := []scene{{Name: "Intro", Saved: time.Date(2025, 6, 1, 20, 0, 0, 0, time.UTC), Loops: []sceneLoop{
+		{Level: 0.5, Controls: map[string]float32{"pan_1": 0.25}},
+	}}}
+	if err := saveScenes(file, list); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadScenes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, list) {
+		t.Errorf("loaded %+v, want %+v", got, list)
+	}
+}
@@ -0,0 +1,90 @@
+// seek.go
+// Seeking: clicking in a playing loop's Pos cell moves its position to that
+// point of the loop. SooperLooper has no seek command, so a seek enters
+// scratch mode, sets scratch_pos and leaves scratch mode again, in one
+// bundle. With --seek confirm, the default, a click only proposes the seek,
+// and y or a second click in the same cell makes it.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/hypebeast/go-osc/osc"
+
+	"jaudio/internal/slstate"
+)
+
+// Seek modes for --seek.
+const (
+	seekOff     = "off"
+	seekConfirm = "confirm"
+	seekDirect  = "direct"
+)
+
+// seekProposal is a seek waiting for confirmation: loop, to frac of its
+// length.
+type seekProposal struct {
+	loop int
+	frac float32
+}
+
+var (
+	// seekMode (--seek) is what a click in a Pos cell does.
+	seekMode = seekConfirm
+	// pendingSeek is the seek proposed by the last click, if any. It
+	// belongs to the TUI goroutine.
+	pendingSeek *seekProposal
+)
+
+// seekMessages move loop i to frac of its length.
+func seekMessages(i int, frac float32) []*osc.Message {
+	hit := fmt.Sprintf("/sl/%d/hit", i)
+	return []*osc.Message{
+		osc.NewMessage(hit, "scratch"),
+		osc.NewMessage(fmt.Sprintf("/sl/%d/set", i), "scratch_pos", frac),
+		osc.NewMessage(hit, "scratch"),
+	}
+}
+
+// seekLoop moves loop i to frac of its length, if it is playing.
+func seekLoop(i int, frac float32) {
+	frac = min(max(frac, 0), 1)
+	mu.Lock()
+	ls := getLoopState(i)
+	state, length := ls.State, ls.controls["loop_len"]
+	client := sl
+	mu.Unlock()
+	if state != slstate.Play || length <= 0 {
+		tuiLog.Info("seek needs a playing loop", "loop", i+1, "state", state)
+		return
+	}
+	tuiLog.Info("seek", "loop", i+1, "to", fmt.Sprintf("%.2fs", frac*length))
+	client.SendBatch(seekMessages(i, frac))
+}
+
+// clickPos handles a click at frac of loop i's Pos cell, as --seek says.
+func clickPos(i int, frac float32) {
+	switch seekMode {
+	case seekDirect:
+		seekLoop(i, frac)
+	case seekConfirm:
+		if p := pendingSeek; p != nil && p.loop == i {
+			pendingSeek = nil
+			seekLoop(i, frac)
+			return
+		}
+		pendingSeek = &seekProposal{loop: i, frac: frac}
+	}
+}
+
+// seekPrompt asks for confirmation of the pending seek. The caller must
+// hold mu.
+func seekPrompt() string {
+	p := pendingSeek
+	if p == nil {
+		return ""
+	}
+	length := getLoopState(p.loop).controls["loop_len"]
+	return trf("Seek L%d to %.1f s? y/n or click again", p.loop+1, p.frac*length)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+
+	"jaudio/internal/slmock"
+	"jaudio/internal/slstate"
+)
+
+// TestSeek tests that a confirmed click in the Pos cell moves a playing
+// loop's position there
+func TestSeek(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 1)
+	sl = startClient(t, sim)
+	defer func(mode string) {
+		mu.Lock()
+		sl = nil
+		mu.Unlock()
+		seekMode, pendingSeek = mode, nil
+	}(seekMode)
+	eventually(t, "a loop", func() bool { return loopCount == 1 && getLoopState(0).haveState })
+	sim.Handle(hitMessage(0, "record"))
+	time.Sleep(50 * time.Millisecond)
+	sim.Handle(hitMessage(0, "record"))
+	sim.Handle(osc.NewMessage("/sl/0/set", "rate", float32(0)))
+	eventually(t, "a playing loop", func() bool {
+		ls := getLoopState(0)
+		return ls.State == slstate.Play && ls.controls["loop_len"] > 0
+	})
+	length := float64(sim.Control(0, "loop_len"))
+
+	seekMode = seekConfirm
+	clickPos(0, 0.5)
+	mu.Lock()
+	prompt := seekPrompt()
+	mu.Unlock()
+	if !strings.HasPrefix(prompt, "Seek L1 to") {
+		t.Errorf("prompt %q after one click", prompt)
+	}
+	clickPos(0, 0.25)
+	if pendingSeek != nil {
+		t.Error("seek still pending after the second click")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for math.Abs(float64(sim.Control(0, "loop_pos"))-length/4) > 1e-4 || sim.State(0) != slmock.StatePlaying {
+		if time.Now().After(deadline) {
+			t.Fatalf("loop at %v of %v in state %d, want a quarter, playing", sim.Control(0, "loop_pos"), length, sim.State(0))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	seekMode = seekOff
+	clickPos(0, 0.5)
+	if pendingSeek != nil {
+		t.Error("seek proposed with --seek off")
+	}
+}
@@ -0,0 +1,142 @@
+// sendqueue.go
+// Outgoing OSC to the engine goes through a bounded queue served by a few
+// workers, so the TUI, MIDI, timers and throttles hand messages over
+// instead of writing to the network themselves. Messages for a loop keep
+// their order, and a stuck transport fills the queue and slows senders
+// down rather than piling up goroutines.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+const (
+	// sendWorkers is how many messages can be on their way at once, each
+	// for a different loop.
+	sendWorkers = 4
+	// sendQueueLen is how many messages each worker can have waiting.
+	sendQueueLen = 256
+	// sendQueueWait is how long a sender waits for room in a full queue
+	// before its message is dropped.
+	sendQueueWait = 250 * time.Millisecond
+)
+
+var (
+	errSendQueueFull   = errors.New("send queue full")
+	errSendQueueClosed = errors.New("send queue closed")
+)
+
+// sendQueue is an oscSender that queues packets for its workers to send on
+// t. Packets are spread over the workers by loop, so those for one loop
+// are sent in order; those for every loop or the whole engine are sent in
+// order with each other.
+type sendQueue struct {
+	t      oscSender
+	queues []chan osc.Packet
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newSendQueue(t oscSender, workers, size int) *sendQueue {
+	q := &sendQueue{t: t, closed: make(chan struct{})}
+	for range max(workers, 1) {
+		q.queues = append(q.queues, make(chan osc.Packet, size))
+	}
+	return q
+}
+
+// Send queues p, waiting up to sendQueueWait for room. It does not wait
+// for p to be sent.
+func (q *sendQueue) Send(p osc.Packet) error {
+	select {
+	case <-q.closed:
+		return errSendQueueClosed
+	default:
+	}
+	ch := q.queues[packetShard(p, len(q.queues))]
+	select {
+	case ch <- p:
+		return nil
+	default:
+	}
+	t := time.NewTimer(sendQueueWait)
+	defer t.Stop()
+	select {
+	case ch <- p:
+		return nil
+	case <-q.closed:
+		return errSendQueueClosed
+	case <-t.C:
+		oscLog.Warn("send queue full, message dropped", "addr", packetAddress(p))
+		return errSendQueueFull
+	}
+}
+
+// run sends what is queued until ctx is done, and then closes the queue:
+// what is still in it is dropped, and later sends fail.
+func (q *sendQueue) run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, ch := range q.queues {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case p := <-ch:
+					if err := q.t.Send(p); err != nil {
+						oscLog.Debug("send failed", "addr", packetAddress(p), "err", err)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	q.closeOnce.Do(func() { close(q.closed) })
+}
+
+// packetShard is the worker of n that sends p: by the loop its first
+// message addresses, with everything else on the first.
+func packetShard(p osc.Packet, n int) int {
+	loop, ok := packetLoop(p)
+	if !ok || loop < 0 {
+		return 0
+	}
+	return loop % n
+}
+
+// packetLoop is the loop p is for, from an address such as /sl/2/hit, or
+// from a bundle's first message.
+func packetLoop(p osc.Packet) (int, bool) {
+	addr := packetAddress(p)
+	rest, ok := strings.CutPrefix(addr, "/sl/")
+	if !ok {
+		return 0, false
+	}
+	n, _, _ := strings.Cut(rest, "/")
+	loop, err := strconv.Atoi(n)
+	return loop, err == nil
+}
+
+// packetAddress is p's address, or its first message's for a bundle.
+func packetAddress(p osc.Packet) string {
+	switch p := p.(type) {
+	case *osc.Message:
+		return p.Address
+	case *osc.Bundle:
+		if len(p.Messages) > 0 {
+			return p.Messages[0].Address
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// recordingSender records what is sent on it, waiting on gate first if set.
+type recordingSender struct {
+	gate chan struct{}
+	mu   sync.Mutex
+	sent []string
+}
+
+func (s *recordingSender) Send(p osc.Packet) error {
+	if s.gate != nil {
+		<-s.gate
+	}
+	m := p.(*osc.Message)
+	s.mu.Lock()
+	s.sent = append(s.sent, fmt.Sprintf("%s %v", m.Address, m.Arguments))
+	s.mu.Unlock()
+	return nil
+}
+
+// TestSendQueue tests that queued messages for a loop are sent in order,
+// and that a full queue drops messages once its senders have waited
+func TestSendQueue(t *testing.T) {
+	s := &recordingSender{gate: make(chan struct{})}
+	q := newSendQueue(s, 2, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.run(ctx)
+		close(done)
+	}()
+
+	// Loop 1's worker is stuck on its first message, with four queued.
+	for i := range 5 {
+		if err := q.Send(osc.NewMessage("/sl/1/set", "wet", float32(i))); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+		if i == 0 {
+			eventually(t, "first message taken", func() bool { return len(q.queues[1]) == 0 })
+		}
+	}
+	if err := q.Send(osc.NewMessage("/sl/3/set", "wet", float32(5))); !errors.Is(err, errSendQueueFull) {
+		t.Errorf("send to a full queue = %v, want %v", err, errSendQueueFull)
+	}
+	close(s.gate)
+	eventually(t, "queue sent", func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.sent) == 5
+	})
+	for i, got := range s.sent {
+		if want := fmt.Sprintf("/sl/1/set [wet %d]", i); got != want {
+			t.Errorf("sent[%d] = %q, want %q", i, got, want)
+		}
+	}
+
+	cancel()
+	<-done
+	if err := q.Send(osc.NewMessage("/sl/0/hit", "record")); !errors.Is(err, errSendQueueClosed) {
+		t.Errorf("send after run = %v, want %v", err, errSendQueueClosed)
+	}
+}
+
+// TestPacketShard tests which worker sends a message
+func TestPacketShard(t *testing.T) {
+	tests := []struct {
+		p    osc.Packet
+		want int
+	}{
+		{osc.NewMessage("/sl/0/hit"), 0},
+		{osc.NewMessage("/sl/5/set"), 1},
+		{osc.NewMessage("/sl/-1/hit"), 0},
+		{osc.NewMessage("/set"), 0},
+		{osc.NewMessage("/ping"), 0},
+		{&osc.Bundle{Messages: []*osc.Message{osc.NewMessage("/sl/2/set"), osc.NewMessage("/sl/3/set")}}, 2},
+	}
+	for _, tt := range tests {
+		if got := packetShard(tt.p, 4); got != tt.want {
+			t.Errorf("packetShard(%s) = %d, want %d", packetAddress(tt.p), got, tt.want)
+		}
+	}
+}
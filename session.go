@@ -0,0 +1,194 @@
+// session.go
+// Session autosave: the GUI state is saved every --autosave seconds and
+// offered back at the next start, so a crash mid-show loses little.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	// autosaveSeconds (--autosave) is how often the session is saved; 0
+	// neither saves nor offers it back.
+	autosaveSeconds = 10
+	// sessionFile (--session-file) is where; empty is the default path.
+	sessionFile = ""
+)
+
+// session is the GUI state kept across restarts. Scenes are not in it, as
+// they are saved when stored.
+type session struct {
+	Saved time.Time `json:"saved"`
+	// Clean is set when sooperGUI exited normally; a session saved while
+	// running was left by a crash.
+	Clean     bool       `json:"clean"`
+	Page      int        `json:"page"`
+	Loop      int        `json:"loop"`
+	Song      int        `json:"song"`
+	Crossfade crossfader `json:"crossfade"`
+	// Master is left out of sessions saved before the master fader.
+	Master     *masterFader `json:"master,omitempty"`
+	Fine       bool         `json:"fine,omitempty"`
+	Sparklines bool         `json:"sparklines,omitempty"`
+	Panes      []string     `json:"panes,omitempty"`
+	// Levels are the loops' levels and scene controls.
+	Levels scene `json:"levels"`
+}
+
+// sessionPanes are the pane toggles a session keeps, by name.
+var sessionPanes = []struct {
+	name  string
+	shown *bool
+}{
+	{"history", &showHistory},
+	{"log", &showLog},
+	{"scenes", &showScenes},
+	{"songs", &showSongs},
+	{"crossfade", &showCrossfade},
+	{"master", &showMaster},
+}
+
+func defaultSessionPath() string {
+	return filepath.Join(stateDir(), "session.json")
+}
+
+// captureSession snapshots the GUI state. The caller must hold mu, and
+// be on the TUI's event goroutine, which owns the view toggles.
+func captureSession(now time.Time) session {
+	s := session{
+		Saved:      now,
+		Page:       currentPage,
+		Loop:       selectedLoop,
+		Song:       currentSong,
+		Crossfade:  xfade,
+		Fine:       fineToggle,
+		Sparklines: sparklineView,
+		Levels:     captureScene("", currentLoops(), now),
+	}
+	m := master
+	s.Master = &m
+	for _, p := range sessionPanes {
+		if *p.shown {
+			s.Panes = append(s.Panes, p.name)
+		}
+	}
+	return s
+}
+
+func loadSession(file string) (*session, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return &s, nil
+}
+
+// saveSession writes s through a temporary file, like saveScenes.
+func saveSession(file string, s session) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// restoreSession puts back the GUI state of s and sends its levels. Songs
+// are made current without sending their settings, which the engine kept
+// unless it restarted. It runs on the TUI's event goroutine; showPane
+// shows a pane the session had open and switchPage switches page.
+func restoreSession(s *session, showPane func(name string), switchPage func(n int)) {
+	switchPage(min(max(s.Page, 0), len(pageNames)-1))
+	mu.Lock()
+	selectLoop(s.Loop)
+	if s.Song < len(songs.Songs) {
+		currentSong = s.Song
+	}
+	xfade = s.Crossfade
+	if s.Master != nil {
+		master = *s.Master
+	}
+	fineToggle, sparklineView = s.Fine, s.Sparklines
+	mu.Unlock()
+	for _, name := range s.Panes {
+		showPane(name)
+	}
+	tuiLog.Info("session restored", "saved", s.Saved.Format(time.DateTime))
+	notify(slog.LevelInfo, trf("Session restored from %s", s.Saved.Format(time.DateTime)))
+	applyScene(s.Levels)
+}
+
+// restorePrompt is the status bar question offering s back.
+func restorePrompt(s *session) string {
+	saved := s.Saved.Local().Format("15:04")
+	if !s.Clean {
+		return trf("sooperGUI did not exit cleanly. Restore the session from %s? y/n", saved)
+	}
+	return trf("Restore the session from %s? y/n", saved)
+}
+
+// autosave saves the session every --autosave seconds while it changes,
+// until ctx is done.
+// capture runs on the TUI's event goroutine and reports false while the
+// saved session has not been answered, so it is kept until then.
+func autosave(ctx context.Context, file string, capture func() (session, bool)) {
+	var last []byte
+	t := time.NewTicker(time.Duration(autosaveSeconds) * time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		s, ok := capture()
+		if !ok {
+			continue
+		}
+		data, _ := json.Marshal(s)
+		if bytes.Equal(data, last) {
+			continue
+		}
+		s.Saved = time.Now()
+		if err := saveSession(file, s); err != nil {
+			tuiLog.Warn("session not saved", "err", err)
+			continue
+		}
+		last = data
+	}
+}
+
+// saveCleanSession saves the session on a normal exit, so the next start
+// does not take it for a crash. The TUI must have stopped.
+func saveCleanSession(file string) {
+	mu.Lock()
+	s := captureSession(time.Now())
+	mu.Unlock()
+	s.Clean = true
+	if err := saveSession(file, s); err != nil {
+		tuiLog.Warn("session not saved", "err", err)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSessionRestore tests that a saved session puts back the view and
+// sends its levels
+func TestSessionRestore(t *testing.T) {
+	var sentMu sync.Mutex
+	sent := map[int]float32{}
+	levelThrottle = newSendThrottle(0, 0, func(loopID int, v float32) {
+		sentMu.Lock()
+		sent[loopID] = v
+		sentMu.Unlock()
+	})
+	t.Cleanup(func() {
+		mu.Lock()
+		currentPage, selectedLoop, currentSong, loopCount = pageMixer, 0, -1, 1
+		loopStates = make(map[int]*LoopState)
+		xfade = crossfader{A: 0, B: 1}
+		showHistory, sparklineView = false, false
+		songs = setlist{}
+		mu.Unlock()
+	})
+
+	mu.Lock()
+	loopCount = 2
+	loopStates = make(map[int]*LoopState)
+	getLoopState(0).Wet, getLoopState(1).Wet = 0.5, 0.25
+	currentPage, selectedLoop, currentSong = pageLoop, 1, 1
+	songs = setlist{Songs: []song{{Name: "A"}, {Name: "B"}}}
+	xfade = crossfader{A: 1, B: 2, Pos: 0.5}
+	showHistory, sparklineView = true, true
+	s := captureSession(time.Date(2025, 6, 1, 21, 4, 0, 0, time.Local))
+	mu.Unlock()
+
+	file := filepath.Join(t.TempDir(), "session.json")
+	if err := saveSession(file, s); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadSession(file)
+	if err != nil || got == nil {
+		t.Fatalf("loadSession = %v, %v", got, err)
+	}
+	if missing, err := loadSession(filepath.Join(t.TempDir(), "none.json")); missing != nil || err != nil {
+		t.Errorf("loadSession of a missing file = %v, %v, want nothing", missing, err)
+	}
+	if p := restorePrompt(got); !strings.Contains(p, "did not exit cleanly") || !strings.Contains(p, "21:04") {
+		t.Errorf("prompt after a crash = %q", p)
+	}
+	got.Clean = true
+	if p := restorePrompt(got); strings.Contains(p, "did not exit cleanly") {
+		t.Errorf("prompt after a normal exit = %q", p)
+	}
+
+	mu.Lock()
+	currentPage, selectedLoop, currentSong = pageMixer, 0, -1
+	getLoopState(0).Wet, getLoopState(1).Wet = 0, 0
+	xfade = crossfader{A: 0, B: 1}
+	showHistory, sparklineView = false, false
+	mu.Unlock()
+
+	var panes []string
+	page := -1
+	restoreSession(got, func(name string) { panes = append(panes, name) }, func(n int) { page = n })
+
+	if page != pageLoop || selectedLoop != 1 || currentSong != 1 || !sparklineView {
+		t.Errorf("page %d, loop %d, song %d, sparklines %v; want the Loop page, loop 2, song 2 and sparklines",
+			page, selectedLoop, currentSong, sparklineView)
+	}
+	if xfade != (crossfader{A: 1, B: 2, Pos: 0.5}) {
+		t.Errorf("crossfader = %+v", xfade)
+	}
+	if !slices.Equal(panes, []string{"history"}) {
+		t.Errorf("panes shown = %q, want history", panes)
+	}
+	eventually(t, "levels sent", func() bool {
+		sentMu.Lock()
+		defer sentMu.Unlock()
+		return sent[1] == 0.5 && sent[2] == 0.25
+	})
+}
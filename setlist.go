@@ -0,0 +1,169 @@
+// setlist.go
+// Songs with per-song loop settings, switched from a navigator pane.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hypebeast/go-osc/osc"
+	"gopkg.in/yaml.v3"
+)
+
+// quantizeModes are SooperLooper's quantize values by name.
+var quantizeModes = map[string]int{"off": 0, "cycle": 1, "8th": 2, "loop": 3}
+
+// setlist is the --setlist file:
+//
+//	songs:
+//	  - name: Intro
+//	    tempo: 96
+//	    loops:
+//	      - {name: Drums, sync: true, quantize: cycle}
+//	      - {name: Bass, quantize: 8th}
+//
+// Settings left out of a song are not sent, so they keep their value from
+// the previous song.
+type setlist struct {
+	Songs []song `yaml:"songs"`
+}
+
+type song struct {
+	Name  string     `yaml:"name"`
+	Tempo float32    `yaml:"tempo"`
+	Loops []songLoop `yaml:"loops"`
+}
+
+type songLoop struct {
+	Name     string `yaml:"name"`
+	Sync     *bool  `yaml:"sync"`
+	Quantize string `yaml:"quantize"`
+}
+
+var (
+	songs       setlist
+	currentSong = -1
+	setlistFile = ""
+	showSongs   bool
+)
+
+func loadSetlist(file string) (setlist, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return setlist{}, err
+	}
+	s, err := parseSetlist(data)
+	if err != nil {
+		return setlist{}, fmt.Errorf("%s: %w", file, err)
+	}
+	return s, nil
+}
+
+func parseSetlist(data []byte) (setlist, error) {
+	var s setlist
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return setlist{}, err
+	}
+	if len(s.Songs) == 0 {
+		return setlist{}, fmt.Errorf("no songs")
+	}
+	for i, sg := range s.Songs {
+		if sg.Name == "" {
+			return setlist{}, fmt.Errorf("song %d: needs a name", i+1)
+		}
+		if sg.Tempo < 0 {
+			return setlist{}, fmt.Errorf("song %q: tempo must not be negative", sg.Name)
+		}
+		if len(sg.Loops) > maxLoops {
+			return setlist{}, fmt.Errorf("song %q: more than %d loops", sg.Name, maxLoops)
+		}
+		for j, l := range sg.Loops {
+			if _, ok := quantizeModes[l.Quantize]; l.Quantize != "" && !ok {
+				return setlist{}, fmt.Errorf("song %q loop %d: quantize must be off, cycle, 8th or loop, not %q", sg.Name, j+1, l.Quantize)
+			}
+		}
+	}
+	return s, nil
+}
+
+// messages returns the engine settings of the song for the first loops
+// loops.
+func (sg song) messages(loops int) []*osc.Message {
+	var msgs []*osc.Message
+	if sg.Tempo > 0 {
+		msgs = append(msgs, osc.NewMessage("/set", "tempo", sg.Tempo))
+	}
+	for i, l := range sg.Loops {
+		if i >= loops {
+			break
+		}
+		addr := fmt.Sprintf("/sl/%d/set", i)
+		if l.Sync != nil {
+			v := float32(0)
+			if *l.Sync {
+				v = 1
+			}
+			msgs = append(msgs, osc.NewMessage(addr, "sync", v))
+		}
+		if l.Quantize != "" {
+			msgs = append(msgs, osc.NewMessage(addr, "quantize", float32(quantizeModes[l.Quantize])))
+		}
+	}
+	return msgs
+}
+
+// switchSong makes song n current and sends its settings as one bundle.
+func switchSong(n int) {
+	mu.Lock()
+	if n < 0 || n >= len(songs.Songs) {
+		mu.Unlock()
+		return
+	}
+	currentSong = n
+	sg := songs.Songs[n]
+	loops := loopCount
+	mu.Unlock()
+
+	tuiLog.Info("song", "n", n+1, "name", sg.Name)
+	if len(sg.Loops) > loops {
+		tuiLog.Warn("song has more loops than the engine", "song", sg.Name, "loops", len(sg.Loops), "engine", loops)
+	}
+	sl.SendBatch(sg.messages(loops))
+}
+
+// setlistText lists the songs for the navigator pane, with the loops of
+// the current song.
+func setlistText(s setlist, current int) string {
+	var b strings.Builder
+	for i, sg := range s.Songs {
+		mark := "  "
+		if i == current {
+			mark = "▶ "
+		}
+		fmt.Fprintf(&b, " %s%2d  %s", mark, i+1, sg.Name)
+		if sg.Tempo > 0 {
+			fmt.Fprintf(&b, "  %g BPM", sg.Tempo)
+		}
+		b.WriteString("\n")
+		if i != current {
+			continue
+		}
+		for j, l := range sg.Loops {
+			var opts []string
+			if l.Sync != nil && *l.Sync {
+				opts = append(opts, "sync")
+			}
+			if l.Quantize != "" {
+				opts = append(opts, "quantize "+l.Quantize)
+			}
+			fmt.Fprintf(&b, "         L%d %s", j+1, l.Name)
+			if len(opts) > 0 {
+				fmt.Fprintf(&b, " (%s)", strings.Join(opts, ", "))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSetlist = `
+songs:
+  - name: Intro
+    tempo: 96
+    loops:
+      - {name: Drums, sync: true, quantize: cycle}
+      - {name: Bass, quantize: 8th}
+      - {name: Pad, sync: false}
+  - name: Outro
+`
+
+// TestParseSetlist tests setlist validation
+func TestParseSetlist(t *testing.T) {
+	s, err := parseSetlist([]byte(testSetlist))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Songs) != 2 || len(s.Songs[0].Loops) != 3 || !*s.Songs[0].Loops[0].Sync {
+		t.Errorf("parsed %+v", s)
+	}
+	for _, bad := range []string{
+		"songs: []",
+		"songs: [{tempo: 90}]",
+		"songs: [{name: A, tempo: -1}]",
+		"songs: [{name: A, loops: [{quantize: bar}]}]",
+	} {
+		if _, err := parseSetlist([]byte(bad)); err == nil {
+			t.Errorf("parseSetlist(%q) succeeded", bad)
+		}
+	}
+}
+
+// TestSongMessages tests the OSC settings sent for a song
+func TestSongMessages(t *testing.T) {
+	s, _ := parseSetlist([]byte(testSetlist))
+	var got []string
+	for _, m := range s.Songs[0].messages(2) {
+		got = append(got, m.String())
+	}
+	want := []string{
+		"/set ,sf tempo 96",
+		"/sl/0/set ,sf sync 1",
+		"/sl/0/set ,sf quantize 1",
+		"/sl/1/set ,sf quantize 2",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("messages(2) =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if msgs := s.Songs[1].messages(4); len(msgs) != 0 {
+		t.Errorf("song without settings sends %v", msgs)
+	}
+}
+
+// TestSetlistText tests the song navigator listing
+func TestSetlistText(t *testing.T) {
+	s, _ := parseSetlist([]byte(testSetlist))
+	want := "" +
+		" ▶  1  Intro  96 BPM\n" +
+		"         L1 Drums (sync, quantize cycle)\n" +
+		"         L2 Bass (quantize 8th)\n" +
+		"         L3 Pad\n" +
+		"    2  Outro\n"
+	if got := setlistText(s, 0); got != want {
+		t.Errorf("setlistText =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestSwitchSong tests that a song's settings reach the engine
+func TestSwitchSong(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 2)
+	sl = startClient(t, sim)
+	defer func() { sl, songs, currentSong = nil, setlist{}, -1 }()
+	eventually(t, "engine online", func() bool { return loopCount == 2 })
+
+	songs, _ = parseSetlist([]byte(testSetlist))
+	switchSong(0)
+	eventually(t, "song settings", func() bool {
+		return sim.Global("tempo") == 96 && sim.Control(0, "sync") == 1 && sim.Control(1, "quantize") == 2
+	})
+	if currentSong != 0 {
+		t.Errorf("currentSong = %d, want 0", currentSong)
+	}
+}
@@ -0,0 +1,151 @@
+// setup.go
+// First-run setup: when the default config file does not exist yet, a form
+// asks for the engine's address, where the TUI opens and the loops' names,
+// and writes them as the config file. Skipping it writes a config file
+// without them, so it is asked only once. Colors are fixed, so there is no
+// theme to pick.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"gopkg.in/yaml.v3"
+)
+
+// setupHeader starts the config file the setup writes.
+const setupHeader = `# sooperGUI config, written by the first-run setup.
+# contrib/config.example.yaml shows every section.
+`
+
+// terminalChoices are the config file's terminal settings, the default
+// first: a new terminal window, a tmux window or pane when run inside tmux,
+// or the terminal sooperGUI was started in.
+var terminalChoices = []string{"window", "tmux-window", "tmux-pane", "current"}
+
+// setupAnswers are the answers to the setup form.
+type setupAnswers struct {
+	Host     string
+	Port     int
+	Terminal string
+	// LoopNames are the loops' names in loop order, separated by commas.
+	LoopNames string
+}
+
+// configFile is the config file the answers make.
+func (a setupAnswers) configFile() ([]byte, error) {
+	if a.Port < 1 || a.Port > 65535 {
+		return nil, fmt.Errorf("port %d out of range", a.Port)
+	}
+	var b bytes.Buffer
+	b.WriteString(setupHeader)
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	err := enc.Encode(struct {
+		Engine    profileEngine  `yaml:"engine"`
+		Terminal  string         `yaml:"terminal,omitempty"`
+		LoopNames map[int]string `yaml:"loop_names,omitempty"`
+	}{profileEngine{strings.TrimSpace(a.Host), a.Port}, a.Terminal, parseLoopNameList(a.LoopNames)})
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// parseLoopNameList reads "Drums, Bass, , Keys" as names by loop number.
+// An empty entry leaves its loop unnamed.
+func parseLoopNameList(s string) map[int]string {
+	var names map[int]string
+	for i, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" && i < maxLoops {
+			if names == nil {
+				names = make(map[int]string)
+			}
+			names[i+1] = name
+		}
+	}
+	return names
+}
+
+// needsSetup reports whether to run the setup before loading file: it is
+// the default config file, it does not exist, and there is a terminal to
+// ask in.
+func needsSetup(file string, named bool) bool {
+	if named {
+		return false
+	}
+	if _, err := os.Stat(file); !errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	st, err := os.Stdin.Stat()
+	return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
+
+// runSetup asks the setup questions and writes file from the answers, or
+// with none if the setup is skipped.
+func runSetup(file string) error {
+	app := tview.NewApplication()
+	data := []byte(setupHeader)
+	root := newSetupForm(file, func(a setupAnswers) error {
+		out, err := a.configFile()
+		if err == nil {
+			data = out
+			app.Stop()
+		}
+		return err
+	}, app.Stop)
+	if err := app.SetRoot(root, true).EnableMouse(true).Run(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		return err
+	}
+	launcherLog.Info("config file written", "file", file)
+	return nil
+}
+
+// newSetupForm builds the setup form, starting from the flags. Save calls
+// save with the answers, showing the error it returns, and Skip or Esc
+// calls skip.
+func newSetupForm(file string, save func(setupAnswers) error, skip func()) tview.Primitive {
+	intro := tview.NewTextView().SetWrap(true).SetWordWrap(true).
+		SetText(trf("No config file yet. Your answers are saved to %s, where you can change them later. Flags given when starting still win.", file))
+	status := tview.NewTextView().SetTextColor(tcell.ColorRed)
+	host := tview.NewInputField().SetLabel(tr("Engine host")).SetText(oscHost).SetFieldWidth(30)
+	port := tview.NewInputField().SetLabel(tr("Engine port")).SetText(strconv.Itoa(oscPort)).
+		SetFieldWidth(6).SetAcceptanceFunc(tview.InputFieldInteger)
+	terminals := []string{tr("A new terminal window"), tr("A new tmux window"), tr("A new tmux pane"), tr("This terminal")}
+	terminal := tview.NewDropDown().SetLabel(tr("Open the TUI in")).SetOptions(terminals, nil).SetCurrentOption(0)
+	names := tview.NewInputField().SetLabel(tr("Loop names")).SetPlaceholder(tr("e.g. Drums, Bass, Keys")).SetFieldWidth(40)
+
+	form := tview.NewForm().AddFormItem(host).AddFormItem(port).AddFormItem(terminal).AddFormItem(names).
+		AddButton(tr("Save"), func() {
+			n, _ := strconv.Atoi(port.GetText())
+			i, _ := terminal.GetCurrentOption()
+			err := save(setupAnswers{Host: host.GetText(), Port: n, Terminal: terminalChoices[max(i, 0)], LoopNames: names.GetText()})
+			if err != nil {
+				status.SetText(trf("Not saved: %v", err))
+			}
+		}).
+		AddButton(tr("Skip"), skip).
+		SetCancelFunc(skip)
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(intro, 3, 0, false).
+		AddItem(form, 0, 1, true).
+		AddItem(status, 1, 0, false)
+	layout.SetBorder(true).SetTitle(" " + tr("sooperGUI setup") + " ")
+	return layout
+}
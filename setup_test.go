@@ -0,0 +1,46 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSetupAnswers tests that the setup's answers make a config file that
+// loads, and that a bad port is refused
+func TestSetupAnswers(t *testing.T) {
+	data, err := setupAnswers{Host: " 10.0.0.2 ", Port: 9000, Terminal: "tmux-pane", LoopNames: "Drums, , Bass"}.configFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := parseConfig(data)
+	if err != nil {
+		t.Fatalf("%v in\n%s", err, data)
+	}
+	if *cfg.Engine != (profileEngine{"10.0.0.2", 9000}) || cfg.Terminal != "tmux-pane" {
+		t.Errorf("engine %+v, terminal %q", cfg.Engine, cfg.Terminal)
+	}
+	if want := map[int]string{1: "Drums", 3: "Bass"}; !maps.Equal(cfg.LoopNames, want) {
+		t.Errorf("loop names = %v, want %v", cfg.LoopNames, want)
+	}
+	if _, err := (setupAnswers{Port: 0, Terminal: "window"}).configFile(); err == nil {
+		t.Error("port 0 accepted")
+	}
+	if cfg, err := parseConfig([]byte(setupHeader)); err != nil || cfg.Engine != nil {
+		t.Errorf("skipped setup config = %+v, %v", cfg, err)
+	}
+}
+
+// TestNeedsSetup tests that only a missing default config file asks for
+// setup
+func TestNeedsSetup(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if needsSetup(file, true) {
+		t.Error("setup for a --config file")
+	}
+	os.WriteFile(file, nil, 0o644)
+	if needsSetup(file, false) {
+		t.Error("setup with a config file")
+	}
+}
@@ -0,0 +1,454 @@
+// slclient.go
+// OSC connection to the SooperLooper engine and the mixer.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	// autoUpdateControls are sent by the engine for every loop as they
+	// change.
+	autoUpdateControls = []string{"state", "next_state", "loop_pos", "loop_len", "cycle_len", "in_peak_meter", "out_peak_meter"}
+	// polledControls are the auto update controls that --poll asks for on
+	// every refresh instead.
+	polledControls = []string{"state", "next_state", "loop_len", "cycle_len"}
+	polledGlobals  = []string{"tempo", "eighth_per_cycle", "sync_source"}
+
+	// pollStates (--poll) polls loop states and the controls shown on every
+	// refresh, for engines whose auto updates do not arrive.
+	pollStates = false
+
+	// returnHost (--return-host) and returnPort (--return-port) override
+	// the address replies are sent to, for NAT, container bridges and
+	// machines with several interfaces.
+	returnHost = ""
+	returnPort = 0
+)
+
+// reregisterInterval is how often updates are registered again, in case
+// the engine lost them without going offline.
+const reregisterInterval = 30 * time.Second
+
+// SLClient receives engine replies over its transport, keeps the engine
+// pinged, and registers updates for every loop, for the engine globals and
+// for the loop on the Loop page. When the engine stops answering pings and
+// later comes back (e.g. after a restart), and every reregisterInterval,
+// the updates are registered again.
+type SLClient struct {
+	engine Transport
+	// out queues what is sent to the engine, for Run to send on engine.
+	out *sendQueue
+	// confirms are the critical commands sent and not yet confirmed.
+	confirms confirmations
+	// sets are the sets sent and not yet echoed.
+	sets setChecks
+	// addr is the engine's address, for logs and hooks.
+	addr  string
+	mixer *mixer
+	// conn is the UDP transport's reply socket, nil for other transports.
+	conn      net.PacketConn
+	returnURL string
+	handle    func(*osc.Message)
+
+	pingEvery       time.Duration
+	pollEvery       time.Duration
+	reregisterEvery time.Duration
+
+	// profile, if set, is pushed when the engine connects.
+	profile *engineProfile
+	// macros can be run by /gui/macro/run.
+	macros map[string]string
+
+	mu           sync.Mutex
+	online       bool
+	registered   int
+	loops        int // as of the last checkLink, while online
+	registeredAt time.Time
+	detail       int // the loop whose Loop page controls are registered
+	profiled     bool
+	// cancel stops Start's Run, which sends its result on done.
+	cancel context.CancelFunc
+	done   chan error
+}
+
+// newSLClient listens for replies on listenAddr (e.g. ":0") and sends to the
+// engine at the given address over UDP, and to mix, which may be nil.
+// Incoming messages are traced and passed to handle.
+func newSLClient(listenAddr, host string, port int, mix *mixer, handle func(*osc.Message)) (*SLClient, error) {
+	conn, err := net.ListenPacket("udp", listenAddr)
+	if err != nil {
+		return nil, err
+	}
+	if err := mix.attach(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	enlargeReadBuffer(conn)
+	localPort := conn.LocalAddr().(*net.UDPAddr).Port
+	t := &udpTransport{engine: osc.NewClient(host, port), conn: conn}
+	c := newTransportClient(t, net.JoinHostPort(host, strconv.Itoa(port)), replyURL(host, localPort), handle)
+	c.mixer, c.conn = mix, conn
+	return c, nil
+}
+
+// newTransportClient talks to the engine at addr over t, on which the
+// replies the engine sends to returnURL arrive. Incoming messages are
+// traced and passed to handle.
+func newTransportClient(t Transport, addr, returnURL string, handle func(*osc.Message)) *SLClient {
+	return &SLClient{
+		engine:          t,
+		out:             newSendQueue(t, sendWorkers, sendQueueLen),
+		addr:            addr,
+		returnURL:       returnURL,
+		handle:          handle,
+		pingEvery:       pingInterval,
+		pollEvery:       time.Duration(refreshRate) * time.Millisecond,
+		reregisterEvery: reregisterInterval,
+		detail:          -1,
+	}
+}
+
+// replyURL is where the engine and mixer at host send replies: the address
+// of an interface that reaches host, and the port listened on, unless
+// overridden by --return-host and --return-port.
+func replyURL(host string, localPort int) string {
+	h, p := returnHost, returnPort
+	if h == "" {
+		h = getLocalIP(host)
+	}
+	if p == 0 {
+		p = localPort
+	}
+	return "osc.udp://" + net.JoinHostPort(h, strconv.Itoa(p))
+}
+
+// Run runs the reply server, the send queue and the ping and poll loops
+// until ctx is done, then closes the transport. It returns early with the
+// error the reply server stopped with, if it does.
+func (c *SLClient) Run(ctx context.Context) error {
+	receive := func(m *osc.Message) {
+		trace.add(false, m)
+		oscLog.Debug("in", "addr", m.Address, "args", m.Arguments)
+		c.confirms.confirm(m.Address)
+		if strings.HasPrefix(m.Address, verifyPrefix) {
+			c.verifyReply(m)
+			return
+		}
+		if strings.HasPrefix(m.Address, guiPrefix) {
+			if err := handleGUI(m, c.macros); err != nil {
+				oscLog.Warn("gui message", "addr", m.Address, "err", err)
+			}
+			return
+		}
+		c.handle(m)
+	}
+	c.mixer.subscribe(c.returnURL)
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		oscLog.Info("server listening", "url", c.returnURL)
+		err := c.engine.Subscribe(receive)
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("server stopped: %w", err)
+	})
+	g.Go(func() error {
+		<-ctx.Done()
+		return c.engine.Close()
+	})
+	g.Go(func() error {
+		c.out.run(ctx)
+		return nil
+	})
+	g.Go(func() error {
+		tickCtx(ctx, c.pingEvery, func() {
+			now := time.Now()
+			sendPing(c.out, c.returnURL, latency.next(now))
+			mu.Lock()
+			packets.add(now, 0, latency.takeLost(), 0)
+			packets.checkSocket(c.conn, now)
+			mu.Unlock()
+		})
+		return nil
+	})
+	g.Go(func() error {
+		tickCtx(ctx, c.pollEvery, c.poll)
+		return nil
+	})
+	g.Go(func() error {
+		tickCtx(ctx, autoUpdateInterval, func() {
+			mu.Lock()
+			foldLoops()
+			mu.Unlock()
+		})
+		return nil
+	})
+	return g.Wait()
+}
+
+// Start runs Run in the background until Close, for callers outside the
+// service group.
+func (c *SLClient) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel, c.done = cancel, make(chan error, 1)
+	go func() { c.done <- c.Run(ctx) }()
+}
+
+// Close stops what Start started, returning the error it stopped with.
+func (c *SLClient) Close() error {
+	c.cancel()
+	return <-c.done
+}
+
+func (c *SLClient) poll() {
+	mu.Lock()
+	n := loopCount
+	detail := -1
+	if currentPage == pageLoop {
+		detail = selectedLoop
+	}
+	mu.Unlock()
+
+	c.checkLink(time.Now(), n, detail)
+	if c.Online() {
+		c.retryUnconfirmed(time.Now())
+	}
+	c.checkSets(time.Now())
+	for i := 0; i < n; i++ {
+		c.mixer.poll(i+1, c.returnURL)
+	}
+	if !pollStates {
+		return
+	}
+	for _, ctrl := range polledGlobals {
+		pollGlobal(c.out, ctrl, c.returnURL)
+	}
+	for i := 0; i < n; i++ {
+		for _, ctrl := range polledControls {
+			pollControl(c.out, i, ctrl, c.returnURL)
+		}
+	}
+	if detail >= 0 {
+		for _, d := range detailControls {
+			pollControl(c.out, detail, d.Name, c.returnURL)
+		}
+	}
+}
+
+// detailUpdates are the Loop page controls that are not updated for every
+// loop.
+func detailUpdates() []string {
+	var out []string
+	for _, d := range detailControls {
+		if !slices.Contains(autoUpdateControls, d.Name) && !slices.Contains(sceneControls, d.Name) &&
+			!(previewCommands && slices.Contains(previewControls, d.Name)) {
+			out = append(out, d.Name)
+		}
+	}
+	return out
+}
+
+// checkLink registers updates when the engine (re)appears, reports more
+// loops than are registered, or has not been registered with for
+// c.reregisterEvery: auto updates for every loop, change updates for the
+// scene controls and the globals, and auto updates for the Loop page
+// controls of loop detail (-1 for none). Each registration is followed by
+// a get for the current value, and sent again until a reply confirms it.
+func (c *SLClient) checkLink(now time.Time, loops, detail int) {
+	online := latency.repliedSince(now.Add(-c.pingEvery * 3 / 2))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case online && !c.online:
+		oscLog.Info("engine connected", "loops", loops)
+		notify(slog.LevelInfo, tr("Engine connected"))
+		runHook("engine_connect", c.addr, strconv.Itoa(loops))
+		c.registeredAt = time.Time{}
+		c.mixer.subscribe(c.returnURL)
+		if c.profile != nil && (!c.profiled || c.profile.EveryConnect) {
+			c.profiled = true
+			oscLog.Info("pushing engine profile", "loops", c.profile.LoopCount)
+			msgs := c.profile.messages(loops)
+			oscSendBundle(c.out, msgs)
+			c.verifySets(msgs, now)
+		}
+	case !online && c.online:
+		oscLog.Warn("engine not responding")
+		notify(slog.LevelWarn, tr("Engine not responding"))
+		runHook("engine_disconnect", c.addr)
+		c.confirms.forget()
+	}
+	c.online, c.loops = online, loops
+	if !online {
+		return
+	}
+	if now.Sub(c.registeredAt) >= c.reregisterEvery {
+		if c.registered > 0 {
+			oscLog.Debug("registering updates again", "loops", c.registered)
+		}
+		c.registeredAt, c.registered, c.detail = now, 0, -1
+		for _, ctrl := range polledGlobals {
+			if pollStates {
+				pollGlobal(c.out, ctrl, c.returnURL)
+			} else {
+				c.registerGlobalConfirmed(ctrl)
+			}
+		}
+	}
+	for ; c.registered < loops; c.registered++ {
+		for _, ctrl := range autoUpdateControls {
+			if pollStates && slices.Contains(polledControls, ctrl) {
+				pollControl(c.out, c.registered, ctrl, c.returnURL)
+			} else {
+				c.registerConfirmed(c.registered, ctrl, false)
+			}
+		}
+		for _, ctrl := range sceneControls {
+			c.registerConfirmed(c.registered, ctrl, true)
+		}
+		if previewCommands {
+			for _, ctrl := range previewControls {
+				c.registerConfirmed(c.registered, ctrl, true)
+			}
+		}
+	}
+	if pollStates || detail == c.detail {
+		return
+	}
+	for _, ctrl := range detailUpdates() {
+		if c.detail >= 0 {
+			unregisterAutoUpdate(c.out, c.detail, ctrl, c.returnURL)
+		}
+		if detail >= 0 {
+			c.registerConfirmed(detail, ctrl, false)
+		}
+	}
+	c.detail = detail
+}
+
+// Online reports whether the engine answered a recent ping.
+func (c *SLClient) Online() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.online
+}
+
+// check reports op to loop, with control if set, as an engine error if
+// the engine is known to ignore it. SooperLooper has no error path for
+// such commands, so they would otherwise fail silently. They are still
+// sent, in case this build of SooperLooper knows more controls.
+func (c *SLClient) check(op string, loop int, control string) {
+	c.mu.Lock()
+	online, loops := c.online, c.loops
+	c.mu.Unlock()
+	if !online {
+		return
+	}
+	if err := checkLoopCommand(loop, loops, control); err != nil {
+		engineError(op, err.Error())
+	}
+}
+
+// Hit sends a SooperLooper command such as "record" to a loop.
+func (c *SLClient) Hit(loop int, cmd string) {
+	c.press(loop, "hit", cmd)
+}
+
+// Down starts a command held until Up, such as a momentary overdub.
+func (c *SLClient) Down(loop int, cmd string) {
+	c.press(loop, "down", cmd)
+}
+
+// Up ends a command started by Down.
+func (c *SLClient) Up(loop int, cmd string) {
+	c.press(loop, "up", cmd)
+}
+
+// press sends cmd to a loop as a hit, down or up.
+func (c *SLClient) press(loop int, kind, cmd string) {
+	if c == nil {
+		return
+	}
+	c.check(cmd, loop, "")
+	m := osc.NewMessage(fmt.Sprintf("/sl/%d/%s", loop, kind))
+	m.Append(cmd)
+	oscSend(c.out, m)
+}
+
+// Set sets an engine control such as "wet" on a loop.
+func (c *SLClient) Set(loop int, ctrl string, value float32) {
+	if c == nil {
+		return
+	}
+	c.check("set", loop, ctrl)
+	setControl(c.out, loop, ctrl, value)
+	c.verifySet(loop, ctrl, value, time.Now())
+}
+
+// SetGlobal sets an engine-wide control such as "tempo".
+func (c *SLClient) SetGlobal(ctrl string, value float32) {
+	if c == nil {
+		return
+	}
+	c.check("set", -2, ctrl)
+	oscSend(c.out, osc.NewMessage("/set", ctrl, value))
+	c.verifySet(-2, ctrl, value, time.Now())
+}
+
+// SendBatch sends messages to the engine in one bundle.
+func (c *SLClient) SendBatch(msgs []*osc.Message) {
+	if c == nil {
+		return
+	}
+	oscSendBundle(c.out, msgs)
+	c.verifySets(msgs, time.Now())
+}
+
+// SaveLoop asks the engine to write a loop's audio to file. Errors are
+// reported on /error/save_loop.
+func (c *SLClient) SaveLoop(loop int, file string) {
+	c.check("save_loop", loop, "")
+	m := osc.NewMessage(fmt.Sprintf("/sl/%d/save_loop", loop))
+	m.Append(file, "float", "little", c.returnURL, errorPrefix+"save_loop")
+	oscSend(c.out, m)
+}
+
+// LoadLoop asks the engine to replace a loop with the audio in file.
+// Errors are reported on /error/load_loop.
+func (c *SLClient) LoadLoop(loop int, file string) {
+	c.check("load_loop", loop, "")
+	m := osc.NewMessage(fmt.Sprintf("/sl/%d/load_loop", loop))
+	m.Append(file, c.returnURL, errorPrefix+"load_loop")
+	c.loadConfirmed(loop, m)
+}
+
+// SetStripGains sends levels to the mixer strips of 1-based loop IDs in
+// one bundle.
+func (c *SLClient) SetStripGains(gains map[int]float32) {
+	if c == nil {
+		return
+	}
+	c.mixer.setGains(gains)
+}
+
+// SetStripGain sends a level to the mixer strip of 1-based loopID.
+func (c *SLClient) SetStripGain(loopID int, value float32) {
+	if c == nil {
+		return
+	}
+	c.mixer.setGain(loopID, value)
+}
@@ -6,14 +6,16 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
 	"net"
 	"os"
-	"os/exec"
-	"regexp"
+	"os/signal"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -23,346 +25,1756 @@ import (
 	"github.com/gdamore/tcell/v2"
 	"github.com/hypebeast/go-osc/osc"
 	"github.com/rivo/tview"
+
+	"jaudio/internal/slstate"
 )
 
 // --- Structs -----------------------------------------------------------------
 
 type LoopState struct {
-	State        int
-	NextState    int
+	State        slstate.State
+	NextState    slstate.State
 	LoopPos      float32
 	InPeakMeter  float32
 	OutPeakMeter float32
 	Wet          float32
+
+	inMeter  ballistics
+	outMeter ballistics
+	inRMS    rmsWindow
+	outRMS   rmsWindow
+	inHist   levelHistory
+	outHist  levelHistory
+
+	// controls holds engine controls such as wet and feedback, by name.
+	controls map[string]float32
+	// countIn is a record waiting for its count-in, if any.
+	countIn *countIn
+	// recordStop ends a fixed length record.
+	recordStop *time.Timer
+	// flashUntil is when the row's flash for the last transition ends.
+	flashUntil time.Time
+	// clipping is set while a meter is at full scale, for the clip cue.
+	clipping bool
+	// updated is when each control last came from the engine, from
+	// firstUpdate on.
+	updated     map[string]time.Time
+	firstUpdate time.Time
+
+	haveState bool
 }
 
-type ButtonState struct {
-	OnStates       []int
-	PendingOnCond  func(state, next int) bool
-	PendingOffCond func(state, next int) bool
+func (ls *LoopState) setControl(ctrl string, v float32) {
+	if ls.controls == nil {
+		ls.controls = make(map[string]float32)
+	}
+	ls.controls[ctrl] = v
 }
 
 // --- Globals -----------------------------------------------------------------
 
 var (
-	stripGainPathRegex = regexp.MustCompile(`^/strip/Sooper(\d+)/Gain/Gain%20\(dB\)$`)
-
 	oscHost     = "127.0.0.1"
 	oscPort     = 9951
 	refreshRate = 200
 
 	loopCount  = 1
 	loopStates = make(map[int]*LoopState)
+	history    stateHistory
 	mu         sync.Mutex
 
-	client     *osc.Client
-	mockClient *osc.Client
+	sl       *SLClient
+	extMixer *mixer
+	latency  = newLatencyTracker()
+
+	latencyWarnMs = 50
 
-	infoLog  = log.New(os.Stdout, "INFO: ", log.Ldate|log.Ltime)
-	errorLog = log.New(os.Stderr, "ERROR: ", log.Ldate|log.Ltime)
+	maxSendRate   = 30
+	levelRampMs   = 0
+	levelThrottle *sendThrottle
+
+	logFile = ""
 
 	greenThreshold  float32 = 0.7
 	yellowThreshold float32 = 0.9
 	redThreshold    float32 = 1.0
 
-	meterMinDB = -70.0
-	meterMaxDB = 0.0
+	meterMinDB   = -70.0
+	meterMaxDB   = 0.0
+	meterRelease = 11.8
+	rmsWindowMs  = 300
+	sparkSeconds = 10
+	// meterStyle is how meter bars are drawn: braille, block, or auto for
+	// braille where the terminal can show it.
+	meterStyle = "auto"
+	// meterColors is how meter bars are colored: gradient, zones, or auto
+	// for a gradient where the terminal has true color.
+	meterColors = "auto"
+	// posStyle is how the Pos column shows loop positions: number, clock,
+	// cycles, or auto for clock on narrow screens.
+	posStyle = "auto"
+
+	levelMax     float32 = 0.921
+	levelLawFlag         = "linear"
+	lvlLaw       levelLaw
 
 	debugFlag      *bool
 	stateDebugFlag *bool
+	devFlag        *bool
+	demoFlag       *bool
+
+	fineToggle    bool
+	sparklineView bool
+	showHistory   bool
+	showLog       bool
+	showScenes    bool
+	logPaneLevel  = slog.LevelInfo
+)
+
+const (
+	fineRatio   = 10
+	fineNudgeDB = 0.5
+	wheelStepDB = 1.0
+
+	// maxLoops bounds the loop count and indexes accepted from the network.
+	maxLoops = 64
+
+	historyHeight   = 10
+	scenePaneHeight = maxScenes + 2
+	songPaneHeight  = 12
+	logPaneHeight   = 12
 )
 
 // --- main --------------------------------------------------------------------
 
+// usage is the --help text.
+const usage = `Usage: sooperGUI [OPTIONS]
+       sooperGUI export-layout --format touchosc|opensc [OPTIONS]
+  --osc-host         OSC host (default 127.0.0.1)
+  --osc-port         OSC UDP port (default 9951)
+  --refresh-rate     TUI refresh rate ms (default 200)
+  --poll             Poll loop states and shown controls every refresh,
+                     for engines that send no auto updates
+  --stale-after      Grey out positions and meters after this many auto
+                     update intervals without an update (default 10, 1 s;
+                     0 never)
+  --latency-warn     Highlight OSC round trips above ms (default 50)
+  --max-send-rate    Max level messages/s per loop (default 30)
+  --level-ramp       Ramp large level jumps over ms, e.g. 100 (default 0, off)
+  --meter-min-db     Bottom of the meter scale in dB (default -70)
+  --meter-max-db     Top of the meter scale in dB (default 0)
+  --meter-release    Meter fall rate in dB/s, 0 for raw (default 11.8)
+  --rms-window       RMS averaging window ms, 0 for peak only (default 300)
+  --sparkline-seconds  Meter history in sparkline view, s (default 10)
+  --meter-style      Meter bars: braille, block, or auto for braille where
+                     the terminal can show it (default auto)
+  --meter-colors     Meter colors: gradient, zones, or auto for a gradient
+                     where the terminal has true color (default auto)
+  --pos-style        Loop positions: number (seconds), clock (a glyph that
+                     fills up over the loop), cycles (the cycle count and a
+                     bar with a segment per cycle), or auto for clock on
+                     screens under 80 columns (default auto)
+  --level-max        Level sent when the Level bar is full (default 0.921)
+  --level-law        Level bar law: linear, log or iec (default linear)
+  --level-step       dB the level keys + and - move a selected Level cell
+                     (default 1)
+  --level-coarse-step
+                     dB PgUp and PgDn move a selected Level cell (default 6)
+  --cue-lead         Seconds before a loop's end its loop_end cue sounds
+                     (default 0.5)
+  --scene-ramp       Ramp scene recalls over ms (default 0, jump)
+  --autosave         Save the session every this many seconds and offer it
+                     back at the next start (default 10, 0 off)
+  --session-file     Session file
+                     (default $XDG_STATE_HOME/sooperGUI/session.json)
+  --scenes-file      Scene file
+                     (default $XDG_STATE_HOME/sooperGUI/scenes.json)
+  --audio-dir        Directory the L file browser opens in, w saves loops
+                     to and B bounces sessions to (default the current
+                     directory)
+  --fade-bars        Length of the d<loop> fade-out in bars (default 4)
+  --record-length    End records after this long, e.g. "4 cycles" or 8s
+                     (default off)
+  --count-in         Start records on the first bar line at least this many
+                     beats away (default 0, straight away)
+  --overdub-mode     Overdub keys, pedals and pads: latch (each press
+                     toggles) or momentary (only while held) (default latch)
+  --fade-control     Control the fade-out lowers: feedback or wet
+                     (default feedback)
+  --seek             What a click in a playing loop's Pos cell does: off,
+                     confirm (propose a seek, y or a second click makes it)
+                     or direct (default confirm)
+  --preview          Say in the status bar when a loop command from the
+                     palette takes effect under quantize and sync, and count
+                     down to it once sent (default true)
+  --verify-resend    Set a control once more when the engine does not echo
+                     the value set, before marking it unconfirmed
+  --mixer            Mixer preset for loop Levels: ardour, non-mixer,
+                     slmock or none (default slmock)
+  --mixer-config     Mixer config file (YAML), overrides --mixer
+  --setlist          Setlist file (YAML) for the song navigator
+  --click-control    Global engine control toggled by k to enable a click
+  --flash            Flash a loop's row in the color of its new state when it
+                     changes (default true; --flash=false to disable)
+  --follow           Select the loop whose state changed last, or the one
+                     recording (toggled with F)
+  --link             Show the tempo and peer count of the Ableton Link
+                     session on the network
+  --link-tempo       Set the engine's tempo to the Link session's (implies
+                     --link)
+  --spawn-engine     Run sooperlooper -p <osc-port> -l <engine-loops> as a
+                     child process, with its output in the log
+  --engine-cmd       Engine executable (default sooperlooper)
+  --engine-loops     Loops the spawned engine starts with (default 1)
+  --engine-restart   Restart the spawned engine when it exits
+                     (default true; --engine-restart=false to disable)
+  --config           Config file with the engine profile
+                     (default $XDG_CONFIG_HOME/sooperGUI/config.yaml)
+  --profile          Use this profile of the config file: its engine,
+                     panes and macros (switch from the palette, Ctrl+P)
+  --debug            Verbose logging, and redraw and OSC timings in the
+                     status bar
+  --log-file         Log file, rotated at 5 MB
+                     (default $XDG_STATE_HOME/sooperGUI/sooperGUI.log)
+  --state-debug      Add state debug column
+  --dev              Enable developer screens (F10: OSC inspector)
+  --demo             Run against a built-in fake engine (no SooperLooper)
+  --a11y             Screen reader mode: loops in words, and a bell on
+                     state changes
+  --a11y-announce    Command run with each announcement, e.g. spd-say
+  --render-once      Print the table once to stdout and exit
+  --render-format    Output of --render-once: plain or ansi (default plain)
+  --render-width     Width of --render-once output (default 80)
+  --tmux             Open in a new tmux window or pane instead of st:
+                     window or pane
+  --tmux-size        Height of the --tmux pane, lines or % (default 50%)
+  --bridge           Run without the TUI, serving the REST API
+  --http             Serve the REST API on this address
+                     (default off, 127.0.0.1:8080 with --bridge)
+  --pprof            Serve pprof and a runtime diagnostics page on this
+                     address, e.g. localhost:6060 (default off)
+  --listen-port      UDP port for engine replies and /gui messages
+                     (default 0, any free port)
+  --return-host      Host the engine sends replies to (default the address
+                     of the interface facing --osc-host)
+  --return-port      Port the engine sends replies to, when a NAT or
+                     container forwards it (default --listen-port)
+  --tunnel           Reach the engine through ssh to this user@host, which
+                     runs the engine and sooperGUI (default off)
+  --tunnel-cmd       sooperGUI on the --tunnel host (default sooperGUI)
+  --lang             Language of the TUI: en or de
+                     (default from $SOOPERGUI_LANG or the locale)
+  -h, --help         Show this help
+
+Commands:
+  export-layout      Write a TouchOSC or Open Stage Control layout with the
+                     loops, scenes and macros (export-layout -h for its
+                     options)
+  doctor             Check that the engine can be reached and that its
+                     replies arrive, and print a diagnosis (doctor -h for
+                     its options)`
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "export-layout" {
+		os.Exit(exportLayout(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(doctor(os.Args[2:]))
+	}
 	flag.StringVar(&oscHost, "osc-host", oscHost, "OSC host")
 	flag.IntVar(&oscPort, "osc-port", oscPort, "OSC UDP port")
+	flag.IntVar(&listenPort, "listen-port", listenPort, "UDP port for engine replies and /gui control messages (0 picks a free port)")
+	flag.StringVar(&returnHost, "return-host", returnHost, "Host the engine sends replies to, instead of the address of the interface facing --osc-host")
+	flag.IntVar(&returnPort, "return-port", returnPort, "Port the engine sends replies to, instead of --listen-port")
+	flag.StringVar(&tunnelTarget, "tunnel", tunnelTarget, "Reach the engine through ssh to this user@host, which runs the engine and sooperGUI")
+	flag.StringVar(&tunnelCommand, "tunnel-cmd", tunnelCommand, "sooperGUI on the --tunnel host")
+	flag.BoolVar(&tunnelServe, "tunnel-serve", tunnelServe, "Serve the far end of a --tunnel on stdin and stdout (run by ssh)")
 	flag.IntVar(&refreshRate, "refresh-rate", refreshRate, "TUI refresh rate in ms")
+	flag.BoolVar(&pollStates, "poll", pollStates, "Poll loop states and shown controls every refresh, for engines that send no auto updates")
+	flag.IntVar(&staleAfter, "stale-after", staleAfter, "Grey out positions and meters after this many auto update intervals without an update (0 never)")
+	flag.IntVar(&latencyWarnMs, "latency-warn", latencyWarnMs, "Highlight OSC round-trip times above this many ms")
+	flag.IntVar(&maxSendRate, "max-send-rate", maxSendRate, "Max level messages per second per loop")
+	flag.IntVar(&levelRampMs, "level-ramp", levelRampMs, "Ramp large level jumps over this many ms (0 disables)")
+	flag.Float64Var(&meterMinDB, "meter-min-db", meterMinDB, "Bottom of the meter scale in dB")
+	flag.Float64Var(&meterMaxDB, "meter-max-db", meterMaxDB, "Top of the meter scale in dB")
+	flag.Float64Var(&meterRelease, "meter-release", meterRelease, "Meter fall rate in dB/s (0 shows raw levels)")
+	flag.IntVar(&rmsWindowMs, "rms-window", rmsWindowMs, "RMS averaging window in ms (0 shows peak only)")
+	flag.IntVar(&sparkSeconds, "sparkline-seconds", sparkSeconds, "Meter history shown in sparkline view, in seconds")
+	flag.StringVar(&meterStyle, "meter-style", meterStyle, "Meter bars: braille, block, or auto for braille where the terminal can show it")
+	flag.StringVar(&meterColors, "meter-colors", meterColors, "Meter colors: gradient, zones, or auto for a gradient where the terminal has true color")
+	flag.StringVar(&posStyle, "pos-style", posStyle, "Loop positions: number, clock, cycles, or auto for clock on screens under 80 columns")
+	levelMaxFlag := flag.Float64("level-max", float64(levelMax), "Level sent when the Level bar is full")
+	flag.StringVar(&levelLawFlag, "level-law", levelLawFlag, "Level bar law: linear, log or iec")
+	flag.Float64Var(&levelStepDB, "level-step", levelStepDB, "dB the level keys + and - move a selected Level cell")
+	flag.Float64Var(&levelCoarseStepDB, "level-coarse-step", levelCoarseStepDB, "dB PgUp and PgDn move a selected Level cell")
+	flag.Float64Var(&cueLead, "cue-lead", cueLead, "Seconds before a loop's end its loop_end cue sounds")
 
+	flag.IntVar(&sceneRampMs, "scene-ramp", sceneRampMs, "Ramp scene recalls over this many ms (0 jumps)")
+	flag.StringVar(&scenesFile, "scenes-file", scenesFile, "Scene file (default $XDG_STATE_HOME/sooperGUI/scenes.json)")
+	flag.IntVar(&autosaveSeconds, "autosave", autosaveSeconds, "Save the session every this many seconds and offer it back at the next start (0 off)")
+	flag.StringVar(&sessionFile, "session-file", sessionFile, "Session file (default $XDG_STATE_HOME/sooperGUI/session.json)")
+	flag.StringVar(&audioDir, "audio-dir", audioDir, "Directory the file browser opens in and w saves loops to (default the current directory)")
+	flag.StringVar(&clickControl, "click-control", clickControl, "Global engine control that enables a click, toggled with k")
+	flag.BoolVar(&flashRows, "flash", flashRows, "Flash a loop's row in the color of its new state when it changes")
+	flag.BoolVar(&followAudio, "follow", followAudio, "Select the loop whose state changed last, or the one recording (toggled with F)")
+	flag.BoolVar(&linkListen, "link", linkListen, "Show the tempo and peer count of the Ableton Link session on the network")
+	flag.BoolVar(&linkTempo, "link-tempo", linkTempo, "Set the engine's tempo to the Link session's (implies --link)")
+	flag.IntVar(&fadeBars, "fade-bars", fadeBars, "Length of the d<loop> fade-out in bars")
+	flag.StringVar(&recordLengthFlag, "record-length", recordLengthFlag, "End records after this long, e.g. \"4 cycles\" or 8s (off leaves them running)")
+	flag.IntVar(&countInBeats, "count-in", countInBeats, "Start records on the first bar line at least this many beats away (0 records straight away)")
+	flag.StringVar(&overdubMode, "overdub-mode", overdubMode, "Overdub keys, pedals and pads: latch (each press toggles) or momentary (only while held)")
+	flag.StringVar(&fadeControl, "fade-control", fadeControl, "Control the fade-out lowers: feedback or wet")
+	flag.StringVar(&seekMode, "seek", seekMode, "What a click in a playing loop's Pos cell does: off, confirm or direct")
+	flag.BoolVar(&previewCommands, "preview", previewCommands, "Say when a loop command takes effect under quantize and sync, and count down to it once sent")
+	flag.BoolVar(&verifyResend, "verify-resend", verifyResend, "Set a control once more when the engine does not echo the value set, before marking it unconfirmed")
+	mixerFlag := flag.String("mixer", "slmock", "Mixer preset for loop Levels ("+mixerPresetNames()+") or none")
+	mixerConfigFile := flag.String("mixer-config", "", "Mixer config file (YAML), overrides --mixer")
+	flag.StringVar(&setlistFile, "setlist", setlistFile, "Setlist file (YAML) for the song navigator")
+	flag.BoolVar(&spawnEngine, "spawn-engine", spawnEngine, "Run the engine as a child process on --osc-port")
+	flag.StringVar(&engineCommand, "engine-cmd", engineCommand, "Engine executable for --spawn-engine")
+	flag.IntVar(&engineLoops, "engine-loops", engineLoops, "Loops the spawned engine starts with")
+	flag.BoolVar(&engineRestart, "engine-restart", engineRestart, "Restart the spawned engine when it exits")
 	debugFlag = flag.Bool("debug", false, "Verbose logging")
+	flag.StringVar(&configFile, "config", configFile, "Config file (default $XDG_CONFIG_HOME/sooperGUI/config.yaml)")
+	flag.StringVar(&profileName, "profile", profileName, "Profile of the config file to use")
+	flag.StringVar(&logFile, "log-file", logFile, "Log file (default $XDG_STATE_HOME/sooperGUI/sooperGUI.log)")
 	stateDebugFlag = flag.Bool("state-debug", false, "Show state column")
+	devFlag = flag.Bool("dev", false, "Enable developer screens (F10: OSC inspector)")
+	demoFlag = flag.Bool("demo", false, "Run against a built-in fake engine instead of SooperLooper")
+	flag.BoolVar(&a11yMode, "a11y", a11yMode, "Screen reader mode: loops in words, and announcements of state changes")
+	flag.StringVar(&a11yAnnounce, "a11y-announce", a11yAnnounce, "Command run with each --a11y announcement as its last argument, e.g. spd-say")
+	renderOnceFlag := flag.Bool("render-once", false, "Print the table once to stdout and exit")
+	renderFormat := flag.String("render-format", "plain", "Output of --render-once: plain or ansi")
+	renderWidth := flag.Int("render-width", 80, "Width of --render-once output")
+	tmuxFlag := flag.String("tmux", "", "Open in a new tmux window or pane instead of st: window or pane")
+	tmuxSize := flag.String("tmux-size", "50%", "Height of the --tmux pane, in lines or a percentage")
+	bridgeFlag := flag.Bool("bridge", false, "Run without the TUI, serving the REST API (for systemd)")
+	httpAddr := flag.String("http", "", "Serve the REST API on this address, e.g. 127.0.0.1:8080")
+	flag.StringVar(&pprofAddr, "pprof", pprofAddr, "Serve pprof and runtime diagnostics on this address, e.g. localhost:6060")
+
+	langFlag := flag.String("lang", "", "Language of the TUI: en or de (default from $SOOPERGUI_LANG or the locale)")
 
 	help := flag.Bool("help", false, "Show help")
 	flag.BoolVar(help, "h", false, "Show help (shorthand)")
 	flag.Parse()
 
+	if err := setLanguage(*langFlag); err != nil {
+		fmt.Fprintln(os.Stderr, "sooperGUI:", err)
+		os.Exit(2)
+	}
 	if *help {
-		fmt.Println(`Usage: sooperGUI [OPTIONS]
-  --osc-host         OSC host (default 127.0.0.1)
-  --osc-port         OSC UDP port (default 9951)
-  --refresh-rate     TUI refresh rate ms (default 200)
-  --debug            Verbose logging
-  --state-debug      Add state debug column
-  -h, --help         Show this help`)
+		fmt.Println(tr(usage))
 		os.Exit(0)
 	}
 
-	// Relaunch in st only if st exists and env not set
+	if *debugFlag {
+		logLevel.Set(slog.LevelDebug)
+	}
+	if err := setupLogging(logFile); err != nil {
+		logger.Warn("file logging disabled", "err", err)
+	}
+
+	levelMax = float32(*levelMaxFlag)
+	var err error
+	if lvlLaw, err = parseLevelLaw(levelLawFlag); err != nil {
+		fatal(logger, "invalid flag", "err", err)
+	}
+	if levelStepDB <= 0 || levelCoarseStepDB <= 0 {
+		fatal(logger, "--level-step and --level-coarse-step must be above 0", "step", levelStepDB, "coarse", levelCoarseStepDB)
+	}
+	linkListen = linkListen || linkTempo
+	if cueLead < 0 {
+		fatal(logger, "--cue-lead must not be negative", "value", cueLead)
+	}
+	if fadeControl != "feedback" && fadeControl != "wet" {
+		fatal(logger, "--fade-control must be feedback or wet", "value", fadeControl)
+	}
+	if seekMode != seekOff && seekMode != seekConfirm && seekMode != seekDirect {
+		fatal(logger, "--seek must be off, confirm or direct", "value", seekMode)
+	}
+	if meterStyle != "auto" && meterStyle != "braille" && meterStyle != "block" {
+		fatal(logger, "--meter-style must be auto, braille or block", "value", meterStyle)
+	}
+	if meterColors != "auto" && meterColors != "gradient" && meterColors != "zones" {
+		fatal(logger, "--meter-colors must be auto, gradient or zones", "value", meterColors)
+	}
+	if posStyle != "auto" && posStyle != "number" && posStyle != "clock" && posStyle != "cycles" {
+		fatal(logger, "--pos-style must be auto, number, clock or cycles", "value", posStyle)
+	}
+	if _, err := parseRecordLength(recordLengthFlag); err != nil {
+		fatal(logger, "--record-length", "err", err)
+	}
+	if countInBeats < 0 {
+		fatal(logger, "--count-in must not be negative", "value", countInBeats)
+	}
+	if returnPort < 0 || returnPort > 65535 {
+		fatal(logger, "--return-port must be 0 to 65535", "value", returnPort)
+	}
+	if tunnelTarget != "" && spawnEngine {
+		fatal(logger, "--tunnel and --spawn-engine cannot be used together")
+	}
+	if autosaveSeconds < 0 {
+		fatal(logger, "--autosave must not be negative", "value", autosaveSeconds)
+	}
+	if staleAfter < 0 {
+		fatal(logger, "--stale-after must not be negative", "value", staleAfter)
+	}
+	if overdubMode != "latch" && overdubMode != "momentary" {
+		fatal(logger, "--overdub-mode must be latch or momentary", "value", overdubMode)
+	}
+	if fadeBars < 1 {
+		fatal(logger, "--fade-bars must be at least 1", "value", fadeBars)
+	}
+	if engineLoops < 1 || engineLoops > maxLoops {
+		fatal(logger, "--engine-loops out of range", "value", engineLoops, "max", maxLoops)
+	}
+	if meterMinDB >= meterMaxDB {
+		fatal(logger, "--meter-min-db must be below --meter-max-db", "min", meterMinDB, "max", meterMaxDB)
+	}
+	if !(levelMax > 0) {
+		fatal(logger, "--level-max must be above 0", "value", levelMax)
+	}
+	if lvlLaw == lawLog && float64(levelMax) <= dbToAmp(meterMinDB) {
+		fatal(logger, "--level-max must be above --meter-min-db with --level-law log", "value", levelMax, "min_amplitude", dbToAmp(meterMinDB))
+	}
+
+	if tunnelServe {
+		// stderr goes back to the --tunnel end, which logs it.
+		console.Set(nil)
+		if err := serveTunnel(os.Stdin, os.Stdout, net.JoinHostPort(oscHost, strconv.Itoa(oscPort)), listenPort); err != nil {
+			fmt.Fprintln(os.Stderr, "sooperGUI:", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	levelThrottle = newSendThrottle(maxSendRate, time.Duration(levelRampMs)*time.Millisecond, func(loopID int, value float32) {
+		sl.SetStripGain(loopID, value)
+	})
+	masterThrottle = newMasterThrottle()
+
+	if *renderOnceFlag {
+		// Keep stderr quiet for status bar scripts; errors are reported below.
+		console.Set(nil)
+		startEngine(*demoFlag)
+		err := renderOnce(os.Stdout, *renderFormat, *renderWidth, 3*time.Second)
+		engineProc.stop()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "sooperGUI:", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Not for --render-once, which status bars run over and over.
+	named := configFile != ""
+	if !named {
+		configFile = defaultConfigPath()
+	}
+	if !*bridgeFlag && needsSetup(configFile, named) {
+		if err := runSetup(configFile); err != nil {
+			logger.Warn("setup not saved", "err", err)
+		}
+	}
+	if appConfig, err = loadConfig(configFile, named); err != nil {
+		fatal(logger, "config", "err", err)
+	}
+	profile, err := appConfig.useProfile(profileName)
+	if err != nil {
+		fatal(logger, "--profile", "err", err)
+	}
+	useEngine(appConfig.Engine, flag.CommandLine)
+	useEngine(profile.Engine, flag.CommandLine)
+	displayFlags.refreshRate, displayFlags.latencyWarnMs, displayFlags.staleAfter = refreshRate, latencyWarnMs, staleAfter
+	applyConfig(appConfig)
+	setHooks(appConfig.Hooks)
+
+	if *bridgeFlag {
+		if *httpAddr == "" {
+			*httpAddr = defaultHTTPAddr
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		services = newServiceGroup(ctx)
+		startMirrors(appConfig.Mirrors)
+		startHooks()
+		startEngine(*demoFlag)
+		startFootswitches(appConfig.Footswitches)
+		startMIDI(appConfig.MIDI, appConfig.Macros)
+		startMackie(appConfig.Mackie)
+		startMQTT(appConfig.MQTT)
+		startLink()
+		startDiag()
+		err := runBridge(*httpAddr)
+		stop()
+		if err != nil {
+			fatal(logger, "bridge", "err", err)
+		}
+		os.Exit(0)
+	}
+
+	// Relaunch in tmux or a terminal window unless we are the relaunched copy.
 	if os.Getenv("SOOPERGUI_XTERM") == "" {
-		if _, err := exec.LookPath("st"); err == nil {
-			self, err := os.Executable()
-			if err != nil {
-				errorLog.Fatalf("cannot find executable: %v", err)
-			}
-			args := os.Args[1:]
-			env := append(os.Environ(), "SOOPERGUI_XTERM=1")
-			cmd := exec.Command("st", "-f", "monospace:size=10", "-c", "sooperGUI", "-e", self)
-			cmd.Args = append(cmd.Args, args...)
-			cmd.Env = env
-			cmd.Stdout, cmd.Stderr, cmd.Stdin = os.Stdout, os.Stderr, os.Stdin
-			infoLog.Println("launching new st window…")
-			if err := cmd.Start(); err != nil {
-				errorLog.Fatalf("failed to launch st: %v", err)
-			}
-			go func() {
-				time.Sleep(time.Second)
-				if cmd.Process != nil {
-					_ = cmd.Process.Signal(syscall.SIGWINCH)
-				}
-			}()
-			cmd.Wait()
+		mode := *tmuxFlag
+		// The config file's tmux settings apply inside tmux only.
+		if mode == "" && os.Getenv("TMUX") != "" {
+			switch appConfig.Terminal {
+			case "tmux-window":
+				mode = "window"
+			case "tmux-pane":
+				mode = "pane"
+			}
+		}
+		if mode != "" {
+			if err := launchInTmux(mode, *tmuxSize); err != nil {
+				fatal(launcherLog, "tmux launch failed", "err", err)
+			}
 			os.Exit(0)
 		}
+		if appConfig.Terminal != "current" {
+			relaunchInTerminal()
+		}
 	}
 
 	if os.Getenv("SOOPERGUI_XTERM") != "" {
 		fmt.Print("\033]10;#00FF00\007\033]11;#000000\007")
-		ppid := os.Getppid()
-		if parent, _ := os.OpenFile(fmt.Sprintf("/proc/%d/fd/1", ppid), os.O_WRONLY, 0); parent != nil {
-			infoLog.SetOutput(parent)
+		// Mirror logs to the terminal that launched this window, where the
+		// platform allows it.
+		console.Set(host.parentConsole())
+	}
+
+	if scenesFile == "" {
+		scenesFile = defaultScenesPath()
+	}
+	if scenes, err = loadScenes(scenesFile); err != nil {
+		tuiLog.Warn("scenes not loaded", "err", err)
+	}
+	if sessionFile == "" {
+		sessionFile = defaultSessionPath()
+	}
+	// restoreOffer is the saved session until it is restored or declined.
+	var restoreOffer *session
+	if autosaveSeconds > 0 {
+		if restoreOffer, err = loadSession(sessionFile); err != nil {
+			tuiLog.Warn("session not loaded", "err", err)
 		}
-		if parent, _ := os.OpenFile(fmt.Sprintf("/proc/%d/fd/2", ppid), os.O_WRONLY, 0); parent != nil {
-			errorLog.SetOutput(parent)
+	}
+
+	switch {
+	case *mixerConfigFile != "":
+		cfg, err := loadMixerConfig(*mixerConfigFile)
+		if err != nil {
+			fatal(logger, "mixer config", "err", err)
+		}
+		extMixer = newMixer(cfg)
+	case *mixerFlag != "none":
+		cfg, err := mixerPreset(*mixerFlag)
+		if err != nil {
+			fatal(logger, "invalid flag", "err", err)
 		}
+		extMixer = newMixer(cfg)
+	}
+	if tunnelTarget != "" && extMixer != nil {
+		// Its messages would bypass the tunnel.
+		oscLog.Info("mixer not used through --tunnel")
+		extMixer = nil
 	}
 
-	listener, err := net.ListenPacket("udp", ":0")
-	if err != nil {
-		errorLog.Fatalf("udp listen: %v", err)
+	if setlistFile != "" {
+		if songs, err = loadSetlist(setlistFile); err != nil {
+			fatal(logger, "setlist", "err", err)
+		}
 	}
-	defer listener.Close()
 
-	localPort := listener.LocalAddr().(*net.UDPAddr).Port
-	returnIP := getLocalIP(oscHost)
-	returnURL := fmt.Sprintf("osc.udp://%s:%d", returnIP, localPort)
+	// The TUI ignores Ctrl+C; these quit it cleanly.
+	quitCtx, stopQuit := signal.NotifyContext(context.Background(), syscall.SIGHUP, syscall.SIGTERM)
+	defer stopQuit()
+	services = newServiceGroup(quitCtx)
+	startMirrors(appConfig.Mirrors)
+	startHooks()
+	startEngine(*demoFlag)
+	startFootswitches(appConfig.Footswitches)
+	startMIDI(appConfig.MIDI, appConfig.Macros)
+	startMackie(appConfig.Mackie)
+	startMQTT(appConfig.MQTT)
+	startLink()
+	startDiag()
+	if *httpAddr != "" {
+		goService("REST API", func(ctx context.Context) error { return serveHTTP(ctx, *httpAddr) })
+	}
 
-	client = osc.NewClient(oscHost, oscPort)
-	mockClient = osc.NewClient("127.0.0.1", 9090)
+	app := tview.NewApplication()
+	table := tview.NewTable().SetBorders(true).SetFixed(1, 0)
+	tableCells := &cellGrid{table: table}
+	historyView := tview.NewTextView()
+	historyView.SetBorder(true).SetTitle(" " + tr("History") + " ")
+	logView := tview.NewTextView().SetDynamicColors(false)
+	logView.SetBorder(true)
+	sceneView := tview.NewTextView()
+	sceneView.SetBorder(true).SetTitle(" " + tr("Scenes (F1–F9: recall, c: save)") + " ")
+	sceneName := tview.NewInputField().SetLabel(" " + tr("Save scene as:") + " ")
+	songView := tview.NewTextView()
+	songView.SetBorder(true).SetTitle(" " + tr("Songs (PgUp/PgDn: switch)") + " ")
+	crossfadeView := tview.NewTextView()
+	crossfadeView.SetBorder(true).SetTitle(" " + tr("Crossfade (a/b: pick scenes, [ ]: move)") + " ")
+	var crossfadeBarX, crossfadeBarW int
+	masterView := tview.NewTextView().SetDynamicColors(true)
+	masterView.SetBorder(true).SetTitle(" " + tr("Master (M: mute, { }: move)") + " ")
+	var masterBarX, masterBarW int
+	a11yView := tview.NewTextView()
+	var mixerView tview.Primitive = table
+	if a11yMode {
+		mixerView = a11yView
+	}
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).AddItem(mixerView, 0, 1, true)
+	detailHeader := tview.NewTextView().SetDynamicColors(true)
+	detailTable := tview.NewTable().SetSelectable(true, false)
+	detailCells := &cellGrid{table: detailTable}
+	detailHistory := tview.NewTextView()
+	detailHistory.SetBorder(true).SetTitle(" " + tr("State History") + " ")
+	loopView := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(detailHeader, 2, 0, false).
+		AddItem(detailTable, 0, 1, true).
+		AddItem(detailHistory, detailHistoryLines+2, 0, false)
+	loopView.SetBorder(true).SetTitle(" " + tr("Loop (<, >: other loop; ←, →: change; Enter: type a value)") + " ")
+	valueInput := tview.NewInputField()
+	globalsView := tview.NewTextView()
+	globalsView.SetBorder(true).SetTitle(" " + tr("Globals") + " ")
+	midiView := tview.NewTextView()
+	midiView.SetBorder(true).SetTitle(" " + tr("MIDI Bindings") + " ")
+	fullLogView := tview.NewTextView()
+	fullLogView.SetBorder(true)
+	pages := tview.NewPages()
+	for i, p := range []tview.Primitive{layout, loopView, globalsView, midiView, fullLogView} {
+		pages.AddPage(pageNames[i], p, true, i == pageMixer)
+	}
+	tabBar := tview.NewTextView().SetDynamicColors(true)
+	statusBar := tview.NewTextView().SetDynamicColors(true)
+	screen := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tabBar, 1, 0, false).
+		AddItem(pages, 0, 1, true).
+		AddItem(statusBar, 1, 0, false)
+	paletteInput := tview.NewInputField().SetLabel(" > ")
+	paletteList := tview.NewList().ShowSecondaryText(false).SetHighlightFullLine(true)
+	palette := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(paletteInput, 1, 0, true).
+		AddItem(paletteList, 0, 1, false)
+	palette.SetBorder(true).SetTitle(" " + tr("Commands (Enter: run, Esc: close)") + " ")
+	root := tview.NewPages().
+		AddPage("main", screen, true, true).
+		AddPage("palette", tview.NewGrid().SetColumns(0, 64, 0).SetRows(0, 20, 0).
+			AddItem(palette, 1, 1, 1, 1, 0, 0, true), true, false)
+	insp := newInspector(app)
+	showInspector := false
+	browserOpen := false
+	closeBrowser := func() {
+		browserOpen = false
+		root.HidePage("browser")
+		app.SetFocus(table)
+	}
+	browser := newFileBrowser(func(loop int, path string) {
+		closeBrowser()
+		loadLoopFile(loop, path)
+	}, closeBrowser)
+	root.AddPage("browser", tview.NewGrid().SetColumns(0, 100, 0).SetRows(0, 24, 0).
+		AddItem(browser.root, 1, 1, 1, 1, 0, 0, true), true, false)
+	// openBrowser opens the browser for loop i: in dir on sel, or where it
+	// was left when dir is "".
+	openBrowser := func(i int, dir, sel string) {
+		if dir == "" {
+			browser.open(i)
+		} else {
+			browser.openAt(i, dir, sel)
+		}
+		browserOpen = true
+		root.ShowPage("browser")
+		app.SetFocus(browser.list)
+	}
 
-	dispatcher := osc.NewStandardDispatcher()
-	dispatcher.AddMsgHandler("*", func(m *osc.Message) {
-		if *debugFlag {
-			infoLog.Printf("OSC IN %s %v", m.Address, m.Arguments)
+	resizeScr, err := newResizeScreen()
+	if err != nil {
+		fatal(tuiLog, "terminal", "err", err)
+	}
+	app.SetScreen(resizeScr)
+	var screenWidth int = 80
+	var drawScreen tcell.Screen
+	var updateTable func()
+	// tableNeeds is the narrowest the table as last laid out can get. A
+	// narrower screen would cut it off.
+	tableNeeds := 0
+	braille := meterStyle == "braille"
+	gradient := meterColors == "gradient"
+	app.SetBeforeDrawFunc(func(s tcell.Screen) bool {
+		w, h := s.Size()
+		if drawScreen == nil && meterStyle == "auto" {
+			braille = s.CanDisplay('⣿', false)
+			tuiLog.Debug("meter style", "braille", braille)
+		}
+		if drawScreen == nil && meterColors == "auto" {
+			gradient = s.Colors() >= 1<<24
+			tuiLog.Debug("meter colors", "gradient", gradient, "colors", s.Colors())
+		}
+		screenWidth, drawScreen = w, s
+		// The table was laid out for the old size; redo it now rather
+		// than at the next refresh.
+		if resizeScr.takeResize() {
+			tuiLog.Debug("terminal resized", "width", w, "height", h)
+			mu.Lock()
+			dropBarCaches()
+			mu.Unlock()
+			updateTable()
+		}
+		if !showInspector && (w < tableNeeds || h < minScreenHeight) {
+			drawTooSmall(s, tableNeeds, minScreenHeight)
+			return true
+		}
+		return false
+	})
+	app.SetAfterDrawFunc(func(s tcell.Screen) {
+		drawToasts(s, time.Now())
+	})
+	beep := func() {
+		queueUpdate(app, func() {
+			if drawScreen != nil {
+				drawScreen.Beep()
+			}
+		})
+	}
+	if a11yMode {
+		startAnnouncer(beep)
+	}
+	startCues(beep)
+
+	valueInput.SetDoneFunc(func(key tcell.Key) {
+		row, _ := detailTable.GetSelection()
+		if d := detailControls[row]; key == tcell.KeyEnter {
+			if v, err := d.parse(valueInput.GetText()); err != nil {
+				tuiLog.Warn("value not set", "err", err)
+			} else {
+				mu.Lock()
+				i := selectedLoop
+				mu.Unlock()
+				setDetailControl(i, d, v)
+			}
 		}
-		handleOSC(m)
+		screen.RemoveItem(valueInput)
+		app.SetFocus(detailTable)
 	})
-	server := &osc.Server{Addr: fmt.Sprintf(":%d", localPort), Dispatcher: dispatcher}
-	go func() {
-		infoLog.Printf("OSC server listening on %s", returnURL)
-		if err := server.Serve(listener); err != nil {
-			errorLog.Fatalf("osc server: %v", err)
+	// editDetail changes the selected Loop page control by dir steps.
+	editDetail := func(dir int) {
+		row, _ := detailTable.GetSelection()
+		d := detailControls[row]
+		mu.Lock()
+		i := selectedLoop
+		v, ok := detailValue(i, d)
+		mu.Unlock()
+		if ok {
+			setDetailControl(i, d, d.step(v, dir))
 		}
-	}()
+	}
 
-	sendPing(client, returnURL)
-	for i := 0; i < loopCount; i++ {
-		registerAutoUpdate(client, i, "loop_pos", returnURL, debugFlag)
-		registerAutoUpdate(client, i, "in_peak_meter", returnURL, debugFlag)
-		registerAutoUpdate(client, i, "out_peak_meter", returnURL, debugFlag)
+	// levelInput takes the Level of levelLoop in dB, absolute or, with
+	// levelRelative, added to the current Level.
+	levelInput := tview.NewInputField()
+	levelLoop, levelRelative := 0, false
+	var levelReturn tview.Primitive
+	labelLevelInput := func() {
+		if levelRelative {
+			levelInput.SetLabel(" " + trf("Loop %d Level change in ±dB (Tab: absolute):", levelLoop+1) + " ")
+		} else {
+			levelInput.SetLabel(" " + trf("Loop %d Level in dB (Tab: relative):", levelLoop+1) + " ")
+		}
+	}
+	levelInput.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		if ev.Key() != tcell.KeyTab {
+			return ev
+		}
+		levelRelative = !levelRelative
+		mu.Lock()
+		wet := getLoopState(levelLoop).Wet
+		mu.Unlock()
+		if levelRelative {
+			levelInput.SetText("")
+		} else {
+			levelInput.SetText(levelDBText(wet))
+		}
+		labelLevelInput()
+		return nil
+	})
+	levelInput.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			mu.Lock()
+			wet := getLoopState(levelLoop).Wet
+			mu.Unlock()
+			if v, err := parseLevelEntry(levelInput.GetText(), levelRelative, wet); err != nil {
+				tuiLog.Warn("level not set", "err", err)
+				notify(slog.LevelWarn, trf("Level not set: %v", err))
+			} else {
+				editLevel(levelLoop, v)
+			}
+		}
+		screen.RemoveItem(levelInput)
+		app.SetFocus(levelReturn)
+	})
+	// openLevelInput opens the field typing loop i's Level in dB.
+	openLevelInput := func(i int) {
+		mu.Lock()
+		wet, n := getLoopState(i).Wet, loopCount
+		mu.Unlock()
+		if i >= n {
+			return
+		}
+		levelLoop, levelRelative, levelReturn = i, false, app.GetFocus()
+		labelLevelInput()
+		levelInput.SetText(levelDBText(wet))
+		screen.AddItem(levelInput, 1, 0, true)
+		app.SetFocus(levelInput)
 	}
 
-	go func() {
-		for {
-			for i := 0; i < loopCount; i++ {
-				pollControl(client, i, "state", returnURL, debugFlag)
-				pollControl(client, i, "next_state", returnURL, debugFlag)
-				if mockClient != nil {
-					pollStripGain(mockClient, i+1, returnURL, debugFlag)
-				}
+	sceneName.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			if err := storeScene(strings.TrimSpace(sceneName.GetText())); err != nil {
+				tuiLog.Warn("scene not saved", "err", err)
+				notify(slog.LevelWarn, trf("Scene not saved: %v", err))
 			}
-			time.Sleep(time.Duration(refreshRate) * time.Millisecond)
 		}
-	}()
+		screen.RemoveItem(sceneName)
+		app.SetFocus(table)
+	})
 
-	app := tview.NewApplication()
-	table := tview.NewTable().SetBorders(true).SetFixed(1, 0)
+	// exportOpen is set while the form saving a loop is shown.
+	exportOpen := false
+	closeExport := func() {
+		exportOpen = false
+		root.RemovePage("export")
+		app.SetFocus(table)
+	}
+	openExport := func(i int) {
+		form := newExportForm(i, exportOptions, func(e exportSettings) {
+			exportOptions = e
+			closeExport()
+			saveLoopFile(i, e, func(path string) {
+				app.QueueUpdateDraw(func() {
+					mu.Lock()
+					sel := selectedLoop
+					mu.Unlock()
+					openBrowser(sel, filepath.Dir(path), filepath.Base(path))
+				})
+			})
+		}, closeExport)
+		exportOpen = true
+		root.AddPage("export", tview.NewGrid().SetColumns(0, 44, 0).SetRows(0, 11, 0).
+			AddItem(form, 1, 1, 1, 1, 0, 0, true), true, true)
+		app.SetFocus(form)
+	}
 
-	var screenWidth int = 80
-	app.SetBeforeDrawFunc(func(s tcell.Screen) bool {
-		w, _ := s.Size()
-		screenWidth = w
-		return false
+	// Keys that take a loop number 1–9 as a second key, e.g. d2.
+	loopKeys := map[rune]func(loop int){
+		'd': toggleFade,
+		'y': markCopySource,
+		'v': pasteLoop,
+		'w': openExport,
+		'=': openLevelInput,
+	}
+	var pendingLoopKey rune
+	// pendingPanic is set by ! until y confirms the panic.
+	pendingPanic := false
+
+	switchPage := func(n int) {
+		mu.Lock()
+		currentPage = n
+		mu.Unlock()
+		pages.SwitchToPage(pageNames[n])
+		if n == pageMixer {
+			app.SetFocus(table)
+		}
+	}
+	chord := chordInput{isVerb: func(r rune) bool {
+		_, verb := chordVerbs[r]
+		_, loopKey := loopKeys[r]
+		return verb || loopKey
+	}}
+	chordGen := 0
+	// endChord handles a chord left without a verb: a lone page number
+	// switches page.
+	endChord := func() {
+		if typed := chord.flush(); len(typed) == 1 && typed[0] >= '1' && typed[0] < '1'+byte(len(pageNames)) {
+			switchPage(int(typed[0] - '1'))
+		}
+	}
+	held := keyHold{queue: func(f func()) { app.QueueUpdate(f) }}
+	paneViews := map[string]struct {
+		view   tview.Primitive
+		height int
+	}{
+		"history":   {historyView, historyHeight},
+		"log":       {logView, logPaneHeight},
+		"scenes":    {sceneView, scenePaneHeight},
+		"songs":     {songView, songPaneHeight},
+		"crossfade": {crossfadeView, 3},
+		"master":    {masterView, 3},
+	}
+	showPane := func(name string) {
+		for _, p := range sessionPanes {
+			if p.name == name && !*p.shown {
+				*p.shown = true
+				layout.AddItem(paneViews[name].view, paneViews[name].height, 0, false)
+			}
+		}
+	}
+	for _, name := range profile.Panes {
+		showPane(name)
+	}
+	runChord := func(loops []int, verb rune) {
+		mu.Lock()
+		n := loopCount
+		mu.Unlock()
+		var hold []int
+		for _, i := range loops {
+			switch {
+			case i >= n:
+				tuiLog.Warn("no such loop", "loop", i+1)
+			case loopKeys[verb] != nil:
+				loopKeys[verb](i)
+			case momentary(chordVerbs[verb]):
+				hold = append(hold, i)
+			default:
+				hitLoop(i, chordVerbs[verb])
+			}
+		}
+		if hold != nil {
+			held.start(verb, chordVerbs[verb], hold)
+		}
+	}
+
+	var (
+		paletteAll, paletteShown []paletteAction
+		paletteOpen              bool
+		paletteReturn            tview.Primitive
+		handleKey                func(*tcell.EventKey) *tcell.EventKey
+	)
+	refreshPalette := func() {
+		paletteShown = filterPalette(paletteAll, paletteInput.GetText())
+		paletteList.Clear()
+		for _, a := range paletteShown {
+			paletteList.AddItem(fmt.Sprintf("%s  [gray]%s[-]", a.Name, a.keysLabel()), "", 0, nil)
+		}
+	}
+	openPalette := func() {
+		mu.Lock()
+		paletteAll = paletteActions()
+		mu.Unlock()
+		paletteInput.SetText("")
+		refreshPalette()
+		paletteOpen, paletteReturn = true, app.GetFocus()
+		root.ShowPage("palette")
+		app.SetFocus(paletteInput)
+	}
+	closePalette := func() {
+		paletteOpen = false
+		root.HidePage("palette")
+		app.SetFocus(paletteReturn)
+	}
+	paletteInput.SetChangedFunc(func(string) { refreshPalette() })
+	paletteInput.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+		switch ev.Key() {
+		case tcell.KeyUp:
+			paletteList.SetCurrentItem(max(paletteList.GetCurrentItem()-1, 0))
+		case tcell.KeyDown:
+			paletteList.SetCurrentItem(paletteList.GetCurrentItem() + 1)
+		default:
+			return ev
+		}
+		return nil
+	})
+	paletteInput.SetDoneFunc(func(key tcell.Key) {
+		i := paletteList.GetCurrentItem()
+		closePalette()
+		if key != tcell.KeyEnter || i >= len(paletteShown) {
+			return
+		}
+		a := paletteShown[i]
+		tuiLog.Debug("command", "name", a.Name)
+		if a.Run != nil {
+			a.Run()
+		}
+		for _, k := range a.Keys {
+			handleKey(k)
+		}
+		if chord.pending() {
+			endChord()
+		}
 	})
 
-	app.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+	handleKey = func(ev *tcell.EventKey) *tcell.EventKey {
 		if ev.Key() == tcell.KeyCtrlC {
 			return nil
 		}
+		if ev.Key() == tcell.KeyCtrlP && !showInspector && !browserOpen && !exportOpen {
+			if paletteOpen {
+				closePalette()
+			} else {
+				openPalette()
+			}
+			return nil
+		}
+		if paletteOpen || browserOpen || exportOpen {
+			return ev
+		}
+		if app.GetFocus() == sceneName || app.GetFocus() == valueInput || app.GetFocus() == levelInput {
+			return ev
+		}
+		if ev.Key() == tcell.KeyCtrlZ || ev.Key() == tcell.KeyCtrlY {
+			undoGUIChange(ev.Key() == tcell.KeyCtrlY)
+			return nil
+		}
+		if ev.Key() == tcell.KeyF10 && *devFlag {
+			showInspector = !showInspector
+			if showInspector {
+				insp.refresh()
+				app.SetRoot(insp.root, true)
+			} else {
+				app.SetRoot(root, true)
+			}
+			return nil
+		}
+		if showInspector {
+			return ev
+		}
+		if held.repeat(ev) {
+			return nil
+		}
+		if restoreOffer != nil {
+			offer := restoreOffer
+			restoreOffer = nil
+			if ev.Key() == tcell.KeyRune && ev.Rune() == 'y' {
+				restoreSession(offer, showPane, switchPage)
+			}
+			return nil
+		}
+		if pendingPanic {
+			pendingPanic = false
+			if ev.Key() == tcell.KeyRune && ev.Rune() == 'y' {
+				go runPanic()
+			}
+			return nil
+		}
+		if p := pendingSeek; p != nil {
+			pendingSeek = nil
+			if ev.Key() == tcell.KeyRune && ev.Rune() == 'y' {
+				seekLoop(p.loop, p.frac)
+			}
+			return nil
+		}
+		if pendingLoopKey != 0 {
+			action := loopKeys[pendingLoopKey]
+			pendingLoopKey = 0
+			if r := ev.Rune(); ev.Key() == tcell.KeyRune && r >= '1' && r <= '9' {
+				action(int(r - '1'))
+			}
+			return nil
+		}
+		if r := ev.Rune(); ev.Key() == tcell.KeyRune || chord.pending() {
+			if ev.Key() != tcell.KeyRune {
+				r = 0
+			}
+			switch step, loops, verb := chord.add(r); step {
+			case chordMore:
+				chordGen++
+				gen := chordGen
+				time.AfterFunc(chordTimeout, func() {
+					app.QueueUpdateDraw(func() {
+						if gen == chordGen && chord.pending() {
+							endChord()
+						}
+					})
+				})
+				return nil
+			case chordDone:
+				runChord(loops, verb)
+				return nil
+			}
+			if chord.pending() {
+				if ev.Key() == tcell.KeyEscape {
+					chord.flush()
+					return nil
+				}
+				endChord()
+			}
+		}
+		if _, ok := loopKeys[ev.Rune()]; ok && ev.Key() == tcell.KeyRune {
+			pendingLoopKey = ev.Rune()
+			return nil
+		}
+		if currentPage == pageLoop {
+			switch {
+			case ev.Rune() == '<' || ev.Rune() == '>':
+				mu.Lock()
+				if ev.Rune() == '<' {
+					selectLoop(selectedLoop - 1)
+				} else {
+					selectLoop(selectedLoop + 1)
+				}
+				mu.Unlock()
+				return nil
+			case ev.Key() == tcell.KeyLeft || ev.Key() == tcell.KeyRight:
+				dir := 1
+				if ev.Key() == tcell.KeyLeft {
+					dir = -1
+				}
+				editDetail(dir)
+				return nil
+			case ev.Key() == tcell.KeyEnter:
+				row, _ := detailTable.GetSelection()
+				switch d := detailControls[row]; d.Kind {
+				case detailNumber:
+					mu.Lock()
+					v, _ := detailValue(selectedLoop, d)
+					mu.Unlock()
+					valueInput.SetLabel(" " + trf("%s (%g to %g):", d.Name, d.Min, d.Max) + " ").SetText(d.format(v, true))
+					screen.AddItem(valueInput, 1, 0, true)
+					app.SetFocus(valueInput)
+				case detailToggle, detailChoice:
+					editDetail(1)
+				}
+				return nil
+			}
+		}
+		if currentPage == pageMixer && handleLevelKey(ev) {
+			return nil
+		}
+		if ev.Key() >= tcell.KeyF1 && ev.Key() < tcell.KeyF1+maxScenes {
+			recallScene(int(ev.Key() - tcell.KeyF1))
+			return nil
+		}
+		if (ev.Key() == tcell.KeyPgDn || ev.Key() == tcell.KeyPgUp) && len(songs.Songs) > 0 {
+			mu.Lock()
+			n := currentSong + 1
+			if ev.Key() == tcell.KeyPgUp {
+				n = currentSong - 1
+			}
+			mu.Unlock()
+			switchSong(n)
+			return nil
+		}
+		if ev.Key() == tcell.KeyF12 {
+			showLog = !showLog
+			if showLog {
+				layout.AddItem(logView, logPaneHeight, 0, false)
+			} else {
+				layout.RemoveItem(logView)
+			}
+			return nil
+		}
+		if ev.Key() == tcell.KeyRune {
+			switch ev.Rune() {
+			case 'f':
+				fineToggle = !fineToggle
+				return nil
+			case 's':
+				sparklineView = !sparklineView
+				return nil
+			case 'h':
+				showHistory = !showHistory
+				if showHistory {
+					layout.AddItem(historyView, historyHeight, 0, false)
+				} else {
+					layout.RemoveItem(historyView)
+				}
+				return nil
+			case 'p':
+				showScenes = !showScenes
+				if showScenes {
+					layout.AddItem(sceneView, scenePaneHeight, 0, false)
+				} else {
+					layout.RemoveItem(sceneView)
+				}
+				return nil
+			case 'm':
+				showMetronome = !showMetronome
+				return nil
+			case '!':
+				pendingPanic = true
+				return nil
+			case 'L':
+				mu.Lock()
+				i := selectedLoop
+				mu.Unlock()
+				openBrowser(i, "", "")
+				return nil
+			case 'B':
+				bounceSession(func(dir string) {
+					app.QueueUpdateDraw(func() {
+						mu.Lock()
+						sel := selectedLoop
+						mu.Unlock()
+						openBrowser(sel, dir, "")
+					})
+				})
+				return nil
+			case 'F':
+				mu.Lock()
+				followAudio = !followAudio
+				mu.Unlock()
+				return nil
+			case 'k':
+				if clickControl != "" {
+					toggleClick()
+					return nil
+				}
+			case 'n':
+				showSongs = !showSongs
+				if showSongs {
+					layout.AddItem(songView, songPaneHeight, 0, false)
+				} else {
+					layout.RemoveItem(songView)
+				}
+				return nil
+			case 'x':
+				showCrossfade = !showCrossfade
+				if showCrossfade {
+					layout.AddItem(crossfadeView, 3, 0, false)
+				} else {
+					layout.RemoveItem(crossfadeView)
+				}
+				return nil
+			case 'V':
+				showMaster = !showMaster
+				if showMaster {
+					layout.AddItem(masterView, 3, 0, false)
+				} else {
+					layout.RemoveItem(masterView)
+				}
+				return nil
+			case 'M':
+				toggleMasterMute()
+				return nil
+			case '{', '}':
+				if !showMaster {
+					break
+				}
+				step := masterStepDB
+				if ev.Rune() == '{' {
+					step = -step
+				}
+				mu.Lock()
+				level := master.Level
+				mu.Unlock()
+				setMaster(nudgeMaster(level, step))
+				return nil
+			case 'a', 'b', '[', ']':
+				if !showCrossfade {
+					break
+				}
+				mu.Lock()
+				pos := xfade.Pos
+				switch ev.Rune() {
+				case 'a':
+					xfade.A = nextSlot(xfade.A, scenes)
+				case 'b':
+					xfade.B = nextSlot(xfade.B, scenes)
+				}
+				mu.Unlock()
+				switch ev.Rune() {
+				case '[':
+					moveCrossfader(pos - crossfadeStep)
+				case ']':
+					moveCrossfader(pos + crossfadeStep)
+				}
+				return nil
+			case 'c':
+				sceneName.SetText("")
+				mu.Lock()
+				sceneName.SetPlaceholder(trf("Scene %d", len(scenes)+1))
+				mu.Unlock()
+				screen.AddItem(sceneName, 1, 0, true)
+				app.SetFocus(sceneName)
+				return nil
+			case 'l':
+				if showLog || currentPage == pageLog {
+					logPaneLevel = nextLogLevel(logPaneLevel)
+					return nil
+				}
+			case 'N':
+				if showLog || currentPage == pageLog {
+					showNotifications = !showNotifications
+					return nil
+				}
+			}
+		}
 		return ev
-	})
+	}
+	app.SetInputCapture(handleKey)
 
-	buttonDefs := map[string]ButtonState{
-		"RECORD": {
-			OnStates:       []int{2, 3},
-			PendingOnCond:  func(state, next int) bool { return state == 1 && (next == 4 || next == -1) },
-			PendingOffCond: func(state, next int) bool { return (state == 2 || state == 3) && next == 4 },
-		},
-		"OVERDUB": {
-			OnStates:       []int{5},
-			PendingOnCond:  func(state, next int) bool { return state == 4 && next == 5 },
-			PendingOffCond: func(state, next int) bool { return state == 5 && next == 4 },
-		},
-		"MUTE": {
-			OnStates:       []int{10, 20},
-			PendingOnCond:  func(state, next int) bool { return state == 4 && next == 10 },
-			PendingOffCond: func(state, next int) bool { return (state == 10 || state == 20) && next == 4 },
-		},
-	}
-
-	updateTable := func() {
+	updateTable = func() {
+		if showInspector {
+			insp.refresh()
+			return
+		}
 		mu.Lock()
 		defer mu.Unlock()
 
-		headers := []string{"ID", "Rec", "Dub", "Mute", "Pos", "Meter In", "Meter Out", "Level"}
-		fixedColWidths := []int{5, 8, 8, 8, 9}
-		if *stateDebugFlag {
-			headers = append(headers, "State Debug")
-			fixedColWidths = append(fixedColWidths, 14)
+		foldLoops()
+		now := time.Now()
+		selectLoop(selectedLoop)
+		tabBar.SetText(pageTabsText(currentPage, selectedLoop))
+		switch currentPage {
+		case pageLoop:
+			ls := getLoopState(selectedLoop)
+			detailHeader.SetText(detailHeaderText(selectedLoop, ls))
+			rows := make([][]cell, len(detailControls))
+			for r, d := range detailControls {
+				v, ok := ls.controls[d.Name]
+				rows[r] = detailRow(d, v, ok, 20)
+				if unconfirmedSets[setKey{selectedLoop, d.Name}] {
+					markUnconfirmed(&rows[r][1])
+				}
+			}
+			detailCells.set(rows)
+			detailHistory.SetText(loopHistoryText(&history, selectedLoop, detailHistoryLines))
+		case pageGlobals:
+			globalsView.SetText(globalsPageText())
+		case pageMIDI:
+			midiView.SetText(midiPageText(appConfig.MIDI))
+		case pageLog:
+			_, _, _, h := fullLogView.GetInnerRect()
+			var b strings.Builder
+			lines, title := logLines, trf("Log ≥%s (l: level, N: notifications)", logPaneLevel)
+			if showNotifications {
+				lines, title = notificationLog, trf("Notifications ≥%s (l: level, N: log)", logPaneLevel)
+			}
+			for _, l := range lines.tail(max(h, 1), logPaneLevel) {
+				b.WriteString(l.Text + "\n")
+			}
+			fullLogView.SetTitle(" " + title + " ")
+			fullLogView.SetText(b.String())
 		}
-		numCols := len(headers)
-
-		table.Clear()
-		fixedTotal := 0
-		for i := 0; i < numCols; i++ {
-			if i == 5 || i == 6 || i == 7 {
-				continue
+		loops := currentLoops()
+		if a11yMode {
+			a11yView.SetText(a11yText(loops))
+		} else {
+			opt := tableOptions{Width: screenWidth, Fine: fineToggle, Sparkline: sparklineView, StateDebug: *stateDebugFlag, Braille: braille, Gradient: gradient, PosClock: posClock(posStyle, screenWidth), PosCycles: posStyle == "cycles"}
+			opt.Flash = flashRows
+			if levelSelected || followAudio {
+				opt.Selected, opt.LevelSelected = selectedLoop+1, levelSelected
 			}
-			if i < len(fixedColWidths) {
-				fixedTotal += fixedColWidths[i]
+			rows := renderTable(opt, loops, now)
+			tableNeeds = minTableWidth(opt, rows)
+			tableCells.set(rows)
+		}
+
+		status := statusText(now)
+		if showMetronome {
+			status += "  " + metronomeStatus(now)
+		}
+		if s := syncStatus(now, appConfig.MIDI != nil); s != "" {
+			status += "  " + s
+		}
+		if s := linkStatus(now); s != "" {
+			status += "  " + s
+		}
+		if f := fadeStatus(); f != "" {
+			status += "  " + f
+		}
+		if copySource >= 0 {
+			status += "  " + trf("copy L%d", copySource+1)
+		}
+		if f := followStatus(); f != "" {
+			status += "  " + f
+		}
+		if w := waitStatus(now); w != "" {
+			status += "  " + w
+		}
+		if u := unconfirmedStatus(); u != "" {
+			status += "  " + u
+		}
+		if paletteOpen {
+			if n := paletteList.GetCurrentItem(); n < len(paletteShown) {
+				if p := paletteShown[n].preview(now); p != "" {
+					status += "  [yellow]" + p + "[-]"
+				}
 			}
 		}
-		meterCols := 3
-		meterWidth := screenWidth - fixedTotal - (numCols-1)*1
-		if meterWidth < 3*len("Meter In") {
-			meterWidth = 3 * len("Meter In")
+		if pendingLoopKey != 0 {
+			status += fmt.Sprintf("  [yellow]%c… %s[-]", pendingLoopKey, tr("loop 1–9?"))
+		}
+		if pendingPanic {
+			status += "  [red]" + tr("Panic: mute all loops? y/n") + "[-]"
 		}
-		meterWidthEach := meterWidth / meterCols
-		if meterWidthEach < 1 {
-			meterWidthEach = 1
+		if p := seekPrompt(); p != "" {
+			status += "  [yellow]" + p + "[-]"
 		}
+		if restoreOffer != nil {
+			status += "  [yellow]" + restorePrompt(restoreOffer) + "[-]"
+		}
+		if chord.pending() {
+			status += fmt.Sprintf("  [yellow]%s… %s[-]", chord.text(), tr("command?"))
+		}
+		if *debugFlag {
+			status += "  " + frameStatus()
+		}
+		statusBar.SetText(status)
 
-		bold := tcell.StyleDefault.Bold(true)
-		for i, h := range headers {
-			w := getColWidth(i, fixedColWidths, meterWidthEach)
-			cell := tview.NewTableCell(" " + h + " ").SetSelectable(false).SetStyle(bold).SetMaxWidth(w).SetAlign(tview.AlignCenter)
-			if i >= 5 && i <= 7 {
-				cell.SetExpansion(1)
+		if showHistory {
+			var b strings.Builder
+			for _, e := range history.recent(historyHeight - 2) {
+				b.WriteString(e.String() + "\n")
 			}
-			table.SetCell(0, i, cell)
+			historyView.SetText(b.String())
 		}
-
-		for i := 0; i < loopCount; i++ {
-			ls := loopStates[i]
-			if ls == nil {
-				ls = &LoopState{}
+		if showScenes {
+			sceneView.SetText(sceneListText(scenes))
+		}
+		if showSongs {
+			if len(songs.Songs) == 0 {
+				songView.SetText(" " + tr("No setlist. Start with --setlist <file>.") + "\n")
+			} else {
+				songView.SetText(setlistText(songs, currentSong))
 			}
-			row := i + 1
-			table.SetCell(row, 0, tview.NewTableCell(" "+strconv.Itoa(i+1)+" ").SetMaxWidth(fixedColWidths[0]).SetAlign(tview.AlignCenter))
-			table.SetCell(row, 1, buttonStateCell(ls.State, ls.NextState, fixedColWidths[1], buttonDefs["RECORD"]))
-			table.SetCell(row, 2, buttonStateCell(ls.State, ls.NextState, fixedColWidths[2], buttonDefs["OVERDUB"]))
-			table.SetCell(row, 3, buttonStateCell(ls.State, ls.NextState, fixedColWidths[3], buttonDefs["MUTE"]))
-			table.SetCell(row, 4, tview.NewTableCell(fmt.Sprintf(" %.2f ", ls.LoopPos)).SetMaxWidth(fixedColWidths[4]).SetAlign(tview.AlignCenter))
-			table.SetCell(row, 5, meterBarCell(ls.InPeakMeter, meterWidthEach))
-			table.SetCell(row, 6, meterBarCell(ls.OutPeakMeter, meterWidthEach))
-			table.SetCell(row, 7, meterBarCell(ls.Wet, meterWidthEach))
-			if *stateDebugFlag {
-				table.SetCell(row, 8, tview.NewTableCell(fmt.Sprintf("S:%d N:%d", ls.State, ls.NextState)).SetAlign(tview.AlignCenter))
+		}
+		if showCrossfade {
+			_, _, w, _ := crossfadeView.GetInnerRect()
+			var text string
+			text, crossfadeBarX, crossfadeBarW = crossfadeLine(xfade, scenes, w)
+			crossfadeView.SetText(text)
+		}
+		if showMaster {
+			_, _, w, _ := masterView.GetInnerRect()
+			var text string
+			text, masterBarX, masterBarW = masterLine(master, w)
+			masterView.SetText(text)
+		}
+		if showLog {
+			var b strings.Builder
+			lines, title := logLines, trf("Log ≥%s (l: level, N: notifications, F12: close)", logPaneLevel)
+			if showNotifications {
+				lines, title = notificationLog, trf("Notifications ≥%s (l: level, N: log, F12: close)", logPaneLevel)
+			}
+			for _, l := range lines.tail(logPaneHeight-2, logPaneLevel) {
+				b.WriteString(l.Text + "\n")
 			}
+			logView.SetTitle(" " + title + " ")
+			logView.SetText(b.String())
 		}
 	}
 
+	var (
+		dragRow    int
+		fineActive bool
+		fineX      int
+		fineFill   float32
+	)
 	table.SetMouseCapture(func(action tview.MouseAction, ev *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
-		if action != tview.MouseLeftClick && action != tview.MouseLeftDown && action != tview.MouseMove {
-			return action, ev
-		}
 		x, y := ev.Position()
-		row, col, ok := tableCoordinatesAt(table, x, y)
-		if !ok || row == 0 {
+		row := dragRow
+		switch action {
+		case tview.MouseLeftUp:
+			dragRow = 0
+			return action, ev
+		case tview.MouseScrollUp, tview.MouseScrollDown:
+			// Wheel over a Level bar adjusts it; anywhere else the table
+			// scrolls its rows.
+			r, col, ok := tableCoordinatesAt(table, x, y)
+			if !ok || r == 0 || col != colLevel || r > loopCount {
+				return action, ev
+			}
+			step := wheelStepDB
+			if ev.Modifiers()&tcell.ModCtrl != 0 {
+				step = fineNudgeDB
+			}
+			if action == tview.MouseScrollDown {
+				step = -step
+			}
+			mu.Lock()
+			wet := getLoopState(r - 1).Wet
+			mu.Unlock()
+			editLevel(r-1, nudgeLevel(wet, step))
+			return action, nil
+		case tview.MouseLeftDown, tview.MouseLeftClick:
+			r, col, ok := tableCoordinatesAt(table, x, y)
+			if ok && r > 0 && col == colID && r <= loopCount {
+				mu.Lock()
+				selectLoop(r - 1)
+				mu.Unlock()
+			}
+			if ok && r > 0 && col == colPos && r <= loopCount && action == tview.MouseLeftClick {
+				if cx, _, w := table.GetCell(r, colPos).GetLastPosition(); w > 0 {
+					clickPos(r-1, (float32(x-cx)+0.5)/float32(w))
+				}
+				return action, nil
+			}
+			if !ok || r == 0 || col != colLevel || r > loopCount {
+				return action, ev
+			}
+			if levelSelected {
+				mu.Lock()
+				selectLoop(r - 1)
+				mu.Unlock()
+			}
+			row, fineActive = r, false
+			if action == tview.MouseLeftDown {
+				dragRow = r
+			}
+		case tview.MouseMove:
+			if row == 0 || ev.Buttons()&tcell.Button1 == 0 {
+				return action, ev
+			}
+		default:
 			return action, ev
 		}
-		if col != 7 || row > loopCount {
+
+		cellContentX, _, cellContentWidth := table.GetCell(row, colLevel).GetLastPosition()
+		if cellContentWidth <= 0 {
 			return action, ev
 		}
-		cellContentX, _, cellContentWidth := table.GetCell(row, col).GetLastPosition()
-		relX := x - cellContentX
 		var fill float32
-		if cellContentWidth > 0 {
-			fill = float32(relX) / float32(cellContentWidth)
+		if fineToggle || ev.Modifiers()&tcell.ModShift != 0 {
+			if !fineActive {
+				fineActive, fineX = true, x
+				mu.Lock()
+				fineFill = lvlLaw.fill(getLoopState(row-1).Wet, levelMax, meterMinDB)
+				mu.Unlock()
+			}
+			fill = fineFill + float32(x-fineX)/float32(cellContentWidth)/fineRatio
+		} else {
+			fineActive = false
+			fill = float32(x-cellContentX) / float32(cellContentWidth)
 		}
-		if fill < 0 {
-			fill = 0
+		editLevel(row-1, lvlLaw.amplitude(fill, levelMax, meterMinDB))
+		return action, ev
+	})
+
+	crossfadeView.SetMouseCapture(func(action tview.MouseAction, ev *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
+		switch action {
+		case tview.MouseLeftDown, tview.MouseLeftClick, tview.MouseMove:
+		default:
+			return action, ev
 		}
-		if fill > 1 {
-			fill = 1
+		if action == tview.MouseMove && ev.Buttons()&tcell.Button1 == 0 {
+			return action, ev
 		}
-		const maxWet = 0.921
-		wet := fill * maxWet
-		if wet > maxWet {
-			wet = maxWet
+		x, _ := ev.Position()
+		innerX, _, _, _ := crossfadeView.GetInnerRect()
+		if crossfadeBarW > 1 {
+			moveCrossfader(float32(x-innerX-crossfadeBarX) / float32(crossfadeBarW-1))
 		}
-		mu.Lock()
-		if loopStates[row-1] != nil {
-			loopStates[row-1].Wet = wet
+		return action, nil
+	})
+
+	masterView.SetMouseCapture(func(action tview.MouseAction, ev *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
+		switch action {
+		case tview.MouseLeftDown, tview.MouseLeftClick, tview.MouseMove:
+		default:
+			return action, ev
 		}
-		mu.Unlock()
-		go func(loopID int, value float32) {
-			addr := fmt.Sprintf("/strip/Sooper%d/Gain/Gain%%20(dB)", loopID)
-			m := osc.NewMessage(addr)
-			m.Append(value)
-			if mockClient != nil {
-				_ = mockClient.Send(m)
-			}
-		}(row, wet)
-		return action, ev
+		if action == tview.MouseMove && ev.Buttons()&tcell.Button1 == 0 {
+			return action, ev
+		}
+		x, _ := ev.Position()
+		innerX, _, _, _ := masterView.GetInnerRect()
+		if masterBarW > 0 {
+			fill := float32(x-innerX-masterBarX) / float32(masterBarW)
+			setMaster(lvlLaw.amplitude(min(max(fill, 0), 1), 1, meterMinDB))
+		}
+		return action, nil
 	})
 
-	go func() {
+	every := time.Duration(refreshRate) * time.Millisecond
+	goService("refresh", func(ctx context.Context) error {
+		t := time.NewTicker(every)
+		defer t.Stop()
 		for {
-			app.QueueUpdateDraw(updateTable)
-			time.Sleep(time.Duration(refreshRate) * time.Millisecond)
+			// The rate changes with the config file's.
+			rate := every
+			queueUpdate(app, func() {
+				start := time.Now()
+				updateTable()
+				drawn := time.Now()
+				app.ForceDraw()
+				done := time.Now()
+				tableTimes.add(drawn.Sub(start))
+				drawTimes.add(done.Sub(drawn))
+				mu.Lock()
+				rate = time.Duration(refreshRate) * time.Millisecond
+				mu.Unlock()
+				checkFrameBudget(done.Sub(start), rate, done)
+			})
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-t.C:
+			}
+			if rate != every {
+				every = rate
+				t.Reset(every)
+			}
+		}
+	})
+	goService("config watcher", func(ctx context.Context) error {
+		// Reloading is a convenience: the TUI runs on without it.
+		if err := watchConfig(ctx, configFile, func() { reloadConfig(configFile, named) }); err != nil {
+			tuiLog.Info("config file not watched", "err", err)
 		}
-	}()
+		return nil
+	})
+	// A quit signal or a failed service stops the TUI.
+	goService("quit", func(ctx context.Context) error {
+		<-ctx.Done()
+		// Queued, so a TUI that has not started yet stops as it starts.
+		go app.QueueUpdate(app.Stop)
+		return nil
+	})
+
+	tuiLog.Info("TUI running – press Ctrl+C (ignored) or close window to quit")
+	if os.Getenv("SOOPERGUI_XTERM") == "" {
+		// The TUI owns this terminal now; keep logging to the file only.
+		console.Set(nil)
+	}
+	if autosaveSeconds > 0 {
+		goService("autosave", func(ctx context.Context) error {
+			autosave(ctx, sessionFile, func() (session, bool) {
+				var s session
+				ok := false
+				queueUpdate(app, func() {
+					if restoreOffer == nil {
+						mu.Lock()
+						s, ok = captureSession(time.Time{}), true
+						mu.Unlock()
+					}
+				})
+				return s, ok
+			})
+			return nil
+		})
+	}
+	err = app.SetRoot(root, true).EnableMouse(true).Run()
+	failed := stopServices()
+	engineProc.stop()
+	if autosaveSeconds > 0 && restoreOffer == nil && failed == nil {
+		saveCleanSession(sessionFile)
+	}
+	if err != nil {
+		console.Set(os.Stderr)
+		fatal(tuiLog, "tview", "err", err)
+	}
+	if failed != nil {
+		console.Set(os.Stderr)
+		fatal(tuiLog, "stopped", "err", failed)
+	}
+	if nextProfile != "" {
+		console.Set(os.Stderr)
+		fatal(tuiLog, "restart", "profile", nextProfile, "err", restartWithProfile(nextProfile))
+	}
+}
 
-	infoLog.Println("TUI running – press Ctrl+C (ignored) or close window to quit")
-	if err := app.SetRoot(table, true).EnableMouse(true).Run(); err != nil {
-		errorLog.Fatalf("tview: %v", err)
+func startEngine(demo bool) {
+	switch {
+	case demo:
+		goService("demo engine", runDemo)
+	case spawnEngine:
+		startEngineProcess()
+		connectEngine()
+	default:
+		connectEngine()
+	}
+}
+
+// connectEngine starts the OSC server for replies, then pings, registers
+// for and polls the engine in the background. With --tunnel it first
+// connects the tunnel, which the engine is then reached through.
+func connectEngine() {
+	host, port := oscHost, oscPort
+	var tun *tunnel
+	if tunnelTarget != "" {
+		var err error
+		if tun, err = startTunnel(tunnelTarget, oscPort); err != nil {
+			fatal(oscLog, "tunnel", "err", err)
+		}
+		host, port = "127.0.0.1", tun.port()
+		returnHost, returnPort = "127.0.0.1", tun.remotePort
+	}
+	c, err := newSLClient(fmt.Sprintf(":%d", listenPort), host, port, extMixer, handleOSC)
+	if err != nil {
+		fatal(oscLog, "udp listen", "err", err)
 	}
+	if tun != nil {
+		tun.deliverTo(c.conn.LocalAddr())
+	}
+	c.profile = appConfig.Profile
+	c.macros = appConfig.Macros
+	sl = c
+	goService("engine", c.Run)
 }
 
 // --- TUI helpers -------------------------------------------------------------
 
-func meterBarCell(val float32, width int) *tview.TableCell {
-	fill := amplitudeToMeterFill(val, meterMinDB, meterMaxDB)
-	fullChars := int(math.Ceil(float64(fill) * float64(width)))
-	if fullChars < 0 {
-		fullChars = 0
+func statusText(now time.Time) string {
+	if *demoFlag {
+		return " [yellow]" + tr("demo engine") + "[-]"
+	}
+	host := oscHost
+	if tunnelTarget != "" {
+		host = "ssh " + tunnelTarget
 	}
-	if fullChars > width {
-		fullChars = width
+	text := fmt.Sprintf(" osc %s:%d  ", host, oscPort)
+	rtt, ok := latency.current(now)
+	switch {
+	case !ok && a11yMode:
+		text += "[red]RTT – " + tr("(no reply)") + "[-]"
+	case !ok:
+		text += "[red]RTT –[-]"
+	case rtt > time.Duration(latencyWarnMs)*time.Millisecond && a11yMode:
+		text += fmt.Sprintf("[red]RTT %.1f ms %s[-]", float64(rtt)/float64(time.Millisecond), tr("(slow)"))
+	case rtt > time.Duration(latencyWarnMs)*time.Millisecond:
+		text += fmt.Sprintf("[red]RTT %.1f ms[-]", float64(rtt)/float64(time.Millisecond))
+	default:
+		text += fmt.Sprintf("[green]RTT %.1f ms[-]", float64(rtt)/float64(time.Millisecond))
 	}
+	if loss := packets.lossText(now); ok && loss != "" {
+		text += "  " + loss
+	}
+	return text
+}
 
-	var color tcell.Color
+func meterColor(fill float32) tcell.Color {
 	switch {
 	case fill < greenThreshold:
-		color = tcell.ColorGreen
+		return tcell.ColorGreen
 	case fill < yellowThreshold:
-		color = tcell.ColorYellow
-	default:
-		color = tcell.ColorRed
+		return tcell.ColorYellow
 	}
+	return tcell.ColorRed
+}
+
+func setLevel(idx int, wet float32) {
+	if wet < 0 {
+		wet = 0
+	}
+	if wet > levelMax {
+		wet = levelMax
+	}
+	mu.Lock()
+	getLoopState(idx).Wet = wet
+	gain := stripGain(wet)
+	mu.Unlock()
+	levelThrottle.Set(idx+1, gain)
+}
 
-	bar := strings.Repeat("█", fullChars) + strings.Repeat(" ", width-fullChars)
-	return tview.NewTableCell(bar).SetTextColor(color).SetAlign(tview.AlignLeft)
+func nudgeLevel(wet float32, db float64) float32 {
+	floor := float32(math.Pow(10, meterMinDB/20))
+	if wet < floor {
+		if db < 0 {
+			return 0
+		}
+		wet = floor
+	}
+	v := float32(float64(wet) * math.Pow(10, db/20))
+	if v < floor {
+		return 0
+	}
+	if v > levelMax {
+		v = levelMax
+	}
+	return v
 }
 
 func amplitudeToMeterFill(val float32, minDB, maxDB float64) float32 {
@@ -379,16 +1791,6 @@ func amplitudeToMeterFill(val float32, minDB, maxDB float64) float32 {
 	return float32((db - minDB) / (maxDB - minDB))
 }
 
-func getColWidth(col int, fixed []int, meter int) int {
-	if col == 5 || col == 6 || col == 7 {
-		return meter
-	}
-	if col < len(fixed) {
-		return fixed[col]
-	}
-	return 10
-}
-
 func tableCoordinatesAt(t *tview.Table, x, y int) (row, col int, ok bool) {
 	ok = false
 	// Iterate over all cells to find which one contains the coordinates (x, y)
@@ -402,7 +1804,7 @@ func tableCoordinatesAt(t *tview.Table, x, y int) (row, col int, ok bool) {
 			}
 			// GetLastPosition likely returns x, y, width of the cell's content.
 			cellX, cellY, cellWidth := tableCell.GetLastPosition()
-			
+
 			// Assuming cell height is 1 for click detection purposes (y must match cellY).
 			if x >= cellX && x < cellX+cellWidth && y == cellY {
 				row, col, ok = r_idx, c_idx, true
@@ -413,22 +1815,6 @@ func tableCoordinatesAt(t *tview.Table, x, y int) (row, col int, ok bool) {
 	return
 }
 
-func buttonStateCell(state, next, width int, def ButtonState) *tview.TableCell {
-	label := "OFF"
-	color := tcell.ColorRed
-
-	switch {
-	case def.PendingOnCond(state, next):
-		label, color = "ON", tcell.ColorYellow
-	case def.PendingOffCond(state, next):
-		label, color = "OFF", tcell.ColorYellow
-	case containsInt(def.OnStates, state):
-		label, color = "ON", tcell.ColorGreen
-	}
-
-	return tview.NewTableCell(" " + label + " ").SetTextColor(color).SetAlign(tview.AlignCenter).SetMaxWidth(width)
-}
-
 func containsInt(arr []int, v int) bool {
 	for _, x := range arr {
 		if x == v {
@@ -456,127 +1842,230 @@ func getLocalIP(host string) string {
 	return "127.0.0.1"
 }
 
-func sendPing(c *osc.Client, returnURL string) {
+func sendPing(c oscSender, returnURL, replyPath string) {
 	m := osc.NewMessage("/ping")
 	m.Append(returnURL)
-	m.Append("/pong")
-	_ = c.Send(m)
+	m.Append(replyPath)
+	oscSend(c, m)
 }
 
-func registerAutoUpdate(c *osc.Client, loop int, control, returnURL string, dbg *bool) {
+func registerAutoUpdate(c oscSender, loop int, control, returnURL string) {
 	path := fmt.Sprintf("/sl/%d/register_auto_update", loop)
 	m := osc.NewMessage(path)
 	m.Append(control)
-	m.Append(int32(100))
+	m.Append(int32(autoUpdateInterval / time.Millisecond))
 	m.Append(returnURL)
 	m.Append(fmt.Sprintf("/sl/%d/update_%s", loop, control))
-	if *dbg {
-		infoLog.Printf("OSC OUT %s %v", path, m.Arguments)
-	}
-	_ = c.Send(m)
+	oscSend(c, m)
 }
 
-func pollControl(c *osc.Client, loop int, control, returnURL string, dbg *bool) {
-	m := osc.NewMessage(fmt.Sprintf("/sl/%d/get", loop))
+func unregisterAutoUpdate(c oscSender, loop int, control, returnURL string) {
+	m := osc.NewMessage(fmt.Sprintf("/sl/%d/unregister_auto_update", loop))
 	m.Append(control)
 	m.Append(returnURL)
 	m.Append(fmt.Sprintf("/sl/%d/update_%s", loop, control))
-	if *dbg {
-		infoLog.Printf("OSC OUT poll %s", control)
-	}
-	_ = c.Send(m)
+	oscSend(c, m)
 }
 
-func pollStripGain(c *osc.Client, loopID int, returnURL string, dbg *bool) {
-	if c == nil {
-		return
-	}
-	m := osc.NewMessage("/get_strip_gain")
-	m.Append(int32(loopID))
+// registerUpdate asks for control to be sent whenever it changes, e.g. from
+// SooperLooper's own GUI.
+func registerUpdate(c oscSender, loop int, control, returnURL string) {
+	m := osc.NewMessage(fmt.Sprintf("/sl/%d/register_update", loop))
+	m.Append(control)
 	m.Append(returnURL)
-	m.Append(fmt.Sprintf("/strip/Sooper%d/Gain/Gain%%20(dB)", loopID))
-	if *dbg {
-		infoLog.Printf("OSC OUT poll strip gain %d", loopID)
-	}
-	_ = c.Send(m)
+	m.Append(fmt.Sprintf("/sl/%d/update_%s", loop, control))
+	oscSend(c, m)
+}
+
+func setControl(c oscSender, loop int, control string, value float32) {
+	m := osc.NewMessage(fmt.Sprintf("/sl/%d/set", loop))
+	m.Append(control)
+	m.Append(value)
+	oscSend(c, m)
+}
+
+func pollControl(c oscSender, loop int, control, returnURL string) {
+	m := osc.NewMessage(fmt.Sprintf("/sl/%d/get", loop))
+	m.Append(control)
+	m.Append(returnURL)
+	m.Append(fmt.Sprintf("/sl/%d/update_%s", loop, control))
+	oscSend(c, m)
 }
 
 func handleOSC(msg *osc.Message) {
+	defer oscTimes.since(time.Now())
+	if i, ok := queueLoopUpdate(msg); ok {
+		// Applied now if nothing holds mu, else by its holder's next fold.
+		if i >= 0 && mu.TryLock() {
+			foldLoop(i)
+			mu.Unlock()
+		}
+		return
+	}
 	mu.Lock()
 	defer mu.Unlock()
 
-	switch {
-	case stripGainPathRegex.MatchString(msg.Address):
-		matches := stripGainPathRegex.FindStringSubmatch(msg.Address)
-		if len(matches) > 1 {
-			id, _ := strconv.Atoi(matches[1])
-			idx := id - 1
-			if idx >= 0 && len(msg.Arguments) == 1 {
-				switch v := msg.Arguments[0].(type) {
-				case float32:
-					getLoopState(idx).Wet = v
-				case float64:
-					getLoopState(idx).Wet = float32(v)
-				}
+	if extMixer.isFeedback(msg.Address) {
+		if id, v, ok := extMixer.feedback(msg); ok && validLoopIndex(id-1) {
+			if wet, ok := reportedLevel(v); ok {
+				getLoopState(id - 1).Wet = wet
 			}
 		}
-	case msg.Address == "/pong":
-		if len(msg.Arguments) >= 3 {
-			if v, ok := msg.Arguments[2].(int32); ok {
-				loopCount = int(v)
+		return
+	}
+	engineRoutes.dispatch(msg)
+}
+
+// engineRoutes dispatch the engine's replies and updates for handleOSC.
+var engineRoutes = newEngineRoutes()
+
+func newEngineRoutes() *oscRouter {
+	r := newOSCRouter()
+	r.handle("/pong", handlePong)
+	r.handlePrefix(pongPrefix, func(msg *osc.Message, _ string) { handlePong(msg) })
+	r.handlePrefix(errorPrefix, func(msg *osc.Message, _ string) { engineErrorReply(msg.Address, msg.Arguments) })
+	r.handlePrefix(globalUpdatePrefix, func(msg *osc.Message, ctrl string) {
+		if len(msg.Arguments) >= 3 && msg.Arguments[1] == ctrl && slices.Contains(polledGlobals, ctrl) {
+			if v, ok := argFloat(msg.Arguments[2]); ok {
+				globals[ctrl] = v
 			}
 		}
-	case strings.Contains(msg.Address, "/update_state"):
-		commonUpdate(msg, "state", func(ls *LoopState, v float32) { ls.State = int(v) })
-	case strings.Contains(msg.Address, "/update_next_state"):
-		commonUpdate(msg, "next_state", func(ls *LoopState, v float32) { ls.NextState = int(v) })
-	case strings.Contains(msg.Address, "/update_loop_pos"):
-		commonUpdate(msg, "loop_pos", func(ls *LoopState, v float32) { ls.LoopPos = v })
-	case strings.Contains(msg.Address, "/update_in_peak_meter"):
-		commonUpdate(msg, "in_peak_meter", func(ls *LoopState, v float32) { ls.InPeakMeter = v })
-	case strings.Contains(msg.Address, "/update_out_peak_meter"):
-		commonUpdate(msg, "out_peak_meter", func(ls *LoopState, v float32) { ls.OutPeakMeter = v })
-	case strings.Contains(msg.Address, "/update_wet"):
-		commonUpdate(msg, "wet", func(ls *LoopState, v float32) { ls.Wet = v })
-	}
+	})
+	return r
 }
 
-func commonUpdate(msg *osc.Message, ctrl string, apply func(*LoopState, float32)) {
-	if len(msg.Arguments) < 3 {
-		return
+func handlePong(msg *osc.Message) {
+	if now := time.Now(); latency.reply(msg.Address, now) {
+		packets.pong(msg.Address, now)
 	}
-	loopIdx := parseLoopIndex(msg.Address)
-	if idx, ok := msg.Arguments[0].(int32); !ok || int(idx) != loopIdx {
-		return
+	if len(msg.Arguments) >= 3 {
+		if v, ok := argInt(msg.Arguments[2]); ok && v >= 0 {
+			if v < loopCount {
+				dropLoops(v)
+			}
+			loopCount = min(v, maxLoops)
+		}
 	}
-	if c, ok := msg.Arguments[1].(string); !ok || c != ctrl {
-		return
+}
+
+// loopUpdate applies a loop control's update to the loop at index i.
+type loopUpdate func(ls *LoopState, i int, v float32)
+
+// loopUpdates are the updates of the controls that need more than
+// storing, by control. Meters are applied by applyUpdate.
+var loopUpdates = map[string]loopUpdate{
+	"state":      updateLoopState,
+	"next_state": func(ls *LoopState, _ int, v float32) { ls.NextState = slstate.State(v) },
+	"loop_pos": func(ls *LoopState, i int, v float32) {
+		cueLoopPos(ls, ls.LoopPos, v)
+		ls.LoopPos = v
+		scheduleRecordStop(i, ls)
+	},
+}
+
+func updateLoopState(ls *LoopState, i int, v float32) {
+	if ls.haveState && slstate.State(v) != ls.State {
+		e := stateEvent{At: time.Now(), Loop: i, From: ls.State, To: slstate.State(v)}
+		history.record(e)
+		followLoop(e.Loop, e.To)
+		ls.flashUntil = e.At.Add(flashTime)
+		cue(e.To.String())
+		stateHooks(e)
+		oscLog.Info("loop state", "loop", e.Loop+1, "from", e.From, "to", e.To)
+		if a11yMode {
+			announceState(e)
+		}
+		if ls.recordStop != nil {
+			ls.recordStop.Stop()
+			ls.recordStop = nil
+		}
+		if e.To == slstate.Record {
+			// Until the next update, the position is the
+			// loop's from before the record.
+			ls.LoopPos = 0
+		}
 	}
-	var val float32
-	switch v := msg.Arguments[2].(type) {
+	ls.State, ls.haveState = slstate.State(v), true
+}
+
+// argFloat accepts any numeric OSC argument. SooperLooper sends float32,
+// but other senders may use doubles or integers.
+func argFloat(a any) (float32, bool) {
+	var f float64
+	switch v := a.(type) {
 	case float32:
-		val = v
+		f = float64(v)
 	case float64:
-		val = float32(v)
+		f = v
+	case int32:
+		f = float64(v)
+	case int64:
+		f = float64(v)
 	default:
-		return
+		return 0, false
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
 	}
-	apply(getLoopState(loopIdx), val)
+	return float32(f), true
 }
 
-func parseLoopIndex(addr string) int {
-	p := strings.Split(addr, "/")
-	if len(p) > 2 {
-		if i, err := strconv.Atoi(p[2]); err == nil {
-			return i
+func argInt(a any) (int, bool) {
+	switch v := a.(type) {
+	case int32:
+		return int(v), true
+	case int64:
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return 0, false
 		}
+		return int(v), true
 	}
-	return 0
+	return 0, false
 }
 
+func validLoopIndex(idx int) bool {
+	return idx >= 0 && idx < maxLoops
+}
+
+// loopUpdateValue is the value of an update of ctrl on the loop at index
+// i, checking that its arguments name the same loop and control.
+func loopUpdateValue(msg *osc.Message, i int, ctrl string) (float32, bool) {
+	if len(msg.Arguments) < 3 {
+		return 0, false
+	}
+	if idx, ok := argInt(msg.Arguments[0]); !ok || idx != i || !validLoopIndex(idx) {
+		return 0, false
+	}
+	if c, ok := msg.Arguments[1].(string); !ok || c != ctrl {
+		return 0, false
+	}
+	return argFloat(msg.Arguments[2])
+}
+
+// parseLoopUpdate returns n and the control from the <n>/update_<control>
+// that follows /sl/ in a loop update's address.
+func parseLoopUpdate(rest string) (n int, ctrl string, ok bool) {
+	num, ctrl, ok := strings.Cut(rest, "/update_")
+	if !ok {
+		return 0, "", false
+	}
+	n, err := strconv.Atoi(num)
+	return n, ctrl, err == nil
+}
+
+// getLoopState is the state of the loop at index idx, made on first use.
+// It is kept for any loop there can be, even before the engine reports
+// having it, so levels and controls set before the first /pong, as by a
+// session restore, are not lost; the engine's own updates for loops it
+// does not have are rejected before they get here. An index no loop can
+// have gets a blank state that is not kept.
 func getLoopState(idx int) *LoopState {
-	if loopStates[idx] == nil {
-		loopStates[idx] = &LoopState{}
+	if ls := loopStates[idx]; ls != nil {
+		return ls
+	}
+	ls := &LoopState{}
+	if validLoopIndex(idx) {
+		loopStates[idx] = ls
 	}
-	return loopStates[idx]
+	return ls
 }
@@ -68,4 +68,31 @@ func TestContainsInt(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+// TestNudgeLevel tests the nudgeLevel function
+func TestNudgeLevel(t *testing.T) {
+	floor := float32(math.Pow(10, meterMinDB/20))
+	tests := []struct {
+		name string
+		wet  float32
+		db   float64
+		want float32
+	}{
+		{"up 6dB", 0.25, 6, float32(0.25 * math.Pow(10, 6.0/20))},
+		{"down 6dB", 0.5, -6, float32(0.5 * math.Pow(10, -6.0/20))},
+		{"up from silence starts at floor", 0, 0.5, float32(float64(floor) * math.Pow(10, 0.5/20))},
+		{"down from silence stays silent", 0, -0.5, 0},
+		{"down below floor goes silent", floor, -0.5, 0},
+		{"capped at levelMax", 0.9, 6, levelMax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nudgeLevel(tt.wet, tt.db)
+			if math.Abs(float64(got-tt.want)) > floatTolerance {
+				t.Errorf("nudgeLevel(%v, %v) = %v, want %v", tt.wet, tt.db, got, tt.want)
+			}
+		})
+	}
+}
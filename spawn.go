@@ -0,0 +1,151 @@
+// spawn.go
+// Running SooperLooper as a supervised child process.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	engineRestartMin = time.Second
+	engineRestartMax = 30 * time.Second
+	// engineStableAfter is how long the engine must run before a crash
+	// restarts it without delay growing.
+	engineStableAfter = time.Minute
+)
+
+var (
+	spawnEngine   bool
+	engineCommand = "sooperlooper"
+	engineLoops   = 1
+	engineRestart = true
+
+	engineProc *engineProcess
+)
+
+// engineProcess runs the engine, logs its output, and restarts it when it
+// exits unless it was stopped.
+type engineProcess struct {
+	path    string
+	args    []string
+	restart bool
+	delay   time.Duration // first restart delay, doubled after each quick crash
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stopped bool
+	done    chan struct{}
+}
+
+// engineArgs is the sooperlooper command line for port and loops.
+func engineArgs(port, loops int) []string {
+	return []string{"-p", strconv.Itoa(port), "-l", strconv.Itoa(loops)}
+}
+
+func newEngineProcess(path string, args []string, restart bool) *engineProcess {
+	return &engineProcess{path: path, args: args, restart: restart, delay: engineRestartMin, done: make(chan struct{})}
+}
+
+// startEngineProcess spawns --engine-cmd for the --osc-port engine.
+func startEngineProcess() {
+	if getLocalIP(oscHost) != "127.0.0.1" {
+		fatal(engineLog, "--spawn-engine needs the engine on this machine", "host", oscHost)
+	}
+	engineProc = newEngineProcess(engineCommand, engineArgs(oscPort, engineLoops), engineRestart)
+	goService("engine process", engineProc.run)
+}
+
+// run starts the engine and waits for it, restarting it after a crash
+// with a delay that grows while it keeps crashing soon after starting.
+// Once ctx is done it kills the engine and returns.
+func (e *engineProcess) run(ctx context.Context) error {
+	defer close(e.done)
+	defer context.AfterFunc(ctx, e.kill)()
+	delay := e.delay
+	for {
+		start := time.Now()
+		err := e.runOnce()
+		e.mu.Lock()
+		stopped := e.stopped
+		e.mu.Unlock()
+		if stopped {
+			return nil
+		}
+		if !e.restart {
+			engineLog.Error("engine exited", "err", err)
+			return nil
+		}
+		if time.Since(start) > engineStableAfter {
+			delay = e.delay
+		}
+		engineLog.Error("engine exited, restarting", "err", err, "in", delay)
+		if !sleepCtx(ctx, delay) {
+			return nil
+		}
+		delay = min(delay*2, engineRestartMax)
+	}
+}
+
+// runOnce runs the engine until it exits, logging each line it prints.
+func (e *engineProcess) runOnce() error {
+	e.mu.Lock()
+	if e.stopped {
+		e.mu.Unlock()
+		return nil
+	}
+	cmd := exec.Command(e.path, e.args...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		e.mu.Unlock()
+		return err
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		e.mu.Unlock()
+		return err
+	}
+	e.cmd = cmd
+	e.mu.Unlock()
+
+	engineLog.Info("engine started", "cmd", e.path, "args", e.args, "pid", cmd.Process.Pid)
+	logEngineOutput(out)
+	return cmd.Wait()
+}
+
+// kill kills the engine and keeps the supervisor from restarting it.
+func (e *engineProcess) kill() {
+	e.mu.Lock()
+	e.stopped = true
+	if e.cmd != nil && e.cmd.Process != nil {
+		e.cmd.Process.Kill()
+	}
+	e.mu.Unlock()
+}
+
+// logEngineOutput logs each line read from r until it ends.
+func logEngineOutput(r io.Reader) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		engineLog.Info(s.Text())
+	}
+}
+
+// stop kills the engine and waits for the supervisor to finish. It is
+// safe to call on a nil engineProcess.
+func (e *engineProcess) stop() {
+	if e == nil {
+		return
+	}
+	e.kill()
+	select {
+	case <-e.done:
+	case <-time.After(5 * time.Second):
+	}
+}
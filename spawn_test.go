@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEngine writes a script that records each start in runs, prints its
+// arguments and exits with code.
+func fakeEngine(t *testing.T, code string) (script, runs string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell")
+	}
+	dir := t.TempDir()
+	script, runs = filepath.Join(dir, "engine"), filepath.Join(dir, "runs")
+	body := "#!/bin/sh\necho run >> " + runs + "\necho fake engine \"$@\"\nexit " + code + "\n"
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return script, runs
+}
+
+func countRuns(runs string) int {
+	data, _ := os.ReadFile(runs)
+	return strings.Count(string(data), "run\n")
+}
+
+// TestEngineRestart tests that a crashing engine is restarted and its output logged
+func TestEngineRestart(t *testing.T) {
+	script, runs := fakeEngine(t, "1")
+	e := newEngineProcess(script, engineArgs(9951, 2), true)
+	e.delay = 10 * time.Millisecond
+	go e.run(context.Background())
+	deadline := time.Now().Add(3 * time.Second)
+	for countRuns(runs) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	e.stop()
+	if n := countRuns(runs); n < 3 {
+		t.Fatalf("engine ran %d times, want restarts", n)
+	}
+	found := false
+	for _, l := range logLines.tail(logRingSize, slog.LevelInfo) {
+		found = found || strings.Contains(l.Text, "fake engine -p 9951 -l 2")
+	}
+	if !found {
+		t.Error("engine output not in the log")
+	}
+}
+
+// TestEngineNoRestart tests that --engine-restart=false leaves a crashed engine down
+func TestEngineNoRestart(t *testing.T) {
+	script, runs := fakeEngine(t, "1")
+	e := newEngineProcess(script, engineArgs(9951, 1), false)
+	e.delay = 10 * time.Millisecond
+	go e.run(context.Background())
+	select {
+	case <-e.done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("supervisor still running")
+	}
+	if n := countRuns(runs); n != 1 {
+		t.Errorf("engine ran %d times, want 1", n)
+	}
+}
+
+// TestEngineRestartCancel tests that the supervisor stops waiting to
+// restart a crashed engine once its context is done
+func TestEngineRestartCancel(t *testing.T) {
+	script, runs := fakeEngine(t, "1")
+	e := newEngineProcess(script, engineArgs(9951, 1), true)
+	e.delay = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	go e.run(ctx)
+	deadline := time.Now().Add(3 * time.Second)
+	for countRuns(runs) < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-e.done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("supervisor still waiting to restart")
+	}
+	if n := countRuns(runs); n != 1 {
+		t.Errorf("engine ran %d times, want 1", n)
+	}
+}
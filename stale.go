@@ -0,0 +1,59 @@
+// stale.go
+// Stale updates: positions and meters whose auto updates have stopped
+// coming are greyed out rather than shown as live.
+
+package main
+
+import (
+	"slices"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// autoUpdateInterval is the interval auto updates are registered with.
+const autoUpdateInterval = 100 * time.Millisecond
+
+// staleAfter (--stale-after) is how many auto update intervals a live
+// control may go without an update before it is shown as stale; 0 never.
+var staleAfter = 10
+
+// liveControls change all the time, so the engine sends them on every auto
+// update interval. Other controls may rightly go unsent while they stay put.
+var liveControls = []string{"loop_pos", "in_peak_meter", "out_peak_meter"}
+
+// noteUpdate records that an update of ctrl came at now.
+func (ls *LoopState) noteUpdate(ctrl string, now time.Time) {
+	if ls.updated == nil {
+		ls.updated = make(map[string]time.Time)
+		ls.firstUpdate = now
+	}
+	if slices.Contains(liveControls, ctrl) {
+		packets.liveUpdate(ls.updated[ctrl], now)
+	}
+	ls.updated[ctrl] = now
+}
+
+// stale reports whether live control ctrl has had no update for
+// staleAfter intervals at now. A control never updated counts from the
+// loop's first update of any control.
+func (ls *LoopState) stale(ctrl string, now time.Time) bool {
+	if staleAfter <= 0 || !slices.Contains(liveControls, ctrl) || ls.firstUpdate.IsZero() {
+		return false
+	}
+	last, ok := ls.updated[ctrl]
+	if !ok {
+		last = ls.firstUpdate
+	}
+	return now.Sub(last) > time.Duration(staleAfter)*autoUpdateInterval
+}
+
+// staleCell greys out c.
+func staleCell(c cell) cell {
+	spans := make([]span, len(c.Spans))
+	for i, s := range c.Spans {
+		spans[i] = span{Text: s.Text, Color: tcell.ColorGray}
+	}
+	c.Spans = spans
+	return c
+}
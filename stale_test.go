@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// TestStale tests that live controls go stale without updates, counting
+// from the loop's first update, and are greyed out in the table
+func TestStale(t *testing.T) {
+	t0 := time.Now()
+	ls := &LoopState{}
+	if ls.stale("in_peak_meter", t0.Add(time.Hour)) {
+		t.Error("a loop with no updates at all is stale")
+	}
+	ls.noteUpdate("state", t0)
+	ls.noteUpdate("in_peak_meter", t0)
+	if ls.stale("out_peak_meter", t0.Add(500*time.Millisecond)) {
+		t.Error("out meter stale after 0.5 s")
+	}
+	now := t0.Add(1500 * time.Millisecond)
+	ls.noteUpdate("in_peak_meter", now.Add(-100*time.Millisecond))
+	for ctrl, want := range map[string]bool{"in_peak_meter": false, "out_peak_meter": true, "loop_pos": true, "state": false} {
+		if got := ls.stale(ctrl, now); got != want {
+			t.Errorf("stale(%s) = %v, want %v", ctrl, got, want)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	rows := renderTable(tableOptions{Width: 100}, []*LoopState{ls}, now)
+	for col, want := range map[int]bool{colPos: true, colMeterIn: false, colMeterOut: true} {
+		grey := true
+		for _, s := range rows[1][col].Spans {
+			grey = grey && s.Color == tcell.ColorGray
+		}
+		if grey != want {
+			t.Errorf("column %d greyed out = %v, want %v", col, grey, want)
+		}
+	}
+
+	defer func(n int) { staleAfter = n }(staleAfter)
+	staleAfter = 0
+	if ls.stale("loop_pos", now) {
+		t.Error("stale with --stale-after 0")
+	}
+}
@@ -0,0 +1,118 @@
+// surface.go
+// OSC control surface API: /gui/... messages from TouchOSC, Lemur and the
+// like, sent to the port sooperGUI listens on.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+const guiPrefix = "/gui/"
+
+// listenPort is the UDP port for engine replies and /gui messages; 0 picks
+// a free port.
+var listenPort = 0
+
+// guiCommands maps /gui addresses to the action they run. A trailing
+// space takes the message's first argument, e.g. /gui/select_loop 2 runs
+// "select 2".
+var guiCommands = map[string]string{
+	"/gui/select_loop":   "select ",
+	"/gui/select_next":   "select next",
+	"/gui/select_prev":   "select prev",
+	"/gui/scene/recall":  "scene ",
+	"/gui/song":          "song ",
+	"/gui/song/next":     "song next",
+	"/gui/song/prev":     "song prev",
+	"/gui/macro/run":     "macro ",
+	"/gui/action":        "",
+	"/gui/loop/level":    "level ",
+	"/gui/loop/command":  "",
+	"/gui/loop/selected": "",
+}
+
+// handleGUI runs a /gui message:
+//
+//	/gui/select_loop i:loop, /gui/select_next, /gui/select_prev
+//	/gui/scene/recall i:scene
+//	/gui/song i:song, /gui/song/next, /gui/song/prev
+//	/gui/macro/run s:name
+//	/gui/action s:actions [f:value]       any actions, as in MIDI bindings
+//	/gui/loop/level i:loop f:position     a Level fader, 0 to 1
+//	/gui/loop/command s:command i:loop    e.g. record 1; loop -1 is all
+//	/gui/loop/selected s:command          on the loop on the Loop page
+//
+// Numbers may be sent as ints, floats or strings. Macros come from the
+// config file.
+func handleGUI(m *osc.Message, macros map[string]string) error {
+	prefix, ok := guiCommands[m.Address]
+	if !ok {
+		return fmt.Errorf("unknown address %s", m.Address)
+	}
+	args := make([]string, len(m.Arguments))
+	for i, a := range m.Arguments {
+		args[i] = oscArgString(a)
+	}
+	arg := func(i int) string {
+		if i < len(args) {
+			return args[i]
+		}
+		return ""
+	}
+
+	action, value := prefix, float32(1)
+	switch m.Address {
+	case "/gui/action":
+		action = arg(0)
+		if v, err := strconv.ParseFloat(arg(1), 32); err == nil {
+			value = float32(v)
+		}
+	case "/gui/loop/level":
+		action += arg(0)
+		v, err := strconv.ParseFloat(arg(1), 32)
+		if err != nil {
+			return fmt.Errorf("%s needs a loop and a position", m.Address)
+		}
+		value = min(max(float32(v), 0), 1)
+	case "/gui/loop/command":
+		loop := arg(1)
+		if n, err := strconv.Atoi(loop); err == nil && n < 0 {
+			loop = "all"
+		}
+		action = arg(0) + " " + loop
+	case "/gui/loop/selected":
+		action = arg(0)
+	default:
+		if strings.HasSuffix(prefix, " ") {
+			action += arg(0)
+		}
+	}
+	steps, _, err := parseActions(action, macros)
+	if err != nil {
+		return err
+	}
+	runActions(steps, value)
+	return nil
+}
+
+// oscArgString formats an argument as an action word: numbers without a
+// fraction as integers.
+func oscArgString(a any) string {
+	switch v := a.(type) {
+	case string:
+		return v
+	case float32:
+		if v == float32(int(v)) {
+			return strconv.Itoa(int(v))
+		}
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return oscArgString(float32(v))
+	}
+	return fmt.Sprint(a)
+}
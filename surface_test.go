@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/hypebeast/go-osc/osc"
+	"jaudio/internal/slmock"
+)
+
+// TestGUISurface tests /gui messages sent to the client's listening port
+func TestGUISurface(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 3)
+	sl = startClient(t, sim)
+	defer func() { sl = nil }()
+	sl.macros = map[string]string{"start": "select 3; record 2"}
+	eventually(t, "three loops", func() bool { return loopCount == 3 })
+
+	gui := osc.NewClient("127.0.0.1", sl.conn.LocalAddr().(*net.UDPAddr).Port)
+	gui.Send(osc.NewMessage("/gui/macro/run", "start"))
+	eventually(t, "loop 3 selected", func() bool { return selectedLoop == 2 })
+	eventually(t, "loop 2 recording", func() bool {
+		ls := loopStates[1]
+		return ls != nil && ls.State == slmock.StateRecording
+	})
+
+	gui.Send(osc.NewMessage("/gui/select_loop", float32(1)))
+	eventually(t, "loop 1 selected", func() bool { return selectedLoop == 0 })
+	gui.Send(osc.NewMessage("/gui/loop/command", "record", int32(-1)))
+	eventually(t, "loops 1 and 3 recording", func() bool {
+		for _, i := range []int{0, 2} {
+			if ls := loopStates[i]; ls == nil || ls.State != slmock.StateRecording {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// TestHandleGUIErrors tests that bad /gui messages are reported, not run
+func TestHandleGUIErrors(t *testing.T) {
+	for _, m := range []*osc.Message{
+		osc.NewMessage("/gui/unknown"),
+		osc.NewMessage("/gui/select_loop"),
+		osc.NewMessage("/gui/macro/run", "missing"),
+		osc.NewMessage("/gui/loop/level", int32(1)),
+		osc.NewMessage("/gui/action", "explode"),
+	} {
+		if err := handleGUI(m, nil); err == nil {
+			t.Errorf("%s: no error", m)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// throttle.go
+// Per-loop coalescing of outgoing level messages.
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// rampMinJump is the smallest change that is ramped rather than sent as is;
+// drags produce many small steps that need no smoothing.
+const rampMinJump = 0.05
+
+// sendThrottle limits how often a value is sent for each loop. Values set
+// while a send is pending replace the pending one, so the latest value is
+// always the one delivered and at most one timer per loop is outstanding.
+// With a non-zero ramp, large jumps are sent as a series of interpolated
+// values spread over roughly the ramp duration.
+type sendThrottle struct {
+	interval time.Duration
+	ramp     time.Duration
+	send     func(loopID int, value float32)
+
+	mu    sync.Mutex
+	loops map[int]*throttledValue
+}
+
+type throttledValue struct {
+	value    float32
+	lastSent time.Time
+	timer    *time.Timer
+
+	sent     float32
+	hasSent  bool
+	rampStep float32
+}
+
+func newSendThrottle(maxPerSecond int, ramp time.Duration, send func(loopID int, value float32)) *sendThrottle {
+	if maxPerSecond < 1 {
+		maxPerSecond = 1
+	}
+	return &sendThrottle{
+		interval: time.Second / time.Duration(maxPerSecond),
+		ramp:     ramp,
+		send:     send,
+		loops:    make(map[int]*throttledValue),
+	}
+}
+
+func (t *sendThrottle) Set(loopID int, value float32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tv := t.loops[loopID]
+	if tv == nil {
+		tv = &throttledValue{}
+		t.loops[loopID] = tv
+	}
+	tv.value = value
+	tv.rampStep = 0
+	if jump := abs32(value - tv.sent); t.ramp > 0 && tv.hasSent && jump > rampMinJump {
+		steps := float32(t.ramp) / float32(t.interval)
+		if steps > 1 {
+			tv.rampStep = jump / steps
+		}
+	}
+	if tv.timer != nil {
+		return
+	}
+	t.schedule(loopID, tv)
+}
+
+// sentAs records that loopID was sent value by other means, such as a
+// bundle, so a pending send delivers it and later ramps start from it.
+func (t *sendThrottle) sentAs(loopID int, value float32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tv := t.loops[loopID]
+	if tv == nil {
+		tv = &throttledValue{}
+		t.loops[loopID] = tv
+	}
+	tv.value, tv.sent, tv.hasSent, tv.rampStep = value, value, true, 0
+}
+
+func (t *sendThrottle) schedule(loopID int, tv *throttledValue) {
+	wait := t.interval - time.Since(tv.lastSent)
+	if wait < 0 {
+		wait = 0
+	}
+	tv.timer = time.AfterFunc(wait, func() { t.flush(loopID) })
+}
+
+func (t *sendThrottle) flush(loopID int) {
+	t.mu.Lock()
+	tv := t.loops[loopID]
+	value := tv.value
+	if tv.rampStep > 0 {
+		switch {
+		case value > tv.sent+tv.rampStep:
+			value = tv.sent + tv.rampStep
+		case value < tv.sent-tv.rampStep:
+			value = tv.sent - tv.rampStep
+		default:
+			tv.rampStep = 0
+		}
+	}
+	tv.sent, tv.hasSent = value, true
+	tv.lastSent = time.Now()
+	tv.timer = nil
+	if value != tv.value {
+		t.schedule(loopID, tv)
+	}
+	t.mu.Unlock()
+
+	t.send(loopID, value)
+}
+
+func abs32(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSendThrottleCoalesces tests that a burst of values is coalesced and the latest value is sent
+func TestSendThrottleCoalesces(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		sent []float32
+	)
+	th := newSendThrottle(20, 0, func(loopID int, value float32) {
+		mu.Lock()
+		defer mu.Unlock()
+		if loopID != 1 {
+			t.Errorf("send for loop %d, want 1", loopID)
+		}
+		sent = append(sent, value)
+	})
+
+	for i := 1; i <= 100; i++ {
+		th.Set(1, float32(i)/100)
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) == 0 || len(sent) > 3 {
+		t.Fatalf("got %d sends for a burst of 100 values, want 1-3", len(sent))
+	}
+	if last := sent[len(sent)-1]; last != 1 {
+		t.Errorf("last sent value = %v, want 1", last)
+	}
+}
+
+// TestSendThrottlePerLoop tests that loops are throttled independently
+func TestSendThrottlePerLoop(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		sent = make(map[int]float32)
+	)
+	th := newSendThrottle(30, 0, func(loopID int, value float32) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent[loopID] = value
+	})
+
+	th.Set(1, 0.25)
+	th.Set(2, 0.5)
+	th.Set(3, 0.75)
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[int]float32{1: 0.25, 2: 0.5, 3: 0.75}
+	for id, v := range want {
+		if sent[id] != v {
+			t.Errorf("loop %d: sent %v, want %v", id, sent[id], v)
+		}
+	}
+}
+
+// TestSendThrottleRamp tests that a large jump is sent as increasing intermediate values
+func TestSendThrottleRamp(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		sent []float32
+	)
+	th := newSendThrottle(100, 50*time.Millisecond, func(loopID int, value float32) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, value)
+	})
+
+	th.Set(1, 0)
+	time.Sleep(20 * time.Millisecond)
+	th.Set(1, 0.9)
+	time.Sleep(150 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) < 4 {
+		t.Fatalf("got sends %v, want the jump split into several steps", sent)
+	}
+	for i := 1; i < len(sent); i++ {
+		if sent[i] < sent[i-1] {
+			t.Errorf("ramp not monotonic: %v", sent)
+			break
+		}
+	}
+	if last := sent[len(sent)-1]; last != 0.9 {
+		t.Errorf("last sent value = %v, want 0.9", last)
+	}
+}
@@ -0,0 +1,121 @@
+// toast.go
+// Toasts: short notifications drawn over the top right of the TUI for a few
+// seconds, colored by severity, for connection events, errors, scene recalls
+// and saves. Past ones are kept for the log pane and Log page.
+
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	// toastTime is how long a toast stays up.
+	toastTime = 5 * time.Second
+	// toastMax is how many toasts are up at once. Older ones go early.
+	toastMax   = 3
+	toastWidth = 50
+	// toastTop is the first screen row toasts are drawn on, below the page
+	// tabs.
+	toastTop = 1
+	// notificationHistory is how many past notifications are kept.
+	notificationHistory = 100
+)
+
+// toast is a notification on screen until Until.
+type toast struct {
+	Level slog.Level
+	Text  string
+	Until time.Time
+}
+
+var (
+	// toasts are the notifications on screen, oldest first. They have
+	// their own lock, as they come from every goroutine, some holding mu.
+	toasts struct {
+		sync.Mutex
+		shown []toast
+	}
+	notificationLog = newLogRing(notificationHistory)
+	// showNotifications (N) shows the notifications instead of the log in
+	// the log pane and on the Log page.
+	showNotifications bool
+)
+
+// notify shows text as a toast at level, and keeps it in the history.
+func notify(level slog.Level, text string) {
+	now := time.Now()
+	notificationLog.add(logLine{Level: level, Text: now.Format(time.TimeOnly) + " " + level.String() + " " + text})
+	toasts.Lock()
+	defer toasts.Unlock()
+	shown := toasts.shown[:0]
+	for _, t := range toasts.shown {
+		if now.Before(t.Until) {
+			shown = append(shown, t)
+		}
+	}
+	shown = append(shown, toast{Level: level, Text: text, Until: now.Add(toastTime)})
+	toasts.shown = shown[max(len(shown)-toastMax, 0):]
+}
+
+// activeToasts returns the toasts up at now, newest first.
+func activeToasts(now time.Time) []toast {
+	toasts.Lock()
+	defer toasts.Unlock()
+	var out []toast
+	for i := len(toasts.shown) - 1; i >= 0; i-- {
+		if t := toasts.shown[i]; now.Before(t.Until) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// toastStyle is the colors of a toast at level.
+func toastStyle(level slog.Level) (fg, bg tcell.Color) {
+	switch {
+	case level >= slog.LevelError:
+		return tcell.ColorWhite, tcell.ColorMaroon
+	case level >= slog.LevelWarn:
+		return tcell.ColorBlack, tcell.ColorOlive
+	}
+	return tcell.ColorWhite, tcell.ColorNavy
+}
+
+// toastMark is the sign before a toast at level, so the severity is not
+// told by color alone.
+func toastMark(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "✗"
+	case level >= slog.LevelWarn:
+		return "!"
+	}
+	return "✓"
+}
+
+// drawToasts draws the toasts up at now over the top right of the screen,
+// newest at the top.
+func drawToasts(s tcell.Screen, now time.Time) {
+	w, h := s.Size()
+	width := min(toastWidth, w)
+	for i, t := range activeToasts(now) {
+		y := toastTop + i
+		if y >= h {
+			break
+		}
+		fg, bg := toastStyle(t.Level)
+		style := tcell.StyleDefault.Foreground(fg).Background(bg)
+		for x := w - width; x < w; x++ {
+			s.SetContent(x, y, ' ', nil, style)
+		}
+		text := " " + toastMark(t.Level) + " " + strings.ReplaceAll(t.Text, "\n", " ")
+		tview.Print(s, tview.Escape(text), w-width, y, width-1, tview.AlignLeft, fg)
+	}
+}
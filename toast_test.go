@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// TestNotify tests how many toasts are up, for how long, and the history
+func TestNotify(t *testing.T) {
+	for i := range toastMax + 1 {
+		notify(slog.LevelInfo, fmt.Sprintf("toast %d", i))
+	}
+	notify(slog.LevelWarn, "newest")
+	now := time.Now()
+	shown := activeToasts(now)
+	if len(shown) != toastMax || shown[0].Text != "newest" || shown[1].Text != fmt.Sprintf("toast %d", toastMax) {
+		t.Errorf("toasts %+v, want the newest %d, newest first", shown, toastMax)
+	}
+	if shown := activeToasts(now.Add(toastTime)); len(shown) != 0 {
+		t.Errorf("toasts %+v still up after %v", shown, toastTime)
+	}
+	lines := notificationLog.tail(2, slog.LevelWarn)
+	if len(lines) == 0 || !strings.HasSuffix(lines[len(lines)-1].Text, "WARN newest") {
+		t.Errorf("history %+v, want the warning last", lines)
+	}
+}
+
+// TestDrawToasts tests drawing toasts at the top right in their colors
+func TestDrawToasts(t *testing.T) {
+	notify(slog.LevelError, "engine [gone]")
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Fini()
+	s.SetSize(80, 10)
+	drawToasts(s, time.Now())
+	s.Show()
+	cells, w, _ := s.GetContents()
+	var row strings.Builder
+	for x := range w {
+		row.WriteString(string(cells[toastTop*w+x].Runes))
+	}
+	if got := strings.TrimSpace(row.String()); got != "✗ engine [gone]" {
+		t.Errorf("toast row %q", got)
+	}
+	if _, bg, _ := cells[toastTop*w+w-1].Style.Decompose(); bg != tcell.ColorMaroon {
+		t.Errorf("error toast background %v", bg)
+	}
+	if len(cells[toastTop*w+w-toastWidth-1].Runes) > 0 && cells[toastTop*w+w-toastWidth-1].Runes[0] != ' ' {
+		t.Error("toast drawn wider than toastWidth")
+	}
+}
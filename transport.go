@@ -0,0 +1,53 @@
+// transport.go
+// Transports carry OSC between sooperGUI and the engine. UDP, which
+// SooperLooper speaks, is the default; other backends, such as TCP OSC, a
+// WebSocket bridge or an in-process engine for tests, implement Transport
+// and are handed to newTransportClient.
+
+package main
+
+import (
+	"net"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// oscSender sends OSC packets, a message or a bundle. *osc.Client and every
+// Transport are one.
+type oscSender interface {
+	Send(p osc.Packet) error
+}
+
+// Transport carries OSC to the engine and its replies back.
+type Transport interface {
+	oscSender
+	// Subscribe passes every message received to handle, one at a time and
+	// in order, the messages of a bundle each on their own. It returns
+	// when the transport is closed or fails, with the reason.
+	Subscribe(handle func(*osc.Message)) error
+	// Close ends Subscribe and releases the transport.
+	Close() error
+}
+
+// udpTransport sends to the engine from a port of its own and receives on
+// conn, the port the return URL names.
+type udpTransport struct {
+	engine *osc.Client
+	conn   net.PacketConn
+}
+
+func (t *udpTransport) Send(p osc.Packet) error {
+	return t.engine.Send(p)
+}
+
+func (t *udpTransport) Subscribe(handle func(*osc.Message)) error {
+	d := osc.NewStandardDispatcher()
+	if err := d.AddMsgHandler("*", handle); err != nil {
+		return err
+	}
+	return (&osc.Server{Dispatcher: d}).Serve(t.conn)
+}
+
+func (t *udpTransport) Close() error {
+	return t.conn.Close()
+}
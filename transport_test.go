@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// pipeTransport is an in-process Transport: what is sent goes to sent,
+// dropped when that is full, and what is put on replies is received.
+type pipeTransport struct {
+	sent    chan osc.Packet
+	replies chan *osc.Message
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func newPipeTransport() *pipeTransport {
+	return &pipeTransport{sent: make(chan osc.Packet, 1024), replies: make(chan *osc.Message), closed: make(chan struct{})}
+}
+
+func (t *pipeTransport) Send(p osc.Packet) error {
+	select {
+	case t.sent <- p:
+	default:
+	}
+	return nil
+}
+
+func (t *pipeTransport) Subscribe(handle func(*osc.Message)) error {
+	for {
+		select {
+		case m := <-t.replies:
+			handle(m)
+		case <-t.closed:
+			return net.ErrClosed
+		}
+	}
+}
+
+func (t *pipeTransport) Close() error {
+	t.once.Do(func() { close(t.closed) })
+	return nil
+}
+
+// awaitSent waits for a message to addr with first argument arg among those
+// sent on t.
+func (t *pipeTransport) awaitSent(tb testing.TB, addr string, arg any) {
+	tb.Helper()
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case p := <-t.sent:
+			if m, ok := p.(*osc.Message); ok && m.Address == addr && (arg == nil || len(m.Arguments) > 0 && m.Arguments[0] == arg) {
+				return
+			}
+		case <-deadline:
+			tb.Fatalf("no %s %v sent", addr, arg)
+		}
+	}
+}
+
+// TestTransportClient tests that the client pings, sends commands and
+// receives replies over a transport other than UDP
+func TestTransportClient(t *testing.T) {
+	pipe := newPipeTransport()
+	received := make(chan *osc.Message, 1)
+	c := newTransportClient(pipe, "in-process", "osc.udp://127.0.0.1:1", func(m *osc.Message) { received <- m })
+	c.Start()
+
+	pipe.awaitSent(t, "/ping", "osc.udp://127.0.0.1:1")
+	c.Hit(0, "record")
+	pipe.awaitSent(t, "/sl/0/hit", "record")
+
+	pipe.replies <- osc.NewMessage("/sl/0/update_state", int32(0), "state", float32(2))
+	select {
+	case m := <-received:
+		if m.Address != "/sl/0/update_state" {
+			t.Errorf("received %s, want /sl/0/update_state", m.Address)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("reply not received")
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
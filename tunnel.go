@@ -0,0 +1,304 @@
+// tunnel.go
+// Remote control over SSH: --tunnel carries the engine's OSC through ssh
+// to sooperGUI --tunnel-serve on the engine's machine, encrypted and
+// authenticated by ssh.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// tunnelHello starts the first frame from the remote end, followed by
+	// the port it receives engine replies on.
+	tunnelHello = "sooperGUI-tunnel 1 "
+	// tunnelTimeout is how long the remote end may take to answer, password
+	// prompt included.
+	tunnelTimeout = time.Minute
+)
+
+var (
+	// tunnelTarget (--tunnel) is the ssh destination, e.g. user@host, of
+	// the machine running the engine. Empty sends OSC directly.
+	tunnelTarget = ""
+	// tunnelCommand (--tunnel-cmd) is sooperGUI on the remote machine.
+	tunnelCommand = "sooperGUI"
+	// tunnelServe (--tunnel-serve) runs the remote end on stdin and stdout.
+	tunnelServe = false
+)
+
+// Frames carry one datagram each, after its length as two bytes big endian.
+
+func writeFrame(w io.Writer, p []byte) error {
+	if len(p) > 0xffff {
+		return fmt.Errorf("datagram of %d bytes too long for the tunnel", len(p))
+	}
+	buf := make([]byte, 2+len(p))
+	binary.BigEndian.PutUint16(buf, uint16(len(p)))
+	copy(buf[2:], p)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	var n [2]byte
+	if _, err := io.ReadFull(r, n[:]); err != nil {
+		return nil, err
+	}
+	p := make([]byte, binary.BigEndian.Uint16(n[:]))
+	if _, err := io.ReadFull(r, p); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return p, nil
+}
+
+// serveTunnel is the remote end: it sends the datagrams framed on in to the
+// engine at engineAddr from a loopback UDP port (0 picks one), and frames
+// what comes back to that port on out, until in ends.
+func serveTunnel(in io.Reader, out io.Writer, engineAddr string, port int) error {
+	engine, err := net.ResolveUDPAddr("udp", engineAddr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	hello := tunnelHello + strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port)
+	if err := writeFrame(out, []byte(hello)); err != nil {
+		return err
+	}
+	go func() {
+		buf := make([]byte, 0xffff)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil || writeFrame(out, buf[:n]) != nil {
+				return
+			}
+		}
+	}()
+	r := bufio.NewReader(in)
+	for {
+		p, err := readFrame(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		conn.WriteTo(p, engine)
+	}
+}
+
+// tunnel is the local end: a loopback UDP port standing in for the engine,
+// whose datagrams go framed to the remote end, with the replies coming back
+// sent on to the client's port. The remote end is restarted when its
+// session ends, keeping the port replies go to.
+type tunnel struct {
+	conn       *net.UDPConn
+	remotePort int // the remote end's port for engine replies
+	client     atomic.Pointer[net.UDPAddr]
+
+	mu  sync.Mutex
+	out io.Writer // to the current session, nil between sessions
+}
+
+// newTunnel opens the local end's port and starts forwarding from it.
+func newTunnel() (*tunnel, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return nil, err
+	}
+	t := &tunnel{conn: conn}
+	go t.forward()
+	return t, nil
+}
+
+// port is where the client sends to instead of the engine.
+func (t *tunnel) port() int { return t.conn.LocalAddr().(*net.UDPAddr).Port }
+
+// deliverTo sends the replies to the client listening on addr.
+func (t *tunnel) deliverTo(addr net.Addr) {
+	port := addr.(*net.UDPAddr).Port
+	t.client.Store(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+}
+
+// forward frames the client's datagrams to the current session, dropping
+// them between sessions as the network would.
+func (t *tunnel) forward() {
+	buf := make([]byte, 0xffff)
+	for {
+		n, _, err := t.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		t.mu.Lock()
+		if t.out != nil && writeFrame(t.out, buf[:n]) != nil {
+			t.out = nil
+		}
+		t.mu.Unlock()
+	}
+}
+
+// start begins a session with the remote end on in and out, returning once
+// it has said hello. The session runs until in ends.
+func (t *tunnel) start(in io.Reader, out io.Writer) (*bufio.Reader, error) {
+	r := bufio.NewReader(in)
+	hello, err := readFrame(r)
+	if err != nil {
+		return nil, fmt.Errorf("no answer from the remote end: %w", err)
+	}
+	port, err := strconv.Atoi(strings.TrimPrefix(string(hello), tunnelHello))
+	if !strings.HasPrefix(string(hello), tunnelHello) || err != nil {
+		return nil, fmt.Errorf("unexpected answer from the remote end: %q", hello)
+	}
+	if t.remotePort != 0 && port != t.remotePort {
+		return nil, fmt.Errorf("remote end on port %d, want %d", port, t.remotePort)
+	}
+	t.remotePort = port
+	t.mu.Lock()
+	t.out = out
+	t.mu.Unlock()
+	return r, nil
+}
+
+// receive sends the replies of a session on to the client until it ends.
+func (t *tunnel) receive(r *bufio.Reader) error {
+	defer func() {
+		t.mu.Lock()
+		t.out = nil
+		t.mu.Unlock()
+	}()
+	for {
+		p, err := readFrame(r)
+		if err != nil {
+			return err
+		}
+		if addr := t.client.Load(); addr != nil {
+			t.conn.WriteToUDP(p, addr)
+		}
+	}
+}
+
+// tunnelArgs is the ssh command line running the remote end at target for
+// the engine on port, with replies to replyPort (0 picks one).
+func tunnelArgs(target string, port, replyPort int) []string {
+	return []string{"-T", "-o", "ServerAliveInterval=10", target, "--",
+		tunnelCommand, "--tunnel-serve", "--osc-port", strconv.Itoa(port), "--listen-port", strconv.Itoa(replyPort)}
+}
+
+// session runs ssh to the remote end, returning once the session is up, or
+// with an error if it does not come up within tunnelTimeout. done gets the
+// error the session ends with.
+func (t *tunnel) session(target string, port int) (done chan error, err error) {
+	cmd := exec.Command("ssh", tunnelArgs(target, port, t.remotePort)...)
+	in, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	go func() {
+		s := bufio.NewScanner(stderr)
+		for s.Scan() {
+			oscLog.Warn("tunnel: " + s.Text())
+		}
+	}()
+	timer := time.AfterFunc(tunnelTimeout, func() { cmd.Process.Kill() })
+	r, err := t.start(in, out)
+	timer.Stop()
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	done = make(chan error, 1)
+	go func() {
+		err := t.receive(r)
+		out.Close()
+		if werr := cmd.Wait(); werr != nil {
+			err = werr
+		}
+		done <- err
+	}()
+	return done, nil
+}
+
+// startTunnel connects to the remote end at target for the engine on port,
+// and keeps reconnecting when the session ends, with a delay that grows
+// while it keeps failing.
+func startTunnel(target string, port int) (*tunnel, error) {
+	t, err := newTunnel()
+	if err != nil {
+		return nil, err
+	}
+	done, err := t.session(target, port)
+	if err != nil {
+		t.conn.Close()
+		return nil, err
+	}
+	oscLog.Info("tunnel up", "target", target, "port", t.remotePort)
+	goService("tunnel", func(ctx context.Context) error {
+		stop := context.AfterFunc(ctx, t.close)
+		defer stop()
+		delay := engineRestartMin
+		for {
+			start := time.Now()
+			var err error
+			select {
+			case <-ctx.Done():
+				return nil
+			case err = <-done:
+			}
+			if time.Since(start) > engineStableAfter {
+				delay = engineRestartMin
+			}
+			for {
+				oscLog.Error("tunnel closed, reconnecting", "err", err, "in", delay)
+				if !sleepCtx(ctx, delay) {
+					return nil
+				}
+				delay = min(delay*2, engineRestartMax)
+				if done, err = t.session(target, port); err == nil {
+					oscLog.Info("tunnel up", "target", target, "port", t.remotePort)
+					break
+				}
+			}
+		}
+	})
+	return t, nil
+}
+
+// close ends the current session, whose remote end then exits, and stops
+// forwarding.
+func (t *tunnel) close() {
+	t.conn.Close()
+	t.mu.Lock()
+	if c, ok := t.out.(io.Closer); ok {
+		c.Close()
+	}
+	t.mu.Unlock()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"jaudio/internal/slmock"
+)
+
+// TestFrames tests that datagrams keep their bounds through the tunnel's
+// byte stream
+func TestFrames(t *testing.T) {
+	var buf bytes.Buffer
+	want := [][]byte{[]byte("/ping"), {}, bytes.Repeat([]byte{7}, 0xffff)}
+	for _, p := range want {
+		if err := writeFrame(&buf, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeFrame(&buf, make([]byte, 0x10000)); err == nil {
+		t.Error("datagram over 64 KiB framed")
+	}
+	r := bufio.NewReader(&buf)
+	for _, p := range want {
+		got, err := readFrame(r)
+		if err != nil || !bytes.Equal(got, p) {
+			t.Fatalf("readFrame = %d bytes, %v, want %d bytes", len(got), err, len(p))
+		}
+	}
+	if _, err := readFrame(r); err != io.EOF {
+		t.Errorf("readFrame at the end = %v, want EOF", err)
+	}
+}
+
+// TestTunnelArgs tests the ssh command line for the remote end
+func TestTunnelArgs(t *testing.T) {
+	got := tunnelArgs("me@stage", 9951, 40000)
+	want := []string{"-T", "-o", "ServerAliveInterval=10", "me@stage", "--",
+		"sooperGUI", "--tunnel-serve", "--osc-port", "9951", "--listen-port", "40000"}
+	if !slices.Equal(got, want) {
+		t.Errorf("tunnelArgs = %q, want %q", got, want)
+	}
+}
+
+// TestTunnel tests controlling an engine through both ends of a tunnel,
+// joined by pipes where ssh would be
+func TestTunnel(t *testing.T) {
+	sim := startSim(t, "127.0.0.1:0", 2)
+	toRemote, fromLocal := io.Pipe()
+	toLocal, fromRemote := io.Pipe()
+	t.Cleanup(func() { fromLocal.Close() })
+	go serveTunnel(toRemote, fromRemote, sim.conn.LocalAddr().String(), 0)
+
+	tun, err := newTunnel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tun.conn.Close() })
+	r, err := tun.start(toLocal, fromLocal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go tun.receive(r)
+
+	mu.Lock()
+	loopStates = make(map[int]*LoopState)
+	loopCount = 1
+	mu.Unlock()
+	returnHost, returnPort = "127.0.0.1", tun.remotePort
+	c, err := newSLClient("127.0.0.1:0", "127.0.0.1", tun.port(), nil, handleOSC)
+	returnHost, returnPort = "", 0
+	if err != nil {
+		t.Fatal(err)
+	}
+	tun.deliverTo(c.conn.LocalAddr())
+	c.pingEvery, c.pollEvery = 50*time.Millisecond, 20*time.Millisecond
+	c.Start()
+	t.Cleanup(func() { c.Close() })
+
+	eventually(t, "engine online through the tunnel", func() bool { return c.Online() && loopCount == 2 })
+	c.Hit(1, "record")
+	eventually(t, "loop 2 recording", func() bool {
+		ls := loopStates[1]
+		return ls != nil && ls.State == slmock.StateRecording
+	})
+}
@@ -0,0 +1,188 @@
+// udpstats.go
+// Packet loss: a large receive buffer for the OSC socket, and counts of
+// lost and out of order packets, shown as a loss percentage.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// oscReadBuffer is asked for as the OSC socket's receive buffer. Linux
+	// caps it at net.core.rmem_max.
+	oscReadBuffer = 4 << 20
+	// lossBuckets one second buckets make up the loss percentage.
+	lossBuckets = 10
+	// lossGapIntervals is the longest gap in a live control's updates that
+	// counts as lost packets. Longer ones are the updates stopping, which
+	// is shown as stale instead.
+	lossGapIntervals = 10
+	// lossWarnPercent and lossBadPercent color the loss yellow and red.
+	lossWarnPercent = 1
+	lossBadPercent  = 5
+)
+
+// packets are the loss counts for the status bar. It is guarded by mu.
+var packets = packetStats{drops: -1}
+
+// packetStats counts packets received, lost and out of order over the last
+// lossBuckets seconds. Lost packets are gaps in the live controls' auto
+// updates, pings that got no reply and the kernel's drops for the socket.
+// Out of order ones are pongs older than one already received.
+type packetStats struct {
+	buckets [lossBuckets]packetBucket
+	pongSeq int
+	drops   int  // the kernel's drop count when last read, -1 before
+	full    bool // the receive buffer was last seen nearly full
+}
+
+type packetBucket struct {
+	sec                       int64
+	received, lost, reordered int
+}
+
+func (p *packetStats) add(now time.Time, received, lost, reordered int) {
+	sec := now.Unix()
+	b := &p.buckets[sec%lossBuckets]
+	if b.sec != sec {
+		*b = packetBucket{sec: sec}
+	}
+	b.received += received
+	b.lost += lost
+	b.reordered += reordered
+}
+
+// liveUpdate counts an update of a live control at now, the last one
+// having come at prev, and the updates missed in between.
+func (p *packetStats) liveUpdate(prev, now time.Time) {
+	lost := 0
+	if gap := now.Sub(prev); !prev.IsZero() && gap <= lossGapIntervals*autoUpdateInterval {
+		lost = max(int(math.Round(float64(gap)/float64(autoUpdateInterval)))-1, 0)
+	}
+	p.add(now, 1, lost, 0)
+}
+
+// pong counts a ping reply on addr, /pong/<seq>.
+func (p *packetStats) pong(addr string, now time.Time) {
+	seq, err := strconv.Atoi(strings.TrimPrefix(addr, pongPrefix))
+	if err != nil {
+		return
+	}
+	reordered := 0
+	if seq < p.pongSeq {
+		reordered = 1
+	}
+	p.pongSeq = max(p.pongSeq, seq)
+	p.add(now, 1, 0, reordered)
+}
+
+// kernelDrops counts the drops since the last reading of the kernel's
+// count, drops.
+func (p *packetStats) kernelDrops(drops int, now time.Time) {
+	if p.drops >= 0 && drops > p.drops {
+		oscLog.Warn("OSC packets dropped by the kernel", "count", drops-p.drops)
+		p.add(now, 0, drops-p.drops, 0)
+	}
+	p.drops = drops
+}
+
+// loss returns the percentage of packets lost and the number out of order
+// over the last lossBuckets seconds, and false when none came or went.
+func (p *packetStats) loss(now time.Time) (percent float64, reordered int, ok bool) {
+	var received, lost int
+	for _, b := range p.buckets {
+		if now.Unix()-b.sec < lossBuckets {
+			received, lost, reordered = received+b.received, lost+b.lost, reordered+b.reordered
+		}
+	}
+	if received+lost == 0 {
+		return 0, 0, false
+	}
+	return 100 * float64(lost) / float64(received+lost), reordered, true
+}
+
+// lossText is the status bar part for the packet loss.
+func (p *packetStats) lossText(now time.Time) string {
+	percent, reordered, ok := p.loss(now)
+	if !ok {
+		return ""
+	}
+	color := "green"
+	switch {
+	case percent >= lossBadPercent:
+		color = "red"
+	case percent >= lossWarnPercent:
+		color = "yellow"
+	}
+	text := fmt.Sprintf("[%s]%s[-]", color, trf("loss %.1f%%", percent))
+	if reordered > 0 {
+		text += " " + trf("%d out of order", reordered)
+	}
+	return text
+}
+
+// enlargeReadBuffer asks for an oscReadBuffer receive buffer, so bursts of
+// updates are not dropped while the TUI is busy.
+func enlargeReadBuffer(conn net.PacketConn) {
+	udp, ok := conn.(*net.UDPConn)
+	if !ok {
+		return
+	}
+	if err := udp.SetReadBuffer(oscReadBuffer); err != nil {
+		oscLog.Warn("receive buffer not enlarged", "err", err)
+		return
+	}
+	switch size, _, _, ok := socketStats(udp); {
+	case !ok:
+	case size < oscReadBuffer:
+		oscLog.Info("receive buffer smaller than asked for; raise net.core.rmem_max for more", "bytes", size, "want", oscReadBuffer)
+	default:
+		oscLog.Info("receive buffer", "bytes", size)
+	}
+}
+
+// parseProcUDP finds the socket with inode in /proc/net/udp, returning its
+// receive queue in bytes and its drop count.
+func parseProcUDP(data string, inode uint64) (queued, drops int, ok bool) {
+	want := strconv.FormatUint(inode, 10)
+	for _, line := range strings.Split(data, "\n") {
+		// sl local rem st tx:rx tr:when retrnsmt uid timeout inode ref pointer drops
+		f := strings.Fields(line)
+		if len(f) < 13 || f[9] != want {
+			continue
+		}
+		_, rx, _ := strings.Cut(f[4], ":")
+		q, err1 := strconv.ParseInt(rx, 16, 64)
+		d, err2 := strconv.Atoi(f[12])
+		if err1 != nil || err2 != nil {
+			return 0, 0, false
+		}
+		return int(q), d, true
+	}
+	return 0, 0, false
+}
+
+// checkSocket reads the socket's kernel drop count, and warns when its
+// receive buffer is nearly full. The caller must hold mu.
+func (p *packetStats) checkSocket(conn net.PacketConn, now time.Time) {
+	udp, ok := conn.(*net.UDPConn)
+	if !ok {
+		return
+	}
+	size, queued, drops, ok := socketStats(udp)
+	if !ok {
+		return
+	}
+	p.kernelDrops(drops, now)
+	full := size > 0 && queued > size*3/4
+	if full && !p.full {
+		oscLog.Warn("receive buffer nearly full", "queued", queued, "bytes", size)
+	}
+	p.full = full
+}
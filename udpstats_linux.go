@@ -0,0 +1,43 @@
+//go:build linux
+
+package main
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// socketStats returns the socket's receive buffer size (as the kernel
+// counts it, with overhead), the bytes queued in it and the packets the
+// kernel dropped, from SO_RCVBUF and /proc/net/udp.
+func socketStats(conn *net.UDPConn) (size, queued, drops int, ok bool) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	var (
+		inode uint64
+		serr  error
+	)
+	err = raw.Control(func(fd uintptr) {
+		size, serr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+		var st syscall.Stat_t
+		if syscall.Fstat(int(fd), &st) == nil {
+			inode = st.Ino
+		}
+	})
+	if err != nil || serr != nil {
+		return 0, 0, 0, false
+	}
+	for _, file := range []string{"/proc/net/udp", "/proc/net/udp6"} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		if queued, drops, ok := parseProcUDP(string(data), inode); ok {
+			return size, queued, drops, true
+		}
+	}
+	return size, 0, 0, true
+}
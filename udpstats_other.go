@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+import "net"
+
+// The kernel's socket counters are read from /proc, on Linux only.
+func socketStats(*net.UDPConn) (size, queued, drops int, ok bool) { return 0, 0, 0, false }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestPacketStats tests counting lost updates from gaps, out of order
+// pongs, and the loss over the last seconds
+func TestPacketStats(t *testing.T) {
+	t0 := time.Unix(1000, 0)
+	p := packetStats{drops: -1}
+	p.liveUpdate(time.Time{}, t0)
+	p.liveUpdate(t0, t0.Add(100*time.Millisecond))
+	p.liveUpdate(t0.Add(100*time.Millisecond), t0.Add(400*time.Millisecond)) // two missed
+	p.liveUpdate(t0.Add(400*time.Millisecond), t0.Add(5*time.Second))        // stopped, not lost
+	p.pong(pongPrefix+"2", t0)
+	p.pong(pongPrefix+"1", t0)
+	p.kernelDrops(3, t0)
+	p.kernelDrops(5, t0) // two dropped
+	if percent, reordered, _ := p.loss(t0.Add(5 * time.Second)); percent != 40 || reordered != 1 {
+		t.Errorf("loss = %.1f%%, %d out of order, want 40%%, 1", percent, reordered)
+	}
+	if got, want := p.lossText(t0.Add(5*time.Second)), "[red]loss 40.0%[-] 1 out of order"; got != want {
+		t.Errorf("lossText = %q, want %q", got, want)
+	}
+	if _, _, ok := p.loss(t0.Add(20 * time.Second)); ok {
+		t.Error("loss counted from more than 10 s ago")
+	}
+}
+
+// TestParseProcUDP tests finding a socket's queue and drops in /proc/net/udp
+func TestParseProcUDP(t *testing.T) {
+	data := `   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  100: 0100007F:E0B1 00000000:0000 07 00000000:00000300 00:00000000 00000000  1000        0 41234 2 0000000000000000 7
+  101: 00000000:14E7 00000000:0000 07 00000000:00000000 00:00000000 00000000  1000        0 41235 2 0000000000000000 0
+`
+	if queued, drops, ok := parseProcUDP(data, 41234); !ok || queued != 0x300 || drops != 7 {
+		t.Errorf("parseProcUDP = %d, %d, %v, want 768, 7, true", queued, drops, ok)
+	}
+	if _, _, ok := parseProcUDP(data, 1); ok {
+		t.Error("found a socket that is not there")
+	}
+}
+
+// TestSocketStats tests reading the receive buffer of a real socket
+func TestSocketStats(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("socket counters are read on Linux only")
+	}
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	enlargeReadBuffer(conn)
+	size, _, drops, ok := socketStats(conn.(*net.UDPConn))
+	if !ok || size <= 0 || drops != 0 {
+		t.Errorf("socketStats = %d bytes, %d drops, %v", size, drops, ok)
+	}
+}
@@ -0,0 +1,167 @@
+// undo.go
+// GUI undo: Level and Loop page changes made in the TUI can be undone with
+// Ctrl+Z and redone with Ctrl+Y. This is apart from the engine's own undo,
+// which works on the audio.
+
+package main
+
+import "time"
+
+const (
+	// undoDepth is how many changes can be undone.
+	undoDepth = 100
+	// undoMerge joins changes to the same loop and control made closer
+	// together than this into one step, so a drag undoes as a whole.
+	undoMerge = time.Second
+	// undoLevel is the Control of a Level change.
+	undoLevel = "level"
+)
+
+// guiChange is a control of a loop set from From to To at At. Linked are
+// the Level changes the loop's link group made with it.
+type guiChange struct {
+	At       time.Time
+	Loop     int
+	Control  string
+	From, To float32
+	Linked   []guiChange
+}
+
+// undoStack holds the changes that can be undone and those undone that can
+// be redone. A new change clears the redo side.
+type undoStack struct {
+	done, undone []guiChange
+	// sealed stops the next change merging into the last one, after an
+	// undo or redo.
+	sealed bool
+}
+
+// guiUndo is the TUI's undo stack. It is guarded by mu.
+var guiUndo undoStack
+
+// continued returns the last change when c would continue it.
+func (u *undoStack) continued(c guiChange) (*guiChange, bool) {
+	if n := len(u.done); n > 0 && !u.sealed {
+		last := &u.done[n-1]
+		if last.Loop == c.Loop && last.Control == c.Control && c.At.Sub(last.At) < undoMerge {
+			return last, true
+		}
+	}
+	return nil, false
+}
+
+// record adds c, or extends the last change when c continues it. The
+// Linked changes of a continuing c must start where the last one's did.
+func (u *undoStack) record(c guiChange) {
+	if last, ok := u.continued(c); ok {
+		last.At, last.To, last.Linked = c.At, c.To, c.Linked
+		u.undone = nil
+		return
+	}
+	if c.From == c.To {
+		return
+	}
+	u.sealed = false
+	u.undone = nil
+	u.done = append(u.done, c)
+	if len(u.done) > undoDepth {
+		u.done = u.done[len(u.done)-undoDepth:]
+	}
+}
+
+// undo takes the last change off, for its From to be sent.
+func (u *undoStack) undo() (guiChange, bool) {
+	if len(u.done) == 0 {
+		return guiChange{}, false
+	}
+	c := u.done[len(u.done)-1]
+	u.done = u.done[:len(u.done)-1]
+	u.undone = append(u.undone, c)
+	u.sealed = true
+	return c, true
+}
+
+// redo puts the last undone change back, for its To to be sent.
+func (u *undoStack) redo() (guiChange, bool) {
+	if len(u.undone) == 0 {
+		return guiChange{}, false
+	}
+	c := u.undone[len(u.undone)-1]
+	u.undone = u.undone[:len(u.undone)-1]
+	u.done = append(u.done, c)
+	u.sealed = true
+	return c, true
+}
+
+// editLevel sets loop idx's Level from the TUI, as a change that can be
+// undone, moving its link group with it. A drag moves the group from where
+// it started, so members that hit silence or levelMax on the way come back
+// to their offsets.
+func editLevel(idx int, wet float32) {
+	wet = min(max(wet, 0), levelMax)
+	mu.Lock()
+	c := guiChange{At: time.Now(), Loop: idx, Control: undoLevel, From: getLoopState(idx).Wet, To: wet}
+	if linked := linkedLoops(idx); len(linked) > 0 {
+		from := map[int]float32{idx: c.From}
+		for _, j := range linked {
+			from[j] = getLoopState(j).Wet
+		}
+		if last, ok := guiUndo.continued(c); ok && len(last.Linked) == len(linked) {
+			from[idx] = last.From
+			for _, l := range last.Linked {
+				from[l.Loop] = l.From
+			}
+		}
+		to := linkedLevels(from, idx, wet)
+		c.To = to[idx]
+		for _, j := range linked {
+			c.Linked = append(c.Linked, guiChange{Loop: j, Control: undoLevel, From: from[j], To: to[j]})
+		}
+	}
+	guiUndo.record(c)
+	mu.Unlock()
+	setLevel(idx, c.To)
+	for _, l := range c.Linked {
+		setLevel(l.Loop, l.To)
+	}
+}
+
+// undoGUIChange undoes the last TUI change, or redoes the last one undone,
+// sending the value back to the engine or mixer.
+func undoGUIChange(redo bool) {
+	mu.Lock()
+	take, what := guiUndo.undo, "undone"
+	if redo {
+		take, what = guiUndo.redo, "redone"
+	}
+	c, ok := take()
+	mu.Unlock()
+	if !ok {
+		return
+	}
+	v := c.From
+	if redo {
+		v = c.To
+	}
+	tuiLog.Info("GUI change "+what, "loop", c.Loop+1, "control", c.Control, "value", v, "made", c.At.Format(time.TimeOnly))
+	sendGUIValue(c.Loop, c.Control, v)
+	for _, l := range c.Linked {
+		v := l.From
+		if redo {
+			v = l.To
+		}
+		sendGUIValue(l.Loop, l.Control, v)
+	}
+}
+
+// sendGUIValue sets a loop's Level or engine control without recording it.
+func sendGUIValue(i int, ctrl string, v float32) {
+	if ctrl == undoLevel {
+		setLevel(i, v)
+		return
+	}
+	mu.Lock()
+	getLoopState(i).setControl(ctrl, v)
+	mu.Unlock()
+	controlThrottle(ctrl).Set(i+1, v)
+}
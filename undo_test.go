@@ -0,0 +1,84 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestUndoStack tests merging, undo, redo and the depth of the GUI undo
+// stack
+func TestUndoStack(t *testing.T) {
+	at := time.Date(2025, 6, 1, 20, 0, 0, 0, time.UTC)
+	var u undoStack
+	// A drag: one step from 0.2 to 0.6.
+	for i, v := range []float32{0.3, 0.5, 0.6} {
+		u.record(guiChange{At: at.Add(time.Duration(i) * 100 * time.Millisecond), Loop: 0, Control: undoLevel, From: 0.2 + 0.1*float32(i), To: v})
+	}
+	u.record(guiChange{At: at.Add(time.Second), Loop: 0, Control: "feedback", From: 1, To: 0.5})
+	u.record(guiChange{At: at.Add(3 * time.Second), Loop: 0, Control: "feedback", From: 0.5, To: 0.5})
+	if len(u.done) != 2 {
+		t.Fatalf("done = %+v, want the drag and the feedback change", u.done)
+	}
+	if c, ok := u.undo(); !ok || c.Control != "feedback" || c.From != 1 {
+		t.Errorf("undo = %+v, %v, want feedback back to 1", c, ok)
+	}
+	if c, ok := u.undo(); !ok || c.From != 0.2 || c.To != 0.6 {
+		t.Errorf("undo = %+v, %v, want the whole drag from 0.2 to 0.6", c, ok)
+	}
+	if _, ok := u.undo(); ok {
+		t.Error("undo with nothing left")
+	}
+	if c, ok := u.redo(); !ok || c.To != 0.6 {
+		t.Errorf("redo = %+v, %v, want the drag", c, ok)
+	}
+	// Right after a redo, a change to the same control is a step of its own.
+	u.record(guiChange{At: at.Add(1100 * time.Millisecond), Loop: 0, Control: undoLevel, From: 0.6, To: 0.1})
+	if len(u.done) != 2 || len(u.undone) != 0 {
+		t.Errorf("after a new change: done %d, undone %d; want 2 and none to redo", len(u.done), len(u.undone))
+	}
+
+	for i := range undoDepth + 5 {
+		u.record(guiChange{At: at.Add(time.Duration(i) * time.Minute), Loop: i % 2, Control: undoLevel, From: 0, To: 1})
+	}
+	if len(u.done) != undoDepth {
+		t.Errorf("%d changes kept, want %d", len(u.done), undoDepth)
+	}
+}
+
+// TestUndoGUIChange tests that undo and redo send the values back
+func TestUndoGUIChange(t *testing.T) {
+	var sentMu sync.Mutex
+	var sent []float32
+	levelThrottle = newSendThrottle(1000, 0, func(_ int, v float32) {
+		sentMu.Lock()
+		sent = append(sent, v)
+		sentMu.Unlock()
+	})
+	last := func() float32 {
+		sentMu.Lock()
+		defer sentMu.Unlock()
+		if len(sent) == 0 {
+			return -1
+		}
+		return sent[len(sent)-1]
+	}
+	t.Cleanup(func() {
+		mu.Lock()
+		guiUndo = undoStack{}
+		loopStates = make(map[int]*LoopState)
+		mu.Unlock()
+	})
+	mu.Lock()
+	guiUndo = undoStack{}
+	loopStates = make(map[int]*LoopState)
+	getLoopState(1).Wet = 0.25
+	mu.Unlock()
+
+	editLevel(1, 0.75)
+	eventually(t, "level sent", func() bool { return last() == 0.75 })
+	undoGUIChange(false)
+	eventually(t, "level undone", func() bool { return last() == 0.25 && loopStates[1].Wet == 0.25 })
+	undoGUIChange(true)
+	eventually(t, "level redone", func() bool { return last() == 0.75 && loopStates[1].Wet == 0.75 })
+}
@@ -0,0 +1,236 @@
+// verify.go
+// Set verification: every /set is followed, once the engine has had time to
+// apply it, by a get answered on a path of its own, and the value the
+// engine echoes is compared with the one set. A
+// control whose echo differs, or never comes, is marked unconfirmed on the
+// Loop and Globals pages and counted in the status bar, and with
+// --verify-resend is set once more first. UDP control is otherwise fire
+// and forget.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/hypebeast/go-osc/osc"
+)
+
+const (
+	// verifyPrefix starts the reply paths of verification gets:
+	// /verify/<loop>/<control>, with loop -2 for a global control.
+	verifyPrefix = "/verify/"
+	// verifyDelay is how long after a set its value is asked for: the
+	// engine applies sets in its audio thread, and answers gets at once.
+	// The get goes with the next poll after.
+	verifyDelay = 50 * time.Millisecond
+	// verifyTimeout is how long a set waits for the engine to echo it.
+	verifyTimeout = time.Second
+)
+
+var (
+	// verifyResend (--verify-resend) sends an unconfirmed set once more
+	// before marking it.
+	verifyResend = false
+
+	// unverifiedControls are set without verification, as the engine
+	// changes them itself: triggers, and the scratch position.
+	unverifiedControls = []string{"scratch_pos", "delay_trigger", "tap_tempo", "save_loop",
+		"select_next_loop", "select_prev_loop", "select_all_loops"}
+
+	// unconfirmedSets are the controls whose last set the engine has not
+	// confirmed. Guarded by mu.
+	unconfirmedSets = make(map[setKey]bool)
+)
+
+// setKey is a control of a loop, or with loop -2 a global one.
+type setKey struct {
+	loop    int
+	control string
+}
+
+// pendingSet is a set awaiting its echo.
+type pendingSet struct {
+	value float32
+	// getAt is when to ask for the value, unless asked is set.
+	getAt  time.Time
+	asked  bool
+	due    time.Time
+	resent bool
+	// echo is the last value echoed, if one was.
+	echo   float32
+	echoed bool
+}
+
+// setChecks are the sets awaiting their echoes.
+type setChecks struct {
+	mu      sync.Mutex
+	pending map[setKey]*pendingSet
+}
+
+// verifySet records that control was set to v on loop (-2 for a global),
+// to be checked by checkSets. Sets of every loop or the selected one are
+// not verified.
+func (c *SLClient) verifySet(loop int, control string, v float32, now time.Time) {
+	if loop < 0 && loop != -2 || slices.Contains(unverifiedControls, control) {
+		return
+	}
+	k := setKey{loop, control}
+	c.sets.mu.Lock()
+	if c.sets.pending == nil {
+		c.sets.pending = make(map[setKey]*pendingSet)
+	}
+	c.sets.pending[k] = &pendingSet{value: v, getAt: now.Add(verifyDelay), due: now.Add(verifyTimeout)}
+	c.sets.mu.Unlock()
+}
+
+// verifySets verifies the sets among msgs, as sent in a bundle.
+func (c *SLClient) verifySets(msgs []*osc.Message, now time.Time) {
+	for _, m := range msgs {
+		if loop, control, v, ok := parseSet(m); ok {
+			c.verifySet(loop, control, v, now)
+		}
+	}
+}
+
+// parseSet returns the loop (-2 for a global), control and value m sets,
+// if it is a set.
+func parseSet(m *osc.Message) (int, string, float32, bool) {
+	loop := -2
+	if m.Address != "/set" {
+		rest, ok := strings.CutPrefix(m.Address, "/sl/")
+		n, ok2 := strings.CutSuffix(rest, "/set")
+		i, err := strconv.Atoi(n)
+		if !ok || !ok2 || err != nil {
+			return 0, "", 0, false
+		}
+		loop = i
+	}
+	if len(m.Arguments) < 2 {
+		return 0, "", 0, false
+	}
+	control, ok := m.Arguments[0].(string)
+	v, ok2 := argFloat(m.Arguments[1])
+	return loop, control, v, ok && ok2
+}
+
+// getVerify asks the engine for k's value, answered on its verify path.
+func (c *SLClient) getVerify(k setKey) {
+	path := "/get"
+	if k.loop != -2 {
+		path = fmt.Sprintf("/sl/%d/get", k.loop)
+	}
+	oscSend(c.out, osc.NewMessage(path, k.control, c.returnURL, fmt.Sprintf("%s%d/%s", verifyPrefix, k.loop, k.control)))
+}
+
+// verifyReply takes the engine's echo on a verify path: a value matching
+// the one set confirms it. A value that differs may be the echo of an
+// earlier set, or come from an engine slow to apply it, so it is asked for
+// again, and only counts once the set is due.
+func (c *SLClient) verifyReply(m *osc.Message) {
+	n, control, ok := strings.Cut(strings.TrimPrefix(m.Address, verifyPrefix), "/")
+	loop, err := strconv.Atoi(n)
+	if !ok || err != nil || len(m.Arguments) < 3 {
+		return
+	}
+	v, ok := argFloat(m.Arguments[2])
+	if !ok {
+		return
+	}
+	k := setKey{loop, control}
+	c.sets.mu.Lock()
+	p := c.sets.pending[k]
+	confirmed := p != nil && sameValue(v, p.value)
+	switch {
+	case confirmed:
+		delete(c.sets.pending, k)
+	case p != nil:
+		p.echo, p.echoed = v, true
+		p.asked, p.getAt = false, time.Now().Add(verifyDelay)
+	}
+	c.sets.mu.Unlock()
+	if confirmed {
+		mu.Lock()
+		delete(unconfirmedSets, k)
+		mu.Unlock()
+	}
+}
+
+// sameValue reports whether the engine's echo v is the value set, allowing
+// for rounding on the way.
+func sameValue(v, set float32) bool {
+	return math.Abs(float64(v-set)) <= 1e-4*max(1, math.Abs(float64(set)))
+}
+
+// checkSets asks for the values of the sets due to be checked by now, and
+// resends, with --verify-resend, or marks unconfirmed those not echoed in
+// time.
+func (c *SLClient) checkSets(now time.Time) {
+	var ask, failed []setKey
+	resend := make(map[setKey]float32)
+	c.sets.mu.Lock()
+	for k, p := range c.sets.pending {
+		switch {
+		case now.Before(p.due):
+			if !p.asked && !now.Before(p.getAt) {
+				p.asked = true
+				ask = append(ask, k)
+			}
+		case verifyResend && !p.resent:
+			p.resent, p.asked = true, false
+			p.getAt, p.due = now.Add(verifyDelay), now.Add(verifyTimeout)
+			resend[k] = p.value
+			oscLog.Info("set not confirmed, sending it again", "loop", k.loop, "control", k.control, "value", p.value)
+		default:
+			delete(c.sets.pending, k)
+			failed = append(failed, k)
+			var echo any = "no reply"
+			if p.echoed {
+				echo = p.echo
+			}
+			oscLog.Warn("set not confirmed", "loop", k.loop, "control", k.control, "value", p.value, "engine", echo)
+		}
+	}
+	c.sets.mu.Unlock()
+
+	for k, v := range resend {
+		if k.loop == -2 {
+			oscSend(c.out, osc.NewMessage("/set", k.control, v))
+		} else {
+			setControl(c.out, k.loop, k.control, v)
+		}
+	}
+	for _, k := range ask {
+		c.getVerify(k)
+	}
+	if len(failed) == 0 {
+		return
+	}
+	mu.Lock()
+	for _, k := range failed {
+		unconfirmedSets[k] = true
+	}
+	mu.Unlock()
+}
+
+// markUnconfirmed shows a value cell as not confirmed by the engine.
+func markUnconfirmed(c *cell) {
+	spans := append(slices.Clone(c.Spans), span{Text: " ?", Color: tcell.ColorRed})
+	spans[0].Color = tcell.ColorRed
+	c.Spans = spans
+}
+
+// unconfirmedStatus counts the unconfirmed controls for the status bar.
+// The caller must hold mu.
+func unconfirmedStatus() string {
+	if len(unconfirmedSets) == 0 {
+		return ""
+	}
+	return "[red]" + trf("%d settings unconfirmed", len(unconfirmedSets)) + "[-]"
+}
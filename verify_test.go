@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// TestVerifySet tests that a set is asked for once the engine has had time
+// to apply it, confirmed by a matching echo, and marked unconfirmed, after
+// one resend with --verify-resend, when the echo differs
+func TestVerifySet(t *testing.T) {
+	defer func(r bool) {
+		verifyResend = r
+		unconfirmedSets = make(map[setKey]bool)
+	}(verifyResend)
+	pipe := newPipeTransport()
+	c := newTransportClient(pipe, "in-process", "osc.udp://127.0.0.1:1", handleOSC)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.out.run(ctx)
+	echo := func(loop int, ctrl string, v float32) {
+		c.verifyReply(osc.NewMessage(verifyPrefix+strconv.Itoa(loop)+"/"+ctrl, int32(loop), ctrl, v))
+	}
+
+	now := time.Now()
+	c.verifySet(0, "wet", 0.5, now)
+	c.verifySet(-2, "tempo", 100, now.Add(time.Millisecond))
+	c.verifySet(-1, "wet", 0.5, now)
+	c.verifySet(0, "scratch_pos", 0.5, now)
+	if len(c.sets.pending) != 2 {
+		t.Fatalf("%d sets pending, want wet and tempo", len(c.sets.pending))
+	}
+	c.checkSets(now)
+	c.checkSets(now.Add(verifyDelay))
+	pipe.awaitSent(t, "/sl/0/get", "wet")
+	c.checkSets(now.Add(verifyDelay + time.Millisecond))
+	pipe.awaitSent(t, "/get", "tempo")
+	echo(0, "wet", 0.5)
+	echo(-2, "tempo", 90)
+	if _, ok := c.sets.pending[setKey{0, "wet"}]; ok {
+		t.Error("wet not confirmed by its echo")
+	}
+
+	verifyResend = true
+	c.checkSets(now.Add(verifyTimeout + time.Millisecond))
+	pipe.awaitSent(t, "/set", "tempo")
+	if len(unconfirmedSets) != 0 {
+		t.Errorf("unconfirmed before the resend was checked: %v", unconfirmedSets)
+	}
+	c.checkSets(now.Add(2*verifyTimeout + time.Millisecond))
+	if !unconfirmedSets[setKey{-2, "tempo"}] || len(unconfirmedSets) != 1 {
+		t.Errorf("unconfirmed = %v, want tempo", unconfirmedSets)
+	}
+
+	c.verifySet(-2, "tempo", 90, now)
+	echo(-2, "tempo", 90)
+	if len(unconfirmedSets) != 0 {
+		t.Errorf("unconfirmed after a confirmed set = %v", unconfirmedSets)
+	}
+}
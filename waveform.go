@@ -0,0 +1,161 @@
+// waveform.go
+// Waveform previews of WAV files in braille, for the file browser and
+// loops saved with w, and reading WAV samples.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// waveformRows is the preview's height in lines, four dots each.
+	waveformRows = 6
+	// waveformMaxData is the most sample data previewed, about three
+	// minutes of 24-bit stereo at 48 kHz, so moving through the browser
+	// stays quick.
+	waveformMaxData = 32 << 20
+)
+
+var errNoPreview = errors.New("no preview")
+
+// wavPeaks reads the WAV file at path and returns the peak amplitude, 0 to
+// 1 over all channels, of each of columns equal slices of it.
+func wavPeaks(path string, columns int) ([]float32, error) {
+	a, err := readAudioInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case a.Format != "WAV":
+		return nil, fmt.Errorf("%w of %s files", errNoPreview, a.Format)
+	case a.Frames*int64(a.Channels*((a.Bits+7)/8)) > waveformMaxData:
+		return nil, fmt.Errorf("%w: longer than %d MB", errNoPreview, waveformMaxData>>20)
+	}
+	a, samples, err := readWAV(path)
+	if err != nil {
+		return nil, err
+	}
+	peaks := make([]float32, max(columns, 0))
+	if columns <= 0 || a.Frames == 0 {
+		return peaks, nil
+	}
+	for i, v := range samples {
+		c := int(int64(i/a.Channels) * int64(columns) / a.Frames)
+		peaks[c] = max(peaks[c], min(float32(math.Abs(float64(v))), 1))
+	}
+	return peaks, nil
+}
+
+// readWAV reads the WAV file at path: its header, and its samples
+// interleaved, from -1 to 1.
+func readWAV(path string) (audioInfo, []float32, error) {
+	a, err := readAudioInfo(path)
+	if err != nil {
+		return a, nil, err
+	}
+	if a.Format != "WAV" {
+		return a, nil, fmt.Errorf("%s is not a WAV file", filepath.Base(path))
+	}
+	sample, err := sampleDecoder(a)
+	if err != nil {
+		return a, nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return a, nil, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(12, io.SeekStart); err != nil {
+		return a, nil, err
+	}
+	var data io.Reader
+	riffChunks(f, binary.LittleEndian, func(id string, size int64) bool {
+		if id == "data" {
+			data = io.LimitReader(f, size)
+		}
+		return data != nil
+	})
+	if data == nil {
+		return a, nil, errors.New("WAV without a data chunk")
+	}
+	raw, err := io.ReadAll(data)
+	if err != nil {
+		return a, nil, err
+	}
+
+	width := (a.Bits + 7) / 8
+	frames := len(raw) / (width * a.Channels)
+	samples := make([]float32, frames*a.Channels)
+	for i := range samples {
+		samples[i] = float32(sample(raw[i*width:]))
+	}
+	a.Frames = int64(frames)
+	return a, samples, nil
+}
+
+// sampleDecoder returns a function decoding one of a's samples to -1 to 1.
+func sampleDecoder(a audioInfo) (func([]byte) float64, error) {
+	le := binary.LittleEndian
+	switch {
+	case a.Float && a.Bits == 32:
+		return func(b []byte) float64 { return float64(math.Float32frombits(le.Uint32(b))) }, nil
+	case a.Float && a.Bits == 64:
+		return func(b []byte) float64 { return math.Float64frombits(le.Uint64(b)) }, nil
+	case a.Float:
+	case a.Bits == 8:
+		return func(b []byte) float64 { return (float64(b[0]) - 128) / 128 }, nil
+	case a.Bits == 16:
+		return func(b []byte) float64 { return float64(int16(le.Uint16(b))) / (1 << 15) }, nil
+	case a.Bits == 24:
+		return func(b []byte) float64 {
+			return float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+		}, nil
+	case a.Bits == 32:
+		return func(b []byte) float64 { return float64(int32(le.Uint32(b))) / (1 << 31) }, nil
+	}
+	return nil, fmt.Errorf("%d-bit samples not supported", a.Bits)
+}
+
+// waveformLines draws peaks two per character, mirrored about the middle
+// of rows lines of braille.
+func waveformLines(peaks []float32, rows int) []string {
+	dots := rows * 4
+	mid := dots / 2
+	// span is the lowest and highest dot, counted from the bottom, that
+	// the peak at column c lights; none when lo == hi.
+	span := func(c int) (lo, hi int) {
+		if c >= len(peaks) {
+			return 0, 0
+		}
+		h := min(int(math.Ceil(float64(peaks[c])*float64(mid))), mid)
+		return mid - h, mid + h
+	}
+	lines := make([]string, rows)
+	for r := range rows {
+		bottom := (rows - 1 - r) * 4
+		var b strings.Builder
+		for c := 0; c < len(peaks); c += 2 {
+			ch := rune(0x2800)
+			for col, bits := range [2][4]rune{brailleLeft, brailleRight} {
+				lo, hi := span(c + col)
+				for d := range 4 {
+					if y := bottom + d; y >= lo && y < hi {
+						ch |= bits[d]
+					}
+				}
+			}
+			b.WriteRune(ch)
+		}
+		lines[r] = b.String()
+	}
+	return lines
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestWAV writes a WAV file of frames of samples, one per channel,
+// each encoded by put in bits-bit samples.
+func writeTestWAV(t *testing.T, path string, format uint16, bits int, frames [][]float64, put func([]byte, float64)) {
+	t.Helper()
+	le := binary.LittleEndian
+	channels, width := len(frames[0]), bits/8
+	var fmtChunk []byte
+	fmtChunk = le.AppendUint16(fmtChunk, format)
+	fmtChunk = le.AppendUint16(fmtChunk, uint16(channels))
+	fmtChunk = le.AppendUint32(fmtChunk, 48000)
+	fmtChunk = le.AppendUint32(fmtChunk, uint32(48000*channels*width))
+	fmtChunk = le.AppendUint16(fmtChunk, uint16(channels*width))
+	fmtChunk = le.AppendUint16(fmtChunk, uint16(bits))
+	data := make([]byte, len(frames)*channels*width)
+	for i, f := range frames {
+		for ch, v := range f {
+			put(data[(i*channels+ch)*width:], v)
+		}
+	}
+	wav := append([]byte("RIFF\x00\x00\x00\x00WAVE"), chunk(le, "fmt ", fmtChunk)...)
+	wav = append(wav, chunk(le, "data", data)...)
+	if err := os.WriteFile(path, wav, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWAVPeaks tests decoding each sample format into column peaks
+func TestWAVPeaks(t *testing.T) {
+	le := binary.LittleEndian
+	dir := t.TempDir()
+	// Quiet then loud, the loud part on the second channel only.
+	frames := [][]float64{{0.1, 0}, {-0.1, 0}, {0, 0.5}, {0, -1}}
+	want := []float32{0.1, 1}
+	for _, tc := range []struct {
+		name   string
+		format uint16
+		bits   int
+		put    func([]byte, float64)
+	}{
+		{"u8", 1, 8, func(b []byte, v float64) { b[0] = byte(math.Round(v*127 + 128)) }},
+		{"s16", 1, 16, func(b []byte, v float64) { le.PutUint16(b, uint16(int16(math.Round(v*32767)))) }},
+		{"s24", 1, 24, func(b []byte, v float64) {
+			n := uint32(int32(math.Round(v * 8388607)))
+			b[0], b[1], b[2] = byte(n), byte(n>>8), byte(n>>16)
+		}},
+		{"s32", 1, 32, func(b []byte, v float64) { le.PutUint32(b, uint32(int32(math.Round(v*2147483647)))) }},
+		{"f32", 3, 32, func(b []byte, v float64) { le.PutUint32(b, math.Float32bits(float32(v))) }},
+		{"f64", 3, 64, func(b []byte, v float64) { le.PutUint64(b, math.Float64bits(v)) }},
+	} {
+		path := filepath.Join(dir, tc.name+".wav")
+		writeTestWAV(t, path, tc.format, tc.bits, frames, tc.put)
+		peaks, err := wavPeaks(path, 2)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		for i := range want {
+			if math.Abs(float64(peaks[i]-want[i])) > 0.01 {
+				t.Errorf("%s: peaks %v, want %v", tc.name, peaks, want)
+				break
+			}
+		}
+	}
+
+	aiff := filepath.Join(dir, "a.aiff")
+	comm := make([]byte, 18)
+	comm[1] = 1 // one channel
+	os.WriteFile(aiff, append([]byte("FORM\x00\x00\x00\x00AIFF"), chunk(binary.BigEndian, "COMM", comm)...), 0o644)
+	if _, err := wavPeaks(aiff, 10); !errors.Is(err, errNoPreview) {
+		t.Errorf("AIFF preview: %v, want no preview", err)
+	}
+}
+
+// TestWaveformLines tests the braille drawing
+func TestWaveformLines(t *testing.T) {
+	lines := waveformLines([]float32{0, 1, 0.2, 0.2, 0}, 2)
+	got := strings.Join(lines, "\n")
+	for _, l := range lines {
+		if n := len([]rune(l)); n != 3 {
+			t.Fatalf("line %q has %d characters, want 3", l, n)
+		}
+	}
+	// The full peak lights the whole right column of the first character,
+	// the quiet ones a dot each side of the middle.
+	if []rune(lines[0])[0] != brailleRune(0, 4) || []rune(lines[1])[0] != brailleRune(0, 4) {
+		t.Errorf("full peak drawn as\n%s", got)
+	}
+	if []rune(lines[0])[1] != brailleRune(1, 1) || []rune(lines[1])[1] != 0x2800|brailleLeft[3]|brailleRight[3] {
+		t.Errorf("quiet peaks drawn as\n%s", got)
+	}
+	if []rune(lines[0])[2] != 0x2800 {
+		t.Errorf("silence drawn as\n%s", got)
+	}
+}